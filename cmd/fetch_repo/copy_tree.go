@@ -22,6 +22,12 @@ import (
 )
 
 func copyTree(destRoot, srcRoot string) error {
+	return copyTreeSkipping(destRoot, srcRoot, nil)
+}
+
+// copyTreeSkipping is like copyTree, but it doesn't copy directories for which
+// skip returns true, given their slash-separated paths relative to srcRoot.
+func copyTreeSkipping(destRoot, srcRoot string, skip func(rel string) bool) error {
 	return filepath.Walk(srcRoot, func(src string, info os.FileInfo, e error) (err error) {
 		if e != nil {
 			return e
@@ -33,6 +39,9 @@ func copyTree(destRoot, srcRoot string) error {
 		if rel == "." {
 			return nil
 		}
+		if info.IsDir() && skip != nil && skip(filepath.ToSlash(rel)) {
+			return filepath.SkipDir
+		}
 		dest := filepath.Join(destRoot, rel)
 		switch {
 		case info.Mode().IsDir():
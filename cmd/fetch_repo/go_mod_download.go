@@ -31,11 +31,14 @@ func findGoPath() string {
 	return goPath
 }
 
-func runGoModDownload(dl *GoModDownloadResult, dest string, importpath string, version string) error {
+// runGoModDownload runs "go mod download" for a module. If env is not nil,
+// it's the environment for the go command.
+func runGoModDownload(dl *GoModDownloadResult, dest string, importpath string, version string, env []string) error {
 	buf := bytes.NewBuffer(nil)
 	bufErr := bytes.NewBuffer(nil)
 	cmd := exec.Command(findGoPath(), "mod", "download", "-json", "-modcacherw")
 	cmd.Dir = dest
+	cmd.Env = env
 
 	if version != "" && importpath != "" {
 		cmd.Args = append(cmd.Args, importpath+"@"+version)
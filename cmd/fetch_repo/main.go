@@ -35,20 +35,38 @@ var (
 	// Common flags
 	importpath = flag.String("importpath", "", "Go importpath to the repository fetch")
 	path       = flag.String("path", "", "absolute or relative path to a local go module")
+	vendorDir  = flag.String("vendor-dir", "", "absolute or relative path to a vendor directory made with 'go mod vendor' containing the module named by --importpath")
 	dest       = flag.String("dest", "", "destination directory")
 	no_fetch   = flag.Bool("no-fetch", false, "files already exist, do not fetch")
 	clean      = flag.Bool("clean", false, "remove existing bazel build files")
 
 	// Repository flags
-	remote = flag.String("remote", "", "The URI of the remote repository. Must be used with the --vcs flag.")
-	cmd    = flag.String("vcs", "", "Version control system to use to fetch the repository. Should be one of: git,hg,svn,bzr. Must be used with the --remote flag.")
-	rev    = flag.String("rev", "", "target revision")
+	remote     = flag.String("remote", "", "The URI of the remote repository. Must be used with the --vcs flag.")
+	cmd        = flag.String("vcs", "", "Version control system to use to fetch the repository. Should be one of: git,hg,svn,bzr. Must be used with the --remote flag.")
+	rev        = flag.String("rev", "", "target revision")
+	submodules = flag.Bool("submodules", false, "initialize and check out git submodules recursively after fetching")
 
 	// Module flags
 	version = flag.String("version", "", "module version. Must be semantic version or pseudo-version.")
 	sum     = flag.String("sum", "", "hash of module contents")
+
+	// Module proxy flags
+	proxies         stringList
+	proxyHeaders    stringList
+	proxyCAFile     = flag.String("proxy-ca-file", "", "PEM file with certificates to trust when connecting to module proxies, in addition to the system's")
+	proxyClientCert = flag.String("proxy-client-cert", "", "PEM file with a client certificate presented to module proxies. Must be used with --proxy-client-key.")
+	proxyClientKey  = flag.String("proxy-client-key", "", "PEM file with the key for --proxy-client-cert")
+
+	// Offline flags
+	offline          = flag.Bool("offline", false, "only read modules from the module cache, without accessing the network")
+	moduleArchiveDir = flag.String("module-archive-dir", "", "directory laid out like GOMODCACHE/cache/download to read modules from instead of the module cache. Implies --offline.")
 )
 
+func init() {
+	flag.Var(&proxies, "proxy", "URL of a module proxy to download from, instead of those in GOPROXY. May be repeated; proxies are tried in order.")
+	flag.Var(&proxyHeaders, "proxy-header", "HTTP header sent to module proxies, in \"Name: Value\" form. ${VAR} in the value is replaced with an environment variable. May be repeated.")
+}
+
 // Override in tests to disable network calls.
 var repoRootForImportPath = vcs.RepoRootForImportPath
 
@@ -69,6 +87,27 @@ func main() {
 		log.Fatal("fetch_repo does not accept positional arguments")
 	}
 
+	pc := proxyConfig{
+		urls:       proxies,
+		headers:    proxyHeaders,
+		caFile:     *proxyCAFile,
+		clientCert: *proxyClientCert,
+		clientKey:  *proxyClientKey,
+	}
+	if pc.enabled() && (*no_fetch || *path != "" || *version == "") {
+		log.Fatal("module proxy flags may only be set in module mode")
+	}
+	oc := offlineConfig{
+		enabled:    *offline || *moduleArchiveDir != "",
+		archiveDir: *moduleArchiveDir,
+	}
+	if oc.enabled && (*no_fetch || *path != "" || *version == "") {
+		log.Fatal("-offline and -module-archive-dir may only be set in module mode")
+	}
+	if oc.enabled && pc.enabled() {
+		log.Fatal("-offline and -module-archive-dir can't be used with module proxy flags")
+	}
+
 	if *no_fetch {
 		// Nothing to do
 	} else if *path != "" {
@@ -84,6 +123,9 @@ func main() {
 		if *rev != "" {
 			log.Fatal("-rev must not be set in module path mode")
 		}
+		if *submodules {
+			log.Fatal("-submodules must not be set in module path mode")
+		}
 		if *version != "" {
 			log.Fatal("-version must not be set in module path mode")
 		}
@@ -93,6 +135,24 @@ func main() {
 		if err := copyTree(*dest, *path); err != nil {
 			log.Fatal(err)
 		}
+	} else if *vendorDir != "" {
+		if *importpath == "" {
+			log.Fatal("-importpath must be set in vendor mode")
+		}
+		if *remote != "" || *cmd != "" || *rev != "" || *submodules {
+			log.Fatal("-remote, -vcs, -rev, and -submodules must not be set in vendor mode")
+		}
+		if *version != "" || *sum != "" {
+			log.Fatal("-version and -sum must not be set in vendor mode")
+		}
+		if err := copyVendoredModule(*dest, *vendorDir, *importpath); err != nil {
+			log.Fatal(err)
+		}
+		// Build files in the vendor directory were generated for the main
+		// repository, with labels that don't work in this one.
+		if err := cleanBuildFiles(*dest); err != nil {
+			log.Fatal(err)
+		}
 	} else if *version != "" {
 		if *remote != "" {
 			log.Fatal("-remote must not be set in module mode")
@@ -103,13 +163,16 @@ func main() {
 		if *rev != "" {
 			log.Fatal("-rev must not be set in module mode")
 		}
+		if *submodules {
+			log.Fatal("-submodules must not be set in module mode")
+		}
 		if *version == "" {
 			log.Fatal("-version must be set in module mode")
 		}
 		if *sum == "" {
 			log.Fatal("-sum must be set in module mode")
 		}
-		if err := fetchModule(*dest, *importpath, *version, *sum); err != nil {
+		if err := fetchModule(*dest, *importpath, *version, *sum, pc, oc); err != nil {
 			log.Fatal(err)
 		}
 	} else {
@@ -122,7 +185,7 @@ func main() {
 		if *rev == "" {
 			log.Fatal("-rev must be set in repository mode")
 		}
-		if err := fetchRepo(*dest, *remote, *cmd, *importpath, *rev); err != nil {
+		if err := fetchRepo(*dest, *remote, *cmd, *importpath, *rev, *submodules); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -17,10 +17,56 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-func fetchModule(dest, importpath, version, sum string) error {
+// offlineConfig describes how fetch_repo downloads modules without network
+// access.
+type offlineConfig struct {
+	// enabled is true if modules may only be read from the module cache,
+	// or from archiveDir if it's set.
+	enabled bool
+
+	// archiveDir is a directory laid out like GOMODCACHE/cache/download, for
+	// example a copy of one checked into a repository. It's used as a file://
+	// module proxy.
+	archiveDir string
+}
+
+// env returns the environment for the go command in offline mode. The go
+// command reports an error for any module it would need to fetch.
+func (oc offlineConfig) env() ([]string, error) {
+	goproxy := "off"
+	if oc.archiveDir != "" {
+		dir, err := filepath.Abs(oc.archiveDir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("module archive directory: %w", err)
+		}
+		dir = filepath.ToSlash(dir)
+		if !strings.HasPrefix(dir, "/") {
+			dir = "/" + dir
+		}
+		goproxy = (&url.URL{Scheme: "file", Path: dir}).String()
+	}
+	return append(os.Environ(), "GOPROXY="+goproxy, "GONOPROXY=none", "GOSUMDB=off"), nil
+}
+
+// notFoundError explains that a module wasn't found without network access.
+func (oc offlineConfig) notFoundError(importpath, version string, err error) error {
+	where := "the module cache"
+	if oc.archiveDir != "" {
+		where = "module archive directory " + oc.archiveDir
+	}
+	return fmt.Errorf("%s@%s could not be read from %s, and offline mode is enabled, so it was not downloaded. Add it with \"go mod download %s@%s\", or disable offline mode.\n%w", importpath, version, where, importpath, version, err)
+}
+
+func fetchModule(dest, importpath, version, sum string, pc proxyConfig, oc offlineConfig) error {
 	// Check that version is a complete semantic version or pseudo-version.
 	if _, ok := parse(version); !ok {
 		return fmt.Errorf("%q is not a valid semantic version", version)
@@ -28,6 +74,25 @@ func fetchModule(dest, importpath, version, sum string) error {
 		return fmt.Errorf("-version must be a complete semantic version. %q is a prefix.", version)
 	}
 
+	// In offline mode, only read modules from the module cache or archive
+	// directory. Otherwise, download through a local proxy if the go command
+	// can't reach the module proxies on its own. Since only that proxy may be
+	// used, GONOPROXY is set so it isn't bypassed for private modules.
+	var env []string
+	if oc.enabled {
+		var err error
+		if env, err = oc.env(); err != nil {
+			return err
+		}
+	} else if pc.enabled() {
+		proxyURL, stop, err := startProxy(pc)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		env = append(os.Environ(), "GOPROXY="+proxyURL, "GONOPROXY=none")
+	}
+
 	// Download the module. In Go 1.11, this command must be run in a module,
 	// so we create a dummy module in the current directory (which should be
 	// empty).
@@ -50,11 +115,13 @@ func fetchModule(dest, importpath, version, sum string) error {
 	}
 
 	dl := GoModDownloadResult{}
-	err = runGoModDownload(&dl, dest, importpath, version)
+	err = runGoModDownload(&dl, dest, importpath, version, env)
 	// Remove the temporary files before copying, so they don't leak into dest.
 	os.Remove("go.mod")
 	os.Remove("go.sum")
-	if err != nil {
+	if err != nil && oc.enabled {
+		return oc.notFoundError(importpath, version, err)
+	} else if err != nil {
 		return err
 	}
 
@@ -0,0 +1,83 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOfflineEnv(t *testing.T) {
+	archiveDir := t.TempDir()
+	for _, tc := range []struct {
+		desc        string
+		oc          offlineConfig
+		wantGoproxy string
+		wantErr     bool
+	}{
+		{
+			desc:        "module_cache",
+			oc:          offlineConfig{enabled: true},
+			wantGoproxy: "off",
+		},
+		{
+			desc:        "archive_dir",
+			oc:          offlineConfig{enabled: true, archiveDir: archiveDir},
+			wantGoproxy: "file://" + filepath.ToSlash(archiveDir),
+		},
+		{
+			desc:    "missing_archive_dir",
+			oc:      offlineConfig{enabled: true, archiveDir: filepath.Join(archiveDir, "missing")},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			env, err := tc.oc.env()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("got success; want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var goproxy, gonoproxy string
+			for _, kv := range env {
+				if v, ok := strings.CutPrefix(kv, "GOPROXY="); ok {
+					goproxy = v
+				} else if v, ok := strings.CutPrefix(kv, "GONOPROXY="); ok {
+					gonoproxy = v
+				}
+			}
+			if goproxy != tc.wantGoproxy {
+				t.Errorf("GOPROXY: got %q; want %q", goproxy, tc.wantGoproxy)
+			}
+			if gonoproxy != "none" {
+				t.Errorf("GONOPROXY: got %q; want %q", gonoproxy, "none")
+			}
+		})
+	}
+
+	err := offlineConfig{enabled: true, archiveDir: "third_party/modules"}.notFoundError("example.com/m", "v1.0.0", errors.New("not found"))
+	for _, want := range []string{"example.com/m@v1.0.0", "module archive directory third_party/modules", "offline mode is enabled", "not found"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// stringList is a flag.Value that accumulates each value it's set to.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// proxyConfig describes how fetch_repo downloads modules in module mode, when
+// GOPROXY and the settings the go command understands aren't enough: for
+// example, when a proxy needs an authorization header or a private CA.
+type proxyConfig struct {
+	// urls are the module proxies to try, in order. Each request falls back
+	// to the next proxy on any error. If empty, the proxies in GOPROXY are
+	// used.
+	urls []string
+
+	// headers are sent with each request, in "Name: Value" form. Environment
+	// variables like ${TOKEN} in values are expanded, so secrets don't need
+	// to be written in build files.
+	headers []string
+
+	// caFile is a PEM file with certificates to trust in addition to the
+	// system's. clientCert and clientKey are PEM files with a certificate and
+	// key presented to proxies that require client authentication.
+	caFile, clientCert, clientKey string
+}
+
+func (pc proxyConfig) enabled() bool {
+	return len(pc.urls) > 0 || len(pc.headers) > 0 || pc.caFile != "" || pc.clientCert != "" || pc.clientKey != ""
+}
+
+// startProxy starts a module proxy on a loopback address that forwards
+// requests to the proxies in pc with its headers and TLS settings. The go
+// command can then use it without any authentication of its own. startProxy
+// returns the URL of the proxy and a function that stops it.
+func startProxy(pc proxyConfig) (proxyURL string, stop func(), err error) {
+	upstreams := pc.urls
+	if len(upstreams) == 0 {
+		upstreams = goproxyURLs(os.Getenv("GOPROXY"))
+	}
+	if len(upstreams) == 0 {
+		return "", nil, errors.New("no module proxies: set -proxy or GOPROXY")
+	}
+
+	header := make(http.Header)
+	for _, h := range pc.headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return "", nil, fmt.Errorf("invalid header %q: want \"Name: Value\"", h)
+		}
+		header.Add(strings.TrimSpace(name), os.ExpandEnv(strings.TrimSpace(value)))
+	}
+
+	tlsConfig, err := pc.tlsConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{Transport: transport}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	srv := &http.Server{Handler: &forwardingProxy{client: client, upstreams: upstreams, header: header}}
+	go srv.Serve(ln)
+	return "http://" + ln.Addr().String(), func() { srv.Close() }, nil
+}
+
+// goproxyURLs returns the proxy URLs in a GOPROXY value, skipping "direct"
+// and "off". If GOPROXY is empty, the go command's default proxy is returned.
+func goproxyURLs(goproxy string) []string {
+	if goproxy == "" {
+		return []string{"https://proxy.golang.org"}
+	}
+	var urls []string
+	for _, u := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		if u = strings.TrimSpace(u); u != "" && u != "direct" && u != "off" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+func (pc proxyConfig) tlsConfig() (*tls.Config, error) {
+	if pc.caFile == "" && pc.clientCert == "" && pc.clientKey == "" {
+		return nil, nil
+	}
+	config := &tls.Config{}
+	if pc.caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(pc.caFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", pc.caFile)
+		}
+		config.RootCAs = pool
+	}
+	if pc.clientCert != "" || pc.clientKey != "" {
+		if pc.clientCert == "" || pc.clientKey == "" {
+			return nil, errors.New("-proxy-client-cert and -proxy-client-key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(pc.clientCert, pc.clientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// forwardingProxy is an http.Handler that serves the module proxy protocol
+// by forwarding each request to upstream proxies in order, returning the
+// first successful response.
+type forwardingProxy struct {
+	client    *http.Client
+	upstreams []string
+	header    http.Header
+}
+
+func (p *forwardingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var errs []string
+	notFound := true
+	for _, upstream := range p.upstreams {
+		url := strings.TrimSuffix(upstream, "/") + r.URL.EscapedPath()
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+		if err != nil {
+			errs = append(errs, err.Error())
+			notFound = false
+			continue
+		}
+		for name, values := range p.header {
+			req.Header[name] = values
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			errs = append(errs, err.Error())
+			notFound = false
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			errs = append(errs, fmt.Sprintf("%s: %s", url, resp.Status))
+			if resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone {
+				notFound = false
+			}
+			continue
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		w.WriteHeader(http.StatusOK)
+		io.Copy(w, resp.Body)
+		resp.Body.Close()
+		return
+	}
+
+	// The go command treats 404 and 410 as "not found", and reports other
+	// statuses as errors.
+	status := http.StatusBadGateway
+	if notFound {
+		status = http.StatusNotFound
+	}
+	http.Error(w, strings.Join(errs, "\n"), status)
+}
@@ -0,0 +1,138 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestForwardingProxy(t *testing.T) {
+	t.Setenv("PROXY_TOKEN", "secret")
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	private := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/example.com/!m/@v/v1.0.0.info" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"Version":"v1.0.0"}`)
+	}))
+	defer private.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: private.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		desc       string
+		pc         proxyConfig
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc: "fallback",
+			pc: proxyConfig{
+				urls:    []string{missing.URL, private.URL},
+				headers: []string{"Authorization: Bearer ${PROXY_TOKEN}"},
+				caFile:  caFile,
+			},
+			path:       "/example.com/!m/@v/v1.0.0.info",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"Version":"v1.0.0"}`,
+		},
+		{
+			desc: "not_found",
+			pc: proxyConfig{
+				urls:    []string{missing.URL, private.URL},
+				headers: []string{"Authorization: Bearer ${PROXY_TOKEN}"},
+				caFile:  caFile,
+			},
+			path:       "/example.com/!m/@v/v2.0.0.info",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			desc: "unauthorized",
+			pc: proxyConfig{
+				urls:   []string{private.URL},
+				caFile: caFile,
+			},
+			path:       "/example.com/!m/@v/v1.0.0.info",
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			desc: "untrusted",
+			pc: proxyConfig{
+				urls:    []string{private.URL},
+				headers: []string{"Authorization: Bearer ${PROXY_TOKEN}"},
+			},
+			path:       "/example.com/!m/@v/v1.0.0.info",
+			wantStatus: http.StatusBadGateway,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			proxyURL, stop, err := startProxy(tc.pc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stop()
+			resp, err := http.Get(proxyURL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("got status %d; want %d\n%s", resp.StatusCode, tc.wantStatus, body)
+			}
+			if tc.wantBody != "" && string(body) != tc.wantBody {
+				t.Errorf("got body %q; want %q", body, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestGoproxyURLs(t *testing.T) {
+	for _, tc := range []struct {
+		goproxy string
+		want    []string
+	}{
+		{goproxy: "", want: []string{"https://proxy.golang.org"}},
+		{goproxy: "https://a.example.com,https://b.example.com|direct", want: []string{"https://a.example.com", "https://b.example.com"}},
+		{goproxy: "off", want: nil},
+	} {
+		if got := goproxyURLs(tc.goproxy); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("goproxyURLs(%q): got %q; want %q", tc.goproxy, got, tc.want)
+		}
+	}
+}
@@ -17,16 +17,40 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
 
 	"golang.org/x/tools/go/vcs"
 )
 
-func fetchRepo(dest, remote, cmd, importpath, rev string) error {
+func fetchRepo(dest, remote, cmd, importpath, rev string, submodules bool) error {
 	root, err := getRepoRoot(remote, cmd, importpath)
 	if err != nil {
 		return err
 	}
-	return root.VCS.CreateAtRev(dest, root.Repo, rev)
+	if submodules && root.VCS.Cmd != "git" {
+		return fmt.Errorf("-submodules is only supported for git repositories; %s uses %s", importpath, root.VCS.Name)
+	}
+	if err := root.VCS.CreateAtRev(dest, root.Repo, rev); err != nil {
+		return err
+	}
+	if submodules {
+		return updateSubmodules(dest)
+	}
+	return nil
+}
+
+// updateSubmodules initializes and checks out the submodules of the git
+// repository in dir, recursively, at the commits recorded by the superproject.
+func updateSubmodules(dir string) error {
+	cmd := exec.Command("git", "submodule", "update", "--init", "--recursive")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("updating submodules in %s: %w", dir, err)
+	}
+	return nil
 }
 
 func getRepoRoot(remote, cmd, importpath string) (*vcs.RepoRoot, error) {
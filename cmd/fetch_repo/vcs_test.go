@@ -0,0 +1,98 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchRepoSubmodules(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// Submodules are cloned from local paths in this test, which git refuses
+	// by default.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	tmp := t.TempDir()
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+
+	sub := filepath.Join(tmp, "sub")
+	if err := os.MkdirAll(sub, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	git(sub, "init", "-q")
+	if err := os.WriteFile(filepath.Join(sub, "sub.c"), []byte("int x;\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	git(sub, "add", ".")
+	git(sub, "commit", "-q", "-m", "sub")
+
+	super := filepath.Join(tmp, "super")
+	if err := os.MkdirAll(super, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	git(super, "init", "-q")
+	git(super, "submodule", "add", "-q", sub, "third_party/sub")
+	git(super, "commit", "-q", "-m", "super")
+	rev := git(super, "rev-parse", "HEAD")
+	rev = rev[:len(rev)-1]
+
+	for _, tc := range []struct {
+		desc       string
+		submodules bool
+		wantSub    bool
+	}{
+		{desc: "without_submodules"},
+		{desc: "with_submodules", submodules: true, wantSub: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			if err := fetchRepo(dest, super, "git", "example.com/super", rev, tc.submodules); err != nil {
+				t.Fatal(err)
+			}
+			_, err := os.Stat(filepath.Join(dest, "third_party/sub/sub.c"))
+			if gotSub := err == nil; gotSub != tc.wantSub {
+				t.Errorf("submodule file present: got %v, want %v", gotSub, tc.wantSub)
+			}
+		})
+	}
+}
+
+func TestFetchRepoSubmodulesRequiresGit(t *testing.T) {
+	err := fetchRepo(t.TempDir(), "https://example.com/repo", "hg", "example.com/repo", "abc", true)
+	if err == nil {
+		t.Fatal("got nil error, want error for hg repository")
+	}
+}
@@ -0,0 +1,87 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// copyVendoredModule copies the module modPath from vendorDir, a directory
+// made with "go mod vendor", to dest. Directories of other modules nested
+// inside the module's directory, like those of example.com/m/sub when
+// modPath is example.com/m, are not copied.
+func copyVendoredModule(dest, vendorDir, modPath string) error {
+	modulesTxt := filepath.Join(vendorDir, "modules.txt")
+	mods, err := readVendoredModules(modulesTxt)
+	if err != nil {
+		return err
+	}
+	var found bool
+	var nested []string
+	for _, m := range mods {
+		if m == modPath {
+			found = true
+		} else if strings.HasPrefix(m, modPath+"/") {
+			nested = append(nested, strings.TrimPrefix(m, modPath+"/"))
+		}
+	}
+	if !found {
+		return fmt.Errorf("module %s is not listed in %s; run 'go mod vendor' to update the vendor directory", modPath, modulesTxt)
+	}
+	src := filepath.Join(vendorDir, filepath.FromSlash(modPath))
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("module %s is not vendored: %w", modPath, err)
+	}
+	return copyTreeSkipping(dest, src, func(rel string) bool {
+		for _, n := range nested {
+			if rel == n {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// readVendoredModules returns the paths of the modules listed in a
+// vendor/modules.txt file.
+func readVendoredModules(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var mods []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Module lines look like "# example.com/m v1.2.3", optionally followed
+		// by a replacement. Lines starting with "## " are annotations.
+		line := s.Text()
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			mods = append(mods, fields[1])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return mods, nil
+}
@@ -0,0 +1,108 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCopyVendoredModule(t *testing.T) {
+	vendorDir := t.TempDir()
+	files := map[string]string{
+		"modules.txt": `# example.com/m v1.0.0
+## explicit; go 1.21
+example.com/m
+example.com/m/inner
+# example.com/m/sub v0.1.0 => ../sub
+## explicit
+example.com/m/sub
+# example.com/other v1.2.0
+example.com/other
+`,
+		"example.com/m/m.go":           "package m\n",
+		"example.com/m/LICENSE":        "license\n",
+		"example.com/m/inner/inner.go": "package inner\n",
+		"example.com/m/sub/sub.go":     "package sub\n",
+		"example.com/other/other.go":   "package other\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(vendorDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		desc, modPath string
+		want          []string
+		wantErr       string
+	}{
+		{
+			desc:    "nested_module_excluded",
+			modPath: "example.com/m",
+			want:    []string{"LICENSE", "inner/inner.go", "m.go"},
+		},
+		{
+			desc:    "nested_module",
+			modPath: "example.com/m/sub",
+			want:    []string{"sub.go"},
+		},
+		{
+			desc:    "not_listed",
+			modPath: "example.com/missing",
+			wantErr: "is not listed in",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			dest := t.TempDir()
+			err := copyVendoredModule(dest, vendorDir, tc.modPath)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v; want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			err = filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(dest, path)
+				got = append(got, filepath.ToSlash(rel))
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("copied files (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -231,3 +231,114 @@ go_library(
 		t.Fatalf("got %q; want %q", err, wantError)
 	}
 }
+
+func TestDiffSummary(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/hello
+`,
+		},
+		{Path: "hello.go", Content: `package hello`},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/hello/lib",
+)
+
+go_test(
+    name = "lib_test",
+    srcs = ["lib_test.go"],
+)
+`,
+		},
+		{Path: "lib/lib.go", Content: `package lib`},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	wantError := "encountered changes while running diff"
+	if err := runGazelle(dir, []string{"-mode=diff", "-patch=p", "-diff_summary=summary.json"}); err == nil || err.Error() != wantError {
+		t.Fatalf("got %v; want %q", err, wantError)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "summary.json",
+		Content: `{
+  "files_changed": 2,
+  "rules_added": 1,
+  "rules_removed": 1,
+  "rules_modified": 1,
+  "files": [
+    {
+      "path": "BUILD.bazel",
+      "status": "modified",
+      "rules_added": [
+        "//:hello"
+      ]
+    },
+    {
+      "path": "lib/BUILD.bazel",
+      "status": "modified",
+      "rules_removed": [
+        "//lib:lib_test"
+      ],
+      "rules_modified": [
+        "//lib"
+      ]
+    }
+  ]
+}
+`,
+	}})
+}
+
+func TestDiffContextAndRelativePaths(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/hello
+`,
+		},
+		{
+			Path: "sub/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "sub",
+    srcs = ["old.go"],
+    importpath = "example.com/hello/sub",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{Path: "sub/new.go", Content: `package sub`},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	// Paths are printed relative to the directory where "bazel run" was
+	// invoked.
+	t.Setenv("BUILD_WORKING_DIRECTORY", filepath.Join(dir, "sub"))
+	args := []string{"-mode=diff", "-patch=p", "-diff_context=0", "-diff_paths=relative", "."}
+	wantError := "encountered changes while running diff"
+	if err := runGazelle(dir, args); err == nil || err.Error() != wantError {
+		t.Fatalf("got %v; want %q", err, wantError)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "p",
+		Content: `--- BUILD.bazel	1970-01-01 00:00:00.000000001 +0000
++++ BUILD.bazel	1970-01-01 00:00:00.000000001 +0000
+@@ -5 +5 @@
+-    srcs = ["old.go"],
++    srcs = ["new.go"],
+`,
+	}})
+}
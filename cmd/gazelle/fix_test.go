@@ -16,6 +16,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -96,7 +97,7 @@ func TestCreateFile(t *testing.T) {
 	}
 
 	// Check that Gazelle creates a new file named "BUILD.bazel".
-	if err = run(dir, defaultArgs(dir)); err != nil {
+	if err = run(context.Background(), dir, defaultArgs(dir)); err != nil {
 		t.Fatalf("run failed: %v", err)
 	}
 
@@ -126,7 +127,7 @@ func TestUpdateFile(t *testing.T) {
 	}
 
 	// Check that Gazelle updates the BUILD file in place.
-	if err = run(dir, defaultArgs(dir)); err != nil {
+	if err = run(context.Background(), dir, defaultArgs(dir)); err != nil {
 		t.Fatalf("run failed: %v", err)
 	}
 
@@ -180,7 +181,7 @@ go_binary(
 	modTime := st.ModTime()
 
 	// Ensure that Gazelle does not write to the BUILD file.
-	if err = run(dir, defaultArgs(dir)); err != nil {
+	if err = run(context.Background(), dir, defaultArgs(dir)); err != nil {
 		t.Fatalf("run failed: %v", err)
 	}
 
@@ -338,7 +339,7 @@ go_library(
 				}
 				tc.args[i] = replacer.Replace(tc.args[i])
 			}
-			if err := run(dir, tc.args); err != nil {
+			if err := run(context.Background(), dir, tc.args); err != nil {
 				t.Error(err)
 			}
 			testtools.CheckFiles(t, dir, tc.want)
@@ -376,7 +377,7 @@ go_library(
 	defer cleanup()
 
 	// Check that Gazelle does not update the BUILD file, due to lang filter.
-	if err := run(dir, []string{
+	if err := run(context.Background(), dir, []string{
 		"-repo_root", dir,
 		"-go_prefix", "example.com/repo",
 		"-lang=proto",
@@ -622,7 +623,7 @@ go_library(
 			dir, cleanup := testtools.CreateFiles(t, tc.before)
 			defer cleanup()
 
-			if err := run(dir, []string{
+			if err := run(context.Background(), dir, []string{
 				"-repo_root", dir,
 				"-go_prefix", "example.com/repo",
 				dir,
@@ -634,3 +635,85 @@ go_library(
 		})
 	}
 }
+
+func TestFix_RenamedReferences(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `
+# gazelle:prefix example.com/repo
+# gazelle:go_naming_convention import
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "go_default_library",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{Path: "lib/lib.go", Content: "package lib"},
+		{
+			Path: "tools/BUILD.bazel",
+			Content: `
+sh_test(
+    name = "check",
+    srcs = ["check.sh"],
+    data = [
+        "//lib",
+        "//lib:go_default_library",
+    ],
+)
+
+sh_test(
+    name = "kept",
+    srcs = ["kept.sh"],
+    data = ["//lib:go_default_library"],  # keep
+)
+`,
+		},
+	})
+	defer cleanup()
+
+	if err := run(context.Background(), dir, []string{"fix", "-repo_root", dir}); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "tools/BUILD.bazel",
+			Content: `
+sh_test(
+    name = "check",
+    srcs = ["check.sh"],
+    data = ["//lib"],
+)
+
+sh_test(
+    name = "kept",
+    srcs = ["kept.sh"],
+    data = ["//lib:go_default_library"],  # keep
+)
+`,
+		},
+	})
+}
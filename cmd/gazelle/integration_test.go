@@ -20,13 +20,21 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
 	"github.com/bazelbuild/bazel-gazelle/internal/wspace"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
 	"github.com/bazelbuild/bazel-gazelle/testtools"
 	"github.com/google/go-cmp/cmp"
 )
@@ -42,7 +50,7 @@ func skipIfWorkspaceVisible(t *testing.T, dir string) {
 }
 
 func runGazelle(wd string, args []string) error {
-	return run(wd, args)
+	return run(context.Background(), wd, args)
 }
 
 // TestHelp checks that help commands do not panic due to nil flag values.
@@ -112,6 +120,70 @@ func TestErrorOutsideWorkspace(t *testing.T) {
 	}
 }
 
+func TestWorkspacelessRepository(t *testing.T) {
+	for _, marker := range []string{"MODULE.bazel", "REPO.bazel"} {
+		t.Run(marker, func(t *testing.T) {
+			files := []testtools.FileSpec{
+				{Path: marker},
+				{Path: "BUILD.bazel", Content: "# gazelle:prefix example.com/repo"},
+				{Path: "a/a.go", Content: "package a"},
+				{
+					Path: "go.mod",
+					Content: `
+module example.com/repo
+
+go 1.19
+
+require github.com/stretchr/testify v1.8.4
+`,
+				},
+			}
+			dir, cleanup := testtools.CreateFiles(t, files)
+			defer cleanup()
+			skipIfWorkspaceVisible(t, filepath.Dir(dir))
+
+			if err := runGazelle(filepath.Join(dir, "a"), []string{"-go_naming_convention=import"}); err != nil {
+				t.Fatal(err)
+			}
+			testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+				Path: "a/BUILD.bazel",
+				Content: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "a",
+    srcs = ["a.go"],
+    importpath = "example.com/repo/a",
+    visibility = ["//visibility:public"],
+)
+`,
+			}})
+
+			// Without a WORKSPACE file, update-repos only writes the macro.
+			if err := runGazelle(dir, []string{"update-repos", "-from_file=go.mod", "-to_macro=deps.bzl%go_deps"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "WORKSPACE")); !os.IsNotExist(err) {
+				t.Errorf("update-repos created a WORKSPACE file: %v", err)
+			}
+			testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+				Path: "deps.bzl",
+				Content: `
+load("@bazel_gazelle//:deps.bzl", "go_repository")
+
+def go_deps():
+    go_repository(
+        name = "com_github_stretchr_testify",
+        importpath = "github.com/stretchr/testify",
+        sum = "h1:CcVxjf3Q8PM0mHUKJCdn+eZZtm5yQwehR5yeSVQQcUk=",
+        version = "v1.8.4",
+    )
+`,
+			}})
+		})
+	}
+}
+
 func TestBuildFileNameIgnoresBuild(t *testing.T) {
 	files := []testtools.FileSpec{
 		{Path: "WORKSPACE"},
@@ -406,6 +478,91 @@ my_custom_macro(
 	}
 }
 
+func TestWrapperMacro(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "WORKSPACE",
+		},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/wrapper
+# gazelle:wrapper_macro my_go_rule go_library,go_binary
+`,
+		},
+		{
+			Path: "cmd/BUILD.bazel",
+			Content: `load("//custom:def.bzl", "my_go_rule")
+
+my_go_rule(
+    name = "cmd_lib",
+    srcs = ["old.go"],
+    extra_arg = "lib",
+    importpath = "example.com/wrapper/cmd",
+    visibility = ["//visibility:private"],
+)
+
+my_go_rule(
+    name = "cmd",
+    extra_arg = "bin",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "cmd/main.go",
+			Content: `package main
+
+import _ "example.com/wrapper/lib"
+
+func main() {}
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("//custom:def.bzl", "my_go_rule")
+
+my_go_rule(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/wrapper/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path:    "lib/lib.go",
+			Content: "package lib",
+		},
+	})
+	t.Cleanup(cleanup)
+	if err := runGazelle(dir, []string{"-external=vendored"}); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "cmd/BUILD.bazel",
+			Content: `load("//custom:def.bzl", "my_go_rule")
+
+my_go_rule(
+    name = "cmd_lib",
+    srcs = ["main.go"],
+    extra_arg = "lib",
+    importpath = "example.com/wrapper/cmd",
+    visibility = ["//visibility:private"],
+    deps = ["//lib"],
+)
+
+my_go_rule(
+    name = "cmd",
+    embed = [":cmd_lib"],
+    extra_arg = "bin",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
 func TestMapKindEdgeCases(t *testing.T) {
 	for name, tc := range map[string]struct {
 		before []testtools.FileSpec
@@ -851,6 +1008,96 @@ other_custom_go_library(
     importpath = "example.com/mapkind/dir",
     visibility = ["//visibility:public"],
 )
+`,
+				},
+			},
+		},
+		"map_kind renames and drops attributes": {
+			before: []testtools.FileSpec{
+				{
+					Path: "WORKSPACE",
+				},
+				{
+					Path: "BUILD.bazel",
+					Content: `# gazelle:prefix example.com/mapkind
+# gazelle:go_naming_convention go_default_library
+# gazelle:map_kind go_library my_go_library //custom:def.bzl deps=dependencies -importpath
+`,
+				},
+				{
+					Path: "dir/file.go",
+					Content: `package dir
+
+import _ "github.com/external"
+`,
+				},
+			},
+			after: []testtools.FileSpec{
+				{
+					Path: "dir/BUILD.bazel",
+					Content: `load("//custom:def.bzl", "my_go_library")
+
+my_go_library(
+    name = "go_default_library",
+    srcs = ["file.go"],
+    dependencies = ["//vendor/github.com/external:go_default_library"],
+    visibility = ["//visibility:public"],
+)
+`,
+				},
+			},
+		},
+		"map_kind merges renamed attributes of existing rules": {
+			before: []testtools.FileSpec{
+				{
+					Path: "WORKSPACE",
+				},
+				{
+					Path: "BUILD.bazel",
+					Content: `# gazelle:prefix example.com/mapkind
+# gazelle:go_naming_convention go_default_library
+# gazelle:map_kind go_library my_go_library //custom:def.bzl deps=dependencies -importpath
+`,
+				},
+				{
+					Path: "dir/BUILD.bazel",
+					Content: `load("//custom:def.bzl", "my_go_library")
+
+my_go_library(
+    name = "go_default_library",
+    srcs = ["old.go"],
+    dependencies = [
+        "//old:go_default_library",
+        "//pinned:go_default_library",  # keep
+    ],
+    extra = "kept",
+    visibility = ["//visibility:public"],
+)
+`,
+				},
+				{
+					Path: "dir/file.go",
+					Content: `package dir
+
+import _ "github.com/external"
+`,
+				},
+			},
+			after: []testtools.FileSpec{
+				{
+					Path: "dir/BUILD.bazel",
+					Content: `load("//custom:def.bzl", "my_go_library")
+
+my_go_library(
+    name = "go_default_library",
+    srcs = ["file.go"],
+    dependencies = [
+        "//pinned:go_default_library",  # keep
+        "//vendor/github.com/external:go_default_library",
+    ],
+    extra = "kept",
+    visibility = ["//visibility:public"],
+)
 `,
 				},
 			},
@@ -1749,7 +1996,7 @@ def my_go_deps():
 	}
 }
 
-func TestUpdateReposWithBzlmodWithoutToMacro(t *testing.T) {
+func TestUpdateReposToShardedMacro(t *testing.T) {
 	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
 		{Path: "WORKSPACE"},
 		{
@@ -1761,160 +2008,2987 @@ go 1.19
 
 require (
 	github.com/stretchr/testify v1.8.4
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
 )
 `,
 		},
 	})
-
 	t.Cleanup(cleanup)
 
 	args := []string{
 		"update-repos",
 		"-from_file=go.mod",
-		"-bzlmod",
+		"-to_macro=deps/%shard.bzl%go_deps",
 	}
 	if err := runGazelle(dir, args); err != nil {
 		t.Fatal(err)
 	}
 
-	// Confirm that the WORKSPACE is still empty
-	want := ""
-	if got, err := os.ReadFile(filepath.Join(dir, "WORKSPACE")); err != nil {
-		t.Fatal(err)
-	} else if string(got) != want {
-		t.Fatalf("got %s ; want %s; diff %s", string(got), want, cmp.Diff(string(got), want))
-	}
-}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "WORKSPACE",
+			Content: `
+load(
+    "//:deps/c.bzl",
+    go_deps_c = "go_deps",
+)
+load(
+    "//:deps/o.bzl",
+    go_deps_o = "go_deps",
+)
 
-func TestCgoFlagsHaveExternalPrefix(t *testing.T) {
-	files := []testtools.FileSpec{
+# gazelle:repository_macro deps/c.bzl%go_deps
+go_deps_c()
+
+# gazelle:repository_macro deps/o.bzl%go_deps
+go_deps_o()
+`,
+		},
 		{
-			Path:    "external/com_example_foo_v2/go.mod",
-			Content: "module example.com/foo/v2",
-		}, {
-			Path: "external/com_example_foo_v2/cgo_static.go",
+			Path: "deps/c.bzl",
 			Content: `
-package duckdb
+load("@bazel_gazelle//:deps.bzl", "go_repository")
 
-/*
-#cgo LDFLAGS: -lstdc++ -lm -ldl -L${SRCDIR}/deps
-*/
-import "C"
+def go_deps():
+    go_repository(
+        name = "com_github_stretchr_testify",
+        importpath = "github.com/stretchr/testify",
+        sum = "h1:CcVxjf3Q8PM0mHUKJCdn+eZZtm5yQwehR5yeSVQQcUk=",
+        version = "v1.8.4",
+    )
 `,
 		},
-	}
-	dir, cleanup := testtools.CreateFiles(t, files)
-	defer cleanup()
+		{
+			Path: "deps/o.bzl",
+			Content: `
+load("@bazel_gazelle//:deps.bzl", "go_repository")
 
-	repoRoot := filepath.Join(dir, "external", "com_example_foo_v2")
+def go_deps():
+    go_repository(
+        name = "org_golang_x_xerrors",
+        importpath = "golang.org/x/xerrors",
+        sum = "h1:go1bK/D/BFZV2I8cIQd1NKEZ+0owSTG1fDTci4IqFcE=",
+        version = "v0.0.0-20200804184101-5ec99f83aff1",
+    )
+`,
+		},
+	})
 
-	args := []string{"update", "-repo_root", repoRoot, "-go_prefix", "example.com/foo/v2", "-go_repository_mode", "-go_repository_module_mode"}
-	if err := runGazelle(repoRoot, args); err != nil {
+	// Rules are updated in the shards they're in on the next run. Shards
+	// are chosen by hash, so rules written there aren't moved.
+	args = []string{
+		"update-repos",
+		"-from_file=go.mod",
+		"-to_macro=deps/%shard.bzl%go_deps",
+		"-to_macro_shard=hash",
+		"-to_macro_shards=4",
+	}
+	if err := runGazelle(dir, args); err != nil {
 		t.Fatal(err)
 	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{Path: "deps/0.bzl", NotExist: true},
+		{Path: "deps/1.bzl", NotExist: true},
+		{Path: "deps/2.bzl", NotExist: true},
+		{Path: "deps/3.bzl", NotExist: true},
+	})
+}
 
-	testtools.CheckFiles(t, repoRoot, []testtools.FileSpec{
+func TestUpdateReposLockFile(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
 		{
-			Path: "BUILD.bazel",
+			Path: "go.mod",
 			Content: `
-load("@io_bazel_rules_go//go:def.bzl", "go_library")
+module example.com/foo/v2
 
-go_library(
-    name = "foo",
-    srcs = ["cgo_static.go"],
-    cgo = True,
-    clinkopts = ["-lstdc++ -lm -ldl -Lexternal/com_example_foo_v2/deps"],
-    importpath = "example.com/foo/v2",
-    importpath_aliases = ["example.com/foo"],
-    visibility = ["//visibility:public"],
+go 1.19
+
+require (
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
 )
 `,
 		},
-	})
-}
-
-func TestCgoFlagsHaveDotDotPrefixWithSiblingRepositoryLayout(t *testing.T) {
-	files := []testtools.FileSpec{
 		{
-			Path:    "execroot/com_example_foo_v2/go.mod",
-			Content: "module example.com/foo/v2",
-		}, {
-			Path: "execroot/com_example_foo_v2/cgo_static.go",
-			Content: `
-package duckdb
-
-/*
-#cgo LDFLAGS: -lstdc++ -lm -ldl -L${SRCDIR}/deps
-*/
-import "C"
+			// The locked sum is used instead of downloading the module.
+			Path: "gazelle_lock.json",
+			Content: `{
+  "version": 1,
+  "sums": {
+    "golang.org/x/xerrors@v0.0.0-20200804184101-5ec99f83aff1": "h1:locked="
+  }
+}
 `,
 		},
-	}
-	dir, cleanup := testtools.CreateFiles(t, files)
-	defer cleanup()
-
-	repoRoot := filepath.Join(dir, "execroot", "com_example_foo_v2")
+	})
+	t.Cleanup(cleanup)
 
-	args := []string{"update", "-repo_root", repoRoot, "-go_prefix", "example.com/foo/v2", "-go_repository_mode", "-go_repository_module_mode"}
-	if err := runGazelle(repoRoot, args); err != nil {
+	args := []string{
+		"update-repos",
+		"-from_file=go.mod",
+		"-to_macro=deps.bzl%go_deps",
+		"-lock_file=gazelle_lock.json",
+	}
+	if err := runGazelle(dir, args); err != nil {
 		t.Fatal(err)
 	}
 
-	testtools.CheckFiles(t, repoRoot, []testtools.FileSpec{
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
 		{
-			Path: "BUILD.bazel",
+			Path: "deps.bzl",
 			Content: `
-load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@bazel_gazelle//:deps.bzl", "go_repository")
+
+def go_deps():
+    go_repository(
+        name = "com_github_stretchr_testify",
+        importpath = "github.com/stretchr/testify",
+        sum = "h1:CcVxjf3Q8PM0mHUKJCdn+eZZtm5yQwehR5yeSVQQcUk=",
+        version = "v1.8.4",
+    )
+    go_repository(
+        name = "org_golang_x_xerrors",
+        importpath = "golang.org/x/xerrors",
+        sum = "h1:locked=",
+        version = "v0.0.0-20200804184101-5ec99f83aff1",
+    )
+`,
+		},
+		{
+			Path: "gazelle_lock.json",
+			Content: `{
+  "version": 1,
+  "sums": {
+    "github.com/stretchr/testify@v1.8.4": "h1:CcVxjf3Q8PM0mHUKJCdn+eZZtm5yQwehR5yeSVQQcUk=",
+    "golang.org/x/xerrors@v0.0.0-20200804184101-5ec99f83aff1": "h1:locked="
+  }
+}
+`,
+		},
+	})
+}
+
+func TestUpdateReposExcludeModule(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "WORKSPACE",
+			Content: `
+load("//:deps.bzl", "go_deps")
+
+# gazelle:repository_macro deps.bzl%go_deps
+go_deps()
+`,
+		},
+		{
+			Path: "deps.bzl",
+			Content: `
+load("@bazel_gazelle//:deps.bzl", "go_repository")
+
+def go_deps():
+    go_repository(
+        name = "com_github_pkg_errors",
+        importpath = "github.com/pkg/errors",
+        sum = "h1:FEBLx1zS214owpjy7qsBeixbURkuhQAwrK5UwLGTwt4=",
+        version = "v0.9.1",
+    )
+    go_repository(
+        name = "com_github_pkg_diff",
+        importpath = "github.com/pkg/diff",
+        sum = "h1:aoZm08cpOy4WuID//EZDgcC4zIxODThtZNPirFr42+A=",
+        version = "v0.0.0-20210226163009-20ebb0f2a09e",
+    )
+    go_repository(
+        name = "org_golang_x_xerrors",
+        importpath = "golang.org/x/xerrors",
+        sum = "h1:go4+OqTx3rAX7yIf3tOWNa4dzLmpHJlmMt2bcyTnaTg=",
+        version = "v0.0.0-20200804184101-5ec99f83aff1",
+    )
+`,
+		},
+		{
+			Path: "go.mod",
+			Content: `
+module example.com/foo
+
+go 1.19
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
+)
+`,
+		},
+		{
+			Path: "go.sum",
+			Content: `
+github.com/pkg/errors v0.9.1 h1:FEBLx1zS214owpjy7qsBeixbURkuhQAwrK5UwLGTwt4=
+golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 h1:go4+OqTx3rAX7yIf3tOWNa4dzLmpHJlmMt2bcyTnaTg=
+`,
+		},
+	})
+	t.Cleanup(cleanup)
+
+	args := []string{
+		"update-repos",
+		"-from_file=go.mod",
+		"-to_macro=deps.bzl%go_deps",
+		"-exclude_module=github.com/pkg/**",
+	}
+	if err := runGazelle(dir, args); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "deps.bzl",
+			Content: `
+load("@bazel_gazelle//:deps.bzl", "go_repository")
+
+def go_deps():
+    go_repository(
+        name = "org_golang_x_xerrors",
+        importpath = "golang.org/x/xerrors",
+        sum = "h1:go4+OqTx3rAX7yIf3tOWNa4dzLmpHJlmMt2bcyTnaTg=",
+        version = "v0.0.0-20200804184101-5ec99f83aff1",
+    )
+`,
+		},
+	})
+}
+
+func TestUpdateReposUpdateRules(t *testing.T) {
+	registryDir, registryCleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path:    "modules/rules_go/metadata.json",
+			Content: `{"versions": ["0.49.0", "0.50.1", "0.51.0-rc1", "0.50.2"], "yanked_versions": {"0.50.2": "broken"}}`,
+		},
+		{
+			Path:    "modules/rules_go/0.50.1/source.json",
+			Content: `{"url": "https://github.com/bazel-contrib/rules_go/releases/download/v0.50.1/rules_go-v0.50.1.zip", "integrity": "sha256-9C1GFtLNM6rRK8ER+35DD19L0/ZCanMWGZHhK5o4YwI="}`,
+		},
+		{
+			Path:    "modules/gazelle/0.40.0/source.json",
+			Content: `{"url": "https://github.com/bazel-contrib/bazel-gazelle/releases/download/v0.40.0/bazel-gazelle-v0.40.0.tar.gz", "integrity": "sha256-qxGLg7fyCs3FAvLmpqq6p0AiSdrUpVyPJ+PUZLUSZm8="}`,
+		},
+	})
+	t.Cleanup(registryCleanup)
+	registry := httptest.NewServer(http.FileServer(http.Dir(registryDir)))
+	t.Cleanup(registry.Close)
+
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `module(name = "example")
+
+bazel_dep(name = "rules_go", version = "0.49.0", repo_name = "io_bazel_rules_go")
+bazel_dep(name = "gazelle", version = "0.38.0")
+`,
+		},
+		{
+			Path: "WORKSPACE",
+			Content: `
+load("@bazel_tools//tools/build_defs/repo:http.bzl", "http_archive")
+
+http_archive(
+    name = "io_bazel_rules_go",
+    sha256 = "2697f6bc7c529ee5e6a2d9799870b9ec9eaeb3ee7d70ed50b87a2c2c97e13d9e",
+    urls = [
+        "https://mirror.bazel.build/github.com/bazelbuild/rules_go/releases/download/v0.23.8/rules_go-v0.23.8.tar.gz",
+        "https://github.com/bazelbuild/rules_go/releases/download/v0.23.8/rules_go-v0.23.8.tar.gz",
+    ],
+)
+
+http_archive(
+    name = "bazel_gazelle",
+    sha256 = "cdb02a887a7187ea4d5a27452311a75ed8637379a1287d8eeb952138ea485f7d",
+    urls = [
+        "https://mirror.bazel.build/github.com/bazelbuild/bazel-gazelle/releases/download/v0.21.1/bazel-gazelle-v0.21.1.tar.gz",
+        "https://github.com/bazelbuild/bazel-gazelle/releases/download/v0.21.1/bazel-gazelle-v0.21.1.tar.gz",
+    ],
+)
+`,
+		},
+	})
+	t.Cleanup(cleanup)
+
+	args := []string{
+		"update-repos",
+		"-update_rules=rules_go,gazelle@v0.40.0",
+		"-update_rules_registry=" + registry.URL,
+	}
+	if err := runGazelle(dir, args); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `module(name = "example")
+
+bazel_dep(name = "rules_go", version = "0.50.1", repo_name = "io_bazel_rules_go")
+bazel_dep(name = "gazelle", version = "0.40.0")
+`,
+		},
+		{
+			Path: "WORKSPACE",
+			Content: `
+load("@bazel_tools//tools/build_defs/repo:http.bzl", "http_archive")
+
+http_archive(
+    name = "io_bazel_rules_go",
+    sha256 = "f42d4616d2cd33aad12bc111fb7e430f5f4bd3f6426a73161991e12b9a386302",
+    urls = [
+        "https://mirror.bazel.build/github.com/bazel-contrib/rules_go/releases/download/v0.50.1/rules_go-v0.50.1.zip",
+        "https://github.com/bazel-contrib/rules_go/releases/download/v0.50.1/rules_go-v0.50.1.zip",
+    ],
+)
+
+http_archive(
+    name = "bazel_gazelle",
+    sha256 = "ab118b83b7f20acdc502f2e6a6aabaa7402249dad4a55c8f27e3d464b512666f",
+    urls = [
+        "https://mirror.bazel.build/github.com/bazel-contrib/bazel-gazelle/releases/download/v0.40.0/bazel-gazelle-v0.40.0.tar.gz",
+        "https://github.com/bazel-contrib/bazel-gazelle/releases/download/v0.40.0/bazel-gazelle-v0.40.0.tar.gz",
+    ],
+)
+`,
+		},
+	})
+}
+
+func TestModTidy(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `module(name = "example")
+
+bazel_dep(name = "gazelle", version = "0.40.0")
+
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+go_deps.module(
+    path = "example.com/extra",
+    sum = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+    version = "v1.0.0",
+)
+go_deps.module(
+    indirect = True,
+    path = "example.com/indirect",
+    sum = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+    version = "v1.0.0",
+)
+go_deps.module_override(
+    path = "github.com/stretchr/testify",
+    repo_name = "testify",
+)
+use_repo(
+    go_deps,
+    "com_github_kept",  # keep
+    "com_github_removed",
+    "org_golang_x_xerrors",
+)
+`,
+		},
+		{
+			Path: "go.mod",
+			Content: `module example.com/foo
+
+go 1.24
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/tools v0.30.0 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+)
+
+tool golang.org/x/tools/cmd/stringer
+`,
+		},
+	})
+	t.Cleanup(cleanup)
+
+	if err := runGazelle(dir, []string{"mod", "tidy", "-mode=diff"}); !errors.Is(err, update.ErrDiff) {
+		t.Fatalf("got %v; want %v", err, update.ErrDiff)
+	}
+	if err := runGazelle(dir, []string{"mod", "tidy"}); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "MODULE.bazel",
+		Content: `module(name = "example")
+
+bazel_dep(name = "gazelle", version = "0.40.0")
+
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+go_deps.module(
+    path = "example.com/extra",
+    sum = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+    version = "v1.0.0",
+)
+go_deps.module(
+    indirect = True,
+    path = "example.com/indirect",
+    sum = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+    version = "v1.0.0",
+)
+go_deps.module_override(
+    path = "github.com/stretchr/testify",
+    repo_name = "testify",
+)
+use_repo(
+    go_deps,
+    "com_example_extra",
+    "com_github_kept",  # keep
+    "com_github_pkg_errors",
+    "org_golang_x_tools",
+    "testify",
+)
+`,
+	}})
+	if err := runGazelle(dir, []string{"mod", "tidy", "-mode=diff"}); err != nil {
+		t.Fatalf("after tidy: got %v; want no diff", err)
+	}
+}
+
+func TestModOverride(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `module(name = "example")
+
+bazel_dep(name = "gazelle", version = "0.40.0")
+
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+use_repo(go_deps, "com_github_foo_bar")
+`,
+		},
+	})
+	t.Cleanup(cleanup)
+
+	for _, args := range [][]string{
+		{"-directive=gazelle:proto disable", "-build_file_generation=on", "gazelle", "github.com/foo/bar"},
+		{"-directive=gazelle:go_naming_convention import", "-directive=gazelle:proto disable", "gazelle", "github.com/foo/bar"},
+		{"-patch=//patches:bar.patch", "-patch_strip=1", "module", "github.com/foo/bar"},
+		{"-url=https://example.com/baz.zip", "-strip_prefix=baz-1.0", "archive", "github.com/foo/baz"},
+		{"-remove", "-directive=gazelle:proto disable", "gazelle", "github.com/foo/bar"},
+		{"-remove", "archive", "github.com/foo/baz"},
+	} {
+		if err := runGazelle(dir, append([]string{"mod", "override"}, args...)); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "MODULE.bazel",
+		Content: `module(name = "example")
+
+bazel_dep(name = "gazelle", version = "0.40.0")
+
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+use_repo(go_deps, "com_github_foo_bar")
+
+go_deps.gazelle_override(
+    build_file_generation = "on",
+    directives = ["gazelle:go_naming_convention import"],
+    path = "github.com/foo/bar",
+)
+go_deps.module_override(
+    patch_strip = 1,
+    patches = ["//patches:bar.patch"],
+    path = "github.com/foo/bar",
+)
+`,
+	}})
+
+	wantErr := "mod override: urls can't be set on module_override"
+	if err := runGazelle(dir, []string{"mod", "override", "-url=https://example.com", "module", "github.com/foo/bar"}); err == nil || err.Error() != wantErr {
+		t.Errorf("got %v; want %q", err, wantErr)
+	}
+}
+
+func TestModWhy(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `module(name = "example")
+
+bazel_dep(name = "gazelle", version = "0.40.0")
+
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+go_deps.module_override(
+    path = "github.com/stretchr/testify",
+    repo_name = "testify",
+)
+use_repo(
+    go_deps,
+    "com_github_pkg_errors",
+    assert = "testify",
+)
+`,
+		},
+		{
+			Path: "go.mod",
+			Content: `module example.com/foo
+
+go 1.24
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.8.4
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `go_library(
+    name = "lib",
+    deps = ["@com_github_pkg_errors//:errors"],
+)
+
+go_test(
+    name = "lib_test",
+    deps = [
+        ":lib",
+        "@assert//assert",
+    ],
+)
+`,
+		},
+	})
+	t.Cleanup(cleanup)
+
+	prevGoModGraph := goModGraph
+	goModGraph = func(string) ([]byte, error) {
+		return []byte(`example.com/foo github.com/pkg/errors@v0.9.1
+example.com/foo github.com/pmezard/go-difflib@v1.0.0
+example.com/foo github.com/stretchr/testify@v1.8.4
+github.com/stretchr/testify@v1.8.4 github.com/pmezard/go-difflib@v1.0.0
+`), nil
+	}
+	t.Cleanup(func() { goModGraph = prevGoModGraph })
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevStdout := os.Stdout
+	os.Stdout = w
+	runErr := runGazelle(dir, []string{"mod", "why", "github.com/pmezard/go-difflib", "@assert", "example.com/unused"})
+	os.Stdout = prevStdout
+	w.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	want := `# github.com/pmezard/go-difflib (@com_github_pmezard_go_difflib)
+required through:
+	example.com/foo
+	github.com/pmezard/go-difflib@v1.0.0
+(no targets depend on this repository)
+
+# github.com/stretchr/testify (@testify)
+required through:
+	example.com/foo
+	github.com/stretchr/testify@v1.8.4
+depended on by:
+	//lib:lib_test
+
+# example.com/unused (@com_example_unused)
+(main module does not need module example.com/unused)
+(no targets depend on this repository)
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdateReposWithBzlmodWithoutToMacro(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "go.mod",
+			Content: `
+module example.com/foo/v2
+
+go 1.19
+
+require (
+	github.com/stretchr/testify v1.8.4
+)
+`,
+		},
+	})
+
+	t.Cleanup(cleanup)
+
+	args := []string{
+		"update-repos",
+		"-from_file=go.mod",
+		"-bzlmod",
+	}
+	if err := runGazelle(dir, args); err != nil {
+		t.Fatal(err)
+	}
+
+	// Confirm that the WORKSPACE is still empty
+	want := ""
+	if got, err := os.ReadFile(filepath.Join(dir, "WORKSPACE")); err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Fatalf("got %s ; want %s; diff %s", string(got), want, cmp.Diff(string(got), want))
+	}
+}
+
+// npmLang is a minimal language that declares npm packages as tags of a
+// module extension. It's used to test update-repos with languages other
+// than Go in bzlmod mode.
+type npmLang struct {
+	language.BaseLang
+}
+
+func (*npmLang) Name() string { return "npm" }
+
+func (*npmLang) Kinds() map[string]rule.KindInfo {
+	return map[string]rule.KindInfo{"npm_package": {}}
+}
+
+func (*npmLang) UpdateRepos(args language.UpdateReposArgs) language.UpdateReposResult {
+	var res language.UpdateReposResult
+	for _, imp := range args.Imports {
+		name, version, _ := strings.Cut(imp, "@")
+		r := rule.NewRule("npm_package", "npm_"+strings.ReplaceAll(name, "-", "_"))
+		r.SetAttr("package", name)
+		r.SetAttr("version", version)
+		res.Gen = append(res.Gen, r)
+	}
+	return res
+}
+
+func (*npmLang) UpdateModule(args language.UpdateModuleArgs) language.UpdateModuleResult {
+	u := language.ExtensionUpdate{
+		BzlFile: "@rules_npm//:extensions.bzl",
+		Name:    "npm",
+		TagKey:  "package",
+	}
+	for _, r := range args.Gen {
+		tag := rule.NewRule("package", "")
+		tag.SetAttr("package", r.AttrString("package"))
+		tag.SetAttr("version", r.AttrString("version"))
+		u.Tags = append(u.Tags, tag)
+		u.UseRepos = append(u.UseRepos, r.Name())
+	}
+	return language.UpdateModuleResult{Extensions: []language.ExtensionUpdate{u}}
+}
+
+func TestUpdateReposWithBzlmodModuleUpdater(t *testing.T) {
+	oldLanguages := languages
+	languages = []language.Language{&npmLang{}}
+	t.Cleanup(func() { languages = oldLanguages })
+
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `
+module(name = "example")
+
+bazel_dep(name = "rules_npm", version = "1.0.0")
+
+npm = use_extension("@rules_npm//:extensions.bzl", "npm")
+npm.package(
+    package = "left-pad",
+    version = "1.2.0",
+)
+npm.package(
+    package = "is-odd",
+    version = "2.0.0",
+)  # keep
+use_repo(npm, "npm_left_pad")
+`,
+		},
+	})
+	t.Cleanup(cleanup)
+
+	args := []string{"update-repos", "-bzlmod", "left-pad@1.3.0", "is-odd@3.0.0", "is-even@1.0.0"}
+	if err := runGazelle(dir, args); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `
+module(name = "example")
+
+bazel_dep(name = "rules_npm", version = "1.0.0")
+
+npm = use_extension("@rules_npm//:extensions.bzl", "npm")
+npm.package(
+    package = "left-pad",
+    version = "1.3.0",
+)
+npm.package(
+    package = "is-odd",
+    version = "2.0.0",
+)  # keep
+use_repo(npm, "npm_is_even", "npm_is_odd", "npm_left_pad")
+
+npm.package(
+    package = "is-even",
+    version = "1.0.0",
+)
+`,
+		},
+	})
+}
+
+func TestCgoFlagsHaveExternalPrefix(t *testing.T) {
+	files := []testtools.FileSpec{
+		{
+			Path:    "external/com_example_foo_v2/go.mod",
+			Content: "module example.com/foo/v2",
+		}, {
+			Path: "external/com_example_foo_v2/cgo_static.go",
+			Content: `
+package duckdb
+
+/*
+#cgo LDFLAGS: -lstdc++ -lm -ldl -L${SRCDIR}/deps
+*/
+import "C"
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	repoRoot := filepath.Join(dir, "external", "com_example_foo_v2")
+
+	args := []string{"update", "-repo_root", repoRoot, "-go_prefix", "example.com/foo/v2", "-go_repository_mode", "-go_repository_module_mode"}
+	if err := runGazelle(repoRoot, args); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, repoRoot, []testtools.FileSpec{
+		{
+			Path: "BUILD.bazel",
+			Content: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "foo",
+    srcs = ["cgo_static.go"],
+    cgo = True,
+    clinkopts = ["-lstdc++ -lm -ldl -Lexternal/com_example_foo_v2/deps"],
+    importpath = "example.com/foo/v2",
+    importpath_aliases = ["example.com/foo"],
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
+func TestCgoFlagsHaveDotDotPrefixWithSiblingRepositoryLayout(t *testing.T) {
+	files := []testtools.FileSpec{
+		{
+			Path:    "execroot/com_example_foo_v2/go.mod",
+			Content: "module example.com/foo/v2",
+		}, {
+			Path: "execroot/com_example_foo_v2/cgo_static.go",
+			Content: `
+package duckdb
+
+/*
+#cgo LDFLAGS: -lstdc++ -lm -ldl -L${SRCDIR}/deps
+*/
+import "C"
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	repoRoot := filepath.Join(dir, "execroot", "com_example_foo_v2")
+
+	args := []string{"update", "-repo_root", repoRoot, "-go_prefix", "example.com/foo/v2", "-go_repository_mode", "-go_repository_module_mode"}
+	if err := runGazelle(repoRoot, args); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, repoRoot, []testtools.FileSpec{
+		{
+			Path: "BUILD.bazel",
+			Content: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "foo",
+    srcs = ["cgo_static.go"],
+    cgo = True,
+    clinkopts = ["-lstdc++ -lm -ldl -L../com_example_foo_v2/deps"],
+    importpath = "example.com/foo/v2",
+    importpath_aliases = ["example.com/foo"],
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
+// TestEmptyTestdataNoData checks that an empty testdata subdirectory does not
+// result in a data attribute being added to go_test rules.
+func TestEmptyTestdataNoData(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "example_test.go",
+			Content: `
+package example
+
+import "testing"
+
+func TestExample(t *testing.T) {}
+`,
+		},
+		{Path: "testdata/"},
+	})
+	defer cleanup()
+
+	if err := runGazelle(dir, []string{"-go_prefix", "example.com/foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	buildContent, err := os.ReadFile(filepath.Join(dir, "BUILD.bazel"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify that the BUILD file doesn't contain a data attribute
+	if strings.Contains(string(buildContent), "data = ") {
+		t.Errorf("BUILD file should not contain data attribute for empty testdata\n%s", string(buildContent))
+	}
+
+	// Verify that a go_test rule was generated
+	if !strings.Contains(string(buildContent), "go_test(") {
+		t.Errorf("BUILD file should contain a go_test rule\n%s", string(buildContent))
+	}
+}
+
+// TestDelete checks that "gazelle delete" removes build files left empty in
+// directories without sources, along with references to their rules.
+func TestDelete(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+`,
+		},
+		{
+			Path: "gone/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "gone",
+    srcs = ["gone.go"],
+    importpath = "example.com/repo/gone",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "commented/BUILD.bazel",
+			Content: `# Keep this package.
+
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "commented",
+    srcs = ["commented.go"],
+    importpath = "example.com/repo/commented",
+)
+`,
+		},
+		{
+			Path: "app/BUILD.bazel",
+			Content: `sh_binary(
+    name = "tool",
+    srcs = ["tool.sh"],
+    deps = [
+        "//gone",
+        "//other",
+    ],
+)
+
+sh_library(
+    name = "lib",
+    deps = ["//gone:gone"],
+)
+
+sh_library(
+    name = "kept",
+    deps = ["//gone"],  # keep
+)
+`,
+		},
+	})
+	defer cleanup()
+
+	if err := runGazelle(dir, []string{"delete"}); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{Path: "gone/BUILD.bazel", NotExist: true},
+		{Path: "commented/BUILD.bazel", Content: "# Keep this package.\n"},
+		{
+			Path: "app/BUILD.bazel",
+			Content: `sh_binary(
+    name = "tool",
+    srcs = ["tool.sh"],
+    deps = ["//other"],
+)
+
+sh_library(name = "lib")
+
+sh_library(
+    name = "kept",
+    deps = ["//gone"],  # keep
+)
+`,
+		},
+	})
+}
+
+func TestFormat(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:map_kind go_library my_library //tools:my.bzl
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+go_library(name="lib", srcs=["lib.go"],
+  importpath="example.com/repo/lib")
+`,
+		},
+		{Path: "lib/lib.go", Content: "package lib\n"},
+		{Path: "lib/new.go", Content: "package lib\n"},
+		{
+			Path:    "mapped/sub/BUILD.bazel",
+			Content: `my_library(name="sub")` + "\n",
+		},
+		{
+			Path:    "skipped/BUILD.bazel",
+			Content: `go_library(name="skipped")` + "\n",
+		},
+	})
+	defer cleanup()
+
+	if err := runGazelle(dir, []string{"fmt", "lib/BUILD.bazel", "lib/new.go", "mapped"}); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+)
+`,
+		},
+		{
+			Path: "mapped/sub/BUILD.bazel",
+			Content: `load("//tools:my.bzl", "my_library")
+
+my_library(name = "sub")
+`,
+		},
+		{
+			Path:    "skipped/BUILD.bazel",
+			Content: `go_library(name="skipped")` + "\n",
+		},
+	})
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{Path: "lib/lib.go", Content: "package lib\n"},
+		{Path: "lib/new.go", Content: "package lib\n"},
+		{
+			Path: "app/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "app",
+    srcs = ["app.go"],
+    importpath = "example.com/repo/app",
+    visibility = ["//visibility:public"],
+    deps = ["//lib"],
+)
+`,
+		},
+		{Path: "app/app.go", Content: "package app\n\nimport _ \"example.com/repo/lib\"\nimport _ \"example.com/repo/util\"\n"},
+		{
+			Path: "util/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "util",
+    importpath = "example.com/repo/util",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{Path: "util/util.go", Content: "package util\n"},
+		{Path: "changed.txt", Content: "lib/new.go\n"},
+	})
+	defer cleanup()
+
+	if err := runGazelle(dir, []string{"-changed_files=changed.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = [
+        "lib.go",
+        "new.go",
+    ],
+    importpath = "example.com/repo/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "app/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "app",
+    srcs = ["app.go"],
+    importpath = "example.com/repo/app",
+    visibility = ["//visibility:public"],
+    deps = [
+        "//lib",
+        "//util",
+    ],
+)
+`,
+		},
+		{
+			Path: "util/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "util",
+    importpath = "example.com/repo/util",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
+func TestStateFile(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+`,
+		},
+		{Path: "app/app.go", Content: "package app\n\nimport _ \"example.com/other/util\"\n"},
+		{Path: "lib/lib.go", Content: "package lib\n"},
+	})
+	defer cleanup()
+
+	args := []string{"-state_file=gazelle-state.json"}
+	if err := runGazelle(dir, args); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gazelle-state.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a library with the import path app depends on. The app directory is
+	// unchanged, but its dependencies must be resolved again because the index
+	// changed.
+	if err := os.Mkdir(filepath.Join(dir, "util"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "util", "BUILD.bazel"), []byte("# gazelle:prefix example.com/other/util\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "util", "util.go"), []byte("package util\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGazelle(dir, args); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []testtools.FileSpec{
+		{
+			Path: "app/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "app",
+    srcs = ["app.go"],
+    importpath = "example.com/repo/app",
+    visibility = ["//visibility:public"],
+    deps = ["//util"],
+)
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	}
+	testtools.CheckFiles(t, dir, want)
+
+	// Run again with nothing changed. All directories are skipped, and build
+	// files stay the same.
+	if err := runGazelle(dir, args); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, want)
+}
+
+func TestStream(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+`,
+		},
+		{Path: "app/app.go", Content: "package app\n\nimport _ \"example.com/repo/lib\"\n"},
+		{Path: "lib/lib.go", Content: "package lib\n"},
+	})
+	defer cleanup()
+
+	if err := runGazelle(dir, []string{"-stream"}); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "app/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "app",
+    srcs = ["app.go"],
+    importpath = "example.com/repo/app",
+    visibility = ["//visibility:public"],
+    deps = ["//lib"],
+)
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+
+	if err := runGazelle(dir, []string{"-stream", "-delete_empty_build_files"}); err == nil {
+		t.Error("-stream with -delete_empty_build_files: got success; want error")
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+`,
+		},
+		{Path: "lib/lib.go", Content: "package lib\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := run(ctx, dir, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v; want %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lib", "BUILD.bazel")); !os.IsNotExist(err) {
+		t.Errorf("build file was written after interruption: %v", err)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+`,
+		},
+		{Path: "app/app.go", Content: "package app\n\nimport _ \"../../outside\"\n"},
+	})
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want int
+	}{
+		{desc: "diff", args: []string{"-mode=diff"}, want: update.ExitDiff},
+		{desc: "config", args: []string{"-mode=bogus"}, want: update.ExitConfig},
+		{desc: "unresolved", args: []string{"-strict"}, want: update.ExitUnresolved},
+		{desc: "ok", args: nil, want: update.ExitOK},
+		{desc: "update_repos_flag", args: []string{"update-repos", "-bogus"}, want: update.ExitConfig},
+		{desc: "update_repos_args", args: []string{"update-repos"}, want: update.ExitConfig},
+		{desc: "mod_tidy_flag", args: []string{"mod", "tidy", "-bogus"}, want: update.ExitConfig},
+		{desc: "mod_unknown", args: []string{"mod", "bogus"}, want: update.ExitConfig},
+		{desc: "resolve_flag", args: []string{"resolve", "-bogus", "go", "example.com/repo/app"}, want: update.ExitConfig},
+		{desc: "resolve_args", args: []string{"resolve", "go"}, want: update.ExitConfig},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := update.ExitCode(runGazelle(dir, tc.args)); got != tc.want {
+				t.Errorf("got exit code %d; want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerationLog(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/hello
+# gazelle:go_naming_convention import
+`,
+		},
+		{Path: "hello.go", Content: `package hello
+
+import _ "example.com/dup"
+`},
+		{
+			Path: "a/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+# keep
+go_library(
+    name = "a",
+    importpath = "example.com/dup",
+)
+`,
+		},
+		{
+			Path: "b/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+# keep
+go_library(
+    name = "b",
+    importpath = "example.com/dup",
+)
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, []string{"-generation_log=log.json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "log.json",
+		Content: `{
+  "packages": [
+    {
+      "pkg": "a"
+    },
+    {
+      "pkg": "b"
+    },
+    {
+      "pkg": "",
+      "directives": [
+        "gazelle:prefix example.com/hello",
+        "gazelle:go_naming_convention import"
+      ],
+      "rules": [
+        {
+          "kind": "go_library",
+          "name": "hello"
+        }
+      ]
+    }
+  ],
+  "unresolved": [
+    "rule //:hello imports \"example.com/dup\" which matches multiple rules: //a and //b. # gazelle:resolve may be used to disambiguate"
+  ]
+}
+`,
+	}})
+}
+
+func TestResolveReplacementImportPath(t *testing.T) {
+	files := []testtools.FileSpec{
+		{
+			Path: "WORKSPACE",
+			Content: `
+go_repository(
+    name = "com_example_orig",
+    importpath = "example.com/orig",
+    replace = "example.com/fork",
+    sum = "h1:abc=",
+    version = "v1.0.0",
+)
+`,
+		},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/hello\n",
+		},
+		{
+			Path: "hello.go",
+			Content: `package hello
+
+import (
+	_ "example.com/fork/sub"
+	_ "example.com/orig/sub"
+)
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, []string{"-go_naming_convention_external=import"}); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "BUILD.bazel",
+		Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+# gazelle:prefix example.com/hello
+
+go_library(
+    name = "hello",
+    srcs = ["hello.go"],
+    importpath = "example.com/hello",
+    visibility = ["//visibility:public"],
+    deps = ["@com_example_orig//sub"],
+)
+`,
+	}})
+}
+
+func TestThirdPartyPreset(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo\n",
+		},
+		{
+			Path:    "third_party/BUILD.bazel",
+			Content: "# gazelle:third_party vendored\n",
+		},
+		{Path: "third_party/foo/internal/bar/bar.go", Content: "package bar\n"},
+		{Path: "third_party/foo/internal/bar/bar_test.go", Content: "package bar\n"},
+		{Path: "internal/baz/baz.go", Content: "package baz\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "third_party/foo/internal/bar/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "bar",
+    srcs = ["bar.go"],
+    importpath = "example.com/repo/third_party/foo/internal/bar",
+    tags = ["vendored"],
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "internal/baz/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "baz",
+    srcs = ["baz.go"],
+    importpath = "example.com/repo/internal/baz",
+    visibility = ["//:__subpackages__"],
+)
+`,
+		},
+	})
+}
+
+func TestFixDryRun(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo\n",
+		},
+		{
+			Path: "a/BUILD.bazel",
+			Content: `# gazelle:go_naming_convention go_default_library
+
+go_library(
+    name = "go_default_library",
+    srcs = ["a.go"],
+    importpath = "example.com/repo/a",
+)
+
+go_test(
+    name = "go_default_test",
+    srcs = ["a_test.go"],
+    embed = [":go_default_library"],
+)
+
+go_test(
+    name = "go_default_xtest",
+    srcs = ["a_x_test.go"],
+)
+`,
+		},
+		{Path: "a/a.go", Content: "package a\n"},
+		{Path: "a/a_test.go", Content: "package a\n"},
+		{Path: "a/a_x_test.go", Content: "package a_test\n"},
+		{
+			Path: "b/BUILD.bazel",
+			Content: `go_library(
+    name = "b",
+    srcs = ["b.go"],
+    importpath = "example.com/repo/b",
+)
+
+filegroup(
+    name = "data",
+    srcs = ["data.txt"],
+)
+`,
+		},
+		{Path: "b/data.txt"},
+		{
+			Path: "c/BUILD.bazel",
+			Content: `go_library(
+    name = "c",
+    srcs = ["c.go"],
+    importpath = "example.com/repo/c",
+)
+`,
+		},
+		{Path: "c/README"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevStdout := os.Stdout
+	os.Stdout = w
+	runErr := runGazelle(dir, []string{"fix", "-dry_run", "-delete_empty_build_files"})
+	os.Stdout = prevStdout
+	w.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	want := `{
+  "changes": [
+    {
+      "action": "rename",
+      "path": "a/BUILD.bazel",
+      "kind": "go_test",
+      "rule": "//a:go_default_xtest",
+      "to": "//a:go_default_test",
+      "reason": "merged into //a:go_default_test by the go extension's fix"
+    },
+    {
+      "action": "delete_rule",
+      "path": "b/BUILD.bazel",
+      "kind": "go_library",
+      "rule": "//b",
+      "reason": "its sources no longer exist"
+    },
+    {
+      "action": "delete_file",
+      "path": "c/BUILD.bazel",
+      "reason": "no rules remain after rules for missing sources were deleted (-delete_empty_build_files)"
+    }
+  ]
+}
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("dry run report (-want +got):\n%s", diff)
+	}
+
+	// Files are left as they were.
+	testtools.CheckFiles(t, dir, files)
+}
+
+func TestGoTestRunDirectives(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:go_test_args -test.v
+# gazelle:go_test_env MODE=integration "GREETING=hello world"
+# gazelle:go_test_env_inherit HOME
+`,
+		},
+		{Path: "a/a_test.go", Content: "package a\n"},
+		{
+			Path: "b/BUILD.bazel",
+			Content: `# gazelle:go_test_args -update
+# gazelle:go_test_env MODE=unit
+
+load("@io_bazel_rules_go//go:def.bzl", "go_test")
+
+go_test(
+    name = "b_test",
+    srcs = ["b_test.go"],
+    args = ["-manual"],
+    env = {"MANUAL": "1"},
+)
+`,
+		},
+		{Path: "b/b_test.go", Content: "package b\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	want := []testtools.FileSpec{
+		{
+			Path: "a/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_test")
+
+go_test(
+    name = "a_test",
+    srcs = ["a_test.go"],
+    args = ["-test.v"],
+    env = {
+        "GREETING": "hello world",
+        "MODE": "integration",
+    },
+    env_inherit = ["HOME"],
+)
+`,
+		},
+		{
+			Path: "b/BUILD.bazel",
+			Content: `# gazelle:go_test_args -update
+# gazelle:go_test_env MODE=unit
+
+load("@io_bazel_rules_go//go:def.bzl", "go_test")
+
+go_test(
+    name = "b_test",
+    srcs = ["b_test.go"],
+    args = [
+        "-manual",
+        "-test.v",
+        "-update",
+    ],
+    env = {
+        "MANUAL": "1",
+        "GREETING": "hello world",
+        "MODE": "unit",
+    },
+    env_inherit = ["HOME"],
+)
+`,
+		},
+	}
+	// Values are merged the same way when Gazelle runs again.
+	for i := 0; i < 2; i++ {
+		if err := runGazelle(dir, nil); err != nil {
+			t.Fatal(err)
+		}
+		testtools.CheckFiles(t, dir, want)
+	}
+}
+
+func TestGoBinaryLibraryName(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo\n# gazelle:go_binary_library_name lib\n",
+		},
+		{Path: "cmd/foo/main.go", Content: "package main\n\nfunc main() {}\n"},
+		{Path: "cmd/foo/main_test.go", Content: "package main\n"},
+		{
+			Path:    "tools/BUILD.bazel",
+			Content: "# gazelle:go_binary_library_name {binary}_library\n",
+		},
+		{Path: "tools/bar/main.go", Content: "package main\n\nfunc main() {}\n"},
+		{Path: "lib/baz/baz.go", Content: "package baz\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "cmd/foo/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_binary", "go_library", "go_test")
+
+go_library(
+    name = "lib",
+    srcs = ["main.go"],
+    importpath = "example.com/repo/cmd/foo",
+    visibility = ["//visibility:private"],
+)
+
+go_binary(
+    name = "foo",
+    embed = [":lib"],
+    visibility = ["//visibility:public"],
+)
+
+go_test(
+    name = "foo_test",
+    srcs = ["main_test.go"],
+    embed = [":lib"],
+)
+`,
+		},
+		{
+			Path: "tools/bar/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_binary", "go_library")
+
+go_library(
+    name = "bar_library",
+    srcs = ["main.go"],
+    importpath = "example.com/repo/tools/bar",
+    visibility = ["//visibility:private"],
+)
+
+go_binary(
+    name = "bar",
+    embed = [":bar_library"],
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "lib/baz/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "baz",
+    srcs = ["baz.go"],
+    importpath = "example.com/repo/lib/baz",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
+func TestImportMapOverride(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo\n",
+		},
+		{
+			Path: "forks/mod/BUILD.bazel",
+			Content: `# gazelle:prefix github.com/upstream/mod
+# gazelle:importmap_override github.com/upstream/mod example.com/repo/forks/mod
+`,
+		},
+		{Path: "forks/mod/mod.go", Content: "package mod\n"},
+		{Path: "forks/mod/sub/sub.go", Content: "package sub\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "forks/mod/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+# gazelle:prefix github.com/upstream/mod
+# gazelle:importmap_override github.com/upstream/mod example.com/repo/forks/mod
+
+go_library(
+    name = "mod",
+    srcs = ["mod.go"],
+    importmap = "example.com/repo/forks/mod",
+    importpath = "github.com/upstream/mod",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "forks/mod/sub/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "sub",
+    srcs = ["sub.go"],
+    importmap = "example.com/repo/forks/mod/sub",
+    importpath = "github.com/upstream/mod/sub",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
+func TestImportPathDirective(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo\n",
+		},
+		{
+			Path:    "gen/api/BUILD.bazel",
+			Content: "# gazelle:importpath example.com/custom/api\n",
+		},
+		{Path: "gen/api/api.go", Content: "package api\n"},
+		{Path: "gen/api/sub/sub.go", Content: "package sub\n"},
+		{
+			Path: "use/use.go",
+			Content: `package use
+
+import _ "example.com/custom/api"
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "gen/api/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+# gazelle:importpath example.com/custom/api
+
+go_library(
+    name = "api",
+    srcs = ["api.go"],
+    importpath = "example.com/custom/api",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			// The directive doesn't apply to subdirectories.
+			Path: "gen/api/sub/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "sub",
+    srcs = ["sub.go"],
+    importpath = "example.com/repo/gen/api/sub",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "use/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "use",
+    srcs = ["use.go"],
+    importpath = "example.com/repo/use",
+    visibility = ["//visibility:public"],
+    deps = ["//gen/api"],
+)
+`,
+		},
+	})
+}
+
+func TestGoSrcsGroups(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:go_srcs_groups go generated cgo
+`,
+		},
+		{
+			Path: "a/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+genrule(
+    name = "gen",
+    outs = ["z_gen.go"],
+    cmd = "echo 'package a' > $@",
+)
+
+go_library(
+    name = "a",
+    srcs = [
+        "a.go",
+        "b_cgo.go",
+        "old.go",
+        "z_gen.go",
+    ],
+    importpath = "example.com/repo/a",
+)
+`,
+		},
+		{Path: "a/a.go", Content: "package a\n"},
+		{Path: "a/c.go", Content: "package a\n"},
+		{Path: "a/b_cgo.go", Content: "package a\n\nimport \"C\"\n"},
+		{Path: "a/b.c"},
+		{Path: "a/b.h"},
+		{Path: "b/b.go", Content: "package b\n"},
+		{Path: "b/b_test.go", Content: "package b\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	want := []testtools.FileSpec{
+		{
+			Path: "a/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+genrule(
+    name = "gen",
+    outs = ["z_gen.go"],
+    cmd = "echo 'package a' > $@",
+)
+
+go_library(
+    name = "a",
+    srcs = [
+        # Go sources
+        "a.go",
+        "c.go",
+        # Generated sources
+        "z_gen.go",
+        # cgo sources
+        "b.c",
+        "b.h",
+        "b_cgo.go",
+    ],
+    cgo = True,
+    importpath = "example.com/repo/a",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			// Lists with only one group don't have comments.
+			Path: "b/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+go_library(
+    name = "b",
+    srcs = ["b.go"],
+    importpath = "example.com/repo/b",
+    visibility = ["//visibility:public"],
+)
+
+go_test(
+    name = "b_test",
+    srcs = ["b_test.go"],
+    embed = [":b"],
+)
+`,
+		},
+	}
+	// Groups stay in place when Gazelle runs again.
+	for i := 0; i < 2; i++ {
+		if err := runGazelle(dir, nil); err != nil {
+			t.Fatal(err)
+		}
+		testtools.CheckFiles(t, dir, want)
+	}
+}
+
+func TestGoTestCombine(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:go_test_combine true
+`,
+		},
+		{
+			Path: "a/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+go_library(
+    name = "a",
+    srcs = ["a.go"],
+    importpath = "example.com/repo/a",
+)
+
+go_test(
+    name = "a_test",
+    srcs = ["a_test.go"],
+    embed = [":a"],
+)
+
+go_test(
+    name = "a_xtest",
+    srcs = ["x_test.go"],
+    deps = [":a"],
+)
+`,
+		},
+		{Path: "a/a.go", Content: "package a\n"},
+		{Path: "a/a_test.go", Content: "package a\n"},
+		{
+			Path: "a/x_test.go",
+			Content: `package a_test
+
+import _ "example.com/repo/a"
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "a/BUILD.bazel",
+		Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+go_library(
+    name = "a",
+    srcs = ["a.go"],
+    importpath = "example.com/repo/a",
+    visibility = ["//visibility:public"],
+)
+
+go_test(
+    name = "a_test",
+    srcs = [
+        "a_test.go",
+        "x_test.go",
+    ],
+    embed = [":a"],
+)
+`,
+	}})
+}
+
+func TestGoShadowStd(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "BUILD.bazel", Content: "# gazelle:prefix example.com/repo\n"},
+		{
+			Path: "encoding/json5/BUILD.bazel",
+			Content: `# gazelle:importpath encoding/json
+`,
+		},
+		{Path: "encoding/json5/json.go", Content: "package json\n"},
+		{
+			Path: "a/a.go",
+			Content: `package a
+
+import _ "encoding/json"
+`,
+		},
+		{
+			Path: "b/BUILD.bazel",
+			Content: `# gazelle:go_shadow_std encoding/json
+`,
+		},
+		{
+			Path: "b/b.go",
+			Content: `package b
+
+import _ "encoding/json"
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevStderr := os.Stderr
+	os.Stderr = w
+	runErr := runGazelle(dir, nil)
+	os.Stderr = prevStderr
+	w.Close()
+	logs, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "a/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "a",
+    srcs = ["a.go"],
+    importpath = "example.com/repo/a",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "b/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+# gazelle:go_shadow_std encoding/json
+
+go_library(
+    name = "b",
+    srcs = ["b.go"],
+    importpath = "example.com/repo/b",
+    visibility = ["//visibility:public"],
+    deps = ["//encoding/json5:json"],
+)
+`,
+		},
+	})
+	want := `//a imports "encoding/json", which is a standard library package, but //encoding/json5:json also provides it.`
+	if got := string(logs); !strings.Contains(got, want) {
+		t.Errorf("got log:\n%s\nwant message containing:\n%s", got, want)
+	}
+	if got := string(logs); strings.Contains(got, "//b imports") {
+		t.Errorf("got warning for shadowed import:\n%s", got)
+	}
+}
+
+func TestEmbedSymlinks(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:follow a/static/shared
+`,
+		},
+		{Path: "store/abc123", Content: "png"},
+		{Path: "store/dir/x.txt", Content: "x"},
+		{
+			Path: "a/a.go",
+			Content: `package a
+
+import "embed"
+
+//go:embed logo.png static
+var fs embed.FS
+`,
+		},
+		{Path: "a/logo.png", Symlink: "../store/abc123"},
+		{Path: "a/static/index.html"},
+		{Path: "a/static/img.png", Symlink: "../../store/abc123"},
+		{Path: "a/static/broken.png", Symlink: "../../store/missing"},
+		{Path: "a/static/shared", Symlink: "../../store/dir"},
+		{Path: "a/static/unfollowed", Symlink: "../../store/dir"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "a/BUILD.bazel",
+		Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "a",
+    srcs = ["a.go"],
+    embedsrcs = [
+        "logo.png",
+        "static/img.png",
+        "static/index.html",
+        "static/shared/x.txt",
+    ],
+    importpath = "example.com/repo/a",
+    visibility = ["//visibility:public"],
+)
+`,
+	}})
+}
+
+func TestGoExtraSrcs(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo",
+		},
+		{
+			Path: "foo/BUILD.bazel",
+			Content: `# gazelle:go_extra_srcs ../gen/foo/*.go //other:extra.go
+`,
+		},
+		{Path: "foo/foo.go", Content: "package foo\n"},
+		{Path: "foo/sub/sub.go", Content: "package sub\n"},
+		{
+			Path: "gen/foo/BUILD.bazel",
+			Content: `# gazelle:ignore
+
+exports_files(glob(["*.go"]))
+`,
+		},
+		{
+			Path: "gen/foo/gen.go",
+			Content: `package foo
+
+import "example.com/repo/bar"
+
+var _ = bar.X
+`,
+		},
+		{Path: "gen/foo/gen_test.go", Content: "package foo\n"},
+		{Path: "gen/foo/README.md"},
+		{Path: "bar/bar.go", Content: "package bar\n\nvar X int\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "foo/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+# gazelle:go_extra_srcs ../gen/foo/*.go //other:extra.go
+
+go_library(
+    name = "foo",
+    srcs = [
+        "foo.go",
+        "//gen/foo:gen.go",
+        "//other:extra.go",
+    ],
+    importpath = "example.com/repo/foo",
+    visibility = ["//visibility:public"],
+    deps = ["//bar"],
+)
+
+go_test(
+    name = "foo_test",
+    srcs = ["//gen/foo:gen_test.go"],
+    embed = [":foo"],
+)
+`,
+		},
+		{
+			Path: "foo/sub/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "sub",
+    srcs = ["sub.go"],
+    importpath = "example.com/repo/foo/sub",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
+func TestGoogleapisModule(t *testing.T) {
+	files := []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `module(name = "example")
+
+bazel_dep(name = "rules_go", version = "0.50.1", repo_name = "io_bazel_rules_go")
+bazel_dep(name = "googleapis", version = "0.0.0-20241220-5e258e33.bcr.1", repo_name = "com_google_googleapis")
+`,
+		},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo",
+		},
+		{
+			Path: "foo/foo.proto",
+			Content: `syntax = "proto3";
+
+package foo;
+
+option go_package = "example.com/repo/foo";
+
+import "google/api/annotations.proto";
+import "google/rpc/error_details.proto";
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "foo/BUILD.bazel",
+		Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+proto_library(
+    name = "foo_proto",
+    srcs = ["foo.proto"],
+    visibility = ["//visibility:public"],
+    deps = [
+        "@com_google_googleapis//google/api:annotations_proto",
+        "@com_google_googleapis//google/rpc:error_details_proto",
+    ],
+)
+
+go_proto_library(
+    name = "foo_go_proto",
+    importpath = "example.com/repo/foo",
+    proto = ":foo_proto",
+    visibility = ["//visibility:public"],
+    deps = [
+        "@com_google_googleapis//google/api:annotations_go_proto",
+        "@com_google_googleapis//google/rpc:errdetails_go_proto",
+    ],
+)
+
+go_library(
+    name = "foo",
+    embed = [":foo_go_proto"],
+    importpath = "example.com/repo/foo",
+    visibility = ["//visibility:public"],
+)
+`,
+	}})
+}
+
+func TestGoProtoImportPathConflict(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo",
+		},
+		{
+			Path: "a/a.proto",
+			Content: `syntax = "proto3";
+
+package a;
+
+option go_package = "example.com/repo/shared";
+`,
+		},
+		{
+			Path: "b/b.proto",
+			Content: `syntax = "proto3";
+
+package b;
+
+option go_package = "example.com/repo/shared";
+`,
+		},
+		{
+			Path: "c/c.go",
+			Content: `package c
+
+import _ "example.com/repo/shared"
+`,
+		},
+		{
+			Path: "d/d.go",
+			Content: `package d
+
+import _ "example.com/repo/shared"
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevStderr := os.Stderr
+	os.Stderr = w
+	runErr := runGazelle(dir, nil)
+	os.Stderr = prevStderr
+	w.Close()
+	logs, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	if n := strings.Count(string(logs), "importpath conflict: "); n != 1 {
+		t.Errorf("got %d importpath conflict reports; want 1. Logs:\n%s", n, logs)
+	}
+	for _, want := range []string{
+		`2 libraries built from protos have the importpath "example.com/repo/shared":
+	//a:shared
+	//b:shared
+`,
+		"# gazelle:resolve go example.com/repo/shared //a:shared",
+		`rule //c imports "example.com/repo/shared" which matches multiple rules built from protos: //a:shared and //b:shared.`,
+		`rule //d imports "example.com/repo/shared" which matches multiple rules built from protos: //a:shared and //b:shared.`,
+	} {
+		if !strings.Contains(string(logs), want) {
+			t.Errorf("logs don't contain %q. Logs:\n%s", want, logs)
+		}
+	}
+}
+
+func TestGoGrpcSeparateLibrary(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:go_grpc_separate_library true
+`,
+		},
+		{
+			Path: "foo/foo.proto",
+			Content: `syntax = "proto3";
+
+package foo;
+
+option go_package = "example.com/repo/foo";
+
+message Request {}
+
+service Foo {
+  rpc Get(Request) returns (Request);
+}
+`,
+		},
+		{
+			Path: "bar/bar.proto",
+			Content: `syntax = "proto3";
+
+package bar;
+
+option go_package = "example.com/repo/bar";
+
+message Bar {}
+`,
+		},
+		{
+			Path: "use/use.go",
+			Content: `package use
+
+import _ "example.com/repo/foo"
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := runGazelle(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "foo/BUILD.bazel",
+			Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_grpc_library", "go_proto_library")
+
+proto_library(
+    name = "foo_proto",
+    srcs = ["foo.proto"],
+    visibility = ["//visibility:public"],
+)
+
+go_proto_library(
+    name = "foo_go_proto",
+    importpath = "example.com/repo/foo",
+    proto = ":foo_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_grpc_library(
+    name = "foo_go_grpc",
+    compilers = ["@io_bazel_rules_go//proto:go_grpc_v2"],
+    embed = [":foo_go_proto"],
+    importpath = "example.com/repo/foo",
+    proto = ":foo_proto",
+    visibility = ["//visibility:public"],
+)
 
 go_library(
     name = "foo",
-    srcs = ["cgo_static.go"],
-    cgo = True,
-    clinkopts = ["-lstdc++ -lm -ldl -L../com_example_foo_v2/deps"],
-    importpath = "example.com/foo/v2",
-    importpath_aliases = ["example.com/foo"],
+    embed = [":foo_go_grpc"],
+    importpath = "example.com/repo/foo",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "bar/BUILD.bazel",
+			Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+proto_library(
+    name = "bar_proto",
+    srcs = ["bar.proto"],
+    visibility = ["//visibility:public"],
+)
+
+go_proto_library(
+    name = "bar_go_proto",
+    importpath = "example.com/repo/bar",
+    proto = ":bar_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "bar",
+    embed = [":bar_go_proto"],
+    importpath = "example.com/repo/bar",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "use/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "use",
+    srcs = ["use.go"],
+    importpath = "example.com/repo/use",
     visibility = ["//visibility:public"],
+    deps = ["//foo"],
 )
 `,
 		},
 	})
 }
 
-// TestEmptyTestdataNoData checks that an empty testdata subdirectory does not
-// result in a data attribute being added to go_test rules.
-func TestEmptyTestdataNoData(t *testing.T) {
-	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+// TestGoGrpcLibraryWithoutSeparateLibrary checks that a hand-written
+// go_grpc_library is left alone when go_grpc_separate_library is off.
+func TestGoGrpcLibraryWithoutSeparateLibrary(t *testing.T) {
+	files := []testtools.FileSpec{
 		{Path: "WORKSPACE"},
+		{Path: "BUILD.bazel", Content: "# gazelle:prefix example.com/repo\n"},
 		{
-			Path: "example_test.go",
-			Content: `
-package example
+			Path: "foo/foo.proto",
+			Content: `syntax = "proto3";
 
-import "testing"
+package foo;
 
-func TestExample(t *testing.T) {}
+option go_package = "example.com/repo/foo";
+
+message Request {}
+
+service Foo {
+  rpc Get(Request) returns (Request);
+}
 `,
 		},
-		{Path: "testdata/"},
-	})
+		{
+			Path: "foo/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//proto:def.bzl", "go_grpc_library")
+
+go_grpc_library(
+    name = "foo_go_grpc",
+    compilers = ["//tools:custom_grpc"],
+    importpath = "example.com/repo/foo/grpc",
+    proto = ":foo_proto",
+)
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
 	defer cleanup()
 
-	if err := runGazelle(dir, []string{"-go_prefix", "example.com/foo"}); err != nil {
+	if err := runGazelle(dir, nil); err != nil {
 		t.Fatal(err)
 	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "foo/BUILD.bazel",
+		Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_grpc_library", "go_proto_library")
 
-	buildContent, err := os.ReadFile(filepath.Join(dir, "BUILD.bazel"))
+go_grpc_library(
+    name = "foo_go_grpc",
+    compilers = ["//tools:custom_grpc"],
+    importpath = "example.com/repo/foo/grpc",
+    proto = ":foo_proto",
+)
+
+proto_library(
+    name = "foo_proto",
+    srcs = ["foo.proto"],
+    visibility = ["//visibility:public"],
+)
+
+go_proto_library(
+    name = "foo_go_proto",
+    compilers = [
+        "@io_bazel_rules_go//proto:go_proto",
+        "@io_bazel_rules_go//proto:go_grpc_v2",
+    ],
+    importpath = "example.com/repo/foo",
+    proto = ":foo_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "foo",
+    embed = [":foo_go_proto"],
+    importpath = "example.com/repo/foo",
+    visibility = ["//visibility:public"],
+)
+`,
+	}})
+}
+
+func TestGoProtoMissingGoPackage(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:prefix example.com/repo",
+		},
+		{
+			Path:    "infer/infer.proto",
+			Content: "syntax = \"proto3\";\n\npackage infer;\n",
+		},
+		{
+			Path:    "explicit/BUILD.bazel",
+			Content: "# gazelle:go_proto_missing_go_package report\n",
+		},
+		{
+			Path:    "explicit/explicit.proto",
+			Content: "syntax = \"proto3\";\n\npackage explicit;\n",
+		},
+		{
+			Path:    "warn/BUILD.bazel",
+			Content: "# gazelle:go_proto_missing_go_package warn\n",
+		},
+		{
+			Path:    "warn/a.proto",
+			Content: "syntax = \"proto3\";\n\npackage warn;\n",
+		},
+		{
+			Path:    "warn/b.proto",
+			Content: "syntax = \"proto3\";\n\npackage warn;\n",
+		},
+		{
+			Path: "error/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+# gazelle:go_proto_missing_go_package error
+
+go_proto_library(
+    name = "error_go_proto",
+    importpath = "example.com/repo/error",
+    proto = ":error_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "error",
+    embed = [":error_go_proto"],
+    importpath = "example.com/repo/error",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path:    "error/error.proto",
+			Content: "syntax = \"proto3\";\n\npackage error;\n",
+		},
+		{
+			Path:    "error/ok/BUILD.bazel",
+			Content: "",
+		},
+		{
+			Path: "error/ok/ok.proto",
+			Content: `syntax = "proto3";
+
+package ok;
+
+option go_package = "example.com/repo/error/ok";
+`,
+		},
+		{
+			Path:    "error/new/new.proto",
+			Content: "syntax = \"proto3\";\n\npackage new;\n",
+		},
+		{
+			Path: "dep/dep.proto",
+			Content: `syntax = "proto3";
+
+package dep;
+
+option go_package = "example.com/repo/dep";
+
+import "error/new/new.proto";
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	r, w, err := os.Pipe()
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Verify that the BUILD file doesn't contain a data attribute
-	if strings.Contains(string(buildContent), "data = ") {
-		t.Errorf("BUILD file should not contain data attribute for empty testdata\n%s", string(buildContent))
+	prevStderr := os.Stderr
+	os.Stderr = w
+	runErr := runGazelle(dir, []string{"-strict"})
+	os.Stderr = prevStderr
+	w.Close()
+	logs, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Imports of libraries rejected in error mode can't be resolved unless
+	// an existing rule provides them.
+	if runErr == nil {
+		t.Fatal("got success; want unresolved import of error/new/new.proto")
+	}
+	if want := "unresolved imports (1):\n\trule //dep:dep_go_proto imports \"error/new/new.proto\", but Go rules aren't generated for it"; !strings.Contains(runErr.Error(), want) {
+		t.Errorf("got error:\n%v\nwant error containing:\n%s", runErr, want)
 	}
 
-	// Verify that a go_test rule was generated
-	if !strings.Contains(string(buildContent), "go_test(") {
-		t.Errorf("BUILD file should contain a go_test rule\n%s", string(buildContent))
+	for _, want := range []string{
+		`explicit/explicit.proto: no go_package option; using importpath "example.com/repo/explicit", inferred from the directory`,
+		`warn/a.proto: warning: no go_package option; using importpath "example.com/repo/warn", inferred from the directory`,
+		`warn/b.proto: warning: no go_package option; using importpath "example.com/repo/warn", inferred from the directory`,
+		`error/error.proto: error: no go_package option; not generating Go rules for :error_proto.`,
+		`error/new/new.proto: error: no go_package option; not generating Go rules for :new_proto.`,
+	} {
+		if !strings.Contains(string(logs), want) {
+			t.Errorf("logs don't contain %q. Logs:\n%s", want, logs)
+		}
+	}
+	for _, notWant := range []string{"infer.proto", "ok.proto"} {
+		if strings.Contains(string(logs), notWant) {
+			t.Errorf("logs unexpectedly mention %s. Logs:\n%s", notWant, logs)
+		}
 	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{
+		{
+			Path: "infer/BUILD.bazel",
+			Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+proto_library(
+    name = "infer_proto",
+    srcs = ["infer.proto"],
+    visibility = ["//visibility:public"],
+)
+
+go_proto_library(
+    name = "infer_go_proto",
+    importpath = "example.com/repo/infer",
+    proto = ":infer_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "infer",
+    embed = [":infer_go_proto"],
+    importpath = "example.com/repo/infer",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "explicit/BUILD.bazel",
+			Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+# gazelle:go_proto_missing_go_package report
+
+proto_library(
+    name = "explicit_proto",
+    srcs = ["explicit.proto"],
+    visibility = ["//visibility:public"],
+)
+
+go_proto_library(
+    name = "explicit_go_proto",
+    importpath = "example.com/repo/explicit",
+    proto = ":explicit_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "explicit",
+    embed = [":explicit_go_proto"],
+    importpath = "example.com/repo/explicit",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "warn/BUILD.bazel",
+			Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+# gazelle:go_proto_missing_go_package warn
+
+proto_library(
+    name = "warn_proto",
+    srcs = [
+        "a.proto",
+        "b.proto",
+    ],
+    visibility = ["//visibility:public"],
+)
+
+go_proto_library(
+    name = "warn_go_proto",
+    importpath = "example.com/repo/warn",
+    proto = ":warn_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "warn",
+    embed = [":warn_go_proto"],
+    importpath = "example.com/repo/warn",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "error/BUILD.bazel",
+			Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+# gazelle:go_proto_missing_go_package error
+
+go_proto_library(
+    name = "error_go_proto",
+    importpath = "example.com/repo/error",
+    proto = ":error_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "error",
+    embed = [":error_go_proto"],
+    importpath = "example.com/repo/error",
+    visibility = ["//visibility:public"],
+)
+
+proto_library(
+    name = "error_proto",
+    srcs = ["error.proto"],
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path: "error/ok/BUILD.bazel",
+			Content: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+proto_library(
+    name = "ok_proto",
+    srcs = ["ok.proto"],
+    visibility = ["//visibility:public"],
+)
+
+go_proto_library(
+    name = "ok_go_proto",
+    importpath = "example.com/repo/error/ok",
+    proto = ":ok_proto",
+    visibility = ["//visibility:public"],
+)
+
+go_library(
+    name = "ok",
+    embed = [":ok_go_proto"],
+    importpath = "example.com/repo/error/ok",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+	})
+}
+
+func TestStrictSummary(t *testing.T) {
+	t.Run("config", func(t *testing.T) {
+		dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+			{Path: "WORKSPACE"},
+			{
+				Path:    "BUILD.bazel",
+				Content: "# gazelle:prefix example.com/repo\n# gazelle:bogus_one x\n",
+			},
+			{Path: "a/BUILD.bazel", Content: "# gazelle:bogus_two y\n"},
+			{Path: "a/a.go", Content: "package a\n"},
+			{Path: "b/BUILD.bazel", Content: "go_library(\n"},
+		})
+		defer cleanup()
+
+		err := runGazelle(dir, []string{"-strict"})
+		if got := update.ExitCode(err); got != update.ExitConfig {
+			t.Fatalf("got exit code %d (%v); want %d", got, err, update.ExitConfig)
+		}
+		for _, want := range []string{
+			"strict mode found 3 problems:",
+			"build file errors (1):",
+			"invalid directives (2):",
+			"gazelle:bogus_one",
+			"gazelle:bogus_two",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error does not contain %q:\n%v", want, err)
+			}
+		}
+		testtools.CheckFiles(t, dir, []testtools.FileSpec{
+			{Path: "a/BUILD.bazel", Content: "# gazelle:bogus_two y\n"},
+		})
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+			{Path: "WORKSPACE"},
+			{Path: "BUILD.bazel", Content: "# gazelle:prefix example.com/repo\n"},
+			{
+				Path: "a/BUILD.bazel",
+				Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "x",
+    importpath = "example.com/repo/a",
+)
+
+go_library(
+    name = "y",
+    importpath = "example.com/repo/a",
+)
+`,
+			},
+			{Path: "a/a.go", Content: "package a\n\nimport _ \"../outside\"\n"},
+		})
+		defer cleanup()
+
+		err := runGazelle(dir, []string{"-strict"})
+		if got := update.ExitCode(err); got != update.ExitConfig {
+			t.Fatalf("got exit code %d (%v); want %d", got, err, update.ExitConfig)
+		}
+		for _, want := range []string{
+			"strict mode found 2 problems:",
+			"merge failures (1):",
+			"could not merge go_library(a): multiple rules have the same attribute importpath",
+			"unresolved imports (1):",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error does not contain %q:\n%v", want, err)
+			}
+		}
+	})
 }
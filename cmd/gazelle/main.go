@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 
 	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
 	"github.com/bazelbuild/bazel-gazelle/config"
@@ -36,6 +37,13 @@ const (
 	updateCmd command = iota
 	fixCmd
 	updateReposCmd
+	watchCmd
+	serverCmd
+	listCmd
+	configCmd
+	resolveCmd
+	deleteCmd
+	fmtCmd
 	helpCmd
 )
 
@@ -44,6 +52,13 @@ var commandFromName = map[string]command{
 	"help":         helpCmd,
 	"update":       updateCmd,
 	"update-repos": updateReposCmd,
+	"watch":        watchCmd,
+	"server":       serverCmd,
+	"list":         listCmd,
+	"config":       configCmd,
+	"resolve":      resolveCmd,
+	"delete":       deleteCmd,
+	"fmt":          fmtCmd,
 }
 
 var nameFromCommand = []string{
@@ -51,6 +66,13 @@ var nameFromCommand = []string{
 	"update",
 	"fix",
 	"update-repos",
+	"watch",
+	"server",
+	"list",
+	"config",
+	"resolve",
+	"delete",
+	"fmt",
 	"help",
 }
 
@@ -62,11 +84,9 @@ func main() {
 	log.SetPrefix("gazelle: ")
 	log.SetFlags(0) // don't print timestamps
 
-	// TODO(#2279): interpret arguments as paths relative to either
-	// BUILD_WORKSPACE_DIRECTORY or BUILD_WORKING_DIRECTORY, depending on whether
-	// the paths were specified as arguments to the gazelle macro or passed as
-	// arguments to `bazel run`. Currently, we mix them together and interpret
-	// relative to BUILD_WORKSPACE_DIRECTORY.
+	// Arguments are interpreted relative to BUILD_WORKSPACE_DIRECTORY, except
+	// for directories passed as arguments to `bazel run`, which update
+	// interprets relative to BUILD_WORKING_DIRECTORY (#2279).
 	var wd string
 	if wsDir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); wsDir != "" {
 		wd = wsDir
@@ -77,17 +97,38 @@ func main() {
 		}
 	}
 
-	if err := run(wd, os.Args[1:]); err != nil && err != flag.ErrHelp {
-		if errors.Is(err, update.ErrDiff) {
-			os.Exit(1)
-		} else {
-			log.Fatal(err)
+	// Ctrl-C cancels ctx. Commands stop what they're doing and return without
+	// leaving partially updated build files. After that, signal handling is
+	// reset, so pressing Ctrl-C again exits immediately.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+
+	// Language plugins are loaded before any command runs, so their flags
+	// and directives are known to all of them.
+	pluginPaths, args, err := extractPluginsFlag(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	pluginLangs, err := loadPlugins(pluginPaths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	languages = append(languages, pluginLangs...)
+
+	if err := run(ctx, wd, args); err != nil && err != flag.ErrHelp {
+		// Print errors directly, so they're shown even with -q.
+		if !errors.Is(err, update.ErrDiff) {
+			fmt.Fprintf(os.Stderr, "gazelle: %v\n", err)
 		}
+		os.Exit(update.ExitCode(err))
 	}
 }
 
-func run(wd string, args []string) error {
-	ctx := context.Background()
+func run(ctx context.Context, wd string, args []string) error {
 	if len(args) == 1 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
 		return help()
 	}
@@ -99,6 +140,26 @@ func run(wd string, args []string) error {
 		return help()
 	case "update-repos":
 		return updateRepos(wd, args[1:])
+	case "config":
+		return update.PrintConfig(ctx, languages, wd, args[1:], os.Stdout)
+	case "resolve":
+		return resolveImport(ctx, wd, args[1:], os.Stdout)
+	case "delete":
+		// delete is update with empty build files removed. References to
+		// their rules are only removed in directories that are visited.
+		return update.Run(ctx, languages, wd, append([]string{"update", "-delete_empty_build_files"}, args[1:]...))
+	case "fmt":
+		return update.Format(ctx, languages, wd, args[1:])
+	case "mod":
+		return modCmd(wd, args[1:])
+	case "list":
+		// list is update without writing files. Flags in args may still set
+		// -list_format.
+		return update.Run(ctx, languages, wd, append([]string{"update", "-mode=list"}, args[1:]...))
+	case "server":
+		return serve(ctx, wd, args[1:])
+	case "watch":
+		return watch(ctx, wd, args[1:])
 	default:
 		// Either "fix", "update", or a directory name. Pass through args[0].
 		// update.Run knows what to do with it.
@@ -125,8 +186,34 @@ Gazelle defaults to "update".
       existing rules.
   update-repos - updates repository rules in the WORKSPACE file. Run with
       -h for details.
+  config - prints the effective configuration for a directory, including
+      the directives applied in it and its parents.
+  delete - same as update, but also deletes build files left empty because
+      their directories no longer contain sources, and removes references
+      to their rules from other build files.
+  fmt - reformats existing build files and fixes their load statements
+      without generating rules. Accepts build files or directories, and the
+      same flags as update.
+  mod - edits MODULE.bazel. "mod tidy" updates use_repo calls for the go_deps
+      extension to match the Go modules required directly. "mod override"
+      adds and removes go_deps override tags. "mod why" explains why a Go
+      module is needed. Run with -h for details.
+  list - prints the directories whose build files update would change,
+      without writing anything. Accepts the same flags as update.
+  resolve - prints the label an import string resolves to and why. Run with
+      -h for details.
+  server - answers update and import resolution requests from editors and
+      other tools over JSON-RPC on stdin and stdout. Run with -h for details.
+  watch - runs update, then updates build files in directories where files
+      change until interrupted. Run with -h for details.
   help - show this message.
 
+Language extensions compiled as Go plugins with -buildmode=plugin may be
+loaded with -plugins=path1.so,path2.so, given with any command. Each plugin
+must export a NewLanguage function returning a language.Language, and must be
+built from the same Gazelle sources and Go version as this binary. Plugins are
+supported on Linux, macOS, and FreeBSD when Gazelle is built with cgo.
+
 For usage information for a specific command, run the command with the -h flag.
 For example:
 
@@ -142,24 +229,15 @@ without notice.
 // filterLanguages returns the subset of input languages that pass the config's
 // filter, if any. Gazelle should not generate rules for languages not returned.
 func filterLanguages(c *config.Config, langs []language.Language) []language.Language {
-	if len(c.Langs) == 0 {
+	if len(c.Langs) == 0 && len(c.DisabledLangs) == 0 {
 		return langs
 	}
 
 	var result []language.Language
 	for _, inputLang := range langs {
-		if containsLang(c.Langs, inputLang) {
+		if c.LangEnabled(inputLang.Name()) {
 			result = append(result, inputLang)
 		}
 	}
 	return result
 }
-
-func containsLang(langNames []string, lang language.Language) bool {
-	for _, langName := range langNames {
-		if langName == lang.Name() {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,406 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	gzflag "github.com/bazel-contrib/bazel-gazelle/v2/flag"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/mod/modfile"
+)
+
+// modCmd runs a "gazelle mod" subcommand, which edits MODULE.bazel.
+func modCmd(wd string, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		modUsage(nil)
+		return flag.ErrHelp
+	}
+	switch args[0] {
+	case "tidy":
+		return modTidy(wd, args[1:])
+	case "override":
+		return modOverride(wd, args[1:])
+	case "why":
+		return modWhy(wd, args[1:])
+	default:
+		modUsage(nil)
+		return update.ConfigError(fmt.Errorf("mod: unknown subcommand %q", args[0]))
+	}
+}
+
+func modUsage(fs *flag.FlagSet) {
+	fmt.Fprint(os.Stderr, `usage:
+
+gazelle mod tidy [flags...]
+gazelle mod override [flags...] gazelle|module|archive module-path
+gazelle mod why [flags...] module-path|@repo...
+
+The mod command edits MODULE.bazel.
+
+tidy updates use_repo calls for the go_deps module extension, so they import
+exactly the repositories of Go modules required directly by go.mod files
+imported with go_deps.from_file and of go_deps.module tags not marked
+indirect. Repositories marked with "# keep" comments aren't removed. This
+makes the same change as "bazel mod tidy", without running Bazel.
+
+override adds or updates a go_deps.gazelle_override, module_override, or
+archive_override tag for a Go module, setting the attributes given with
+flags. Values of list flags like -directive are added to the tag's existing
+lists. With -remove, values of list flags are removed from the tag instead,
+or the whole tag is removed if no list flags are given. Tags marked with
+"# keep" comments aren't modified.
+
+why explains why the main module needs each Go module or repository. It
+prints the shortest chain of requirements from the main module to the module,
+as reported by "go mod graph" for go.mod files imported with
+go_deps.from_file, and the targets in the main repository whose attributes
+refer to the module's repository. It doesn't edit MODULE.bazel.
+
+`)
+	if fs != nil {
+		fmt.Fprint(os.Stderr, "FLAGS:\n\n")
+		fs.PrintDefaults()
+	}
+}
+
+type modConfig struct {
+	mode string
+
+	// Flags for "gazelle mod override". Unset list flags are nil, and unset
+	// scalar flags aren't in scalarAttrs.
+	remove      bool
+	directives  []string
+	extraArgs   []string
+	patches     []string
+	patchCmds   []string
+	urls        []string
+	scalarAttrs map[string]any
+}
+
+const modName = "_mod"
+
+func getModConfig(c *config.Config) *modConfig {
+	return c.Exts[modName].(*modConfig)
+}
+
+var _ config.Configurer = (*modConfigurer)(nil)
+
+type modConfigurer struct{}
+
+func (*modConfigurer) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config) {
+	mc := &modConfig{scalarAttrs: make(map[string]any)}
+	c.Exts[modName] = mc
+	fs.StringVar(&mc.mode, "mode", "fix", "fix: rewrites MODULE.bazel in place\n\tdiff: prints a diff of the changes to MODULE.bazel")
+	if cmd != "mod override" {
+		return
+	}
+	fs.BoolVar(&mc.remove, "remove", false, "remove values of list flags from the override, or the whole override if no list flags are given")
+	fs.Var(&gzflag.MultiFlag{Values: &mc.directives}, "directive", "gazelle_override: a Gazelle directive, like gazelle:proto disable (can specify multiple times)")
+	fs.Var(&gzflag.MultiFlag{Values: &mc.extraArgs}, "build_extra_arg", "gazelle_override: an extra argument to pass to Gazelle (can specify multiple times)")
+	fs.Var(&gzflag.MultiFlag{Values: &mc.patches}, "patch", "module_override, archive_override: the label of a patch to apply (can specify multiple times)")
+	fs.Var(&gzflag.MultiFlag{Values: &mc.patchCmds}, "patch_cmd", "module_override, archive_override: a command to run after patches are applied (can specify multiple times)")
+	fs.Var(&gzflag.MultiFlag{Values: &mc.urls}, "url", "archive_override: a URL of an archive containing the module (can specify multiple times)")
+	fs.Func("build_file_generation", "gazelle_override: auto, on, off, or clean", func(v string) error {
+		switch v {
+		case "auto", "on", "off", "clean":
+			mc.scalarAttrs["build_file_generation"] = v
+			return nil
+		default:
+			return fmt.Errorf("must be auto, on, off, or clean")
+		}
+	})
+	fs.Func("patch_strip", "module_override, archive_override: the number of leading path components to strip from file names in patches", func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("must be a non-negative integer")
+		}
+		mc.scalarAttrs["patch_strip"] = n
+		return nil
+	})
+	for _, name := range []string{"repo_name", "sha256", "strip_prefix"} {
+		kinds := "module_override"
+		if name != "repo_name" {
+			kinds = "archive_override"
+		}
+		fs.Func(name, fmt.Sprintf("%s: the %s attribute", kinds, name), func(v string) error {
+			mc.scalarAttrs[name] = v
+			return nil
+		})
+	}
+}
+
+func (*modConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) error {
+	mc := getModConfig(c)
+	if mc.mode != "fix" && mc.mode != "diff" {
+		return fmt.Errorf("unrecognized mode: %q", mc.mode)
+	}
+	return nil
+}
+
+func (*modConfigurer) KnownDirectives() []string { return nil }
+
+func (*modConfigurer) Configure(c *config.Config, rel string, f *rule.File) {}
+
+// newModConfiguration parses flags for the "gazelle mod" subcommand cmd
+// and returns the configuration and positional arguments.
+func newModConfiguration(wd, cmd string, args []string) (*config.Config, []string, error) {
+	c := config.New()
+	c.WorkDir = wd
+	cexts := []config.Configurer{&config.CommonConfigurer{}, &modConfigurer{}}
+	fs := flag.NewFlagSet("gazelle", flag.ContinueOnError)
+	fs.Usage = func() {}
+	if err := update.RegisterFlags(fs, "mod "+cmd, c, cexts); err != nil {
+		return nil, nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			modUsage(fs)
+			return nil, nil, err
+		}
+		// flag already prints the error; don't print it again.
+		return nil, nil, update.ConfigError(errors.New("Try -help for more information"))
+	}
+	for _, cext := range cexts {
+		if err := cext.CheckFlags(fs, c); err != nil {
+			return nil, nil, update.ConfigError(err)
+		}
+	}
+	return c, fs.Args(), nil
+}
+
+// saveModuleFile writes f back to disk, or with -mode=diff, prints a diff
+// of the changes and returns update.ErrDiff if there are any.
+func saveModuleFile(c *config.Config, f *rule.ModuleFile) error {
+	newContent := f.Format()
+	if string(newContent) == string(f.Content) {
+		return nil
+	}
+	if getModConfig(c).mode == "diff" {
+		diff := difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(f.Content)),
+			B:        difflib.SplitLines(string(newContent)),
+			FromFile: "MODULE.bazel",
+			ToFile:   "MODULE.bazel",
+			Context:  3,
+		}
+		if err := difflib.WriteUnifiedDiff(os.Stdout, diff); err != nil {
+			return err
+		}
+		return update.ErrDiff
+	}
+	return f.Save(f.Path)
+}
+
+// modTidy implements "gazelle mod tidy".
+func modTidy(wd string, args []string) error {
+	c, args, err := newModConfiguration(wd, "tidy", args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return update.ConfigError(fmt.Errorf("mod tidy: unexpected arguments: %q", args))
+	}
+
+	modulePath := filepath.Join(c.RepoRoot, "MODULE.bazel")
+	f, err := rule.LoadModuleFile(modulePath)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, p := range f.ExtensionProxies() {
+		if !isGoDepsExtension(c, p) {
+			continue
+		}
+		found = true
+		repos, err := goDepsDirectRepos(c, f, p.Ident)
+		if err != nil {
+			return err
+		}
+		f.SetUseRepos(p.Ident, repos)
+	}
+	if !found {
+		return fmt.Errorf("%s: no go_deps extension usage found", modulePath)
+	}
+	return saveModuleFile(c, f)
+}
+
+// isGoDepsExtension returns whether p is a usage of Gazelle's go_deps
+// module extension.
+func isGoDepsExtension(c *config.Config, p rule.ExtensionProxy) bool {
+	if p.Name != "go_deps" {
+		return false
+	}
+	l, err := label.Parse(p.BzlFile)
+	if err != nil || l.Pkg != "" || l.Name != "extensions.bzl" {
+		return false
+	}
+	switch l.Repo {
+	case "gazelle", "bazel_gazelle":
+		return true
+	default:
+		return l.Repo != "" && l.Repo == c.ModuleToApparentName("gazelle")
+	}
+}
+
+// goDepsDirectRepos returns the sorted names of the repositories the
+// go_deps extension reports as direct dependencies of the root module for
+// the tags of the proxy named ident. These are the repositories of modules
+// required by go.mod files without "// indirect" comments, or that provide
+// tools, and of go_deps.module tags without indirect = True. Modules in the
+// go.work file itself aren't included.
+func goDepsDirectRepos(c *config.Config, f *rule.ModuleFile, ident string) ([]string, error) {
+	var modPaths []string
+	repoNames := make(map[string]string)
+	for _, tag := range f.Tags(ident) {
+		switch strings.TrimPrefix(tag.Kind(), ident+".") {
+		case "from_file":
+			if goMod := tag.AttrString("go_mod"); goMod != "" {
+				p, err := mainRepoPath(c, goMod)
+				if err != nil {
+					return nil, err
+				}
+				paths, err := goModDirectDeps(p, nil)
+				if err != nil {
+					return nil, err
+				}
+				modPaths = append(modPaths, paths...)
+			}
+			if goWork := tag.AttrString("go_work"); goWork != "" {
+				p, err := mainRepoPath(c, goWork)
+				if err != nil {
+					return nil, err
+				}
+				paths, err := goWorkDirectDeps(p)
+				if err != nil {
+					return nil, err
+				}
+				modPaths = append(modPaths, paths...)
+			}
+		case "module":
+			if !tag.AttrBool("indirect") {
+				modPaths = append(modPaths, tag.AttrString("path"))
+			}
+		case "module_override":
+			if name := tag.AttrString("repo_name"); name != "" {
+				repoNames[tag.AttrString("path")] = name
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var repos []string
+	for _, modPath := range modPaths {
+		name := repoNames[modPath]
+		if name == "" {
+			name = label.ImportPathToBazelRepoName(modPath)
+		}
+		if !seen[name] {
+			seen[name] = true
+			repos = append(repos, name)
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// mainRepoPath returns the file system path of a file in the main
+// repository, given its label in MODULE.bazel.
+func mainRepoPath(c *config.Config, s string) (string, error) {
+	l, err := label.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if l.Repo != "" && l.Repo != "@" {
+		return "", fmt.Errorf("%s: go_deps.from_file must name a file in the main repository", s)
+	}
+	return filepath.Join(c.RepoRoot, filepath.FromSlash(path.Join(l.Pkg, l.Name))), nil
+}
+
+// goModDirectDeps returns the paths of modules that the go.mod file at
+// goModPath requires directly, other than those in exclude.
+func goModDirectDeps(goModPath string, exclude map[string]bool) ([]string, error) {
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	mf, err := modfile.Parse(goModPath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// A tool's module may be marked indirect, but it's needed to build the
+	// tool. Any prefix of a tool's package path may be its module.
+	toolModules := make(map[string]bool)
+	for _, t := range mf.Tool {
+		for p := t.Path; p != "." && p != "/"; p = path.Dir(p) {
+			toolModules[p] = true
+		}
+	}
+
+	var paths []string
+	for _, r := range mf.Require {
+		if (!r.Indirect || toolModules[r.Mod.Path]) && !exclude[r.Mod.Path] {
+			paths = append(paths, r.Mod.Path)
+		}
+	}
+	return paths, nil
+}
+
+// goWorkDirectDeps returns the paths of modules that the modules used by
+// the go.work file at goWorkPath require directly.
+func goWorkDirectDeps(goWorkPath string) ([]string, error) {
+	data, err := os.ReadFile(goWorkPath)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := modfile.ParseWork(goWorkPath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	var goModPaths []string
+	members := make(map[string]bool)
+	for _, u := range wf.Use {
+		goModPath := filepath.Join(filepath.Dir(goWorkPath), filepath.FromSlash(u.Path), "go.mod")
+		data, err := os.ReadFile(goModPath)
+		if err != nil {
+			return nil, err
+		}
+		members[modfile.ModulePath(data)] = true
+		goModPaths = append(goModPaths, goModPath)
+	}
+	var paths []string
+	for _, goModPath := range goModPaths {
+		p, err := goModDirectDeps(goModPath, members)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p...)
+	}
+	return paths, nil
+}
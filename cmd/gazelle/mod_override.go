@@ -0,0 +1,157 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+)
+
+// overrideAttrs lists the attributes of each go_deps override tag that
+// "gazelle mod override" can set, other than path.
+var overrideAttrs = map[string][]string{
+	"gazelle": {"build_extra_args", "build_file_generation", "directives"},
+	"module":  {"patch_cmds", "patch_strip", "patches", "repo_name"},
+	"archive": {"patch_cmds", "patch_strip", "patches", "sha256", "strip_prefix", "urls"},
+}
+
+// modOverride implements "gazelle mod override".
+func modOverride(wd string, args []string) error {
+	c, args, err := newModConfiguration(wd, "override", args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return update.ConfigError(errors.New("mod override: expected an override kind (gazelle, module, or archive) and a module path"))
+	}
+	kind, modPath := args[0], args[1]
+	allowed, ok := overrideAttrs[kind]
+	if !ok {
+		return update.ConfigError(fmt.Errorf("mod override: unknown override kind %q; expected gazelle, module, or archive", kind))
+	}
+	mc := getModConfig(c)
+	lists := map[string][]string{
+		"build_extra_args": mc.extraArgs,
+		"directives":       mc.directives,
+		"patch_cmds":       mc.patchCmds,
+		"patches":          mc.patches,
+		"urls":             mc.urls,
+	}
+	for attr, values := range lists {
+		if values == nil {
+			delete(lists, attr)
+		} else if !slices.Contains(allowed, attr) {
+			return fmt.Errorf("mod override: %s can't be set on %s_override", attr, kind)
+		}
+	}
+	for attr := range mc.scalarAttrs {
+		if !slices.Contains(allowed, attr) {
+			return fmt.Errorf("mod override: %s can't be set on %s_override", attr, kind)
+		}
+		if mc.remove {
+			return fmt.Errorf("mod override: only list attributes may be given with -remove, but %s was set", attr)
+		}
+	}
+	for _, d := range mc.directives {
+		if !strings.HasPrefix(d, "gazelle:") {
+			return fmt.Errorf("mod override: directive %q must start with \"gazelle:\"", d)
+		}
+	}
+
+	modulePath := filepath.Join(c.RepoRoot, "MODULE.bazel")
+	f, err := rule.LoadModuleFile(modulePath)
+	if err != nil {
+		return err
+	}
+	ident := ""
+	for _, p := range f.ExtensionProxies() {
+		if isGoDepsExtension(c, p) && !p.DevDependency && !p.Isolate {
+			ident = p.Ident
+			break
+		}
+	}
+	if ident == "" {
+		return fmt.Errorf("%s: no go_deps extension usage found", modulePath)
+	}
+
+	tagKind := ident + "." + kind + "_override"
+	var tag *rule.Rule
+	for _, t := range f.Tags(ident) {
+		if t.Kind() == tagKind && t.AttrString("path") == modPath {
+			tag = t
+			break
+		}
+	}
+	if tag != nil && tag.ShouldKeep() {
+		return fmt.Errorf("%s: %s for %s is marked with # keep", modulePath, tagKind, modPath)
+	}
+
+	if mc.remove {
+		if tag == nil {
+			return fmt.Errorf("%s: no %s for %s", modulePath, tagKind, modPath)
+		}
+		if len(lists) == 0 {
+			tag.Delete()
+		}
+		for attr, values := range lists {
+			setOrDeleteList(tag, attr, slices.DeleteFunc(tag.AttrStrings(attr), func(v string) bool {
+				return slices.Contains(values, v)
+			}))
+		}
+		return saveModuleFile(c, f)
+	}
+
+	if tag == nil {
+		tag = rule.NewRule(tagKind, "")
+		tag.SetAttr("path", modPath)
+		f.AddTag(tag)
+	}
+	attrs := make([]string, 0, len(mc.scalarAttrs))
+	for attr := range mc.scalarAttrs {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	for _, attr := range attrs {
+		tag.SetAttr(attr, mc.scalarAttrs[attr])
+	}
+	for attr, values := range lists {
+		list := tag.AttrStrings(attr)
+		for _, v := range values {
+			if !slices.Contains(list, v) {
+				list = append(list, v)
+			}
+		}
+		setOrDeleteList(tag, attr, list)
+	}
+	return saveModuleFile(c, f)
+}
+
+// setOrDeleteList sets a list attribute of r, or deletes it if the list is
+// empty.
+func setOrDeleteList(r *rule.Rule, attr string, list []string) {
+	if len(list) == 0 {
+		r.DelAttr(attr)
+	} else {
+		r.SetAttr(attr, list)
+	}
+}
@@ -0,0 +1,347 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	bzl "github.com/bazelbuild/buildtools/build"
+	"golang.org/x/mod/modfile"
+)
+
+// goModGraph runs "go mod graph" in dir. It's a variable so tests can
+// replace it.
+var goModGraph = func(dir string) ([]byte, error) {
+	goTool := "go"
+	if goroot, ok := os.LookupEnv("GOROOT"); ok {
+		goTool = filepath.Join(goroot, "bin", "go")
+	}
+	if runtime.GOOS == "windows" {
+		goTool += ".exe"
+	}
+	cmd := exec.Command(goTool, "mod", "graph")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running go mod graph: %v\n%s", err, stderr.Bytes())
+	}
+	return out, nil
+}
+
+// modWhy implements "gazelle mod why".
+func modWhy(wd string, args []string) error {
+	c, args, err := newModConfiguration(wd, "why", args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return update.ConfigError(errors.New("mod why: expected module paths or @repository names"))
+	}
+
+	info, err := readGoDepsInfo(c)
+	if err != nil {
+		return err
+	}
+	modPathForRepo := make(map[string]string)
+	for modPath, name := range info.repoNames {
+		modPathForRepo[name] = modPath
+	}
+
+	type target struct{ modPath, repo string }
+	var targets []target
+	for _, arg := range args {
+		var t target
+		if name, ok := strings.CutPrefix(arg, "@"); ok {
+			if t.repo = info.useRepos[name]; t.repo == "" {
+				t.repo = name
+			}
+			if t.modPath = modPathForRepo[t.repo]; t.modPath == "" {
+				t.modPath = findModuleForRepo(info.goModPaths, t.repo)
+			}
+		} else {
+			t.modPath = arg
+			if t.repo = info.repoNames[arg]; t.repo == "" {
+				t.repo = label.ImportPathToBazelRepoName(arg)
+			}
+		}
+		targets = append(targets, t)
+	}
+
+	importers, err := findRepoImporters(c)
+	if err != nil {
+		return err
+	}
+
+	for i, t := range targets {
+		if i > 0 {
+			fmt.Println()
+		}
+		if t.modPath == "" {
+			fmt.Printf("# @%s\n", t.repo)
+			fmt.Println("(no module found for this repository)")
+		} else {
+			fmt.Printf("# %s (@%s)\n", t.modPath, t.repo)
+			var chain []string
+			for _, goModPath := range info.goModPaths {
+				if chain, err = requirementChain(goModPath, t.modPath); err != nil {
+					return err
+				}
+				if chain != nil {
+					break
+				}
+			}
+			if chain == nil {
+				fmt.Printf("(main module does not need module %s)\n", t.modPath)
+			} else {
+				fmt.Println("required through:")
+				for _, m := range chain {
+					fmt.Printf("\t%s\n", m)
+				}
+			}
+		}
+		var rules []string
+		for apparent, repo := range info.useRepos {
+			if repo == t.repo {
+				rules = append(rules, importers[apparent]...)
+			}
+		}
+		if _, ok := info.useRepos[t.repo]; !ok {
+			rules = append(rules, importers[t.repo]...)
+		}
+		sort.Strings(rules)
+		rules = slices.Compact(rules)
+		if len(rules) == 0 {
+			fmt.Println("(no targets depend on this repository)")
+		} else {
+			fmt.Println("depended on by:")
+			for _, r := range rules {
+				fmt.Printf("\t%s\n", r)
+			}
+		}
+	}
+	return nil
+}
+
+// goDepsInfo describes how the main module uses the go_deps extension.
+type goDepsInfo struct {
+	// goModPaths lists the go.mod files imported with go_deps.from_file.
+	goModPaths []string
+
+	// repoNames maps module paths to repository names set with
+	// go_deps.module_override.
+	repoNames map[string]string
+
+	// useRepos maps the apparent names of repositories imported with
+	// use_repo to the names go_deps gives them.
+	useRepos map[string]string
+}
+
+// readGoDepsInfo reads the main module's use of go_deps from MODULE.bazel.
+// Without a MODULE.bazel file or go_deps.from_file tags, the go.mod file in
+// the repository root is used.
+func readGoDepsInfo(c *config.Config) (goDepsInfo, error) {
+	info := goDepsInfo{
+		repoNames: make(map[string]string),
+		useRepos:  make(map[string]string),
+	}
+	modulePath := filepath.Join(c.RepoRoot, "MODULE.bazel")
+	if f, err := rule.LoadModuleFile(modulePath); err == nil {
+		for _, p := range f.ExtensionProxies() {
+			if !isGoDepsExtension(c, p) {
+				continue
+			}
+			for apparent, name := range f.UseRepoMapping(p.Ident) {
+				info.useRepos[apparent] = name
+			}
+			for _, tag := range f.Tags(p.Ident) {
+				switch strings.TrimPrefix(tag.Kind(), p.Ident+".") {
+				case "from_file":
+					if goMod := tag.AttrString("go_mod"); goMod != "" {
+						p, err := mainRepoPath(c, goMod)
+						if err != nil {
+							return goDepsInfo{}, err
+						}
+						info.goModPaths = append(info.goModPaths, p)
+					}
+				case "module_override":
+					if name := tag.AttrString("repo_name"); name != "" {
+						info.repoNames[tag.AttrString("path")] = name
+					}
+				}
+			}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return goDepsInfo{}, err
+	}
+	if len(info.goModPaths) == 0 {
+		info.goModPaths = []string{filepath.Join(c.RepoRoot, "go.mod")}
+	}
+	return info, nil
+}
+
+// findModuleForRepo returns the path of the module required by one of the
+// go.mod files whose default repository name is name, or "" if there is none.
+func findModuleForRepo(goModPaths []string, name string) string {
+	for _, goModPath := range goModPaths {
+		data, err := os.ReadFile(goModPath)
+		if err != nil {
+			continue
+		}
+		mf, err := modfile.ParseLax(goModPath, data, nil)
+		if err != nil {
+			continue
+		}
+		for _, r := range mf.Require {
+			if label.ImportPathToBazelRepoName(r.Mod.Path) == name {
+				return r.Mod.Path
+			}
+		}
+	}
+	return ""
+}
+
+// requirementChain returns the shortest chain of module requirements from
+// the module defined in goModPath to modPath, as reported by "go mod graph".
+// The first element is the main module, and the others are path@version.
+// requirementChain returns nil if the main module doesn't need modPath.
+func requirementChain(goModPath, modPath string) ([]string, error) {
+	data, err := goModGraph(filepath.Dir(goModPath))
+	if err != nil {
+		return nil, err
+	}
+	edges := make(map[string][]string)
+	var main string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		from, to := fields[0], fields[1]
+		if main == "" && !strings.Contains(from, "@") {
+			main = from
+		}
+		edges[from] = append(edges[from], to)
+	}
+	if main == "" {
+		return nil, nil
+	}
+
+	prev := map[string]string{main: ""}
+	queue := []string{main}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if p, _, _ := strings.Cut(node, "@"); p == modPath {
+			var chain []string
+			for n := node; n != ""; n = prev[n] {
+				chain = append(chain, n)
+			}
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+			return chain, nil
+		}
+		for _, next := range edges[node] {
+			if _, ok := prev[next]; !ok {
+				prev[next] = node
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findRepoImporters reads the build files in the main repository and
+// returns the labels of rules with attributes referring to targets in each
+// external repository, by apparent repository name.
+func findRepoImporters(c *config.Config) (map[string][]string, error) {
+	seen := make(map[string]map[string]bool)
+	err := filepath.WalkDir(c.RepoRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); p != c.RepoRoot && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "bazel-")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !c.IsValidBuildFileName(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(c.RepoRoot, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		pkg := filepath.ToSlash(rel)
+		if pkg == "." {
+			pkg = ""
+		}
+		f, err := rule.LoadFile(p, pkg)
+		if err != nil {
+			return err
+		}
+		for _, r := range f.Rules {
+			from := label.New("", pkg, r.Name()).String()
+			for _, key := range r.AttrKeys() {
+				bzl.Walk(r.Attr(key), func(e bzl.Expr, _ []bzl.Expr) {
+					s, ok := e.(*bzl.StringExpr)
+					if !ok || !strings.HasPrefix(s.Value, "@") {
+						return
+					}
+					l, err := label.Parse(s.Value)
+					if err != nil || l.Repo == "" {
+						return
+					}
+					if seen[l.Repo] == nil {
+						seen[l.Repo] = make(map[string]bool)
+					}
+					seen[l.Repo][from] = true
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	importers := make(map[string][]string)
+	for repo, rules := range seen {
+		for r := range rules {
+			importers[repo] = append(importers[repo], r)
+		}
+		sort.Strings(importers[repo])
+	}
+	return importers, nil
+}
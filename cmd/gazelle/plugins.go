@@ -0,0 +1,73 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/language"
+)
+
+// pluginSymbol is the name of the function a language plugin must export.
+// Its type must be func() language.Language.
+const pluginSymbol = "NewLanguage"
+
+// extractPluginsFlag removes -plugins flags from args and returns the paths
+// they list, in order, along with the remaining arguments. Plugins are loaded
+// before other flags are parsed, since they may register flags of their own.
+func extractPluginsFlag(args []string) (paths, rest []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || strings.TrimPrefix(name, "-") != "plugins" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("flag needs an argument: -plugins")
+			}
+			i++
+			value = args[i]
+		}
+		for _, p := range strings.Split(value, ",") {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths, rest, nil
+}
+
+// loadPlugins opens the language plugins at paths and returns the languages
+// they provide. Plugins must be built with -buildmode=plugin from the same
+// Gazelle sources and Go version as this binary.
+func loadPlugins(paths []string) ([]language.Language, error) {
+	var langs []language.Language
+	for _, p := range paths {
+		lang, err := openPlugin(p)
+		if err != nil {
+			return nil, fmt.Errorf("loading plugin %s: %w", p, err)
+		}
+		langs = append(langs, lang)
+	}
+	return langs, nil
+}
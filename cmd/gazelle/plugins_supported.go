@@ -0,0 +1,41 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/bazelbuild/bazel-gazelle/language"
+)
+
+func openPlugin(path string) (language.Language, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(pluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	newLanguage, ok := sym.(func() language.Language)
+	if !ok {
+		return nil, fmt.Errorf("%s has type %T; want func() language.Language", pluginSymbol, sym)
+	}
+	return newLanguage(), nil
+}
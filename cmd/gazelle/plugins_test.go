@@ -0,0 +1,85 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExtractPluginsFlag(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		args      []string
+		wantPaths []string
+		wantRest  []string
+		wantErr   bool
+	}{
+		{
+			desc:     "none",
+			args:     []string{"update", "-go_prefix=example.com/foo"},
+			wantRest: []string{"update", "-go_prefix=example.com/foo"},
+		},
+		{
+			desc:      "equals",
+			args:      []string{"update", "-plugins=a.so,b.so", "-repo_root=."},
+			wantPaths: []string{"a.so", "b.so"},
+			wantRest:  []string{"update", "-repo_root=."},
+		},
+		{
+			desc:      "separate",
+			args:      []string{"--plugins", "a.so", "update-repos", "-plugins=b.so", "example.com/foo"},
+			wantPaths: []string{"a.so", "b.so"},
+			wantRest:  []string{"update-repos", "example.com/foo"},
+		},
+		{
+			desc:     "after double dash",
+			args:     []string{"update", "--", "-plugins=a.so"},
+			wantRest: []string{"update", "--", "-plugins=a.so"},
+		},
+		{
+			desc:    "missing value",
+			args:    []string{"update", "-plugins"},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			paths, rest, err := extractPluginsFlag(tc.args)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("got success; want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantPaths, paths); diff != "" {
+				t.Errorf("paths (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRest, rest); diff != "" {
+				t.Errorf("rest (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadPluginsMissing(t *testing.T) {
+	if _, err := loadPlugins([]string{"does_not_exist.so"}); err == nil {
+		t.Fatal("got success; want error")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+
+	"github.com/bazelbuild/bazel-gazelle/language"
+)
+
+func openPlugin(path string) (language.Language, error) {
+	return nil, errors.New("language plugins aren't supported on this platform, or Gazelle was built without cgo")
+}
@@ -0,0 +1,137 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// resolveImport prints the labels that an import would resolve to and
+// explains why. args are flags, as in "gazelle update", followed by a
+// language name and an import string. The -from flag names the rule that
+// imports the string; it's handled here, not by update.
+func resolveImport(ctx context.Context, wd string, args []string, w io.Writer) error {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		resolveUsage()
+		return flag.ErrHelp
+	}
+	fromStr, args := cutFlag(args, "from")
+	if len(args) < 2 || strings.HasPrefix(args[len(args)-2], "-") || strings.HasPrefix(args[len(args)-1], "-") {
+		resolveUsage()
+		return update.ConfigError(errors.New("resolve: expected a language name and an import string"))
+	}
+	lang, imp := args[len(args)-2], args[len(args)-1]
+	args = args[:len(args)-2]
+
+	from := label.New("", "", "")
+	if fromStr != "" {
+		var err error
+		if from, err = label.Parse(fromStr); err != nil {
+			return update.ConfigError(fmt.Errorf("resolve: -from: %v", err))
+		}
+	}
+
+	// Only the index is needed. Don't recurse from the working directory;
+	// eager indexing still reads the whole repository.
+	index, err := update.LoadIndex(ctx, languages, wd, append([]string{"-r=false"}, args...))
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+	res, err := index.Resolve(lang, imp, from)
+	if err != nil {
+		return err
+	}
+	return writeResolution(w, lang, imp, res)
+}
+
+func writeResolution(w io.Writer, lang, imp string, res update.Resolution) error {
+	var b strings.Builder
+	for _, l := range res.Labels {
+		fmt.Fprintln(&b, l)
+	}
+	switch {
+	case res.Source == update.ResolvedByDirective:
+		b.WriteString("reason: a resolve or resolve_regexp directive matches this import\n")
+	case res.Source == update.ResolvedByIndex && len(res.Labels) == 1:
+		b.WriteString("reason: a rule in the repository provides this import (index hit)\n")
+	case res.Source == update.ResolvedByIndex:
+		fmt.Fprintf(&b, "reason: %d rules in the repository provide this import, so it's ambiguous; use a resolve directive to choose one\n", len(res.Labels))
+	case res.Source == update.ResolvedSelfImport:
+		b.WriteString("reason: only the importing rule provides this import, so no dependency is needed\n")
+	case res.Source == update.ResolvedByLanguage && len(res.Labels) == 0:
+		fmt.Fprintf(&b, "reason: the %s extension reports that no dependency is needed, for example, because this is a standard library import\n", lang)
+	case res.Source == update.ResolvedByLanguage:
+		fmt.Fprintf(&b, "reason: no rule in the repository provides this import, so the %s extension named the library by convention, usually in an external repository\n", lang)
+	default:
+		return fmt.Errorf("resolve: could not resolve %s import %q", lang, imp)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// cutFlag removes the flag with the given name and its value from args,
+// returning the value and the remaining arguments. The flag may be written
+// as -name=value, -name value, or with two dashes.
+func cutFlag(args []string, name string) (value string, rest []string) {
+	rest = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg)
+			continue
+		}
+		n, v, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if n != name {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			v = args[i]
+		}
+		value = v
+	}
+	return value, rest
+}
+
+func resolveUsage() {
+	fmt.Fprint(os.Stderr, `usage: gazelle resolve [flags...] [-from=label] lang import
+
+The resolve command prints the labels of the libraries that an import string
+would resolve to, followed by the reason: a resolve directive, a rule found in
+the index of the repository's build files, or the language extension's own
+logic, like naming conventions for external repositories.
+
+  gazelle resolve go example.com/foo/bar
+  gazelle resolve -from=//cmd/server go example.com/foo/bar
+
+-from names the rule that contains the import. Directives that apply to its
+package are used. It defaults to the repository root package.
+
+Other flags are the same as for "gazelle update". Run "gazelle update -h" for
+a list.
+`)
+}
@@ -0,0 +1,113 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveImport(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "go.mod", Content: "module example.com/repo\n\nrequire github.com/pkg/errors v0.9.1\n"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:resolve go example.com/other //third_party:other
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+)
+`,
+		},
+		{
+			Path: "dup/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "a",
+    importpath = "example.com/repo/dup",
+)
+
+go_library(
+    name = "b",
+    importpath = "example.com/repo/dup",
+)
+`,
+		},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want string
+	}{
+		{
+			desc: "index",
+			args: []string{"go", "example.com/repo/lib"},
+			want: "//lib\nreason: a rule in the repository provides this import (index hit)\n",
+		},
+		{
+			desc: "ambiguous",
+			args: []string{"go", "example.com/repo/dup"},
+			want: "//dup:a\n//dup:b\nreason: 2 rules in the repository provide this import, so it's ambiguous; use a resolve directive to choose one\n",
+		},
+		{
+			desc: "directive",
+			args: []string{"-from=//lib", "go", "example.com/other"},
+			want: "//third_party:other\nreason: a resolve or resolve_regexp directive matches this import\n",
+		},
+		{
+			desc: "self",
+			args: []string{"-from", "//lib", "go", "example.com/repo/lib"},
+			want: "reason: only the importing rule provides this import, so no dependency is needed\n",
+		},
+		{
+			desc: "external",
+			args: []string{"-go_naming_convention_external=import", "go", "github.com/pkg/errors"},
+			want: "@com_github_pkg_errors//:errors\nreason: no rule in the repository provides this import, so the go extension named the library by convention, usually in an external repository\n",
+		},
+		{
+			desc: "std",
+			args: []string{"go", "fmt"},
+			want: "reason: the go extension reports that no dependency is needed, for example, because this is a standard library import\n",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := resolveImport(context.Background(), dir, tc.args, &out); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, out.String()); diff != "" {
+				t.Errorf("output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// JSON-RPC 2.0 error codes used by the server.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type updateParams struct {
+	// Dirs lists directories to update, either absolute or relative to the
+	// server's working directory.
+	Dirs []string `json:"dirs"`
+}
+
+type updateResult struct {
+	// Dirs lists the directories that were updated.
+	Dirs []string `json:"dirs"`
+}
+
+type resolveParams struct {
+	Lang   string `json:"lang"`
+	Import string `json:"import"`
+
+	// From is the label of the rule that imports Import. It may be omitted
+	// for imports from the repository root package.
+	From string `json:"from"`
+}
+
+type resolveResult struct {
+	Labels []string `json:"labels"`
+	Source string   `json:"source"`
+}
+
+// server answers JSON-RPC requests from an editor or other tool.
+type server struct {
+	wd   string
+	args []string
+
+	// sess keeps the configuration and rule index in memory between
+	// requests. The first request indexes the whole repository. Later
+	// updates only visit and re-index the requested directories.
+	sess *update.Session
+
+	// index answers resolve requests with the state in sess. It's discarded
+	// before each update, which changes that state.
+	index *update.Index
+}
+
+// serve runs a JSON-RPC 2.0 server that reads one request per line from
+// stdin and writes one response per line to stdout, until stdin is closed
+// or a shutdown request arrives. args are flags applied to every update
+// and to indexing, as in "gazelle update".
+func serve(ctx context.Context, wd string, args []string) error {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		serverUsage()
+		return flag.ErrHelp
+	}
+
+	// Output from updates, for example, with -mode=print, goes to stderr.
+	// Stdout is kept for responses.
+	ctx = update.WithOutput(ctx, os.Stderr)
+
+	s := &server{wd: wd, args: args, sess: update.NewSession()}
+	defer s.closeIndex()
+	return s.serve(ctx, os.Stdin, os.Stdout)
+}
+
+func (s *server) serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx = update.WithSession(ctx, s.sess)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		var resp rpcResponse
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+		} else {
+			resp.ID = req.ID
+			resp.Result, resp.Error = s.handle(ctx, req)
+		}
+		// Notifications (requests without IDs) don't get responses unless
+		// they're invalid.
+		if req.ID != nil || resp.Error != nil {
+			resp.JSONRPC = "2.0"
+			if resp.ID == nil {
+				resp.ID = json.RawMessage("null")
+			}
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+		}
+		if req.Method == "shutdown" {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *server) handle(ctx context.Context, req rpcRequest) (any, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: `jsonrpc must be "2.0"`}
+	}
+	switch req.Method {
+	case "update":
+		var params updateParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params.Dirs) == 0 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "update expects {\"dirs\": [...]}"}
+		}
+		dirs := make([]string, len(params.Dirs))
+		for i, dir := range params.Dirs {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(s.wd, dir)
+			}
+			dirs[i] = dir
+			s.sess.Invalidate(dir)
+		}
+		s.closeIndex()
+		if err := update.Run(ctx, languages, s.wd, incrementalArgs(s.args, dirs)); err != nil && !errors.Is(err, update.ErrDiff) {
+			return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+		}
+		return updateResult{Dirs: params.Dirs}, nil
+
+	case "resolve":
+		var params resolveParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Lang == "" || params.Import == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "resolve expects {\"lang\": ..., \"import\": ..., \"from\": ...}"}
+		}
+		from := label.New("", "", "")
+		if params.From != "" {
+			var err error
+			if from, err = label.Parse(params.From); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+		}
+		if s.index == nil {
+			s.index = s.sess.Index()
+		}
+		if s.index == nil {
+			index, err := update.LoadIndex(ctx, languages, s.wd, s.args)
+			if err != nil {
+				return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+			}
+			s.index = index
+		}
+		res, err := s.index.Resolve(params.Lang, params.Import, from)
+		if err != nil {
+			return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+		}
+		result := resolveResult{Labels: []string{}, Source: res.Source}
+		for _, l := range res.Labels {
+			result.Labels = append(result.Labels, l.String())
+		}
+		return result, nil
+
+	case "shutdown":
+		return struct{}{}, nil
+
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+func (s *server) closeIndex() {
+	if s.index == nil {
+		return
+	}
+	if err := s.index.Close(); err != nil {
+		log.Print(err)
+	}
+	s.index = nil
+}
+
+func serverUsage() {
+	fmt.Fprint(os.Stderr, `usage: gazelle server [flags...]
+
+The server command speaks JSON-RPC 2.0 on stdin and stdout, one message per
+line, so that editors and other tools can ask Gazelle to do work without
+starting a new process each time. Flags are the same as for "gazelle update"
+and apply to every request. Output from updates, for example, with
+-mode=print, is written to stderr.
+
+The configuration and index of existing build files are read once and kept in
+memory. Each update re-indexes the directories it updates, so the index stays
+current as long as build files are only changed through the server.
+
+Methods:
+
+  update {"dirs": ["path/to/dir", ...]}
+      Updates build files in the given directories, without recursion.
+      Returns {"dirs": [...]}.
+
+  resolve {"lang": "go", "import": "example.com/foo", "from": "//pkg:target"}
+      Finds the rules that provide an import, using resolve directives, the
+      index of existing build files, and the language extension. Returns {"labels": [...],
+      "source": "directive"|"index"|"language"|"self"|""}.
+
+  shutdown {}
+      Stops the server.
+`)
+}
@@ -0,0 +1,132 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServer(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:prefix example.com/repo
+# gazelle:resolve go example.com/other //third_party:other
+`,
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["lib.go"],
+    importpath = "example.com/repo/lib",
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{Path: "lib/lib.go", Content: "package lib\n"},
+		{Path: "cmd/main.go", Content: "package main\n\nimport _ \"example.com/repo/lib\"\n"},
+		{Path: "pkg/pkg.go", Content: "package pkg\n"},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	in := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "resolve", "params": {"lang": "go", "import": "example.com/repo/lib", "from": "//cmd"}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "resolve", "params": {"lang": "go", "import": "example.com/other"}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "resolve", "params": {"lang": "go", "import": "example.com/repo/lib", "from": "//lib"}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "update", "params": {"dirs": ["cmd"]}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "update", "params": {"dirs": ["pkg"]}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "resolve", "params": {"lang": "go", "import": "example.com/repo/pkg", "from": "//cmd"}}`,
+		`{"jsonrpc": "2.0", "id": 7, "method": "bogus"}`,
+		`{"jsonrpc": "2.0", "id": 8, "method": "shutdown"}`,
+		`{"jsonrpc": "2.0", "id": 9, "method": "shutdown"}`,
+	}, "\n")
+	var out, printed bytes.Buffer
+	s := &server{wd: dir, args: []string{"-go_naming_convention=import"}, sess: update.NewSession()}
+	defer s.closeIndex()
+	ctx := update.WithOutput(context.Background(), &printed)
+	if err := s.serve(ctx, strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"jsonrpc":"2.0","id":1,"result":{"labels":["//lib"],"source":"index"}}
+{"jsonrpc":"2.0","id":2,"result":{"labels":["//third_party:other"],"source":"directive"}}
+{"jsonrpc":"2.0","id":3,"result":{"labels":[],"source":"self"}}
+{"jsonrpc":"2.0","id":4,"result":{"dirs":["cmd"]}}
+{"jsonrpc":"2.0","id":5,"result":{"dirs":["pkg"]}}
+{"jsonrpc":"2.0","id":6,"result":{"labels":["//pkg"],"source":"index"}}
+{"jsonrpc":"2.0","id":7,"error":{"code":-32601,"message":"unknown method \"bogus\""}}
+{"jsonrpc":"2.0","id":8,"result":{}}
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("responses (-want +got):\n%s", diff)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "cmd/BUILD.bazel",
+		Content: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "cmd_lib",
+    srcs = ["main.go"],
+    importpath = "example.com/repo/cmd",
+    visibility = ["//visibility:public"],
+    deps = ["//lib"],
+)
+`,
+	}})
+	if printed.Len() != 0 {
+		t.Errorf("unexpected output from updates:\n%s", printed.String())
+	}
+}
+
+func TestServerOutput(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "BUILD.bazel", Content: "# gazelle:prefix example.com/repo\n"},
+		{Path: "lib/lib.go", Content: "package lib\n"},
+	})
+	defer cleanup()
+
+	in := `{"jsonrpc": "2.0", "id": 1, "method": "update", "params": {"dirs": ["lib"]}}`
+	var out, printed bytes.Buffer
+	s := &server{wd: dir, args: []string{"-mode=print"}, sess: update.NewSession()}
+	defer s.closeIndex()
+	ctx := update.WithOutput(context.Background(), &printed)
+	if err := s.serve(ctx, strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"jsonrpc":"2.0","id":1,"result":{"dirs":["lib"]}}
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("responses (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(printed.String(), `name = "lib"`) {
+		t.Errorf("printed build file missing from output:\n%s", printed.String())
+	}
+}
@@ -20,13 +20,18 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	gzflag "github.com/bazel-contrib/bazel-gazelle/v2/flag"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/internal/wspace"
 	"github.com/bazelbuild/bazel-gazelle/label"
@@ -34,6 +39,7 @@ import (
 	"github.com/bazelbuild/bazel-gazelle/merger"
 	"github.com/bazelbuild/bazel-gazelle/repo"
 	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bmatcuk/doublestar/v4"
 )
 
 type updateReposConfig struct {
@@ -41,7 +47,16 @@ type updateReposConfig struct {
 	importPaths   []string
 	macroFileName string
 	macroDefName  string
+	macroShard    string
+	macroShards   int
 	pruneRules    bool
+	excludes      []string
+	updateRules   []rulesUpdate
+	registry      string
+	lockFile      string
+	cacheDir      string
+	cacheTTL      time.Duration
+	redirectsPath string
 	workspace     *rule.File
 	repoFileMap   map[string]*rule.File
 	cpuProfile    string
@@ -65,18 +80,65 @@ type macroFlag struct {
 }
 
 func (f macroFlag) Set(value string) error {
-	args := strings.Split(value, "%")
-	if len(args) != 2 {
+	i := strings.LastIndex(value, "%")
+	if i < 0 || strings.Contains(strings.ReplaceAll(value[:i], shardPlaceholder, ""), "%") {
 		return fmt.Errorf("Failure parsing to_macro: %s, expected format is macroFile%%defName", value)
 	}
-	if strings.HasPrefix(args[0], "..") {
-		return fmt.Errorf("Failure parsing to_macro: %s, macro file path %s should not start with \"..\"", value, args[0])
+	fileName, defName := value[:i], value[i+1:]
+	if strings.HasPrefix(fileName, "..") {
+		return fmt.Errorf("Failure parsing to_macro: %s, macro file path %s should not start with \"..\"", value, fileName)
 	}
-	*f.macroFileName = args[0]
-	*f.macroDefName = args[1]
+	*f.macroFileName = fileName
+	*f.macroDefName = defName
 	return nil
 }
 
+// shardPlaceholder may appear in the macro file path given with -to_macro.
+// New repository rules are then split across several macro files, with the
+// placeholder replaced by each rule's shard, chosen with -to_macro_shard.
+const shardPlaceholder = "%shard"
+
+// macroFileNameForRule returns the macro file that a new repository rule
+// with the given name should be written to.
+func macroFileNameForRule(uc *updateReposConfig, name string) string {
+	if !strings.Contains(uc.macroFileName, shardPlaceholder) {
+		return uc.macroFileName
+	}
+	return strings.ReplaceAll(uc.macroFileName, shardPlaceholder, macroShard(uc, name))
+}
+
+// macroShard returns the shard for a repository rule with the given name.
+// With -to_macro_shard=letter, this is the first letter of the name, or "_"
+// if it doesn't start with a letter. With -to_macro_shard=hash, this is a
+// hash of the name modulo -to_macro_shards, zero-padded so shards sort in
+// order.
+func macroShard(uc *updateReposConfig, name string) string {
+	if uc.macroShard == "hash" {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		width := len(strconv.Itoa(uc.macroShards - 1))
+		return fmt.Sprintf("%0*d", width, h.Sum32()%uint32(uc.macroShards))
+	}
+	if name != "" {
+		if c := unicode.ToLower(rune(name[0])); 'a' <= c && c <= 'z' {
+			return string(c)
+		}
+	}
+	return "_"
+}
+
+// macroLoadName returns the name a macro defined in macroFileName is loaded
+// as in WORKSPACE. Sharded macros all have the same name, so each is loaded
+// with its shard as a suffix, like "go_deps_a".
+func macroLoadName(uc *updateReposConfig, macroFileName string) string {
+	if macroFileName == uc.macroFileName {
+		return uc.macroDefName
+	}
+	prefix, suffix, _ := strings.Cut(uc.macroFileName, shardPlaceholder)
+	shard := strings.TrimSuffix(strings.TrimPrefix(macroFileName, prefix), suffix)
+	return uc.macroDefName + "_" + shard
+}
+
 func (f macroFlag) String() string {
 	return ""
 }
@@ -84,9 +146,21 @@ func (f macroFlag) String() string {
 func (*updateReposConfigurer) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config) {
 	uc := &updateReposConfig{}
 	c.Exts[updateReposName] = uc
-	fs.StringVar(&uc.repoFilePath, "from_file", "", "Gazelle will translate repositories listed in this file into repository rules in WORKSPACE or a .bzl macro function. Gopkg.lock and go.mod files are supported")
+	fs.StringVar(&uc.repoFilePath, "from_file", "", "Gazelle will translate repositories listed in this file into repository rules in WORKSPACE or a .bzl macro function. go.mod and go.work files and .json and .yaml dependency manifests are supported")
 	fs.Var(macroFlag{macroFileName: &uc.macroFileName, macroDefName: &uc.macroDefName}, "to_macro", "Tells Gazelle to write repository rules into a .bzl macro function rather than the WORKSPACE file. . The expected format is: macroFile%defName")
+	fs.StringVar(&uc.macroShard, "to_macro_shard", "letter", "when the -to_macro file path contains %shard, how new repository rules are split across macro files: letter (by the first letter of the rule name) or hash (by a hash of the rule name, into -to_macro_shards files)")
+	fs.IntVar(&uc.macroShards, "to_macro_shards", 16, "the number of macro files new repository rules are split across with -to_macro_shard=hash")
 	fs.BoolVar(&uc.pruneRules, "prune", false, "When enabled, Gazelle will remove rules that no longer have equivalent repos in the go.mod file. Can only used with -from_file.")
+	fs.Var(&gzflag.MultiFlag{Values: &uc.excludes}, "exclude_module", "a module path pattern, like github.com/internal/**, for modules that are provided by other repository rules. Matching go_repository rules are not generated and are removed from WORKSPACE and macro files (can specify multiple times)")
+	fs.Func("update_rules", "a comma-separated list of rules to update to a release, like rules_go@v0.50.1,gazelle. Without a version, the latest release is used. Updates bazel_dep calls in MODULE.bazel and http_archive rules in WORKSPACE", func(v string) (err error) {
+		uc.updateRules, err = parseUpdateRules(v)
+		return err
+	})
+	fs.StringVar(&uc.registry, "update_rules_registry", defaultRegistry, "the Bazel registry where releases of rules named with -update_rules are looked up")
+	fs.StringVar(&uc.cacheDir, "remote_cache_dir", "", "directory where lookups of remote repositories and modules are persisted when -remote_cache_ttl is set. Defaults to gazelle/remote in the user cache directory")
+	fs.DurationVar(&uc.cacheTTL, "remote_cache_ttl", 0, "when positive, lookups of remote repositories and modules are persisted on disk and reused by later runs for this long")
+	fs.StringVar(&uc.redirectsPath, "remote_redirects", "", "JSON file with rules mapping import path prefixes to remote repositories, used instead of looking them up over the network")
+	fs.StringVar(&uc.lockFile, "lock_file", "", "a JSON file, relative to the repository root, where repository metadata looked up over the network is recorded. Metadata in this file is reused instead of being looked up again")
 
 	fs.StringVar(&uc.cpuProfile, "cpuprofile", "", "write cpu profile to `file`")
 	fs.StringVar(&uc.memProfile, "memprofile", "", "write memory profile to `file`")
@@ -100,6 +174,38 @@ func (*updateReposConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) err
 	}
 	uc.profile = p
 
+	if uc.macroShard != "letter" && uc.macroShard != "hash" {
+		return fmt.Errorf("unrecognized -to_macro_shard: %q", uc.macroShard)
+	}
+	if uc.macroShards <= 0 {
+		return fmt.Errorf("-to_macro_shards must be positive")
+	}
+
+	for _, pattern := range uc.excludes {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid -exclude_module pattern: %q", pattern)
+		}
+	}
+
+	if uc.cacheTTL < 0 {
+		return fmt.Errorf("-remote_cache_ttl must not be negative")
+	}
+	if uc.cacheTTL > 0 {
+		if uc.cacheDir == "" {
+			if uc.cacheDir, err = repo.DefaultDiskCacheDir(); err != nil {
+				return fmt.Errorf("-remote_cache_ttl set but -remote_cache_dir is not: %v", err)
+			}
+		} else if !filepath.IsAbs(uc.cacheDir) {
+			uc.cacheDir = filepath.Join(c.WorkDir, uc.cacheDir)
+		}
+	}
+	if uc.redirectsPath != "" && !filepath.IsAbs(uc.redirectsPath) {
+		uc.redirectsPath = filepath.Join(c.WorkDir, uc.redirectsPath)
+	}
+	if uc.lockFile != "" && !filepath.IsAbs(uc.lockFile) {
+		uc.lockFile = filepath.Join(c.RepoRoot, uc.lockFile)
+	}
+
 	switch {
 	case uc.repoFilePath != "":
 		if len(fs.Args()) != 0 {
@@ -110,7 +216,7 @@ func (*updateReposConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) err
 		}
 
 	default:
-		if len(fs.Args()) == 0 {
+		if len(fs.Args()) == 0 && len(uc.updateRules) == 0 {
 			return fmt.Errorf("no repositories specified\nTry -help for more information.")
 		}
 		if uc.pruneRules {
@@ -124,6 +230,10 @@ func (*updateReposConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) err
 	if err != nil {
 		if c.Bzlmod {
 			return nil
+		} else if os.IsNotExist(err) && wspace.IsWorkspaceless(c.RepoRoot) {
+			// Repositories are declared in MODULE.bazel, as with -bzlmod.
+			c.Bzlmod = true
+			return nil
 		} else {
 			return fmt.Errorf("loading WORKSPACE file: %v", err)
 		}
@@ -191,16 +301,44 @@ func updateRepos(wd string, args []string) (err error) {
 			})
 		}
 	}
+	if len(uc.updateRules) > 0 {
+		if err := updateRulesVersions(uc, c.RepoRoot); err != nil {
+			return err
+		}
+		if uc.repoFilePath == "" && len(uc.importPaths) == 0 {
+			return nil
+		}
+	}
+
 	rc, cleanup := repo.NewRemoteCache(knownRepos)
 	defer func() {
 		if cerr := cleanup(); err == nil && cerr != nil {
 			err = cerr
 		}
 	}()
+	if uc.cacheTTL > 0 {
+		rc.UseDiskCache(uc.cacheDir, uc.cacheTTL)
+	}
+	if uc.redirectsPath != "" {
+		redirects, err := repo.LoadRedirects(uc.redirectsPath)
+		if err != nil {
+			return err
+		}
+		rc.UseRedirects(redirects)
+	}
+	if uc.lockFile != "" {
+		lock, err := repo.LoadLock(uc.lockFile)
+		if err != nil {
+			return err
+		}
+		rc.UseLock(lock)
+	}
 
 	// Fix the workspace file with each language.
-	for _, lang := range filterLanguages(c, languages) {
-		lang.Fix(c, uc.workspace)
+	if uc.workspace != nil {
+		for _, lang := range filterLanguages(c, languages) {
+			lang.Fix(c, uc.workspace)
+		}
 	}
 
 	// Generate rules from command language arguments or by importing a file.
@@ -213,6 +351,15 @@ func updateRepos(wd string, args []string) (err error) {
 	if err != nil {
 		return err
 	}
+	gen, empty = excludeModules(c, uc, gen, empty)
+
+	// In bzlmod mode, languages may declare the generated repositories as
+	// module extension tags in MODULE.bazel.
+	if c.Bzlmod {
+		if err := updateModuleFile(c, gen); err != nil {
+			return err
+		}
+	}
 
 	// Organize generated and empty rules by file. A rule should go into the file
 	// it came from (by name). New rules should go into WORKSPACE or the file
@@ -251,44 +398,41 @@ func updateRepos(wd string, args []string) (err error) {
 		emptyForFiles[f] = append(emptyForFiles[f], r)
 	}
 
-	var macroPath string
-	if uc.macroFileName != "" {
-		macroPath = filepath.Join(c.RepoRoot, filepath.Clean(uc.macroFileName))
-	}
 	// If we are in bzlmod mode, then do not update the workspace. However, if a macro file was
 	// specified, proceed with generating the macro file. This is useful for rule repositories that
 	// build with bzlmod enabled, but support clients that use legacy WORKSPACE dependency loading.
-	if !c.Bzlmod || macroPath != "" {
-		var newGenFile *rule.File
-		for f := range genForFiles {
-			if macroPath == "" && wspace.IsWORKSPACE(f.Path) ||
-				macroPath != "" && f.Path == macroPath && f.DefName == uc.macroDefName {
-				newGenFile = f
-				break
+	// If the macro file is sharded, only shards with new rules are created.
+	var macroFileNames []string
+	if !c.Bzlmod || uc.macroFileName != "" {
+		newGenByFileName := make(map[string][]*rule.Rule)
+		if !strings.Contains(uc.macroFileName, shardPlaceholder) {
+			newGenByFileName[uc.macroFileName] = newGen
+		} else {
+			for _, r := range newGen {
+				fileName := macroFileNameForRule(uc, r.Name())
+				newGenByFileName[fileName] = append(newGenByFileName[fileName], r)
 			}
 		}
-		if newGenFile == nil {
-			if uc.macroFileName == "" {
-				newGenFile = uc.workspace
-			} else {
-				var err error
-				newGenFile, err = rule.LoadMacroFile(macroPath, "", uc.macroDefName)
-				if os.IsNotExist(err) {
-					newGenFile, err = rule.EmptyMacroFile(macroPath, "", uc.macroDefName)
-					if err != nil {
-						return fmt.Errorf("error creating %q: %v", macroPath, err)
-					}
-				} else if err != nil {
-					return fmt.Errorf("error loading %q: %v", macroPath, err)
-				}
+		for fileName := range newGenByFileName {
+			macroFileNames = append(macroFileNames, fileName)
+		}
+		sort.Strings(macroFileNames)
+		for _, fileName := range macroFileNames {
+			newGenFile, err := findNewGenFile(c, uc, genForFiles, fileName)
+			if err != nil {
+				return err
 			}
+			genForFiles[newGenFile] = append(genForFiles[newGenFile], newGenByFileName[fileName]...)
 		}
-		genForFiles[newGenFile] = append(genForFiles[newGenFile], newGen...)
 	}
 
-	workspaceInsertIndex := findWorkspaceInsertIndex(uc.workspace, kinds, loads)
-	for _, r := range genForFiles[uc.workspace] {
-		r.SetPrivateAttr(merger.UnstableInsertIndexKey, workspaceInsertIndex)
+	// In bzlmod mode, there may not be a WORKSPACE file.
+	var workspaceInsertIndex int
+	if uc.workspace != nil {
+		workspaceInsertIndex = findWorkspaceInsertIndex(uc.workspace, kinds, loads)
+		for _, r := range genForFiles[uc.workspace] {
+			r.SetPrivateAttr(merger.UnstableInsertIndexKey, workspaceInsertIndex)
+		}
 	}
 
 	// Merge rules and fix loads in each file.
@@ -307,10 +451,12 @@ func updateRepos(wd string, args []string) (err error) {
 		}
 	}
 	// If we are in bzlmod mode, then do not update the workspace.
-	if !c.Bzlmod && ensureMacroInWorkspace(uc, workspaceInsertIndex) {
-		if !seenFile[uc.workspace] {
-			seenFile[uc.workspace] = true
-			sortedFiles = append(sortedFiles, uc.workspace)
+	if !c.Bzlmod {
+		for _, fileName := range macroFileNames {
+			if ensureMacroInWorkspace(uc, fileName, workspaceInsertIndex) && !seenFile[uc.workspace] {
+				seenFile[uc.workspace] = true
+				sortedFiles = append(sortedFiles, uc.workspace)
+			}
 		}
 	}
 	sort.Slice(sortedFiles, func(i, j int) bool {
@@ -356,9 +502,107 @@ func updateRepos(wd string, args []string) (err error) {
 		}
 	}
 
+	if uc.lockFile != "" {
+		return saveLock(uc.lockFile, rc, gen)
+	}
 	return nil
 }
 
+// excludeModules drops generated rules for modules matching an
+// -exclude_module pattern. Existing go_repository rules for those modules are
+// added to empty so they are removed from the files that declare them.
+func excludeModules(c *config.Config, uc *updateReposConfig, gen, empty []*rule.Rule) ([]*rule.Rule, []*rule.Rule) {
+	if len(uc.excludes) == 0 {
+		return gen, empty
+	}
+	isExcluded := func(r *rule.Rule) bool {
+		if r.Kind() != "go_repository" {
+			return false
+		}
+		importPath := r.AttrString("importpath")
+		for _, pattern := range uc.excludes {
+			if doublestar.MatchUnvalidated(pattern, importPath) {
+				return true
+			}
+		}
+		return false
+	}
+
+	kept := gen[:0]
+	for _, r := range gen {
+		if !isExcluded(r) {
+			kept = append(kept, r)
+		}
+	}
+
+	emptyNames := make(map[string]bool)
+	for _, r := range empty {
+		emptyNames[r.Name()] = true
+	}
+	for _, r := range c.Repos {
+		name := r.Name()
+		if !isExcluded(r) || emptyNames[name] || uc.repoFileMap[name] == nil {
+			continue
+		}
+		emptyNames[name] = true
+		empty = append(empty, rule.NewRule("go_repository", name))
+	}
+	return kept, empty
+}
+
+// saveLock writes the metadata rc looked up or read from the lock file to
+// lockFile, together with the sums of the generated go_repository rules.
+func saveLock(lockFile string, rc *repo.RemoteCache, gen []*rule.Rule) error {
+	lock := rc.Lock()
+	for _, r := range gen {
+		if r.Kind() != "go_repository" {
+			continue
+		}
+		modPath := r.AttrString("replace")
+		if modPath == "" {
+			modPath = r.AttrString("importpath")
+		}
+		version, sum := r.AttrString("version"), r.AttrString("sum")
+		if modPath != "" && version != "" && sum != "" {
+			lock.Sums[modPath+"@"+version] = sum
+		}
+	}
+	return lock.Save(lockFile)
+}
+
+// findNewGenFile returns the file new repository rules should be added to:
+// the macro file macroFileName, or WORKSPACE if macroFileName is empty.
+// Files in genForFiles are preferred, since they may already have rules to
+// merge. Otherwise, the macro file is loaded, or created if it doesn't exist.
+func findNewGenFile(c *config.Config, uc *updateReposConfig, genForFiles map[*rule.File][]*rule.Rule, macroFileName string) (*rule.File, error) {
+	var macroPath string
+	if macroFileName != "" {
+		macroPath = filepath.Join(c.RepoRoot, filepath.Clean(macroFileName))
+	}
+	for f := range genForFiles {
+		if macroPath == "" && wspace.IsWORKSPACE(f.Path) ||
+			macroPath != "" && f.Path == macroPath && f.DefName == uc.macroDefName {
+			return f, nil
+		}
+	}
+	if macroPath == "" {
+		return uc.workspace, nil
+	}
+	f, err := rule.LoadMacroFile(macroPath, "", uc.macroDefName)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(macroPath), 0o777); err != nil {
+			return nil, fmt.Errorf("error creating %q: %v", macroPath, err)
+		}
+		f, err = rule.EmptyMacroFile(macroPath, "", uc.macroDefName)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %q: %v", macroPath, err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error loading %q: %v", macroPath, err)
+	}
+	return f, nil
+}
+
 func newUpdateReposConfiguration(wd string, args []string, cexts []config.Configurer) (*config.Config, error) {
 	c := config.New()
 	c.WorkDir = wd
@@ -366,8 +610,8 @@ func newUpdateReposConfiguration(wd string, args []string, cexts []config.Config
 	// Flag will call this on any parse error. Don't print usage unless
 	// -h or -help were passed explicitly.
 	fs.Usage = func() {}
-	for _, cext := range cexts {
-		cext.RegisterFlags(fs, "update-repos", c)
+	if err := update.RegisterFlags(fs, "update-repos", c, cexts); err != nil {
+		return nil, err
 	}
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -375,11 +619,11 @@ func newUpdateReposConfiguration(wd string, args []string, cexts []config.Config
 			return nil, err
 		}
 		// flag already prints the error; don't print it again.
-		return nil, errors.New("Try -help for more information")
+		return nil, update.ConfigError(errors.New("Try -help for more information"))
 	}
 	for _, cext := range cexts {
 		if err := cext.CheckFlags(fs, c); err != nil {
-			return nil, err
+			return nil, update.ConfigError(err)
 		}
 	}
 	return c, nil
@@ -394,10 +638,15 @@ gazelle update-repos example.com/repo1 example.com/repo2
 # Import repositories from lock file
 gazelle update-repos -from_file=file
 
+# Update rules_go and Gazelle to the latest releases
+gazelle update-repos -update_rules=rules_go,gazelle
+
 The update-repos command updates repository rules in the WORKSPACE file.
 update-repos can add or update repositories explicitly by import path.
 update-repos can also import repository rules from a vendoring tool's lock
-file (currently only deps' Gopkg.lock is supported).
+file (currently only deps' Gopkg.lock is supported). With -update_rules, it
+updates the versions of rules_go and Gazelle declared in MODULE.bazel and
+WORKSPACE.
 
 FLAGS:
 
@@ -523,24 +772,24 @@ func findWorkspaceInsertIndex(f *rule.File, kinds map[string]rule.KindInfo, load
 	return insertBefore
 }
 
-// ensureMacroInWorkspace adds a call to the repository macro if the -to_macro
-// flag was used, and the macro was not called or declared with a
-// '# gazelle:repository_macro' directive.
+// ensureMacroInWorkspace adds a call to the repository macro in
+// macroFileName if the -to_macro flag was used, and the macro was not called
+// or declared with a '# gazelle:repository_macro' directive.
 //
 // ensureMacroInWorkspace returns true if the WORKSPACE file was updated
 // and should be saved.
-func ensureMacroInWorkspace(uc *updateReposConfig, insertIndex int) (updated bool) {
-	if uc.macroFileName == "" {
+func ensureMacroInWorkspace(uc *updateReposConfig, macroFileName string, insertIndex int) (updated bool) {
+	if macroFileName == "" {
 		return false
 	}
 
 	// Check whether the macro is already declared.
 	// We won't add a call if the macro is declared but not called. It might
 	// be called somewhere else.
-	macroValue := uc.macroFileName + "%" + uc.macroDefName
+	macroValue := macroFileName + "%" + uc.macroDefName
 	for _, d := range uc.workspace.Directives {
 		if d.Key == "repository_macro" {
-			if parsed, _ := repo.ParseRepositoryMacroDirective(d.Value); parsed != nil && parsed.Path == uc.macroFileName && parsed.DefName == uc.macroDefName {
+			if parsed, _ := repo.ParseRepositoryMacroDirective(d.Value); parsed != nil && parsed.Path == macroFileName && parsed.DefName == uc.macroDefName {
 				return false
 			}
 		}
@@ -552,7 +801,7 @@ func ensureMacroInWorkspace(uc *updateReposConfig, insertIndex int) (updated boo
 	var loadedDefName string
 	for _, l := range uc.workspace.Loads {
 		switch l.Name() {
-		case ":" + uc.macroFileName, "//:" + uc.macroFileName, "@//:" + uc.macroFileName:
+		case ":" + macroFileName, "//:" + macroFileName, "@//:" + macroFileName:
 			load = l
 			pairs := l.SymbolPairs()
 			for _, pair := range pairs {
@@ -572,14 +821,19 @@ func ensureMacroInWorkspace(uc *updateReposConfig, insertIndex int) (updated boo
 	// Add the load and call if they're missing.
 	if call == nil {
 		if load == nil {
-			load = rule.NewLoad("//:" + uc.macroFileName)
+			load = rule.NewLoad("//:" + macroFileName)
 			load.Insert(uc.workspace, insertIndex)
 		}
 		if loadedDefName == "" {
-			load.Add(uc.macroDefName)
+			loadedDefName = macroLoadName(uc, macroFileName)
+			if loadedDefName == uc.macroDefName {
+				load.Add(uc.macroDefName)
+			} else {
+				load.AddAlias(uc.macroDefName, loadedDefName)
+			}
 		}
 
-		call = rule.NewRule(uc.macroDefName, "")
+		call = rule.NewRule(loadedDefName, "")
 		call.InsertAt(uc.workspace, insertIndex)
 	}
 
@@ -0,0 +1,106 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	v2rule "github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// updateModuleFile passes the repository rules generated by update-repos to
+// each language that implements language.ModuleUpdater, then adds the
+// extension tags and use_repo entries they return to MODULE.bazel. It does
+// nothing if the main repository doesn't have a MODULE.bazel file.
+func updateModuleFile(c *config.Config, gen []*rule.Rule) error {
+	f, err := v2rule.LoadModuleFile(filepath.Join(c.RepoRoot, "MODULE.bazel"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, lang := range filterLanguages(c, languages) {
+		updater, ok := lang.(language.ModuleUpdater)
+		if !ok {
+			continue
+		}
+		kinds := lang.Kinds()
+		var langGen []*rule.Rule
+		for _, r := range gen {
+			if _, ok := kinds[r.Kind()]; ok {
+				langGen = append(langGen, r)
+			}
+		}
+		if len(langGen) == 0 {
+			continue
+		}
+		res := updater.UpdateModule(language.UpdateModuleArgs{
+			Config: c,
+			Gen:    langGen,
+			File:   f,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		for _, u := range res.Extensions {
+			applyExtensionUpdate(f, u)
+		}
+	}
+
+	if string(f.Format()) == string(f.Content) {
+		return nil
+	}
+	return f.Save(f.Path)
+}
+
+// applyExtensionUpdate adds the tags and repositories in u to the usage of
+// u's extension in f, declaring the usage if needed.
+func applyExtensionUpdate(f *v2rule.ModuleFile, u language.ExtensionUpdate) {
+	if len(u.Tags) == 0 && len(u.UseRepos) == 0 {
+		return
+	}
+	ident := f.UseExtension(u.BzlFile, u.Name, u.DevDependency)
+	for _, tag := range u.Tags {
+		kind := ident + "." + tag.Kind()
+		var existing *v2rule.Rule
+		for _, t := range f.Tags(ident) {
+			if t.Kind() == kind && (u.TagKey == "" || t.AttrString(u.TagKey) == tag.AttrString(u.TagKey)) {
+				existing = t
+				break
+			}
+		}
+		if existing != nil && existing.ShouldKeep() {
+			continue
+		}
+		add := existing == nil
+		if add {
+			existing = v2rule.NewRule(kind, "")
+		}
+		for _, key := range tag.AttrKeys() {
+			existing.SetAttr(key, tag.Attr(key))
+		}
+		if add {
+			f.AddTag(existing)
+		}
+	}
+	f.AddUseRepos(ident, u.UseRepos...)
+}
@@ -0,0 +1,197 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	v2rule "github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"golang.org/x/mod/semver"
+)
+
+// defaultRegistry is the Bazel registry that release versions and archives
+// of rules updated with -update_rules are looked up in.
+const defaultRegistry = "https://bcr.bazel.build"
+
+// updatableRules maps the names of modules that -update_rules can update to
+// the names of the http_archive rules that declare them in WORKSPACE.
+var updatableRules = map[string]string{
+	"gazelle":  "bazel_gazelle",
+	"rules_go": "io_bazel_rules_go",
+}
+
+// rulesUpdate is a module named with -update_rules and the version it
+// should be updated to. An empty version means the latest release.
+type rulesUpdate struct {
+	module, version string
+}
+
+// parseUpdateRules parses the value of -update_rules, a comma-separated list
+// of module names with optional versions, like "rules_go@v0.50.1,gazelle".
+func parseUpdateRules(value string) ([]rulesUpdate, error) {
+	var updates []rulesUpdate
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		module, version, _ := strings.Cut(s, "@")
+		if _, ok := updatableRules[module]; !ok {
+			return nil, fmt.Errorf("-update_rules: unknown rules %q; expected rules_go or gazelle", module)
+		}
+		if version == "latest" {
+			version = ""
+		}
+		updates = append(updates, rulesUpdate{module: module, version: strings.TrimPrefix(version, "v")})
+	}
+	return updates, nil
+}
+
+// registrySource is the part of a module version's source.json in a Bazel
+// registry that describes its archive.
+type registrySource struct {
+	URL         string `json:"url"`
+	Integrity   string `json:"integrity"`
+	StripPrefix string `json:"strip_prefix"`
+}
+
+// fetchRegistryJSON decodes the JSON file at path in registry into v.
+func fetchRegistryJSON(registry, path string, v any) error {
+	url := strings.TrimSuffix(registry, "/") + "/" + path
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("fetching %s: %v", url, err)
+	}
+	return nil
+}
+
+// latestRegistryVersion returns the latest version of module in registry
+// that isn't yanked or a prerelease.
+func latestRegistryVersion(registry, module string) (string, error) {
+	var metadata struct {
+		Versions       []string          `json:"versions"`
+		YankedVersions map[string]string `json:"yanked_versions"`
+	}
+	if err := fetchRegistryJSON(registry, "modules/"+module+"/metadata.json", &metadata); err != nil {
+		return "", err
+	}
+	latest := ""
+	for _, v := range metadata.Versions {
+		if _, yanked := metadata.YankedVersions[v]; yanked || !semver.IsValid("v"+v) || semver.Prerelease("v"+v) != "" {
+			continue
+		}
+		if latest == "" || semver.Compare("v"+v, "v"+latest) > 0 {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no releases of %s found in %s", module, registry)
+	}
+	return latest, nil
+}
+
+// updateRulesVersions updates the bazel_dep calls in MODULE.bazel and the
+// http_archive rules in WORKSPACE that declare the modules named with
+// -update_rules, and writes the files that changed.
+func updateRulesVersions(uc *updateReposConfig, repoRoot string) error {
+	moduleFile, err := v2rule.LoadModuleFile(filepath.Join(repoRoot, "MODULE.bazel"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for _, u := range uc.updateRules {
+		version := u.version
+		if version == "" {
+			if version, err = latestRegistryVersion(uc.registry, u.module); err != nil {
+				return err
+			}
+		}
+
+		if moduleFile != nil {
+			if dep, ok := moduleFile.BazelDep(u.module); ok {
+				dep.Version = version
+				moduleFile.SetBazelDep(dep)
+			}
+		}
+
+		if uc.workspace == nil {
+			continue
+		}
+		var archive *rule.Rule
+		for _, r := range uc.workspace.Rules {
+			if r.Kind() == "http_archive" && r.Name() == updatableRules[u.module] {
+				archive = r
+				break
+			}
+		}
+		if archive == nil || archive.ShouldKeep() {
+			continue
+		}
+		var src registrySource
+		if err := fetchRegistryJSON(uc.registry, "modules/"+u.module+"/"+version+"/source.json", &src); err != nil {
+			return err
+		}
+		algo, sum, ok := strings.Cut(src.Integrity, "-")
+		if !ok || algo != "sha256" {
+			return fmt.Errorf("%s %s: unsupported integrity %q", u.module, version, src.Integrity)
+		}
+		sha256, err := base64.StdEncoding.DecodeString(sum)
+		if err != nil {
+			return fmt.Errorf("%s %s: unsupported integrity %q", u.module, version, src.Integrity)
+		}
+		archive.DelAttr("url")
+		urls := []string{src.URL}
+		if strings.HasPrefix(src.URL, "https://github.com/") {
+			urls = append([]string{"https://mirror.bazel.build/" + strings.TrimPrefix(src.URL, "https://")}, urls...)
+		}
+		archive.SetAttr("urls", urls)
+		archive.SetAttr("sha256", hex.EncodeToString(sha256))
+		if src.StripPrefix != "" {
+			archive.SetAttr("strip_prefix", src.StripPrefix)
+		} else {
+			archive.DelAttr("strip_prefix")
+		}
+		archive.DelAttr("integrity")
+	}
+
+	if moduleFile != nil && string(moduleFile.Format()) != string(moduleFile.Content) {
+		if err := moduleFile.Save(moduleFile.Path); err != nil {
+			return err
+		}
+	}
+	if uc.workspace != nil && string(uc.workspace.Format()) != string(uc.workspace.Content) {
+		if err := uc.workspace.Save(uc.workspace.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
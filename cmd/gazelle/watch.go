@@ -0,0 +1,350 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/internal/wspace"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is how long watch waits after a file changes before updating
+// build files. Changes made during this time are handled together, so that
+// saving several files or switching branches triggers one update.
+const watchDelay = 200 * time.Millisecond
+
+// watch runs "gazelle update" with args once, then watches the repository
+// for changes and updates build files in changed directories until
+// interrupted.
+//
+// The rule index built by the first update is kept in memory. Later updates
+// only visit directories with changed files, without recursion, and index
+// those directories again, so dependencies are resolved the same way as in
+// the first update.
+func watch(ctx context.Context, wd string, args []string) error {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		watchUsage()
+		return flag.ErrHelp
+	}
+	repoRoot, err := watchRepoRoot(wd, args)
+	if err != nil {
+		return err
+	}
+
+	w, err := newDirWatcher(repoRoot)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	sess := update.NewSession()
+	runCtx := update.WithSession(ctx, sess)
+	w.beginUpdate()
+	err = update.Run(runCtx, languages, wd, args)
+	w.endUpdate()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil && !errors.Is(err, update.ErrDiff) {
+		return err
+	}
+	log.Printf("watching %s for changes", repoRoot)
+
+	for {
+		dirs, removed, err := w.wait(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, dir := range removed {
+			sess.Invalidate(dir)
+		}
+		if len(dirs) == 0 {
+			continue
+		}
+		log.Printf("updating %s", strings.Join(relDirs(repoRoot, dirs), " "))
+		w.beginUpdate()
+		err = update.Run(runCtx, languages, wd, incrementalArgs(args, dirs))
+		w.endUpdate()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && !errors.Is(err, update.ErrDiff) {
+			log.Print(err)
+		}
+	}
+}
+
+// incrementalArgs returns arguments for an update of dirs after the first
+// update. Recursion is turned off, but flags in args may override that.
+func incrementalArgs(args, dirs []string) []string {
+	incArgs := make([]string, 0, len(args)+len(dirs)+1)
+	incArgs = append(incArgs, "-r=false")
+	incArgs = append(incArgs, args...)
+	return append(incArgs, dirs...)
+}
+
+// watchRepoRoot returns the repository root directory, named by -repo_root
+// in args or found by searching the parent directories of wd. The flags in
+// args haven't been parsed yet, so watchRepoRoot looks for -repo_root on
+// its own.
+func watchRepoRoot(wd string, args []string) (string, error) {
+	var repoRoot string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if name == "repo_root" {
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			repoRoot = value
+		}
+	}
+	if repoRoot == "" {
+		return wspace.FindRepoRoot(wd)
+	}
+	if !filepath.IsAbs(repoRoot) {
+		repoRoot = filepath.Join(wd, repoRoot)
+	}
+	return repoRoot, nil
+}
+
+func relDirs(repoRoot string, dirs []string) []string {
+	rels := make([]string, len(dirs))
+	for i, dir := range dirs {
+		rel, err := filepath.Rel(repoRoot, dir)
+		if err != nil || rel == "." {
+			rel = "//"
+		} else {
+			rel = "//" + filepath.ToSlash(rel)
+		}
+		rels[i] = rel
+	}
+	return rels
+}
+
+// dirWatcher records directories in a repository whose contents change.
+type dirWatcher struct {
+	root    string
+	watcher *fsnotify.Watcher
+
+	// changed is signaled when a directory is added to dirs.
+	changed chan struct{}
+
+	mu   sync.Mutex
+	dirs map[string]bool
+
+	// updating is true while Gazelle updates build files. Changes to build
+	// files are ignored while updating and for watchDelay afterward, until
+	// ignoreBuildFilesUntil, so that Gazelle doesn't react to its own writes.
+	updating              bool
+	ignoreBuildFilesUntil time.Time
+}
+
+func newDirWatcher(root string) (*dirWatcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &dirWatcher{
+		root:    root,
+		watcher: fw,
+		changed: make(chan struct{}, 1),
+		dirs:    make(map[string]bool),
+	}
+	w.addTree(root)
+	go w.run()
+	return w, nil
+}
+
+func (w *dirWatcher) close() {
+	if err := w.watcher.Close(); err != nil {
+		log.Print(err)
+	}
+}
+
+// addTree watches dir and its subdirectories, except for those that never
+// contain sources.
+func (w *dirWatcher) addTree(dir string) {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Print(err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != w.root && shouldIgnoreWatchPath(d.Name()) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			log.Print(err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+func (w *dirWatcher) run() {
+	for {
+		select {
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			base := filepath.Base(ev.Name)
+			if shouldIgnoreWatchPath(base) || (isBuildFileName(base) && w.ignoringBuildFiles()) {
+				continue
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				if st, err := os.Lstat(ev.Name); err == nil && st.IsDir() {
+					w.addTree(ev.Name)
+					w.record(ev.Name)
+				}
+			}
+			w.record(filepath.Dir(ev.Name))
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Print(err)
+		}
+	}
+}
+
+func (w *dirWatcher) record(dir string) {
+	w.mu.Lock()
+	w.dirs[dir] = true
+	w.mu.Unlock()
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until directories change and no more changes arrive for
+// watchDelay, then returns the changed directories and the directories that
+// were deleted.
+func (w *dirWatcher) wait(ctx context.Context) (dirs, removed []string, err error) {
+	select {
+	case <-w.changed:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	timer := time.NewTimer(watchDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.changed:
+			timer.Reset(watchDelay)
+		case <-timer.C:
+			dirs, removed := w.takeChangedDirs()
+			return dirs, removed, nil
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// takeChangedDirs returns the directories that changed since the last call
+// and still exist, and the directories that were deleted, both sorted, and
+// forgets them.
+func (w *dirWatcher) takeChangedDirs() (dirs, removed []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for dir := range w.dirs {
+		if _, err := os.Stat(dir); err == nil {
+			dirs = append(dirs, dir)
+		} else {
+			removed = append(removed, dir)
+		}
+	}
+	sort.Strings(dirs)
+	sort.Strings(removed)
+	w.dirs = make(map[string]bool)
+	return dirs, removed
+}
+
+// beginUpdate and endUpdate are called before and after Gazelle updates
+// build files.
+func (w *dirWatcher) beginUpdate() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.updating = true
+}
+
+func (w *dirWatcher) endUpdate() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.updating = false
+	w.ignoreBuildFilesUntil = time.Now().Add(watchDelay)
+}
+
+func (w *dirWatcher) ignoringBuildFiles() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.updating || time.Now().Before(w.ignoreBuildFilesUntil)
+}
+
+// shouldIgnoreWatchPath returns whether changes to a file or directory with
+// the given base name should be ignored: version control metadata and Bazel's
+// output directory symlinks.
+func shouldIgnoreWatchPath(base string) bool {
+	return base == ".git" || base == ".hg" || base == ".jj" || strings.HasPrefix(base, "bazel-")
+}
+
+// isBuildFileName reports whether name is one of the default build file
+// names, which Gazelle itself writes.
+func isBuildFileName(name string) bool {
+	for _, n := range config.DefaultValidBuildFileNames {
+		if name == n {
+			return true
+		}
+	}
+	return false
+}
+
+func watchUsage() {
+	fmt.Fprint(os.Stderr, `usage: gazelle watch [flags...] [directories...]
+
+The watch command runs "gazelle update" with the given flags and directories,
+then watches the repository for file changes. When files change, Gazelle
+updates build files in the changed directories only. The index of libraries
+built by the first update is kept in memory and updated with the changed
+directories, so dependencies are resolved the same way as in a full update.
+
+Run "gazelle update -h" for a list of flags. Stop watching with Ctrl-C.
+`)
+}
@@ -0,0 +1,132 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIncrementalArgs(t *testing.T) {
+	got := incrementalArgs([]string{"-go_prefix=example.com/repo"}, []string{"/repo/a", "/repo/b"})
+	want := []string{"-r=false", "-go_prefix=example.com/repo", "/repo/a", "/repo/b"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestWatchRepoRoot(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		args []string
+		want string
+	}{
+		{desc: "equals", args: []string{"-repo_root=/repo"}, want: "/repo"},
+		{desc: "separate", args: []string{"--repo_root", "/repo"}, want: "/repo"},
+		{desc: "relative", args: []string{"-repo_root=repo"}, want: "/work/repo"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := watchRepoRoot("/work", tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDirWatcher(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a/a.go", Content: "package a\n"},
+		{Path: "b/b.go", Content: "package b\n"},
+		{Path: "bazel-out/x.go", Content: "package x\n"},
+	})
+	defer cleanup()
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newDirWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+
+	// Build files written while updating are ignored.
+	w.beginUpdate()
+	writeFile(t, filepath.Join(dir, "a/BUILD.bazel"), "")
+	w.endUpdate()
+	writeFile(t, filepath.Join(dir, "bazel-out/y.go"), "package x\n")
+	writeFile(t, filepath.Join(dir, "b/c.go"), "package b\n")
+	if err := os.Mkdir(filepath.Join(dir, "b/sub"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	got, _, err := w.wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "b"), filepath.Join(dir, "b/sub")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("changed directories (-want +got):\n%s", diff)
+	}
+
+	// New directories are watched.
+	writeFile(t, filepath.Join(dir, "b/sub/sub.go"), "package sub\n")
+	got, _, err = w.wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{filepath.Join(dir, "b/sub")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("changed directories (-want +got):\n%s", diff)
+	}
+
+	// Deleted directories are reported separately.
+	if err := os.RemoveAll(filepath.Join(dir, "b/sub")); err != nil {
+		t.Fatal(err)
+	}
+	got, removed, err := w.wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{filepath.Join(dir, "b")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("changed directories (-want +got):\n%s", diff)
+	}
+	wantRemoved := []string{filepath.Join(dir, "b/sub")}
+	if diff := cmp.Diff(wantRemoved, removed); diff != "" {
+		t.Errorf("removed directories (-want +got):\n%s", diff)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o666); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -16,8 +16,8 @@ limitations under the License.
 // Command generate_repo_config takes in a build config file such as
 // WORKSPACE and generates a stripped version of the file. The generated
 // config file should contain only the information relevant to gazelle for
-// dependency resolution, so go_repository rules with importpath
-// and name defined, plus any directives.
+// dependency resolution, so go_repository rules with name, importpath,
+// naming convention, build directives, and replace target defined.
 //
 // This command is used by the go_repository_config rule to generate a repo
 // config file used by all go_repository rules. A list of macro files is
@@ -115,6 +115,12 @@ func generateRepoConfig(configDest, configSource string) ([]string, error) {
 			if namingConvention := rsrc.AttrString("build_naming_convention"); namingConvention != "" {
 				rdst.SetAttr("build_naming_convention", namingConvention)
 			}
+			if directives := rsrc.AttrStrings("build_directives"); len(directives) > 0 {
+				rdst.SetAttr("build_directives", directives)
+			}
+			if replace := rsrc.AttrString("replace"); replace != "" {
+				rdst.SetAttr("replace", replace)
+			}
 		} else if rsrc.Kind() == httpArchiveRuleKind && rsrc.Name() == "io_bazel_rules_go" {
 			rdst = rule.NewRule(httpArchiveRuleKind, "io_bazel_rules_go")
 			rdst.SetAttr("urls", rsrc.AttrStrings("urls"))
@@ -49,6 +49,7 @@ def go_repositories():
     # gazelle:repo test2
     go_repository(
         name = "org_golang_x_net",
+        build_directives = ["gazelle:go_naming_convention import_alias"],
         importpath = "golang.org/x/net",
         tag = "1.2",
     )
@@ -58,10 +59,23 @@ def go_repositories():
         importpath = "golang.org/x/sys",
         remote = "https://github.com/golang/sys",
     )
+    go_repository(
+        name = "com_example_forked",
+        importpath = "example.com/forked",
+        replace = "example.com/fork",
+        sum = "h1:abc=",
+        version = "v1.0.0",
+    )
 `,
 			wantContent: `
 # Code generated by generate_repo_config.go; DO NOT EDIT.
 
+go_repository(
+    name = "com_example_forked",
+    importpath = "example.com/forked",
+    replace = "example.com/fork",
+)
+
 go_repository(
     name = "com_github_pkg_errors",
     importpath = "github.com/pkg/errors",
@@ -69,6 +83,7 @@ go_repository(
 
 go_repository(
     name = "org_golang_x_net",
+    build_directives = ["gazelle:go_naming_convention import_alias"],
     importpath = "golang.org/x/net",
 )
 
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"testing"
 
+	v2 "github.com/bazel-contrib/bazel-gazelle/v2/config"
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
 )
 
@@ -43,7 +44,7 @@ func TestCommonConfigurerFlags(t *testing.T) {
 	cc := &CommonConfigurer{}
 	fs := flag.NewFlagSet("test", flag.ContinueOnError)
 	cc.RegisterFlags(fs, "test", c)
-	args := []string{"-repo_root", dir, "-lang", "go"}
+	args := []string{"-repo_root", dir, "-lang", "go", "-jobs", "2"}
 	if err := fs.Parse(args); err != nil {
 		t.Fatal(err)
 	}
@@ -59,6 +60,10 @@ func TestCommonConfigurerFlags(t *testing.T) {
 	if !reflect.DeepEqual(c.Langs, wantLangs) {
 		t.Errorf("for Langs, got %#v, want %#v", c.Langs, wantLangs)
 	}
+
+	if c.Jobs != 2 {
+		t.Errorf("for Jobs, got %d, want 2", c.Jobs)
+	}
 }
 
 func TestCommonConfigurerDirectives(t *testing.T) {
@@ -77,6 +82,237 @@ func TestCommonConfigurerDirectives(t *testing.T) {
 	}
 }
 
+func TestCommonConfigurerLangDirectives(t *testing.T) {
+	for _, tc := range []struct {
+		desc         string
+		parent, kid  string
+		wantEnabled  []string
+		wantDisabled []string
+	}{
+		{
+			desc:         "disable in child",
+			kid:          "-proto",
+			wantEnabled:  []string{"go", "bzl"},
+			wantDisabled: []string{"proto"},
+		}, {
+			desc:         "disable selected language",
+			parent:       "go,proto",
+			kid:          "-proto",
+			wantEnabled:  []string{"go"},
+			wantDisabled: []string{"proto", "bzl"},
+		}, {
+			desc:         "re-enable in child",
+			parent:       "-proto,-bzl",
+			kid:          "+proto",
+			wantEnabled:  []string{"go", "proto"},
+			wantDisabled: []string{"bzl"},
+		}, {
+			desc:         "add to selection",
+			parent:       "go",
+			kid:          "+proto",
+			wantEnabled:  []string{"go", "proto"},
+			wantDisabled: []string{"bzl"},
+		}, {
+			desc:         "replace selection",
+			parent:       "-proto",
+			kid:          "proto",
+			wantEnabled:  []string{"proto"},
+			wantDisabled: []string{"go", "bzl"},
+		}, {
+			desc:         "disable only selected language",
+			parent:       "proto",
+			kid:          "-proto",
+			wantDisabled: []string{"go", "proto", "bzl"},
+		}, {
+			desc:        "clear selection",
+			parent:      "go,-proto",
+			kid:         "",
+			wantEnabled: []string{"go", "proto", "bzl"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := New()
+			cc := &CommonConfigurer{}
+			if tc.parent != "" {
+				cc.Configure(c, "", &rule.File{Directives: []rule.Directive{{Key: "lang", Value: tc.parent}}})
+			}
+			c = c.Clone()
+			cc.Configure(c, "sub", &rule.File{Directives: []rule.Directive{{Key: "lang", Value: tc.kid}}})
+			for _, name := range tc.wantEnabled {
+				if !c.LangEnabled(name) {
+					t.Errorf("LangEnabled(%q): got false, want true", name)
+				}
+			}
+			for _, name := range tc.wantDisabled {
+				if c.LangEnabled(name) {
+					t.Errorf("LangEnabled(%q): got true, want false", name)
+				}
+			}
+		})
+	}
+}
+
+func TestCommonConfigurerMapKindAttrs(t *testing.T) {
+	c := New()
+	cc := &CommonConfigurer{}
+	cc.Configure(c, "", &rule.File{Directives: []rule.Directive{
+		{Key: "map_kind", Value: "go_library my_library //:my.bzl deps=dependencies -importpath"},
+		{Key: "map_kind", Value: "go_test my_test //:my.bzl deps"},
+	}})
+	want := map[string]MappedKind{
+		"go_library": {
+			FromKind: "go_library",
+			KindName: "my_library",
+			KindLoad: "//:my.bzl",
+		},
+	}
+	if !reflect.DeepEqual(c.KindMap, want) {
+		t.Errorf("for KindMap, got %#v, want %#v", c.KindMap, want)
+	}
+	wantAttrs := map[string]v2.MappedAttrs{
+		"go_library": {
+			Renamed: map[string]string{"deps": "dependencies"},
+			Dropped: []string{"importpath"},
+		},
+	}
+	if !reflect.DeepEqual(c.KindAttrMap, wantAttrs) {
+		t.Errorf("for KindAttrMap, got %#v, want %#v", c.KindAttrMap, wantAttrs)
+	}
+
+	// Mapping the kind again without attribute changes clears the inherited
+	// changes. MappedKind is compared with == so that it stays comparable.
+	sub := c.Clone()
+	cc.Configure(sub, "sub", &rule.File{Directives: []rule.Directive{
+		{Key: "map_kind", Value: "go_library my_library //:my.bzl"},
+	}})
+	if sub.KindMap["go_library"] != want["go_library"] {
+		t.Errorf("for KindMap in sub, got %#v, want %#v", sub.KindMap["go_library"], want["go_library"])
+	}
+	if len(sub.KindAttrMap) != 0 {
+		t.Errorf("for KindAttrMap in sub, got %#v, want none", sub.KindAttrMap)
+	}
+}
+
+func TestCommonConfigurerWrapperMacro(t *testing.T) {
+	c := New()
+	cc := &CommonConfigurer{}
+	cc.Configure(c, "", &rule.File{Directives: []rule.Directive{
+		{Key: "alias_kind", Value: "my_library go_library"},
+		{Key: "wrapper_macro", Value: "my_rule go_library,go_binary"},
+	}})
+	wantAliasMap := map[string]string{"my_library": "go_library", "my_rule": "go_library"}
+	if !reflect.DeepEqual(c.AliasMap, wantAliasMap) {
+		t.Errorf("for AliasMap, got %#v, want %#v", c.AliasMap, wantAliasMap)
+	}
+	wantWrapperMacros := map[string][]string{"my_rule": {"go_library", "go_binary"}}
+	if !reflect.DeepEqual(c.WrapperMacros, wantWrapperMacros) {
+		t.Errorf("for WrapperMacros, got %#v, want %#v", c.WrapperMacros, wantWrapperMacros)
+	}
+
+	c = c.Clone()
+	cc.Configure(c, "sub", &rule.File{Directives: []rule.Directive{
+		{Key: "reset", Value: "alias_kind"},
+	}})
+	wantAliasMap = map[string]string{"my_rule": "go_library"}
+	if !reflect.DeepEqual(c.AliasMap, wantAliasMap) {
+		t.Errorf("after reset, for AliasMap, got %#v, want %#v", c.AliasMap, wantAliasMap)
+	}
+}
+
+func TestUnderlyingKind(t *testing.T) {
+	c := New()
+	cc := &CommonConfigurer{}
+	cc.Configure(c, "", &rule.File{Directives: []rule.Directive{
+		{Key: "map_kind", Value: "go_library my_library //:my.bzl"},
+		{Key: "map_kind", Value: "my_library my_other_library //:other.bzl"},
+		{Key: "map_kind", Value: "go_binary go_binary //:my.bzl"},
+		{Key: "alias_kind", Value: "my_macro my_library"},
+		{Key: "wrapper_macro", Value: "my_wrapper go_test,go_binary"},
+	}})
+	for _, tc := range []struct {
+		kind, want string
+	}{
+		{kind: "my_library", want: "go_library"},
+		{kind: "my_other_library", want: "go_library"},
+		{kind: "my_macro", want: "go_library"},
+		{kind: "my_wrapper", want: "go_test"},
+		{kind: "go_binary", want: "go_binary"},
+		{kind: "go_library", want: "go_library"},
+		{kind: "cc_library", want: "cc_library"},
+	} {
+		if got := c.UnderlyingKind(tc.kind); got != tc.want {
+			t.Errorf("UnderlyingKind(%q): got %q, want %q", tc.kind, got, tc.want)
+		}
+	}
+}
+
+func TestCommonConfigurerReset(t *testing.T) {
+	c := New()
+	cc := &CommonConfigurer{}
+	parent, err := rule.LoadData("BUILD.bazel", "", []byte(`
+# gazelle:lang go
+# gazelle:map_kind go_library my_library //:my.bzl
+# gazelle:alias_kind my_binary go_binary
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc.Configure(c, "", parent)
+
+	c = c.Clone()
+	child, err := rule.LoadData(filepath.Join("sub", "BUILD.bazel"), "sub", []byte(`
+# gazelle:reset lang,map_kind
+# gazelle:map_kind go_test my_test //:my.bzl
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc.Configure(c, "sub", child)
+
+	if c.Langs != nil {
+		t.Errorf("for Langs, got %#v, want nil", c.Langs)
+	}
+	wantKindMap := map[string]MappedKind{
+		"go_test": {FromKind: "go_test", KindName: "my_test", KindLoad: "//:my.bzl"},
+	}
+	if !reflect.DeepEqual(c.KindMap, wantKindMap) {
+		t.Errorf("for KindMap, got %#v, want %#v", c.KindMap, wantKindMap)
+	}
+	wantAliasMap := map[string]string{"my_binary": "go_binary"}
+	if !reflect.DeepEqual(c.AliasMap, wantAliasMap) {
+		t.Errorf("for AliasMap, got %#v, want %#v", c.AliasMap, wantAliasMap)
+	}
+}
+
+func TestCommonConfigurerThirdParty(t *testing.T) {
+	c := New()
+	cc := &CommonConfigurer{}
+	for _, tc := range []struct {
+		rel, value string
+		wantOn     bool
+		wantTags   []string
+	}{
+		{rel: "third_party", value: "", wantOn: true, wantTags: []string{"third_party"}},
+		{rel: "third_party/a", value: "vendored, imported", wantOn: true, wantTags: []string{"vendored", "imported"}},
+		{rel: "third_party/a/b", value: "off", wantOn: false},
+	} {
+		c = c.Clone()
+		cc.Configure(c, tc.rel, &rule.File{Directives: []rule.Directive{
+			{Key: "third_party", Value: tc.value},
+		}})
+		if c.ThirdParty != tc.wantOn || !reflect.DeepEqual(c.ThirdPartyTags, tc.wantTags) {
+			t.Errorf("%s: got ThirdParty %v, ThirdPartyTags %#v; want %v, %#v", tc.rel, c.ThirdParty, c.ThirdPartyTags, tc.wantOn, tc.wantTags)
+		}
+	}
+
+	cc.Configure(c, "x", &rule.File{Directives: []rule.Directive{{Key: "third_party"}}})
+	c = c.Clone()
+	cc.Configure(c, "x/y", &rule.File{Directives: []rule.Directive{{Key: "reset", Value: "third_party"}}})
+	if c.ThirdParty || c.ThirdPartyTags != nil {
+		t.Errorf("after reset, got ThirdParty %v, ThirdPartyTags %#v; want false, nil", c.ThirdParty, c.ThirdPartyTags)
+	}
+}
+
 func TestCommonConfigurerRepoName(t *testing.T) {
 	cases := []struct {
 		desc     string
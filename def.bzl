@@ -124,7 +124,7 @@ def _gazelle_runner_attr_factory(test_runner = False):
         attrs.update({
             "workspace": attr.label(
                 allow_single_file = True,
-                doc = "Label of the WORKSPACE file",
+                doc = "Label of a file in the repository root, like MODULE.bazel, REPO.bazel, or WORKSPACE",
                 mandatory = True,
             ),
             "mode": attr.string(
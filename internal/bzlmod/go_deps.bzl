@@ -80,6 +80,20 @@ _GAZELLE_ATTRS = {
         A list of additional command line arguments to pass to Gazelle when generating build files.
         """,
     ),
+    "build_languages": attr.string_list(
+        default = [],
+        doc = """
+        Gazelle languages to generate build files with. This sets the `build_languages`
+        attribute of the Go module's `go_repository`.
+        """,
+    ),
+    "build_env": attr.string_dict(
+        default = {},
+        doc = """
+        Environment variables to set when running Gazelle to generate build files,
+        for example `GOFLAGS`, `CGO_ENABLED`, or variables read by Gazelle extensions.
+        """,
+    ),
     "directives": attr.string_list(
         doc = """Gazelle configuration directives to use for this Go module's external repository.
 
@@ -160,6 +174,12 @@ def _get_build_file_generation(path, gazelle_overrides, gazelle_default_attribut
 def _get_build_extra_args(path, gazelle_overrides, gazelle_default_attributes):
     return _get_override_or_default(gazelle_overrides, gazelle_default_attributes, DEFAULT_BUILD_EXTRA_ARGS_BY_PATH, path, [], "build_extra_args")
 
+def _get_build_languages(path, gazelle_overrides, gazelle_default_attributes):
+    return _get_override_or_default(gazelle_overrides, gazelle_default_attributes, {}, path, [], "build_languages")
+
+def _get_build_env(path, gazelle_overrides, gazelle_default_attributes):
+    return _get_override_or_default(gazelle_overrides, gazelle_default_attributes, {}, path, {}, "build_env")
+
 def _get_patches(path, module_overrides):
     return _get_override_or_default(module_overrides, struct(), {}, path, [], "patches")
 
@@ -792,6 +812,8 @@ Mismatch between versions requested for Go module {module}:
             "build_directives": _get_directives(path, gazelle_overrides, gazelle_default_attributes),
             "build_file_generation": _get_build_file_generation(path, gazelle_overrides, gazelle_default_attributes),
             "build_extra_args": _get_build_extra_args(path, gazelle_overrides, gazelle_default_attributes),
+            "build_env": _get_build_env(path, gazelle_overrides, gazelle_default_attributes),
+            "build_languages": _get_build_languages(path, gazelle_overrides, gazelle_default_attributes),
             "patches": _get_patches(path, module_overrides),
             "patch_args": _get_patch_args(path, module_overrides),
             "patch_cmds": _get_patch_cmds(path, module_overrides),
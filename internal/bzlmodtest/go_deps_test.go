@@ -0,0 +1,144 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bzlmod_test checks that go_deps generates working go_repository
+// rules in a workspace that uses MODULE.bazel instead of WORKSPACE.
+package bzlmod_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
+	"github.com/bazelbuild/rules_go/go/tools/bazel_testing"
+)
+
+var testArgs = bazel_testing.Args{
+	Main: `
+-- BUILD.bazel --
+-- hello.go --
+package main
+
+func main() {}
+`,
+	ModuleFileSuffix: `
+bazel_dep(name = "rules_go", version = "0.59.0", repo_name = "io_bazel_rules_go")
+
+go_sdk = use_extension("@io_bazel_rules_go//go:extensions.bzl", "go_sdk")
+go_sdk.download(version = "1.24.12")
+
+go_deps = use_extension("@bazel_gazelle//:extensions.bzl", "go_deps")
+go_deps.config(
+    go_env = {
+        "GOPRIVATE": "example.com/m",
+        "GOSUMDB": "off",
+    },
+)
+go_deps.module(
+    path = "github.com/pkg/errors",
+    sum = "h1:iURUrRGxPUNPdy5/HRSm+Yj6okJ6UtLINN0Q9M4+h3I=",
+    version = "v0.8.1",
+)
+go_deps.gazelle_override(
+    directives = ["gazelle:go_naming_convention go_default_library"],
+    path = "github.com/pkg/errors",
+)
+go_deps.module(
+    path = "golang.org/x/xerrors",
+    sum = "h1:go1bK/D/BFZV2I8cIQd1NKEZ+0owSTG1fDTci4IqFcE=",
+    version = "v0.0.0-20200804184101-5ec99f83aff1",
+)
+go_deps.module(
+    path = "github.com/apex/log",
+    sum = "h1:J5rld6WVFi6NxA6m8GJ1LJqu3+GiTFIt3mYv27gdQWI=",
+    version = "v1.1.0",
+)
+go_deps.gazelle_override(
+    directives = ["gazelle:exclude handlers"],
+    path = "github.com/apex/log",
+)
+use_repo(
+    go_deps,
+    "com_github_apex_log",
+    "com_github_pkg_errors",
+    "org_golang_x_xerrors",
+)
+`,
+}
+
+func TestMain(m *testing.M) {
+	bazel_testing.TestMain(m, testArgs)
+}
+
+func TestBuild(t *testing.T) {
+	if err := bazel_testing.RunBazel("build", "@com_github_pkg_errors//:go_default_library", "@org_golang_x_xerrors//:xerrors"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGazelleOverrideExclude(t *testing.T) {
+	err := bazel_testing.RunBazel("query", "@com_github_apex_log//handlers/...")
+	if err == nil {
+		t.Fatal("Should not generate build files for @com_github_apex_log//handlers/...")
+	}
+	if !strings.Contains(err.Error(), "no targets found beneath 'handlers'") {
+		t.Fatal("Unexpected error:\n", err)
+	}
+}
+
+func TestRepoConfig(t *testing.T) {
+	if err := bazel_testing.RunBazel("build", "@com_github_pkg_errors//:go_default_library"); err != nil {
+		t.Fatal(err)
+	}
+	outDir := findExternalRepo(t, "bazel_gazelle_go_repository_config")
+	testtools.CheckFiles(t, outDir, []testtools.FileSpec{
+		{
+			Path: "WORKSPACE",
+			Content: `{{(?s).*}}go_repository(
+    name = "com_github_pkg_errors",
+    build_naming_convention = "go_default_library",
+    importpath = "github.com/pkg/errors",
+){{(?s).*}}`,
+			Pattern: true,
+		},
+		{
+			Path:    "go_env.bzl",
+			Content: `GO_ENV = {{.*}}"GOPRIVATE": "example.com/m"{{.*}}`,
+			Pattern: true,
+		},
+	})
+}
+
+// findExternalRepo returns the directory of the external repository whose
+// canonical name ends with the given apparent name. Canonical names of
+// module extension repositories differ between Bazel versions, so the
+// directory is found by suffix.
+func findExternalRepo(t *testing.T, name string) string {
+	t.Helper()
+	out, err := bazel_testing.BazelOutput("info", "output_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputBase := strings.TrimSpace(string(out))
+	matches, err := filepath.Glob(filepath.Join(outputBase, "external", "*"+name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("found %d external repositories named %s; want 1: %v", len(matches), name, matches)
+	}
+	return matches[0]
+}
@@ -39,7 +39,8 @@ def gazelle_generation_test(name, gazelle_binary, test_data, build_in_suffix = "
                 |-- BUILD.out --> BUILD file expected after running gazelle.
     ```
 
-    To update the expected files, run `UPDATE_SNAPSHOTS=true bazel run //path/to:the_test_target`.
+    To update the expected files, run `UPDATE_GOLDEN=1 bazel run //path/to:the_test_target`.
+    `UPDATE_SNAPSHOTS=true` is also accepted.
 
     Args:
         name: The name of the test.
@@ -13,7 +13,7 @@
 # limitations under the License.
 
 load("@bazel_tools//tools/build_defs/repo:utils.bzl", "patch", "read_user_netrc", "use_netrc")
-load("//internal:common.bzl", "env_execute", "executable_extension", "watch")
+load("//internal:common.bzl", "env_execute", "executable_extension", "getenv", "watch")
 load("//internal:go_repository_cache.bzl", "read_cache_env")
 
 _DOC = """
@@ -29,6 +29,12 @@ returned by `go env GOPATH`. Alternatively, by setting the environment variable
 `GO_REPOSITORY_USE_HOST_MODCACHE=1`, you can force `go_repository` to use only
 the module cache on the host system in the location returned by `go env GOMODCACHE`.
 
+By setting the environment variable `GO_REPOSITORY_OFFLINE=1`, or the `offline`
+attribute, you can force `go_repository` to read modules only from the module
+cache (prefilled, for example, with `GO_REPOSITORY_USE_HOST_MODCACHE=1` and
+`go mod download`) or from `module_archive_dir`. Modules that aren't there are
+reported as errors instead of being downloaded.
+
 **Example**
 
 ```starlark
@@ -75,6 +81,13 @@ go_repository(
     remote = "https://github.com/thediveo/enumflag",
     vcs = "git",
 )
+
+# Generate build files for a module vendored with "go mod vendor"
+go_repository(
+    name = "com_github_pkg_errors",
+    importpath = "github.com/pkg/errors",
+    vendor_dir = "vendor",
+)
 ```
 
 """
@@ -117,6 +130,32 @@ Authorization: Bearer RANDOM-TOKEN
 # We can't disable timeouts on Bazel, but we can set them to large values.
 _GO_REPOSITORY_TIMEOUT = 86400
 
+# Gazelle writes a log of the directives, rules, and unresolved imports in each
+# directory to this file in the repository, for debugging.
+_GENERATION_LOG = "gazelle_generation_log.json"
+
+def _workspace_path(ctx, attr_name):
+    """Returns the absolute path named by a string attribute.
+
+    Relative paths are resolved against the main workspace's root directory,
+    since that's how update-repos writes them for local replace directives.
+    """
+    path = getattr(ctx.attr, attr_name)
+    if path.startswith("/") or (len(path) > 1 and path[1] == ":"):
+        return path
+    if not hasattr(ctx, "workspace_root"):
+        fail("%s %s must be absolute with this version of Bazel" % (attr_name, path))
+    return str(ctx.workspace_root.get_child(path))
+
+def _env_var_names(s):
+    """Returns the names of environment variables referenced like ${NAME} in s."""
+    names = []
+    for part in s.split("${")[1:]:
+        end = part.find("}")
+        if end > 0:
+            names.append(part[:end])
+    return names
+
 def _go_repository_impl(ctx):
     # TODO(#549): vcs repositories are not cached and still need to be fetched.
     # Download the repository or module.
@@ -139,18 +178,37 @@ def _go_repository_impl(ctx):
 
     reproducible = False
     if ctx.attr.local_path:
+        local_path = _workspace_path(ctx, "local_path")
         if hasattr(ctx, "watch_tree"):
             # https://github.com/bazelbuild/bazel/commit/fffa0affebbacf1961a97ef7cd248be64487d480
-            ctx.watch_tree(ctx.attr.local_path)
+            ctx.watch_tree(local_path)
         else:
             print("""
   WARNING: go.mod replace directives to module paths is only supported in bazel 7.1.0-rc1 or later,
-          Because of this changes to %s will not be detected by your version of Bazel.""" % ctx.attr.local_path)
+          Because of this changes to %s will not be detected by your version of Bazel.""" % local_path)
 
-        fetch_repo_args = ["--path", ctx.attr.local_path, "--dest", ctx.path("")]
+        fetch_repo_args = ["--path", local_path, "--dest", ctx.path("")]
+    elif ctx.attr.vendor_dir:
+        # vendor mode
+        for key in ("urls", "commit", "tag", "vcs", "remote", "version", "sum", "replace"):
+            if getattr(ctx.attr, key):
+                fail("cannot specify both vendor_dir and %s" % key, key)
+        vendor_dir = _workspace_path(ctx, "vendor_dir")
+        module_dir = "%s/%s" % (vendor_dir, ctx.attr.importpath)
+        if hasattr(ctx, "watch_tree"):
+            ctx.watch_tree(module_dir)
+        watch(ctx, "%s/modules.txt" % vendor_dir)
+        fetch_repo_args = [
+            "--vendor-dir",
+            vendor_dir,
+            "--importpath",
+            ctx.attr.importpath,
+            "--dest",
+            ctx.path(""),
+        ]
     elif ctx.attr.urls:
         # HTTP mode
-        for key in ("commit", "tag", "vcs", "remote", "version", "sum", "replace"):
+        for key in ("commit", "tag", "vcs", "remote", "submodules", "version", "sum", "replace"):
             if getattr(ctx.attr, key):
                 fail("cannot specifiy both urls and %s" % key, key)
         result = ctx.download_and_extract(
@@ -194,9 +252,13 @@ def _go_repository_impl(ctx):
             fetch_repo_args.extend(["--rev", rev])
         if ctx.attr.vcs:
             fetch_repo_args.extend(["--vcs", ctx.attr.vcs])
+        if ctx.attr.submodules:
+            if ctx.attr.vcs not in ("", "git"):
+                fail("submodules may only be set when vcs is git", "submodules")
+            fetch_repo_args.append("--submodules")
     elif ctx.attr.version:
         # module mode
-        for key in ("urls", "strip_prefix", "type", "sha256", "commit", "tag", "vcs", "remote"):
+        for key in ("urls", "strip_prefix", "type", "sha256", "commit", "tag", "vcs", "remote", "submodules"):
             if getattr(ctx.attr, key):
                 fail("cannot specify both version and %s" % key)
         if not ctx.attr.sum:
@@ -213,9 +275,29 @@ def _go_repository_impl(ctx):
             "-version=" + ctx.attr.version,
             "-sum=" + ctx.attr.sum,
         ]
+        for proxy in ctx.attr.module_proxies:
+            fetch_repo_args.append("-proxy=" + proxy)
+        for header in ctx.attr.module_proxy_headers:
+            fetch_repo_args.append("-proxy-header=" + header)
+        for attr_name, flag in [
+            ("module_proxy_ca_file", "-proxy-ca-file="),
+            ("module_proxy_client_cert", "-proxy-client-cert="),
+            ("module_proxy_client_key", "-proxy-client-key="),
+        ]:
+            if getattr(ctx.attr, attr_name):
+                fetch_repo_args.append(flag + _workspace_path(ctx, attr_name))
+        if ctx.attr.module_archive_dir:
+            fetch_repo_args.append("-module-archive-dir=" + _workspace_path(ctx, "module_archive_dir"))
+        elif ctx.attr.offline or getenv(ctx, "GO_REPOSITORY_OFFLINE") == "1":
+            fetch_repo_args.append("-offline")
     else:
         fail("one of urls, commit, tag, or version must be specified")
 
+    if not ctx.attr.version:
+        for attr_name in ("module_proxies", "module_proxy_headers", "module_proxy_ca_file", "module_proxy_client_cert", "module_proxy_client_key", "module_archive_dir", "offline"):
+            if getattr(ctx.attr, attr_name):
+                fail("%s may only be set when version is set" % attr_name)
+
     env = read_cache_env(ctx, go_env_cache)
     env_keys = [
         # keep sorted
@@ -274,6 +356,13 @@ def _go_repository_impl(ctx):
                         fail("%s is not defined as an environment variable, but you asked for GIT_COUNT_COUNT=%d" % (j, count))
                 env_keys = env_keys + [key, value]
 
+    # Variables referenced in module proxy headers are expanded by fetch_repo.
+    for header in ctx.attr.module_proxy_headers:
+        for name in _env_var_names(header):
+            if name not in ctx.os.environ:
+                fail("%s is referenced in module_proxy_headers but is not defined as an environment variable" % name)
+            env_keys = env_keys + [name]
+
     env.update({k: ctx.os.environ[k] for k in env_keys if k in ctx.os.environ})
 
     # Clean existing build files if requested
@@ -345,13 +434,17 @@ def _go_repository_impl(ctx):
             ctx.path(""),
             "-repo_config",
             repo_config,
+            "-generation_log",
+            ctx.path(_GENERATION_LOG),
         ]
-        if ctx.attr.version or ctx.attr.local_path:
+        if ctx.attr.version or ctx.attr.local_path or ctx.attr.vendor_dir:
             cmd.append("-go_repository_module_mode")
         if ctx.attr.build_file_name:
             cmd.extend(["-build_file_name", ctx.attr.build_file_name])
         if ctx.attr.build_tags:
             cmd.extend(["-build_tags", ",".join(ctx.attr.build_tags)])
+        if ctx.attr.build_languages:
+            cmd.append("-lang=" + ",".join(ctx.attr.build_languages))
         if ctx.attr.build_external:
             cmd.extend(["-external", ctx.attr.build_external])
         if ctx.attr.build_file_proto_mode:
@@ -362,17 +455,39 @@ def _go_repository_impl(ctx):
             cmd.append("-bzlmod")
         cmd.extend(ctx.attr.build_extra_args)
         cmd.append(ctx.path(""))
+        gazelle_env = dict(env)
+        gazelle_env.update(ctx.attr.build_env)
         ctx.report_progress("running Gazelle")
-        result = env_execute(ctx, cmd, environment = env, timeout = _GO_REPOSITORY_TIMEOUT)
+        result = env_execute(ctx, cmd, environment = gazelle_env, timeout = _GO_REPOSITORY_TIMEOUT)
         if result.return_code:
-            fail("failed to generate BUILD files for %s: %s" % (
+            fail("failed to generate BUILD files for %s: %s\nSee %s for the directives, rules, and unresolved imports in each directory." % (
                 ctx.attr.importpath,
                 result.stderr,
+                ctx.path(_GENERATION_LOG),
             ))
         if ctx.attr.debug_mode and result.stderr:
             print("%s gazelle.stdout: %s" % (ctx.name, result.stdout))
             print("%s gazelle.stderr: %s" % (ctx.name, result.stderr))
 
+        if ctx.attr.build_post_hook:
+            hook_path = ctx.path(ctx.attr.build_post_hook)
+            watch(ctx, hook_path)
+            ctx.report_progress("running build_post_hook")
+            result = env_execute(
+                ctx,
+                [hook_path, ctx.path("")],
+                environment = gazelle_env,
+                timeout = _GO_REPOSITORY_TIMEOUT,
+            )
+            if result.return_code:
+                fail("build_post_hook failed for %s: %s" % (
+                    ctx.attr.importpath,
+                    result.stderr,
+                ))
+            if ctx.attr.debug_mode and result.stderr:
+                print("%s build_post_hook.stdout: %s" % (ctx.name, result.stdout))
+                print("%s build_post_hook.stderr: %s" % (ctx.name, result.stderr))
+
     # Apply patches if necessary.
     patch(ctx)
 
@@ -501,6 +616,13 @@ go_repository = repository_rule(
             usually inferred from `importpath`, but you can set `remote` to download
             from a private repository or a fork.""",
         ),
+        "submodules": attr.bool(
+            default = False,
+            doc = """If true, git submodules are initialized and checked out recursively
+            after the repository is downloaded. This is needed for projects that keep
+            sources, for example C sources compiled with cgo, in submodules. May only
+            be used with git, when `commit` or `tag` is set.""",
+        ),
 
         # Attributes for a repository that should be downloaded via HTTP.
         "urls": attr.string_list(
@@ -539,7 +661,16 @@ go_repository = repository_rule(
 
         # Attributes for a module that should be loaded from the local file system.
         "local_path": attr.string(
-            doc = """ If specified, `go_repository` will load the module from this local directory""",
+            doc = """ If specified, `go_repository` will load the module from this local directory.
+            Relative paths are resolved against the main workspace's root directory.""",
+        ),
+        "vendor_dir": attr.string(
+            doc = """If specified, `go_repository` will load the module from this `vendor`
+            directory, made with `go mod vendor`, instead of downloading it. The module's
+            files are copied from the `importpath` subdirectory, except directories of
+            other modules listed in `modules.txt`, and build files there, which were
+            generated for the main workspace, are replaced. Relative paths are resolved
+            against the main workspace's root directory.""",
         ),
 
         # Attributes for a module that should be downloaded with the Go toolchain.
@@ -556,12 +687,52 @@ go_repository = repository_rule(
             A value for `sum` may be found in the `go.sum` file or by running
             `go mod download -json <module>@<version>`.""",
         ),
+        "module_archive_dir": attr.string(
+            doc = """A directory laid out like `GOMODCACHE/cache/download`, for example a
+            copy of one checked into the repository, to read the module from instead
+            of the network. Implies `offline`. Relative paths are resolved against
+            the main workspace's root directory.""",
+        ),
+        "module_proxies": attr.string_list(
+            doc = """Module proxy URLs to download the module from, instead of those in
+            `GOPROXY`. Proxies are tried in order, falling back to the next one on
+            any error. May only be set when `version` is set.""",
+        ),
+        "module_proxy_headers": attr.string_list(
+            doc = """HTTP headers sent to module proxies, in `"Name: Value"` form, for
+            example `"Authorization: Bearer ${GOPROXY_TOKEN}"`. `${NAME}` in a value
+            is replaced with the environment variable `NAME`, so secrets don't need
+            to be written in build files. Headers are sent to the proxies in
+            `module_proxies`, or those in `GOPROXY` if that's empty.""",
+        ),
+        "module_proxy_ca_file": attr.string(
+            doc = """A PEM file with certificates to trust when connecting to module
+            proxies, in addition to the system's. Relative paths are resolved
+            against the main workspace's root directory.""",
+        ),
+        "module_proxy_client_cert": attr.string(
+            doc = """A PEM file with a client certificate presented to module proxies that
+            require TLS client authentication. `module_proxy_client_key` must also
+            be set. Relative paths are resolved against the main workspace's root
+            directory.""",
+        ),
+        "module_proxy_client_key": attr.string(
+            doc = """A PEM file with the key for `module_proxy_client_cert`. Relative paths
+            are resolved against the main workspace's root directory.""",
+        ),
+        "offline": attr.bool(
+            doc = """If true, the module is only read from the module cache, or from
+            `module_archive_dir` if it's set. If it isn't there, fetching fails
+            with an error instead of accessing the network. Setting the environment
+            variable `GO_REPOSITORY_OFFLINE=1` has the same effect for all
+            `go_repository` rules. May only be set when `version` is set.""",
+        ),
         "replace": attr.string(
             doc = """A replacement for the module named by `importpath`. The module named by
             `replace` will be downloaded at `version` and verified with `sum`.
 
-            NOTE: There is no `go_repository` equivalent to file path `replace`
-            directives. Use `local_repository` instead.""",
+            NOTE: File path `replace` directives are expressed with `local_path`
+            instead.""",
         ),
 
         # Attributes for a repository that needs automatic build file generation
@@ -621,6 +792,15 @@ go_repository = repository_rule(
         "build_tags": attr.string_list(
             doc = "This sets Gazelle's `-build_tags` command line flag.",
         ),
+        "build_languages": attr.string_list(
+            doc = """Gazelle languages to generate build files with, like `["go", "proto"]`.
+            Names starting with `-` exclude a language, and names starting with `+` add
+            one; a list with only those adjusts the default set of all languages. For
+            example, `["-proto"]` runs every language except proto. Languages must be
+            compiled into the Gazelle binary used by `go_repository`.
+
+            This sets Gazelle's `-lang` command line flag.""",
+        ),
         "build_file_proto_mode": attr.string(
             doc = """One of `"default"`, `"legacy"`, `"disable"`, `"disable_global"` or `"package"`.
 
@@ -638,6 +818,20 @@ go_repository = repository_rule(
         "build_extra_args": attr.string_list(
             doc = "A list of additional command line arguments to pass to Gazelle when generating build files.",
         ),
+        "build_post_hook": attr.label(
+            allow_single_file = True,
+            doc = """An executable to run after Gazelle generates build files. It's run in
+            the repository's root directory with the absolute path of that directory as
+            its only argument, and may modify any files there. This is useful for
+            fixups that `patches` can't express because the files being changed are
+            generated. Patches are applied after the hook runs. The hook is not run
+            if build files aren't generated.""",
+        ),
+        "build_env": attr.string_dict(
+            doc = """Environment variables to set when running Gazelle to generate build
+            files, for example `GOFLAGS`, `CGO_ENABLED`, or variables read by Gazelle
+            extensions. These take precedence over variables inherited from the host.""",
+        ),
         "build_config": attr.label(
             default = "@bazel_gazelle_go_repository_config//:WORKSPACE",
             doc = """A file that Gazelle should read to learn about external repositories before
@@ -0,0 +1,73 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package langtest runs a language extension over a single package in
+// tests, the way Gazelle would before resolving dependencies.
+package langtest
+
+import (
+	"path"
+	"testing"
+
+	v2rule "github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/merger"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// Generate configures lang with the directives in root, the build file at
+// the repository root, and then with old, the build file of the package
+// rel. When rel is empty, old is the root build file and root is ignored.
+// Generate then calls GenerateRules with args, filling in Config, Rel and
+// File, and merges the generated and empty rules into old. It returns the
+// formatted build file.
+func Generate(t *testing.T, lang language.Language, root, rel, old string, args language.GenerateArgs) string {
+	t.Helper()
+	c := config.New()
+	if rel != "" {
+		rootFile, err := rule.LoadData("BUILD.bazel", "", []byte(root))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lang.Configure(c, "", rootFile)
+		c = c.Clone()
+	}
+	f, err := rule.LoadData(path.Join(rel, "BUILD.bazel"), rel, []byte(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lang.Configure(c, rel, f)
+
+	args.Config = c
+	args.Rel = rel
+	args.File = f
+	res := lang.GenerateRules(args)
+	if len(res.Gen) != len(res.Imports) {
+		t.Fatalf("got %d generated rules and %d imports", len(res.Gen), len(res.Imports))
+	}
+
+	kinds := make(map[string]rule.KindInfo)
+	for kind, info := range v2rule.GenericKinds {
+		kinds[kind] = info
+	}
+	for kind, info := range lang.Kinds() {
+		kinds[kind] = info
+	}
+	merger.MergeFile(f, res.Empty, res.Gen, merger.PreResolve, kinds, nil)
+	f.Sync()
+	return string(bzl.Format(f.File))
+}
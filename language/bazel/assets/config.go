@@ -0,0 +1,137 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assets
+
+import (
+	"log"
+	"path"
+	"strings"
+
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	filegroupDirective = "assets_filegroup"
+	exportsDirective   = "assets_exports"
+)
+
+// assetsConfig holds the asset patterns that apply to a directory. It's
+// inherited by subdirectories.
+type assetsConfig struct {
+	groups  []assetGroup
+	exports []string
+}
+
+// assetGroup is a filegroup named name containing files in the package that
+// match any of patterns.
+type assetGroup struct {
+	name     string
+	patterns []string
+}
+
+func getAssetsConfig(c *config.Config) *assetsConfig {
+	ac, _ := c.Exts[languageName].(*assetsConfig)
+	if ac == nil {
+		return &assetsConfig{}
+	}
+	return ac
+}
+
+func (*assetsLang) KnownDirectives() []string {
+	return []string{filegroupDirective, exportsDirective}
+}
+
+func (*assetsLang) Configure(c *config.Config, rel string, f *rule.File) {
+	parent := getAssetsConfig(c)
+	ac := &assetsConfig{
+		groups:  append([]assetGroup(nil), parent.groups...),
+		exports: parent.exports,
+	}
+	c.Exts[languageName] = ac
+	if f == nil {
+		return
+	}
+
+	for _, d := range f.Directives {
+		switch d.Key {
+		case filegroupDirective:
+			fields := strings.Fields(d.Value)
+			if len(fields) == 0 {
+				log.Printf("%s: %s requires a filegroup name", f.Path, filegroupDirective)
+				continue
+			}
+			name, patterns := fields[0], fields[1:]
+			if !checkPatterns(f.Path, patterns) {
+				continue
+			}
+			ac.groups = removeGroup(ac.groups, name)
+			if len(patterns) > 0 {
+				ac.groups = append(ac.groups, assetGroup{name: name, patterns: patterns})
+			}
+
+		case exportsDirective:
+			patterns := strings.Fields(d.Value)
+			if checkPatterns(f.Path, patterns) {
+				ac.exports = patterns
+			}
+
+		case "reset":
+			if v2config.ResetsDirective(d, filegroupDirective) {
+				ac.groups = nil
+			}
+			if v2config.ResetsDirective(d, exportsDirective) {
+				ac.exports = nil
+			}
+		}
+	}
+}
+
+// checkPatterns reports whether all patterns are valid, logging the first
+// that isn't.
+func checkPatterns(filePath string, patterns []string) bool {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			log.Printf("%s: invalid asset pattern %q: %v", filePath, p, err)
+			return false
+		}
+	}
+	return true
+}
+
+func removeGroup(groups []assetGroup, name string) []assetGroup {
+	for i, g := range groups {
+		if g.name == name {
+			return append(groups[:i:i], groups[i+1:]...)
+		}
+	}
+	return groups
+}
+
+// matchFiles returns the files that match any of patterns, in order.
+func matchFiles(files, patterns []string) []string {
+	var matched []string
+	for _, f := range files {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, f); ok {
+				matched = append(matched, f)
+				break
+			}
+		}
+	}
+	return matched
+}
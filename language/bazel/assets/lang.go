@@ -0,0 +1,132 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package assets generates filegroup and exports_files rules for static
+// files like templates, configuration files, and database migrations, so
+// other packages can depend on them.
+//
+// Assets are configured with directives, which apply to the directory where
+// they're written and its subdirectories:
+//
+//	# gazelle:assets_filegroup <name> <pattern>...
+//
+// generates a filegroup named <name> in each package, containing the files
+// in the package whose names match any of the patterns. Patterns use the
+// syntax of path.Match. Writing the directive with only a name stops
+// generating that filegroup.
+//
+//	# gazelle:assets_exports <pattern>...
+//
+// generates an exports_files rule listing the files in each package whose
+// names match any of the patterns. Writing the directive with no patterns
+// stops generating it. Packages with an exports_files call that passes its
+// files positionally are left alone.
+//
+// Generated filegroups are public; their visibility may be changed by hand.
+// Rules whose files are all removed are deleted.
+package assets
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	languageName = "assets"
+	exportsKind  = "exports_files"
+)
+
+type assetsLang struct {
+	language.BaseLang
+}
+
+// NewLanguage returns a language that generates filegroup and exports_files
+// rules for static assets.
+func NewLanguage() language.Language {
+	return &assetsLang{}
+}
+
+func (*assetsLang) Name() string {
+	return languageName
+}
+
+// ResolvesConcurrently reports that Resolve may be called concurrently.
+func (*assetsLang) ResolvesConcurrently() bool {
+	return true
+}
+
+// Kinds returns exports_files only. filegroup is one of rule.GenericKinds,
+// so it's merged the same way regardless of which language generates it.
+func (*assetsLang) Kinds() map[string]rule.KindInfo {
+	return map[string]rule.KindInfo{
+		exportsKind: {
+			MatchAny:       true,
+			NonEmptyAttrs:  map[string]bool{"srcs": true},
+			MergeableAttrs: map[string]bool{"srcs": true},
+		},
+	}
+}
+
+func (*assetsLang) Loads() []rule.LoadInfo {
+	panic("ApparentLoads should be called instead")
+}
+
+func (*assetsLang) ApparentLoads(func(string) string) []rule.LoadInfo {
+	return nil
+}
+
+func (*assetsLang) GenerateRules(args language.GenerateArgs) language.GenerateResult {
+	var res language.GenerateResult
+	ac := getAssetsConfig(args.Config)
+
+	for _, g := range ac.groups {
+		r := rule.NewRule("filegroup", g.name)
+		if srcs := matchFiles(args.RegularFiles, g.patterns); len(srcs) > 0 {
+			r.SetAttr("srcs", srcs)
+			r.SetAttr("visibility", []string{"//visibility:public"})
+			res.Gen = append(res.Gen, r)
+			res.Imports = append(res.Imports, nil)
+		} else {
+			res.Empty = append(res.Empty, r)
+		}
+	}
+
+	if len(ac.exports) > 0 && !hasPositionalExports(args.File) {
+		r := rule.NewRule(exportsKind, "")
+		if srcs := matchFiles(args.RegularFiles, ac.exports); len(srcs) > 0 {
+			r.SetAttr("srcs", srcs)
+			res.Gen = append(res.Gen, r)
+			res.Imports = append(res.Imports, nil)
+		} else {
+			res.Empty = append(res.Empty, r)
+		}
+	}
+	return res
+}
+
+// hasPositionalExports reports whether f has an exports_files call that
+// passes its files positionally. The merger only updates keyword arguments,
+// so such calls are maintained by hand.
+func hasPositionalExports(f *rule.File) bool {
+	if f == nil {
+		return false
+	}
+	for _, r := range f.Rules {
+		if r.Kind() == exportsKind && len(r.Args()) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/internal/language/langtest"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateRules(t *testing.T) {
+	for _, tc := range []struct {
+		desc, root, old, want string
+		files                 []string
+	}{
+		{
+			desc: "filegroup_and_exports",
+			root: `
+# gazelle:assets_filegroup templates *.tmpl *.html
+# gazelle:assets_exports *.yaml
+`,
+			files: []string{"a.tmpl", "b.html", "config.yaml", "main.go"},
+			want: `
+filegroup(
+    name = "templates",
+    srcs = [
+        "a.tmpl",
+        "b.html",
+    ],
+    visibility = ["//visibility:public"],
+)
+
+exports_files(srcs = ["config.yaml"])
+`,
+		}, {
+			desc: "merge_existing",
+			root: `
+# gazelle:assets_filegroup migrations *.sql
+# gazelle:assets_exports *.yaml
+`,
+			files: []string{"001.sql", "002.sql", "new.yaml"},
+			old: `
+filegroup(
+    name = "migrations",
+    srcs = ["001.sql"],
+    visibility = ["//db:__pkg__"],
+)
+
+exports_files(srcs = ["old.yaml"])
+`,
+			want: `
+filegroup(
+    name = "migrations",
+    srcs = [
+        "001.sql",
+        "002.sql",
+    ],
+    visibility = ["//db:__pkg__"],
+)
+
+exports_files(srcs = ["new.yaml"])
+`,
+		}, {
+			desc: "delete_empty",
+			root: `
+# gazelle:assets_filegroup migrations *.sql
+# gazelle:assets_exports *.yaml
+`,
+			files: []string{"README.md"},
+			old: `
+filegroup(
+    name = "migrations",
+    srcs = ["001.sql"],
+)
+
+exports_files(srcs = ["old.yaml"])
+`,
+			want: ``,
+		}, {
+			desc:  "positional_exports",
+			root:  `# gazelle:assets_exports *.yaml`,
+			files: []string{"new.yaml"},
+			old:   `exports_files(["old.yaml"])`,
+			want:  `exports_files(["old.yaml"])`,
+		}, {
+			desc: "overridden_in_package",
+			root: `
+# gazelle:assets_filegroup templates *.tmpl
+# gazelle:assets_filegroup configs *.yaml
+# gazelle:assets_exports *.yaml
+`,
+			files: []string{"a.tmpl", "b.yaml"},
+			old: `
+# gazelle:assets_filegroup templates
+# gazelle:assets_exports
+`,
+			want: `
+# gazelle:assets_filegroup templates
+# gazelle:assets_exports
+
+filegroup(
+    name = "configs",
+    srcs = ["b.yaml"],
+    visibility = ["//visibility:public"],
+)
+`,
+		}, {
+			desc: "reset",
+			root: `
+# gazelle:assets_filegroup templates *.tmpl
+# gazelle:assets_exports *.tmpl
+`,
+			files: []string{"a.tmpl"},
+			old:   `# gazelle:reset assets_filegroup,assets_exports`,
+			want:  `# gazelle:reset assets_filegroup,assets_exports`,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := strings.TrimSpace(langtest.Generate(t, NewLanguage(), tc.root, "pkg", tc.old, language.GenerateArgs{
+				RegularFiles: tc.files,
+			}))
+			if diff := cmp.Diff(strings.TrimSpace(tc.want), got); diff != "" {
+				t.Errorf("(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bzl
+
+import (
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+func (*bzlLang) GenerateRules(args language.GenerateArgs) language.GenerateResult {
+	var res language.GenerateResult
+	bzlFiles := make(map[string]bool)
+	for _, f := range args.RegularFiles {
+		if !strings.HasSuffix(f, ".bzl") {
+			continue
+		}
+		bzlFiles[f] = true
+
+		r := rule.NewRule(libraryKind, strings.TrimSuffix(f, ".bzl"))
+		r.SetAttr("srcs", []string{f})
+		r.SetAttr("visibility", []string{libraryVisibility(args.Rel)})
+		loads, err := readLoads(args.Config, filepath.Join(args.Dir, f))
+		if err != nil {
+			log.Printf("%s: %v", path.Join(args.Rel, f), err)
+		}
+		res.Gen = append(res.Gen, r)
+		res.Imports = append(res.Imports, loadLabels(args.Config, args.Rel, loads))
+	}
+
+	if args.File != nil {
+		for _, r := range args.File.Rules {
+			if r.Kind() == libraryKind && isStale(r, bzlFiles) {
+				res.Empty = append(res.Empty, rule.NewRule(libraryKind, r.Name()))
+			}
+		}
+	}
+	return res
+}
+
+// libraryVisibility returns the visibility for bzl_library rules in the
+// package rel. Packages in or under a directory named "private" or
+// "internal" are visible to the subpackages of that directory's parent.
+func libraryVisibility(rel string) string {
+	if rel == "" {
+		return "//visibility:public"
+	}
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		if part == "private" || part == "internal" {
+			return "//" + path.Join(parts[:i]...) + ":__subpackages__"
+		}
+	}
+	return "//visibility:public"
+}
+
+// isStale returns whether r is a bzl_library whose srcs are all .bzl files in
+// the package that no longer exist. Rules with other srcs, like labels in
+// other packages, are left alone.
+func isStale(r *rule.Rule, bzlFiles map[string]bool) bool {
+	srcs := r.AttrStrings("srcs")
+	if len(srcs) == 0 {
+		return false
+	}
+	for _, src := range srcs {
+		if !strings.HasSuffix(src, ".bzl") || strings.ContainsAny(src, ":/") || bzlFiles[src] {
+			return false
+		}
+	}
+	return true
+}
+
+type loadsKey struct{}
+
+// readLoads returns the modules loaded by the .bzl file at filename.
+func readLoads(c *config.Config, filename string) ([]string, error) {
+	v, err := c.ParseFile(filename, loadsKey{}, func(filename string, data []byte) (any, error) {
+		f, err := bzl.ParseBzl(filename, data)
+		if err != nil {
+			return nil, err
+		}
+		var loads []string
+		for _, stmt := range f.Stmt {
+			if load, ok := stmt.(*bzl.LoadStmt); ok {
+				loads = append(loads, load.Module.Value)
+			}
+		}
+		return loads, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]string), nil
+}
+
+// loadLabels converts the modules loaded by a file in the package rel into
+// absolute labels. Labels in the main repository have an empty Repo.
+// Modules that aren't valid labels are logged and skipped.
+func loadLabels(c *config.Config, rel string, loads []string) []label.Label {
+	var labels []label.Label
+	for _, load := range loads {
+		l, err := label.Parse(load)
+		if err != nil {
+			log.Printf("%s: invalid load %q: %v", rel, load, err)
+			continue
+		}
+		if l.Repo == c.RepoName {
+			l.Repo = ""
+		}
+		if l.Repo == "" && l.Relative {
+			l.Pkg = rel
+			l.Relative = false
+		}
+		labels = append(labels, l)
+	}
+	return labels
+}
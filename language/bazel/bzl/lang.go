@@ -0,0 +1,88 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bzl generates bzl_library rules for Starlark (.bzl) files.
+//
+// Each .bzl file gets its own bzl_library rule, named after the file without
+// its extension. The deps of each rule are resolved from the file's load
+// statements: loads of files in the same repository resolve to the
+// bzl_library rules that contain them, and loads from other repositories
+// resolve to a rule named after the loaded file, following the convention
+// used by most rules repositories. Loads from @bazel_tools are skipped.
+//
+// bzl_library rules in directories named "private" or "internal" are visible
+// only to the packages under the directory's parent; other rules are public.
+package bzl
+
+import (
+	"fmt"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	languageName = "bzl"
+	libraryKind  = "bzl_library"
+)
+
+type bzlLang struct {
+	language.BaseLang
+}
+
+// NewLanguage returns a language that generates bzl_library rules.
+func NewLanguage() language.Language {
+	return &bzlLang{}
+}
+
+func (*bzlLang) Name() string {
+	return languageName
+}
+
+// ResolvesConcurrently reports that Resolve may be called concurrently.
+func (*bzlLang) ResolvesConcurrently() bool {
+	return true
+}
+
+func (*bzlLang) Kinds() map[string]rule.KindInfo {
+	return map[string]rule.KindInfo{
+		libraryKind: {
+			MatchAttrs:     []string{"srcs"},
+			NonEmptyAttrs:  map[string]bool{"srcs": true},
+			MergeableAttrs: map[string]bool{"srcs": true},
+			ResolveAttrs:   map[string]bool{"deps": true},
+		},
+	}
+}
+
+func (*bzlLang) Loads() []rule.LoadInfo {
+	panic("ApparentLoads should be called instead")
+}
+
+func (*bzlLang) ApparentLoads(moduleToApparentName func(string) string) []rule.LoadInfo {
+	skylib := moduleToApparentName("bazel_skylib")
+	if skylib == "" {
+		skylib = "bazel_skylib"
+	}
+	return []rule.LoadInfo{
+		{
+			Name:    fmt.Sprintf("@%s//:bzl_library.bzl", skylib),
+			Symbols: []string{libraryKind},
+		},
+	}
+}
+
+func (*bzlLang) Fix(c *config.Config, f *rule.File) {}
@@ -0,0 +1,232 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bzl
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	bzl "github.com/bazelbuild/buildtools/build"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateAndResolve(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "def.bzl",
+			Content: `
+load("//lib:paths.bzl", "paths")
+load("//lib/private:impl.bzl", _impl = "impl")
+load("@rules_go//go:def.bzl", "go_library")
+load("@rules_cc//cc:defs.bzl", "cc_library")
+load("@bazel_tools//tools/build_defs/repo:http.bzl", "http_archive")
+`,
+		},
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:resolve bzl @rules_go//go:def.bzl @rules_go//go:def_lib\n",
+		},
+		{
+			Path: "lib/BUILD.bazel",
+			Content: `
+load("@bazel_skylib//:bzl_library.bzl", "bzl_library")
+
+bzl_library(
+    name = "removed",
+    srcs = ["removed.bzl"],
+)
+
+bzl_library(
+    name = "all",
+    srcs = [":paths.bzl"],
+)
+`,
+		},
+		{Path: "lib/paths.bzl", Content: `load(":strings.bzl", "strings")`},
+		{Path: "lib/strings.bzl"},
+		{Path: "lib/README.md"},
+		{Path: "lib/private/impl.bzl", Content: `load("@my_repo//lib:strings.bzl", "strings")`},
+	})
+	defer cleanup()
+
+	lang := NewLanguage()
+	c := testtools.NewTestConfig(t, []config.Configurer{
+		&config.CommonConfigurer{},
+		&resolve.Configurer{},
+	}, []language.Language{lang}, []string{"-repo_root=" + dir})
+	c.RepoName = "my_repo"
+	root, err := rule.LoadFile(filepath.Join(dir, "BUILD.bazel"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	(&resolve.Configurer{}).Configure(c, "", root)
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver {
+		if r.Kind() == libraryKind {
+			return lang
+		}
+		return nil
+	}, nil)
+
+	type pkgResult struct {
+		f       *rule.File
+		rules   []*rule.Rule
+		imports []interface{}
+	}
+	pkgs := map[string]*pkgResult{}
+	for _, rel := range []string{"", "lib", "lib/private"} {
+		pkgDir := filepath.Join(dir, filepath.FromSlash(rel))
+		f, err := rule.LoadFile(filepath.Join(pkgDir, "BUILD.bazel"), rel)
+		if err != nil {
+			f = rule.EmptyFile(filepath.Join(pkgDir, "BUILD.bazel"), rel)
+		}
+		ents, err := c.ReadDir(pkgDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var regularFiles []string
+		for _, ent := range ents {
+			if !ent.IsDir() {
+				regularFiles = append(regularFiles, ent.Name())
+			}
+		}
+		res := lang.GenerateRules(language.GenerateArgs{
+			Config:       c,
+			Dir:          pkgDir,
+			Rel:          rel,
+			File:         f,
+			RegularFiles: regularFiles,
+		})
+		for _, r := range res.Empty {
+			for _, old := range f.Rules {
+				if old.Name() == r.Name() {
+					old.Delete()
+				}
+			}
+		}
+		pr := &pkgResult{f: f}
+		for i, r := range res.Gen {
+			if old := findRule(f, r); old != nil {
+				old.SetAttr("srcs", r.AttrStrings("srcs"))
+				r = old
+			} else {
+				r.Insert(f)
+			}
+			ix.AddRule(c, r, f)
+			pr.rules = append(pr.rules, r)
+			pr.imports = append(pr.imports, res.Imports[i])
+		}
+		pkgs[rel] = pr
+	}
+	ix.Finish()
+	for _, pr := range pkgs {
+		for i, r := range pr.rules {
+			lang.Resolve(c, ix, nil, r, pr.imports[i], label.New(c.RepoName, pr.f.Pkg, r.Name()))
+		}
+		pr.f.Sync()
+	}
+
+	for rel, want := range map[string]string{
+		"": `
+# gazelle:resolve bzl @rules_go//go:def.bzl @rules_go//go:def_lib
+
+bzl_library(
+    name = "def",
+    srcs = ["def.bzl"],
+    visibility = ["//visibility:public"],
+    deps = [
+        "//lib:all",
+        "//lib/private:impl",
+        "@rules_go//go:def_lib",
+    ],
+)
+`,
+		"lib": `
+load("@bazel_skylib//:bzl_library.bzl", "bzl_library")
+
+bzl_library(
+    name = "all",
+    srcs = ["paths.bzl"],
+    deps = [":strings"],
+)
+
+bzl_library(
+    name = "strings",
+    srcs = ["strings.bzl"],
+    visibility = ["//visibility:public"],
+)
+`,
+		"lib/private": `
+bzl_library(
+    name = "impl",
+    srcs = ["impl.bzl"],
+    visibility = ["//lib:__subpackages__"],
+    deps = ["//lib:strings"],
+)
+`,
+	} {
+		got := strings.TrimSpace(string(bzl.Format(pkgs[rel].f.File)))
+		if diff := cmp.Diff(strings.TrimSpace(want), got); diff != "" {
+			t.Errorf("%q (-want,+got):\n%s", rel, diff)
+		}
+	}
+
+	// Loads from other repositories without a resolve directive aren't
+	// guessed.
+	var unresolved []string
+	for _, err := range ix.UnresolvedErrors() {
+		unresolved = append(unresolved, err.Error())
+	}
+	if len(unresolved) != 1 || !strings.Contains(unresolved[0], "@rules_cc//cc:defs.bzl") {
+		t.Errorf("got unresolved errors %q; want one for @rules_cc//cc:defs.bzl", unresolved)
+	}
+}
+
+// findRule returns the rule in f with the same srcs as r, standing in for
+// the merger's matching in this test.
+func findRule(f *rule.File, r *rule.Rule) *rule.Rule {
+	for _, old := range f.Rules {
+		if old.Kind() != r.Kind() {
+			continue
+		}
+		for _, src := range old.AttrStrings("srcs") {
+			if strings.TrimPrefix(src, ":") == r.AttrStrings("srcs")[0] {
+				return old
+			}
+		}
+	}
+	return nil
+}
+
+func TestLibraryVisibility(t *testing.T) {
+	for rel, want := range map[string]string{
+		"":                     "//visibility:public",
+		"lib":                  "//visibility:public",
+		"internal":             "//:__subpackages__",
+		"go/private/rules":     "//go:__subpackages__",
+		"a/internal/b/private": "//a:__subpackages__",
+	} {
+		if got := libraryVisibility(rel); got != want {
+			t.Errorf("libraryVisibility(%q) = %q; want %q", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bzl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/repo"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// Imports returns the labels of the .bzl files in r's srcs, so that loads of
+// those files resolve to r.
+func (*bzlLang) Imports(c *config.Config, r *rule.Rule, f *rule.File) []resolve.ImportSpec {
+	var imports []resolve.ImportSpec
+	for _, src := range r.AttrStrings("srcs") {
+		if !strings.HasSuffix(src, ".bzl") {
+			continue
+		}
+		l, err := label.Parse(src)
+		if err != nil {
+			continue
+		}
+		if l.Repo == c.RepoName {
+			l.Repo = ""
+		}
+		if l.Relative {
+			l = label.New("", f.Pkg, l.Name)
+		}
+		if l.Repo != "" {
+			continue
+		}
+		imports = append(imports, resolve.ImportSpec{Lang: languageName, Imp: l.String()})
+	}
+	return imports
+}
+
+func (*bzlLang) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, r *rule.Rule, importsRaw interface{}, from label.Label) {
+	if importsRaw == nil {
+		// may not be set in tests.
+		return
+	}
+	imports := importsRaw.([]label.Label)
+	r.DelAttr("deps")
+	depSet := make(map[string]bool)
+	for _, imp := range imports {
+		l, err := resolveLoad(c, ix, imp, from)
+		if err == errSkipLoad {
+			continue
+		} else if err != nil {
+			ix.ReportUnresolved(err)
+			continue
+		}
+		depSet[l.Rel(from.Repo, from.Pkg).String()] = true
+	}
+	if len(depSet) > 0 {
+		deps := make([]string, 0, len(depSet))
+		for dep := range depSet {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		r.SetAttr("deps", deps)
+	}
+}
+
+var errSkipLoad = errors.New("load does not need a dependency")
+
+// resolveLoad returns the label of the bzl_library that provides the loaded
+// file imp. Loads from other repositories are only resolved with resolve
+// directives.
+func resolveLoad(c *config.Config, ix *resolve.RuleIndex, imp, from label.Label) (label.Label, error) {
+	spec := resolve.ImportSpec{Lang: languageName, Imp: imp.String()}
+	if l, ok := resolve.FindRuleWithOverride(c, spec, languageName); ok {
+		return l, nil
+	}
+
+	if imp.Repo == "bazel_tools" {
+		return label.NoLabel, errSkipLoad
+	}
+
+	matches := ix.FindRulesByImportWithConfig(c, spec, languageName)
+	if len(matches) == 0 {
+		if imp.Repo != "" {
+			// Rules in other repositories aren't indexed, and their names
+			// can't be guessed from the file name.
+			return label.NoLabel, fmt.Errorf("no bzl_library provides %s, loaded by %s; add a '# gazelle:resolve bzl %s <label>' directive", imp, from, imp)
+		}
+		return label.NoLabel, fmt.Errorf("no bzl_library provides %s, loaded by %s", imp, from)
+	}
+	if len(matches) > 1 {
+		return label.NoLabel, fmt.Errorf("multiple rules (%s and %s) provide %s, loaded by %s", matches[0].Label, matches[1].Label, imp, from)
+	}
+	if matches[0].IsSelfImport(from) {
+		return label.NoLabel, errSkipLoad
+	}
+	return matches[0].Label, nil
+}
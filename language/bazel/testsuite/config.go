@@ -0,0 +1,87 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testsuite
+
+import (
+	"strings"
+
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	nameDirective = "test_suite_name"
+	tagsDirective = "test_suite_tags"
+
+	defaultSuiteName = "all_tests"
+)
+
+type suiteConfig struct {
+	// name is the name of the generated test_suite, or "" if generation is
+	// turned off.
+	name string
+
+	// tags are the tags for which additional suites are generated.
+	tags []string
+}
+
+func getSuiteConfig(c *config.Config) *suiteConfig {
+	sc, _ := c.Exts[languageName].(*suiteConfig)
+	if sc == nil {
+		return &suiteConfig{name: defaultSuiteName}
+	}
+	return sc
+}
+
+func (*testSuiteLang) KnownDirectives() []string {
+	return []string{nameDirective, tagsDirective}
+}
+
+func (*testSuiteLang) Configure(c *config.Config, rel string, f *rule.File) {
+	sc := *getSuiteConfig(c)
+	c.Exts[languageName] = &sc
+	if f == nil {
+		return
+	}
+	for _, d := range f.Directives {
+		switch d.Key {
+		case nameDirective:
+			switch name := strings.TrimSpace(d.Value); name {
+			case "":
+				sc.name = defaultSuiteName
+			case "off":
+				sc.name = ""
+			default:
+				sc.name = name
+			}
+		case tagsDirective:
+			sc.tags = nil
+			for _, tag := range strings.Split(d.Value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					sc.tags = append(sc.tags, tag)
+				}
+			}
+		case "reset":
+			if v2config.ResetsDirective(d, nameDirective) {
+				sc.name = defaultSuiteName
+			}
+			if v2config.ResetsDirective(d, tagsDirective) {
+				sc.tags = nil
+			}
+		}
+	}
+}
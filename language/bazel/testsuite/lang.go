@@ -0,0 +1,148 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testsuite generates a test_suite rule in each package that has
+// tests, listing the test rules in the package. The suite's tests are kept in
+// sync as tests are added and removed, and the suite is deleted when the
+// package has no more tests.
+//
+// A rule is a test if its kind ends with "_test". Tests generated by other
+// languages are included, as are test rules already in the build file. Since
+// this language sees only the rules generated by languages before it, it
+// must come after them in the languages of a gazelle_binary.
+//
+// The suite is named "all_tests" by default. This can be changed with the
+// directive
+//
+//	# gazelle:test_suite_name <name>
+//
+// and turned off for a directory and its subdirectories with
+// "# gazelle:test_suite_name off". The directive
+//
+//	# gazelle:test_suite_tags <tag>,...
+//
+// additionally generates a suite named "<name>_<tag>" for each tag, with the
+// same tests and the tag in its tags attribute, so that Bazel runs only the
+// tests with that tag.
+package testsuite
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	languageName = "test_suite"
+	suiteKind    = "test_suite"
+)
+
+type testSuiteLang struct {
+	language.BaseLang
+}
+
+// NewLanguage returns a language that generates test_suite rules.
+func NewLanguage() language.Language {
+	return &testSuiteLang{}
+}
+
+func (*testSuiteLang) Name() string {
+	return languageName
+}
+
+// ResolvesConcurrently reports that Resolve may be called concurrently.
+func (*testSuiteLang) ResolvesConcurrently() bool {
+	return true
+}
+
+func (*testSuiteLang) Kinds() map[string]rule.KindInfo {
+	return map[string]rule.KindInfo{
+		suiteKind: {
+			NonEmptyAttrs:  map[string]bool{"tests": true},
+			MergeableAttrs: map[string]bool{"tests": true},
+		},
+	}
+}
+
+func (*testSuiteLang) Loads() []rule.LoadInfo {
+	panic("ApparentLoads should be called instead")
+}
+
+func (*testSuiteLang) ApparentLoads(func(string) string) []rule.LoadInfo {
+	return nil
+}
+
+func (*testSuiteLang) GenerateRules(args language.GenerateArgs) language.GenerateResult {
+	var res language.GenerateResult
+	sc := getSuiteConfig(args.Config)
+	if sc.name == "" {
+		return res
+	}
+
+	tests := packageTests(args)
+	names := []string{sc.name}
+	for _, tag := range sc.tags {
+		names = append(names, sc.name+"_"+tag)
+	}
+	for i, name := range names {
+		r := rule.NewRule(suiteKind, name)
+		if len(tests) == 0 {
+			res.Empty = append(res.Empty, r)
+			continue
+		}
+		r.SetAttr("tests", tests)
+		if i > 0 {
+			r.SetAttr("tags", []string{sc.tags[i-1]})
+		}
+		res.Gen = append(res.Gen, r)
+		res.Imports = append(res.Imports, nil)
+	}
+	return res
+}
+
+// packageTests returns sorted relative labels of the test rules that will be
+// in the package: tests generated by other languages and tests already in
+// the build file that aren't about to be deleted as empty.
+func packageTests(args language.GenerateArgs) []string {
+	testSet := make(map[string]bool)
+	for _, r := range args.OtherGen {
+		if isTest(r) {
+			testSet[r.Name()] = true
+		}
+	}
+	if args.File != nil {
+		removed := make(map[string]bool)
+		for _, r := range args.OtherEmpty {
+			removed[r.Kind()+":"+r.Name()] = true
+		}
+		for _, r := range args.File.Rules {
+			if isTest(r) && !removed[r.Kind()+":"+r.Name()] {
+				testSet[r.Name()] = true
+			}
+		}
+	}
+	tests := make([]string, 0, len(testSet))
+	for name := range testSet {
+		tests = append(tests, ":"+name)
+	}
+	sort.Strings(tests)
+	return tests
+}
+
+func isTest(r *rule.Rule) bool {
+	return strings.HasSuffix(r.Kind(), "_test") && r.Name() != ""
+}
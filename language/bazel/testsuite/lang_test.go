@@ -0,0 +1,153 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testsuite
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/internal/language/langtest"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateRules(t *testing.T) {
+	for _, tc := range []struct {
+		desc, root, old, want string
+		gen, empty            []string
+	}{
+		{
+			desc: "generated_tests",
+			gen:  []string{"go_library:lib", "go_test:lib_test", "sh_test:a_test"},
+			want: `
+test_suite(
+    name = "all_tests",
+    tests = [
+        ":a_test",
+        ":lib_test",
+    ],
+)
+`,
+		}, {
+			desc: "existing_tests",
+			gen:  []string{"go_test:lib_test"},
+			old: `
+py_test(name = "manual_test")
+
+test_suite(
+    name = "all_tests",
+    tests = [
+        ":lib_test",
+        ":old_test",
+    ],
+    visibility = ["//visibility:public"],
+)
+`,
+			want: `
+py_test(name = "manual_test")
+
+test_suite(
+    name = "all_tests",
+    tests = [
+        ":lib_test",
+        ":manual_test",
+    ],
+    visibility = ["//visibility:public"],
+)
+`,
+		}, {
+			desc:  "delete_empty",
+			gen:   []string{"go_library:lib"},
+			empty: []string{"go_test:lib_test"},
+			old: `
+go_test(name = "lib_test")
+
+test_suite(
+    name = "all_tests",
+    tests = [":lib_test"],
+)
+`,
+			want: `go_test(name = "lib_test")`,
+		}, {
+			desc: "name_and_tags",
+			root: `
+# gazelle:test_suite_name tests
+# gazelle:test_suite_tags unit,integration
+`,
+			gen: []string{"go_test:lib_test"},
+			want: `
+test_suite(
+    name = "tests",
+    tests = [":lib_test"],
+)
+
+test_suite(
+    name = "tests_unit",
+    tags = ["unit"],
+    tests = [":lib_test"],
+)
+
+test_suite(
+    name = "tests_integration",
+    tags = ["integration"],
+    tests = [":lib_test"],
+)
+`,
+		}, {
+			desc: "off",
+			root: `# gazelle:test_suite_name off`,
+			gen:  []string{"go_test:lib_test"},
+			want: ``,
+		}, {
+			desc: "reset",
+			root: `
+# gazelle:test_suite_name off
+# gazelle:test_suite_tags unit
+`,
+			old: `# gazelle:reset test_suite_name,test_suite_tags`,
+			gen: []string{"go_test:lib_test"},
+			want: `
+# gazelle:reset test_suite_name,test_suite_tags
+
+test_suite(
+    name = "all_tests",
+    tests = [":lib_test"],
+)
+`,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := strings.TrimSpace(langtest.Generate(t, NewLanguage(), tc.root, "pkg", tc.old, language.GenerateArgs{
+				OtherGen:   makeRules(tc.gen),
+				OtherEmpty: makeRules(tc.empty),
+			}))
+			if diff := cmp.Diff(strings.TrimSpace(tc.want), got); diff != "" {
+				t.Errorf("(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// makeRules returns rules for "kind:name" specs.
+func makeRules(specs []string) []*rule.Rule {
+	var rules []*rule.Rule
+	for _, spec := range specs {
+		kind, name, _ := strings.Cut(spec, ":")
+		rules = append(rules, rule.NewRule(kind, name))
+	}
+	return rules
+}
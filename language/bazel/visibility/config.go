@@ -19,6 +19,7 @@ import (
 	"flag"
 	"strings"
 
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 )
@@ -79,6 +80,15 @@ func (*visibilityExtension) Configure(c *config.Config, _ string, f *rule.File)
 			for _, feature := range strings.Split(d.Value, ",") {
 				newFeatures = append(newFeatures, feature)
 			}
+		case "reset":
+			if v2config.ResetsDirective(d, _visibilityDirectiveName) {
+				cfg.visibilityTargets = nil
+				newVisTargets = nil
+			}
+			if v2config.ResetsDirective(d, _featureDirectiveName) {
+				cfg.features = nil
+				newFeatures = nil
+			}
 		}
 	}
 
@@ -287,3 +287,35 @@ func Test_MultipleDirectivesAcrossFilesSupercede(t *testing.T) {
 		t.Fatal("expected returned visibility to match '//src:__subpackages__'")
 	}
 }
+
+func Test_ResetDirective(t *testing.T) {
+	file1, err := rule.LoadData("path", "pkg", []byte(`
+# gazelle:default_visibility //src:__subpackages__
+`))
+	if err != nil {
+		t.Fatalf("expected not nil - %+v", err)
+	}
+	file2, err := rule.LoadData("path/path", "pkg", []byte(`
+# gazelle:reset default_visibility
+`))
+	if err != nil {
+		t.Fatalf("expected not nil - %+v", err)
+	}
+
+	cfg := config.New()
+	ext := visibility.NewLanguage()
+	ext.Configure(cfg, "path", file1)
+
+	// clone the config as if we were decending through Walk
+	cfg2 := cfg.Clone()
+	ext.Configure(cfg2, "path/path", file2)
+
+	res := ext.GenerateRules(language.GenerateArgs{
+		Config: cfg2,
+		File:   rule.EmptyFile("path/path/file", "pkg"),
+	})
+
+	if len(res.Gen) != 0 {
+		t.Fatal("expected array of length 0")
+	}
+}
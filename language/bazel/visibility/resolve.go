@@ -32,6 +32,11 @@ func (*visibilityExtension) Name() string {
 	return _extName
 }
 
+// ResolvesConcurrently reports that Resolve may be called concurrently.
+func (*visibilityExtension) ResolvesConcurrently() bool {
+	return true
+}
+
 // Imports noops because no imports are needed to leverage this functionality.
 func (*visibilityExtension) Imports(c *config.Config, r *rule.Rule, f *rule.File) []resolve.ImportSpec {
 	return nil
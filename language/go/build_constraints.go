@@ -20,8 +20,9 @@ import (
 	"bytes"
 	"fmt"
 	"go/build/constraint"
-	"os"
 	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
 )
 
 // readTags reads and extracts build tags from the block of comments
@@ -29,8 +30,8 @@ import (
 // rest of the file by a blank line. Each string in the returned slice
 // is the trimmed text of a line after a "+build" prefix.
 // Based on go/build.Context.shouldBuild.
-func readTags(path string) (*buildTags, error) {
-	f, err := os.Open(path)
+func readTags(c *config.Config, path string) (*buildTags, error) {
+	f, err := c.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -21,18 +21,19 @@ import (
 	"fmt"
 	"go/build"
 	"log"
-	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	gzflag "github.com/bazelbuild/bazel-gazelle/flag"
 	"github.com/bazelbuild/bazel-gazelle/internal/module"
 	"github.com/bazelbuild/bazel-gazelle/internal/version"
 	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/pathtools"
 	"github.com/bazelbuild/bazel-gazelle/repo"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 	bzl "github.com/bazelbuild/buildtools/build"
@@ -55,6 +56,14 @@ type goConfig struct {
 	// -build_tags or # gazelle:build_tags. Some tags, like gc, are always on.
 	genericTags map[string]bool
 
+	// goBuildTags is a set of tags that the sources in a directory and its
+	// subdirectories are compiled with. Unlike genericTags, these tags aren't
+	// deferred to Bazel: files that require them are included, and files
+	// that require their negation are excluded. Set with
+	// # gazelle:go_build_tags, which replaces the set instead of modifying
+	// it, so the map may be shared between configurations.
+	goBuildTags map[string]bool
+
 	// prefix is a prefix of an import path, used to generate importpath
 	// attributes. Set with -go_prefix or # gazelle:prefix.
 	prefix string
@@ -67,6 +76,20 @@ type goConfig struct {
 	// to infer an importpath for a rule without setting the prefix.
 	prefixSet bool
 
+	// importPathOverride is the import path of libraries in the directory
+	// importPathOverrideRel, used instead of one inferred from the prefix.
+	// Unlike prefix, it doesn't apply to subdirectories. Set with
+	// # gazelle:importpath.
+	importPathOverride    string
+	importPathOverrideRel string
+
+	// extraSrcs are sources added to the Go package in the directory
+	// extraSrcsRel with the go_extra_srcs directive. Each one is a label or
+	// a slash-separated path relative to that directory, which may have
+	// wildcards in its last element.
+	extraSrcs    []string
+	extraSrcsRel string
+
 	// importMapPrefix is a prefix of a package path, used to generate importmap
 	// attributes. Set with # gazelle:importmap_prefix.
 	importMapPrefix string
@@ -75,6 +98,12 @@ type goConfig struct {
 	// was set ("" for the root directory).
 	importMapPrefixRel string
 
+	// importMapOverrides set importmap attributes for libraries whose import
+	// paths start with a given prefix, taking precedence over importMapPrefix.
+	// The longest matching prefix is used. Set with
+	// # gazelle:importmap_override.
+	importMapOverrides []importMapOverride
+
 	// depMode determines how imports that are not standard, indexed, or local
 	// (under the current prefix) should be resolved.
 	depMode dependencyMode
@@ -89,6 +118,17 @@ type goConfig struct {
 	// imports in external repositories with unknown naming conventions.
 	goNamingConventionExternal namingConvention
 
+	// goNamingConventionAliases indicates whether fix should leave an alias
+	// with the old name when it renames a library for go_naming_convention.
+	goNamingConventionAliases bool
+
+	// binaryLibName is the name of the go_library generated for main
+	// packages with the import and import_alias naming conventions.
+	// "{binary}" is replaced with the name Gazelle derives from the import
+	// path. Empty means the default, "{binary}_lib". Set with
+	// # gazelle:go_binary_library_name.
+	binaryLibName string
+
 	// goProtoCompilers is the protocol buffers compiler(s) to use for go code,
 	// or nil if not explicitly set.
 	goProtoCompilers []string
@@ -97,6 +137,16 @@ type goConfig struct {
 	// or nil if not explicitly set.
 	goGrpcCompilers []string
 
+	// missingGoPackage determines what happens when the .proto files of a
+	// proto_library don't have a go_package option.
+	missingGoPackage missingGoPackageMode
+
+	// grpcSeparateLibrary indicates whether gRPC code for protos with
+	// services should be generated by a go_grpc_library that embeds the
+	// go_proto_library, instead of by adding gRPC compilers to the
+	// go_proto_library. Set with # gazelle:go_grpc_separate_library.
+	grpcSeparateLibrary bool
+
 	// goRepositoryMode is true if Gazelle was invoked by a go_repository rule.
 	// In this mode, we won't go out to the network to resolve external deps.
 	goRepositoryMode bool
@@ -124,6 +174,12 @@ type goConfig struct {
 	// testMode determines how go_test targets are generated.
 	testMode testMode
 
+	// testCombine indicates whether fix should squash go_test rules that
+	// only contain test sources of the package into the package's go_test,
+	// combining split internal and external tests. Set with
+	// # gazelle:go_test_combine.
+	testCombine bool
+
 	// buildDirectives, buildExternalAttr, buildExtraArgsAttr,
 	// buildFileGenerationAttr, buildFileNamesAttr, buildFileProtoModeAttr and
 	// buildTagsAttr are attributes for go_repository rules, set on the command
@@ -156,6 +212,28 @@ type goConfig struct {
 	cppopts    []string
 	cxxopts    []string
 	clinkopts  []string
+
+	// testArgs, testEnv, and testEnvInherit are set as the args, env, and
+	// env_inherit attributes of generated go_test rules. They are set with
+	// the go_test_args, go_test_env, and go_test_env_inherit directives, and
+	// are merged into existing rules without removing values already there.
+	// testEnv is replaced instead of modified, so it may be shared between
+	// configurations.
+	testArgs       []string
+	testEnv        map[string]string
+	testEnvInherit []string
+
+	// stdShadows is a list of import paths of standard library packages
+	// that are resolved like other imports instead of being skipped, because
+	// a library or module outside the standard library provides them. Each
+	// path also covers the packages under it. Set with go_shadow_std.
+	stdShadows []string
+
+	// srcsGroups is the order of the groups that srcs lists of generated
+	// rules are split into, set with the go_srcs_groups directive. When it's
+	// nil, srcs lists are sorted without grouping. It's replaced instead of
+	// modified, so it may be shared between configurations.
+	srcsGroups []srcGroup
 }
 
 // testMode determines how go_test rules are generated.
@@ -225,12 +303,17 @@ func (gc *goConfig) clone() *goConfig {
 	gcCopy.goGrpcCompilers = gc.goGrpcCompilers[:len(gc.goGrpcCompilers):len(gc.goGrpcCompilers)]
 	gcCopy.submodules = gc.submodules[:len(gc.submodules):len(gc.submodules)]
 	gcCopy.goSearch = gc.goSearch[:len(gc.goSearch):len(gc.goSearch)]
+	gcCopy.importMapOverrides = gc.importMapOverrides[:len(gc.importMapOverrides):len(gc.importMapOverrides)]
 	gcCopy.gcGoopts = gc.gcGoopts[:len(gc.gcGoopts):len(gc.gcGoopts)]
 	gcCopy.gcLinkopts = gc.gcLinkopts[:len(gc.gcLinkopts):len(gc.gcLinkopts)]
 	gcCopy.copts = gc.copts[:len(gc.copts):len(gc.copts)]
 	gcCopy.cppopts = gc.cppopts[:len(gc.cppopts):len(gc.cppopts)]
 	gcCopy.cxxopts = gc.cxxopts[:len(gc.cxxopts):len(gc.cxxopts)]
 	gcCopy.clinkopts = gc.clinkopts[:len(gc.clinkopts):len(gc.clinkopts)]
+	gcCopy.testArgs = gc.testArgs[:len(gc.testArgs):len(gc.testArgs)]
+	gcCopy.testEnvInherit = gc.testEnvInherit[:len(gc.testEnvInherit):len(gc.testEnvInherit)]
+	gcCopy.stdShadows = gc.stdShadows[:len(gc.stdShadows):len(gc.stdShadows)]
+	gcCopy.extraSrcs = gc.extraSrcs[:len(gc.extraSrcs):len(gc.extraSrcs)]
 	return &gcCopy
 }
 
@@ -249,6 +332,27 @@ func (gc *goConfig) setBuildTags(tags string) error {
 	return nil
 }
 
+// parseGoBuildTags parses the value of a go_build_tags directive, a comma
+// separated list of build tags. An empty value returns a nil set.
+func parseGoBuildTags(value string) (map[string]bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	tags := make(map[string]bool)
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if strings.HasPrefix(t, "!") {
+			return nil, fmt.Errorf("build tags can't be negated: %s", t)
+		}
+		tags[t] = true
+	}
+	return tags, nil
+}
+
 func getProtoMode(c *config.Config) proto.Mode {
 	if gc := getGoConfig(c); !gc.goGenerateProto {
 		return proto.DisableMode
@@ -401,6 +505,28 @@ type goSearch struct {
 	rel, prefix string
 }
 
+// importMapOverride maps libraries with import paths starting with
+// importPrefix to importmap attributes starting with importMapPrefix.
+type importMapOverride struct {
+	importPrefix, importMapPrefix string
+}
+
+// importMapFor returns the importmap of a library with the import path imp
+// from the longest matching importmap_override, and whether one matched.
+func (gc *goConfig) importMapFor(imp string) (string, bool) {
+	var best *importMapOverride
+	for i := range gc.importMapOverrides {
+		o := &gc.importMapOverrides[i]
+		if pathtools.HasPrefix(imp, o.importPrefix) && (best == nil || len(o.importPrefix) > len(best.importPrefix)) {
+			best = o
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return path.Join(best.importMapPrefix, pathtools.TrimPrefix(imp, best.importPrefix)), true
+}
+
 var (
 	validBuildExternalAttr       = []string{"external", "vendored"}
 	validBuildFileGenerationAttr = []string{"auto", "on", "off", "clean"}
@@ -410,21 +536,35 @@ var (
 func (*goLang) KnownDirectives() []string {
 	return []string{
 		"build_tags",
+		"go_binary_library_name",
+		"go_build_tags",
 		"go_clinkopts",
 		"go_copts",
 		"go_cppopts",
 		"go_cxxopts",
+		"go_extra_srcs",
 		"go_gc_goopts",
 		"go_gc_linkopts",
 		"go_generate_proto",
 		"go_grpc_compilers",
+		"go_grpc_separate_library",
 		"go_naming_convention",
+		"go_naming_convention_aliases",
 		"go_naming_convention_external",
 		"go_proto_compilers",
+		"go_proto_missing_go_package",
 		"go_search",
+		"go_shadow_std",
+		"go_srcs_groups",
 		"go_test",
+		"go_test_args",
+		"go_test_combine",
+		"go_test_env",
+		"go_test_env_inherit",
 		"go_visibility",
+		"importmap_override",
 		"importmap_prefix",
+		"importpath",
 		"prefix",
 	}
 }
@@ -572,7 +712,16 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 				} else {
 					name = repo.Name()
 				}
-				if attr := repo.AttrString("build_naming_convention"); attr == "" {
+				attr := repo.AttrString("build_naming_convention")
+				if attr == "" {
+					// The naming convention may also be set with a directive.
+					for _, d := range repo.AttrStrings("build_directives") {
+						if v, ok := strings.CutPrefix(d, "gazelle:go_naming_convention "); ok {
+							attr = strings.TrimSpace(v)
+						}
+					}
+				}
+				if attr == "" {
 					// No naming convention specified.
 					// go_repsitory uses importAliasNamingConvention by default, so we
 					// could use whichever name.
@@ -590,7 +739,7 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 	}
 
 	if !gc.moduleMode {
-		st, err := os.Stat(filepath.Join(c.RepoRoot, filepath.FromSlash(rel), "go.mod"))
+		st, err := c.Stat(filepath.Join(c.RepoRoot, filepath.FromSlash(rel), "go.mod"))
 		if err == nil && !st.IsDir() {
 			gc.moduleMode = true
 		}
@@ -618,10 +767,25 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 			switch d.Key {
 			case "build_tags":
 				if err := gc.setBuildTags(d.Value); err != nil {
-					log.Print(err)
+					c.ReportDirectiveError(f, d, err)
 					continue
 				}
 
+			case "go_binary_library_name":
+				if err := checkBinaryLibName(d.Value); err != nil {
+					c.ReportDirectiveError(f, d, err)
+					continue
+				}
+				gc.binaryLibName = d.Value
+
+			case "go_build_tags":
+				tags, err := parseGoBuildTags(d.Value)
+				if err != nil {
+					c.ReportDirectiveError(f, d, err)
+					continue
+				}
+				gc.goBuildTags = tags
+
 			case "go_gc_goopts":
 				gc.gcGoopts = appendCompilerFlags(gc.gcGoopts, d.Value)
 
@@ -640,25 +804,45 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 			case "go_clinkopts":
 				gc.clinkopts = appendCompilerFlags(gc.clinkopts, d.Value)
 
+			case "go_extra_srcs":
+				if gc.extraSrcsRel != rel {
+					gc.extraSrcs = nil
+					gc.extraSrcsRel = rel
+				}
+				for _, value := range strings.Fields(d.Value) {
+					if err := checkExtraSrc(value); err != nil {
+						c.ReportDirectiveError(f, d, err)
+						continue
+					}
+					gc.extraSrcs = append(gc.extraSrcs, value)
+				}
+
 			case "go_generate_proto":
 				if goGenerateProto, err := strconv.ParseBool(d.Value); err == nil {
 					gc.goGenerateProto = goGenerateProto
 				} else {
-					log.Printf("parsing go_generate_proto: %v", err)
+					c.ReportDirectiveError(f, d, err)
 				}
 
 			case "go_naming_convention":
 				if nc, err := namingConventionFromString(d.Value); err == nil {
 					gc.goNamingConvention = nc
 				} else {
-					log.Print(err)
+					c.ReportDirectiveError(f, d, err)
+				}
+
+			case "go_naming_convention_aliases":
+				if aliases, err := strconv.ParseBool(d.Value); err == nil {
+					gc.goNamingConventionAliases = aliases
+				} else {
+					c.ReportDirectiveError(f, d, err)
 				}
 
 			case "go_naming_convention_external":
 				if nc, err := namingConventionFromString(d.Value); err == nil {
 					gc.goNamingConventionExternal = nc
 				} else {
-					log.Print(err)
+					c.ReportDirectiveError(f, d, err)
 				}
 
 			case "go_grpc_compilers":
@@ -669,6 +853,13 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 					gc.goGrpcCompilers = splitValue(d.Value)
 				}
 
+			case "go_grpc_separate_library":
+				if separate, err := strconv.ParseBool(d.Value); err == nil {
+					gc.grpcSeparateLibrary = separate
+				} else {
+					c.ReportDirectiveError(f, d, err)
+				}
+
 			case "go_proto_compilers":
 				// Special syntax (empty value) to reset directive.
 				if d.Value == "" {
@@ -677,6 +868,13 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 					gc.goProtoCompilers = splitValue(d.Value)
 				}
 
+			case "go_proto_missing_go_package":
+				if mode, err := missingGoPackageModeFromString(d.Value); err == nil {
+					gc.missingGoPackage = mode
+				} else {
+					c.ReportDirectiveError(f, d, err)
+				}
+
 			case "go_search":
 				// Special syntax (empty value) to reset directive.
 				if d.Value == "" {
@@ -684,11 +882,11 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 				} else {
 					args, err := splitQuoted(d.Value)
 					if err != nil {
-						log.Print(err)
+						c.ReportDirectiveError(f, d, err)
 						continue
 					}
 					if len(args) == 0 || len(args) > 2 {
-						log.Printf("# gazelle:go_search: got %d arguments, expected 1 or 2, a relative directory path and a go prefix", len(args))
+						c.ReportDirectiveError(f, d, fmt.Errorf("got %d arguments, expected 1 or 2, a relative directory path and a go prefix", len(args)))
 						continue
 					}
 					searchDir := args[0]
@@ -703,22 +901,138 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 					gc.goSearch = append(gc.goSearch, goSearch{rel: searchRel, prefix: prefix})
 				}
 
+			case "go_shadow_std":
+				// Special syntax (empty value) to reset directive.
+				if d.Value == "" {
+					gc.stdShadows = nil
+					continue
+				}
+				gc.stdShadows = append(gc.stdShadows, strings.Fields(d.Value)...)
+
+			case "go_srcs_groups":
+				groups, err := parseSrcsGroups(d.Value)
+				if err != nil {
+					c.ReportDirectiveError(f, d, err)
+					continue
+				}
+				gc.srcsGroups = groups
+
 			case "go_test":
 				mode, err := testModeFromString(d.Value)
 				if err != nil {
-					log.Print(err)
+					c.ReportDirectiveError(f, d, err)
 					continue
 				}
 				gc.testMode = mode
 
+			case "go_test_args":
+				gc.testArgs = appendCompilerFlags(gc.testArgs, d.Value)
+
+			case "go_test_combine":
+				if combine, err := strconv.ParseBool(d.Value); err == nil {
+					gc.testCombine = combine
+				} else {
+					c.ReportDirectiveError(f, d, err)
+				}
+
+			case "go_test_env":
+				env, err := parseTestEnv(gc.testEnv, d.Value)
+				if err != nil {
+					c.ReportDirectiveError(f, d, err)
+					continue
+				}
+				gc.testEnv = env
+
+			case "go_test_env_inherit":
+				gc.testEnvInherit = appendCompilerFlags(gc.testEnvInherit, d.Value)
+
 			case "go_visibility":
 				gc.goVisibility = append(gc.goVisibility, strings.TrimSpace(d.Value))
 
+			case "reset":
+				if v2config.ResetsDirective(d, "go_visibility") {
+					gc.goVisibility = nil
+				}
+				if v2config.ResetsDirective(d, "go_build_tags") {
+					gc.goBuildTags = nil
+				}
+				if v2config.ResetsDirective(d, "importmap_override") {
+					gc.importMapOverrides = nil
+				}
+				if v2config.ResetsDirective(d, "go_search") {
+					gc.goSearch = nil
+				}
+				if v2config.ResetsDirective(d, "go_gc_goopts") {
+					gc.gcGoopts = nil
+				}
+				if v2config.ResetsDirective(d, "go_gc_linkopts") {
+					gc.gcLinkopts = nil
+				}
+				if v2config.ResetsDirective(d, "go_copts") {
+					gc.copts = nil
+				}
+				if v2config.ResetsDirective(d, "go_cppopts") {
+					gc.cppopts = nil
+				}
+				if v2config.ResetsDirective(d, "go_cxxopts") {
+					gc.cxxopts = nil
+				}
+				if v2config.ResetsDirective(d, "go_clinkopts") {
+					gc.clinkopts = nil
+				}
+				if v2config.ResetsDirective(d, "go_test_args") {
+					gc.testArgs = nil
+				}
+				if v2config.ResetsDirective(d, "go_test_env") {
+					gc.testEnv = nil
+				}
+				if v2config.ResetsDirective(d, "go_test_env_inherit") {
+					gc.testEnvInherit = nil
+				}
+				if v2config.ResetsDirective(d, "go_shadow_std") {
+					gc.stdShadows = nil
+				}
+				if v2config.ResetsDirective(d, "go_srcs_groups") {
+					gc.srcsGroups = nil
+				}
+
+			case "importmap_override":
+				// Special syntax (empty value) to reset directive.
+				if d.Value == "" {
+					gc.importMapOverrides = nil
+					continue
+				}
+				args, err := splitQuoted(d.Value)
+				if err != nil {
+					c.ReportDirectiveError(f, d, err)
+					continue
+				}
+				if len(args) != 2 {
+					c.ReportDirectiveError(f, d, fmt.Errorf("got %d arguments, expected 2, an import path prefix and an importmap prefix", len(args)))
+					continue
+				}
+				gc.importMapOverrides = append(gc.importMapOverrides, importMapOverride{importPrefix: args[0], importMapPrefix: args[1]})
+
 			case "importmap_prefix":
 				gc.importMapPrefix = d.Value
 				gc.importMapPrefixRel = rel
 
+			case "importpath":
+				if d.Value == "" {
+					continue
+				}
+				if err := checkPrefix(d.Value); err != nil {
+					c.ReportDirectiveError(f, d, fmt.Errorf("invalid importpath: %q", d.Value))
+					continue
+				}
+				gc.importPathOverride = d.Value
+				gc.importPathOverrideRel = rel
+
 			case "prefix":
+				if err := checkPrefix(d.Value); err != nil {
+					c.ReportDirectiveError(f, d, err)
+					continue
+				}
 				setPrefix(d.Value)
 			}
 		}
@@ -747,7 +1061,7 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 		if !gc.prefixSet {
 			// Parse the module directive out of the go.mod file, if present.
 			goModPath := filepath.Join(c.RepoRoot, filepath.FromSlash(rel), "go.mod")
-			goMod, err := os.ReadFile(goModPath)
+			goMod, err := c.ReadFile(goModPath)
 			// Reading the go.mod file is best-effort and may fail for various reasons, such as
 			// the file not existing or being a directory. Do not report errors.
 			if err == nil {
@@ -789,7 +1103,8 @@ func splitValue(value string) []string {
 }
 
 // appendCompilerFlags parses the value of a compiler/linker flag directive
-// (go_gc_goopts, go_copts, ...) and appends the flags to flags. Flags are
+// (go_gc_goopts, go_copts, ...), or of go_test_args or go_test_env_inherit,
+// and appends the flags to flags. Flags are
 // separated by whitespace, and may be quoted to include spaces, e.g.
 // '# gazelle:go_gc_goopts -N -l' yields ["-N", "-l"]. Commas are not
 // separators, so flags that contain commas such as "-Wl,-rpath,/libs" are
@@ -819,7 +1134,7 @@ Update io_bazel_rules_go to a newer version in your WORKSPACE file.`
 		// Bazel has already fetched io_bazel_rules_go. We can read its version
 		// from //go:def.bzl.
 		defBzlPath := filepath.Join(rulesGoPath, "go", "def.bzl")
-		defBzlContent, err := os.ReadFile(defBzlPath)
+		defBzlContent, err := c.ReadFile(defBzlPath)
 		if err != nil {
 			return nil, err
 		}
@@ -892,7 +1207,7 @@ func detectNamingConvention(c *config.Config, rootFile *rule.File) namingConvent
 		var f *rule.File
 		for _, name := range c.ValidBuildFileNames {
 			fpath := filepath.Join(dir, name)
-			data, err := os.ReadFile(fpath)
+			data, err := c.ReadFile(fpath)
 			if err != nil {
 				continue
 			}
@@ -914,7 +1229,7 @@ func detectNamingConvention(c *config.Config, rootFile *rule.File) namingConvent
 		}
 	}
 
-	ents, err := os.ReadDir(c.RepoRoot)
+	ents, err := c.ReadDir(c.RepoRoot)
 	if err != nil {
 		return importNamingConvention
 	}
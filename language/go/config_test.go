@@ -234,6 +234,138 @@ func TestCompilerFlagDirectives(t *testing.T) {
 	}
 }
 
+func TestGoBuildTagsDirective(t *testing.T) {
+	c, _, cexts := testConfig(t, "-build_tags=deferred")
+	configure := func(rel, content string) {
+		f, err := rule.LoadData(filepath.FromSlash(rel+"/BUILD.bazel"), rel, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cext := range cexts {
+			cext.Configure(c, rel, f)
+		}
+	}
+
+	configure("test", "# gazelle:go_build_tags foo, bar\n")
+	gc := getGoConfig(c)
+	if diff := cmp.Diff(map[string]bool{"foo": true, "bar": true}, gc.goBuildTags); diff != "" {
+		t.Errorf("go_build_tags (-want, +got): %s", diff)
+	}
+	if !gc.genericTags["deferred"] {
+		t.Error("go_build_tags should not change tags set with -build_tags")
+	}
+
+	// A later directive replaces the set instead of adding to it.
+	configure("test/child", "# gazelle:go_build_tags baz\n")
+	if diff := cmp.Diff(map[string]bool{"baz": true}, getGoConfig(c).goBuildTags); diff != "" {
+		t.Errorf("go_build_tags in child (-want, +got): %s", diff)
+	}
+
+	// Negated tags are rejected, leaving the set unchanged.
+	configure("test/child/negated", "# gazelle:go_build_tags !baz\n")
+	if diff := cmp.Diff(map[string]bool{"baz": true}, getGoConfig(c).goBuildTags); diff != "" {
+		t.Errorf("go_build_tags after negated tag (-want, +got): %s", diff)
+	}
+
+	// An empty value clears the set.
+	configure("test/child/empty", "# gazelle:go_build_tags\n")
+	if got := getGoConfig(c).goBuildTags; got != nil {
+		t.Errorf("go_build_tags after empty directive: got %v, want nil", got)
+	}
+}
+
+func TestBinaryLibName(t *testing.T) {
+	for _, tc := range []struct {
+		desc, pattern, imp, pkgName string
+		nc                          namingConvention
+		want                        string
+	}{
+		{desc: "default", imp: "example.com/cmd/foo", pkgName: "main", nc: importNamingConvention, want: "foo_lib"},
+		{desc: "fixed", pattern: "lib", imp: "example.com/cmd/foo", pkgName: "main", nc: importNamingConvention, want: "lib"},
+		{desc: "placeholder", pattern: "{binary}_library", imp: "example.com/cmd/foo/v2", pkgName: "main", nc: importAliasNamingConvention, want: "foo_library"},
+		{desc: "not main", pattern: "lib", imp: "example.com/foo", pkgName: "foo", nc: importNamingConvention, want: "foo"},
+		{desc: "go_default_library", pattern: "lib", imp: "example.com/cmd/foo", pkgName: "main", nc: goDefaultLibraryNamingConvention, want: "go_default_library"},
+		{desc: "empty import path", pattern: "{binary}_library", pkgName: "main", nc: importNamingConvention, want: "lib"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			gc := &goConfig{binaryLibName: tc.pattern}
+			if got := gc.libName(tc.nc, tc.imp, tc.pkgName); got != tc.want {
+				t.Errorf("got %q; want %q", got, tc.want)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"{binary}:lib", "my lib"} {
+		if err := checkBinaryLibName(pattern); err == nil {
+			t.Errorf("checkBinaryLibName(%q): got nil error", pattern)
+		}
+	}
+}
+
+func TestImportMapOverrideDirective(t *testing.T) {
+	c, _, cexts := testConfig(t)
+	f, err := rule.LoadData(filepath.FromSlash("BUILD.bazel"), "", []byte(`
+# gazelle:importmap_override github.com/upstream/mod example.com/repo/forks/mod
+# gazelle:importmap_override github.com/upstream/mod/sub example.com/repo/forks/sub
+# gazelle:importmap_override github.com/too/many args here
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cext := range cexts {
+		cext.Configure(c, "", f)
+	}
+	gc := getGoConfig(c)
+	for _, tc := range []struct {
+		imp, want string
+		ok        bool
+	}{
+		{imp: "github.com/upstream/mod", want: "example.com/repo/forks/mod", ok: true},
+		{imp: "github.com/upstream/mod/pkg", want: "example.com/repo/forks/mod/pkg", ok: true},
+		{imp: "github.com/upstream/mod/sub/pkg", want: "example.com/repo/forks/sub/pkg", ok: true},
+		{imp: "github.com/upstream/module"},
+		{imp: "github.com/too/many"},
+	} {
+		got, ok := gc.importMapFor(tc.imp)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("importMapFor(%q): got %q, %v; want %q, %v", tc.imp, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	sub, err := rule.LoadData(filepath.FromSlash("sub/BUILD.bazel"), "sub", []byte("# gazelle:importmap_override\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cext := range cexts {
+		cext.Configure(c, "sub", sub)
+	}
+	if _, ok := getGoConfig(c).importMapFor("github.com/upstream/mod"); ok {
+		t.Error("importmap_override with an empty value should clear overrides")
+	}
+}
+
+func TestImportPathDirective(t *testing.T) {
+	c, _, cexts := testConfig(t)
+	configure := func(rel, content string) {
+		f, err := rule.LoadData(filepath.FromSlash(path.Join(rel, "BUILD.bazel")), rel, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cext := range cexts {
+			cext.Configure(c, rel, f)
+		}
+	}
+	configure("", "# gazelle:prefix example.com/repo\n")
+	configure("gen", "# gazelle:importpath example.com/custom/gen\n")
+	if got, want := InferImportPath(c, "gen"), "example.com/custom/gen"; got != want {
+		t.Errorf("InferImportPath(gen): got %q; want %q", got, want)
+	}
+	configure("gen/sub", "")
+	if got, want := InferImportPath(c, "gen/sub"), "example.com/repo/gen/sub"; got != want {
+		t.Errorf("InferImportPath(gen/sub): got %q; want %q", got, want)
+	}
+}
+
 func TestVendorConfig(t *testing.T) {
 	c, _, cexts := testConfig(t)
 	gc := getGoConfig(c)
@@ -34,4 +34,8 @@ const (
 	// goProtoSuffix is the suffix applied to the labels of all generated
 	// go_proto_library targets.
 	goProtoSuffix = "_go_proto"
+
+	// goGrpcSuffix is the suffix applied to the labels of go_grpc_library
+	// targets generated with # gazelle:go_grpc_separate_library.
+	goGrpcSuffix = "_go_grpc"
 )
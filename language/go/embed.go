@@ -17,6 +17,7 @@ package golang
 
 import (
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path"
@@ -24,6 +25,8 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	v2walk "github.com/bazel-contrib/bazel-gazelle/v2/walk"
+	"github.com/bazelbuild/bazel-gazelle/config"
 	"golang.org/x/mod/module"
 )
 
@@ -56,18 +59,23 @@ func (f *embeddableNode) isHidden() bool {
 // to reference those files if they aren't listed in an export_files
 // declaration.
 //
+// Symbolic links to files may be embedded like the files they point to, since
+// Bazel follows them. Links to directories are only followed when a
+// # gazelle:follow directive applies to them, as when Gazelle walks the
+// repository. Broken links are skipped.
+//
 // This function walks subdirectory trees and may be expensive. Don't call it
 // unless a go:embed directive is actually present.
 //
+// c is the configuration for the directory. Subdirectories are read with its
+// file system, and its ValidBuildFileNames identify Bazel packages in
+// subdirectories that Gazelle did not visit.
+//
 // dir is the absolute path to the directory containing the embed directive.
 //
 // rel is the relative path from the workspace root to the same directory
 // (or "" if the directory is the workspace root itself).
 //
-// validBuildFileNames is the configured list of recognized build file names.
-// These are used to identify Bazel packages in subdirectories that Gazelle
-// did not visit.
-//
 // pkgRels is a set of relative paths from the workspace root to directories
 // that contain (or will contain) build files. It doesn't need to contain
 // entries for the entire workspace, but it should contain entries for
@@ -75,7 +83,7 @@ func (f *embeddableNode) isHidden() bool {
 //
 // subdirs, regFiles, and genFiles are lists of subdirectories, regular files,
 // and declared generated files in dir, respectively.
-func newEmbedResolver(dir, rel string, validBuildFileNames []string, pkgRels map[string]bool, subdirs, regFiles, genFiles []string) *embedResolver {
+func newEmbedResolver(c *config.Config, dir, rel string, pkgRels map[string]bool, subdirs, regFiles, genFiles []string) *embedResolver {
 	root := &embeddableNode{entries: []*embeddableNode{}}
 	index := make(map[string]*embeddableNode)
 
@@ -98,54 +106,113 @@ func newEmbedResolver(dir, rel string, validBuildFileNames []string, pkgRels map
 		return f
 	}
 
-	for _, fs := range [...][]string{regFiles, genFiles} {
-		for _, f := range fs {
-			if !isBadEmbedName(f) {
-				add(f, false)
-			}
+	for _, f := range regFiles {
+		if isBadEmbedName(f) {
+			continue
+		}
+		// Regular files include symbolic links to files and to directories
+		// that aren't followed, and broken links. Only files can be embedded.
+		if fi, err := c.Stat(filepath.Join(dir, f)); err != nil {
+			log.Printf("%s: not embedding %s: %v", dir, f, err)
+			continue
+		} else if fi.IsDir() {
+			continue
+		}
+		add(f, false)
+	}
+	for _, f := range genFiles {
+		if !isBadEmbedName(f) {
+			add(f, false)
 		}
 	}
 
-	for _, subdir := range subdirs {
-		err := filepath.Walk(filepath.Join(dir, subdir), func(p string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			fileRel, _ := filepath.Rel(dir, p)
-			fileRel = filepath.ToSlash(fileRel)
-			base := filepath.Base(p)
-			if !info.IsDir() {
-				if !isBadEmbedName(base) {
-					add(fileRel, false)
-					return nil
-				}
-				return nil
+	// visit adds the directory at p, with the path fileRel relative to dir,
+	// and the files in it, unless it's in another Bazel package. ancestors
+	// describes dir, p, and the directories between them, so that symbolic
+	// links to them aren't followed in a loop.
+	var visit func(p, fileRel string, ancestors []fs.FileInfo)
+	visit = func(p, fileRel string, ancestors []fs.FileInfo) {
+		if pkgRels[path.Join(rel, fileRel)] {
+			// Directory contains a Go package and will contain a build file,
+			// if it doesn't already.
+			return
+		}
+		for _, name := range c.ValidBuildFileNames {
+			if bFileInfo, err := c.Stat(filepath.Join(p, name)); err == nil && !bFileInfo.IsDir() {
+				// Directory already contains a build file.
+				return
 			}
+		}
+		ents, err := c.ReadDir(p)
+		if err != nil {
+			log.Printf("listing embeddable files in %s: %v", dir, err)
+			return
+		}
+		add(fileRel, true)
+		for _, ent := range ents {
+			base := ent.Name()
 			if isBadEmbedName(base) {
-				return filepath.SkipDir
-			}
-			if pkgRels[path.Join(rel, fileRel)] {
-				// Directory contains a Go package and will contain a build file,
-				// if it doesn't already.
-				return filepath.SkipDir
+				continue
 			}
-			for _, name := range validBuildFileNames {
-				if bFileInfo, err := os.Stat(filepath.Join(p, name)); err == nil && !bFileInfo.IsDir() {
-					// Directory already contains a build file.
-					return filepath.SkipDir
+			entPath := filepath.Join(p, base)
+			entRel := path.Join(fileRel, base)
+			isDir := ent.IsDir()
+			if ent.Type()&fs.ModeSymlink != 0 {
+				// Bazel follows links to files. Links to directories are only
+				// followed when the walk would follow them.
+				fi, err := c.Stat(entPath)
+				if err != nil {
+					log.Printf("%s: not embedding %s: %v", dir, entRel, err)
+					continue
 				}
+				if fi.IsDir() && (!v2walk.ShouldFollow(c, path.Join(rel, entRel)) || isAncestor(fi, ancestors)) {
+					continue
+				}
+				isDir = fi.IsDir()
+			}
+			if !isDir {
+				add(entRel, false)
+				continue
 			}
-			add(fileRel, true)
-			return nil
-		})
+			fi, err := c.Stat(entPath)
+			if err != nil {
+				log.Printf("listing embeddable files in %s: %v", dir, err)
+				continue
+			}
+			visit(entPath, entRel, append(ancestors[:len(ancestors):len(ancestors)], fi))
+		}
+	}
+	var dirAncestors []fs.FileInfo
+	if fi, err := c.Stat(dir); err == nil {
+		dirAncestors = []fs.FileInfo{fi}
+	}
+	for _, subdir := range subdirs {
+		if isBadEmbedName(subdir) {
+			continue
+		}
+		p := filepath.Join(dir, subdir)
+		fi, err := c.Stat(p)
 		if err != nil {
 			log.Printf("listing embeddable files in %s: %v", dir, err)
+			continue
 		}
+		visit(p, subdir, append(dirAncestors[:len(dirAncestors):len(dirAncestors)], fi))
 	}
 
 	return &embedResolver{files: root.entries}
 }
 
+// isAncestor returns whether fi describes the same directory as one of
+// ancestors.
+func isAncestor(fi fs.FileInfo, ancestors []fs.FileInfo) bool {
+	for _, a := range ancestors {
+		if os.SameFile(fi, a) {
+			return true
+		}
+	}
+	return false
+}
+
 // resolve expands a single go:embed pattern into a list of files that should
 // be included in embedsrcs. Directory paths are not included in the returned
 // list. This means there's no way to embed an empty directory.
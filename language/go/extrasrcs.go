@@ -0,0 +1,174 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/pathtools"
+)
+
+// isExtraSrcLabel returns whether a go_extra_srcs value is a label rather
+// than a path.
+func isExtraSrcLabel(value string) bool {
+	return strings.HasPrefix(value, "//") || strings.HasPrefix(value, ":") || strings.HasPrefix(value, "@")
+}
+
+// checkExtraSrc returns an error if value isn't a valid go_extra_srcs value:
+// a label, or a relative, slash-separated path that may only have wildcards
+// in its last element.
+func checkExtraSrc(value string) error {
+	if isExtraSrcLabel(value) {
+		if _, err := label.Parse(value); err != nil {
+			return err
+		}
+		return nil
+	}
+	if path.IsAbs(value) || filepath.IsAbs(value) {
+		return fmt.Errorf("path %q must be relative to the directory", value)
+	}
+	dir, base := path.Split(value)
+	if strings.ContainsAny(dir, "*?[") {
+		return fmt.Errorf("path %q may only have wildcards in its last element", value)
+	}
+	if _, err := path.Match(base, ""); err != nil {
+		return fmt.Errorf("path %q: %w", value, err)
+	}
+	return nil
+}
+
+// extraSrcsFileInfos returns information about the sources added to the Go
+// package in the directory rel with the go_extra_srcs directive. Paths are
+// expanded to the files they match, and the names of the files are replaced
+// with labels relative to rel. .go files are returned in goInfos, since
+// they're needed to find the package, and other files are returned in
+// otherInfos.
+//
+// The package of a matched file is the closest directory containing it with
+// a build file, or one that Gazelle has already generated a build file for,
+// according to pkgRels. Labels are returned as-is; their extensions decide
+// how they're treated, and labels without recognized extensions are treated
+// like .go files in the library.
+func extraSrcsFileInfos(c *config.Config, rel string, pkgRels map[string]bool) (goInfos, otherInfos []fileInfo) {
+	gc := getGoConfig(c)
+	if gc.extraSrcsRel != rel {
+		return nil, nil
+	}
+	for _, value := range gc.extraSrcs {
+		if isExtraSrcLabel(value) {
+			l, err := label.Parse(value)
+			if err != nil {
+				continue
+			}
+			info := fileNameInfo(l.Name)
+			if info.ext == unknownExt {
+				info.ext = goExt
+			}
+			info.name = value
+			if info.ext == goExt {
+				goInfos = append(goInfos, info)
+			} else {
+				otherInfos = append(otherInfos, info)
+			}
+			continue
+		}
+
+		dirRel, base := path.Split(path.Join(rel, value))
+		dirRel = path.Clean(dirRel)
+		if dirRel == ".." || strings.HasPrefix(dirRel, "../") {
+			log.Printf("%s: go_extra_srcs path %q is outside the repository", rel, value)
+			continue
+		}
+		if dirRel == "." {
+			dirRel = ""
+		}
+		if dirRel == rel {
+			// Files in the directory itself are already sources.
+			continue
+		}
+		dir := filepath.Join(c.RepoRoot, filepath.FromSlash(dirRel))
+		ents, err := c.ReadDir(dir)
+		if err != nil {
+			log.Printf("%s: go_extra_srcs path %q: %v", rel, value, err)
+			continue
+		}
+		pkg, err := findPackageRel(c, dirRel, rel, pkgRels)
+		if err != nil {
+			log.Printf("%s: go_extra_srcs path %q: %v", rel, value, err)
+			continue
+		}
+		matched := false
+		for _, ent := range ents {
+			if ent.IsDir() {
+				continue
+			}
+			if ok, _ := path.Match(base, ent.Name()); !ok {
+				continue
+			}
+			matched = true
+			fileRel := path.Join(dirRel, ent.Name())
+			p := filepath.Join(dir, ent.Name())
+			name := label.New("", pkg, pathtools.TrimPrefix(fileRel, pkg)).Rel("", rel).String()
+			var info fileInfo
+			if strings.HasSuffix(ent.Name(), ".go") {
+				info = goFileInfo(c, p, dirRel)
+				if len(info.embeds) > 0 {
+					log.Printf("%s: go:embed patterns in extra sources are not supported", p)
+					info.embeds = nil
+				}
+				info.name = name
+				goInfos = append(goInfos, info)
+			} else {
+				info = otherFileInfo(c, p)
+				info.name = name
+				otherInfos = append(otherInfos, info)
+			}
+		}
+		if !matched {
+			log.Printf("%s: go_extra_srcs path %q matched no files", rel, value)
+		}
+	}
+	return goInfos, otherInfos
+}
+
+// findPackageRel returns the slash-separated path of the Bazel package
+// containing the directory dirRel: the closest directory with a build file,
+// or one in pkgRels or rel, which will have a build file.
+func findPackageRel(c *config.Config, dirRel, rel string, pkgRels map[string]bool) (string, error) {
+	for d := dirRel; ; d = path.Dir(d) {
+		if d == "." {
+			d = ""
+		}
+		if d == rel || pkgRels[d] {
+			return d, nil
+		}
+		for _, name := range c.ValidBuildFileNames {
+			if fi, err := c.Stat(filepath.Join(c.RepoRoot, filepath.FromSlash(d), name)); err == nil && !fi.IsDir() {
+				return d, nil
+			}
+		}
+		if d == "" {
+			return "", errors.New("no build file found")
+		}
+	}
+}
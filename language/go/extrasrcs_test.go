@@ -0,0 +1,42 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import "testing"
+
+func TestCheckExtraSrc(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "../gen/foo/*.go"},
+		{value: "gen/foo.go"},
+		{value: "//gen/foo:foo.go"},
+		{value: ":foo.go"},
+		{value: "@other//foo:foo.go"},
+		{value: "/abs/foo.go", wantErr: true},
+		{value: "../gen/*/foo.go", wantErr: true},
+		{value: "../gen/[.go", wantErr: true},
+		{value: "//gen/foo:foo.go:bad", wantErr: true},
+	} {
+		err := checkExtraSrc(tc.value)
+		if tc.wantErr && err == nil {
+			t.Errorf("%q: got success; want error", tc.value)
+		} else if !tc.wantErr && err != nil {
+			t.Errorf("%q: %v", tc.value, err)
+		}
+	}
+}
@@ -71,6 +71,10 @@ type fileInfo struct {
 	// isCgo is true for .go files that import "C".
 	isCgo bool
 
+	// isGenerated is true for files generated by other rules in the same
+	// build file.
+	isGenerated bool
+
 	// goos and goarch contain the OS and architecture suffixes in the filename,
 	// if they were present.
 	goos, goarch string
@@ -211,13 +215,13 @@ func fileNameInfo(path_ string) fileInfo {
 // otherFileInfo returns information about a non-.go file. It will parse
 // part of the file to determine build tags. If the file can't be read, an
 // error will be logged, and partial information will be returned.
-func otherFileInfo(path string) fileInfo {
+func otherFileInfo(c *config.Config, path string) fileInfo {
 	info := fileNameInfo(path)
 	if info.ext == unknownExt {
 		return info
 	}
 
-	tags, err := readTags(info.path)
+	tags, err := readTags(c, info.path)
 	if err != nil {
 		log.Printf("%s: error reading file: %v", info.path, err)
 		return info
@@ -232,10 +236,9 @@ func otherFileInfo(path string) fileInfo {
 // will be returned.
 // This function is intended to match go/build.Context.Import.
 // TODD(#53): extract canonical import path
-func goFileInfo(path, srcdir string) fileInfo {
+func goFileInfo(c *config.Config, path, srcdir string) fileInfo {
 	info := fileNameInfo(path)
-	fset := token.NewFileSet()
-	pf, err := parser.ParseFile(fset, info.path, nil, parser.ImportsOnly|parser.ParseComments)
+	fset, pf, err := parseGoImports(c, info.path)
 	if err != nil {
 		log.Printf("%s: error reading go file: %v", info.path, err)
 		return info
@@ -288,7 +291,7 @@ func goFileInfo(path, srcdir string) fileInfo {
 		}
 	}
 
-	tags, err := readTags(info.path)
+	tags, err := readTags(c, info.path)
 	if err != nil {
 		log.Printf("%s: error reading go file: %v", info.path, err)
 		return info
@@ -296,7 +299,7 @@ func goFileInfo(path, srcdir string) fileInfo {
 	info.tags = tags
 
 	if importsEmbed || info.packageName == "main" {
-		pf, err = parser.ParseFile(fset, info.path, nil, parser.ParseComments)
+		fset, pf, err = ParseGoFile(c, info.path)
 		if err != nil {
 			log.Printf("%s: error reading go file: %v", info.path, err)
 			return info
@@ -335,6 +338,60 @@ func goFileInfo(path, srcdir string) fileInfo {
 	return info
 }
 
+// goFileKey identifies Go files parsed by ParseGoFile in the parse cache.
+type goFileKey struct{}
+
+// goImportsKey identifies Go files parsed by parseGoImports in the parse
+// cache.
+type goImportsKey struct{}
+
+// parsedGoFile is a Go file parsed by ParseGoFile.
+type parsedGoFile struct {
+	fset *token.FileSet
+	f    *ast.File
+}
+
+// ParseGoFile returns the syntax tree of the Go file at path, parsed with
+// comments, and the file set that holds its positions. The file is read and
+// parsed once while rules are generated for its directory and shared through
+// c.ParseFile: the Go extension parses files of main packages and files that
+// use //go:embed this way, and other extensions that need syntax trees for Go
+// files should call ParseGoFile instead of parsing files themselves. The
+// returned values must not be modified.
+func ParseGoFile(c *config.Config, path string) (*token.FileSet, *ast.File, error) {
+	v, err := c.ParseFile(path, goFileKey{}, func(path string, data []byte) (any, error) {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, data, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		return parsedGoFile{fset: fset, f: f}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	pf := v.(parsedGoFile)
+	return pf.fset, pf.f, nil
+}
+
+// parseGoImports is like ParseGoFile, but the file is only parsed up to its
+// import declarations.
+func parseGoImports(c *config.Config, path string) (*token.FileSet, *ast.File, error) {
+	v, err := c.ParseFile(path, goImportsKey{}, func(path string, data []byte) (any, error) {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, data, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		return parsedGoFile{fset: fset, f: f}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	pf := v.(parsedGoFile)
+	return pf.fset, pf.f, nil
+}
+
 // saveCgo extracts CFLAGS, CPPFLAGS, CXXFLAGS, and LDFLAGS directives
 // from a comment above a "C" import. This is intended to match logic in
 // go/build.Context.saveCgo.
@@ -595,9 +652,10 @@ func checkConstraints(c *config.Config, os, arch, osSuffix, archSuffix string, t
 
 	if tags != nil {
 		// Treat provided generic tags as "ignored tags", meaning that both
-		// `tag` and `!tag` are considered true when evaluating build constraints
+		// `tag` and `!tag` are considered true when evaluating build constraints.
+		// Tags set with go_build_tags are evaluated normally.
 		isIgnoredTag := func(tag string) bool {
-			return goConf.genericTags[tag]
+			return goConf.genericTags[tag] && !goConf.goBuildTags[tag]
 		}
 
 		tags = newBuildTags(dropNegationForIgnoredTags(tags.expr, isIgnoredTag))
@@ -622,7 +680,7 @@ func checkConstraints(c *config.Config, os, arch, osSuffix, archSuffix string, t
 
 		}
 
-		return goConf.genericTags[tag]
+		return goConf.genericTags[tag] || goConf.goBuildTags[tag]
 	}
 
 	return tags.eval(checker) && cgoTags.eval(checker)
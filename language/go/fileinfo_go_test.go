@@ -180,7 +180,7 @@ var src string
 				t.Fatal(err)
 			}
 
-			got := goFileInfo(path, "")
+			got := goFileInfo(nil, path, "")
 			// Clear fields we don't care about for testing.
 			got = fileInfo{
 				packageName: got.packageName,
@@ -214,7 +214,7 @@ func TestGoFileInfoFailure(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got := goFileInfo(path, "")
+	got := goFileInfo(nil, path, "")
 	want := fileInfo{
 		path:   path,
 		name:   name,
@@ -363,7 +363,7 @@ import "C"
 				t.Fatal(err)
 			}
 
-			got := goFileInfo(path, "")
+			got := goFileInfo(nil, path, "")
 
 			// Clear fields we don't care about for testing.
 			got = fileInfo{
@@ -449,7 +449,7 @@ import "C"
 		t,
 		"-repo_root="+repo,
 		"-go_prefix=example.com/repo")
-	fi := goFileInfo(filepath.Join(sub, "sub.go"), "sub")
+	fi := goFileInfo(nil, filepath.Join(sub, "sub.go"), "sub")
 	pkgs, _ := buildPackages(c, sub, "sub", false, nil, []fileInfo{fi})
 	got, ok := pkgs["sub"]
 	if !ok {
@@ -459,7 +459,7 @@ import "C"
 		name:    "sub",
 		dir:     sub,
 		rel:     "sub",
-		library: goTarget{cgo: true},
+		library: goTarget{cgo: true, cgoSrcs: map[string]bool{"sub.go": true}},
 	}
 	want.library.sources.addGenericString("sub.go")
 	want.library.copts.addGenericString("-Isub/..")
@@ -521,7 +521,7 @@ func TestGoExperimentsTagsIgnored(t *testing.T) {
 			}
 
 			c, _, _ := testConfig(t)
-			fi := goFileInfo(path, "")
+			fi := goFileInfo(nil, path, "")
 			if !checkConstraints(c, "", "", fi.goos, fi.goarch, fi.tags, nil) {
 				t.Fatalf("constraints should be satisfied for %s", tc.desc)
 			}
@@ -537,3 +537,43 @@ func mustParseBuildTag(t *testing.T, in string) constraint.Expr {
 
 	return x
 }
+
+func TestParseGoFileShared(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	c, _, _ := testConfig(t)
+
+	// goFileInfo parses files in main packages with ParseGoFile. Later calls,
+	// including calls with clones of the configuration, share its result.
+	fi := goFileInfo(c, path, "")
+	if !fi.hasMainFunction {
+		t.Fatal("main function not found")
+	}
+	fset, f, err := ParseGoFile(c.Clone(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset2, f2, err := ParseGoFile(c, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fset != fset2 || f != f2 {
+		t.Error("ParseGoFile parsed the file again")
+	}
+	if got := fset.Position(f.Name.Pos()).Filename; got != path {
+		t.Errorf("got position in %s; want %s", got, path)
+	}
+
+	// Once rules are generated for the directory, the tree is dropped.
+	c.ReleaseParsedFiles(dir)
+	_, f3, err := ParseGoFile(c, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f3 == f {
+		t.Error("ParseGoFile returned a tree for a released directory")
+	}
+}
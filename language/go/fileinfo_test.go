@@ -54,7 +54,7 @@ func TestOtherFileInfo(t *testing.T) {
 			}
 			defer os.Remove(tc.name)
 
-			got := otherFileInfo(filepath.Join(dir, tc.name))
+			got := otherFileInfo(nil, filepath.Join(dir, tc.name))
 
 			// Only check that we can extract tags. Everything else is covered
 			// by other tests.
@@ -395,7 +395,7 @@ package main`,
 				t.Fatal(err)
 			}
 
-			if got, err := readTags(path); err != nil {
+			if got, err := readTags(nil, path); err != nil {
 				t.Fatal(err)
 			} else if diff := cmp.Diff(tc.want, got, fileInfoCmpOption); diff != "" {
 				t.Errorf("(-want, +got): %s", diff)
@@ -412,7 +412,7 @@ func TestCheckConstraints(t *testing.T) {
 	defer os.RemoveAll(dir)
 	for _, tc := range []struct {
 		desc                        string
-		genericTags                 map[string]bool
+		genericTags, goBuildTags    map[string]bool
 		os, arch, filename, content string
 		want                        bool
 	}{
@@ -602,6 +602,28 @@ import "C"
 			desc:    "race msan tags negated",
 			content: "//+ build !msan,!race",
 			want:    true,
+		}, {
+			desc:        "go_build_tags satisfied",
+			goBuildTags: map[string]bool{"foo": true},
+			content:     "//go:build foo\n\npackage foo",
+			want:        true,
+		}, {
+			desc:        "go_build_tags negated",
+			goBuildTags: map[string]bool{"foo": true},
+			content:     "//go:build !foo\n\npackage foo",
+			want:        false,
+		}, {
+			desc:        "go_build_tags overrides generic tag",
+			genericTags: map[string]bool{"foo": true},
+			goBuildTags: map[string]bool{"foo": true},
+			content:     "//go:build !foo\n\npackage foo",
+			want:        false,
+		}, {
+			desc:        "go_build_tags with generic tag",
+			genericTags: map[string]bool{"bar": true},
+			goBuildTags: map[string]bool{"foo": true},
+			content:     "//go:build foo && !bar\n\npackage foo",
+			want:        true,
 		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -611,6 +633,7 @@ import "C"
 			if gc.genericTags == nil {
 				gc.genericTags = map[string]bool{"gc": true}
 			}
+			gc.goBuildTags = tc.goBuildTags
 			filename := tc.filename
 			if filename == "" {
 				filename = tc.desc + ".go"
@@ -625,7 +648,7 @@ import "C"
 				t.Fatal(err)
 			}
 
-			fi := goFileInfo(path, "")
+			fi := goFileInfo(nil, path, "")
 			var cgoTags *cgoTagsAndOpts
 			if len(fi.copts) > 0 {
 				cgoTags = fi.copts[0]
@@ -724,7 +747,7 @@ func TestIsOSArchSpecific(t *testing.T) {
 			if err := os.WriteFile(path, []byte(tc.content), 0o666); err != nil {
 				t.Fatal(err)
 			}
-			fi := goFileInfo(path, "")
+			fi := goFileInfo(nil, path, "")
 			var cgoTags *cgoTagsAndOpts
 			if len(fi.copts) > 0 {
 				cgoTags = fi.copts[0]
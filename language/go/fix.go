@@ -16,9 +16,12 @@ limitations under the License.
 package golang
 
 import (
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/language/proto"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 	bzl "github.com/bazelbuild/buildtools/build"
@@ -33,13 +36,15 @@ func (*goLang) Fix(c *config.Config, f *rule.File) {
 	removeLegacyProto(c, f)
 	removeLegacyGazelle(c, f)
 	migrateNamingConvention(c, f)
+	combineTests(c, f)
 }
 
 // migrateNamingConvention renames rules according to go_naming_convention
 // directives.
 func migrateNamingConvention(c *config.Config, f *rule.File) {
 	// Determine old and new names for go_library and go_test.
-	nc := getGoConfig(c).goNamingConvention
+	gc := getGoConfig(c)
+	nc := gc.goNamingConvention
 	importPath := InferImportPath(c, f.Pkg)
 	if importPath == "" {
 		return
@@ -48,12 +53,12 @@ func migrateNamingConvention(c *config.Config, f *rule.File) {
 	if fileContainsGoBinary(c, f) {
 		pkgName = "main"
 	}
-	libName := libNameByConvention(nc, importPath, pkgName)
+	libName := gc.libName(nc, importPath, pkgName)
 	testName := testNameByConvention(nc, importPath)
 	var migrateLibName, migrateTestName string
 	switch nc {
 	case goDefaultLibraryNamingConvention:
-		migrateLibName = libNameByConvention(importNamingConvention, importPath, pkgName)
+		migrateLibName = gc.libName(importNamingConvention, importPath, pkgName)
 		migrateTestName = testNameByConvention(importNamingConvention, importPath)
 	case importNamingConvention, importAliasNamingConvention:
 		migrateLibName = defaultLibName
@@ -98,6 +103,10 @@ func migrateNamingConvention(c *config.Config, f *rule.File) {
 		case "go_library":
 			if r.Name() == migrateLibName && shouldMigrateLib {
 				r.SetName(libName)
+				// import_alias already generates an alias with the old name.
+				if gc.goNamingConventionAliases && nc != importAliasNamingConvention {
+					insertMigrationAlias(f, r, migrateLibName)
+				}
 			}
 		case "go_test":
 			if r.Name() == migrateTestName && shouldMigrateTest {
@@ -110,6 +119,23 @@ func migrateNamingConvention(c *config.Config, f *rule.File) {
 	}
 }
 
+// insertMigrationAlias inserts a deprecated alias named oldName after the
+// library lib, which was renamed from oldName, so that targets in other
+// packages and repositories that depend on the old name keep building.
+// The alias is marked with a keep comment, since Gazelle would otherwise
+// delete it as stale; it should be deleted by hand once it's unused.
+func insertMigrationAlias(f *rule.File, lib *rule.Rule, oldName string) {
+	newLabel := label.New("", f.Pkg, lib.Name())
+	alias := rule.NewRule("alias", oldName)
+	alias.AddComment("# keep")
+	alias.SetAttr("actual", ":"+lib.Name())
+	alias.SetAttr("deprecation", fmt.Sprintf("%s was renamed to %s by a go_naming_convention change. Depend on %s instead.", label.New("", f.Pkg, oldName), newLabel, newLabel))
+	if vis := lib.Attr("visibility"); vis != nil {
+		alias.SetAttr("visibility", vis)
+	}
+	alias.InsertAt(f, lib.Index()+1)
+}
+
 // fileContainsGoBinary returns whether the file has a go_binary rule.
 func fileContainsGoBinary(c *config.Config, f *rule.File) bool {
 	if f == nil {
@@ -172,8 +198,12 @@ func migrateLibraryEmbed(c *config.Config, f *rule.File) {
 }
 
 // migrateGrpcCompilers converts "go_grpc_library" rules into "go_proto_library"
-// rules with a "compilers" attribute.
+// rules with a "compilers" attribute. Nothing is converted when
+// go_grpc_library rules are generated with go_grpc_separate_library.
 func migrateGrpcCompilers(c *config.Config, f *rule.File) {
+	if getGoConfig(c).grpcSeparateLibrary {
+		return
+	}
 	for _, r := range f.Rules {
 		if r.Kind() != "go_grpc_library" || r.ShouldKeep() || r.Attr("compilers") != nil {
 			continue
@@ -279,6 +309,67 @@ func squashXtest(c *config.Config, f *rule.File) {
 	xtest.Delete()
 }
 
+// combineTests squashes go_test rules that only contain test sources in
+// this directory into the go_test with the conventional name when the
+// go_test_combine directive is set. Gazelle puts all of a package's test
+// sources, internal and external, in that rule, so other rules with the
+// same sources would duplicate them. If there's no rule with the
+// conventional name, the first other rule is renamed.
+func combineTests(c *config.Config, f *rule.File) {
+	gc := getGoConfig(c)
+	if !gc.testCombine || gc.testMode == fileTestMode {
+		return
+	}
+	importPath := InferImportPath(c, f.Pkg)
+	if importPath == "" {
+		return
+	}
+	testName := testNameByConvention(gc.goNamingConvention, importPath)
+
+	var itest *rule.Rule
+	var others []*rule.Rule
+	for _, r := range f.Rules {
+		if r.Kind() != "go_test" {
+			continue
+		}
+		if r.Name() == testName {
+			itest = r
+		} else if !r.ShouldKeep() && hasOnlyTestSrcs(r) {
+			others = append(others, r)
+		}
+	}
+	if len(others) == 0 || itest != nil && itest.ShouldKeep() {
+		return
+	}
+	if itest == nil {
+		itest = others[0]
+		itest.SetName(testName)
+		others = others[1:]
+	}
+	for _, r := range others {
+		if err := rule.SquashRules(r, itest, f.Path); err != nil {
+			log.Print(err)
+			continue
+		}
+		r.Delete()
+	}
+}
+
+// hasOnlyTestSrcs returns whether r has srcs, and they're all _test.go files
+// in the same directory.
+func hasOnlyTestSrcs(r *rule.Rule) bool {
+	srcs := r.AttrStrings("srcs")
+	if len(srcs) == 0 {
+		return false
+	}
+	for _, src := range srcs {
+		if !strings.HasSuffix(src, "_test.go") || strings.ContainsAny(src, ":/") {
+			return false
+		}
+	}
+	return true
+}
+
 // flattenSrcs transforms srcs attributes structured as concatenations of
 // lists and selects (generated from PlatformStrings; see
 // extractPlatformStringsExprs for matching details) into a sorted,
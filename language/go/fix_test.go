@@ -891,6 +891,229 @@ go_proto_library(name = "foo_proto")
 	}
 }
 
+func TestFixNamingConventionAliases(t *testing.T) {
+	for _, tc := range []fixTestCase{
+		{
+			desc:             "go_default_library -> import",
+			namingConvention: importNamingConvention,
+			old: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+go_library(
+    name = "go_default_library",
+    srcs = ["foo.go"],
+    importpath = "example.com/foo",
+    visibility = ["//visibility:public"],
+)
+
+go_test(
+    name = "go_default_test",
+    srcs = ["foo_test.go"],
+    embed = [":go_default_library"],
+)
+`,
+			want: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+go_library(
+    name = "foo",
+    srcs = ["foo.go"],
+    importpath = "example.com/foo",
+    visibility = ["//visibility:public"],
+)
+
+# keep
+alias(
+    name = "go_default_library",
+    actual = ":foo",
+    deprecation = "//:go_default_library was renamed to //:foo by a go_naming_convention change. Depend on //:foo instead.",
+    visibility = ["//visibility:public"],
+)
+
+go_test(
+    name = "foo_test",
+    srcs = ["foo_test.go"],
+    embed = [":foo"],
+)
+`,
+		},
+		{
+			desc:             "import -> go_default_library",
+			namingConvention: goDefaultLibraryNamingConvention,
+			old: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "foo",
+    srcs = ["foo.go"],
+    importpath = "example.com/foo",
+)
+`,
+			want: `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "go_default_library",
+    srcs = ["foo.go"],
+    importpath = "example.com/foo",
+)
+
+# keep
+alias(
+    name = "foo",
+    actual = ":go_default_library",
+    deprecation = "//:foo was renamed to //:go_default_library by a go_naming_convention change. Depend on //:go_default_library instead.",
+)
+`,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			testFix(t, tc, func(f *rule.File) {
+				c, langs, _ := testConfig(t,
+					"-go_naming_convention="+tc.namingConvention.String(),
+					"-go_prefix=example.com/foo",
+				)
+				c.ShouldFix = true
+				getGoConfig(c).goNamingConventionAliases = true
+				for _, lang := range langs {
+					lang.Fix(c, f)
+				}
+			})
+		})
+	}
+}
+
+func TestFixTestCombine(t *testing.T) {
+	for _, tc := range []fixTestCase{
+		{
+			desc: "squash external test",
+			old: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+go_library(
+    name = "foo",
+    srcs = ["foo.go"],
+    importpath = "example.com/foo",
+)
+
+go_test(
+    name = "foo_test",
+    srcs = ["foo_test.go"],
+    embed = [":foo"],
+)
+
+go_test(
+    name = "foo_xtest",
+    srcs = ["x_test.go"],
+    deps = [":foo"],
+)
+
+# keep
+go_test(
+    name = "foo_kept_test",
+    srcs = ["kept_test.go"],
+)
+
+go_test(
+    name = "foo_integration_test",
+    srcs = ["//testing:integration_test.go"],
+)
+`,
+			want: `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+go_library(
+    name = "foo",
+    srcs = ["foo.go"],
+    importpath = "example.com/foo",
+)
+
+go_test(
+    name = "foo_test",
+    srcs = [
+        "foo_test.go",
+        "x_test.go",
+    ],
+    embed = [":foo"],
+    deps = [":foo"],
+)
+
+# keep
+go_test(
+    name = "foo_kept_test",
+    srcs = ["kept_test.go"],
+)
+
+go_test(
+    name = "foo_integration_test",
+    srcs = ["//testing:integration_test.go"],
+)
+`,
+		},
+		{
+			desc: "rename when there's no conventional test",
+			old: `load("@io_bazel_rules_go//go:def.bzl", "go_test")
+
+go_test(
+    name = "internal_test",
+    srcs = ["foo_test.go"],
+)
+
+go_test(
+    name = "external_test",
+    srcs = ["x_test.go"],
+)
+`,
+			want: `load("@io_bazel_rules_go//go:def.bzl", "go_test")
+
+go_test(
+    name = "foo_test",
+    srcs = [
+        "foo_test.go",
+        "x_test.go",
+    ],
+)
+`,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			testFix(t, tc, func(f *rule.File) {
+				c, langs, _ := testConfig(t,
+					"-go_naming_convention=import",
+					"-go_prefix=example.com/foo",
+				)
+				getGoConfig(c).testCombine = true
+				for _, lang := range langs {
+					lang.Fix(c, f)
+				}
+			})
+		})
+	}
+}
+
+func TestFixGrpcSeparateLibrary(t *testing.T) {
+	tc := fixTestCase{
+		desc: "go_grpc_library not migrated",
+		old: `load("@io_bazel_rules_go//proto:def.bzl", "go_grpc_library", "go_proto_library")
+
+go_proto_library(
+    name = "foo_go_proto",
+    importpath = "example.com/repo",
+    proto = ":foo_proto",
+)
+
+go_grpc_library(
+    name = "foo_go_grpc",
+    embed = [":foo_go_proto"],
+    importpath = "example.com/repo",
+    proto = ":foo_proto",
+)
+`,
+	}
+	tc.want = tc.old
+	testFix(t, tc, func(f *rule.File) {
+		c, langs, _ := testConfig(t)
+		getGoConfig(c).grpcSeparateLibrary = true
+		for _, lang := range langs {
+			lang.Fix(c, f)
+		}
+	})
+}
+
 func TestFixLoads(t *testing.T) {
 	for _, tc := range []fixTestCase{
 		{
@@ -44,6 +44,10 @@ func (gl *goLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 	// go_proto_library rule already generated.
 	goProtoRules := make(map[string]struct{})
 
+	// This is a collection of proto_library rule names that Go rules aren't
+	// generated for because they have no go_package option.
+	missingGoPackageRules := make(map[string]bool)
+
 	var protoRuleNames []string
 	protoPackages := make(map[string]proto.Package)
 	protoFileInfo := make(map[string]proto.FileInfo)
@@ -63,6 +67,13 @@ func (gl *goLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 			continue
 		}
 		pkg := r.PrivateAttr(proto.PackageKey).(proto.Package)
+		if !checkGoPackage(c, args.Rel, r.Name(), pkg) {
+			// Treat the library like one with a go_proto_library generated by
+			// another extension, so no go_proto_library is generated for it.
+			goProtoRules[":"+r.Name()] = struct{}{}
+			missingGoPackageRules[r.Name()] = true
+			gl.missingGoPackages.add(c, args.Rel, pkg)
+		}
 		protoPackages[r.Name()] = pkg
 		for name, info := range pkg.Files {
 			protoFileInfo[name] = info
@@ -147,11 +158,13 @@ func (gl *goLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 	var er *embedResolver
 	for i, name := range goFiles {
 		path := filepath.Join(args.Dir, name)
-		goFileInfos[i] = goFileInfo(path, srcdir)
+		goFileInfos[i] = goFileInfo(c, path, srcdir)
 		if len(goFileInfos[i].embeds) > 0 && er == nil {
-			er = newEmbedResolver(args.Dir, args.Rel, c.ValidBuildFileNames, gl.goPkgRels, args.Subdirs, args.RegularFiles, args.GenFiles)
+			er = newEmbedResolver(c, args.Dir, args.Rel, gl.goPkgRels, args.Subdirs, args.RegularFiles, args.GenFiles)
 		}
 	}
+	extraGoFileInfos, extraOtherFileInfos := extraSrcsFileInfos(c, args.Rel, gl.goPkgRels)
+	goFileInfos = append(goFileInfos, extraGoFileInfos...)
 	goPackageMap, goFilesWithUnknownPackage := buildPackages(c, args.Dir, args.Rel, hasTestdata, er, goFileInfos)
 
 	// Select a package to generate rules for. If there is no package, create
@@ -162,6 +175,12 @@ func (gl *goLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 		if _, ok := err.(*build.NoGoError); ok {
 			if len(protoPackages) == 1 {
 				for name, ppkg := range protoPackages {
+					if missingGoPackageRules[name] {
+						// Don't generate an empty library, which would delete
+						// an existing one that embeds the go_proto_library.
+						pkg = nil
+						break
+					}
 					if _, ok := goProtoRules[":"+name]; ok {
 						// if a go_proto_library rule already exists for this
 						// proto package, treat it as if the proto package
@@ -280,6 +299,10 @@ func (gl *goLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 	for _, name := range emptyProtoRuleNames {
 		goProtoName := strings.TrimSuffix(name, "_proto") + goProtoSuffix
 		res.Empty = append(res.Empty, rule.NewRule("go_proto_library", goProtoName))
+		if gc.grpcSeparateLibrary {
+			goGrpcName := strings.TrimSuffix(name, "_proto") + goGrpcSuffix
+			res.Empty = append(res.Empty, rule.NewRule("go_grpc_library", goGrpcName))
+		}
 	}
 	if pkg != nil && (pcMode == proto.PackageMode || pcMode == proto.FileMode) && pkg.firstGoFile() == "" {
 		// In proto package mode, don't generate a go_library embedding a
@@ -301,7 +324,12 @@ func (gl *goLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 
 		// Process the other static files.
 		for _, file := range otherFiles {
-			info := otherFileInfo(filepath.Join(args.Dir, file))
+			info := otherFileInfo(c, filepath.Join(args.Dir, file))
+			if err := pkg.addFile(c, er, info, cgo); err != nil {
+				log.Print(err)
+			}
+		}
+		for _, info := range extraOtherFileInfos {
 			if err := pkg.addFile(c, er, info, cgo); err != nil {
 				log.Print(err)
 			}
@@ -329,6 +357,7 @@ func (gl *goLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 				continue
 			}
 			info := fileNameInfo(filepath.Join(args.Dir, f))
+			info.isGenerated = true
 			if err := pkg.addFile(c, er, info, cgo); err != nil {
 				log.Print(err)
 			}
@@ -522,6 +551,7 @@ type generator struct {
 	c                   *config.Config
 	gc                  *goConfig
 	rel                 string
+	file                *rule.File
 	shouldSetVisibility bool
 
 	shouldIndex     bool
@@ -533,6 +563,7 @@ func newGenerator(c *config.Config, gc *goConfig, args language.GenerateArgs) *g
 		c:                   c,
 		gc:                  gc,
 		rel:                 args.Rel,
+		file:                args.File,
 		shouldSetVisibility: shouldSetVisibility(args),
 		shouldIndex:         c.IndexLibraries && len(gc.goSearch) > 0,
 	}
@@ -611,7 +642,8 @@ func (g *generator) generateProto(mode proto.Mode, targets []protoTarget, import
 			break
 		}
 	}
-	if atLeastOneTargetHasServices {
+	separateGrpc := atLeastOneTargetHasServices && gc.grpcSeparateLibrary
+	if atLeastOneTargetHasServices && !separateGrpc {
 		if gc.goGrpcCompilers != nil {
 			goProtoLibrary.SetAttr("compilers", gc.goGrpcCompilers)
 		} else {
@@ -652,12 +684,42 @@ func (g *generator) generateProto(mode proto.Mode, targets []protoTarget, import
 
 		goProtoLibrary.SetPrivateAttr(config.GazelleImportsKey, combinedImports.build())
 	}
-	return goProtoName, []*rule.Rule{goProtoLibrary}
+
+	// A go_grpc_library is generated for services when
+	// go_grpc_separate_library is set. It embeds the go_proto_library, and
+	// libraries embed it instead. When the directive is set and there are no
+	// services, an empty rule is generated, so a go_grpc_library left from an
+	// earlier run is deleted. Without the directive, go_grpc_library rules
+	// are left alone.
+	if !gc.grpcSeparateLibrary {
+		return goProtoName, []*rule.Rule{goProtoLibrary}
+	}
+	goGrpcName := strings.TrimSuffix(protoName, "_proto") + goGrpcSuffix
+	goGrpcLibrary := rule.NewRule("go_grpc_library", goGrpcName)
+	if !separateGrpc {
+		return goProtoName, []*rule.Rule{goProtoLibrary, goGrpcLibrary}
+	}
+	if protos := goProtoLibrary.AttrStrings("protos"); protos != nil {
+		goGrpcLibrary.SetAttr("protos", protos)
+	} else {
+		goGrpcLibrary.SetAttr("proto", goProtoLibrary.AttrString("proto"))
+	}
+	goGrpcLibrary.SetAttr("embed", []string{":" + goProtoName})
+	g.setImportAttrs(goGrpcLibrary, importPath)
+	if gc.goGrpcCompilers != nil {
+		goGrpcLibrary.SetAttr("compilers", gc.goGrpcCompilers)
+	} else {
+		goGrpcLibrary.SetAttr("compilers", []string{fmt.Sprintf("@%s//proto:go_grpc_v2", gc.rulesGoRepoName)})
+	}
+	if g.shouldSetVisibility {
+		goGrpcLibrary.SetAttr("visibility", visibility)
+	}
+	return goGrpcName, []*rule.Rule{goProtoLibrary, goGrpcLibrary}
 }
 
 func (g *generator) generateLib(pkg *goPackage, embeds []string) *rule.Rule {
 	gc := getGoConfig(g.c)
-	name := libNameByConvention(gc.goNamingConvention, pkg.importPath, pkg.name)
+	name := gc.libName(gc.goNamingConvention, pkg.importPath, pkg.name)
 	goLibrary := rule.NewRule("go_library", name)
 	if !pkg.library.sources.hasGo() && len(embeds) == 0 {
 		return goLibrary // empty
@@ -744,6 +806,7 @@ func (g *generator) generateTests(pkg *goPackage, library string) []*rule.Rule {
 			}
 		}
 		g.setCommonAttrs(goTest, pkg.rel, nil, test, embeds)
+		g.setTestRunAttrs(goTest)
 		if pkg.hasTestdata {
 			goTest.SetAttr("data", rule.GlobValue{Patterns: []string{"testdata/**"}})
 		}
@@ -822,7 +885,11 @@ func (g *generator) setCommonAttrs(r *rule.Rule, pkgRel string, visibility []str
 	}
 
 	if !target.sources.isEmpty() {
-		r.SetAttr("srcs", target.sources.buildFlat())
+		if gc.srcsGroups != nil {
+			r.SetAttr("srcs", target.buildGroupedSrcs(gc.srcsGroups))
+		} else {
+			r.SetAttr("srcs", target.sources.buildFlat())
+		}
 	}
 	if !target.embedSrcs.isEmpty() {
 		r.SetAttr("embedsrcs", target.embedSrcs.build())
@@ -883,7 +950,11 @@ func (g *generator) setImportAttrs(r *rule.Rule, importPath string) {
 		}
 	}
 
-	if gc.importMapPrefix != "" {
+	if importMap, ok := gc.importMapFor(importPath); ok {
+		if importMap != importPath {
+			r.SetAttr("importmap", importMap)
+		}
+	} else if gc.importMapPrefix != "" {
 		fromPrefixRel := pathtools.TrimPrefix(g.rel, gc.importMapPrefixRel)
 		importMap := path.Join(gc.importMapPrefix, fromPrefixRel)
 		if importMap != importPath {
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/language"
 	"github.com/bazelbuild/bazel-gazelle/language/proto"
@@ -126,16 +127,7 @@ func TestGenerateRules(t *testing.T) {
 			merger.FixLoads(f, loads)
 			f.Sync()
 			got := string(bzl.Format(f.File))
-			wantPath := filepath.Join(dir, "BUILD.want")
-			wantBytes, err := os.ReadFile(wantPath)
-			if err != nil {
-				t.Fatalf("error reading %s: %v", wantPath, err)
-			}
-			want := string(wantBytes)
-			want = strings.ReplaceAll(want, "\r\n", "\n")
-			if diff := cmp.Diff(want, got); diff != "" {
-				t.Errorf("(-want, +got): %s", diff)
-			}
+			testtools.CheckGolden(t, filepath.Join(dir, "BUILD.want"), got)
 		})
 	})
 	// Avoid spurious success if we fail to find any tests.
@@ -45,6 +45,32 @@ var goKinds = map[string]rule.KindInfo{
 		},
 		ResolveAttrs: map[string]bool{"deps": true},
 	},
+	"go_grpc_library": {
+		MatchAttrs: []string{"importpath"},
+		NonEmptyAttrs: map[string]bool{
+			"deps":   true,
+			"embed":  true,
+			"proto":  true,
+			"protos": true,
+			"srcs":   true,
+		},
+		SubstituteAttrs: map[string]bool{"proto": true, "protos": true},
+		MergeableAttrs: map[string]bool{
+			"srcs":       true,
+			"importpath": true,
+			"importmap":  true,
+			"cgo":        true,
+			"clinkopts":  true,
+			"cppopts":    true,
+			"copts":      true,
+			"cxxopts":    true,
+			"embed":      true,
+			"proto":      true,
+			"protos":     true,
+			"compilers":  true,
+		},
+		ResolveAttrs: map[string]bool{"deps": true},
+	},
 	"go_library": {
 		MatchAttrs: []string{"importpath"},
 		NonEmptyAttrs: map[string]bool{
@@ -105,6 +131,7 @@ var goKinds = map[string]rule.KindInfo{
 			"commit":       true,
 			"build_tags":   true,
 			"importpath":   true,
+			"local_path":   true,
 			"remote":       true,
 			"replace":      true,
 			"sha256":       true,
@@ -124,6 +151,7 @@ var goKinds = map[string]rule.KindInfo{
 			"srcs":  true,
 		},
 		MergeableAttrs: map[string]bool{
+			"args":        true,
 			"cgo":         true,
 			"clinkopts":   true,
 			"cppopts":     true,
@@ -131,6 +159,8 @@ var goKinds = map[string]rule.KindInfo{
 			"cxxopts":     true,
 			"embed":       true,
 			"embedsrcs":   true,
+			"env":         true,
+			"env_inherit": true,
 			"gc_goopts":   true,
 			"gc_linkopts": true,
 			"srcs":        true,
@@ -62,10 +62,21 @@ type goLang struct {
 	// Go code. If the value is false, it means the directory does not contain
 	// buildable Go code, but it has a subdir which does.
 	goPkgRels map[string]bool
+
+	// importPathConflicts records the import path conflicts between proto
+	// libraries that have been reported.
+	importPathConflicts importPathConflicts
+
+	// missingGoPackages records the proto libraries that Go rules weren't
+	// generated for because they have no go_package option.
+	missingGoPackages missingGoPackages
 }
 
 func (*goLang) Name() string { return goName }
 
+// ResolvesConcurrently reports that Resolve may be called concurrently.
+func (*goLang) ResolvesConcurrently() bool { return true }
+
 func NewLanguage() language.Language {
 	return &goLang{goPkgRels: make(map[string]bool)}
 }
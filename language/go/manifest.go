@@ -0,0 +1,213 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// manifestEntry is a dependency pinned in a JSON or YAML manifest. Each entry
+// names a module with either a version, which is fetched from a module proxy,
+// or a commit, which is fetched with a version control tool.
+type manifestEntry struct {
+	ImportPath string `json:"importpath"`
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	Sum        string `json:"sum"`
+	Remote     string `json:"remote"`
+	VCS        string `json:"vcs"`
+}
+
+// isManifestFile returns whether path looks like a dependency manifest that
+// importReposFromManifest can read.
+func isManifestFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func importReposFromManifest(args language.ImportReposArgs) language.ImportReposResult {
+	data, err := os.ReadFile(args.Path)
+	if err != nil {
+		return language.ImportReposResult{Error: err}
+	}
+	var entries []manifestEntry
+	if filepath.Ext(args.Path) == ".json" {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return language.ImportReposResult{Error: fmt.Errorf("%s: %v", args.Path, err)}
+		}
+	} else {
+		if entries, err = parseYAMLManifest(data); err != nil {
+			return language.ImportReposResult{Error: fmt.Errorf("%s: %v", args.Path, err)}
+		}
+	}
+
+	pathToModule := make(map[string]*moduleFromList)
+	var gen []*rule.Rule
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		if e.ImportPath == "" {
+			return language.ImportReposResult{Error: fmt.Errorf("%s: entry %d: importpath is not set", args.Path, i)}
+		}
+		if seen[e.ImportPath] {
+			return language.ImportReposResult{Error: fmt.Errorf("%s is listed more than once", e.ImportPath)}
+		}
+		seen[e.ImportPath] = true
+
+		switch {
+		case e.Version != "" && e.Commit != "":
+			return language.ImportReposResult{Error: fmt.Errorf("%s: only one of version and commit may be set", e.ImportPath)}
+
+		case e.Version != "":
+			if e.Remote != "" || e.VCS != "" {
+				return language.ImportReposResult{Error: fmt.Errorf("%s: remote and vcs may only be set with commit", e.ImportPath)}
+			}
+			pathToModule[e.ImportPath+"@"+e.Version] = &moduleFromList{
+				Path:    e.ImportPath,
+				Version: e.Version,
+				Sum:     e.Sum,
+			}
+
+		case e.Commit != "":
+			if e.Sum != "" {
+				return language.ImportReposResult{Error: fmt.Errorf("%s: sum may only be set with version", e.ImportPath)}
+			}
+			r := rule.NewRule("go_repository", label.ImportPathToBazelRepoName(e.ImportPath))
+			r.SetAttr("importpath", e.ImportPath)
+			r.SetAttr("commit", e.Commit)
+			if e.Remote != "" {
+				r.SetAttr("remote", e.Remote)
+				vcs := e.VCS
+				if vcs == "" {
+					vcs = "git"
+				}
+				r.SetAttr("vcs", vcs)
+			} else if e.VCS != "" {
+				return language.ImportReposResult{Error: fmt.Errorf("%s: vcs may only be set with remote", e.ImportPath)}
+			}
+			gen = append(gen, r)
+
+		default:
+			return language.ImportReposResult{Error: fmt.Errorf("%s: one of version or commit must be set", e.ImportPath)}
+		}
+	}
+
+	fillLockedSums(pathToModule, args.Cache)
+	pathToModule, err = fillMissingSums(pathToModule)
+	if err != nil {
+		return language.ImportReposResult{Error: fmt.Errorf("finding module sums: %v", err)}
+	}
+	gen = append(gen, toRepositoryRules(pathToModule)...)
+	return language.ImportReposResult{Gen: gen}
+}
+
+// parseYAMLManifest reads a manifest written in a small subset of YAML:
+// a sequence of mappings from the manifestEntry keys to scalar strings.
+// Comments and quoted strings are allowed; anchors, flow collections, and
+// multi-line strings are not.
+func parseYAMLManifest(data []byte) ([]manifestEntry, error) {
+	var entries []manifestEntry
+	var cur *manifestEntry
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := stripYAMLComment(s.Text())
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "- "); ok {
+			entries = append(entries, manifestEntry{})
+			cur = &entries[len(entries)-1]
+			line = rest
+		} else if strings.HasPrefix(line, "  ") && cur != nil {
+			line = strings.TrimSpace(line)
+		} else {
+			return nil, fmt.Errorf("line %d: expected a list of dependencies", lineNum)
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", lineNum)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			if value[len(value)-1] != value[0] {
+				return nil, fmt.Errorf("line %d: unterminated string", lineNum)
+			}
+			if value[0] == '"' {
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNum, err)
+				}
+				value = unquoted
+			} else {
+				value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+			}
+		}
+
+		switch key {
+		case "importpath":
+			cur.ImportPath = value
+		case "version":
+			cur.Version = value
+		case "commit":
+			cur.Commit = value
+		case "sum":
+			cur.Sum = value
+		case "remote":
+			cur.Remote = value
+		case "vcs":
+			cur.VCS = value
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", lineNum, key)
+		}
+	}
+	return entries, s.Err()
+}
+
+// stripYAMLComment removes a comment from the end of a line. A comment starts
+// with a # at the beginning of the line or after a space, outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -18,7 +18,6 @@ package golang
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -32,14 +31,14 @@ func importReposFromModules(args language.ImportReposArgs) language.ImportReposR
 		return language.ImportReposResult{Error: processGoListError(err, data)}
 	}
 
-	pathToModule, err := extractModules(data)
+	pathToModule, localModules, err := extractModules(data)
 	if err != nil {
 		return language.ImportReposResult{Error: err}
 	}
 
 	// Load sums from go.sum. Ideally, they're all there.
 	goSumPath := filepath.Join(filepath.Dir(args.Path), "go.sum")
-	data, _ = os.ReadFile(goSumPath)
+	data, _ = args.Config.ReadFile(goSumPath)
 	lines := bytes.Split(data, []byte("\n"))
 	for _, line := range lines {
 		line = bytes.TrimSpace(line)
@@ -56,10 +55,13 @@ func importReposFromModules(args language.ImportReposArgs) language.ImportReposR
 		}
 	}
 
+	fillLockedSums(pathToModule, args.Cache)
 	pathToModule, err = fillMissingSums(pathToModule)
 	if err != nil {
 		return language.ImportReposResult{Error: fmt.Errorf("finding module sums: %v", err)}
 	}
 
-	return language.ImportReposResult{Gen: toRepositoryRules(pathToModule)}
+	gen := toRepositoryRules(pathToModule)
+	gen = append(gen, localRepositoryRules(localModules, filepath.Dir(args.Path), args.Config.RepoRoot)...)
+	return language.ImportReposResult{Gen: gen}
 }
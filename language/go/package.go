@@ -47,6 +47,10 @@ type goTarget struct {
 	sources, embedSrcs, imports, cppopts, copts, cxxopts, clinkopts platformStringsBuilder
 	cgo, hasInternalTest                                            bool
 	pgoprofile                                                      string
+
+	// generatedSrcs and cgoSrcs are the sources that are generated by other
+	// rules and that use cgo. They're used to group srcs lists.
+	generatedSrcs, cgoSrcs map[string]bool
 }
 
 // protoTarget contains information used to generate a go_proto_library rule.
@@ -185,6 +189,10 @@ func (pkg *goPackage) inferImportPath(c *config.Config) error {
 		log.Panic("importPath already set")
 	}
 	gc := getGoConfig(c)
+	if gc.importPathOverride != "" && gc.importPathOverrideRel == pkg.rel {
+		pkg.importPath = gc.importPathOverride
+		return nil
+	}
 	if !gc.prefixSet {
 		return fmt.Errorf("%s: go prefix is not set, so importpath can't be determined for rules. Set a prefix with a '# gazelle:prefix' comment or with -go_prefix on the command line", pkg.dir)
 	}
@@ -230,6 +238,34 @@ func libNameByConvention(nc namingConvention, imp string, pkgName string) string
 	return name
 }
 
+// binaryLibNamePlaceholder is replaced with the name derived from the import
+// path in go_binary_library_name patterns.
+const binaryLibNamePlaceholder = "{binary}"
+
+// checkBinaryLibName returns an error if pattern, the value of a
+// go_binary_library_name directive, can't produce valid target names.
+func checkBinaryLibName(pattern string) error {
+	if strings.ContainsAny(pattern, ": \t") {
+		return fmt.Errorf("invalid library name %q: must not contain ':' or whitespace", pattern)
+	}
+	return nil
+}
+
+// libName returns a suitable name for a go_library like libNameByConvention.
+// For main packages with the import and import_alias naming conventions, the
+// name is built from the go_binary_library_name pattern, if one is set.
+func (gc *goConfig) libName(nc namingConvention, imp string, pkgName string) string {
+	name := libNameByConvention(nc, imp, pkgName)
+	if pkgName != "main" || nc == goDefaultLibraryNamingConvention || gc.binaryLibName == "" {
+		return name
+	}
+	base := libNameFromImportPath(imp)
+	if base == "" && strings.Contains(gc.binaryLibName, binaryLibNamePlaceholder) {
+		return name
+	}
+	return strings.ReplaceAll(gc.binaryLibName, binaryLibNamePlaceholder, base)
+}
+
 // testNameByConvention returns a suitable name for a go_test using the given
 // naming convention and the import path.
 func testNameByConvention(nc namingConvention, imp string) string {
@@ -264,8 +300,15 @@ func binName(rel, prefix, repoRoot string) string {
 	return pathtools.RelBaseName(rel, prefix, repoRoot)
 }
 
+// InferImportPath returns the import path of a library in the directory rel,
+// set with # gazelle:importpath in that directory or inferred from the
+// prefix and the path of rel relative to the directory where the prefix
+// was set.
 func InferImportPath(c *config.Config, rel string) string {
 	gc := getGoConfig(c)
+	if gc.importPathOverride != "" && rel == gc.importPathOverrideRel {
+		return gc.importPathOverride
+	}
 	if rel == gc.prefixRel {
 		return gc.prefix
 	} else {
@@ -304,6 +347,18 @@ func goProtoImportPath(c *config.Config, pkg proto.Package, rel string) string {
 
 func (t *goTarget) addFile(c *config.Config, er *embedResolver, info fileInfo) {
 	t.cgo = t.cgo || info.isCgo
+	if info.isGenerated {
+		if t.generatedSrcs == nil {
+			t.generatedSrcs = make(map[string]bool)
+		}
+		t.generatedSrcs[info.name] = true
+	}
+	if info.isCgo || info.ext == cExt || info.ext == csExt {
+		if t.cgoSrcs == nil {
+			t.cgoSrcs = make(map[string]bool)
+		}
+		t.cgoSrcs[info.name] = true
+	}
 	add := getPlatformStringsAddFunction(c, info, nil)
 	add(&t.sources, info.name)
 	add(&t.imports, info.imports...)
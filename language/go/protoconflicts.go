@@ -0,0 +1,107 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// importPathConflicts records the import paths shared by more than one
+// library built from protos, usually because .proto files in different
+// packages have the same go_package option. Each conflict is reported once,
+// by the first rule involved in it that's resolved. It's safe for
+// concurrent use.
+type importPathConflicts struct {
+	mu       sync.Mutex
+	reported map[string]bool
+}
+
+// check reports a conflict if the rule r, with the label from, is one of
+// several indexed libraries with its importpath and at least one of them is
+// built from protos. Vendored libraries are ignored, since they're only
+// visible from part of the tree. Imports of the path can't be resolved
+// until the conflict is fixed, so the report lists all the libraries and
+// suggests how to fix it.
+func (pc *importPathConflicts) check(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, from label.Label) {
+	imp := r.AttrString("importpath")
+	if ix == nil || imp == "" || !isGoLibrary(r.Kind()) {
+		return
+	}
+	matches := ix.FindRulesByImport(resolve.ImportSpec{Lang: "go", Imp: imp}, "go")
+	var labels []string
+	isSelf, hasProto := false, false
+	for _, m := range matches {
+		if isVendoredPkg(m.Label.Pkg) {
+			continue
+		}
+		isSelf = isSelf || m.IsSelfImport(from)
+		hasProto = hasProto || isProtoResult(m)
+		labels = append(labels, m.Label.String())
+	}
+	if len(labels) < 2 || !isSelf || !hasProto {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.reported[imp] {
+		return
+	}
+	if pc.reported == nil {
+		pc.reported = make(map[string]bool)
+	}
+	pc.reported[imp] = true
+
+	slices.Sort(labels)
+	log.Printf(`importpath conflict: %d libraries built from protos have the importpath %q:
+	%s
+Imports of %[2]q can't be resolved. Give the .proto files in each package a distinct go_package option, or choose one of the libraries with a directive like:
+	# gazelle:resolve go %[2]s %[4]s`, len(labels), imp, strings.Join(labels, "\n\t"), labels[0])
+}
+
+// isProtoResult returns whether m is a go_proto_library or a library that
+// embeds one, judging by the conventional names of go_proto_library rules.
+func isProtoResult(m resolve.FindResult) bool {
+	if strings.HasSuffix(m.Label.Name, goProtoSuffix) {
+		return true
+	}
+	for _, embed := range m.Embeds {
+		if strings.HasSuffix(embed.Name, goProtoSuffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVendoredPkg returns whether the package pkg is in a vendor directory.
+func isVendoredPkg(pkg string) bool {
+	return slices.Contains(strings.Split(pkg, "/"), "vendor")
+}
+
+// errImportPathConflict returns the error for an import from the rule from
+// that matches both a and b, where at least one is built from protos.
+func errImportPathConflict(from label.Label, imp string, a, b label.Label) error {
+	return fmt.Errorf("rule %s imports %q which matches multiple rules built from protos: %s and %s. See the importpath conflict reported for %[2]q", from, imp, a, b)
+}
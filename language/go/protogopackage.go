@@ -0,0 +1,162 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"path"
+	"slices"
+	"sync"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+)
+
+// missingGoPackageMode determines what Gazelle does with a proto_library
+// whose .proto files don't have a go_package option. Set with
+// # gazelle:go_proto_missing_go_package.
+type missingGoPackageMode int
+
+const (
+	// inferMissingGoPackage infers the importpath of the go_proto_library
+	// from the directory, like the importpath of a go_library, without
+	// reporting anything. It's the default.
+	inferMissingGoPackage missingGoPackageMode = iota
+
+	// reportMissingGoPackage infers the importpath like
+	// inferMissingGoPackage and logs a line for each file.
+	reportMissingGoPackage
+
+	// warnMissingGoPackage infers the importpath like inferMissingGoPackage
+	// and logs a warning for each file.
+	warnMissingGoPackage
+
+	// errorMissingGoPackage logs an error for each file and doesn't generate
+	// Go rules for the proto_library. Imports of the library's files and
+	// importpath that no other rule provides can't be resolved.
+	errorMissingGoPackage
+)
+
+func (m missingGoPackageMode) String() string {
+	switch m {
+	case inferMissingGoPackage:
+		return "infer"
+	case reportMissingGoPackage:
+		return "report"
+	case warnMissingGoPackage:
+		return "warn"
+	case errorMissingGoPackage:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+func missingGoPackageModeFromString(s string) (missingGoPackageMode, error) {
+	switch s {
+	case "infer":
+		return inferMissingGoPackage, nil
+	case "report":
+		return reportMissingGoPackage, nil
+	case "warn":
+		return warnMissingGoPackage, nil
+	case "error":
+		return errorMissingGoPackage, nil
+	default:
+		return 0, fmt.Errorf("unrecognized go_proto_missing_go_package mode: %q; want infer, report, warn, or error", s)
+	}
+}
+
+// checkGoPackage reports the .proto files of the proto_library named name
+// in the directory rel that don't have a go_package option, according to
+// the go_proto_missing_go_package mode. Nothing is reported if another file
+// in the library sets the option, since it determines the importpath.
+// checkGoPackage returns false if Go rules shouldn't be generated for the
+// library.
+func checkGoPackage(c *config.Config, rel, name string, pkg proto.Package) bool {
+	mode := getGoConfig(c).missingGoPackage
+	if mode == inferMissingGoPackage {
+		return true
+	}
+	if _, ok := pkg.Options["go_package"]; ok {
+		return true
+	}
+	for _, file := range slices.Sorted(maps.Keys(pkg.Files)) {
+		p := path.Join(rel, file)
+		switch mode {
+		case reportMissingGoPackage:
+			log.Printf("%s: no go_package option; using importpath %q, inferred from the directory", p, InferImportPath(c, rel))
+		case warnMissingGoPackage:
+			log.Printf("%s: warning: no go_package option; using importpath %q, inferred from the directory", p, InferImportPath(c, rel))
+		case errorMissingGoPackage:
+			log.Printf("%s: error: no go_package option; not generating Go rules for :%s. Add a go_package option or set # gazelle:go_proto_missing_go_package to warn or infer", p, name)
+		}
+	}
+	return mode != errorMissingGoPackage
+}
+
+// missingGoPackages records the proto_library rules that Go rules weren't
+// generated for because their files have no go_package option. Imports of
+// their files and importpaths would otherwise resolve to labels guessed from
+// the path, which don't exist, so they're reported as unresolved instead,
+// which fails runs with -strict. It's safe for concurrent use.
+type missingGoPackages struct {
+	mu sync.Mutex
+
+	// imports maps the proto and Go imports of each library to the
+	// directory it's in.
+	imports map[resolve.ImportSpec]string
+}
+
+// add records the proto_library in the directory rel built from pkg.
+func (mp *missingGoPackages) add(c *config.Config, rel string, pkg proto.Package) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if mp.imports == nil {
+		mp.imports = make(map[resolve.ImportSpec]string)
+	}
+	for file := range pkg.Files {
+		mp.imports[resolve.ImportSpec{Lang: "proto", Imp: path.Join(rel, file)}] = rel
+	}
+	mp.imports[resolve.ImportSpec{Lang: goName, Imp: InferImportPath(c, rel)}] = rel
+}
+
+// check returns an error if imp, imported by a rule of the given kind with
+// the label from, belongs to a library recorded with add, and neither a
+// resolve directive nor an indexed rule provides it.
+func (mp *missingGoPackages) check(c *config.Config, ix *resolve.RuleIndex, kind, imp string, from label.Label) error {
+	spec := resolve.ImportSpec{Lang: goName, Imp: imp}
+	if kind == "go_proto_library" {
+		spec.Lang = "proto"
+	}
+	mp.mu.Lock()
+	rel, ok := mp.imports[spec]
+	mp.mu.Unlock()
+	if !ok || ix == nil {
+		return nil
+	}
+	if _, ok := resolve.FindRuleWithOverride(c, spec, goName); ok {
+		return nil
+	}
+	if len(ix.FindRulesByImportWithConfig(c, spec, goName)) > 0 {
+		return nil
+	}
+	return fmt.Errorf("rule %s imports %q, but Go rules aren't generated for it because the .proto files in %q have no go_package option", from, imp, rel)
+}
@@ -68,6 +68,7 @@ func (gl *goLang) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.Remo
 		// may not be set in tests.
 		return
 	}
+	gl.importPathConflicts.check(c, ix, r, from)
 	imports := importsRaw.(rule.PlatformStrings)
 	r.DelAttr("deps")
 	var resolve func(*config.Config, *resolve.RuleIndex, *repo.RemoteCache, string, label.Label) (label.Label, error)
@@ -78,6 +79,9 @@ func (gl *goLang) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.Remo
 		resolve = ResolveGo
 	}
 	deps, errs := imports.Map(func(imp string) (string, error) {
+		if err := gl.missingGoPackages.check(c, ix, r.Kind(), imp, from); err != nil {
+			return "", err
+		}
 		l, err := resolve(c, ix, rc, imp, from)
 		if err == errSkipImport {
 			return "", nil
@@ -96,7 +100,7 @@ func (gl *goLang) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.Remo
 		return l.String(), nil
 	})
 	for _, err := range errs {
-		log.Print(err)
+		ix.ReportUnresolved(err)
 	}
 	if !deps.IsEmpty() {
 		if r.Kind() == "go_proto_library" {
@@ -131,7 +135,10 @@ func ResolveGo(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, im
 		imp = path.Join(gc.prefix, cleanRel)
 	}
 
-	if IsStandard(imp) {
+	if IsStandard(imp) && !gc.shadowsStd(imp) {
+		if provider := stdShadowProvider(c, ix, rc, imp); provider != "" {
+			log.Printf("%s imports %q, which is a standard library package, but %s also provides it. Treating it as the standard library package; add # gazelle:go_shadow_std %s to resolve it outside the standard library.", from, imp, provider, imp)
+		}
 		return label.NoLabel, errSkipImport
 	}
 
@@ -183,11 +190,51 @@ func ResolveGo(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, im
 	return resolveToExternalLabel(c, resolveFn, imp)
 }
 
+// ResolveImport implements language.ImportResolver with ResolveGo.
+func (*goLang) ResolveImport(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, imp string, from label.Label) (label.Label, error) {
+	l, err := ResolveGo(c, ix, rc, imp, from)
+	if err == errSkipImport {
+		return label.NoLabel, nil
+	}
+	return l, err
+}
+
 // IsStandard returns whether a package is in the standard library.
 func IsStandard(imp string) bool {
 	return stdPackages[imp]
 }
 
+// shadowsStd returns whether imp is covered by a go_shadow_std directive.
+func (gc *goConfig) shadowsStd(imp string) bool {
+	for _, p := range gc.stdShadows {
+		if pathtools.HasPrefix(imp, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// stdShadowProvider returns a description of a library or repository
+// outside the standard library that provides imp, a standard library import
+// path, or "" if there isn't one. Only indexed libraries, the current
+// prefix, and repositories already known to rc are checked.
+func stdShadowProvider(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, imp string) string {
+	if ix != nil {
+		if matches := ix.FindRulesByImportWithConfig(c, resolve.ImportSpec{Lang: "go", Imp: imp}, "go"); len(matches) > 0 {
+			return matches[0].Label.String()
+		}
+	}
+	if gc := getGoConfig(c); gc.prefixSet && gc.prefix != "" && pathtools.HasPrefix(imp, gc.prefix) {
+		return fmt.Sprintf("the prefix %q", gc.prefix)
+	}
+	if rc != nil {
+		if root, name, err := rc.RootStatic(imp); err == nil && root != "" {
+			return fmt.Sprintf("@%s (%s)", name, root)
+		}
+	}
+	return ""
+}
+
 func resolveWithIndexGo(c *config.Config, ix *resolve.RuleIndex, imp string, from label.Label) (label.Label, error) {
 	matches := ix.FindRulesByImportWithConfig(c, resolve.ImportSpec{Lang: "go", Imp: imp}, "go")
 	var bestMatch resolve.FindResult
@@ -228,6 +275,7 @@ func resolveWithIndexGo(c *config.Config, ix *resolve.RuleIndex, imp string, fro
 				embedsProtos = true
 			}
 		}
+		bestMatchIsProto := bestMatchEmbedsProtos || isProtoResult(bestMatch)
 
 		if bestMatch.Label.Equal(label.NoLabel) ||
 			(isVendored && (!bestMatchIsVendored || len(vendorRoot) > len(bestMatchVendorRoot))) ||
@@ -244,6 +292,11 @@ func resolveWithIndexGo(c *config.Config, ix *resolve.RuleIndex, imp string, fro
 			// Current match is worse
 		} else {
 			// Match is ambiguous
+			if bestMatchIsProto || isProtoResult(m) {
+				// The conflict is reported once with all the libraries involved.
+				matchError = errImportPathConflict(from, imp, bestMatch.Label, m.Label)
+				continue
+			}
 			// TODO: consider listing all the ambiguous rules here.
 			matchError = fmt.Errorf("rule %s imports %q which matches multiple rules: %s and %s. # gazelle:resolve may be used to disambiguate", from, imp, bestMatch.Label, m.Label)
 		}
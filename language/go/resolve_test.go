@@ -63,6 +63,40 @@ go_binary(
 `,
 			},
 			want: `go_binary(name = "dep")`,
+		}, {
+			desc: "std_shadowed",
+			index: []buildFile{{
+				rel:     "",
+				content: "# gazelle:go_shadow_std fmt",
+			}, {
+				rel: "shadow",
+				content: `
+go_library(
+    name = "go_default_library",
+    importpath = "fmt/sub",
+)
+`,
+			}},
+			old: buildFile{
+				content: `
+go_binary(
+    name = "dep",
+    _imports = [
+        "fmt",
+        "fmt/sub",
+    ],
+)
+`,
+			},
+			want: `
+go_binary(
+    name = "dep",
+    deps = [
+        "//shadow:go_default_library",
+        "//vendor/fmt",
+    ],
+)
+`,
 		}, {
 			desc: "self_import",
 			old: buildFile{content: `
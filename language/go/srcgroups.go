@@ -0,0 +1,206 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v2rule "github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// srcGroup is a group of source files that srcs lists may be split into
+// with the go_srcs_groups directive.
+type srcGroup int
+
+const (
+	// goSrcGroup contains hand-written files that aren't in another group,
+	// including files Gazelle didn't find itself, like those marked with
+	// "# keep".
+	goSrcGroup srcGroup = iota
+
+	// cgoSrcGroup contains .go files that import "C", along with C, C++,
+	// and header files and assembly files built by the C compiler.
+	cgoSrcGroup
+
+	// generatedSrcGroup contains files generated by other rules in the
+	// same build file.
+	generatedSrcGroup
+)
+
+var srcGroupNames = []string{"go", "cgo", "generated"}
+
+// srcGroupHeaders are the comments placed before the first file of each
+// group. They mark where groups start, so each group stays together when
+// the list is sorted.
+var srcGroupHeaders = []string{"# Go sources", "# cgo sources", "# Generated sources"}
+
+// parseSrcsGroups parses the value of a go_srcs_groups directive, a list of
+// group names in the order their files should appear. Groups that aren't
+// listed come after those that are, in their default order. An empty value
+// returns nil, which sorts srcs without grouping.
+func parseSrcsGroups(value string) ([]srcGroup, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	var groups []srcGroup
+	for _, name := range fields {
+		i := slices.Index(srcGroupNames, name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown group %q; want one of %s", name, strings.Join(srcGroupNames, ", "))
+		}
+		if slices.Contains(groups, srcGroup(i)) {
+			return nil, fmt.Errorf("group %q is listed more than once", name)
+		}
+		groups = append(groups, srcGroup(i))
+	}
+	for i := range srcGroupNames {
+		if !slices.Contains(groups, srcGroup(i)) {
+			groups = append(groups, srcGroup(i))
+		}
+	}
+	return groups, nil
+}
+
+// srcGroup returns the group of the source file name in t.
+func (t *goTarget) srcGroup(name string) srcGroup {
+	switch {
+	case t.generatedSrcs[name]:
+		return generatedSrcGroup
+	case t.cgoSrcs[name]:
+		return cgoSrcGroup
+	case t.cgo && fileNameInfo(name).ext == hExt:
+		// Headers are only C headers in packages that use cgo. Otherwise,
+		// they're Go assembly headers.
+		return cgoSrcGroup
+	default:
+		return goSrcGroup
+	}
+}
+
+// buildGroupedSrcs returns the srcs of t split into groups in the given
+// order.
+func (t *goTarget) buildGroupedSrcs(order []srcGroup) groupedSrcs {
+	srcs := t.sources.buildFlat()
+	g := groupedSrcs{order: order, groups: make(map[string]srcGroup, len(srcs))}
+	for _, src := range srcs {
+		g.groups[src] = t.srcGroup(src)
+	}
+	return g
+}
+
+// groupedSrcs is a list of source files split into groups. Each group is
+// sorted separately and starts with a comment naming it, so buildifier
+// doesn't sort the list as a whole. When there's only one group, the list
+// has no comments.
+//
+// groupedSrcs is merged into an existing list like other srcs lists, then
+// the result is split into groups again, so files added or moved between
+// groups are placed correctly.
+type groupedSrcs struct {
+	order  []srcGroup
+	groups map[string]srcGroup
+}
+
+var (
+	_ rule.BzlExprValue = groupedSrcs{}
+	_ rule.Merger       = groupedSrcs{}
+)
+
+func (s groupedSrcs) BzlExpr() bzl.Expr {
+	return s.group(s.list().List)
+}
+
+func (s groupedSrcs) Merge(other bzl.Expr) bzl.Expr {
+	if other == nil {
+		return s.BzlExpr()
+	}
+	if _, ok := other.(*bzl.ListExpr); !ok {
+		// The existing list is combined with a select, which can't be
+		// grouped. Merge into it like an ungrouped srcs list.
+		merged, err := v2rule.MergePlatformStrings(s.list(), other)
+		if err != nil {
+			return other
+		}
+		return merged
+	}
+	merged := v2rule.MergeList(s.list(), other)
+	if merged == nil {
+		return nil
+	}
+	grouped := s.group(merged.List)
+	grouped.ForceMultiLine = grouped.ForceMultiLine || merged.ForceMultiLine
+	return grouped
+}
+
+// list returns the sources in s as a sorted list without group comments.
+func (s groupedSrcs) list() *bzl.ListExpr {
+	list := &bzl.ListExpr{}
+	for _, src := range slices.Sorted(maps.Keys(s.groups)) {
+		list.List = append(list.List, &bzl.StringExpr{Value: src})
+	}
+	return list
+}
+
+// group splits elems into groups in the order of s.order and sorts each
+// group the way buildifier would. Group comments left from an earlier run
+// are removed first. Elements that aren't strings or aren't sources in s
+// are placed in goSrcGroup.
+func (s groupedSrcs) group(elems []bzl.Expr) *bzl.ListExpr {
+	byGroup := make(map[srcGroup][]bzl.Expr)
+	for _, e := range elems {
+		com := e.Comment()
+		com.Before = slices.DeleteFunc(com.Before, func(c bzl.Comment) bool {
+			return slices.Contains(srcGroupHeaders, strings.TrimSpace(c.Token))
+		})
+		g := goSrcGroup
+		if str, ok := e.(*bzl.StringExpr); ok {
+			if sg, ok := s.groups[str.Value]; ok {
+				g = sg
+			}
+		}
+		byGroup[g] = append(byGroup[g], e)
+	}
+
+	for g, elems := range byGroup {
+		group := &bzl.ListExpr{List: elems}
+		bzl.SortStringList(group)
+		byGroup[g] = group.List
+	}
+
+	list := &bzl.ListExpr{}
+	if len(byGroup) <= 1 {
+		for _, g := range s.order {
+			list.List = append(list.List, byGroup[g]...)
+		}
+		return list
+	}
+	list.ForceMultiLine = true
+	for _, g := range s.order {
+		if len(byGroup[g]) == 0 {
+			continue
+		}
+		first := byGroup[g][0].Comment()
+		first.Before = append([]bzl.Comment{{Token: srcGroupHeaders[g]}}, first.Before...)
+		list.List = append(list.List, byGroup[g]...)
+	}
+	return list
+}
@@ -0,0 +1,155 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSrcsGroups(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		want    []srcGroup
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "go generated cgo", want: []srcGroup{goSrcGroup, generatedSrcGroup, cgoSrcGroup}},
+		{value: "cgo", want: []srcGroup{cgoSrcGroup, goSrcGroup, generatedSrcGroup}},
+		{value: "go bogus", wantErr: true},
+		{value: "go go", wantErr: true},
+	} {
+		got, err := parseSrcsGroups(tc.value)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %v; want error", tc.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.value, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("%q (-want, +got): %s", tc.value, diff)
+		}
+	}
+}
+
+func TestMergeGroupedSrcs(t *testing.T) {
+	f, err := rule.LoadData(filepath.FromSlash("foo/BUILD.bazel"), "foo", []byte(`
+go_library(
+    name = "foo",
+    srcs = [
+        # Go sources
+        "a.go",
+        "gen.go",
+        "kept.go",  # keep
+        "old.go",
+        # cgo sources
+        "z_cgo.go",
+    ],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := rule.NewRule("go_library", "foo")
+	gen.SetAttr("srcs", groupedSrcs{
+		order: []srcGroup{goSrcGroup, generatedSrcGroup, cgoSrcGroup},
+		groups: map[string]srcGroup{
+			"a.go":     goSrcGroup,
+			"b.go":     goSrcGroup,
+			"gen.go":   generatedSrcGroup,
+			"z_cgo.go": cgoSrcGroup,
+			"x.c":      cgoSrcGroup,
+		},
+	})
+	rule.MergeRules(gen, f.Rules[0], goKinds["go_library"].MergeableAttrs, f.Path)
+
+	want := strings.TrimSpace(`
+go_library(
+    name = "foo",
+    srcs = [
+        # Go sources
+        "a.go",
+        "b.go",
+        "kept.go",  # keep
+        # Generated sources
+        "gen.go",
+        # cgo sources
+        "x.c",
+        "z_cgo.go",
+    ],
+)
+`)
+	if got := strings.TrimSpace(string(f.Format())); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMergeGroupedSrcsSelect(t *testing.T) {
+	f, err := rule.LoadData(filepath.FromSlash("foo/BUILD.bazel"), "foo", []byte(`
+go_library(
+    name = "foo",
+    srcs = [
+        "a.go",
+        "old.go",
+    ] + select({
+        "@io_bazel_rules_go//go/platform:linux": [
+            "linux.go",  # keep
+        ],
+        "//conditions:default": [],
+    }),
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := rule.NewRule("go_library", "foo")
+	gen.SetAttr("srcs", groupedSrcs{
+		order: []srcGroup{goSrcGroup, generatedSrcGroup, cgoSrcGroup},
+		groups: map[string]srcGroup{
+			"a.go":     goSrcGroup,
+			"b.go":     goSrcGroup,
+			"z_cgo.go": cgoSrcGroup,
+		},
+	})
+	rule.MergeRules(gen, f.Rules[0], goKinds["go_library"].MergeableAttrs, f.Path)
+
+	want := strings.TrimSpace(`
+go_library(
+    name = "foo",
+    srcs = [
+        "a.go",
+        "b.go",
+        "z_cgo.go",
+    ] + select({
+        "@io_bazel_rules_go//go/platform:linux": [
+            "linux.go",  # keep
+        ],
+        "//conditions:default": [],
+    }),
+)
+`)
+	if got := strings.TrimSpace(string(f.Format())); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
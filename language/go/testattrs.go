@@ -0,0 +1,178 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// parseTestEnv parses the value of a go_test_env directive and adds the
+// variables to env, returning the updated map. Variables are written as
+// NAME=VALUE, separated by whitespace, and may be quoted to include spaces.
+// A later value for the same name replaces an earlier one. env is copied
+// before it's modified, since it may be shared with parent directories. An
+// empty value returns nil.
+func parseTestEnv(env map[string]string, value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	fields, err := splitQuoted(value)
+	if err != nil {
+		return env, err
+	}
+	updated := maps.Clone(env)
+	if updated == nil {
+		updated = make(map[string]string)
+	}
+	for _, field := range fields {
+		name, val, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			return env, fmt.Errorf("expected NAME=VALUE, got %q", field)
+		}
+		updated[name] = val
+	}
+	return updated, nil
+}
+
+// setTestRunAttrs sets the args, env, and env_inherit attributes of a
+// generated go_test rule from the go_test_args, go_test_env, and
+// go_test_env_inherit directives. The values are merged into an existing
+// rule without removing values already there, so they're also set when the
+// existing rule has the attribute and no directive applies.
+func (g *generator) setTestRunAttrs(r *rule.Rule) {
+	gc := getGoConfig(g.c)
+	var existing *rule.Rule
+	if g.file != nil {
+		for _, er := range g.file.Rules {
+			if er.Kind() == r.Kind() && er.Name() == r.Name() {
+				existing = er
+				break
+			}
+		}
+	}
+	hasAttr := func(key string) bool {
+		return existing != nil && existing.Attr(key) != nil
+	}
+	if len(gc.testArgs) > 0 || hasAttr("args") {
+		r.SetAttr("args", mergedStrings(gc.testArgs))
+	}
+	if len(gc.testEnv) > 0 || hasAttr("env") {
+		r.SetAttr("env", mergedEnv(gc.testEnv))
+	}
+	if len(gc.testEnvInherit) > 0 || hasAttr("env_inherit") {
+		r.SetAttr("env_inherit", mergedStrings(gc.testEnvInherit))
+	}
+}
+
+// mergedStrings is a list of strings that is merged into an existing list
+// by appending the strings it doesn't already contain. Unlike lists merged
+// by default, entries in the existing list are never removed.
+type mergedStrings []string
+
+var (
+	_ rule.BzlExprValue = mergedStrings(nil)
+	_ rule.Merger       = mergedStrings(nil)
+)
+
+func (s mergedStrings) BzlExpr() bzl.Expr {
+	return rule.ExprFromValue([]string(s))
+}
+
+func (s mergedStrings) Merge(other bzl.Expr) bzl.Expr {
+	if other == nil {
+		return s.BzlExpr()
+	}
+	list, ok := other.(*bzl.ListExpr)
+	if !ok {
+		// A select or other expression Gazelle can't merge into.
+		return other
+	}
+	seen := make(map[string]bool, len(list.List))
+	for _, e := range list.List {
+		if str, ok := e.(*bzl.StringExpr); ok {
+			seen[str.Value] = true
+		}
+	}
+	merged := *list
+	merged.List = slices.Clone(list.List)
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			merged.List = append(merged.List, &bzl.StringExpr{Value: v})
+		}
+	}
+	return &merged
+}
+
+// mergedEnv is a dict of environment variables that is merged into an
+// existing dict. Variables in mergedEnv replace the values of existing
+// variables with the same names, and other existing variables are kept.
+type mergedEnv map[string]string
+
+var (
+	_ rule.BzlExprValue = mergedEnv(nil)
+	_ rule.Merger       = mergedEnv(nil)
+)
+
+func (e mergedEnv) BzlExpr() bzl.Expr {
+	dict := &bzl.DictExpr{ForceMultiLine: true}
+	for _, name := range slices.Sorted(maps.Keys(e)) {
+		dict.List = append(dict.List, &bzl.KeyValueExpr{
+			Key:   &bzl.StringExpr{Value: name},
+			Value: &bzl.StringExpr{Value: e[name]},
+		})
+	}
+	return dict
+}
+
+func (e mergedEnv) Merge(other bzl.Expr) bzl.Expr {
+	if other == nil {
+		return e.BzlExpr()
+	}
+	dict, ok := other.(*bzl.DictExpr)
+	if !ok {
+		return other
+	}
+	merged := *dict
+	merged.List = make([]*bzl.KeyValueExpr, 0, len(dict.List)+len(e))
+	seen := make(map[string]bool, len(dict.List))
+	for _, kv := range dict.List {
+		if key, ok := kv.Key.(*bzl.StringExpr); ok {
+			seen[key.Value] = true
+			if val, ok := e[key.Value]; ok {
+				updated := *kv
+				updated.Value = &bzl.StringExpr{Value: val}
+				kv = &updated
+			}
+		}
+		merged.List = append(merged.List, kv)
+	}
+	for _, name := range slices.Sorted(maps.Keys(e)) {
+		if !seen[name] {
+			merged.List = append(merged.List, &bzl.KeyValueExpr{
+				Key:   &bzl.StringExpr{Value: name},
+				Value: &bzl.StringExpr{Value: e[name]},
+			})
+		}
+	}
+	return &merged
+}
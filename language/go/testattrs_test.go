@@ -0,0 +1,95 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package golang
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTestEnv(t *testing.T) {
+	parent := map[string]string{"A": "1"}
+	got, err := parseTestEnv(parent, `B=2 "C=with space" A=3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"A": "3", "B": "2", "C": "with space"}, got); diff != "" {
+		t.Errorf("(-want, +got): %s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"A": "1"}, parent); diff != "" {
+		t.Errorf("parent env was modified (-want, +got): %s", diff)
+	}
+
+	if got, err := parseTestEnv(parent, ""); err != nil || got != nil {
+		t.Errorf("empty value: got %v, %v; want nil, nil", got, err)
+	}
+	if _, err := parseTestEnv(parent, "NOVALUE"); err == nil {
+		t.Error("expected error for variable without value")
+	}
+}
+
+func TestMergeTestRunAttrs(t *testing.T) {
+	f, err := rule.LoadData(filepath.FromSlash("foo/BUILD.bazel"), "foo", []byte(`
+go_test(
+    name = "foo_test",
+    args = [
+        "-manual",  # hand-written
+        "-v",
+    ],
+    env = {
+        "MANUAL": "1",
+        "MODE": "old",
+    },
+    env_inherit = select({
+        "//conditions:default": ["HOME"],
+    }),
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := rule.NewRule("go_test", "foo_test")
+	gen.SetAttr("args", mergedStrings{"-v", "-race"})
+	gen.SetAttr("env", mergedEnv{"MODE": "new", "EXTRA": "x"})
+	gen.SetAttr("env_inherit", mergedStrings{"PATH"})
+	rule.MergeRules(gen, f.Rules[0], goKinds["go_test"].MergeableAttrs, f.Path)
+
+	want := strings.TrimSpace(`
+go_test(
+    name = "foo_test",
+    args = [
+        "-manual",  # hand-written
+        "-v",
+        "-race",
+    ],
+    env = {
+        "MANUAL": "1",
+        "MODE": "new",
+        "EXTRA": "x",
+    },
+    env_inherit = select({
+        "//conditions:default": ["HOME"],
+    }),
+)
+`)
+	if got := strings.TrimSpace(string(f.Format())); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
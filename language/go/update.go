@@ -63,12 +63,24 @@ var repoImportFuncs = map[string]func(args language.ImportReposArgs) language.Im
 	"go.work": importReposFromWork,
 }
 
+// repoImportFunc returns the function that imports repositories from path,
+// or nil if the file isn't supported.
+func repoImportFunc(path string) func(args language.ImportReposArgs) language.ImportReposResult {
+	if f := repoImportFuncs[filepath.Base(path)]; f != nil {
+		return f
+	}
+	if isManifestFile(path) {
+		return importReposFromManifest
+	}
+	return nil
+}
+
 func (*goLang) CanImport(path string) bool {
-	return repoImportFuncs[filepath.Base(path)] != nil
+	return repoImportFunc(path) != nil
 }
 
 func (*goLang) ImportRepos(args language.ImportReposArgs) language.ImportReposResult {
-	res := repoImportFuncs[filepath.Base(args.Path)](args)
+	res := repoImportFunc(args.Path)(args)
 	for _, r := range res.Gen {
 		setBuildAttrs(getGoConfig(args.Config), r)
 	}
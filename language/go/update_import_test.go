@@ -589,6 +589,147 @@ go_repository(
 `), nil
 			},
 		},
+		{
+			desc: "modules-local-replace",
+			files: []testtools.FileSpec{
+				{
+					Path: "go.mod",
+					Content: `
+module example.com/main
+
+require example.com/local v1.0.0
+
+replace example.com/local => ./third_party/local
+`,
+				},
+			},
+			want: `
+go_repository(
+    name = "com_example_local",
+    importpath = "example.com/local",
+    local_path = "third_party/local",
+)
+`,
+			stubGoListModules: func(dir string) ([]byte, error) {
+				return []byte(`
+{
+	"Path": "example.com/main",
+	"Main": true
+}
+{
+	"Path": "example.com/local",
+	"Version": "v1.0.0",
+	"Replace": {
+		"Path": "./third_party/local"
+	}
+}
+`), nil
+			},
+		},
+		{
+			desc: "manifest-json",
+			files: []testtools.FileSpec{
+				{
+					Path: "deps.json",
+					Content: `[
+  {
+    "importpath": "github.com/pkg/errors",
+    "version": "v0.9.1",
+    "sum": "h1:FEBLx1zS214owpjy7qsBeixbURkuhQAwrK5UwLGTwt4="
+  },
+  {
+    "importpath": "golang.org/x/xerrors",
+    "version": "v0.0.0-20200804184101-5ec99f83aff1"
+  },
+  {
+    "importpath": "example.com/internal/lib",
+    "commit": "0123456789abcdef0123456789abcdef01234567",
+    "remote": "https://git.example.com/lib"
+  }
+]
+`,
+				},
+			},
+			want: `
+go_repository(
+    name = "com_example_internal_lib",
+    commit = "0123456789abcdef0123456789abcdef01234567",
+    importpath = "example.com/internal/lib",
+    remote = "https://git.example.com/lib",
+    vcs = "git",
+)
+
+go_repository(
+    name = "com_github_pkg_errors",
+    importpath = "github.com/pkg/errors",
+    sum = "h1:FEBLx1zS214owpjy7qsBeixbURkuhQAwrK5UwLGTwt4=",
+    version = "v0.9.1",
+)
+
+go_repository(
+    name = "org_golang_x_xerrors",
+    importpath = "golang.org/x/xerrors",
+    sum = "h1:go4+OqTx3rAX7yIf3tOWNa4dzLmpHJlmMt2bcyTnaTg=",
+    version = "v0.0.0-20200804184101-5ec99f83aff1",
+)
+`,
+			stubGoModDownload: func(dir string, args []string) ([]byte, error) {
+				return []byte(`{
+"Path": "golang.org/x/xerrors",
+"Version": "v0.0.0-20200804184101-5ec99f83aff1",
+"Sum": "h1:go4+OqTx3rAX7yIf3tOWNa4dzLmpHJlmMt2bcyTnaTg="
+}`), nil
+			},
+		},
+		{
+			desc: "manifest-yaml",
+			files: []testtools.FileSpec{
+				{
+					Path: "deps.yaml",
+					Content: `
+# Pinned by the release team.
+- importpath: github.com/pkg/errors
+  version: v0.9.1
+  sum: "h1:FEBLx1zS214owpjy7qsBeixbURkuhQAwrK5UwLGTwt4="  # checked
+
+- importpath: example.com/internal/lib
+  commit: 0123456789abcdef0123456789abcdef01234567
+  remote: 'https://hg.example.com/lib'
+  vcs: hg
+`,
+				},
+			},
+			want: `
+go_repository(
+    name = "com_example_internal_lib",
+    commit = "0123456789abcdef0123456789abcdef01234567",
+    importpath = "example.com/internal/lib",
+    remote = "https://hg.example.com/lib",
+    vcs = "hg",
+)
+
+go_repository(
+    name = "com_github_pkg_errors",
+    importpath = "github.com/pkg/errors",
+    sum = "h1:FEBLx1zS214owpjy7qsBeixbURkuhQAwrK5UwLGTwt4=",
+    version = "v0.9.1",
+)
+`,
+		},
+		{
+			desc: "manifest-version-and-commit",
+			files: []testtools.FileSpec{
+				{
+					Path: "deps.yml",
+					Content: `
+- importpath: github.com/pkg/errors
+  version: v0.9.1
+  commit: 614d223910a179a466c1767a985424175c39b465
+`,
+				},
+			},
+			wantErr: "github.com/pkg/errors: only one of version and commit may be set",
+		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
 			if tc.stubGoModDownload != nil {
@@ -605,7 +746,7 @@ go_repository(
 			defer cleanup()
 
 			filename := filepath.Join(dir, tc.files[0].Path)
-			c := &config.Config{Exts: map[string]interface{}{}}
+			c := &config.Config{RepoRoot: dir, Exts: map[string]interface{}{}}
 			rc, rcCleanup := repo.NewRemoteCache(nil)
 			defer func() {
 				if err := rcCleanup(); err != nil {
@@ -30,6 +30,7 @@ import (
 	"strings"
 
 	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/repo"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 )
 
@@ -52,7 +53,7 @@ type moduleFromList struct {
 	Path, Version, Sum string
 	Main               bool
 	Replace            *struct {
-		Path, Version string
+		Path, Version, Dir string
 	}
 	Error *moduleError
 }
@@ -73,26 +74,26 @@ type moduleFromDownload struct {
 }
 
 // extractModules lists all modules except for the main module,
-// including implicit indirect dependencies.
-func extractModules(data []byte) (map[string]*moduleFromList, error) {
+// including implicit indirect dependencies. Modules replaced with local
+// directories are returned separately, since they have no versions or sums.
+func extractModules(data []byte) (pathToModule map[string]*moduleFromList, localModules []*moduleFromList, err error) {
 	// path@version can be used as a unique identifier for looking up sums
-	pathToModule := map[string]*moduleFromList{}
+	pathToModule = map[string]*moduleFromList{}
 	dec := json.NewDecoder(bytes.NewReader(data))
 	for dec.More() {
 		mod := new(moduleFromList)
 		if err := dec.Decode(mod); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if mod.Error != nil {
-			return nil, fmt.Errorf("error listing %s: %s", mod.Path, mod.Error.Err)
+			return nil, nil, fmt.Errorf("error listing %s: %s", mod.Path, mod.Error.Err)
 		}
 		if mod.Main {
 			continue
 		}
 		if mod.Replace != nil {
 			if filepath.IsAbs(mod.Replace.Path) || build.IsLocalImport(mod.Replace.Path) {
-				log.Printf("go_repository does not support file path replacements for %s -> %s", mod.Path,
-					mod.Replace.Path)
+				localModules = append(localModules, mod)
 				continue
 			}
 			pathToModule[mod.Replace.Path+"@"+mod.Replace.Version] = mod
@@ -100,7 +101,24 @@ func extractModules(data []byte) (map[string]*moduleFromList, error) {
 			pathToModule[mod.Path+"@"+mod.Version] = mod
 		}
 	}
-	return pathToModule, nil
+	return pathToModule, localModules, nil
+}
+
+// fillLockedSums fills in missing sums that rc already knows, typically
+// from a lock file, so that fillMissingSums doesn't need to download them.
+func fillLockedSums(pathToModule map[string]*moduleFromList, rc *repo.RemoteCache) {
+	if rc == nil {
+		return
+	}
+	for pathVer, mod := range pathToModule {
+		if mod.Sum != "" {
+			continue
+		}
+		i := strings.LastIndexByte(pathVer, '@')
+		if sum, ok := rc.ModSum(pathVer[:i], pathVer[i+1:]); ok {
+			mod.Sum = sum
+		}
+	}
 }
 
 // fillMissingSums runs `go mod download` to get missing sums.
@@ -179,6 +197,35 @@ func toRepositoryRules(pathToModule map[string]*moduleFromList) []*rule.Rule {
 	return gen
 }
 
+// localRepositoryRules returns go_repository rules for modules replaced with
+// local directories. modDir is the directory containing the go.mod or go.work
+// file with the replace directives. The local_path of each rule is relative
+// to repoRoot, so it may be checked in; go_repository resolves it against
+// the main workspace's root directory.
+func localRepositoryRules(localModules []*moduleFromList, modDir, repoRoot string) []*rule.Rule {
+	gen := make([]*rule.Rule, 0, len(localModules))
+	for _, mod := range localModules {
+		dir := mod.Replace.Dir
+		if dir == "" {
+			dir = mod.Replace.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(modDir, dir)
+			}
+		}
+		localPath := dir
+		if repoRoot != "" {
+			if rel, err := filepath.Rel(repoRoot, dir); err == nil {
+				localPath = filepath.ToSlash(rel)
+			}
+		}
+		r := rule.NewRule("go_repository", label.ImportPathToBazelRepoName(mod.Path))
+		r.SetAttr("importpath", mod.Path)
+		r.SetAttr("local_path", localPath)
+		gen = append(gen, r)
+	}
+	return gen
+}
+
 // processGoListError attempts a best-effort try to adorn specific error details from the JSON output of `go list`.
 func processGoListError(err error, data []byte) error {
 	dec := json.NewDecoder(bytes.NewReader(data))
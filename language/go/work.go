@@ -29,15 +29,18 @@ func importReposFromWork(args language.ImportReposArgs) language.ImportReposResu
 		return language.ImportReposResult{Error: processGoListError(nil, data)}
 	}
 
-	pathToModule, err := extractModules(data)
+	pathToModule, localModules, err := extractModules(data)
 	if err != nil {
 		return language.ImportReposResult{Error: err}
 	}
 
+	fillLockedSums(pathToModule, args.Cache)
 	pathToModule, err = fillMissingSums(pathToModule)
 	if err != nil {
 		return language.ImportReposResult{Error: fmt.Errorf("finding module sums: %v", err)}
 	}
 
-	return language.ImportReposResult{Gen: toRepositoryRules(pathToModule)}
+	gen := toRepositoryRules(pathToModule)
+	gen = append(gen, localRepositoryRules(localModules, filepath.Dir(args.Path), args.Config.RepoRoot)...)
+	return language.ImportReposResult{Gen: gen}
 }
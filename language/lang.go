@@ -22,8 +22,10 @@ limitations under the License.
 package language
 
 import (
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
 	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/repo"
 	"github.com/bazelbuild/bazel-gazelle/resolve"
 )
 
@@ -205,3 +207,57 @@ type GenerateResult struct {
 	// with multiple language extensions.
 	RelsToIndex []string
 }
+
+// ImportResolver may be implemented by languages that can resolve a single
+// import string without generating a rule. Tools like "gazelle resolve" use
+// it to explain how an import is resolved when no resolve directive applies
+// and no rule in the index provides the import, for example, when the
+// import is provided by an external repository named by convention.
+type ImportResolver interface {
+	// ResolveImport returns the label of the library that provides imp when
+	// it's imported by from. It returns label.NoLabel and a nil error if imp
+	// doesn't need a dependency, like a standard library import.
+	ResolveImport(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, imp string, from label.Label) (label.Label, error)
+}
+
+// PostResolver may be implemented by languages that make final adjustments
+// to a package's build file after dependencies are resolved and generated
+// rules are merged into it, for example sorting, annotating, or validating
+// rules. AfterResolve is called once for each package Gazelle updates in
+// which the language is enabled, before load statements are fixed and the
+// file is written. AfterResolve is called for one package at a time, unless
+// the language implements
+// github.com/bazel-contrib/bazel-gazelle/v2/resolve.ConcurrentResolver.
+type PostResolver interface {
+	// AfterResolve adjusts the merged build file for a package. An error is
+	// logged with the file's path; the file is still written.
+	AfterResolve(args AfterResolveArgs) error
+}
+
+// AfterResolveArgs contains arguments for PostResolver.AfterResolve.
+type AfterResolveArgs struct {
+	// Config is the configuration for the package's directory.
+	Config *config.Config
+
+	// Rel is the slash-separated path to the directory, relative to the
+	// repository root ("" for the root directory itself).
+	Rel string
+
+	// File is the package's build file, with generated rules merged and
+	// dependencies resolved. It may be modified.
+	File *rule.File
+
+	// Rules lists the rules in File of kinds returned by the language's
+	// Kinds method, including kinds mapped with map_kind, in file order.
+	Rules []*rule.Rule
+}
+
+// LoadMigrator may be implemented by languages whose rules or symbols moved
+// from one .bzl file to another. Gazelle rewrites load statements of the
+// old files in each build file it updates, after Fix is called, so they load
+// symbols from the new files. See merger.MigrateLoads.
+type LoadMigrator interface {
+	// LoadMigrations returns the symbols that moved. Migrations in the
+	// repository's configuration file take precedence.
+	LoadMigrations() []rule.LoadMigration
+}
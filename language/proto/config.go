@@ -224,7 +224,7 @@ func (*protoLang) Configure(c *config.Config, rel string, f *rule.File) {
 			case "proto":
 				mode, err := ModeFromString(d.Value)
 				if err != nil {
-					log.Print(err)
+					c.ReportDirectiveError(f, d, err)
 					continue
 				}
 				pc.Mode = mode
@@ -234,7 +234,7 @@ func (*protoLang) Configure(c *config.Config, rel string, f *rule.File) {
 			case "proto_strip_import_prefix":
 				pc.StripImportPrefix = d.Value
 				if err := checkStripImportPrefix(pc.StripImportPrefix, rel); err != nil {
-					log.Print(err)
+					c.ReportDirectiveError(f, d, err)
 				}
 			case "proto_import_prefix":
 				pc.ImportPrefix = d.Value
@@ -245,7 +245,7 @@ func (*protoLang) Configure(c *config.Config, rel string, f *rule.File) {
 				} else {
 					args := strings.Fields(d.Value)
 					if len(args) != 2 {
-						log.Printf("# gazelle:proto_search: got %d arguments, expected 2, stripImportPrefix and importPrefix", len(args))
+						c.ReportDirectiveError(f, d, fmt.Errorf("got %d arguments, expected 2, stripImportPrefix and importPrefix", len(args)))
 						continue
 					}
 					stripImportPrefix := args[0]
@@ -18,12 +18,13 @@ package proto
 import (
 	"bytes"
 	"log"
-	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
 )
 
 // FileInfo contains metadata extracted from a .proto file.
@@ -51,11 +52,17 @@ type Option struct {
 var protoRe = buildProtoRegexp()
 
 func ProtoFileInfo(dir, name string) FileInfo {
+	return protoFileInfo(nil, dir, name)
+}
+
+// protoFileInfo is like ProtoFileInfo, but it reads the file with c's file
+// system.
+func protoFileInfo(c *config.Config, dir, name string) FileInfo {
 	info := FileInfo{
 		Path: filepath.Join(dir, name),
 		Name: name,
 	}
-	content, err := os.ReadFile(info.Path)
+	content, err := c.ReadFile(info.Path)
 	if err != nil {
 		log.Printf("%s: error reading proto file: %v", info.Path, err)
 		return info
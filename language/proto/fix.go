@@ -16,8 +16,8 @@ limitations under the License.
 package proto
 
 import (
-	"log"
-
+	"github.com/bazel-contrib/bazel-gazelle/v2/merger"
+	v2rule "github.com/bazel-contrib/bazel-gazelle/v2/rule"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/rule"
@@ -29,21 +29,23 @@ const (
 	rulesProtoModuleName  = "rules_proto"
 )
 
-// Returns the file name of of a deprecated load statement from @rules_proto.
-// Used to identify which load statements to fix.
-func deprecatedFileLabel(moduleToApparentName func(string) string) label.Label {
-	repoName := moduleToApparentName(rulesProtoModuleName)
-	if repoName == "" {
-		repoName = rulesProtoModuleName
-	}
-
-	return label.New(repoName, "proto", "defs.bzl")
-}
-
-// Maps all old symbols from:
+// protobufSymbols lists all old symbols from:
 // https://github.com/bazelbuild/rules_proto/blob/main/proto/defs.bzl
-// to their new file locations in the directory:
+// with their new file locations in the directory:
 // https://github.com/protocolbuffers/protobuf/tree/main/bazel
+var protobufSymbols = []struct {
+	sym, pkg, file string
+}{
+	{"proto_library", "bazel", "proto_library.bzl"},
+	{"proto_descriptor_set", "bazel", "proto_descriptor_set.bzl"},
+	{"proto_lang_toolchain", "bazel/toolchains", "proto_lang_toolchain.bzl"},
+	{"proto_toolchain", "bazel/toolchains", "proto_toolchain.bzl"},
+	{"ProtoInfo", "bazel/common", "proto_info.bzl"},
+	{"proto_common", "bazel/common", "proto_common.bzl"},
+}
+
+// symbolToFileLabel returns the label of the file in the protobuf
+// repository that defines sym, or label.NoLabel if sym is unknown.
 func symbolToFileLabel(moduleToApparentName func(string) string, sym string) label.Label {
 	repoName := moduleToApparentName(protobufModuleName)
 	if repoName == "" {
@@ -51,58 +53,35 @@ func symbolToFileLabel(moduleToApparentName func(string) string, sym string) lab
 		repoName = protobufWorkspaceName
 	}
 
-	switch sym {
-	case "proto_library":
-		return label.New(repoName, "bazel", "proto_library.bzl")
-	case "proto_descriptor_set":
-		return label.New(repoName, "bazel", "proto_descriptor_set.bzl")
-	case "proto_lang_toolchain":
-		return label.New(repoName, "bazel/toolchains", "proto_lang_toolchain.bzl")
-	case "proto_toolchain":
-		return label.New(repoName, "bazel/toolchains", "proto_toolchain.bzl")
-	case "ProtoInfo":
-		return label.New(repoName, "bazel/common", "proto_info.bzl")
-	case "proto_common":
-		return label.New(repoName, "bazel/common", "proto_common.bzl")
-	default:
-		return label.NoLabel
-	}
-}
-
-func hasProtobufModuleDependency(c *config.Config) bool {
-	return c.ModuleToApparentName(protobufModuleName) != ""
-}
-
-func (*protoLang) Fix(c *config.Config, f *rule.File) {
-	if !hasProtobufModuleDependency(c) {
-		return
-	}
-
-	// Collect deprecated Load statements
-	deprecatedFile := deprecatedFileLabel(c.ModuleToApparentName).String()
-	deprecatedLoads := make([]*rule.Load, 0, len(f.Loads))
-	for _, l := range f.Loads {
-		if l.Name() == deprecatedFile {
-			deprecatedLoads = append(deprecatedLoads, l)
+	for _, s := range protobufSymbols {
+		if s.sym == sym {
+			return label.New(repoName, s.pkg, s.file)
 		}
 	}
+	return label.NoLabel
+}
 
-	if len(deprecatedLoads) == 0 {
-		return
+// LoadMigrations moves symbols loaded from @rules_proto//proto:defs.bzl,
+// which is deprecated, to their new locations in the protobuf module. Other
+// symbols loaded from that file are removed. The migrations only apply when
+// the main module depends on protobuf.
+func (*protoLang) LoadMigrations() []v2rule.LoadMigration {
+	from := label.New(rulesProtoModuleName, "proto", "defs.bzl").String()
+	migrations := make([]v2rule.LoadMigration, 0, len(protobufSymbols)+1)
+	for _, s := range protobufSymbols {
+		migrations = append(migrations, v2rule.LoadMigration{
+			From:   from,
+			Symbol: s.sym,
+			To:     label.New(protobufModuleName, s.pkg, s.file).String(),
+			Module: protobufModuleName,
+		})
 	}
+	return append(migrations, v2rule.LoadMigration{From: from, Module: protobufModuleName})
+}
 
-	// Replace the deprecated load statements with the new load statements for
-	// each symbol
-	for _, l := range deprecatedLoads {
-		l.Delete()
-		for _, sym := range l.Symbols() {
-			if newFileLabel := symbolToFileLabel(c.ModuleToApparentName, sym); newFileLabel != label.NoLabel {
-				newLoad := rule.NewLoad(newFileLabel.String())
-				newLoad.Add(sym)
-				newLoad.Insert(f, l.Index())
-			} else {
-				log.Printf("%s: unknown symbol %q loaded from %s", f.Path, sym, deprecatedFile)
-			}
-		}
-	}
+// Fix applies LoadMigrations to f. Run applies the migrations of all
+// languages after Fix, so this only matters to callers that call Fix
+// directly.
+func (l *protoLang) Fix(c *config.Config, f *rule.File) {
+	merger.MigrateLoads(f, l.LoadMigrations(), c.ModuleToApparentName)
 }
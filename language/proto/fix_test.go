@@ -20,7 +20,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bazel-contrib/bazel-gazelle/v2/merger"
 	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 )
 
@@ -205,8 +207,20 @@ func testFix(t *testing.T, tc fixTestCase) {
 	// Strip leading newline, added for readability
 	want := strings.TrimPrefix(tc.want, "\n")
 
-	NewLanguage().Fix(c, f)
+	migrations := NewLanguage().(language.LoadMigrator).LoadMigrations()
+	merger.MigrateLoads(f, migrations, c.ModuleToApparentName)
 	if got := string(f.Format()); got != want {
 		t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tc.desc, got, want)
 	}
+
+	// Fix applies the same migrations for callers that don't call
+	// MigrateLoads themselves.
+	f, err = rule.LoadData(filepath.Join("old", "BUILD.bazel"), "", []byte(tc.old))
+	if err != nil {
+		t.Fatalf("%s: parse error: %v", tc.desc, err)
+	}
+	NewLanguage().Fix(c, f)
+	if got := string(f.Format()); got != want {
+		t.Errorf("%s: Fix:\ngot:\n%s\nwant:\n%s", tc.desc, got, want)
+	}
 }
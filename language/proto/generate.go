@@ -69,7 +69,7 @@ func (*protoLang) GenerateRules(args language.GenerateArgs) language.GenerateRes
 			}
 		}
 	}
-	pkgs := buildPackages(pc, args.Dir, args.Rel, regularProtoFiles, genProtoFilesNotConsumed)
+	pkgs := buildPackages(args.Config, pc, args.Dir, args.Rel, regularProtoFiles, genProtoFilesNotConsumed)
 	shouldSetVisibility := args.File == nil || !args.File.HasDefaultVisibility()
 	var res language.GenerateResult
 	for _, pkg := range pkgs {
@@ -143,10 +143,10 @@ func RuleName(names ...string) string {
 // buildPackage extracts metadata from the .proto files in a directory and
 // constructs possibly several packages, then selects a package to generate
 // a proto_library rule for.
-func buildPackages(pc *ProtoConfig, dir, rel string, protoFiles, genFiles []string) []*Package {
+func buildPackages(c *config.Config, pc *ProtoConfig, dir, rel string, protoFiles, genFiles []string) []*Package {
 	packageMap := make(map[string]*Package)
 	for _, name := range protoFiles {
-		info := ProtoFileInfo(dir, name)
+		info := protoFileInfo(c, dir, name)
 		key := info.PackageName
 
 		if pc.Mode == FileMode {
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"testing"
 
+	v2testtools "github.com/bazel-contrib/bazel-gazelle/v2/testtools"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/language"
 	"github.com/bazelbuild/bazel-gazelle/merger"
@@ -78,16 +79,7 @@ func TestGenerateRules(t *testing.T) {
 			merger.FixLoads(f, lang.(language.ModuleAwareLanguage).ApparentLoads(func(string) string { return "" }))
 			f.Sync()
 			got := string(bzl.Format(f.File))
-			wantPath := filepath.Join(dir, "BUILD.want")
-			wantBytes, err := os.ReadFile(wantPath)
-			if err != nil {
-				t.Fatalf("error reading %s: %v", wantPath, err)
-			}
-			want := string(wantBytes)
-
-			if got != want {
-				t.Errorf("GenerateRules %q: got:\n%s\nwant:\n%s", rel, got, want)
-			}
+			v2testtools.CheckGolden(t, filepath.Join(dir, "BUILD.want"), got)
 		})
 	})
 }
@@ -0,0 +1,80 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proto
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// googleapisModule is the name of the Bazel module containing the protos in
+// github.com/googleapis/googleapis.
+const googleapisModule = "googleapis"
+
+// googleapisDirs are the directories in googleapis whose protos are
+// resolved to targets in the googleapis module when it's a bazel_dep.
+// Protos in subdirectories aren't included, since their targets don't
+// follow the same conventions.
+var googleapisDirs = map[string]bool{
+	"google/api":         true,
+	"google/longrunning": true,
+	"google/rpc":         true,
+	"google/type":        true,
+}
+
+// googleapisGoNames maps protos in googleapis to the names of their
+// go_proto_library targets, where those aren't named after the proto like
+// "status_go_proto". Protos with the same Go import path share a target.
+var googleapisGoNames = map[string]string{
+	"google/api/client.proto":             "annotations_go_proto",
+	"google/api/field_behavior.proto":     "annotations_go_proto",
+	"google/api/field_info.proto":         "annotations_go_proto",
+	"google/api/http.proto":               "annotations_go_proto",
+	"google/api/launch_stage.proto":       "api_go_proto",
+	"google/api/resource.proto":           "annotations_go_proto",
+	"google/api/routing.proto":            "annotations_go_proto",
+	"google/longrunning/operations.proto": "longrunning_go_proto",
+	"google/rpc/error_details.proto":      "errdetails_go_proto",
+}
+
+// googleapisLabel returns the label of the target in the googleapis module
+// that provides the proto imp: a proto_library, or a go_proto_library if
+// goProto is true. The label uses the module's apparent repository name.
+// false is returned if imp isn't in googleapisDirs or if the module isn't a
+// bazel_dep of the main module.
+func googleapisLabel(c *config.Config, imp string, goProto bool) (label.Label, bool) {
+	dir, file := path.Split(imp)
+	dir = strings.TrimSuffix(dir, "/")
+	if !googleapisDirs[dir] || !strings.HasSuffix(file, ".proto") || c.ModuleToApparentName == nil {
+		return label.NoLabel, false
+	}
+	repo := c.ModuleToApparentName(googleapisModule)
+	if repo == "" {
+		return label.NoLabel, false
+	}
+	base := strings.TrimSuffix(file, ".proto")
+	if !goProto {
+		return label.New(repo, dir, base+"_proto"), true
+	}
+	name, ok := googleapisGoNames[imp]
+	if !ok {
+		name = base + "_go_proto"
+	}
+	return label.New(repo, dir, name), true
+}
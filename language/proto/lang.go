@@ -67,6 +67,9 @@ type protoLang struct{}
 
 func (*protoLang) Name() string { return protoName }
 
+// ResolvesConcurrently reports that Resolve may be called concurrently.
+func (*protoLang) ResolvesConcurrently() bool { return true }
+
 func NewLanguage() language.Language {
 	return &protoLang{}
 }
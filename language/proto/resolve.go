@@ -18,7 +18,6 @@ package proto
 import (
 	"errors"
 	"fmt"
-	"log"
 	"path"
 	"sort"
 	"strings"
@@ -66,7 +65,7 @@ func (*protoLang) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.Remo
 		if err == errSkipImport {
 			continue
 		} else if err != nil {
-			log.Print(err)
+			ix.ReportUnresolved(err)
 		} else {
 			l = l.Rel(from.Repo, from.Pkg)
 			depSet[l.String()] = true
@@ -122,6 +121,12 @@ func resolveProto(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imp str
 		return label.NoLabel, err
 	}
 
+	if pc.Mode.ShouldUseKnownImports() {
+		if l, ok := googleapisLabel(c, imp, false); ok {
+			return l, nil
+		}
+	}
+
 	rel := path.Dir(imp)
 	if rel == "." {
 		rel = ""
@@ -154,6 +159,9 @@ func (*protoLang) CrossResolve(c *config.Config, ix *resolve.RuleIndex, imp reso
 		if l, ok := knownProtoImports[imp.Imp]; ok {
 			return []resolve.FindResult{{Label: l}}
 		}
+		if l, ok := googleapisLabel(c, imp.Imp, true); ok {
+			return []resolve.FindResult{{Label: l}}
+		}
 	}
 	return nil
 }
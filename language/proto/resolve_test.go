@@ -35,6 +35,7 @@ func TestResolveProto(t *testing.T) {
 	type testCase struct {
 		desc      string
 		index     []buildFile
+		modules   map[string]string
 		old, want string
 	}
 	for _, tc := range []testCase{
@@ -96,6 +97,43 @@ proto_library(
     name = "dep_proto",
     deps = ["//:good_proto"],
 )
+`,
+		}, {
+			desc:    "googleapis_module",
+			modules: map[string]string{"googleapis": "com_google_googleapis"},
+			old: `
+proto_library(
+    name = "dep_proto",
+    _imports = [
+        "google/api/annotations.proto",
+        "google/api/expr/v1alpha1/syntax.proto",
+        "google/rpc/status.proto",
+    ],
+)
+`,
+			want: `
+proto_library(
+    name = "dep_proto",
+    deps = [
+        "//google/api/expr/v1alpha1:v1alpha1_proto",
+        "@com_google_googleapis//google/api:annotations_proto",
+        "@com_google_googleapis//google/rpc:status_proto",
+    ],
+)
+`,
+		}, {
+			desc: "googleapis_no_module",
+			old: `
+proto_library(
+    name = "dep_proto",
+    _imports = ["google/rpc/status.proto"],
+)
+`,
+			want: `
+proto_library(
+    name = "dep_proto",
+    deps = ["//google/rpc:rpc_proto"],
+)
 `,
 		}, {
 			desc: "index",
@@ -387,6 +425,9 @@ proto_library(
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
 			c, lang, cexts := testConfig(t, ".")
+			if tc.modules != nil {
+				c.ModuleToApparentName = func(m string) string { return tc.modules[m] }
+			}
 			mrslv := make(mapResolver)
 			mrslv["proto_library"] = lang
 			ix := resolve.NewRuleIndex(mrslv.Resolver, []resolve.CrossResolver{lang.(resolve.CrossResolver)})
@@ -432,6 +473,7 @@ func TestCrossResolve(t *testing.T) {
 	type testCase struct {
 		desc      string
 		protoMode Mode
+		modules   map[string]string
 		imp       resolve.ImportSpec
 		lang      string
 		want      []resolve.FindResult
@@ -479,6 +521,29 @@ func TestCrossResolve(t *testing.T) {
 			lang:      "go",
 			want:      []resolve.FindResult{{Label: label.New("com_github_golang_protobuf", "ptypes/any", "any")}},
 		},
+		{
+			desc:      "proto googleapis import",
+			protoMode: DefaultMode,
+			modules:   map[string]string{"googleapis": "googleapis"},
+			imp:       resolve.ImportSpec{Lang: "proto", Imp: "google/api/http.proto"},
+			lang:      "go",
+			want:      []resolve.FindResult{{Label: label.New("googleapis", "google/api", "annotations_go_proto")}},
+		},
+		{
+			desc:      "proto googleapis import without module",
+			protoMode: DefaultMode,
+			imp:       resolve.ImportSpec{Lang: "proto", Imp: "google/rpc/status.proto"},
+			lang:      "go",
+			want:      nil,
+		},
+		{
+			desc:      "proto googleapis import disable global mode",
+			protoMode: DisableGlobalMode,
+			modules:   map[string]string{"googleapis": "googleapis"},
+			imp:       resolve.ImportSpec{Lang: "proto", Imp: "google/rpc/status.proto"},
+			lang:      "go",
+			want:      nil,
+		},
 		{
 			desc:      "proto unknown import",
 			protoMode: DefaultMode,
@@ -491,6 +556,9 @@ func TestCrossResolve(t *testing.T) {
 			c, lang, _ := testConfig(t, ".")
 			pc := GetProtoConfig(c)
 			pc.Mode = tc.protoMode
+			if tc.modules != nil {
+				c.ModuleToApparentName = func(m string) string { return tc.modules[m] }
+			}
 			ix := (*resolve.RuleIndex)(nil)
 			got := lang.(resolve.CrossResolver).CrossResolve(c, ix, tc.imp, tc.lang)
 			if !reflect.DeepEqual(got, tc.want) {
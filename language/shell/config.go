@@ -0,0 +1,73 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"log"
+	"strings"
+
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const testSuffixDirective = "shell_test_suffix"
+
+var defaultTestSuffixes = []string{"_test.sh", "_test.bash"}
+
+type shellConfig struct {
+	testSuffixes []string
+}
+
+func getShellConfig(c *config.Config) *shellConfig {
+	sc, _ := c.Exts[languageName].(*shellConfig)
+	if sc == nil {
+		return &shellConfig{testSuffixes: defaultTestSuffixes}
+	}
+	return sc
+}
+
+func (*shellLang) KnownDirectives() []string {
+	return []string{testSuffixDirective}
+}
+
+func (*shellLang) Configure(c *config.Config, rel string, f *rule.File) {
+	sc := *getShellConfig(c)
+	c.Exts[languageName] = &sc
+	if f == nil {
+		return
+	}
+	for _, d := range f.Directives {
+		switch d.Key {
+		case testSuffixDirective:
+			var suffixes []string
+			for _, s := range strings.Split(d.Value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					suffixes = append(suffixes, s)
+				}
+			}
+			if len(suffixes) == 0 {
+				log.Printf("%s: %s requires at least one suffix", f.Path, testSuffixDirective)
+				continue
+			}
+			sc.testSuffixes = suffixes
+		case "reset":
+			if v2config.ResetsDirective(d, testSuffixDirective) {
+				sc.testSuffixes = defaultTestSuffixes
+			}
+		}
+	}
+}
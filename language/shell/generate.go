@@ -0,0 +1,128 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// scriptExts are the file extensions of shell scripts.
+var scriptExts = []string{".sh", ".bash"}
+
+// shells are the interpreters a shebang line may name for a file to be
+// treated as a shell script.
+var shells = map[string]bool{
+	"bash": true,
+	"dash": true,
+	"ksh":  true,
+	"sh":   true,
+	"zsh":  true,
+}
+
+func (*shellLang) GenerateRules(args language.GenerateArgs) language.GenerateResult {
+	var res language.GenerateResult
+	sc := getShellConfig(args.Config)
+	scripts := make(map[string]bool)
+	for _, f := range args.RegularFiles {
+		if !isScript(args.Config, filepath.Join(args.Dir, f), f) {
+			continue
+		}
+		scripts[f] = true
+		kind := binaryKind
+		for _, suffix := range sc.testSuffixes {
+			if strings.HasSuffix(f, suffix) {
+				kind = testKind
+				break
+			}
+		}
+		r := rule.NewRule(kind, ruleName(f))
+		r.SetAttr("srcs", []string{f})
+		res.Gen = append(res.Gen, r)
+		res.Imports = append(res.Imports, nil)
+	}
+
+	if args.File != nil {
+		for _, r := range args.File.Rules {
+			if r.Kind() != binaryKind && r.Kind() != testKind {
+				continue
+			}
+			srcs := r.AttrStrings("srcs")
+			if len(srcs) == 1 && !strings.ContainsAny(srcs[0], ":/") && !scripts[srcs[0]] {
+				res.Empty = append(res.Empty, rule.NewRule(r.Kind(), r.Name()))
+			}
+		}
+	}
+	return res
+}
+
+// ruleName returns the name of the rule for the script named file.
+func ruleName(file string) string {
+	if ext := path.Ext(file); ext != "" {
+		return strings.TrimSuffix(file, ext)
+	}
+	return file + "_sh"
+}
+
+// isScript reports whether the file at filePath, named name, is an
+// executable shell script.
+func isScript(c *config.Config, filePath, name string) bool {
+	fi, err := c.Stat(filePath)
+	if err != nil || !fi.Mode().IsRegular() || fi.Mode().Perm()&0o111 == 0 {
+		return false
+	}
+	for _, ext := range scriptExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return shells[shebangInterpreter(c, filePath)]
+}
+
+type shebangKey struct{}
+
+// shebangInterpreter returns the base name of the interpreter named by the
+// shebang line of the file at filePath, looking through "env". It returns
+// "" if the file doesn't start with a shebang line.
+func shebangInterpreter(c *config.Config, filePath string) string {
+	v, err := c.ParseFile(filePath, shebangKey{}, func(_ string, data []byte) (any, error) {
+		if !bytes.HasPrefix(data, []byte("#!")) {
+			return "", nil
+		}
+		line, _, _ := bytes.Cut(data[2:], []byte("\n"))
+		fields := strings.Fields(string(line))
+		if len(fields) > 0 && path.Base(fields[0]) == "env" {
+			fields = fields[1:]
+			for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+				fields = fields[1:]
+			}
+		}
+		if len(fields) == 0 {
+			return "", nil
+		}
+		return path.Base(fields[0]), nil
+	})
+	if err != nil {
+		return ""
+	}
+	return v.(string)
+}
@@ -0,0 +1,136 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
+	"github.com/bazelbuild/bazel-gazelle/internal/language/langtest"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateRules(t *testing.T) {
+	for _, tc := range []struct {
+		desc, build, want string
+		files             []testtools.FileSpec
+	}{
+		{
+			desc: "scripts",
+			files: []testtools.FileSpec{
+				{Path: "deploy.sh", Content: "echo deploy", Mode: 0o755},
+				{Path: "check_test.bash", Content: "exit 0", Mode: 0o755},
+				{Path: "release", Content: "#!/usr/bin/env -S bash -eu\n", Mode: 0o755},
+				{Path: "lib.sh", Content: "f() { :; }"},
+				{Path: "tool.py", Content: "#!/usr/bin/env python3\n", Mode: 0o755},
+				{Path: "run", Content: "#!/bin/sh\n"},
+			},
+			want: `
+sh_test(
+    name = "check_test",
+    srcs = ["check_test.bash"],
+)
+
+sh_binary(
+    name = "deploy",
+    srcs = ["deploy.sh"],
+)
+
+sh_binary(
+    name = "release_sh",
+    srcs = ["release"],
+)
+`,
+		}, {
+			desc: "test_suffix_directive",
+			build: `
+# gazelle:shell_test_suffix _check.sh
+`,
+			files: []testtools.FileSpec{
+				{Path: "lint_check.sh", Mode: 0o755},
+				{Path: "unit_test.sh", Mode: 0o755},
+			},
+			want: `
+# gazelle:shell_test_suffix _check.sh
+
+sh_test(
+    name = "lint_check",
+    srcs = ["lint_check.sh"],
+)
+
+sh_binary(
+    name = "unit_test",
+    srcs = ["unit_test.sh"],
+)
+`,
+		}, {
+			desc: "merge_and_delete",
+			build: `
+sh_binary(
+    name = "custom",
+    srcs = ["build.sh"],
+    data = ["//data"],
+)
+
+sh_binary(
+    name = "removed",
+    srcs = ["removed.sh"],
+)
+
+sh_binary(
+    name = "wrapper",
+    srcs = ["//tools:wrapper.sh"],
+)
+`,
+			files: []testtools.FileSpec{
+				{Path: "build.sh", Mode: 0o755},
+			},
+			want: `
+sh_binary(
+    name = "custom",
+    srcs = ["build.sh"],
+    data = ["//data"],
+)
+
+sh_binary(
+    name = "wrapper",
+    srcs = ["//tools:wrapper.sh"],
+)
+`,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir, cleanup := testtools.CreateFiles(t, tc.files)
+			defer cleanup()
+
+			var regularFiles []string
+			for _, file := range tc.files {
+				regularFiles = append(regularFiles, file.Path)
+			}
+			sort.Strings(regularFiles)
+			got := strings.TrimSpace(langtest.Generate(t, NewLanguage(), "", "", tc.build, language.GenerateArgs{
+				Dir:          dir,
+				RegularFiles: regularFiles,
+			}))
+			if diff := cmp.Diff(strings.TrimSpace(tc.want), got); diff != "" {
+				t.Errorf("(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shell generates sh_binary and sh_test rules for executable shell
+// scripts.
+//
+// A regular file is a script if it's executable and either has a .sh or
+// .bash extension or starts with a shebang line that runs a shell, like
+// "#!/bin/bash" or "#!/usr/bin/env sh". Scripts whose names end with a test
+// suffix get an sh_test rule; other scripts get an sh_binary rule. Rules are
+// named after the script without its extension, or with "_sh" appended if
+// the script has no extension. Rules whose scripts are removed are deleted.
+//
+// Test suffixes are configured with the directive
+//
+//	# gazelle:shell_test_suffix _test.sh,_test.bash
+//
+// which applies to the directory where it's written and its subdirectories.
+// The value above is the default.
+package shell
+
+import (
+	"fmt"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	languageName = "shell"
+	binaryKind   = "sh_binary"
+	testKind     = "sh_test"
+)
+
+type shellLang struct {
+	language.BaseLang
+}
+
+// NewLanguage returns a language that generates sh_binary and sh_test rules.
+func NewLanguage() language.Language {
+	return &shellLang{}
+}
+
+func (*shellLang) Name() string {
+	return languageName
+}
+
+// ResolvesConcurrently reports that Resolve may be called concurrently.
+func (*shellLang) ResolvesConcurrently() bool {
+	return true
+}
+
+func (*shellLang) Kinds() map[string]rule.KindInfo {
+	info := rule.KindInfo{
+		MatchAttrs:     []string{"srcs"},
+		NonEmptyAttrs:  map[string]bool{"srcs": true},
+		MergeableAttrs: map[string]bool{"srcs": true},
+	}
+	return map[string]rule.KindInfo{
+		binaryKind: info,
+		testKind:   info,
+	}
+}
+
+func (*shellLang) Loads() []rule.LoadInfo {
+	panic("ApparentLoads should be called instead")
+}
+
+func (*shellLang) ApparentLoads(moduleToApparentName func(string) string) []rule.LoadInfo {
+	rulesShell := moduleToApparentName("rules_shell")
+	if rulesShell == "" {
+		rulesShell = "rules_shell"
+	}
+	return []rule.LoadInfo{
+		{
+			Name:    fmt.Sprintf("@%s//shell:sh_binary.bzl", rulesShell),
+			Symbols: []string{binaryKind},
+		},
+		{
+			Name:    fmt.Sprintf("@%s//shell:sh_test.bzl", rulesShell),
+			Symbols: []string{testKind},
+		},
+	}
+}
+
+func (*shellLang) Fix(c *config.Config, f *rule.File) {}
@@ -118,3 +118,83 @@ type ImportReposResult struct {
 	// Error is any fatal error that occurred. Non-fatal errors should be logged.
 	Error error
 }
+
+// ModuleUpdater may be implemented by languages whose repository rules are
+// declared through a module extension when the main repository uses Bzlmod.
+// In that case, update-repos doesn't write repository rules to WORKSPACE.
+// Instead, it passes the rules returned by UpdateRepos or ImportRepos to
+// UpdateModule, then adds the extension tags and use_repo entries it returns
+// to MODULE.bazel.
+//
+// EXPERIMENTAL: this may change or be removed.
+type ModuleUpdater interface {
+	UpdateModule(args UpdateModuleArgs) UpdateModuleResult
+}
+
+// UpdateModuleArgs contains arguments for ModuleUpdater.UpdateModule.
+// Arguments are passed in a struct value so that new fields may be added
+// in the future without breaking existing implementations.
+//
+// EXPERIMENTAL: this may change or be removed.
+type UpdateModuleArgs struct {
+	// Config is the configuration for the main workspace.
+	Config *config.Config
+
+	// Gen is a list of repository rules generated by UpdateRepos or
+	// ImportRepos. Only rules of kinds returned by the language's Kinds
+	// method are included.
+	Gen []*rule.Rule
+
+	// File is the main module's MODULE.bazel file. UpdateModule may read it,
+	// for example, to find tags that already declare a repository, but it
+	// should not modify it.
+	File *rule.ModuleFile
+}
+
+// UpdateModuleResult contains return values for ModuleUpdater.UpdateModule.
+// Results are returned through a struct so that new (optional) fields may be
+// added without breaking existing implementations.
+//
+// EXPERIMENTAL: this may change or be removed.
+type UpdateModuleResult struct {
+	// Extensions is a list of changes to make to module extension usages.
+	Extensions []ExtensionUpdate
+
+	// Error is any fatal error that occurred. Non-fatal errors should be logged.
+	Error error
+}
+
+// ExtensionUpdate describes tags and repositories to add to a usage of a
+// module extension in MODULE.bazel. The usage is declared with
+// use_extension if MODULE.bazel doesn't have one already.
+//
+// EXPERIMENTAL: this may change or be removed.
+type ExtensionUpdate struct {
+	// BzlFile is the label of the .bzl file that defines the extension, for
+	// example, "@gazelle//:extensions.bzl".
+	BzlFile string
+
+	// Name is the name of the extension within BzlFile, for example,
+	// "go_deps".
+	Name string
+
+	// DevDependency is true if the extension usage should be declared with
+	// dev_dependency = True.
+	DevDependency bool
+
+	// Tags is a list of tags to add to the extension usage. The kind of each
+	// rule is the name of the tag without the proxy variable, for example,
+	// "module". Tags should be created without a name, for example, with
+	// rule.NewRule("module", "").
+	Tags []*rule.Rule
+
+	// TagKey is the attribute that identifies tags. A tag in Tags replaces
+	// the attributes of an existing tag with the same name and the same value
+	// of this attribute, unless the existing tag is marked with a "# keep"
+	// comment. If TagKey is empty, tags with the same name are replaced.
+	TagKey string
+
+	// UseRepos is a list of repositories to import from the extension with
+	// use_repo.
+	UseRepos []string
+}
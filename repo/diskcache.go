@@ -0,0 +1,182 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/vcs"
+)
+
+// DefaultDiskCacheDir returns the directory where lookups are persisted
+// by default: a "gazelle/remote" directory in the user's cache directory.
+func DefaultDiskCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gazelle", "remote"), nil
+}
+
+// UseDiskCache persists the results of network lookups in dir, so that
+// later RemoteCaches using the same directory, including those in other
+// processes, don't need to repeat them. Repository roots and remotes found
+// with RepoRootForImportPath, module paths found with ModInfo, and module
+// versions found with ModVersionInfo are persisted. Results older than ttl
+// are looked up again, except for module versions queried by their
+// canonical semantic version, which don't change.
+//
+// UseDiskCache wraps the RepoRootForImportPath, ModInfo, and ModVersionInfo
+// functions, so it must be called after they're overridden, and before
+// other methods are called. Errors reading and writing the cache are
+// ignored; lookups fall back to the network.
+func (r *RemoteCache) UseDiskCache(dir string, ttl time.Duration) {
+	dc := diskCache{dir: dir, ttl: ttl}
+
+	repoRootForImportPath := r.RepoRootForImportPath
+	r.RepoRootForImportPath = func(importPath string, verbose bool) (*vcs.RepoRoot, error) {
+		var v diskRepoRoot
+		if dc.get("root", importPath, false, &v) {
+			if cmd := vcs.ByCmd(v.VCS); cmd != nil {
+				return &vcs.RepoRoot{VCS: cmd, Repo: v.Repo, Root: v.Root}, nil
+			}
+		}
+		root, err := repoRootForImportPath(importPath, verbose)
+		if err != nil {
+			return nil, err
+		}
+		if root.VCS != nil {
+			dc.put("root", importPath, diskRepoRoot{Root: root.Root, Repo: root.Repo, VCS: root.VCS.Cmd})
+		}
+		return root, nil
+	}
+
+	modInfo := r.ModInfo
+	r.ModInfo = func(importPath string) (string, error) {
+		var modPath string
+		if dc.get("mod", importPath, false, &modPath) {
+			return modPath, nil
+		}
+		modPath, err := modInfo(importPath)
+		if err != nil {
+			return "", err
+		}
+		dc.put("mod", importPath, modPath)
+		return modPath, nil
+	}
+
+	modVersionInfo := r.ModVersionInfo
+	r.ModVersionInfo = func(modPath, query string) (string, string, error) {
+		key := modPath + "@" + query
+		immutable := semver.IsValid(query) && semver.Canonical(query) == query
+		var v diskModVersion
+		if dc.get("modversion", key, immutable, &v) {
+			return v.Version, v.Sum, nil
+		}
+		version, sum, err := modVersionInfo(modPath, query)
+		if err != nil {
+			return "", "", err
+		}
+		dc.put("modversion", key, diskModVersion{Version: version, Sum: sum})
+		return version, sum, nil
+	}
+}
+
+// diskCache stores values in JSON files in a directory, one file per key.
+type diskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+type diskCacheEntry struct {
+	Key   string          `json:"key"`
+	Time  time.Time       `json:"time"`
+	Value json.RawMessage `json:"value"`
+}
+
+type diskRepoRoot struct {
+	Root string `json:"root"`
+	Repo string `json:"repo"`
+	VCS  string `json:"vcs"`
+}
+
+type diskModVersion struct {
+	Version string `json:"version"`
+	Sum     string `json:"sum"`
+}
+
+// path returns the file where the value for a key of the given kind is
+// stored. Keys are hashed, since they may contain characters that aren't
+// allowed in file names.
+func (dc diskCache) path(kind, key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(dc.dir, kind, hex.EncodeToString(h[:])+".json")
+}
+
+// get reads the value for a key into v. It returns false if there is no
+// value, or if the value is older than the cache's TTL and noExpire is false.
+func (dc diskCache) get(kind, key string, noExpire bool, v interface{}) bool {
+	data, err := os.ReadFile(dc.path(kind, key))
+	if err != nil {
+		return false
+	}
+	var e diskCacheEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return false
+	}
+	if !noExpire && time.Since(e.Time) > dc.ttl {
+		return false
+	}
+	return json.Unmarshal(e.Value, v) == nil
+}
+
+// put writes the value for a key. The file is written to a temporary
+// location first, then renamed, so concurrent readers never see a partially
+// written file.
+func (dc diskCache) put(kind, key string, v interface{}) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(diskCacheEntry{Key: key, Time: time.Now(), Value: value})
+	if err != nil {
+		return
+	}
+	path := dc.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
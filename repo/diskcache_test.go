@@ -0,0 +1,78 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	var rootCalls, modCalls, modVersionCalls int
+	newCache := func(ttl time.Duration) *RemoteCache {
+		rc := NewStubRemoteCache(nil)
+		rc.RepoRootForImportPath = func(importPath string, verbose bool) (*vcs.RepoRoot, error) {
+			rootCalls++
+			return stubRepoRootForImportPath(importPath, verbose)
+		}
+		rc.ModInfo = func(importPath string) (string, error) {
+			modCalls++
+			return stubModInfo(importPath)
+		}
+		rc.ModVersionInfo = func(modPath, query string) (string, string, error) {
+			modVersionCalls++
+			return stubModVersionInfo(modPath, query)
+		}
+		rc.UseDiskCache(dir, ttl)
+		return rc
+	}
+	lookup := func(rc *RemoteCache) {
+		t.Helper()
+		if root, _, err := rc.Root("example.com/repo/pkg"); err != nil || root != "example.com/repo" {
+			t.Fatalf("Root: got %q, %v; want %q", root, err, "example.com/repo")
+		}
+		if remote, vcs, err := rc.Remote("example.com/repo"); err != nil || remote != "https://example.com/repo.git" || vcs != "git" {
+			t.Fatalf("Remote: got %q, %q, %v; want %q, %q", remote, vcs, err, "https://example.com/repo.git", "git")
+		}
+		if modPath, _, err := rc.Mod("example.com/stub/v2/pkg"); err != nil || modPath != "example.com/stub/v2" {
+			t.Fatalf("Mod: got %q, %v; want %q", modPath, err, "example.com/stub/v2")
+		}
+		if _, version, sum, err := rc.ModVersion("example.com/known", "v1.2.3"); err != nil || version != "v1.2.3" || sum != "h1:abcdef" {
+			t.Fatalf("ModVersion: got %q, %q, %v; want %q, %q", version, sum, err, "v1.2.3", "h1:abcdef")
+		}
+	}
+
+	lookup(newCache(time.Hour))
+	if rootCalls != 2 || modCalls != 1 || modVersionCalls != 1 {
+		t.Fatalf("first run: got %d, %d, %d lookups; want 2, 1, 1", rootCalls, modCalls, modVersionCalls)
+	}
+
+	// A second cache reads the results from disk.
+	lookup(newCache(time.Hour))
+	if rootCalls != 2 || modCalls != 1 || modVersionCalls != 1 {
+		t.Errorf("second run: got %d, %d, %d lookups; want 2, 1, 1", rootCalls, modCalls, modVersionCalls)
+	}
+
+	// Expired results are looked up again, except for module versions
+	// queried by canonical version.
+	lookup(newCache(0))
+	if rootCalls != 4 || modCalls != 2 || modVersionCalls != 1 {
+		t.Errorf("expired run: got %d, %d, %d lookups; want 4, 2, 1", rootCalls, modCalls, modVersionCalls)
+	}
+}
@@ -0,0 +1,198 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// LockVersion is the version of the lock file format written by Lock.Save.
+// LoadLock rejects lock files with other versions.
+const LockVersion = 1
+
+// Lock is a record of repository metadata that a RemoteCache looked up over
+// the network: repository roots and remotes, module paths, and module sums.
+// A Lock may be saved to a file after a run and loaded before the next one
+// so that lookups for unchanged modules are reproducible and don't need
+// network access.
+//
+// Only results that identify a specific version are recorded. Queries like
+// "latest" or a branch name are always looked up again, though the sums of
+// the versions they resolve to are recorded.
+type Lock struct {
+	Version int `json:"version"`
+
+	// Roots maps import paths to the root import paths of the repositories
+	// that contain them.
+	Roots map[string]string `json:"roots,omitempty"`
+
+	// Remotes maps repository root import paths to their remote URLs and
+	// version control systems.
+	Remotes map[string]LockRemote `json:"remotes,omitempty"`
+
+	// Modules maps import paths to the paths of the modules that provide them.
+	Modules map[string]string `json:"modules,omitempty"`
+
+	// Sums maps module paths with versions, like "example.com/m@v1.0.0", to
+	// the sums of the modules' content.
+	Sums map[string]string `json:"sums,omitempty"`
+}
+
+// LockRemote is the remote URL and version control system for a repository
+// recorded in a Lock.
+type LockRemote struct {
+	Remote string `json:"remote"`
+	VCS    string `json:"vcs"`
+}
+
+// LoadLock reads a lock file written by Lock.Save. If the file does not
+// exist, LoadLock returns an empty Lock.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Lock{Version: LockVersion}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	l := &Lock{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("reading lock file %s: %w", path, err)
+	}
+	if l.Version != LockVersion {
+		return nil, fmt.Errorf("reading lock file %s: unsupported version %d; want %d", path, l.Version, LockVersion)
+	}
+	return l, nil
+}
+
+// Save writes the lock to a file. Keys are written in sorted order, so
+// the file is stable from one run to the next.
+func (l *Lock) Save(path string) error {
+	l.Version = LockVersion
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return os.WriteFile(path, data, 0o666)
+}
+
+// UseLock adds the metadata recorded in l to the cache, so that the Root,
+// Remote, Mod, and ModVersion methods return it without network access.
+// Entries for repositories passed to NewRemoteCache take precedence over
+// entries in the lock. UseLock must be called before other methods.
+func (r *RemoteCache) UseLock(l *Lock) {
+	for importPath, root := range l.Roots {
+		if _, ok := r.root.cache[importPath]; !ok {
+			r.root.cache[importPath] = &remoteCacheEntry{
+				value:  rootValue{root: root, name: label.ImportPathToBazelRepoName(root)},
+				locked: true,
+			}
+		}
+	}
+	for root, lr := range l.Remotes {
+		if _, ok := r.remote.cache[root]; !ok {
+			r.remote.cache[root] = &remoteCacheEntry{
+				value:  remoteValue{remote: lr.Remote, vcs: lr.VCS},
+				locked: true,
+			}
+		}
+	}
+	for importPath, modPath := range l.Modules {
+		if _, ok := r.mod.cache[importPath]; !ok {
+			r.mod.cache[importPath] = &remoteCacheEntry{
+				value:  modValue{path: modPath, name: label.ImportPathToBazelRepoName(modPath)},
+				locked: true,
+			}
+		}
+	}
+	for pathVersion, sum := range l.Sums {
+		i := strings.LastIndexByte(pathVersion, '@')
+		if i < 0 {
+			continue
+		}
+		r.modVersion.cache[pathVersion] = &remoteCacheEntry{
+			value:  modVersionValue{path: pathVersion[:i], version: pathVersion[i+1:], sum: sum},
+			locked: true,
+		}
+	}
+}
+
+// Lock returns a record of the metadata looked up by the cache or added
+// with UseLock. Failed lookups and repositories passed to NewRemoteCache
+// are not included. Lock should be called after other methods have
+// returned.
+func (r *RemoteCache) Lock() *Lock {
+	l := &Lock{
+		Version: LockVersion,
+		Roots:   make(map[string]string),
+		Remotes: make(map[string]LockRemote),
+		Modules: make(map[string]string),
+		Sums:    make(map[string]string),
+	}
+	r.root.forEachLocked(func(key string, v interface{}) {
+		l.Roots[key] = v.(rootValue).root
+	})
+	r.remote.forEachLocked(func(key string, v interface{}) {
+		rv := v.(remoteValue)
+		l.Remotes[key] = LockRemote{Remote: rv.remote, VCS: rv.vcs}
+	})
+	r.mod.forEachLocked(func(key string, v interface{}) {
+		l.Modules[key] = v.(modValue).path
+	})
+	r.modVersion.forEachLocked(func(_ string, v interface{}) {
+		mv := v.(modVersionValue)
+		l.Sums[mv.path+"@"+mv.version] = mv.sum
+	})
+	return l
+}
+
+// ModSum returns the sum for a specific version of a module, if the cache
+// already knows it from a lock file or an earlier call to ModVersion.
+// ModSum never accesses the network.
+func (r *RemoteCache) ModSum(modPath, version string) (sum string, ok bool) {
+	v, ok, err := r.modVersion.get(modPath + "@" + version)
+	if !ok || err != nil {
+		return "", false
+	}
+	return v.(modVersionValue).sum, true
+}
+
+// forEachLocked calls fn for each entry that was loaded successfully or
+// read from a lock file.
+func (m *remoteCacheMap) forEachLocked(fn func(key string, value interface{})) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, e := range m.cache {
+		if !e.locked || e.err != nil {
+			continue
+		}
+		if e.ready != nil {
+			select {
+			case <-e.ready:
+			default:
+				continue
+			}
+		}
+		fn(key, e.value)
+	}
+}
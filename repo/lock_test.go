@@ -0,0 +1,117 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/vcs"
+)
+
+func TestLock(t *testing.T) {
+	rc := NewStubRemoteCache([]Repo{{Name: "com_example_known", GoPrefix: "example.com/known"}})
+	if _, _, err := rc.Root("example.com/repo/pkg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rc.Remote("example.com/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rc.Mod("example.com/stub/v2/pkg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := rc.ModVersion("example.com/unknown", "latest"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := rc.ModVersion("example.com/missing", "latest"); err == nil {
+		t.Fatal("ModVersion for missing module: got success, want error")
+	}
+
+	got := rc.Lock()
+	want := &Lock{
+		Version: LockVersion,
+		Roots: map[string]string{
+			"example.com/repo/pkg": "example.com/repo",
+		},
+		Remotes: map[string]LockRemote{
+			"example.com/repo": {Remote: "https://example.com/repo.git", VCS: "git"},
+		},
+		Modules: map[string]string{
+			"example.com/stub/v2/pkg": "example.com/stub/v2",
+		},
+		Sums: map[string]string{
+			"example.com/unknown@v1.2.3": "h1:abcdef",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Lock (-want,+got):\n%s", diff)
+	}
+
+	lockPath := filepath.Join(t.TempDir(), "gazelle_lock.json")
+	if err := got.Save(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLock(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, loaded); diff != "" {
+		t.Fatalf("LoadLock (-want,+got):\n%s", diff)
+	}
+
+	// A cache that uses the lock should not need the network.
+	offline := NewStubRemoteCache(nil)
+	offline.RepoRootForImportPath = func(string, bool) (*vcs.RepoRoot, error) {
+		return nil, errors.New("network access")
+	}
+	offline.ModInfo = func(string) (string, error) {
+		return "", errors.New("network access")
+	}
+	offline.ModVersionInfo = func(string, string) (string, string, error) {
+		return "", "", errors.New("network access")
+	}
+	offline.UseLock(loaded)
+	if root, _, err := offline.Root("example.com/repo/pkg/sub"); err != nil || root != "example.com/repo" {
+		t.Errorf("Root: got %q, %v; want %q", root, err, "example.com/repo")
+	}
+	if remote, _, err := offline.Remote("example.com/repo"); err != nil || remote != "https://example.com/repo.git" {
+		t.Errorf("Remote: got %q, %v; want %q", remote, err, "https://example.com/repo.git")
+	}
+	if modPath, _, err := offline.Mod("example.com/stub/v2/pkg"); err != nil || modPath != "example.com/stub/v2" {
+		t.Errorf("Mod: got %q, %v; want %q", modPath, err, "example.com/stub/v2")
+	}
+	if _, _, sum, err := offline.ModVersion("example.com/unknown", "v1.2.3"); err != nil || sum != "h1:abcdef" {
+		t.Errorf("ModVersion: got %q, %v; want %q", sum, err, "h1:abcdef")
+	}
+	if _, _, _, err := offline.ModVersion("example.com/unknown", "latest"); err == nil {
+		t.Errorf("ModVersion with query latest: got success, want network access")
+	}
+	if sum, ok := offline.ModSum("example.com/unknown", "v1.2.3"); !ok || sum != "h1:abcdef" {
+		t.Errorf("ModSum: got %q, %v; want %q, true", sum, ok, "h1:abcdef")
+	}
+}
+
+func TestLoadLockMissing(t *testing.T) {
+	l, err := LoadLock(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Lock{Version: LockVersion}, l); diff != "" {
+		t.Errorf("(-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,172 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v2rule "github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"golang.org/x/mod/modfile"
+)
+
+// ListModuleRepositories returns go_repository rules describing the Go
+// modules that the MODULE.bazel file at modulePath imports from Gazelle's
+// go_deps module extension with use_repo. The rules aren't written anywhere;
+// like the rules returned by ListRepositories, they tell dependency
+// resolution which repository provides each module, so repositories without
+// a WORKSPACE file see the same repositories as the build.
+//
+// Each rule is named with the repository's apparent name in the main module.
+// Module paths are found in go_deps.module tags and in the go.mod and go.work
+// files named by go_deps.from_file tags. Repository names set with
+// go_deps.module_override are respected. Directives from
+// go_deps.gazelle_override tags are set as build_directives.
+func ListModuleRepositories(modulePath string) ([]*rule.Rule, error) {
+	f, err := v2rule.LoadModuleFile(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	repoRoot := filepath.Dir(modulePath)
+	var repos []*rule.Rule
+	seen := make(map[string]bool)
+	for _, p := range f.ExtensionProxies() {
+		if !isGoDepsProxy(p) {
+			continue
+		}
+		var modPaths []string
+		repoNames := make(map[string]string)
+		directives := make(map[string][]string)
+		for _, tag := range f.Tags(p.Ident) {
+			switch strings.TrimPrefix(tag.Kind(), p.Ident+".") {
+			case "from_file":
+				for _, attr := range []string{"go_mod", "go_work"} {
+					if l := tag.AttrString(attr); l != "" {
+						paths, err := goDepsFileModules(repoRoot, l, attr == "go_work")
+						if err != nil {
+							return nil, err
+						}
+						modPaths = append(modPaths, paths...)
+					}
+				}
+			case "module":
+				modPaths = append(modPaths, tag.AttrString("path"))
+			case "module_override":
+				if name := tag.AttrString("repo_name"); name != "" {
+					repoNames[tag.AttrString("path")] = name
+				}
+			case "gazelle_override":
+				directives[tag.AttrString("path")] = tag.AttrStrings("directives")
+			}
+		}
+
+		// Map the names go_deps gives repositories back to module paths.
+		extRepoToModule := make(map[string]string)
+		for _, modPath := range modPaths {
+			name := repoNames[modPath]
+			if name == "" {
+				name = label.ImportPathToBazelRepoName(modPath)
+			}
+			extRepoToModule[name] = modPath
+		}
+
+		mapping := f.UseRepoMapping(p.Ident)
+		apparentNames := make([]string, 0, len(mapping))
+		for apparentName := range mapping {
+			apparentNames = append(apparentNames, apparentName)
+		}
+		sort.Strings(apparentNames)
+		for _, apparentName := range apparentNames {
+			modPath, ok := extRepoToModule[mapping[apparentName]]
+			if !ok || seen[apparentName] {
+				continue
+			}
+			seen[apparentName] = true
+			r := rule.NewRule("go_repository", apparentName)
+			r.SetAttr("importpath", modPath)
+			if ds := directives[modPath]; len(ds) > 0 {
+				r.SetAttr("build_directives", ds)
+			}
+			repos = append(repos, r)
+		}
+	}
+	return repos, nil
+}
+
+// isGoDepsProxy returns whether p is a usage of Gazelle's go_deps module
+// extension. Gazelle's module may have any apparent name, so only the file
+// and extension names are checked.
+func isGoDepsProxy(p v2rule.ExtensionProxy) bool {
+	return p.Name == "go_deps" && strings.HasSuffix(p.BzlFile, "//:extensions.bzl")
+}
+
+// goDepsFileModules returns the paths of the modules required by the go.mod
+// file, or by the modules used in the go.work file, named by the label l in
+// MODULE.bazel.
+func goDepsFileModules(repoRoot, l string, isWork bool) ([]string, error) {
+	parsed, err := label.Parse(l)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Repo != "" && parsed.Repo != "@" {
+		return nil, fmt.Errorf("%s: go_deps.from_file must name a file in the main repository", l)
+	}
+	p := filepath.Join(repoRoot, filepath.FromSlash(path.Join(parsed.Pkg, parsed.Name)))
+	if !isWork {
+		return goModRequires(p)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := modfile.ParseWork(p, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	var modPaths []string
+	for _, u := range wf.Use {
+		paths, err := goModRequires(filepath.Join(filepath.Dir(p), filepath.FromSlash(u.Path), "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		modPaths = append(modPaths, paths...)
+	}
+	return modPaths, nil
+}
+
+// goModRequires returns the paths of the modules required by the go.mod
+// file at goModPath.
+func goModRequires(goModPath string) ([]string, error) {
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	mf, err := modfile.ParseLax(goModPath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(mf.Require))
+	for _, r := range mf.Require {
+		paths = append(paths, r.Mod.Path)
+	}
+	return paths, nil
+}
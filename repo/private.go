@@ -0,0 +1,272 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/tools/go/vcs"
+)
+
+// isPrivate returns whether importPath matches one of the patterns in
+// GOPRIVATE, GONOPROXY, or GONOSUMDB, read with "go env" so that values set
+// with "go env -w" are honored. Metadata for private paths is fetched with
+// credentials, since it may not be public.
+func (r *RemoteCache) isPrivate(importPath string) bool {
+	r.privateOnce.Do(func() {
+		keys := []string{"GOPRIVATE", "GONOPROXY", "GONOSUMDB"}
+		out, err := exec.Command(findGoTool(), append([]string{"env"}, keys...)...).Output()
+		if err != nil {
+			for _, key := range keys {
+				r.privatePatterns = append(r.privatePatterns, os.Getenv(key))
+			}
+			return
+		}
+		r.privatePatterns = strings.Split(strings.TrimSpace(string(out)), "\n")
+	})
+	for _, patterns := range r.privatePatterns {
+		if module.MatchPrefixPatterns(patterns, importPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// repoRootForImportPath is the default RepoRootForImportPath. Roots of
+// private import paths are found by fetching their go-import meta tags with
+// credentials from .netrc or a git credential helper, like the go command
+// does. Other paths are looked up with vcs.RepoRootForImportPath.
+func (r *RemoteCache) repoRootForImportPath(importPath string, verbose bool) (*vcs.RepoRoot, error) {
+	// Import paths on well-known hosts like github.com are matched
+	// statically, and paths with a VCS suffix like ".git" are too, so only
+	// fetch meta tags for other private paths.
+	if !r.isPrivate(importPath) {
+		return vcs.RepoRootForImportPath(importPath, verbose)
+	}
+	if root, err := vcs.RepoRootForImportPath(importPath, verbose); err == nil {
+		return root, nil
+	}
+	host, _, _ := strings.Cut(importPath, "/")
+	user, password := credentials(host)
+	return fetchRepoRoot(http.DefaultClient, "https://"+importPath+"?go-get=1", importPath, user, password)
+}
+
+// fetchRepoRoot fetches the go-import meta tags at url, authenticating with
+// user and password if user is not empty, and returns the root of the
+// repository providing importPath.
+func fetchRepoRoot(client *http.Client, url, importPath, user, password string) (*vcs.RepoRoot, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("finding repository for private import path %s: %w", importPath, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("finding repository for private import path %s: %s returned %s; check credentials in .netrc or a git credential helper", importPath, url, resp.Status)
+	}
+	imports, err := parseMetaGoImports(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("finding repository for private import path %s: parsing %s: %w", importPath, url, err)
+	}
+	for _, mi := range imports {
+		if importPath != mi.prefix && !strings.HasPrefix(importPath, mi.prefix+"/") {
+			continue
+		}
+		cmd := vcs.ByCmd(mi.vcs)
+		if cmd == nil {
+			continue
+		}
+		return &vcs.RepoRoot{VCS: cmd, Repo: mi.repo, Root: mi.prefix}, nil
+	}
+	return nil, fmt.Errorf("finding repository for private import path %s: no go-import meta tag found at %s", importPath, url)
+}
+
+// metaImport is the content of a go-import meta tag.
+type metaImport struct {
+	prefix, vcs, repo string
+}
+
+// parseMetaGoImports returns the go-import meta tags in the head of an HTML
+// document, like the go command.
+func parseMetaGoImports(r io.Reader) ([]metaImport, error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "ascii", "utf-8":
+			return input, nil
+		default:
+			return nil, fmt.Errorf("can't decode XML document using charset %q", charset)
+		}
+	}
+	d.Strict = false
+	var imports []metaImport
+	for {
+		t, err := d.RawToken()
+		if err == io.EOF || len(imports) > 0 && err != nil {
+			return imports, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if e, ok := t.(xml.StartElement); ok && strings.EqualFold(e.Name.Local, "body") {
+			return imports, nil
+		}
+		if e, ok := t.(xml.EndElement); ok && strings.EqualFold(e.Name.Local, "head") {
+			return imports, nil
+		}
+		e, ok := t.(xml.StartElement)
+		if !ok || !strings.EqualFold(e.Name.Local, "meta") || metaAttr(e, "name") != "go-import" {
+			continue
+		}
+		if f := strings.Fields(metaAttr(e, "content")); len(f) == 3 {
+			imports = append(imports, metaImport{prefix: f[0], vcs: f[1], repo: f[2]})
+		}
+	}
+}
+
+func metaAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// credentials returns the user name and password for host from the .netrc
+// file or, if it has none, from git's credential helpers. Empty strings are
+// returned if no credentials are found.
+func credentials(host string) (user, password string) {
+	if data, err := os.ReadFile(netrcPath()); err == nil {
+		if user, password, ok := netrcCredentials(string(data), host); ok {
+			return user, password
+		}
+	}
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\n\n")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", ""
+	}
+	s := bufio.NewScanner(strings.NewReader(string(out)))
+	for s.Scan() {
+		key, value, _ := strings.Cut(s.Text(), "=")
+		switch key {
+		case "username":
+			user = value
+		case "password":
+			password = value
+		}
+	}
+	return user, password
+}
+
+// netrcPath returns the path of the .netrc file: $NETRC if it's set, or
+// .netrc (_netrc on Windows) in the home directory.
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(home, name)
+}
+
+// netrcCredentials returns the login and password for host in the contents
+// of a .netrc file. The first "machine" entry for host is used, or the
+// "default" entry if there is none.
+func netrcCredentials(data, host string) (user, password string, ok bool) {
+	for _, e := range parseNetrc(data) {
+		if e.machine == host || e.machine == "" {
+			return e.login, e.password, true
+		}
+	}
+	return "", "", false
+}
+
+// netrcEntry is an entry in a .netrc file. machine is empty for the
+// "default" entry.
+type netrcEntry struct {
+	machine, login, password string
+}
+
+// parseNetrc returns the entries in the contents of a .netrc file, in
+// order. Macro definitions are skipped.
+func parseNetrc(data string) []netrcEntry {
+	var entries []netrcEntry
+	var e *netrcEntry
+	inMacro := false
+	for _, line := range strings.Split(data, "\n") {
+		if inMacro {
+			// A macro definition ends with an empty line.
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		f := strings.Fields(line)
+		for i := 0; i < len(f); i++ {
+			switch f[i] {
+			case "default":
+				entries = append(entries, netrcEntry{})
+				e = &entries[len(entries)-1]
+			case "machine":
+				if i+1 < len(f) {
+					i++
+					entries = append(entries, netrcEntry{machine: f[i]})
+					e = &entries[len(entries)-1]
+				}
+			case "login", "password", "account":
+				if i+1 >= len(f) {
+					continue
+				}
+				i++
+				if e == nil {
+					continue
+				}
+				if f[i-1] == "login" {
+					e.login = f[i]
+				} else if f[i-1] == "password" {
+					e.password = f[i]
+				}
+			case "macdef":
+				inMacro = true
+				i = len(f)
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,111 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNetrcCredentials(t *testing.T) {
+	netrc := `
+machine git.example.com
+  login alice
+  password secret1
+
+macdef init
+machine git.example.com login mallory password evil
+
+machine other.example.com login bob password secret2
+default login anonymous password guest
+`
+	for _, tc := range []struct {
+		host, wantUser, wantPassword string
+		wantOK                       bool
+	}{
+		{host: "git.example.com", wantUser: "alice", wantPassword: "secret1", wantOK: true},
+		{host: "other.example.com", wantUser: "bob", wantPassword: "secret2", wantOK: true},
+		{host: "unknown.example.com", wantUser: "anonymous", wantPassword: "guest", wantOK: true},
+	} {
+		t.Run(tc.host, func(t *testing.T) {
+			user, password, ok := netrcCredentials(netrc, tc.host)
+			if user != tc.wantUser || password != tc.wantPassword || ok != tc.wantOK {
+				t.Errorf("got %q, %q, %v; want %q, %q, %v", user, password, ok, tc.wantUser, tc.wantPassword, tc.wantOK)
+			}
+		})
+	}
+
+	if _, _, ok := netrcCredentials("machine a login b password c\n", "d"); ok {
+		t.Errorf("found credentials for a host without an entry")
+	}
+}
+
+func TestFetchRepoRoot(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "alice" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `<!DOCTYPE html>
+<html>
+<head>
+<meta name="go-import" content="git.example.com/team/other git https://git.example.com/team/other.git">
+<meta name="go-import" content="git.example.com/team/repo git https://git.example.com/team/repo.git">
+</head>
+<body>
+<meta name="go-import" content="git.example.com/team/repo hg https://ignored">
+</body>
+</html>
+`)
+	}))
+	defer srv.Close()
+
+	root, err := fetchRepoRoot(srv.Client(), srv.URL, "git.example.com/team/repo/pkg", "alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Root != "git.example.com/team/repo" || root.Repo != "https://git.example.com/team/repo.git" || root.VCS.Cmd != "git" {
+		t.Errorf("got root %q, repo %q, vcs %q", root.Root, root.Repo, root.VCS.Cmd)
+	}
+
+	_, err = fetchRepoRoot(srv.Client(), srv.URL, "git.example.com/team/repo/pkg", "", "")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("without credentials: got error %v; want 401", err)
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	t.Setenv("GOPRIVATE", "*.corp.example.com,example.com/private")
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GONOSUMDB", "example.com/nosum")
+	t.Setenv("GOFLAGS", "")
+	rc, cleanup := NewRemoteCache(nil)
+	defer cleanup()
+	for path, want := range map[string]bool{
+		"git.corp.example.com/team/repo": true,
+		"example.com/private/repo":       true,
+		"example.com/nosum/pkg":          true,
+		"example.com/public/repo":        false,
+		"github.com/foo/bar":             false,
+	} {
+		if got := rc.isPrivate(path); got != want {
+			t.Errorf("isPrivate(%q) = %v; want %v", path, got, want)
+		}
+	}
+}
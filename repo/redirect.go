@@ -0,0 +1,166 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"golang.org/x/tools/go/vcs"
+)
+
+// Redirect tells RemoteCache where to find repositories for import paths
+// under a prefix, without looking them up over the network. It works like
+// a go-import meta tag that's configured locally, which is useful for
+// vanity domains and corporate mirrors.
+type Redirect struct {
+	// Prefix is an import path prefix. Elements of the prefix may be "*",
+	// which matches any single element. The part of an import path matched
+	// by Prefix is the root of the repository containing it.
+	Prefix string `json:"prefix"`
+
+	// Remote is a template for the repository's remote URL. "{root}" is
+	// replaced with the repository root, and "{1}", "{2}", and so on are
+	// replaced with the elements matched by each "*" in Prefix.
+	Remote string `json:"remote"`
+
+	// VCS is the version control system for the repository. Defaults to "git".
+	VCS string `json:"vcs,omitempty"`
+
+	// RepoName is a template for the name of the repository rule, expanded
+	// like Remote. If it's empty, the name is derived from the root as usual.
+	RepoName string `json:"repo_name,omitempty"`
+}
+
+// LoadRedirects reads redirects from a JSON file with the form:
+//
+//	{
+//	  "redirects": [
+//	    {
+//	      "prefix": "go.example.com/*",
+//	      "remote": "https://git.example.com/mirror/{1}.git",
+//	      "vcs": "git",
+//	      "repo_name": "example_{1}"
+//	    }
+//	  ]
+//	}
+func LoadRedirects(path string) ([]Redirect, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Redirects []Redirect `json:"redirects"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading redirects from %s: %w", path, err)
+	}
+	for _, rd := range file.Redirects {
+		if err := rd.check(); err != nil {
+			return nil, fmt.Errorf("reading redirects from %s: %w", path, err)
+		}
+	}
+	return file.Redirects, nil
+}
+
+func (rd Redirect) check() error {
+	if rd.Prefix == "" || strings.HasPrefix(rd.Prefix, "/") || strings.HasSuffix(rd.Prefix, "/") {
+		return fmt.Errorf("redirect has invalid prefix %q", rd.Prefix)
+	}
+	if rd.Remote == "" {
+		return fmt.Errorf("redirect for %s has no remote", rd.Prefix)
+	}
+	if rd.VCS != "" && vcs.ByCmd(rd.VCS) == nil {
+		return fmt.Errorf("redirect for %s has unknown vcs %q", rd.Prefix, rd.VCS)
+	}
+	return nil
+}
+
+// UseRedirects makes the Root and Remote methods use redirects for import
+// paths they match, instead of looking them up over the network. When
+// several redirects match, the one with the longest prefix is used.
+// UseRedirects must be called before other methods.
+func (r *RemoteCache) UseRedirects(redirects []Redirect) {
+	r.redirects = make([]Redirect, len(redirects))
+	for i, rd := range redirects {
+		if rd.VCS == "" {
+			rd.VCS = "git"
+		}
+		r.redirects[i] = rd
+	}
+}
+
+// redirectMatch is the result of matching an import path against a Redirect.
+type redirectMatch struct {
+	redirect  *Redirect
+	root      string
+	wildcards []string
+}
+
+// matchRedirect returns the redirect with the longest prefix that matches
+// importPath.
+func (r *RemoteCache) matchRedirect(importPath string) (m redirectMatch, ok bool) {
+	elems := strings.Split(importPath, "/")
+	bestLen := 0
+	for i := range r.redirects {
+		rd := &r.redirects[i]
+		prefixElems := strings.Split(rd.Prefix, "/")
+		if len(prefixElems) > len(elems) || len(prefixElems) <= bestLen {
+			continue
+		}
+		var wildcards []string
+		matched := true
+		for j, pe := range prefixElems {
+			if pe == "*" {
+				wildcards = append(wildcards, elems[j])
+			} else if pe != elems[j] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		bestLen = len(prefixElems)
+		m = redirectMatch{
+			redirect:  rd,
+			root:      strings.Join(elems[:len(prefixElems)], "/"),
+			wildcards: wildcards,
+		}
+		ok = true
+	}
+	return m, ok
+}
+
+// expand replaces "{root}" and "{1}", "{2}", ... in a template.
+func (m redirectMatch) expand(template string) string {
+	oldnew := []string{"{root}", m.root}
+	for i, w := range m.wildcards {
+		oldnew = append(oldnew, "{"+strconv.Itoa(i+1)+"}", w)
+	}
+	return strings.NewReplacer(oldnew...).Replace(template)
+}
+
+func (m redirectMatch) name() string {
+	if m.redirect.RepoName == "" {
+		return label.ImportPathToBazelRepoName(m.root)
+	}
+	return m.expand(m.redirect.RepoName)
+}
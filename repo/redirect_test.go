@@ -0,0 +1,152 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRedirects(t *testing.T) {
+	redirects := []Redirect{
+		{
+			Prefix:   "go.corp.example.com/*",
+			Remote:   "https://git.corp.example.com/mirror/{1}.git",
+			RepoName: "corp_{1}",
+		},
+		{
+			Prefix: "go.corp.example.com/special/tools",
+			Remote: "https://hg.corp.example.com/tools",
+			VCS:    "hg",
+		},
+		{
+			Prefix: "vanity.example.com/*/*",
+			Remote: "https://github.com/{1}/{2}",
+		},
+	}
+	for _, tc := range []struct {
+		desc, importPath                        string
+		wantRoot, wantName, wantRemote, wantVCS string
+	}{
+		{
+			desc:       "wildcard",
+			importPath: "go.corp.example.com/lib/sub/pkg",
+			wantRoot:   "go.corp.example.com/lib",
+			wantName:   "corp_lib",
+			wantRemote: "https://git.corp.example.com/mirror/lib.git",
+			wantVCS:    "git",
+		},
+		{
+			desc:       "longest_prefix",
+			importPath: "go.corp.example.com/special/tools/cmd",
+			wantRoot:   "go.corp.example.com/special/tools",
+			wantName:   "com_example_corp_go_special_tools",
+			wantRemote: "https://hg.corp.example.com/tools",
+			wantVCS:    "hg",
+		},
+		{
+			desc:       "multiple_wildcards",
+			importPath: "vanity.example.com/org/repo",
+			wantRoot:   "vanity.example.com/org/repo",
+			wantName:   "com_example_vanity_org_repo",
+			wantRemote: "https://github.com/org/repo",
+			wantVCS:    "git",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			rc := NewStubRemoteCache(nil)
+			rc.UseRedirects(redirects)
+			root, name, err := rc.Root(tc.importPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root != tc.wantRoot || name != tc.wantName {
+				t.Errorf("Root: got %q, %q; want %q, %q", root, name, tc.wantRoot, tc.wantName)
+			}
+			remote, vcs, err := rc.Remote(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if remote != tc.wantRemote || vcs != tc.wantVCS {
+				t.Errorf("Remote: got %q, %q; want %q, %q", remote, vcs, tc.wantRemote, tc.wantVCS)
+			}
+		})
+	}
+
+	// Paths too short to match a redirect are looked up as usual.
+	rc := NewStubRemoteCache(nil)
+	rc.UseRedirects(redirects)
+	if _, _, err := rc.Root("vanity.example.com/org"); err == nil {
+		t.Errorf("Root for unmatched path: got success, want error from stub lookup")
+	}
+}
+
+func TestLoadRedirects(t *testing.T) {
+	for _, tc := range []struct {
+		desc, content string
+		want          []Redirect
+		wantErr       string
+	}{
+		{
+			desc: "valid",
+			content: `{
+  "redirects": [
+    {"prefix": "go.example.com/*", "remote": "https://git.example.com/{1}", "repo_name": "example_{1}"}
+  ]
+}`,
+			want: []Redirect{{Prefix: "go.example.com/*", Remote: "https://git.example.com/{1}", RepoName: "example_{1}"}},
+		},
+		{
+			desc:    "no_remote",
+			content: `{"redirects": [{"prefix": "go.example.com"}]}`,
+			wantErr: "has no remote",
+		},
+		{
+			desc:    "bad_prefix",
+			content: `{"redirects": [{"prefix": "go.example.com/", "remote": "https://example.com"}]}`,
+			wantErr: "invalid prefix",
+		},
+		{
+			desc:    "bad_vcs",
+			content: `{"redirects": [{"prefix": "go.example.com", "remote": "https://example.com", "vcs": "cvs"}]}`,
+			wantErr: "unknown vcs",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "redirects.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadRedirects(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v; want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
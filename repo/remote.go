@@ -46,8 +46,11 @@ import (
 // TODO(jayconrod): this is very Go-centric. It should be moved to language/go.
 // Unfortunately, doing so would break the resolve.Resolver interface.
 type RemoteCache struct {
-	// RepoRootForImportPath is vcs.RepoRootForImportPath by default. It may
-	// be overridden so that tests may avoid accessing the network.
+	// RepoRootForImportPath is vcs.RepoRootForImportPath by default, except
+	// that roots of private import paths (matched by GOPRIVATE, GONOPROXY, or
+	// GONOSUMDB) are found with credentials from .netrc or a git credential
+	// helper. It may be overridden so that tests may avoid accessing the
+	// network.
 	RepoRootForImportPath func(string, bool) (*vcs.RepoRoot, error)
 
 	// HeadCmd returns the latest commit on the default branch in the given
@@ -69,6 +72,11 @@ type RemoteCache struct {
 	tmpOnce sync.Once
 	tmpDir  string
 	tmpErr  error
+
+	privateOnce     sync.Once
+	privatePatterns []string
+
+	redirects []Redirect
 }
 
 // remoteCacheMap is a thread-safe, idempotent cache. It is used to store
@@ -88,6 +96,10 @@ type remoteCacheEntry struct {
 	// It is non-nil for other entries. It is closed when an entry is ready,
 	// i.e., the operation loading the entry completed.
 	ready chan struct{}
+
+	// locked is true for entries that were loaded by ensure or read from a
+	// lock file. These entries are written by Lock.
+	locked bool
 }
 
 type rootValue struct {
@@ -132,14 +144,14 @@ type Repo struct {
 // directory. This will delete them.
 func NewRemoteCache(knownRepos []Repo) (r *RemoteCache, cleanup func() error) {
 	r = &RemoteCache{
-		RepoRootForImportPath: vcs.RepoRootForImportPath,
-		HeadCmd:               defaultHeadCmd,
-		root:                  remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
-		remote:                remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
-		head:                  remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
-		mod:                   remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
-		modVersion:            remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
-	}
+		HeadCmd:    defaultHeadCmd,
+		root:       remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
+		remote:     remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
+		head:       remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
+		mod:        remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
+		modVersion: remoteCacheMap{cache: make(map[string]*remoteCacheEntry)},
+	}
+	r.RepoRootForImportPath = r.repoRootForImportPath
 	r.ModInfo = func(importPath string) (string, error) {
 		return defaultModInfo(r, importPath)
 	}
@@ -301,6 +313,11 @@ func (r *RemoteCache) Root(importPath string) (root, name string, err error) {
 		}
 	}
 
+	// Try redirects configured by the user.
+	if m, ok := r.matchRedirect(importPath); ok {
+		return m.root, m.name(), nil
+	}
+
 	// Try known prefixes.
 	for _, p := range knownPrefixes {
 		if pathtools.HasPrefix(importPath, p.prefix) {
@@ -348,6 +365,9 @@ func (r *RemoteCache) Root(importPath string) (root, name string, err error) {
 // given root import path. This is suitable for creating new repository rules.
 func (r *RemoteCache) Remote(root string) (remote, vcs string, err error) {
 	v, err := r.remote.ensure(root, func() (interface{}, error) {
+		if m, ok := r.matchRedirect(root); ok {
+			return remoteValue{remote: m.expand(m.redirect.Remote), vcs: m.redirect.VCS}, nil
+		}
 		repo, err := r.RepoRootForImportPath(root, false)
 		if err != nil {
 			return nil, err
@@ -587,7 +607,7 @@ func (m *remoteCacheMap) ensure(key string, load func() (interface{}, error)) (i
 	m.mu.Lock()
 	e, ok := m.cache[key]
 	if !ok {
-		e = &remoteCacheEntry{ready: make(chan struct{})}
+		e = &remoteCacheEntry{ready: make(chan struct{}), locked: true}
 		m.cache[key] = e
 		m.mu.Unlock()
 		e.value, e.err = load()
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -281,6 +282,78 @@ go_repo example.com/go`
 	}
 }
 
+func TestListModuleRepositories(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "MODULE.bazel",
+			Content: `
+bazel_dep(name = "gazelle", version = "0.40.0", repo_name = "bazel_gazelle")
+
+go_deps = use_extension("@bazel_gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+go_deps.module(
+    path = "example.com/tagged",
+    sum = "h1:abc=",
+    version = "v1.0.0",
+)
+go_deps.module_override(
+    path = "example.com/renamed",
+    repo_name = "custom_renamed",
+)
+go_deps.gazelle_override(
+    directives = ["gazelle:go_naming_convention import_alias"],
+    path = "example.com/direct",
+)
+use_repo(
+    go_deps,
+    "com_example_direct",
+    "com_example_tagged",
+    "custom_renamed",
+    "not_a_module",
+    aliased = "com_example_indirect",
+)
+
+other = use_extension("//:other.bzl", "other")
+use_repo(other, "com_example_other")
+`,
+		}, {
+			Path: "go.mod",
+			Content: `module example.com/main
+
+go 1.21
+
+require (
+	example.com/direct v1.0.0
+	example.com/indirect v1.0.0 // indirect
+	example.com/renamed v1.0.0
+)
+`,
+		},
+	})
+	defer cleanup()
+
+	repos, err := repo.ListModuleRepositories(filepath.Join(dir, "MODULE.bazel"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reposToString(repos)
+	want := `aliased example.com/indirect
+com_example_direct example.com/direct
+com_example_tagged example.com/tagged
+custom_renamed example.com/renamed`
+	if got != want {
+		t.Errorf("got\n%s\n\nwant:\n%s", got, want)
+	}
+	for _, r := range repos {
+		if r.Name() == "com_example_direct" {
+			want := []string{"gazelle:go_naming_convention import_alias"}
+			if got := r.AttrStrings("build_directives"); !reflect.DeepEqual(got, want) {
+				t.Errorf("build_directives: got %q; want %q", got, want)
+			}
+		}
+	}
+}
+
 func reposToString(repos []*rule.Rule) string {
 	buf := &strings.Builder{}
 	sep := ""
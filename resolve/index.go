@@ -64,6 +64,9 @@ type Resolver interface {
 	// language.GenerateResult.Imports. Resolve generates a "deps" attribute (or
 	// the appropriate language-specific equivalent) for each import according to
 	// language-specific rules and heuristics.
+	//
+	// Resolve is called for one rule at a time, unless the extension
+	// implements github.com/bazel-contrib/bazel-gazelle/v2/resolve.ConcurrentResolver.
 	Resolve(c *config.Config, ix *RuleIndex, rc *repo.RemoteCache, r *rule.Rule, imports interface{}, from label.Label)
 }
 
@@ -173,6 +176,49 @@ func (ix *RuleIndex) Finish() {
 	ix.v2.Finish()
 }
 
+// Reopen lets rules be added to a finished index again. Rules in packages
+// for which drop returns true are removed. AddRule may then be called with
+// the new rules in those packages, and Finish must be called again before
+// the index is used.
+//
+// Deprecated: Use github.com/bazel-contrib/bazel-gazelle/v2/resolve.RuleIndex.Reopen instead.
+func (ix *RuleIndex) Reopen(drop func(pkg string) bool) {
+	ix.v2.Reopen(drop)
+}
+
+// ReportUnresolved logs err, which explains why an import couldn't be
+// resolved, and counts it. Resolvers should call this instead of logging
+// resolution errors themselves.
+//
+// Deprecated: Use github.com/bazel-contrib/bazel-gazelle/v2/resolve.RuleIndex.ReportUnresolved instead.
+func (ix *RuleIndex) ReportUnresolved(err error) {
+	ix.v2.ReportUnresolved(err)
+}
+
+// UnresolvedCount returns the number of imports reported with
+// ReportUnresolved.
+//
+// Deprecated: Use github.com/bazel-contrib/bazel-gazelle/v2/resolve.RuleIndex.UnresolvedCount instead.
+func (ix *RuleIndex) UnresolvedCount() int {
+	return ix.v2.UnresolvedCount()
+}
+
+// UnresolvedErrors returns the errors reported with ReportUnresolved, in the
+// order they were reported.
+//
+// Deprecated: Use github.com/bazel-contrib/bazel-gazelle/v2/resolve.RuleIndex.UnresolvedErrors instead.
+func (ix *RuleIndex) UnresolvedErrors() []error {
+	return ix.v2.UnresolvedErrors()
+}
+
+// IsIndexed returns whether the rule with label l was indexed, meaning other
+// rules may depend on it through one of its imports.
+//
+// Deprecated: Use github.com/bazel-contrib/bazel-gazelle/v2/resolve.RuleIndex.IsIndexed instead.
+func (ix *RuleIndex) IsIndexed(l label.Label) bool {
+	return ix.v2.IsIndexed(l)
+}
+
 // Deprecated: Use github.com/bazel-contrib/bazel-gazelle/v2/resolve.FindResult instead.
 //
 //go:fix inline
@@ -85,6 +85,13 @@ func MergeDict(srcExpr, dstExpr bzl.Expr) (*bzl.DictExpr, error) {
 	return v2.MergeDict(srcExpr, dstExpr)
 }
 
+// SquashedIntoKey is the key of a private attribute SquashRules sets on
+// src to the name of dst.
+//
+// Deprecated: Use github.com/bazel-contrib/bazel-gazelle/v2/rule.SquashedIntoKey instead.
+//go:fix inline
+const SquashedIntoKey = v2.SquashedIntoKey
+
 // SquashRules copies information from src into dst without discarding
 // information in dst. SquashRules detects duplicate elements in lists and
 // dictionaries, but it doesn't sort elements after squashing. If squashing
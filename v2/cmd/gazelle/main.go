@@ -31,11 +31,9 @@ func main() {
 	log.SetPrefix("gazelle: ")
 	log.SetFlags(0) // don't print timestamps
 
-	// TODO(#2279): interpret arguments as paths relative to either
-	// BUILD_WORKSPACE_DIRECTORY or BUILD_WORKING_DIRECTORY, depending on whether
-	// the paths were specified as arguments to the gazelle macro or passed as
-	// arguments to `bazel run`. Currently, we mix them together and interpret
-	// relative to BUILD_WORKSPACE_DIRECTORY.
+	// Arguments are interpreted relative to BUILD_WORKSPACE_DIRECTORY, except
+	// for directories passed as arguments to `bazel run`, which update
+	// interprets relative to BUILD_WORKING_DIRECTORY (#2279).
 	var wd string
 	if wsDir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); wsDir != "" {
 		wd = wsDir
@@ -47,15 +45,22 @@ func main() {
 		}
 	}
 
+	// Ctrl-C cancels ctx. Commands stop what they're doing and return without
+	// leaving partially updated build files. After that, signal handling is
+	// reset, so pressing Ctrl-C again exits immediately.
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
 
 	if err := run(ctx, wd, os.Args[1:]); err != nil {
 		if !errors.Is(err, update.ErrDiff) {
 			fmt.Fprintf(os.Stderr, "gazelle: %v\n", err)
 		}
 		if !errors.Is(err, flag.ErrHelp) {
-			os.Exit(1)
+			os.Exit(update.ExitCode(err))
 		}
 	}
 }
@@ -0,0 +1,153 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// changedFiles describes the directories affected by a list of changed
+// files passed with -changed_files.
+type changedFiles struct {
+	// dirs is the set of slash-separated directories, relative to the
+	// repository root, that contain changed files. Directories of deleted
+	// files are replaced with their closest existing parent.
+	dirs map[string]bool
+
+	// buildDirs is the subset of dirs where a build file changed. Directives
+	// in a build file apply to subdirectories, so subdirectories with build
+	// files are updated, too.
+	buildDirs map[string]bool
+}
+
+// readChangedFiles reads a list of changed files, one per line, from the
+// file at listPath, or from stdin if listPath is "-". Paths in the list may be
+// absolute or relative to the repository root, like the output of
+// "git diff --name-only".
+func readChangedFiles(c *config.Config, listPath string) (*changedFiles, error) {
+	var r io.Reader
+	if listPath == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(listPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	cf := &changedFiles{
+		dirs:      make(map[string]bool),
+		buildDirs: make(map[string]bool),
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		file := strings.TrimSpace(scanner.Text())
+		if file == "" {
+			continue
+		}
+		if filepath.IsAbs(file) {
+			rel, err := filepath.Rel(c.RepoRoot, file)
+			if err != nil || !isDescendingDir(file, c.RepoRoot) {
+				continue
+			}
+			file = rel
+		}
+		file = filepath.ToSlash(filepath.Clean(file))
+		rel := closestExistingDir(c.RepoRoot, path.Dir(file))
+		cf.dirs[rel] = true
+		if rel == path.Dir(file) && slices.Contains(c.ValidBuildFileNames, path.Base(file)) {
+			cf.buildDirs[rel] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// closestExistingDir returns rel, or its closest parent that exists as a
+// directory in repoRoot. rel is a slash-separated path where "." is the
+// repository root. The result uses "" for the repository root.
+func closestExistingDir(repoRoot, rel string) string {
+	for rel != "." && rel != "" {
+		if fi, err := os.Stat(filepath.Join(repoRoot, filepath.FromSlash(rel))); err == nil && fi.IsDir() {
+			return rel
+		}
+		rel = path.Dir(rel)
+	}
+	return ""
+}
+
+// absDirs returns the absolute paths of the directories with changed files.
+func (cf *changedFiles) absDirs(repoRoot string) []string {
+	dirs := make([]string, 0, len(cf.dirs))
+	for rel := range cf.dirs {
+		dirs = append(dirs, filepath.Join(repoRoot, filepath.FromSlash(rel)))
+	}
+	slices.Sort(dirs)
+	return dirs
+}
+
+// affects returns whether the existing build file f in the directory rel
+// should be updated, even though it's not in a directory with changed files:
+// either a build file in a parent directory changed, or a rule in f refers
+// to a package with changed files, so its dependencies may need to be
+// resolved again. Files with a "# gazelle:ignore" directive are never
+// affected.
+func (cf *changedFiles) affects(c *config.Config, rel string, f *rule.File) bool {
+	if f == nil {
+		return false
+	}
+	for _, d := range f.Directives {
+		if d.Key == "ignore" {
+			return false
+		}
+	}
+	for dir := range cf.buildDirs {
+		if dir == "" || rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	for _, r := range f.Rules {
+		for _, key := range r.AttrKeys() {
+			strs := r.AttrStrings(key)
+			if s := r.AttrString(key); s != "" {
+				strs = append(strs, s)
+			}
+			for _, s := range strs {
+				l, err := label.Parse(s)
+				if err != nil || l.Relative {
+					continue
+				}
+				if (l.Repo == "" || l.Repo == c.RepoName) && l.Pkg != rel && cf.dirs[l.Pkg] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/walk"
+)
+
+// PrintConfig prints the effective configuration for a directory to w:
+// general settings, kind mappings, and every directive applied in the
+// directory and its parents, with the file and line it came from. args are
+// flags, as in Run, followed by at most one directory, which defaults to wd.
+//
+// Language-specific settings are not printed directly, but they're
+// determined by the flags in args and the directives that are printed.
+func PrintConfig(ctx context.Context, languages []language.Language, wd string, args []string, w io.Writer) error {
+	rec := &directiveRecorder{directives: make(map[string][]recordedDirective)}
+	cexts := make([]config.Configurer, 0, len(languages)+5)
+	cexts = append(cexts,
+		&config.CommonConfigurer{},
+		&updateConfigurer{},
+		&walk.Configurer{},
+		&resolve.Configurer{})
+	for _, lang := range languages {
+		cexts = append(cexts, lang)
+	}
+	cexts = append(cexts, rec)
+
+	c, err := newFixUpdateConfiguration(wd, nil, args, cexts)
+	if err != nil {
+		return err
+	}
+	uc := getUpdateConfig(c)
+	if len(uc.dirs) != 1 {
+		return fmt.Errorf("expected one directory, got %d", len(uc.dirs))
+	}
+	rel, err := filepath.Rel(c.RepoRoot, uc.dirs[0])
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		rel = ""
+	}
+
+	var dirConfig *config.Config
+	err = walkContext(ctx, c, cexts, uc.dirs, walk.UpdateDirsMode, func(args walk.Walk2FuncArgs) walk.Walk2FuncResult {
+		if args.Rel == rel {
+			dirConfig = args.Config
+		}
+		return walk.Walk2FuncResult{}
+	})
+	if err != nil {
+		return err
+	}
+	if dirConfig == nil {
+		return fmt.Errorf("%s: directory was not visited; it may be excluded or ignored", uc.dirs[0])
+	}
+
+	var directives []recordedDirective
+	for _, prefix := range relPrefixes(rel) {
+		directives = append(directives, rec.directives[prefix]...)
+	}
+	return writeConfig(w, dirConfig, rel, directives)
+}
+
+// relPrefixes returns rel and its parent directories, starting with the
+// repository root, "".
+func relPrefixes(rel string) []string {
+	prefixes := []string{""}
+	if rel == "" {
+		return prefixes
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		prefixes = append(prefixes, strings.Join(parts[:i+1], "/"))
+	}
+	return prefixes
+}
+
+// recordedDirective is a directive applied in a directory, along with a
+// description of where it came from.
+type recordedDirective struct {
+	rule.Directive
+	source string
+}
+
+// directiveRecorder is a Configurer that records the directives applied in
+// each directory. It doesn't change the configuration.
+type directiveRecorder struct {
+	directives map[string][]recordedDirective
+}
+
+var _ config.Configurer = (*directiveRecorder)(nil)
+
+func (*directiveRecorder) RegisterFlags(*flag.FlagSet, string, *config.Config) {}
+
+func (*directiveRecorder) CheckFlags(*flag.FlagSet, *config.Config) error { return nil }
+
+func (*directiveRecorder) KnownDirectives() []string { return nil }
+
+func (r *directiveRecorder) Configure(c *config.Config, rel string, f *rule.File) {
+	if f == nil {
+		return
+	}
+	fromConfigFile := make(map[rule.Directive]bool)
+	if rel == "" {
+		for _, d := range c.ConfigFileDirectives {
+			fromConfigFile[d] = true
+		}
+	}
+	path := f.Path
+	if p, err := filepath.Rel(c.RepoRoot, f.Path); err == nil {
+		path = filepath.ToSlash(p)
+	}
+	for _, d := range f.Directives {
+		var source string
+		if line := f.DirectiveLine(d); line > 0 {
+			source = fmt.Sprintf("%s:%d", path, line)
+		} else if fromConfigFile[d] {
+			source = "configuration file"
+		} else {
+			// Directives from .gazelle files and directive_file don't have
+			// a line in the build file.
+			source = "//" + rel
+		}
+		r.directives[rel] = append(r.directives[rel], recordedDirective{Directive: d, source: source})
+	}
+}
+
+// writeConfig prints a configuration in the format used by PrintConfig.
+func writeConfig(w io.Writer, c *config.Config, rel string, directives []recordedDirective) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "directory: //%s\n", rel)
+	fmt.Fprintf(&b, "repo_root: %s\n", c.RepoRoot)
+	if c.RepoName != "" {
+		fmt.Fprintf(&b, "repo_name: %s\n", c.RepoName)
+	}
+	fmt.Fprintf(&b, "build_file_names: %s\n", strings.Join(c.ValidBuildFileNames, " "))
+	index := "all"
+	if !c.IndexLibraries {
+		index = "none"
+	} else if c.IndexLazy {
+		index = "lazy"
+	}
+	fmt.Fprintf(&b, "index: %s\n", index)
+	langs := "all"
+	if len(c.Langs) > 0 {
+		langs = strings.Join(c.Langs, " ")
+	}
+	for _, lang := range c.DisabledLangs {
+		langs += " -" + lang
+	}
+	fmt.Fprintf(&b, "langs: %s\n", langs)
+	fmt.Fprintf(&b, "strict: %t\n", c.Strict)
+
+	if len(c.KindMap) > 0 {
+		b.WriteString("\nmap_kind:\n")
+		for _, kind := range sortedKeys(c.KindMap) {
+			mk := c.KindMap[kind]
+			fmt.Fprintf(&b, "  %s %s %s\n", kind, mk.KindName, mk.KindLoad)
+		}
+	}
+	if len(c.AliasMap) > 0 {
+		b.WriteString("\nalias_kind:\n")
+		for _, alias := range sortedKeys(c.AliasMap) {
+			fmt.Fprintf(&b, "  %s %s\n", alias, c.AliasMap[alias])
+		}
+	}
+
+	b.WriteString("\ndirectives, from the repository root down:\n")
+	if len(directives) == 0 {
+		b.WriteString("  (none)\n")
+	}
+	for _, d := range directives {
+		fmt.Fprintf(&b, "  %s: # gazelle:%s", d.source, d.Key)
+		if d.Value != "" {
+			fmt.Fprintf(&b, " %s", d.Value)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
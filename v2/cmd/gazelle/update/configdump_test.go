@@ -0,0 +1,82 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
+)
+
+func TestPrintConfig(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:map_kind go_library my_go_library //:my.bzl
+# gazelle:alias_kind my_macro go_library
+`,
+		},
+		{Path: "a/.gazelle", Content: "exclude gen\n"},
+		{
+			Path: "a/b/BUILD.bazel",
+			Content: `filegroup(name = "b")
+
+# gazelle:exclude data
+`,
+		},
+		{Path: "c/BUILD.bazel", Content: "# gazelle:exclude c\n"},
+	})
+	defer cleanup()
+
+	var buf bytes.Buffer
+	args := []string{"-repo_root", dir, "-index=lazy", filepath.Join("a", "b")}
+	if err := PrintConfig(context.Background(), nil, dir, args, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `directory: //a/b
+repo_root: ` + dir + `
+build_file_names: BUILD.bazel BUILD
+index: lazy
+langs: all
+strict: false
+
+map_kind:
+  go_library my_go_library //:my.bzl
+
+alias_kind:
+  my_macro go_library
+
+directives, from the repository root down:
+  BUILD.bazel:1: # gazelle:map_kind go_library my_go_library //:my.bzl
+  BUILD.bazel:2: # gazelle:alias_kind my_macro go_library
+  //a: # gazelle:exclude gen
+  a/b/BUILD.bazel:3: # gazelle:exclude data
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	err := PrintConfig(context.Background(), nil, dir, []string{"-repo_root", dir, "a", "c"}, &buf)
+	if err == nil || !strings.Contains(err.Error(), "expected one directory") {
+		t.Errorf("got error %v; want error about the number of directories", err)
+	}
+}
@@ -0,0 +1,131 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// deleteModeFromName maps emit modes to functions that delete a build file,
+// or describe its deletion, when -delete_empty_build_files is set.
+var deleteModeFromName = map[string]emitFunc{
+	"print": printDeletedFile,
+	"fix":   fixDeletedFile,
+	"diff":  diffDeletedFile,
+	"json":  func(*config.Config, *rule.File) error { return nil },
+	"list":  listDeletedFile,
+}
+
+// isDeletableBuildFile returns whether f is an existing build file that's
+// empty after rules were merged: it has no rules, no directives, and no
+// statements or comments other than loads. Such a file is left in a
+// directory that no longer contains sources after Gazelle deletes the rules
+// it generated there.
+func isDeletableBuildFile(c *config.Config, f *rule.File) bool {
+	if f.File == nil || len(f.Content) == 0 || f.DefName != "" {
+		return false
+	}
+	if len(f.Rules) > 0 || len(f.Directives) > 0 {
+		return false
+	}
+	f.Sync()
+	for _, stmt := range f.File.Stmt {
+		if _, ok := stmt.(*bzl.LoadStmt); !ok {
+			return false
+		}
+	}
+	if _, err := c.Stat(f.Path); err != nil {
+		return false
+	}
+	return true
+}
+
+// deletedLabels returns the labels of rules in the original content of f,
+// which is about to be deleted.
+func deletedLabels(f *rule.File) []label.Label {
+	old, err := rule.LoadData(f.Path, f.Pkg, f.Content)
+	if err != nil {
+		return nil
+	}
+	labels := make([]label.Label, 0, len(old.Rules))
+	for _, r := range old.Rules {
+		labels = append(labels, label.New("", f.Pkg, r.Name()))
+	}
+	return labels
+}
+
+// removeDeletedDeps removes references to deleted rules from the deps
+// attributes of rules in f. Rules, attributes, and entries marked with
+// # keep are not changed.
+func removeDeletedDeps(c *config.Config, f *rule.File, deleted map[label.Label]bool) {
+	for _, r := range f.Rules {
+		list, ok := r.Attr("deps").(*bzl.ListExpr)
+		if !ok || r.ShouldKeep() || rule.ShouldKeep(&bzl.AssignExpr{Comments: *r.AttrComments("deps")}) {
+			continue
+		}
+		kept := make([]bzl.Expr, 0, len(list.List))
+		for _, elem := range list.List {
+			if str, ok := elem.(*bzl.StringExpr); ok && !rule.ShouldKeep(elem) {
+				l, err := label.Parse(str.Value)
+				if err == nil {
+					l = l.Abs("", f.Pkg)
+					if l.Repo == c.RepoName {
+						l.Repo = ""
+					}
+					if deleted[l] {
+						continue
+					}
+				}
+			}
+			kept = append(kept, elem)
+		}
+		if len(kept) == len(list.List) {
+			continue
+		}
+		if len(kept) == 0 {
+			r.DelAttr("deps")
+		} else {
+			list.List = kept
+			r.SetAttr("deps", list)
+		}
+	}
+}
+
+func fixDeletedFile(c *config.Config, f *rule.File) error {
+	if c.WriteBuildFilesDir != "" {
+		// The file was read from somewhere else, so there's nothing to delete
+		// in the output directory.
+		return nil
+	}
+	if err := os.Remove(f.Path); err != nil {
+		return err
+	}
+	if uc := getUpdateConfig(c); uc.print0 {
+		fmt.Fprintf(uc.out, "%s\x00", f.Path)
+	}
+	return nil
+}
+
+func printDeletedFile(c *config.Config, f *rule.File) error {
+	fmt.Fprintf(getUpdateConfig(c).out, ">>> %s (deleted)\n", f.Path)
+	return nil
+}
@@ -0,0 +1,94 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"sort"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+const defaultCondition = "//conditions:default"
+
+// sortGenerated sorts rules generated by a language by kind, then by name,
+// so that the order in which new rules are inserted into build files doesn't
+// depend on the order in which the language happened to generate them, for
+// example by iterating over a map. imports is permuted along with gen.
+// Branches of select expressions in the rules' attributes are sorted too.
+func sortGenerated(gen []*rule.Rule, imports []interface{}) {
+	idx := make([]int, len(gen))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		ri, rj := gen[idx[i]], gen[idx[j]]
+		if ri.Kind() != rj.Kind() {
+			return ri.Kind() < rj.Kind()
+		}
+		return ri.Name() < rj.Name()
+	})
+	sortedGen := make([]*rule.Rule, len(gen))
+	sortedImports := make([]interface{}, len(imports))
+	for i, j := range idx {
+		sortedGen[i] = gen[j]
+		sortedImports[i] = imports[j]
+	}
+	copy(gen, sortedGen)
+	copy(imports, sortedImports)
+
+	for _, r := range gen {
+		for _, key := range r.AttrKeys() {
+			if expr := r.Attr(key); expr != nil {
+				sortSelectBranches(expr)
+			}
+		}
+	}
+}
+
+// sortSelectBranches sorts the branches of select expressions in expr in
+// place by condition, with the default condition last.
+func sortSelectBranches(expr bzl.Expr) {
+	bzl.Walk(expr, func(e bzl.Expr, _ []bzl.Expr) {
+		call, ok := e.(*bzl.CallExpr)
+		if !ok || len(call.List) != 1 {
+			return
+		}
+		if x, ok := call.X.(*bzl.Ident); !ok || x.Name != "select" {
+			return
+		}
+		dict, ok := call.List[0].(*bzl.DictExpr)
+		if !ok {
+			return
+		}
+		sort.SliceStable(dict.List, func(i, j int) bool {
+			ki, kj := branchCondition(dict.List[i]), branchCondition(dict.List[j])
+			if (ki == defaultCondition) != (kj == defaultCondition) {
+				return kj == defaultCondition
+			}
+			return ki < kj
+		})
+	})
+}
+
+// branchCondition returns the condition of a select branch, or "" if it's
+// not a string.
+func branchCondition(kv *bzl.KeyValueExpr) string {
+	if s, ok := kv.Key.(*bzl.StringExpr); ok {
+		return s.Value
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSortGenerated(t *testing.T) {
+	test := rule.NewRule("go_test", "b_test")
+	libB := rule.NewRule("go_library", "b")
+	libA := rule.NewRule("go_library", "a")
+	libA.SetAttr("deps", &bzl.CallExpr{
+		X: &bzl.Ident{Name: "select"},
+		List: []bzl.Expr{&bzl.DictExpr{
+			List: []*bzl.KeyValueExpr{
+				{Key: &bzl.StringExpr{Value: "//conditions:default"}, Value: &bzl.ListExpr{}},
+				{Key: &bzl.StringExpr{Value: "@rules_go//go/platform:linux"}, Value: &bzl.ListExpr{}},
+				{Key: &bzl.StringExpr{Value: "@rules_go//go/platform:darwin"}, Value: &bzl.ListExpr{}},
+			},
+			ForceMultiLine: true,
+		}},
+	})
+	gen := []*rule.Rule{test, libB, libA}
+	imports := []interface{}{"test", "b", "a"}
+
+	sortGenerated(gen, imports)
+
+	var gotNames []string
+	for _, r := range gen {
+		gotNames = append(gotNames, r.Kind()+" "+r.Name())
+	}
+	wantNames := []string{"go_library a", "go_library b", "go_test b_test"}
+	if diff := cmp.Diff(wantNames, gotNames); diff != "" {
+		t.Errorf("rules (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]interface{}{"a", "b", "test"}, imports); diff != "" {
+		t.Errorf("imports (-want,+got):\n%s", diff)
+	}
+
+	got := strings.TrimSpace(bzl.FormatString(libA.Attr("deps")))
+	want := strings.TrimSpace(`
+select({
+    "@rules_go//go/platform:darwin": [],
+    "@rules_go//go/platform:linux": [],
+    "//conditions:default": [],
+})`)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
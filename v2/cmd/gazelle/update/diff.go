@@ -30,6 +30,17 @@ import (
 var ErrDiff = fmt.Errorf("encountered changes while running diff")
 
 func diffFile(c *config.Config, f *rule.File) error {
+	return writeDiff(c, f, f.Format(), false)
+}
+
+// diffDeletedFile prints a diff that deletes f.
+func diffDeletedFile(c *config.Config, f *rule.File) error {
+	return writeDiff(c, f, nil, true)
+}
+
+// writeDiff prints a unified diff from the original content of f to
+// newContent or, if deleted is set, to /dev/null.
+func writeDiff(c *config.Config, f *rule.File, newContent []byte, deleted bool) error {
 	rel, err := filepath.Rel(c.RepoRoot, f.Path)
 	if err != nil {
 		return fmt.Errorf("error getting old path for file %q: %v", f.Path, err)
@@ -41,24 +52,26 @@ func diffFile(c *config.Config, f *rule.File) error {
 	// See https://github.com/bazelbuild/bazel-gazelle/issues/1528.
 	date := "1970-01-01 00:00:00.000000001 +0000"
 
+	uc := getUpdateConfig(c)
 	diff := difflib.UnifiedDiff{
-		Context:  3,
+		Context:  uc.diffContext,
 		FromDate: date,
 		ToDate:   date,
 	}
 
-	newContent := f.Format()
-	if bytes.Equal(newContent, f.Content) {
+	if !deleted && bytes.Equal(newContent, f.Content) {
 		// No change.
 		return nil
 	}
 
-	if _, err := os.Stat(f.Path); os.IsNotExist(err) {
+	existed := true
+	if _, err := c.Stat(f.Path); os.IsNotExist(err) {
 		diff.FromFile = "/dev/null"
+		existed = false
 	} else if err != nil {
 		return fmt.Errorf("error reading original file: %v", err)
 	} else if c.ReadBuildFilesDir == "" {
-		diff.FromFile = rel
+		diff.FromFile = diffPath(uc, rel, f.Path)
 	} else {
 		diff.FromFile = f.Path
 	}
@@ -67,10 +80,14 @@ func diffFile(c *config.Config, f *rule.File) error {
 		diff.A = difflib.SplitLines(string(f.Content))
 	}
 
-	diff.B = difflib.SplitLines(string(newContent))
+	if !deleted {
+		diff.B = difflib.SplitLines(string(newContent))
+	}
 	outPath := findOutputPath(c, f)
-	if c.WriteBuildFilesDir == "" {
-		diff.ToFile = rel
+	if deleted {
+		diff.ToFile = "/dev/null"
+	} else if c.WriteBuildFilesDir == "" {
+		diff.ToFile = diffPath(uc, rel, f.Path)
 	} else {
 		diff.ToFile = outPath
 	}
@@ -94,17 +111,106 @@ func diffFile(c *config.Config, f *rule.File) error {
 		}
 	}
 
-	uc := getUpdateConfig(c)
-	var out io.Writer = os.Stdout
+	out := uc.out
 	if uc.patchPath != "" {
 		out = &uc.patchBuffer
 	}
-	if err := difflib.WriteUnifiedDiff(out, patchDiff); err != nil {
-		return fmt.Errorf("error diffing %s: %v", f.Path, err)
+	if uc.patchPath != "" || uc.diffFormat != "github" {
+		var err error
+		if uc.diffColor {
+			var buf bytes.Buffer
+			if err = difflib.WriteUnifiedDiff(&buf, patchDiff); err == nil {
+				err = writeColorDiff(out, buf.Bytes())
+			}
+		} else {
+			err = difflib.WriteUnifiedDiff(out, patchDiff)
+		}
+		if err != nil {
+			return fmt.Errorf("error diffing %s: %v", f.Path, err)
+		}
+	}
+	if uc.diffFormat == "github" {
+		if err := writeGitHubAnnotations(uc.out, rel, diff.A, diff.B, !existed, deleted); err != nil {
+			return fmt.Errorf("error diffing %s: %v", f.Path, err)
+		}
 	}
 	if ds, _ := difflib.GetUnifiedDiffString(diff); ds != "" {
+		if uc.diffSummaryPath != "" {
+			recordDiffSummary(c, f, rel, newContent, existed, deleted)
+		}
 		return ErrDiff
 	}
 
 	return nil
 }
+
+// diffPath returns the path of a build file to print in a diff header.
+// rel is the slash-separated path relative to the repository root, and
+// path is the absolute path. With -diff_paths=relative, the path is
+// relative to the directory where Gazelle was invoked instead.
+func diffPath(uc *updateConfig, rel, path string) string {
+	if uc.diffPathBase == "" {
+		return rel
+	}
+	p, err := filepath.Rel(uc.diffPathBase, path)
+	if err != nil {
+		return rel
+	}
+	return filepath.ToSlash(p)
+}
+
+// ANSI escape sequences used to color diffs.
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// writeColorDiff copies a unified diff of one file to w, coloring file
+// headers bold, hunk headers cyan, removed lines red, and added lines green,
+// like "git diff --color".
+func writeColorDiff(w io.Writer, diff []byte) error {
+	inHeader := true
+	for _, line := range bytes.SplitAfter(diff, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		text := bytes.TrimSuffix(line, []byte("\n"))
+		color := ""
+		switch {
+		case bytes.HasPrefix(text, []byte("@@")):
+			inHeader = false
+			color = colorCyan
+		case inHeader:
+			color = colorBold
+		case bytes.HasPrefix(text, []byte("-")):
+			color = colorRed
+		case bytes.HasPrefix(text, []byte("+")):
+			color = colorGreen
+		}
+		var err error
+		if color == "" {
+			_, err = w.Write(line)
+		} else {
+			_, err = fmt.Fprintf(w, "%s%s%s%s", color, text, colorReset, line[len(text):])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTerminal returns whether f is a terminal that colors may be printed
+// to. Colors are never printed when the NO_COLOR environment variable is
+// set or TERM is "dumb". See https://no-color.org.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
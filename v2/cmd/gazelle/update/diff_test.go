@@ -0,0 +1,110 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteColorDiff(t *testing.T) {
+	for _, tc := range []struct {
+		desc, diff, want string
+	}{
+		{
+			desc: "empty",
+		},
+		{
+			desc: "modified",
+			diff: `--- a/BUILD.bazel
++++ a/BUILD.bazel
+@@ -1,3 +1,3 @@
+ go_library(
+-    name = "old",
++    name = "new",
+ )
+`,
+			want: "\x1b[1m--- a/BUILD.bazel\x1b[0m\n" +
+				"\x1b[1m+++ a/BUILD.bazel\x1b[0m\n" +
+				"\x1b[36m@@ -1,3 +1,3 @@\x1b[0m\n" +
+				" go_library(\n" +
+				"\x1b[31m-    name = \"old\",\x1b[0m\n" +
+				"\x1b[32m+    name = \"new\",\x1b[0m\n" +
+				" )\n",
+		},
+		{
+			// Removed and added lines that look like file headers are
+			// colored as changes after the first hunk header.
+			desc: "header-like changes",
+			diff: `--- BUILD
++++ BUILD
+@@ -1 +1 @@
+--- x
++++ y`,
+			want: "\x1b[1m--- BUILD\x1b[0m\n" +
+				"\x1b[1m+++ BUILD\x1b[0m\n" +
+				"\x1b[36m@@ -1 +1 @@\x1b[0m\n" +
+				"\x1b[31m--- x\x1b[0m\n" +
+				"\x1b[32m+++ y\x1b[0m",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeColorDiff(&buf, []byte(tc.diff)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("(-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffPath(t *testing.T) {
+	for _, tc := range []struct {
+		desc, base, rel, path, want string
+	}{
+		{
+			desc: "repo",
+			rel:  "a/b/BUILD.bazel",
+			path: "/repo/a/b/BUILD.bazel",
+			want: "a/b/BUILD.bazel",
+		},
+		{
+			desc: "below base",
+			base: "/repo/a",
+			rel:  "a/b/BUILD.bazel",
+			path: "/repo/a/b/BUILD.bazel",
+			want: "b/BUILD.bazel",
+		},
+		{
+			desc: "outside base",
+			base: "/repo/c",
+			rel:  "a/BUILD.bazel",
+			path: "/repo/a/BUILD.bazel",
+			want: "../a/BUILD.bazel",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			uc := &updateConfig{diffPathBase: tc.base}
+			if got := diffPath(uc, tc.rel, tc.path); got != tc.want {
+				t.Errorf("got %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// diffSummary describes the changes printed by -mode=diff. It's written as
+// JSON to the file named by -diff_summary.
+type diffSummary struct {
+	FilesChanged  int `json:"files_changed"`
+	RulesAdded    int `json:"rules_added"`
+	RulesRemoved  int `json:"rules_removed"`
+	RulesModified int `json:"rules_modified"`
+
+	Files []diffSummaryFile `json:"files"`
+}
+
+// diffSummaryFile describes the changes to one build file.
+type diffSummaryFile struct {
+	// Path is the slash-separated path to the file, relative to the
+	// repository root.
+	Path string `json:"path"`
+
+	// Status is "added", "modified", or "deleted".
+	Status string `json:"status"`
+
+	// RulesAdded, RulesRemoved, and RulesModified list the labels of rules
+	// that were added, removed, or changed in the file.
+	RulesAdded    []string `json:"rules_added,omitempty"`
+	RulesRemoved  []string `json:"rules_removed,omitempty"`
+	RulesModified []string `json:"rules_modified,omitempty"`
+}
+
+// recordDiffSummary adds the changes from the original content of f to
+// newContent to the summary. existed indicates whether f exists on disk, and
+// deleted indicates whether it's being deleted.
+func recordDiffSummary(c *config.Config, f *rule.File, rel string, newContent []byte, existed, deleted bool) {
+	uc := getUpdateConfig(c)
+	file := diffSummaryFile{Path: rel, Status: "modified"}
+	if !existed {
+		file.Status = "added"
+	} else if deleted {
+		file.Status = "deleted"
+	}
+
+	oldRules := summaryRules(f, f.Content)
+	var newRules map[string]string
+	if !deleted {
+		newRules = summaryRules(f, newContent)
+	}
+	for name, fp := range newRules {
+		l := label.New(c.RepoName, f.Pkg, name).String()
+		if oldFP, ok := oldRules[name]; !ok {
+			file.RulesAdded = append(file.RulesAdded, l)
+		} else if oldFP != fp {
+			file.RulesModified = append(file.RulesModified, l)
+		}
+	}
+	for name := range oldRules {
+		if _, ok := newRules[name]; !ok {
+			file.RulesRemoved = append(file.RulesRemoved, label.New(c.RepoName, f.Pkg, name).String())
+		}
+	}
+	sort.Strings(file.RulesAdded)
+	sort.Strings(file.RulesRemoved)
+	sort.Strings(file.RulesModified)
+
+	s := &uc.diffSummary
+	s.FilesChanged++
+	s.RulesAdded += len(file.RulesAdded)
+	s.RulesRemoved += len(file.RulesRemoved)
+	s.RulesModified += len(file.RulesModified)
+	s.Files = append(s.Files, file)
+}
+
+// summaryRules parses content as a build file and returns a map from the
+// names of its rules to strings that change when the rules change.
+func summaryRules(f *rule.File, content []byte) map[string]string {
+	rules := make(map[string]string)
+	if len(content) == 0 {
+		return rules
+	}
+	parsed, err := rule.LoadData(f.Path, f.Pkg, content)
+	if err != nil {
+		return rules
+	}
+	for _, r := range parsed.Rules {
+		var b strings.Builder
+		b.WriteString(r.Kind())
+		for _, arg := range r.Args() {
+			b.WriteString(" ")
+			b.WriteString(bzl.FormatString(arg))
+		}
+		keys := r.AttrKeys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			b.WriteString(" ")
+			b.WriteString(key)
+			b.WriteString("=")
+			b.WriteString(bzl.FormatString(r.Attr(key)))
+		}
+		rules[r.Name()] = b.String()
+	}
+	return rules
+}
+
+// writeDiffSummary writes the summary recorded by recordDiffSummary to path
+// as JSON. Files are sorted by path.
+func writeDiffSummary(path string, s diffSummary) error {
+	sort.Slice(s.Files, func(i, j int) bool { return s.Files[i].Path < s.Files[j].Path })
+	if s.Files == nil {
+		s.Files = []diffSummaryFile{}
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o666)
+}
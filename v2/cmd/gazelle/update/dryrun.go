@@ -0,0 +1,161 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// Actions of planned changes in a -dry_run report.
+const (
+	dryRunRename     = "rename"
+	dryRunDeleteRule = "delete_rule"
+	dryRunDeleteFile = "delete_file"
+)
+
+// dryRunChange describes a rename or deletion that Gazelle would make, as
+// printed by -dry_run.
+type dryRunChange struct {
+	// Action is "rename", "delete_rule", or "delete_file".
+	Action string `json:"action"`
+
+	// Path is the slash-separated path to the build file, relative to the
+	// repository root.
+	Path string `json:"path"`
+
+	// Kind is the kind of the renamed or deleted rule. It's empty when a
+	// whole file is deleted.
+	Kind string `json:"kind,omitempty"`
+
+	// Rule is the label of the renamed or deleted rule, before the change.
+	Rule string `json:"rule,omitempty"`
+
+	// To is the label of the rule after a rename.
+	To string `json:"to,omitempty"`
+
+	// Reason explains why the change would be made.
+	Reason string `json:"reason"`
+}
+
+// dryRunReport collects the changes printed by -dry_run. Changes are
+// recorded while directories are visited and files are emitted, which
+// happen one at a time in dry runs, so it's not safe for concurrent use.
+type dryRunReport struct {
+	Changes []dryRunChange `json:"changes"`
+}
+
+// recordFix adds the rules in f that the Fix method of the language named
+// lang renamed or deleted since before was computed with ruleNames.
+func (r *dryRunReport) recordFix(c *config.Config, lang string, f *rule.File, before map[*rule.Rule]string) {
+	renamed := make(fixRenames)
+	renamed.record(f, before)
+	if len(renamed) == 0 {
+		return
+	}
+	byName := make(map[string]*rule.Rule, len(before))
+	for old, name := range before {
+		byName[name] = old
+	}
+	path := repoRelPath(c, f)
+	for from, to := range renamed {
+		old := byName[from.Name]
+		ch := dryRunChange{
+			Path: path,
+			Kind: old.Kind(),
+			Rule: from.String(),
+		}
+		if to == label.NoLabel {
+			ch.Action = dryRunDeleteRule
+			ch.Reason = fmt.Sprintf("deleted by the %s extension's fix", lang)
+		} else {
+			ch.Action = dryRunRename
+			ch.To = to.String()
+			if into, ok := old.PrivateAttr(rule.SquashedIntoKey).(string); ok && into == to.Name {
+				ch.Reason = fmt.Sprintf("merged into %s by the %s extension's fix", to, lang)
+			} else {
+				ch.Reason = fmt.Sprintf("renamed by the %s extension's fix", lang)
+			}
+		}
+		r.Changes = append(r.Changes, ch)
+	}
+}
+
+// recordEmit adds the rules in fixed, the rules of f after Fix, that were
+// deleted because generated rules for missing sources were merged into f.
+// If deleted is true, f itself would be deleted, and that is recorded
+// instead of its rules.
+func (r *dryRunReport) recordEmit(c *config.Config, f *rule.File, fixed []*rule.Rule, deleted bool) {
+	path := repoRelPath(c, f)
+	if deleted {
+		r.Changes = append(r.Changes, dryRunChange{
+			Action: dryRunDeleteFile,
+			Path:   path,
+			Reason: "no rules remain after rules for missing sources were deleted (-delete_empty_build_files)",
+		})
+		return
+	}
+	f.Sync()
+	kept := make(map[*rule.Rule]bool, len(f.Rules))
+	for _, rl := range f.Rules {
+		kept[rl] = true
+	}
+	for _, rl := range fixed {
+		if kept[rl] {
+			continue
+		}
+		r.Changes = append(r.Changes, dryRunChange{
+			Action: dryRunDeleteRule,
+			Path:   path,
+			Kind:   rl.Kind(),
+			Rule:   label.New("", f.Pkg, rl.Name()).String(),
+			Reason: "its sources no longer exist",
+		})
+	}
+}
+
+// repoRelPath returns the slash-separated path to f relative to the
+// repository root.
+func repoRelPath(c *config.Config, f *rule.File) string {
+	if rel, err := filepath.Rel(c.RepoRoot, f.Path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(f.Path)
+}
+
+// writeDryRunReport prints the changes in r as JSON, sorted by path and
+// rule. Changes to the same rule stay in the order they were recorded.
+func writeDryRunReport(w io.Writer, r *dryRunReport) error {
+	sort.SliceStable(r.Changes, func(i, j int) bool {
+		if r.Changes[i].Path != r.Changes[j].Path {
+			return r.Changes[i].Path < r.Changes[j].Path
+		}
+		return r.Changes[i].Rule < r.Changes[j].Rule
+	})
+	if r.Changes == nil {
+		r.Changes = []dryRunChange{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,120 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDryRunReport(t *testing.T) {
+	c := config.New()
+	c.RepoRoot = "/repo"
+	f, err := rule.LoadData(filepath.FromSlash("/repo/lib/BUILD.bazel"), "lib", []byte(`
+go_library(name = "go_default_library")
+
+cgo_library(name = "cgo_default_library")
+
+go_proto_library(name = "legacy_proto")
+
+go_library(name = "gone")
+
+go_test(name = "lib_test")
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := make(map[string]*rule.Rule)
+	for _, r := range f.Rules {
+		rules[r.Name()] = r
+	}
+
+	var report dryRunReport
+	before := ruleNames(f)
+	rules["go_default_library"].SetName("lib")
+	if err := rule.SquashRules(rules["cgo_default_library"], rules["go_default_library"], f.Path); err != nil {
+		t.Fatal(err)
+	}
+	rules["cgo_default_library"].Delete()
+	report.recordFix(c, "go", f, before)
+
+	before = ruleNames(f)
+	rules["legacy_proto"].Delete()
+	report.recordFix(c, "proto", f, before)
+
+	fixed := slices.Clone(f.Rules)
+	rules["gone"].Delete()
+	report.recordEmit(c, f, fixed, false)
+
+	other := rule.EmptyFile(filepath.FromSlash("/repo/other/BUILD.bazel"), "other")
+	report.recordEmit(c, other, nil, true)
+
+	var buf bytes.Buffer
+	if err := writeDryRunReport(&buf, &report); err != nil {
+		t.Fatal(err)
+	}
+	var got dryRunReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := dryRunReport{Changes: []dryRunChange{
+		{
+			Action: dryRunRename,
+			Path:   "lib/BUILD.bazel",
+			Kind:   "cgo_library",
+			Rule:   "//lib:cgo_default_library",
+			To:     "//lib",
+			Reason: "merged into //lib by the go extension's fix",
+		},
+		{
+			Action: dryRunRename,
+			Path:   "lib/BUILD.bazel",
+			Kind:   "go_library",
+			Rule:   "//lib:go_default_library",
+			To:     "//lib",
+			Reason: "renamed by the go extension's fix",
+		},
+		{
+			Action: dryRunDeleteRule,
+			Path:   "lib/BUILD.bazel",
+			Kind:   "go_library",
+			Rule:   "//lib:gone",
+			Reason: "its sources no longer exist",
+		},
+		{
+			Action: dryRunDeleteRule,
+			Path:   "lib/BUILD.bazel",
+			Kind:   "go_proto_library",
+			Rule:   "//lib:legacy_proto",
+			Reason: "deleted by the proto extension's fix",
+		},
+		{
+			Action: dryRunDeleteFile,
+			Path:   "other/BUILD.bazel",
+			Reason: "no rules remain after rules for missing sources were deleted (-delete_empty_build_files)",
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,90 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+)
+
+// Exit codes for the gazelle command, so that scripts can tell what kind of
+// failure happened without parsing error messages. Use ExitCode to get the
+// exit code for an error returned by Run and other commands.
+const (
+	// ExitOK means the command succeeded.
+	ExitOK = 0
+
+	// ExitDiff means Gazelle was run with -mode=diff, and build files would
+	// change. This is the same exit code Gazelle has always used for diffs.
+	ExitDiff = 1
+
+	// ExitConfig means a flag, directive, configuration file, or build file
+	// was invalid, so Gazelle couldn't run as requested. It's also returned
+	// when attributes fail validation and, with -strict, when generated rules
+	// couldn't be merged into build files.
+	ExitConfig = 2
+
+	// ExitUnresolved means Gazelle was run with -strict and some imports
+	// couldn't be resolved. Build files were still updated.
+	ExitUnresolved = 3
+
+	// ExitInternal means Gazelle failed for another reason, like an I/O
+	// error.
+	ExitInternal = 4
+
+	// ExitInterrupted means Gazelle was interrupted, for example with Ctrl-C.
+	ExitInterrupted = 130
+)
+
+// ErrUnresolvedImports is returned by Run when -strict is set and some
+// imports couldn't be resolved.
+var ErrUnresolvedImports = errors.New("some imports could not be resolved")
+
+// configError wraps errors caused by invalid flags, directives, or input
+// files.
+type configError struct {
+	err error
+}
+
+func (e configError) Error() string { return e.err.Error() }
+
+func (e configError) Unwrap() error { return e.err }
+
+// ConfigError wraps err so that ExitCode returns ExitConfig for it. Commands
+// outside this package use it for invalid flags and arguments.
+func ConfigError(err error) error {
+	return configError{err}
+}
+
+// ExitCode returns the exit code the gazelle command should exit with after
+// a command returned err.
+func ExitCode(err error) int {
+	var cerr configError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &cerr):
+		return ExitConfig
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, ErrUnresolvedImports):
+		return ExitUnresolved
+	case errors.Is(err, ErrDiff):
+		return ExitDiff
+	default:
+		return ExitInternal
+	}
+}
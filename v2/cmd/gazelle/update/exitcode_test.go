@@ -0,0 +1,105 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		err  error
+		want int
+	}{
+		{desc: "success", err: nil, want: ExitOK},
+		{desc: "diff", err: ErrDiff, want: ExitDiff},
+		{desc: "config", err: configError{errors.New("bad flag")}, want: ExitConfig},
+		{desc: "wrapped_config", err: fmt.Errorf("loading: %w", configError{errors.New("bad directive")}), want: ExitConfig},
+		{desc: "unresolved", err: fmt.Errorf("%w: 2", ErrUnresolvedImports), want: ExitUnresolved},
+		{desc: "interrupted", err: interrupted(context.Canceled), want: ExitInterrupted},
+		{desc: "internal", err: errors.New("disk full"), want: ExitInternal},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := ExitCode(tc.err); got != tc.want {
+				t.Errorf("got %d; want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWalkErrorExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		desc             string
+		setup            func(t *testing.T, dir string)
+		want, wantStrict int
+	}{
+		{
+			desc: "syntax_error",
+			setup: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "BUILD.bazel"), []byte("((("), 0o666); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:       ExitConfig,
+			wantStrict: ExitConfig,
+		},
+		{
+			// Invalid directives are only logged without -strict.
+			desc: "unknown_directive",
+			setup: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "BUILD.bazel"), []byte("# gazelle:bogus\n"), 0o666); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:       ExitOK,
+			wantStrict: ExitConfig,
+		},
+		{
+			// The build file can't be read. That's not a problem with the
+			// user's configuration.
+			desc: "io_error",
+			setup: func(t *testing.T, dir string) {
+				if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "BUILD.bazel")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:       ExitInternal,
+			wantStrict: ExitInternal,
+		},
+	} {
+		for _, strict := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/strict=%t", tc.desc, strict), func(t *testing.T) {
+				dir := t.TempDir()
+				tc.setup(t, dir)
+				args := []string{"-repo_root", dir, fmt.Sprintf("-strict=%t", strict)}
+				err := Run(context.Background(), nil, dir, args)
+				want := tc.want
+				if strict {
+					want = tc.wantStrict
+				}
+				if got := ExitCode(err); got != want {
+					t.Errorf("got exit code %d (%v); want %d", got, err, want)
+				}
+			})
+		}
+	}
+}
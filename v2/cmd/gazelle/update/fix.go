@@ -38,8 +38,8 @@ func fixFile(c *config.Config, f *rule.File) error {
 		return err
 	}
 	f.Content = newContent
-	if getUpdateConfig(c).print0 {
-		fmt.Printf("%s\x00", outPath)
+	if uc := getUpdateConfig(c); uc.print0 {
+		fmt.Fprintf(uc.out, "%s\x00", outPath)
 	}
 	return nil
 }
@@ -0,0 +1,169 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/merger"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/walk"
+)
+
+// Format reformats existing build files and fixes their load statements
+// without generating or resolving rules. It's meant to be fast enough to run
+// as a pre-commit hook on changed files.
+//
+// wd and args are interpreted the same way as in Run, except that
+// arguments may name build files as well as directories. Arguments that
+// name other files are ignored, so a list of all changed files may be
+// passed, including files that were deleted. Directories are formatted
+// recursively unless -r=false is set. -mode may be used to print or diff
+// changes instead of writing them.
+func Format(ctx context.Context, languages []language.Language, wd string, args []string) error {
+	cexts := make([]config.Configurer, 0, len(languages)+4)
+	cexts = append(cexts,
+		&config.CommonConfigurer{},
+		&updateConfigurer{out: outputFromContext(ctx), skipMissing: true},
+		&walk.Configurer{},
+		&resolve.Configurer{})
+	for _, lang := range languages {
+		cexts = append(cexts, lang)
+	}
+	c, err := newFixUpdateConfiguration(wd, nil, args, cexts)
+	if errors.Is(err, errVersion) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	uc := getUpdateConfig(c)
+	defer func() {
+		if err := uc.profile.Stop(); err != nil {
+			slog.Warn("stopping profiler", "error", err)
+		}
+	}()
+
+	loads := genericLoads
+	for _, lang := range languages {
+		if moduleAwareLang, ok := lang.(language.ModuleAwareLanguage); ok {
+			loads = append(loads, moduleAwareLang.ApparentLoads(c.ModuleToApparentName)...)
+		} else {
+			loads = append(loads, lang.Loads()...)
+		}
+	}
+
+	// Split arguments into directories and files. Only the directories
+	// containing named files are visited, without recursion.
+	recursive := uc.walkMode == walk.VisitAllUpdateSubdirsMode || uc.walkMode == walk.UpdateSubdirsMode
+	fileArgs := make(map[string]bool)
+	dirArgs := make(map[string]bool)
+	var dirs []string
+	for _, path := range uc.dirs {
+		fi, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			dirArgs[path] = true
+			dirs = append(dirs, path)
+		} else {
+			fileArgs[path] = true
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	mode := walk.UpdateDirsMode
+	if recursive && len(dirArgs) > 0 {
+		mode = walk.UpdateSubdirsMode
+	}
+
+	var exit error
+	var emitErrs []error
+	err = walkContext(ctx, c, cexts, dirs, mode, func(args walk.Walk2FuncArgs) walk.Walk2FuncResult {
+		// If Gazelle is interrupted, files that were already formatted are
+		// kept, and no more are written.
+		f := args.File
+		if ctx.Err() != nil || !args.Update || f == nil || !shouldFormat(f.Path, args.Dir, dirArgs, fileArgs, recursive) {
+			return walk.Walk2FuncResult{}
+		}
+		merger.FixLoads(f, applyKindMappings(sortedMappedKinds(args.Config), loads))
+		if err := uc.emit(args.Config, f); err != nil {
+			if err == ErrDiff {
+				exit = err
+			} else {
+				emitErrs = append(emitErrs, fmt.Errorf("%s: %w", f.Path, err))
+			}
+		}
+		return walk.Walk2FuncResult{}
+	})
+	if err != nil {
+		return configError{err}
+	}
+	if err := ctx.Err(); err != nil {
+		return interrupted(err)
+	}
+	if err := writeEmitOutput(uc); err != nil {
+		emitErrs = append(emitErrs, err)
+	}
+	if len(emitErrs) > 0 {
+		return errors.Join(emitErrs...)
+	}
+	return exit
+}
+
+// shouldFormat returns whether the build file at path in dir was named on
+// the command line, or is in a directory that was, or in a subdirectory of
+// one if recursive is set.
+func shouldFormat(path, dir string, dirArgs, fileArgs map[string]bool, recursive bool) bool {
+	if fileArgs[path] || dirArgs[dir] {
+		return true
+	}
+	if !recursive {
+		return false
+	}
+	for d := range dirArgs {
+		if isDescendingDir(dir, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedMappedKinds returns the kind mappings in c, sorted by the kind
+// being mapped, so loads for mapped kinds can be fixed in files where the
+// mapping wasn't just applied.
+func sortedMappedKinds(c *config.Config) []config.MappedKind {
+	mappedKinds := make([]config.MappedKind, 0, len(c.KindMap))
+	for _, mk := range c.KindMap {
+		mappedKinds = append(mappedKinds, mk)
+	}
+	sort.Slice(mappedKinds, func(i, j int) bool {
+		return mappedKinds[i].FromKind < mappedKinds[j].FromKind
+	})
+	return mappedKinds
+}
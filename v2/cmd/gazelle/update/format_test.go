@@ -0,0 +1,85 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+)
+
+func TestFormatMissingFiles(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "a/BUILD.bazel", Content: "proto_library(name='a')\n"},
+	})
+	defer cleanup()
+
+	langs := []language.Language{proto.NewLanguage()}
+	args := []string{
+		"-repo_root", dir,
+		filepath.Join(dir, "a/BUILD.bazel"),
+		filepath.Join(dir, "a/deleted.proto"),
+		filepath.Join(dir, "deleted/BUILD.bazel"),
+	}
+	if err := Format(context.Background(), langs, dir, args); err != nil {
+		t.Fatal(err)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "a/BUILD.bazel",
+		Content: `
+load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+
+proto_library(name = "a")
+`,
+	}})
+
+	// Only deleted files.
+	args = []string{"-repo_root", dir, filepath.Join(dir, "deleted/BUILD.bazel")}
+	if err := Format(context.Background(), langs, dir, args); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormatWriteError(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "a/BUILD.bazel", Content: "proto_library(name='a')\n"},
+	})
+	defer cleanup()
+
+	langs := []language.Language{proto.NewLanguage()}
+	ctx := WithOutput(context.Background(), errWriter{})
+	args := []string{"-repo_root", dir, "-mode=print", filepath.Join(dir, "a")}
+	err := Format(ctx, langs, dir, args)
+	if err == nil {
+		t.Fatal("got nil error; want error writing output")
+	}
+	if got := ExitCode(err); got != ExitInternal {
+		t.Errorf("got exit code %d; want %d", got, ExitInternal)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
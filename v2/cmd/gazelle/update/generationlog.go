@@ -0,0 +1,92 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+)
+
+// generationLog records what Gazelle did in each directory it updated. It's
+// written as JSON to the file named by -generation_log, even if Gazelle
+// fails, so that generation in places where it's hard to rerun Gazelle, like
+// go_repository, can be debugged afterward.
+type generationLog struct {
+	// Error is the error Gazelle failed with, if any.
+	Error string `json:"error,omitempty"`
+
+	Packages []generationLogPackage `json:"packages"`
+
+	// Unresolved lists the errors explaining why imports couldn't be
+	// resolved.
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// generationLogPackage describes one directory Gazelle updated.
+type generationLogPackage struct {
+	// Pkg is the slash-separated path to the directory, relative to the
+	// repository root.
+	Pkg string `json:"pkg"`
+
+	// Directives lists the directives read from the directory's build file,
+	// in "gazelle:key value" form.
+	Directives []string `json:"directives,omitempty"`
+
+	// Rules lists the rules generated in the directory.
+	Rules []generationLogRule `json:"rules,omitempty"`
+}
+
+// generationLogRule identifies a generated rule.
+type generationLogRule struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// recordPackage adds a directory with the build file f, which may be nil,
+// and the rules generated in it to the log.
+func (gl *generationLog) recordPackage(rel string, f *rule.File, gen []*rule.Rule) {
+	p := generationLogPackage{Pkg: rel}
+	if f != nil {
+		for _, d := range f.Directives {
+			p.Directives = append(p.Directives, "gazelle:"+d.Key+" "+d.Value)
+		}
+	}
+	for _, r := range gen {
+		p.Rules = append(p.Rules, generationLogRule{Kind: r.Kind(), Name: r.Name()})
+	}
+	gl.Packages = append(gl.Packages, p)
+}
+
+// write records runErr, which may be nil, and the unresolved import errors,
+// then writes the log to path.
+func (gl *generationLog) write(path string, runErr error, unresolved []error) error {
+	if runErr != nil {
+		gl.Error = runErr.Error()
+	}
+	for _, err := range unresolved {
+		gl.Unresolved = append(gl.Unresolved, err.Error())
+	}
+	if gl.Packages == nil {
+		gl.Packages = []generationLogPackage{}
+	}
+	data, err := json.MarshalIndent(gl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o666)
+}
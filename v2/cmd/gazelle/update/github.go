@@ -0,0 +1,100 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// writeGitHubAnnotations prints a GitHub Actions workflow command for each
+// hunk in the diff from a to b, lines of the build file at path, so that
+// out-of-date build files are annotated on pull requests. Annotations point
+// at lines in a, the file as it is in the repository. If the file doesn't
+// exist or should be deleted, as indicated by created and deleted, one
+// annotation is printed for the whole file.
+//
+// See https://docs.github.com/en/actions/reference/workflow-commands-for-github-actions.
+func writeGitHubAnnotations(w io.Writer, path string, a, b []string, created, deleted bool) error {
+	if created {
+		msg := "This build file is missing. Run Gazelle to create it."
+		return writeGitHubAnnotation(w, path, 1, 1, msg)
+	}
+	if deleted {
+		msg := "This build file is empty and should be deleted. Run Gazelle to delete it."
+		return writeGitHubAnnotation(w, path, 1, 1, msg)
+	}
+
+	m := difflib.NewMatcher(a, b)
+	for _, group := range m.GetGroupedOpCodes(0) {
+		// Without context, groups may still start and end with empty
+		// equal ranges.
+		var changes []difflib.OpCode
+		for _, op := range group {
+			if op.Tag != 'e' {
+				changes = append(changes, op)
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		first, last := changes[0], changes[len(changes)-1]
+		var msg strings.Builder
+		msg.WriteString("This build file is out of date. Run Gazelle to apply this change:\n")
+		for _, op := range changes {
+			if op.Tag == 'd' || op.Tag == 'r' {
+				for _, line := range a[op.I1:op.I2] {
+					msg.WriteString("-" + strings.TrimSuffix(line, "\n") + "\n")
+				}
+			}
+			if op.Tag == 'i' || op.Tag == 'r' {
+				for _, line := range b[op.J1:op.J2] {
+					msg.WriteString("+" + strings.TrimSuffix(line, "\n") + "\n")
+				}
+			}
+		}
+
+		// Annotate the changed lines, or the line before inserted lines.
+		line := max(first.I1, 1)
+		if first.I2 > first.I1 {
+			line = first.I1 + 1
+		}
+		endLine := max(last.I2, line)
+		if err := writeGitHubAnnotation(w, path, line, endLine, strings.TrimSuffix(msg.String(), "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeGitHubAnnotation(w io.Writer, path string, line, endLine int, msg string) error {
+	_, err := fmt.Fprintf(w, "::error file=%s,line=%d,endLine=%d,title=Gazelle::%s\n",
+		escapeGitHubProperty(path), line, endLine, escapeGitHubData(msg))
+	return err
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value in a workflow command.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,94 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	old := `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["a.go", "b.go"],
+    importpath = "example.com/lib",
+)
+`
+	new := `load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "lib",
+    srcs = ["a.go"],
+    importpath = "example.com/lib",
+    visibility = ["//visibility:public"],
+)
+`
+	for _, tc := range []struct {
+		desc             string
+		path             string
+		a, b             string
+		created, deleted bool
+		want             string
+	}{
+		{
+			desc: "modified",
+			path: "lib/BUILD.bazel",
+			a:    old,
+			b:    new,
+			want: `::error file=lib/BUILD.bazel,line=5,endLine=5,title=Gazelle::This build file is out of date. Run Gazelle to apply this change:%0A-    srcs = ["a.go", "b.go"],%0A+    srcs = ["a.go"],
+::error file=lib/BUILD.bazel,line=6,endLine=6,title=Gazelle::This build file is out of date. Run Gazelle to apply this change:%0A+    visibility = ["//visibility:public"],
+`,
+		},
+		{
+			desc:    "created",
+			path:    "a,b:c/BUILD.bazel",
+			b:       new,
+			created: true,
+			want: `::error file=a%2Cb%3Ac/BUILD.bazel,line=1,endLine=1,title=Gazelle::This build file is missing. Run Gazelle to create it.
+`,
+		},
+		{
+			desc:    "deleted",
+			path:    "lib/BUILD.bazel",
+			a:       old,
+			deleted: true,
+			want: `::error file=lib/BUILD.bazel,line=1,endLine=1,title=Gazelle::This build file is empty and should be deleted. Run Gazelle to delete it.
+`,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var a, b []string
+			if tc.a != "" {
+				a = difflib.SplitLines(tc.a)
+			}
+			if tc.b != "" {
+				b = difflib.SplitLines(tc.b)
+			}
+			var buf bytes.Buffer
+			if err := writeGitHubAnnotations(&buf, tc.path, a, b, tc.created, tc.deleted); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("annotations (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
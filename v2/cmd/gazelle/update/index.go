@@ -0,0 +1,205 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"log/slog"
+	"path"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/repo"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/walk"
+)
+
+// Index is an in-memory index of the rules in a repository's existing build
+// files, along with the configuration of each directory. It answers
+// dependency resolution queries without generating rules, for tools like
+// editor integrations that ask many questions about the same repository.
+//
+// An Index should be closed with Close when it's no longer needed.
+type Index struct {
+	c         *config.Config
+	configs   map[string]*config.Config
+	ruleIndex *resolve.RuleIndex
+	languages []language.Language
+	rc        *repo.RemoteCache
+	cleanupRc func() error
+}
+
+// Resolution describes how an import was resolved by Index.Resolve.
+type Resolution struct {
+	// Labels is the list of rules that provide the import. It's empty if
+	// the import couldn't be resolved, and it may have more than one label if
+	// the import is ambiguous.
+	Labels []label.Label
+
+	// Source explains where Labels came from: ResolvedByDirective if a
+	// resolve or resolve_regexp directive applies, ResolvedByIndex if
+	// Labels were found in the index, or ResolvedByLanguage if the language
+	// extension resolved the import on its own, for example, by naming
+	// convention for an external repository. When Source is
+	// ResolvedByLanguage and Labels is empty, the import doesn't need a
+	// dependency. Source is ResolvedSelfImport if only the importing rule
+	// provides the import. Source is empty if the import couldn't be
+	// resolved.
+	Source string
+}
+
+const (
+	ResolvedByDirective = "directive"
+	ResolvedByIndex     = "index"
+	ResolvedByLanguage  = "language"
+	ResolvedSelfImport  = "self"
+)
+
+// LoadIndex reads configuration and indexes rules in all build files in the
+// repository. wd and args are interpreted the same way as in Run, but
+// directory arguments and flags that only affect how files are written
+// are ignored. If ctx has a Session attached with WithSession, the index is
+// kept in it for later runs.
+func LoadIndex(ctx context.Context, languages []language.Language, wd string, args []string) (*Index, error) {
+	cexts := make([]config.Configurer, 0, len(languages)+4)
+	cexts = append(cexts,
+		&config.CommonConfigurer{},
+		&updateConfigurer{},
+		&walk.Configurer{},
+		&resolve.Configurer{})
+	for _, lang := range languages {
+		cexts = append(cexts, lang)
+	}
+	c, err := newFixUpdateConfiguration(wd, nil, args, cexts)
+	if err != nil {
+		return nil, err
+	}
+
+	mrslv := newMetaResolver()
+	exts := make([]interface{}, 0, len(languages))
+	for _, lang := range languages {
+		for kind := range lang.Kinds() {
+			mrslv.AddBuiltin(kind, lang)
+		}
+		exts = append(exts, lang)
+	}
+	ruleIndex := resolve.NewRuleIndex(mrslv.Resolver, exts...)
+
+	err = walkContext(ctx, c, cexts, []string{c.RepoRoot}, walk.VisitAllUpdateSubdirsMode, func(args walk.Walk2FuncArgs) walk.Walk2FuncResult {
+		mrslv.SetConfig(args.Rel, args.Config)
+		if args.File == nil {
+			return walk.Walk2FuncResult{}
+		}
+		unmapKindAttrs(args.Config, args.File)
+		for _, r := range args.File.Rules {
+			ruleIndex.AddRule(args.Config, r, args.File)
+		}
+		return walk.Walk2FuncResult{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	ruleIndex.Finish()
+	if sess := sessionFromContext(ctx); sess != nil {
+		sess.finishRun(c, languages, mrslv, ruleIndex, nil)
+	}
+	return newIndex(c, mrslv.configs, ruleIndex, languages), nil
+}
+
+// newIndex returns an Index for rules in ruleIndex. c is the configuration
+// for the repository root directory, and configs holds the configuration of
+// each visited directory.
+func newIndex(c *config.Config, configs map[string]*config.Config, ruleIndex *resolve.RuleIndex, languages []language.Language) *Index {
+	x := &Index{
+		c:         c,
+		configs:   configs,
+		ruleIndex: ruleIndex,
+		languages: languages,
+	}
+	x.rc, x.cleanupRc = newRemoteCache(getUpdateConfig(c))
+	if err := maybePopulateRemoteCacheFromGoMod(c, x.rc); err != nil {
+		slog.Warn("reading go.mod", "error", err)
+	}
+	return x
+}
+
+// Close releases resources held by the index, like temporary directories
+// used to look up external repositories.
+func (x *Index) Close() error {
+	return x.cleanupRc()
+}
+
+// Config returns the configuration for the directory named by rel, a
+// slash-separated path relative to the repository root. If rel wasn't
+// visited, the configuration of its closest visited parent is returned.
+func (x *Index) Config(rel string) *config.Config {
+	for {
+		if c, ok := x.configs[rel]; ok {
+			return c
+		}
+		if rel == "" {
+			return x.c
+		}
+		rel = path.Dir(rel)
+		if rel == "." {
+			rel = ""
+		}
+	}
+}
+
+// Resolve finds the rules that provide the import imp, written in the
+// language lang (for example, "go"), as seen by the rule from. Resolve
+// checks resolve directives first, then the index, then asks the language
+// extension, if it implements language.ImportResolver. The rule from itself
+// is never returned.
+func (x *Index) Resolve(lang, imp string, from label.Label) (Resolution, error) {
+	c := x.Config(from.Pkg)
+	spec := resolve.ImportSpec{Lang: lang, Imp: imp}
+	if l, ok := resolve.FindRuleWithOverride(c, spec, lang); ok {
+		return Resolution{Labels: []label.Label{l}, Source: ResolvedByDirective}, nil
+	}
+	var res Resolution
+	matches := x.ruleIndex.FindRulesByImportWithConfig(c, spec, lang)
+	for _, r := range matches {
+		if !r.IsSelfImport(from) {
+			res.Labels = append(res.Labels, r.Label)
+		}
+	}
+	if len(res.Labels) > 0 {
+		res.Source = ResolvedByIndex
+		return res, nil
+	} else if len(matches) > 0 {
+		res.Source = ResolvedSelfImport
+		return res, nil
+	}
+	for _, l := range x.languages {
+		ir, ok := l.(language.ImportResolver)
+		if !ok || l.Name() != lang {
+			continue
+		}
+		dep, err := ir.ResolveImport(c, x.ruleIndex, x.rc, imp, from)
+		if err != nil {
+			return Resolution{}, err
+		}
+		res.Source = ResolvedByLanguage
+		if !dep.Equal(label.NoLabel) {
+			res.Labels = []label.Label{dep}
+		}
+		return res, nil
+	}
+	return res, nil
+}
@@ -0,0 +1,134 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// jsonPackage describes the rules in a build file, as printed by
+// -mode=json.
+type jsonPackage struct {
+	// Package is the slash-separated path to the package, relative to the
+	// repository root.
+	Package string `json:"package"`
+
+	// Path is the slash-separated path to the build file, relative to the
+	// repository root.
+	Path string `json:"path"`
+
+	Rules []jsonRule `json:"rules"`
+}
+
+// jsonRule describes a rule, as printed by -mode=json.
+type jsonRule struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Label string `json:"label"`
+
+	// Attrs holds the rule's attributes, except for name. Strings, lists,
+	// dicts, booleans, and integers are converted to their JSON equivalents.
+	// Other expressions, like select calls, are formatted as Starlark strings.
+	Attrs map[string]any `json:"attrs"`
+
+	// Deps lists the labels in the deps attribute, if it's a list of strings.
+	Deps []string `json:"deps,omitempty"`
+}
+
+// jsonFile records the rules in f. They're printed after all files are
+// emitted by writeJSON.
+func jsonFile(c *config.Config, f *rule.File) error {
+	f.Sync()
+	pkg := jsonPackage{Package: f.Pkg, Path: f.Path, Rules: []jsonRule{}}
+	if rel, err := filepath.Rel(c.RepoRoot, f.Path); err == nil {
+		pkg.Path = filepath.ToSlash(rel)
+	}
+	for _, r := range f.Rules {
+		jr := jsonRule{
+			Kind:  r.Kind(),
+			Name:  r.Name(),
+			Label: label.New(c.RepoName, f.Pkg, r.Name()).String(),
+			Attrs: make(map[string]any),
+			Deps:  r.AttrStrings("deps"),
+		}
+		for _, key := range r.AttrKeys() {
+			if key != "name" {
+				jr.Attrs[key] = jsonValue(r.Attr(key))
+			}
+		}
+		pkg.Rules = append(pkg.Rules, jr)
+	}
+	uc := getUpdateConfig(c)
+	uc.jsonPackages = append(uc.jsonPackages, pkg)
+	return nil
+}
+
+// jsonValue converts a Starlark expression to a value that can be encoded
+// as JSON.
+func jsonValue(x bzl.Expr) any {
+	switch x := x.(type) {
+	case *bzl.StringExpr:
+		return x.Value
+	case *bzl.Ident:
+		switch x.Name {
+		case "True":
+			return true
+		case "False":
+			return false
+		case "None":
+			return nil
+		}
+	case *bzl.LiteralExpr:
+		if n, err := strconv.ParseInt(x.Token, 0, 64); err == nil {
+			return n
+		}
+	case *bzl.ListExpr:
+		values := make([]any, len(x.List))
+		for i, elem := range x.List {
+			values[i] = jsonValue(elem)
+		}
+		return values
+	case *bzl.DictExpr:
+		values := make(map[string]any, len(x.List))
+		for _, kv := range x.List {
+			key, ok := kv.Key.(*bzl.StringExpr)
+			if !ok {
+				return bzl.FormatString(x)
+			}
+			values[key.Value] = jsonValue(kv.Value)
+		}
+		return values
+	}
+	return bzl.FormatString(x)
+}
+
+// writeJSON prints the packages recorded by jsonFile as a JSON array.
+func writeJSON(w io.Writer, pkgs []jsonPackage) error {
+	if pkgs == nil {
+		pkgs = []jsonPackage{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pkgs)
+}
@@ -0,0 +1,88 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+func TestJSONFile(t *testing.T) {
+	c := config.New()
+	c.RepoRoot = "/repo"
+	c.Exts[updateName] = &updateConfig{}
+	f, err := rule.LoadData(filepath.FromSlash("/repo/foo/BUILD.bazel"), "foo", []byte(`
+go_library(
+    name = "foo",
+    srcs = ["foo.go"] + select({
+        "@io_bazel_rules_go//go/platform:linux": ["foo_linux.go"],
+        "//conditions:default": [],
+    }),
+    cgo = True,
+    importpath = "example.com/foo",
+    deps = ["//bar"],
+)
+
+go_test(
+    name = "foo_test",
+    size = "small",
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Rules[1].Delete()
+	if err := jsonFile(c, f); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, getUpdateConfig(c).jsonPackages); err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "package": "foo",
+    "path": "foo/BUILD.bazel",
+    "rules": [
+      {
+        "kind": "go_library",
+        "name": "foo",
+        "label": "//foo",
+        "attrs": {
+          "cgo": true,
+          "deps": [
+            "//bar"
+          ],
+          "importpath": "example.com/foo",
+          "srcs": "[\"foo.go\"] + select({\n    \"@io_bazel_rules_go//go/platform:linux\": [\"foo_linux.go\"],\n    \"//conditions:default\": [],\n})"
+        },
+        "deps": [
+          "//bar"
+        ]
+      }
+    ]
+  }
+]
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
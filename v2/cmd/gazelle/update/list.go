@@ -0,0 +1,112 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// listedFile describes a build file that would change, as printed by
+// -mode=list.
+type listedFile struct {
+	// Dir is the slash-separated path to the directory containing the file,
+	// relative to the repository root. It's "." for the root directory.
+	Dir string `json:"dir"`
+
+	// Path is the slash-separated path to the file, relative to the
+	// repository root.
+	Path string `json:"path"`
+
+	// New is true if the file doesn't exist yet.
+	New bool `json:"new"`
+
+	// Deleted is true if the file would be deleted.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// listFile records f if its formatted content differs from what's on disk.
+// Changed files are printed after all files are emitted by writeList.
+func listFile(c *config.Config, f *rule.File) error {
+	if bytes.Equal(f.Format(), f.Content) {
+		return nil
+	}
+	lf := listedFile{Dir: f.Pkg, Path: filepath.ToSlash(f.Path)}
+	if lf.Dir == "" {
+		lf.Dir = "."
+	}
+	if rel, err := filepath.Rel(c.RepoRoot, f.Path); err == nil {
+		lf.Path = filepath.ToSlash(rel)
+	}
+	if _, err := c.Stat(f.Path); os.IsNotExist(err) {
+		lf.New = true
+	}
+	uc := getUpdateConfig(c)
+	uc.listedFiles = append(uc.listedFiles, lf)
+	return nil
+}
+
+// listDeletedFile records f as a file that would be deleted.
+func listDeletedFile(c *config.Config, f *rule.File) error {
+	lf := listedFile{Dir: f.Pkg, Path: filepath.ToSlash(f.Path), Deleted: true}
+	if lf.Dir == "" {
+		lf.Dir = "."
+	}
+	if rel, err := filepath.Rel(c.RepoRoot, f.Path); err == nil {
+		lf.Path = filepath.ToSlash(rel)
+	}
+	uc := getUpdateConfig(c)
+	uc.listedFiles = append(uc.listedFiles, lf)
+	return nil
+}
+
+// writeList prints the files recorded by listFile, sorted by path. In text
+// format, each directory is printed once on its own line. In json format,
+// the files are printed as a JSON array.
+func writeList(w io.Writer, format string, files []listedFile) error {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	if format == "json" {
+		if files == nil {
+			files = []listedFile{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(files)
+	}
+	seen := make(map[string]bool)
+	dirs := make([]string, 0, len(files))
+	for _, lf := range files {
+		if !seen[lf.Dir] {
+			seen[lf.Dir] = true
+			dirs = append(dirs, lf.Dir)
+		}
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if _, err := fmt.Fprintln(w, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
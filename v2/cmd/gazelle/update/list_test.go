@@ -0,0 +1,108 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+func TestListFile(t *testing.T) {
+	dir := t.TempDir()
+	c := config.New()
+	c.RepoRoot = dir
+	c.Exts[updateName] = &updateConfig{}
+
+	unchanged := "go_library(name = \"a\")\n"
+	if err := os.MkdirAll(filepath.Join(dir, "a"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "BUILD.bazel"), []byte(unchanged), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		pkg, content string
+		modify       func(f *rule.File)
+	}{
+		{pkg: "a", content: unchanged},
+		{pkg: "", content: "", modify: func(f *rule.File) {
+			rule.NewRule("go_library", "root").Insert(f)
+		}},
+		{pkg: "c/d", content: "", modify: func(f *rule.File) {
+			rule.NewRule("go_library", "d").Insert(f)
+		}},
+		{pkg: "a", content: unchanged, modify: func(f *rule.File) {
+			f.Rules[0].SetAttr("srcs", []string{"a.go"})
+		}},
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(tc.pkg), "BUILD.bazel")
+		f, err := rule.LoadData(path, tc.pkg, []byte(tc.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.modify != nil {
+			tc.modify(f)
+		}
+		if err := listFile(c, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := getUpdateConfig(c).listedFiles
+
+	var buf bytes.Buffer
+	if err := writeList(&buf, "text", files); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `.
+a
+c/d
+`
+	if got := buf.String(); got != wantText {
+		t.Errorf("text: got:\n%s\nwant:\n%s", got, wantText)
+	}
+
+	buf.Reset()
+	if err := writeList(&buf, "json", files); err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `[
+  {
+    "dir": ".",
+    "path": "BUILD.bazel",
+    "new": true
+  },
+  {
+    "dir": "a",
+    "path": "a/BUILD.bazel",
+    "new": false
+  },
+  {
+    "dir": "c/d",
+    "path": "c/d/BUILD.bazel",
+    "new": true
+  }
+]
+`
+	if got := buf.String(); got != wantJSON {
+		t.Errorf("json: got:\n%s\nwant:\n%s", got, wantJSON)
+	}
+}
@@ -0,0 +1,142 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// warningCount counts messages logged at WARN level or higher since logging
+// was last configured, including messages logged with the log package. It's
+// reported in progress messages, since most warnings are about imports that
+// couldn't be resolved.
+var warningCount atomic.Int64
+
+// configureLogging sets up the default logger for the given -log_format,
+// printing messages at level or higher to w.
+//
+// Gazelle and its extensions log with the log and log/slog packages.
+// Messages logged with the log package have level WARN. With "text", the
+// default, messages are printed as they always have been, prefixed with
+// "gazelle: ", and attributes logged with slog are appended as key=value
+// pairs. With "json", every message is printed as a JSON object with "time",
+// "level", and "msg" fields, plus any attributes.
+func configureLogging(format string, level slog.Level, w io.Writer) error {
+	var h slog.Handler
+	switch format {
+	case "text":
+		h = &textHandler{mu: new(sync.Mutex), w: w, prefix: "gazelle: ", level: level}
+	case "json":
+		h = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	default:
+		return fmt.Errorf("unrecognized log format: %q", format)
+	}
+	warningCount.Store(0)
+	log.SetPrefix("")
+	slog.SetDefault(slog.New(countingHandler{h}))
+	slog.SetLogLoggerLevel(slog.LevelWarn)
+	return nil
+}
+
+// countingHandler counts warnings in warningCount, even if they're not
+// printed, then passes messages on to Handler.
+type countingHandler struct {
+	slog.Handler
+}
+
+func (h countingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h countingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		warningCount.Add(1)
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h countingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return countingHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h countingHandler) WithGroup(name string) slog.Handler {
+	return countingHandler{h.Handler.WithGroup(name)}
+}
+
+// textHandler prints each message on one line: a prefix, the message, then
+// attributes as key=value pairs. Groups are ignored; attributes keep their
+// own keys.
+type textHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	level  slog.Level
+	attrs  []slog.Attr
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	buf.WriteString(h.prefix)
+	buf.WriteString(r.Message)
+	writeAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&buf, " %s=%s", a.Key, quoteLogValue(a.Value.String()))
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	buf.WriteByte('\n')
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hc := *h
+	hc.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)
+	return &hc
+}
+
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// quoteLogValue quotes s if it's empty or contains characters that would
+// make a key=value pair ambiguous.
+func quoteLogValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
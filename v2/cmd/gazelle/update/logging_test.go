@@ -0,0 +1,129 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// saveLogging restores the default loggers when a test finishes.
+func saveLogging(t *testing.T) {
+	defaultLogger := slog.Default()
+	out, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		slog.SetLogLoggerLevel(slog.LevelInfo)
+		log.SetOutput(out)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	})
+}
+
+func TestConfigureLoggingText(t *testing.T) {
+	for _, tc := range []struct {
+		desc         string
+		level        slog.Level
+		want         string
+		wantWarnings int64
+	}{
+		{
+			desc:  "default",
+			level: slog.LevelInfo,
+			want: `gazelle: BUILD.bazel: unknown directive
+gazelle: stopping profiler error="no such file"
+gazelle: failed path=a/BUILD.bazel
+`,
+			wantWarnings: 3,
+		},
+		{
+			desc:  "verbose",
+			level: slog.LevelDebug,
+			want: `gazelle: generated rules dir="" rules=2
+gazelle: BUILD.bazel: unknown directive
+gazelle: stopping profiler error="no such file"
+gazelle: failed path=a/BUILD.bazel
+`,
+			wantWarnings: 3,
+		},
+		{
+			desc:         "quiet",
+			level:        slog.LevelError,
+			want:         "gazelle: failed path=a/BUILD.bazel\n",
+			wantWarnings: 3,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			saveLogging(t)
+			log.SetPrefix("gazelle: ")
+			var buf bytes.Buffer
+			if err := configureLogging("text", tc.level, &buf); err != nil {
+				t.Fatal(err)
+			}
+			slog.Debug("generated rules", "dir", "", "rules", 2)
+			log.Printf("%s: unknown directive", "BUILD.bazel")
+			slog.Warn("stopping profiler", "error", "no such file")
+			slog.Error("failed", "path", "a/BUILD.bazel")
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("log messages (-want +got):\n%s", diff)
+			}
+			if got := warningCount.Load(); got != tc.wantWarnings {
+				t.Errorf("got %d warnings; want %d", got, tc.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestConfigureLoggingJSON(t *testing.T) {
+	saveLogging(t)
+	log.SetPrefix("gazelle: ")
+	var buf bytes.Buffer
+	if err := configureLogging("json", slog.LevelInfo, &buf); err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("%s: unknown directive", "BUILD.bazel")
+	slog.Warn("stopping profiler", "error", "boom")
+	slog.Debug("hidden")
+
+	var got []map[string]string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]string
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		delete(m, "time")
+		got = append(got, m)
+	}
+	want := []map[string]string{
+		{"level": "WARN", "msg": "BUILD.bazel: unknown directive"},
+		{"level": "WARN", "msg": "stopping profiler", "error": "boom"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("log messages (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigureLoggingUnknown(t *testing.T) {
+	if err := configureLogging("xml", slog.LevelInfo, nil); err == nil {
+		t.Error("got success; want error")
+	}
+}
@@ -17,6 +17,7 @@ package update
 
 import (
 	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/merger"
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/repo"
@@ -28,18 +29,15 @@ type metaResolver struct {
 	// builtins provides a map of the language kinds to their resolver.
 	builtins map[string]resolve.Resolver
 
-	// mappedKinds provides a list of replacements used by File.Pkg.
-	mappedKinds map[string][]config.MappedKind
-
-	// aliasedKinds provides a dict of configured wrapper macros for each package
-	aliasedKinds map[string]map[string]string
+	// configs provides the configuration for each package, which holds the
+	// kind mappings and wrapper macros in effect there.
+	configs map[string]*config.Config
 }
 
 func newMetaResolver() *metaResolver {
 	return &metaResolver{
-		builtins:     make(map[string]resolve.Resolver),
-		mappedKinds:  make(map[string][]config.MappedKind),
-		aliasedKinds: make(map[string]map[string]string),
+		builtins: make(map[string]resolve.Resolver),
+		configs:  make(map[string]*config.Config),
 	}
 }
 
@@ -48,44 +46,29 @@ func (mr *metaResolver) AddBuiltin(kindName string, resolver resolve.Resolver) {
 	mr.builtins[kindName] = resolver
 }
 
-// MappedKind records the fact that the given mapping was applied while
-// processing the given package.
-func (mr *metaResolver) MappedKind(pkgRel string, kind config.MappedKind) {
-	mr.mappedKinds[pkgRel] = append(mr.mappedKinds[pkgRel], kind)
-}
-
-// AliasedKinds records the configured wrapper macros for a package
-func (mr *metaResolver) AliasedKinds(pkgRel string, aliasedKinds map[string]string) {
-	// Note: it is somewhat of a hack to store the aliased kinds in the metaResolver
-	// by each package. A more appropriate place for this would be to keep it in the
-	// config.Config struct. However, the config.Config struct is not available at
-	// all of the call sites where the Resolve method is called.
-	//
-	// For example, when the RuleIndex is finalizing and collecting information about
-	// embedded targets, it does this once across the entire index.
-	mr.aliasedKinds[pkgRel] = aliasedKinds
+// SetConfig records the configuration for a package. The configuration is
+// needed to find the underlying kinds of mapped and aliased rules in call
+// sites that don't have it, like the RuleIndex collecting embedded targets
+// across the entire index.
+func (mr *metaResolver) SetConfig(pkgRel string, c *config.Config) {
+	mr.configs[pkgRel] = c
 }
 
-// Resolver returns a resolver for the given rule and package, and a bool
-// indicating whether one was found. Empty string may be passed for pkgRel,
-// which results in consulting the builtin kinds only.
+// Resolver returns a resolver for the given rule and package, or nil if none
+// was found. If no configuration was recorded for pkgRel, only the builtin
+// kinds are consulted.
 func (mr *metaResolver) Resolver(r *rule.Rule, pkgRel string) resolve.Resolver {
 	ruleKind := r.Kind()
-
-	if wrappedKind, ok := mr.aliasedKinds[pkgRel][ruleKind]; ok {
+	if wrappedKind, ok := r.PrivateAttr(merger.WrappedKindKey).(string); ok {
 		ruleKind = wrappedKind
 	}
 
-	// Once we have checked alias kinds, still look through our mapped kinds so that we can handle
-	// an aliased kind that points to a mapped kind:
-	// e.g other_macro should use the go_library resolver here:
+	// Undo alias_kind, wrapper_macro, and map_kind. An aliased kind may point
+	// to a mapped kind: e.g other_macro should use the go_library resolver here:
 	//   # gazelle:map_kind my_go_library go_library //:foo.bzl
 	//   # gazelle:alias_kind other_macro my_go_library
-	for _, mappedKind := range mr.mappedKinds[pkgRel] {
-		if mappedKind.KindName == ruleKind {
-			ruleKind = mappedKind.FromKind
-			break
-		}
+	if c := mr.configs[pkgRel]; c != nil {
+		ruleKind = c.UnderlyingKind(ruleKind)
 	}
 
 	// If the underlying kind is different, we need to apply the inverse map_kind operation so that
@@ -0,0 +1,85 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// workerPool runs functions concurrently, with at most a fixed number
+// running at once. Gazelle uses it to merge, resolve, and write build files
+// in parallel. Errors are reported in the order functions were submitted, so
+// output doesn't depend on scheduling.
+//
+// When the pool's size is 1, as with -jobs=1, functions run synchronously
+// in the calling goroutine, in order.
+type workerPool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// newWorkerPool returns a pool that runs up to jobs functions at once, as set
+// by -jobs. If jobs is 0, the pool runs up to GOMAXPROCS functions at once.
+func newWorkerPool(jobs int) *workerPool {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	return &workerPool{sem: make(chan struct{}, jobs)}
+}
+
+// do runs f in the pool. It blocks until a worker is available.
+func (p *workerPool) do(f func() error) {
+	p.mu.Lock()
+	i := len(p.errs)
+	p.errs = append(p.errs, nil)
+	p.mu.Unlock()
+
+	run := func() {
+		err := f()
+		p.mu.Lock()
+		p.errs[i] = err
+		p.mu.Unlock()
+	}
+	if cap(p.sem) == 1 {
+		run()
+		return
+	}
+	p.wg.Add(1)
+	p.sem <- struct{}{}
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		run()
+	}()
+}
+
+// wait blocks until all functions submitted so far have returned. It returns
+// their errors, joined in the order the functions were submitted.
+func (p *workerPool) wait() error {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := errors.Join(p.errs...)
+	p.errs = nil
+	return err
+}
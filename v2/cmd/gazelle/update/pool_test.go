@@ -0,0 +1,91 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWorkerPoolErrorOrder(t *testing.T) {
+	p := &workerPool{sem: make(chan struct{}, 4)}
+	var n atomic.Int64
+	for i := range 8 {
+		p.do(func() error {
+			// Later functions finish first.
+			time.Sleep(time.Duration(8-i) * time.Millisecond)
+			n.Add(1)
+			if i%3 == 0 {
+				return errors.New(string(rune('a' + i)))
+			}
+			return nil
+		})
+	}
+	err := p.wait()
+	if got := n.Load(); got != 8 {
+		t.Errorf("got %d calls; want 8", got)
+	}
+	if diff := cmp.Diff("a\nd\ng", err.Error()); diff != "" {
+		t.Errorf("errors (-want +got):\n%s", diff)
+	}
+	if err := p.wait(); err != nil {
+		t.Errorf("second wait: got %v; want nil", err)
+	}
+}
+
+func TestWorkerPoolSerial(t *testing.T) {
+	p := &workerPool{sem: make(chan struct{}, 1)}
+	var order []int
+	for i := range 4 {
+		p.do(func() error {
+			order = append(order, i)
+			return nil
+		})
+		if len(order) != i+1 {
+			t.Fatalf("function %d didn't run synchronously", i)
+		}
+	}
+	if err := p.wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewWorkerPoolJobs(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	if got := cap(newWorkerPool(3).sem); got != 3 {
+		t.Errorf("with jobs=3, got size %d; want 3", got)
+	}
+	if got := cap(newWorkerPool(0).sem); got != procs {
+		t.Errorf("with jobs=0, got size %d; want GOMAXPROCS (%d)", got, procs)
+	}
+
+	// -jobs bounds the pool, not the number of CPUs Gazelle may use.
+	dir := t.TempDir()
+	jobs := fmt.Sprintf("-jobs=%d", procs+1)
+	if err := Run(context.Background(), nil, dir, []string{"-repo_root", dir, jobs}); err != nil {
+		t.Fatal(err)
+	}
+	if got := runtime.GOMAXPROCS(0); got != procs {
+		t.Errorf("after %s, GOMAXPROCS is %d; want %d", jobs, got, procs)
+	}
+}
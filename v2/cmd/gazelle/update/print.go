@@ -17,15 +17,15 @@ package update
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
 	"github.com/bazelbuild/bazel-gazelle/config"
 )
 
 func printFile(c *config.Config, f *rule.File) error {
-	fmt.Printf(">>> %s\n", f.Path)
+	out := getUpdateConfig(c).out
+	fmt.Fprintf(out, ">>> %s\n", f.Path)
 	content := f.Format()
-	_, err := os.Stdout.Write(content)
+	_, err := out.Write(content)
 	return err
 }
@@ -0,0 +1,93 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often progress is logged with -progress.
+var progressInterval = 5 * time.Second
+
+// progress counts the work done by Run, so that long runs in large
+// repositories don't appear to hang. When reporting is enabled, progress is
+// logged periodically at INFO level while Run works. Otherwise, a summary is
+// logged at DEBUG level when Run finishes.
+type progress struct {
+	phase     atomic.Value // string
+	dirs      atomic.Int64
+	generated atomic.Int64
+	resolved  atomic.Int64
+
+	report bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// startProgress starts counting work. If report is true, progress is logged
+// every progressInterval until stop is called.
+func startProgress(report bool) *progress {
+	p := &progress{report: report, done: make(chan struct{})}
+	p.phase.Store("walking")
+	if report {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			ticker := time.NewTicker(progressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					p.log(slog.LevelInfo, "progress")
+				case <-p.done:
+					return
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// setPhase records what Run is doing now: "walking" directories and
+// generating rules, "resolving" dependencies, or "writing" build files.
+func (p *progress) setPhase(phase string) {
+	p.phase.Store(phase)
+}
+
+// stop stops periodic reports and logs a summary.
+func (p *progress) stop() {
+	close(p.done)
+	p.wg.Wait()
+	level := slog.LevelDebug
+	if p.report {
+		level = slog.LevelInfo
+	}
+	p.setPhase("done")
+	p.log(level, "finished")
+}
+
+func (p *progress) log(level slog.Level, msg string) {
+	slog.Log(context.Background(), level, msg,
+		"phase", p.phase.Load(),
+		"dirs", p.dirs.Load(),
+		"generated", p.generated.Load(),
+		"resolved", p.resolved.Load(),
+		"warnings", warningCount.Load())
+}
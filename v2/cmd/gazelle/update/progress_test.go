@@ -0,0 +1,69 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	saveLogging(t)
+	defer func(interval time.Duration) { progressInterval = interval }(progressInterval)
+	progressInterval = time.Hour
+
+	for _, tc := range []struct {
+		desc   string
+		report bool
+		level  slog.Level
+		want   string
+	}{
+		{
+			desc:  "summary hidden",
+			level: slog.LevelInfo,
+		},
+		{
+			desc:  "summary verbose",
+			level: slog.LevelDebug,
+			want:  "gazelle: finished phase=done dirs=3 generated=2 resolved=1 warnings=1\n",
+		},
+		{
+			desc:   "report",
+			report: true,
+			level:  slog.LevelInfo,
+			want:   "gazelle: finished phase=done dirs=3 generated=2 resolved=1 warnings=1\n",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := configureLogging("text", tc.level, &buf); err != nil {
+				t.Fatal(err)
+			}
+			p := startProgress(tc.report)
+			p.dirs.Add(3)
+			p.generated.Add(2)
+			p.setPhase("resolving")
+			p.resolved.Add(1)
+			warningCount.Add(1)
+			p.stop()
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
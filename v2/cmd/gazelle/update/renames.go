@@ -0,0 +1,147 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// referenceAttrs are the attributes in which references to rules renamed or
+// deleted by Fix are updated.
+var referenceAttrs = []string{"deps", "embed", "data"}
+
+// fixRenames maps the labels of rules that language Fix methods renamed or
+// deleted to their new labels. Rules that were deleted without being
+// squashed into another rule map to label.NoLabel. Labels have an empty
+// repository name.
+type fixRenames map[label.Label]label.Label
+
+// ruleNames returns the names of the rules in f, to be compared with the
+// rules in f after Fix.
+func ruleNames(f *rule.File) map[*rule.Rule]string {
+	names := make(map[*rule.Rule]string, len(f.Rules))
+	for _, r := range f.Rules {
+		names[r] = r.Name()
+	}
+	return names
+}
+
+// record adds the rules in f that were renamed or deleted since before was
+// computed with ruleNames. A rule isn't considered renamed if another rule
+// now has its old name, unless that rule is an alias of the renamed rule.
+func (fr fixRenames) record(f *rule.File, before map[*rule.Rule]string) {
+	f.Sync()
+	after := make(map[*rule.Rule]bool, len(f.Rules))
+	byName := make(map[string]*rule.Rule, len(f.Rules))
+	for _, r := range f.Rules {
+		after[r] = true
+		byName[r.Name()] = r
+	}
+	for r, oldName := range before {
+		var newName string
+		if after[r] {
+			newName = r.Name()
+			if newName == oldName {
+				continue
+			}
+		} else if into, ok := r.PrivateAttr(rule.SquashedIntoKey).(string); ok && byName[into] != nil {
+			newName = into
+		}
+		if other := byName[oldName]; other != nil && !(other.Kind() == "alias" && newName != "" && other.AttrString("actual") == ":"+newName) {
+			continue
+		}
+		from := label.New("", f.Pkg, oldName)
+		if newName == "" {
+			fr[from] = label.NoLabel
+		} else {
+			fr[from] = label.New("", f.Pkg, newName)
+		}
+	}
+}
+
+// rewriteRenamedReferences updates labels in the deps, embed, and data
+// attributes of rules in f that refer to rules in renames. References to
+// deleted rules are removed. Rules, attributes, and entries marked with
+// # keep are not changed.
+func rewriteRenamedReferences(c *config.Config, f *rule.File, renames fixRenames) {
+	if len(renames) == 0 {
+		return
+	}
+	for _, r := range f.Rules {
+		if r.ShouldKeep() {
+			continue
+		}
+		for _, key := range referenceAttrs {
+			expr := r.Attr(key)
+			if expr == nil || rule.ShouldKeep(&bzl.AssignExpr{Comments: *r.AttrComments(key)}) {
+				continue
+			}
+			changed := false
+			bzl.Walk(expr, func(e bzl.Expr, _ []bzl.Expr) {
+				if list, ok := e.(*bzl.ListExpr); ok && rewriteList(c, f.Pkg, list, renames) {
+					changed = true
+				}
+			})
+			if !changed {
+				continue
+			}
+			if list, ok := expr.(*bzl.ListExpr); ok && len(list.List) == 0 {
+				r.DelAttr(key)
+			} else {
+				r.SetAttr(key, expr)
+			}
+		}
+	}
+}
+
+// rewriteList replaces or removes the strings in list that are labels in
+// renames, relative to the package pkg. It returns whether list changed.
+func rewriteList(c *config.Config, pkg string, list *bzl.ListExpr, renames fixRenames) bool {
+	changed := false
+	seen := make(map[string]bool)
+	kept := list.List[:0]
+	for _, elem := range list.List {
+		str, ok := elem.(*bzl.StringExpr)
+		if !ok || rule.ShouldKeep(elem) {
+			kept = append(kept, elem)
+			continue
+		}
+		if l, err := label.Parse(str.Value); err == nil {
+			l = l.Abs("", pkg)
+			if l.Repo == c.RepoName {
+				l.Repo = ""
+			}
+			if to, ok := renames[l]; ok {
+				changed = true
+				if to == label.NoLabel {
+					continue
+				}
+				str.Value = to.Rel("", pkg).String()
+			}
+		}
+		if seen[str.Value] {
+			changed = true
+			continue
+		}
+		seen[str.Value] = true
+		kept = append(kept, elem)
+	}
+	list.List = kept
+	return changed
+}
@@ -0,0 +1,125 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFixRenames(t *testing.T) {
+	f, err := rule.LoadData("lib/BUILD.bazel", "lib", []byte(`
+go_library(name = "go_default_library")
+
+cgo_library(name = "cgo_default_library")
+
+go_library(name = "renamed_with_alias")
+
+go_proto_library(name = "legacy_proto")
+
+go_test(name = "unchanged_test")
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := ruleNames(f)
+	rules := make(map[string]*rule.Rule)
+	for _, r := range f.Rules {
+		rules[r.Name()] = r
+	}
+	rules["go_default_library"].SetName("lib")
+	if err := rule.SquashRules(rules["cgo_default_library"], rules["go_default_library"], f.Path); err != nil {
+		t.Fatal(err)
+	}
+	rules["cgo_default_library"].Delete()
+	rules["renamed_with_alias"].SetName("new_name")
+	alias := rule.NewRule("alias", "renamed_with_alias")
+	alias.SetAttr("actual", ":new_name")
+	alias.Insert(f)
+	rules["legacy_proto"].Delete()
+
+	renames := make(fixRenames)
+	renames.record(f, before)
+	want := fixRenames{
+		label.New("", "lib", "go_default_library"):  label.New("", "lib", "lib"),
+		label.New("", "lib", "cgo_default_library"): label.New("", "lib", "lib"),
+		label.New("", "lib", "renamed_with_alias"):  label.New("", "lib", "new_name"),
+		label.New("", "lib", "legacy_proto"):        label.NoLabel,
+	}
+	if diff := cmp.Diff(want, renames); diff != "" {
+		t.Errorf("(-want,+got):\n%s", diff)
+	}
+
+	c := config.New()
+	c.RepoName = "repo"
+	other, err := rule.LoadData("other/BUILD.bazel", "other", []byte(`
+go_binary(
+    name = "bin",
+    embed = ["@repo//lib:go_default_library"],
+    deps = [
+        "//lib:cgo_default_library",
+        "//lib:go_default_library",
+        "//lib:legacy_proto",
+        "//other:dep",
+    ] + select({
+        "@io_bazel_rules_go//go/platform:linux": ["//lib:renamed_with_alias"],
+        "//conditions:default": [],
+    }),
+)
+
+filegroup(
+    name = "data",
+    data = ["//lib:legacy_proto"],
+)
+
+filegroup(
+    name = "kept",
+    data = ["//lib:legacy_proto"],  # keep
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewriteRenamedReferences(c, other, renames)
+	wantOther := `
+go_binary(
+    name = "bin",
+    embed = ["//lib"],
+    deps = [
+        "//lib",
+        "//other:dep",
+    ] + select({
+        "@io_bazel_rules_go//go/platform:linux": ["//lib:new_name"],
+        "//conditions:default": [],
+    }),
+)
+
+filegroup(name = "data")
+
+filegroup(
+    name = "kept",
+    data = ["//lib:legacy_proto"],  # keep
+)
+`
+	if diff := cmp.Diff(strings.TrimSpace(wantOther), strings.TrimSpace(string(other.Format()))); diff != "" {
+		t.Errorf("(-want,+got):\n%s", diff)
+	}
+}
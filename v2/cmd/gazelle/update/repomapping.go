@@ -0,0 +1,65 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/buildtools/build"
+)
+
+// applyRepoMapping rewrites labels of external repositories in the
+// attributes of rules in f that were generated as gen, so that they use the
+// apparent names the repositories have in this repository. See
+// Config.ApparentRepoName. Rules, attributes, and values marked with
+// "# keep" are not changed.
+func applyRepoMapping(c *config.Config, f *rule.File, gen []*rule.Rule) {
+	genNames := make(map[string]bool, len(gen))
+	for _, r := range gen {
+		genNames[r.Name()] = true
+	}
+	for _, r := range f.Rules {
+		if !genNames[r.Name()] || r.ShouldKeep() {
+			continue
+		}
+		for _, key := range r.AttrKeys() {
+			if key == "name" || rule.ShouldKeep(&build.AssignExpr{Comments: *r.AttrComments(key)}) {
+				continue
+			}
+			build.Walk(r.Attr(key), func(e build.Expr, stk []build.Expr) {
+				str, ok := e.(*build.StringExpr)
+				if !ok || !strings.HasPrefix(str.Value, "@") || rule.ShouldKeep(str) {
+					return
+				}
+				for _, parent := range stk {
+					if rule.ShouldKeep(parent) {
+						return
+					}
+				}
+				l, err := label.Parse(str.Value)
+				if err != nil {
+					return
+				}
+				if apparent := c.ApparentLabel(l); !apparent.Equal(l) || apparent.Canonical != l.Canonical {
+					str.Value = apparent.String()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/buildtools/build"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyRepoMapping(t *testing.T) {
+	c := config.New()
+	c.ModuleToApparentName = func(module string) string {
+		return map[string]string{"rules_go": "my_rules_go"}[module]
+	}
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+go_library(
+    name = "lib",
+    deps = [
+        "//other",
+        "@io_bazel_rules_go//proto/wkt:any_go_proto",
+        "@rules_go//go/runfiles",  # keep
+        "@org_golang_x_sys//unix",
+    ] + select({
+        "@io_bazel_rules_go//go/platform:linux": ["@@rules_go+//go/tools/bzltestutil"],
+        "//conditions:default": [],
+    }),
+    embed = ["@io_bazel_rules_go//:embedded"],  # keep
+)
+
+go_test(
+    name = "handwritten_test",
+    deps = ["@io_bazel_rules_go//go/tools/bazel"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyRepoMapping(c, f, []*rule.Rule{rule.NewRule("go_library", "lib")})
+	f.Sync()
+	want := `
+go_library(
+    name = "lib",
+    embed = ["@io_bazel_rules_go//:embedded"],  # keep
+    deps = [
+        "//other",
+        "@my_rules_go//proto/wkt:any_go_proto",
+        "@org_golang_x_sys//unix",
+        "@rules_go//go/runfiles",  # keep
+    ] + select({
+        "@my_rules_go//go/platform:linux": ["@my_rules_go//go/tools/bzltestutil"],
+        "//conditions:default": [],
+    }),
+)
+
+go_test(
+    name = "handwritten_test",
+    deps = ["@io_bazel_rules_go//go/tools/bazel"],
+)
+`
+	got := string(build.Format(f.File))
+	if diff := cmp.Diff(strings.TrimSpace(want), strings.TrimSpace(got)); diff != "" {
+		t.Errorf("(-want,+got):\n%s", diff)
+	}
+}
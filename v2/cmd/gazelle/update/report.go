@@ -0,0 +1,259 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// Report collects the outcome of Run for programs that embed Gazelle instead
+// of running it as a command. Attach a Report to the context passed to Run
+// with WithReport. Most programs should use the runner package, which wraps
+// this in a simpler API.
+type Report struct {
+	// Logger, if set, receives Gazelle's log messages instead of standard
+	// error. -log_format is ignored, but -v and -q still set the level of
+	// messages Gazelle logs.
+	Logger *slog.Logger
+
+	// DryRun prevents Run from writing or printing anything. Files lists the
+	// changes that would have been made. -mode is ignored.
+	DryRun bool
+
+	// Files lists build files Run changed, or would change with DryRun,
+	// in no particular order. It's filled in by Run.
+	Files []ReportFile
+
+	// Diagnostics lists warnings and errors Run logged, in the order they
+	// were logged. It's filled in by Run.
+	Diagnostics []ReportDiagnostic
+
+	// Languages records the time each language spent configuring
+	// directories, generating rules, and resolving dependencies, and the
+	// number of rules it generated and resolved, in the order languages were
+	// passed to Run. It's filled in by Run.
+	Languages []LanguageMetrics
+
+	mu sync.Mutex
+}
+
+// ReportFile describes a build file changed by Run.
+type ReportFile struct {
+	// Path is the absolute path to the file.
+	Path string
+
+	// Created is true if the file didn't exist before.
+	Created bool
+
+	// Deleted is true if the file was deleted with -delete_empty_build_files.
+	Deleted bool
+
+	// Old and New are the file's content before and after Run. Old is nil
+	// for created files, and New is nil for deleted files.
+	Old, New []byte
+}
+
+// ReportDiagnostic is a warning or error logged by Run.
+type ReportDiagnostic struct {
+	Level   slog.Level
+	Message string
+
+	// Path is the value of the message's "path" attribute, usually the file
+	// or directory the message is about. It's empty if there isn't one.
+	Path string
+}
+
+type reportKey struct{}
+
+// WithReport returns a copy of ctx with r attached. When Run is called with
+// the returned context, it records what it does in r.
+func WithReport(ctx context.Context, r *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, r)
+}
+
+func reportFromContext(ctx context.Context) *Report {
+	r, _ := ctx.Value(reportKey{}).(*Report)
+	return r
+}
+
+type fsKey struct{}
+
+// WithFS returns a copy of ctx with fsys attached. When Run is called with
+// the returned context, it reads the repository from fsys instead of the OS
+// file system. fsys is rooted at the repository root, which is the working
+// directory passed to Run unless -repo_root is set. See config.Config.FS.
+func WithFS(ctx context.Context, fsys fs.FS) context.Context {
+	return context.WithValue(ctx, fsKey{}, fsys)
+}
+
+func fsFromContext(ctx context.Context) fs.FS {
+	fsys, _ := ctx.Value(fsKey{}).(fs.FS)
+	return fsys
+}
+
+type outputKey struct{}
+
+// WithOutput returns a copy of ctx with w attached. When Run is called with
+// the returned context, it writes output for modes like -mode=print and
+// -mode=diff to w instead of os.Stdout.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+func outputFromContext(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(outputKey{}).(io.Writer)
+	return w
+}
+
+// captureLogs replaces the default logger configured by configureLogging so
+// that warnings and errors are recorded as diagnostics. Messages are passed on
+// to r.Logger if it's set, or the configured logger otherwise.
+func (r *Report) captureLogs(level slog.Level) {
+	if r.Logger == nil {
+		slog.SetDefault(slog.New(&reportHandler{Handler: slog.Default().Handler(), report: r}))
+		return
+	}
+	h := &reportHandler{Handler: levelHandler{r.Logger.Handler(), level}, report: r}
+	slog.SetDefault(slog.New(countingHandler{h}))
+}
+
+// wrapEmit replaces the emit functions in uc with functions that record
+// changed files. With DryRun, nothing is written or printed.
+func (r *Report) wrapEmit(uc *updateConfig) {
+	emit, emitDeleted := uc.emit, uc.emitDeleted
+	if r.DryRun {
+		emit = func(*config.Config, *rule.File) error { return nil }
+		emitDeleted = emit
+		uc.concurrentEmit = true
+		uc.patchPath = ""
+		uc.diffSummaryPath = ""
+		uc.jsonOutput = false
+		uc.listOutput = false
+		uc.saveState = false
+	}
+	uc.emit = func(c *config.Config, f *rule.File) error {
+		newContent := f.Format()
+		if bytes.Equal(f.Content, newContent) {
+			return emit(c, f)
+		}
+		rf := ReportFile{Path: findOutputPath(c, f), New: newContent}
+		if _, err := c.Stat(rf.Path); os.IsNotExist(err) {
+			rf.Created = true
+		} else {
+			rf.Old = f.Content
+		}
+		err := emit(c, f)
+		if err == nil || err == ErrDiff {
+			r.addFile(rf)
+		}
+		return err
+	}
+	uc.emitDeleted = func(c *config.Config, f *rule.File) error {
+		err := emitDeleted(c, f)
+		if err == nil || err == ErrDiff {
+			r.addFile(ReportFile{Path: filepath.Clean(f.Path), Deleted: true, Old: f.Content})
+		}
+		return err
+	}
+}
+
+func (r *Report) addFile(f ReportFile) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Files = append(r.Files, f)
+}
+
+func (r *Report) addDiagnostic(d ReportDiagnostic) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Diagnostics = append(r.Diagnostics, d)
+}
+
+// reportHandler records warnings and errors in a Report, then passes
+// messages on to Handler.
+type reportHandler struct {
+	slog.Handler
+	report *Report
+	path   string
+}
+
+func (h *reportHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h *reportHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		d := ReportDiagnostic{Level: r.Level, Message: r.Message, Path: h.path}
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == "path" {
+				d.Path = a.Value.String()
+				return false
+			}
+			return true
+		})
+		h.report.addDiagnostic(d)
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *reportHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hc := *h
+	hc.Handler = h.Handler.WithAttrs(attrs)
+	for _, a := range attrs {
+		if a.Key == "path" {
+			hc.path = a.Value.String()
+		}
+	}
+	return &hc
+}
+
+func (h *reportHandler) WithGroup(name string) slog.Handler {
+	hc := *h
+	hc.Handler = h.Handler.WithGroup(name)
+	return &hc
+}
+
+// levelHandler drops messages below level, so -v and -q apply to a logger
+// provided by an embedding program.
+type levelHandler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{h.Handler.WithAttrs(attrs), h.level}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{h.Handler.WithGroup(name), h.level}
+}
@@ -0,0 +1,119 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+)
+
+// Session holds state that Run keeps in memory between runs in one process,
+// as in "gazelle watch" and "gazelle server". The first run with a Session
+// indexes libraries in the whole repository as usual. LoadIndex may also be
+// called with a Session to do this. Later runs reuse that index and the
+// configuration of each directory, visit only the directories they update,
+// and index those directories again, so dependencies are resolved the same
+// way as in a run over the whole repository.
+//
+// Only runs that index all libraries (the default, -index=all) are kept.
+// After a run fails, the next run indexes the whole repository again.
+//
+// A Session may only be used by one Run at a time.
+type Session struct {
+	repoRoot  string
+	c         *config.Config
+	languages []language.Language
+	mrslv     *metaResolver
+	ruleIndex *resolve.RuleIndex
+
+	// stale is the set of packages, by slash-separated path relative to
+	// repoRoot, whose rules are dropped from the index in the next run.
+	stale map[string]bool
+}
+
+// NewSession returns an empty Session. Attach it to the context passed to
+// Run with WithSession.
+func NewSession() *Session {
+	return &Session{stale: make(map[string]bool)}
+}
+
+type sessionKey struct{}
+
+// WithSession returns a copy of ctx with s attached. Runs with the returned
+// context share the state in s.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+func sessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// Invalidate records that files in dir, an absolute path, changed or that
+// dir was deleted. Rules in dir are dropped from the index at the start of
+// the next run, which should update dir if it still exists.
+func (s *Session) Invalidate(dir string) {
+	if s.repoRoot == "" {
+		return
+	}
+	rel, err := filepath.Rel(s.repoRoot, dir)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		rel = ""
+	}
+	s.stale[rel] = true
+}
+
+// Index returns an Index backed by the state of the last run, or nil if no
+// run has indexed the repository yet. The Index must be closed, and it must
+// not be used after the next run with s starts.
+func (s *Session) Index() *Index {
+	if s.ruleIndex == nil {
+		return nil
+	}
+	return newIndex(s.c, s.mrslv.configs, s.ruleIndex, s.languages)
+}
+
+// warm returns whether a run with the configuration c can reuse the index
+// from an earlier run.
+func (s *Session) warm(c *config.Config) bool {
+	return s != nil && s.ruleIndex != nil && s.repoRoot == c.RepoRoot && c.IndexLibraries && !c.IndexLazy
+}
+
+// finishRun records the state of a run that ended with err, so that the next
+// run can reuse it. The state is discarded if the run failed or didn't index
+// all libraries.
+func (s *Session) finishRun(c *config.Config, languages []language.Language, mrslv *metaResolver, ruleIndex *resolve.RuleIndex, err error) {
+	s.stale = make(map[string]bool)
+	if (err != nil && !errors.Is(err, ErrDiff)) || !c.IndexLibraries || c.IndexLazy {
+		s.repoRoot, s.c, s.languages, s.mrslv, s.ruleIndex = "", nil, nil, nil, nil
+		return
+	}
+	s.repoRoot = c.RepoRoot
+	s.c = c
+	s.languages = languages
+	s.mrslv = mrslv
+	s.ruleIndex = ruleIndex
+}
@@ -0,0 +1,98 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+)
+
+func TestSession(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "b/BUILD.bazel",
+			Content: `proto_library(
+    name = "custom",
+    srcs = ["b.proto"],
+)
+`,
+		},
+		{Path: "b/b.proto", Content: `syntax = "proto3";`},
+	})
+	defer cleanup()
+
+	sess := NewSession()
+	ctx := WithSession(context.Background(), sess)
+	langs := []language.Language{proto.NewLanguage()}
+	if err := Run(ctx, langs, dir, []string{"-repo_root", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later run only visits d and resolves its imports with the index from
+	// the first run. b isn't read again until it's invalidated.
+	renamed := `proto_library(
+    name = "renamed",
+    srcs = ["b.proto"],
+)
+`
+	if err := os.WriteFile(filepath.Join(dir, "b/BUILD.bazel"), []byte(renamed), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "d"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	dProto := `syntax = "proto3";
+import "b/b.proto";
+`
+	if err := os.WriteFile(filepath.Join(dir, "d/d.proto"), []byte(dProto), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(ctx, langs, dir, []string{"-repo_root", dir, "-r=false", filepath.Join(dir, "d")}); err != nil {
+		t.Fatal(err)
+	}
+	checkDeps(t, dir, "//b:custom")
+
+	// Rules in invalidated directories are replaced.
+	sess.Invalidate(filepath.Join(dir, "b"))
+	if err := Run(ctx, langs, dir, []string{"-repo_root", dir, "-r=false", filepath.Join(dir, "b"), filepath.Join(dir, "d")}); err != nil {
+		t.Fatal(err)
+	}
+	checkDeps(t, dir, "//b:renamed")
+}
+
+func checkDeps(t *testing.T, dir, dep string) {
+	t.Helper()
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "d/BUILD.bazel",
+		Content: `
+load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+
+proto_library(
+    name = "d_proto",
+    srcs = ["d.proto"],
+    visibility = ["//visibility:public"],
+    deps = ["` + dep + `"],
+)
+`,
+	}})
+}
@@ -0,0 +1,156 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"log/slog"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// staleKeep describes a list entry marked with # keep that Gazelle no longer
+// generates and that nothing in the repository justifies anymore.
+type staleKeep struct {
+	rule, attr, value, reason string
+}
+
+// findStaleKeeps compares the srcs and deps of the rules in f with the
+// resolved rules generated for the same package, and returns entries kept
+// with # keep comments that are stale. An entry is stale if the generated
+// rule doesn't contain it and either
+//
+//   - it's a source file that no longer exists,
+//   - it's a dependency on a rule in the repository that's in the index, so
+//     Gazelle would have generated the dependency if any source still
+//     imported it, or
+//   - it's a dependency on a package in the repository that no longer exists.
+//
+// Dependencies on other repositories and rules Gazelle doesn't index are
+// never reported, since Gazelle can't tell whether they're still needed.
+// isIndexed reports whether a rule in the main repository is in the index.
+func findStaleKeeps(c *config.Config, f *rule.File, gen []*rule.Rule, isIndexed func(label.Label) bool) []staleKeep {
+	if f == nil {
+		return nil
+	}
+	genByName := make(map[string]*rule.Rule, len(gen))
+	for _, r := range gen {
+		genByName[r.Name()] = r
+	}
+	var stale []staleKeep
+	for _, r := range f.Rules {
+		g := genByName[r.Name()]
+		if g == nil || g.Kind() != r.Kind() || r.ShouldKeep() {
+			continue
+		}
+		for _, key := range []string{"srcs", "deps"} {
+			list, ok := r.Attr(key).(*bzl.ListExpr)
+			if !ok {
+				continue
+			}
+			attrKept := rule.ShouldKeep(&bzl.AssignExpr{Comments: *r.AttrComments(key)})
+			generated := make(map[string]bool)
+			for _, v := range g.AttrStrings(key) {
+				generated[normalizeKeepEntry(c, f.Pkg, key, v)] = true
+			}
+			for _, elem := range list.List {
+				str, ok := elem.(*bzl.StringExpr)
+				if !ok || !(attrKept || rule.ShouldKeep(elem)) {
+					continue
+				}
+				if generated[normalizeKeepEntry(c, f.Pkg, key, str.Value)] {
+					continue
+				}
+				var reason string
+				if key == "srcs" {
+					reason = staleSrcReason(c, f.Pkg, str.Value)
+				} else {
+					reason = staleDepReason(c, f.Pkg, str.Value, isIndexed)
+				}
+				if reason != "" {
+					stale = append(stale, staleKeep{rule: r.Name(), attr: key, value: str.Value, reason: reason})
+				}
+			}
+		}
+	}
+	return stale
+}
+
+// normalizeKeepEntry returns a form of the srcs or deps entry v, in the
+// package pkg, that's equal for equivalent labels.
+func normalizeKeepEntry(c *config.Config, pkg, key, v string) string {
+	if key == "srcs" && !isLabelString(v) {
+		return v
+	}
+	l, err := label.Parse(v)
+	if err != nil {
+		return v
+	}
+	l = l.Abs("", pkg)
+	if l.Repo == c.RepoName {
+		l.Repo = ""
+	}
+	return l.String()
+}
+
+// staleSrcReason returns why the srcs entry v in pkg is stale, or "" if it
+// isn't. Only plain file names are checked; labels may refer to generated
+// files.
+func staleSrcReason(c *config.Config, pkg, v string) string {
+	if isLabelString(v) {
+		return ""
+	}
+	if _, err := c.Stat(filepath.Join(c.RepoRoot, filepath.FromSlash(path.Join(pkg, v)))); err == nil {
+		return ""
+	}
+	return "file does not exist"
+}
+
+// staleDepReason returns why the deps entry v in pkg is stale, or "" if it
+// isn't.
+func staleDepReason(c *config.Config, pkg, v string, isIndexed func(label.Label) bool) string {
+	l, err := label.Parse(v)
+	if err != nil {
+		return ""
+	}
+	l = l.Abs("", pkg)
+	if l.Repo != "" && l.Repo != c.RepoName {
+		return ""
+	}
+	if isIndexed(label.New(c.RepoName, l.Pkg, l.Name)) {
+		return "no source imports it"
+	}
+	if _, err := c.Stat(filepath.Join(c.RepoRoot, filepath.FromSlash(l.Pkg))); err != nil {
+		return "package does not exist"
+	}
+	return ""
+}
+
+func isLabelString(v string) bool {
+	return strings.HasPrefix(v, ":") || strings.HasPrefix(v, "//") || strings.HasPrefix(v, "@")
+}
+
+// reportStaleKeeps logs a warning for each stale # keep entry in f.
+func reportStaleKeeps(c *config.Config, f *rule.File, gen []*rule.Rule, isIndexed func(label.Label) bool) {
+	for _, s := range findStaleKeeps(c, f, gen, isIndexed) {
+		slog.Warn("stale # keep comment", "path", f.Path, "rule", s.rule, "attr", s.attr, "value", s.value, "reason", s.reason)
+	}
+}
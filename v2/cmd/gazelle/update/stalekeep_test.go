@@ -0,0 +1,97 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindStaleKeeps(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"lib/lib.go", "lib/extra.go", "other/other.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := config.New()
+	c.RepoRoot = dir
+	c.RepoName = "repo"
+
+	f, err := rule.LoadData("lib/BUILD.bazel", "lib", []byte(`
+go_library(
+    name = "lib",
+    srcs = [
+        "extra.go",  # keep
+        "gone.go",  # keep
+        "lib.go",
+        ":generated.go",  # keep
+    ],
+    deps = [
+        "//other",  # keep
+        "//other:indexed",  # keep
+        "//removed/pkg",  # keep
+        "@other_repo//pkg",  # keep
+        "@repo//other:still_used",  # keep
+    ],
+)
+
+go_test(
+    name = "lib_test",
+    srcs = ["gone_test.go"],  # keep
+    deps = ["//other:indexed"],
+)
+
+go_library(
+    name = "kept",
+    srcs = ["gone.go"],  # keep
+) # keep
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := rule.NewRule("go_library", "lib")
+	lib.SetAttr("srcs", []string{"lib.go"})
+	lib.SetAttr("deps", []string{"//other:still_used"})
+	libTest := rule.NewRule("go_test", "lib_test")
+	kept := rule.NewRule("go_library", "kept")
+	gen := []*rule.Rule{lib, libTest, kept}
+	indexed := map[label.Label]bool{
+		label.New("repo", "other", "indexed"):    true,
+		label.New("repo", "other", "still_used"): true,
+	}
+
+	got := findStaleKeeps(c, f, gen, func(l label.Label) bool { return indexed[l] })
+	want := []staleKeep{
+		{rule: "lib", attr: "srcs", value: "gone.go", reason: "file does not exist"},
+		{rule: "lib", attr: "deps", value: "//other:indexed", reason: "no source imports it"},
+		{rule: "lib", attr: "deps", value: "//removed/pkg", reason: "package does not exist"},
+		{rule: "lib_test", attr: "srcs", value: "gone_test.go", reason: "file does not exist"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(staleKeep{})); diff != "" {
+		t.Errorf("(-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,356 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/walk"
+)
+
+// stateVersion is the version of the state file format. State files with a
+// different version are ignored.
+const stateVersion = 1
+
+// stateFile is the JSON content of the file named by -state_file.
+type stateFile struct {
+	Version int `json:"version"`
+
+	// Index is a fingerprint of the import specs of every indexed rule.
+	Index string `json:"index"`
+
+	// Dirs maps slash-separated directories, relative to the repository
+	// root, to fingerprints of their inputs after the last update: the
+	// configuration applied to them, the names and contents of their files,
+	// and the content of their build files.
+	Dirs map[string]string `json:"dirs"`
+}
+
+// incrementalState lets Run skip directories whose inputs haven't changed
+// since a previous run recorded them in a state file with -state_file.
+//
+// A directory is skipped when the fingerprint of its inputs matches the
+// state file. Its existing rules are indexed, and Gazelle assumes its build
+// file is already up to date. That's true as long as dependency resolution
+// would give the same results, so after walking, Run compares a fingerprint
+// of the index with the state file. If the index changed, Run generates rules
+// in skipped directories after all, so their dependencies are resolved again.
+//
+// A nil *incrementalState is disabled.
+type incrementalState struct {
+	path string
+	prev stateFile
+	next stateFile
+
+	// seed is mixed into every directory fingerprint. It covers flags and
+	// files outside of directories that may affect generated rules.
+	seed string
+
+	// configHashes holds a fingerprint of the directives applied in each
+	// directory, including those in parent directories.
+	configHashes map[string]string
+
+	// indexEntries describes the import specs of every indexed rule, for the
+	// index fingerprint.
+	indexEntries []string
+
+	// skipped holds the arguments for each skipped directory, in case the
+	// index changed and rules must be generated after all.
+	skipped []walk.Walk2FuncArgs
+
+	// pending holds partial fingerprints of updated directories, including
+	// skipped ones. They're completed with the content of each build file
+	// after it's written. mu guards pending and next.Dirs while files are
+	// written concurrently.
+	mu      sync.Mutex
+	pending map[string]pendingDir
+}
+
+type pendingDir struct {
+	partial string
+	file    *rule.File
+	failed  bool
+}
+
+// outputOnlyFlags are flags that don't affect the content of generated
+// build files, so changing them doesn't invalidate the state file.
+var outputOnlyFlags = map[string]bool{
+	"changed_files": true,
+	"cpuprofile":    true,
+	"diff_format":   true,
+	"diff_summary":  true,
+	"list_format":   true,
+	"log_format":    true,
+	"memprofile":    true,
+	"mode":          true,
+	"patch":         true,
+	"print0":        true,
+	"progress":      true,
+	"q":             true,
+	"state_file":    true,
+	"timings":       true,
+	"trace":         true,
+	"v":             true,
+}
+
+// flagsFingerprint returns a fingerprint of the flags that were set in fs,
+// except for flags that only affect output.
+func flagsFingerprint(fs *flag.FlagSet) string {
+	h := sha256.New()
+	fs.Visit(func(f *flag.Flag) {
+		if !outputOnlyFlags[f.Name] {
+			fmt.Fprintf(h, "%s=%s\n", f.Name, f.Value.String())
+		}
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadIncrementalState reads the state file at path, if it exists. Files
+// that can't be read or parsed are ignored with a warning, since the state
+// file is only an optimization. seedFiles are files outside of visited
+// directories that affect generation, like go.mod; they're hashed into
+// every directory fingerprint along with flagsHash, the names of enabled
+// languages, and the configuration file directives in c.
+func loadIncrementalState(c *config.Config, statePath, flagsHash string, langNames, seedFiles []string) *incrementalState {
+	st := &incrementalState{
+		path:         statePath,
+		next:         stateFile{Version: stateVersion, Dirs: make(map[string]string)},
+		configHashes: make(map[string]string),
+		pending:      make(map[string]pendingDir),
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "version=%d\ngazelle=%s\nflags=%s\n", stateVersion, BazelModuleVersion, flagsHash)
+	for _, name := range langNames {
+		fmt.Fprintf(h, "language %s\n", name)
+	}
+	for _, d := range c.ConfigFileDirectives {
+		fmt.Fprintf(h, "directive %s=%s\n", d.Key, d.Value)
+	}
+	for _, p := range seedFiles {
+		fmt.Fprintf(h, "file %s\n", p)
+		hashFile(h, p)
+	}
+	st.seed = hex.EncodeToString(h.Sum(nil))
+
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st
+	} else if err != nil {
+		slog.Warn("reading state file; updating all directories", "path", statePath, "error", err)
+		return st
+	}
+	if err := json.Unmarshal(data, &st.prev); err != nil || st.prev.Version != stateVersion {
+		slog.Warn("ignoring state file with unknown format; updating all directories", "path", statePath)
+		st.prev = stateFile{}
+	}
+	return st
+}
+
+// hashFile writes the contents of the file at p to h. Missing files are
+// written as a marker, so creating a file changes the fingerprint.
+func hashFile(h hash.Hash, p string) {
+	f, err := os.Open(p)
+	if err != nil {
+		io.WriteString(h, "<missing>\n")
+		return
+	}
+	defer f.Close()
+	io.Copy(h, f)
+}
+
+// stateConfigurer is a Configurer that records a fingerprint of the
+// directives applied in each directory in an incrementalState. It doesn't
+// change the configuration. st is set after flags are parsed; it's nil if
+// -state_file wasn't set.
+type stateConfigurer struct {
+	st *incrementalState
+}
+
+var _ config.Configurer = (*stateConfigurer)(nil)
+
+func (*stateConfigurer) RegisterFlags(*flag.FlagSet, string, *config.Config) {}
+
+func (*stateConfigurer) CheckFlags(*flag.FlagSet, *config.Config) error { return nil }
+
+func (*stateConfigurer) KnownDirectives() []string { return nil }
+
+func (sc *stateConfigurer) Configure(c *config.Config, rel string, f *rule.File) {
+	if sc.st == nil {
+		return
+	}
+	parent := sc.st.seed
+	if rel != "" {
+		parentRel := path.Dir(rel)
+		if parentRel == "." {
+			parentRel = ""
+		}
+		parent = sc.st.configHashes[parentRel]
+	}
+	if f == nil || len(f.Directives) == 0 {
+		sc.st.configHashes[rel] = parent
+		return
+	}
+	h := sha256.New()
+	io.WriteString(h, parent)
+	for _, d := range f.Directives {
+		fmt.Fprintf(h, "\n%s=%s", d.Key, d.Value)
+	}
+	sc.st.configHashes[rel] = hex.EncodeToString(h.Sum(nil))
+}
+
+// partialFingerprint returns a fingerprint of the inputs of the directory
+// visited with args, except for its build file, including one Gazelle may
+// create. The state file itself is not an input, since it changes on every
+// run.
+func (st *incrementalState) partialFingerprint(args walk.Walk2FuncArgs) string {
+	buildName := args.Config.DefaultBuildFileName()
+	if args.File != nil {
+		buildName = filepath.Base(args.File.Path)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "config %s\n", st.configHashes[args.Rel])
+	for _, name := range args.RegularFiles {
+		p := filepath.Join(args.Dir, filepath.FromSlash(name))
+		if name == buildName || p == st.path {
+			continue
+		}
+		fmt.Fprintf(h, "file %s\n", name)
+		hashFile(h, p)
+	}
+	for _, name := range args.Subdirs {
+		fmt.Fprintf(h, "dir %s\n", name)
+	}
+	for _, name := range args.GenFiles {
+		fmt.Fprintf(h, "gen %s\n", name)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprint completes a partial fingerprint with the content of the
+// directory's build file, which may be nil.
+func fingerprint(partial string, f *rule.File) string {
+	h := sha256.New()
+	io.WriteString(h, partial)
+	if f != nil {
+		fmt.Fprintf(h, "\nbuild %s\n", filepath.Base(f.Path))
+		h.Write(f.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// skip returns whether the directory visited with args is unchanged since
+// the state file was written.
+func (st *incrementalState) skip(args walk.Walk2FuncArgs) bool {
+	partial := st.partialFingerprint(args)
+	st.pending[args.Rel] = pendingDir{partial: partial, file: args.File}
+	if prev, ok := st.prev.Dirs[args.Rel]; ok && prev == fingerprint(partial, args.File) {
+		st.skipped = append(st.skipped, args)
+		return true
+	}
+	return false
+}
+
+// indexRule records the import specs of an indexed rule.
+func (st *incrementalState) indexRule(c *config.Config, r *rule.Rule, f *rule.File, rslv resolve.Resolver) {
+	entry := label.New(c.RepoName, f.Pkg, r.Name()).String() + " " + r.Kind()
+	if rslv != nil {
+		specs := rslv.Imports(c, r, f)
+		for _, spec := range specs {
+			entry += " " + spec.Lang + ":" + spec.Imp
+		}
+	}
+	st.indexEntries = append(st.indexEntries, entry)
+}
+
+// indexChanged returns whether the fingerprint of the index differs from
+// the state file.
+func (st *incrementalState) indexChanged() bool {
+	sort.Strings(st.indexEntries)
+	h := sha256.New()
+	for _, e := range st.indexEntries {
+		fmt.Fprintln(h, e)
+	}
+	st.next.Index = hex.EncodeToString(h.Sum(nil))
+	return st.next.Index != st.prev.Index
+}
+
+// written records the fingerprint of the directory rel after its build file
+// was emitted. f is nil if the build file was deleted. ok is false if the
+// file couldn't be written, in which case the directory isn't recorded, so
+// it's updated on the next run.
+func (st *incrementalState) written(rel string, f *rule.File, ok bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	p, found := st.pending[rel]
+	if !found {
+		return
+	}
+	if !ok {
+		p.failed = true
+		st.pending[rel] = p
+		return
+	}
+	delete(st.pending, rel)
+	st.next.Dirs[rel] = fingerprint(p.partial, f)
+}
+
+// save writes the state file. Directories where no build file was written,
+// like skipped directories, are recorded with their current build files.
+// Directories that weren't visited keep their previous fingerprints, unless
+// they no longer exist.
+func (st *incrementalState) save(repoRoot string) error {
+	for rel, p := range st.pending {
+		if !p.failed {
+			st.next.Dirs[rel] = fingerprint(p.partial, p.file)
+		}
+	}
+	for rel, fp := range st.prev.Dirs {
+		if _, ok := st.next.Dirs[rel]; ok {
+			continue
+		}
+		if _, ok := st.pending[rel]; ok {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(repoRoot, filepath.FromSlash(rel))); err == nil && fi.IsDir() {
+			st.next.Dirs[rel] = fp
+		}
+	}
+	data, err := json.MarshalIndent(st.next, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return os.WriteFile(st.path, data, 0o666)
+}
@@ -0,0 +1,68 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/walk"
+)
+
+func TestIncrementalStateSkip(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	c := config.New()
+	c.RepoRoot = dir
+
+	// visit loads the state file, visits the directory, and saves the state
+	// file, returning whether the directory was skipped.
+	visit := func() bool {
+		st := loadIncrementalState(c, statePath, "", nil, nil)
+		(&stateConfigurer{st: st}).Configure(c, "", nil)
+		skipped := st.skip(walk.Walk2FuncArgs{
+			Dir:          dir,
+			Config:       c,
+			Update:       true,
+			RegularFiles: []string{"a.go", "state.json"},
+		})
+		if err := st.save(dir); err != nil {
+			t.Fatal(err)
+		}
+		return skipped
+	}
+
+	if visit() {
+		t.Error("first visit: directory was skipped without a state file")
+	}
+	if !visit() {
+		t.Error("second visit: unchanged directory was not skipped")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package b\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if visit() {
+		t.Error("third visit: directory was skipped after a file changed")
+	}
+	if !visit() {
+		t.Error("fourth visit: unchanged directory was not skipped")
+	}
+}
@@ -0,0 +1,165 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
+	v2walk "github.com/bazel-contrib/bazel-gazelle/v2/walk"
+)
+
+// Categories of problems reported at the end of a run, in the order they're
+// listed in the summary. Only invalid attributes are reported without -strict.
+const (
+	problemBuildFile   = "build file errors"
+	problemDirective   = "invalid directives"
+	problemMerge       = "merge failures"
+	problemInvalidAttr = "invalid attributes"
+	problemUnresolved  = "unresolved imports"
+)
+
+var problemCategories = []string{problemBuildFile, problemDirective, problemMerge, problemInvalidAttr, problemUnresolved}
+
+// strictProblems collects problems found during a run with -strict, and
+// attributes that fail validation in any run, so that Gazelle can report all
+// of them in a summary at the end instead of stopping at the first one. It
+// may be used concurrently.
+type strictProblems struct {
+	// strict is set when Gazelle is run with -strict.
+	strict bool
+
+	mu         sync.Mutex
+	byCategory map[string][]error
+}
+
+// add records errs in the given category. Nil errors are ignored.
+func (p *strictProblems) add(category string, errs ...error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if p.byCategory == nil {
+			p.byCategory = make(map[string][]error)
+		}
+		p.byCategory[category] = append(p.byCategory[category], err)
+	}
+}
+
+// isConfigWalkError reports whether every error joined in err, returned by
+// walk.Walk2, is an invalid directive or an invalid build or directive file.
+// Other errors, like failures to read a directory, aren't caused by the
+// configuration.
+func isConfigWalkError(err error) bool {
+	for _, e := range splitErrors(err) {
+		var derr *v2config.DirectiveError
+		var berr *v2walk.BuildFileError
+		if !errors.As(e, &derr) && !errors.As(e, &berr) {
+			return false
+		}
+	}
+	return true
+}
+
+// addWalkErrors records the errors joined in err, returned by walk.Walk2.
+// Invalid directives are recorded separately from other build file errors.
+func (p *strictProblems) addWalkErrors(err error) {
+	for _, e := range splitErrors(err) {
+		var derr *v2config.DirectiveError
+		if errors.As(e, &derr) {
+			p.add(problemDirective, e)
+		} else {
+			p.add(problemBuildFile, e)
+		}
+	}
+}
+
+// err returns an error with a summary of the problems, grouped by category,
+// or nil if there were none. Problems other than unresolved imports are
+// configuration errors. Otherwise, the error wraps ErrUnresolvedImports.
+func (p *strictProblems) err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	total := 0
+	for _, errs := range p.byCategory {
+		total += len(errs)
+	}
+	if total == 0 {
+		return nil
+	}
+	var b strings.Builder
+	noun := "problems"
+	if total == 1 {
+		noun = "problem"
+	}
+	if p.strict {
+		fmt.Fprintf(&b, "strict mode found %d %s:", total, noun)
+	} else {
+		fmt.Fprintf(&b, "found %d %s:", total, noun)
+	}
+	for _, category := range problemCategories {
+		errs := p.byCategory[category]
+		if len(errs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s (%d):", category, len(errs))
+		for _, err := range errs {
+			fmt.Fprintf(&b, "\n\t%s", strings.ReplaceAll(err.Error(), "\n", "\n\t"))
+		}
+	}
+	serr := strictError{summary: b.String(), unresolved: len(p.byCategory[problemUnresolved]) > 0}
+	if total > len(p.byCategory[problemUnresolved]) {
+		return configError{serr}
+	}
+	return serr
+}
+
+// strictError summarizes the problems found in a run with -strict.
+type strictError struct {
+	summary    string
+	unresolved bool
+}
+
+func (e strictError) Error() string { return e.summary }
+
+func (e strictError) Unwrap() error {
+	if e.unresolved {
+		return ErrUnresolvedImports
+	}
+	return nil
+}
+
+// splitErrors returns the errors joined in err with errors.Join, or err
+// itself if it wasn't joined. Nested joins are flattened.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, splitErrors(e)...)
+	}
+	return errs
+}
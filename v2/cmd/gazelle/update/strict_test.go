@@ -0,0 +1,111 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"testing"
+
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+)
+
+func TestStrictProblems(t *testing.T) {
+	directiveErr := &v2config.DirectiveError{
+		Path:      "a/BUILD.bazel",
+		Line:      2,
+		Directive: rule.Directive{Key: "bogus"},
+		Err:       errors.New("unknown directive"),
+	}
+	for _, tc := range []struct {
+		desc      string
+		nonStrict bool
+		add       func(p *strictProblems)
+		wantCode  int
+		want      string
+	}{
+		{
+			desc:     "none",
+			add:      func(p *strictProblems) { p.add(problemMerge, nil) },
+			wantCode: ExitOK,
+		},
+		{
+			desc: "walk",
+			add: func(p *strictProblems) {
+				p.addWalkErrors(errors.Join(
+					errors.New("b/BUILD.bazel:1:5: syntax error"),
+					errors.Join(directiveErr),
+				))
+			},
+			wantCode: ExitConfig,
+			want: `strict mode found 2 problems:
+build file errors (1):
+	b/BUILD.bazel:1:5: syntax error
+invalid directives (1):
+	a/BUILD.bazel:2: gazelle:bogus: unknown directive`,
+		},
+		{
+			desc: "merge_and_unresolved",
+			add: func(p *strictProblems) {
+				p.add(problemUnresolved, errors.New("rule //a:a imports \"x\" which could not be resolved"))
+				p.add(problemMerge, errors.New("a/BUILD.bazel: could not merge go_library(a): multiple rules have the same name"))
+			},
+			wantCode: ExitConfig,
+			want: `strict mode found 2 problems:
+merge failures (1):
+	a/BUILD.bazel: could not merge go_library(a): multiple rules have the same name
+unresolved imports (1):
+	rule //a:a imports "x" which could not be resolved`,
+		},
+		{
+			desc: "unresolved",
+			add: func(p *strictProblems) {
+				p.add(problemUnresolved, errors.New("first\nsecond"))
+			},
+			wantCode: ExitUnresolved,
+			want: `strict mode found 1 problem:
+unresolved imports (1):
+	first
+	second`,
+		},
+		{
+			desc:      "invalid_attr",
+			nonStrict: true,
+			add: func(p *strictProblems) {
+				p.add(problemInvalidAttr, errors.New(`a/BUILD.bazel:3: sh_test "a": srcs: must have exactly one source`))
+			},
+			wantCode: ExitConfig,
+			want: `found 1 problem:
+invalid attributes (1):
+	a/BUILD.bazel:3: sh_test "a": srcs: must have exactly one source`,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			p := &strictProblems{strict: !tc.nonStrict}
+			tc.add(p)
+			err := p.err()
+			if got := ExitCode(err); got != tc.wantCode {
+				t.Errorf("got exit code %d; want %d", got, tc.wantCode)
+			}
+			if err == nil {
+				return
+			}
+			if got := err.Error(); got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// applyThirdPartyPreset adjusts the rules a language generated in a directory
+// marked with # gazelle:third_party. Test rules are dropped along with their
+// imports. Other rules are made public, replacing any visibility the language
+// chose, such as the restricted visibility of Go internal packages, and
+// c.ThirdPartyTags are added to their tags. Since visibility and tags aren't
+// merged into existing rules, rules already in the build file keep theirs.
+func applyThirdPartyPreset(c *config.Config, gen []*rule.Rule, imports []interface{}) ([]*rule.Rule, []interface{}) {
+	if !c.ThirdParty {
+		return gen, imports
+	}
+	keptGen := gen[:0]
+	keptImports := imports[:0]
+	for i, r := range gen {
+		if strings.HasSuffix(r.Kind(), "_test") {
+			continue
+		}
+		r.SetAttr("visibility", []string{"//visibility:public"})
+		if len(c.ThirdPartyTags) > 0 {
+			tags := r.AttrStrings("tags")
+			for _, tag := range c.ThirdPartyTags {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			r.SetAttr("tags", tags)
+		}
+		keptGen = append(keptGen, r)
+		keptImports = append(keptImports, imports[i])
+	}
+	return keptGen, keptImports
+}
@@ -0,0 +1,62 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyThirdPartyPreset(t *testing.T) {
+	lib := rule.NewRule("go_library", "lib")
+	lib.SetAttr("visibility", []string{"//foo:__subpackages__"})
+	lib.SetAttr("tags", []string{"manual", "vendored"})
+	test := rule.NewRule("go_test", "lib_test")
+	proto := rule.NewRule("proto_library", "lib_proto")
+	gen := []*rule.Rule{lib, test, proto}
+	imports := []interface{}{"lib", "test", "proto"}
+
+	c := config.New()
+	if gotGen, _ := applyThirdPartyPreset(c, gen, imports); len(gotGen) != 3 {
+		t.Fatalf("got %d rules outside a third-party directory; want 3", len(gotGen))
+	}
+
+	c.ThirdParty = true
+	c.ThirdPartyTags = []string{"vendored", "imported"}
+	gotGen, gotImports := applyThirdPartyPreset(c, gen, imports)
+
+	type ruleAttrs struct {
+		Name             string
+		Visibility, Tags []string
+	}
+	var got []ruleAttrs
+	for _, r := range gotGen {
+		got = append(got, ruleAttrs{r.Name(), r.AttrStrings("visibility"), r.AttrStrings("tags")})
+	}
+	want := []ruleAttrs{
+		{"lib", []string{"//visibility:public"}, []string{"manual", "vendored", "imported"}},
+		{"lib_proto", []string{"//visibility:public"}, []string{"vendored", "imported"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("rules (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]interface{}{"lib", "proto"}, gotImports); diff != "" {
+		t.Errorf("imports (-want,+got):\n%s", diff)
+	}
+}
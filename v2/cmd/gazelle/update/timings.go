@@ -0,0 +1,205 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// phaseTimings records the time Run spends in each phase, printed with
+// -timings. Walking, configuring, generating, and indexing are interleaved,
+// since Gazelle configures each directory, generates rules, and indexes
+// them as it walks the tree; walk is the time spent walking outside of the
+// other three phases, mostly reading directories and build files.
+type phaseTimings struct {
+	walk, configure, generate, index, resolve, write time.Duration
+
+	// configureStart is when configuration of the current directory began.
+	configureStart time.Time
+
+	// langs records the time each language spends in the configure,
+	// generate, and resolve phases, in the order languages are passed to
+	// Run. langsByName indexes it by language name.
+	langs       []*langTimings
+	langsByName map[string]*langTimings
+}
+
+// langTimings records the time one language spends configuring directories,
+// generating rules, and resolving dependencies, and the number of rules it
+// generates and resolves. Directories are configured and rules are generated
+// one directory at a time during the walk, but dependencies are resolved
+// concurrently, so resolution metrics are updated atomically.
+type langTimings struct {
+	name                string
+	configure, generate time.Duration
+	generated, empty    int
+	resolve, resolved   atomic.Int64
+
+	configureStart time.Time
+}
+
+// LanguageMetrics describes the work one language did during Run.
+type LanguageMetrics struct {
+	// Name is the name of the language.
+	Name string
+
+	// Configure, Generate, and Resolve are the time the language spent in
+	// its Configure, GenerateRules, and Resolve methods.
+	Configure, Generate, Resolve time.Duration
+
+	// Generated is the number of rules the language generated, and Empty
+	// is the number of empty rules it returned for deletion.
+	Generated, Empty int
+
+	// Resolved is the number of rules whose dependencies the language
+	// resolved.
+	Resolved int
+}
+
+// configurers returns a pair of configuration extensions to insert at the
+// beginning and end of the list passed to walk.Walk2. Together, they measure
+// the time spent in the others' Configure methods.
+func (t *phaseTimings) configurers() (start, end config.Configurer) {
+	return &timingConfigurer{start: true, begin: &t.configureStart, total: &t.configure},
+		&timingConfigurer{begin: &t.configureStart, total: &t.configure}
+}
+
+// languageConfigurers returns a pair of configuration extensions to insert
+// before and after the language with the given name in the list passed to
+// walk.Walk2. Together, they measure the time spent in the language's
+// Configure method.
+func (t *phaseTimings) languageConfigurers(name string) (start, end config.Configurer) {
+	lt := t.langsByName[name]
+	if lt == nil {
+		lt = &langTimings{name: name}
+		t.langs = append(t.langs, lt)
+		if t.langsByName == nil {
+			t.langsByName = make(map[string]*langTimings)
+		}
+		t.langsByName[name] = lt
+	}
+	return &timingConfigurer{start: true, begin: &lt.configureStart, total: &lt.configure},
+		&timingConfigurer{begin: &lt.configureStart, total: &lt.configure}
+}
+
+// languageMetrics returns the metrics recorded for each language.
+func (t *phaseTimings) languageMetrics() []LanguageMetrics {
+	metrics := make([]LanguageMetrics, 0, len(t.langs))
+	for _, lt := range t.langs {
+		metrics = append(metrics, LanguageMetrics{
+			Name:      lt.name,
+			Configure: lt.configure,
+			Generate:  lt.generate,
+			Resolve:   time.Duration(lt.resolve.Load()),
+			Generated: lt.generated,
+			Empty:     lt.empty,
+			Resolved:  int(lt.resolved.Load()),
+		})
+	}
+	return metrics
+}
+
+// timingConfigurer is a Configurer that measures the time spent configuring
+// each directory. It doesn't change the configuration. A start configurer
+// records the time in begin; an end configurer adds the time since then to
+// total.
+type timingConfigurer struct {
+	start bool
+	begin *time.Time
+	total *time.Duration
+}
+
+var _ config.Configurer = (*timingConfigurer)(nil)
+
+func (*timingConfigurer) RegisterFlags(*flag.FlagSet, string, *config.Config) {}
+
+func (*timingConfigurer) CheckFlags(*flag.FlagSet, *config.Config) error { return nil }
+
+func (*timingConfigurer) KnownDirectives() []string { return nil }
+
+func (tc *timingConfigurer) Configure(*config.Config, string, *rule.File) {
+	if tc.start {
+		*tc.begin = time.Now()
+	} else {
+		*tc.total += time.Since(*tc.begin)
+	}
+}
+
+// writeTimings prints a table of the time spent in each phase to w.
+func writeTimings(w io.Writer, t *phaseTimings) error {
+	phases := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"walk", t.walk},
+		{"configure", t.configure},
+		{"generate", t.generate},
+		{"index", t.index},
+		{"resolve", t.resolve},
+		{"write", t.write},
+	}
+	var total time.Duration
+	for _, p := range phases {
+		total += p.d
+	}
+	if _, err := fmt.Fprintf(w, "%-10s %10s %6s\n", "phase", "time", "share"); err != nil {
+		return err
+	}
+	for _, p := range phases {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(p.d) / float64(total)
+		}
+		if _, err := fmt.Fprintf(w, "%-10s %10s %5.1f%%\n", p.name, roundTiming(p.d), share); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "%-10s %10s\n", "total", roundTiming(total)); err != nil {
+		return err
+	}
+
+	if len(t.langs) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n%-10s %10s %10s %10s %9s %9s\n", "language", "configure", "generate", "resolve", "generated", "resolved"); err != nil {
+		return err
+	}
+	for _, m := range t.languageMetrics() {
+		if _, err := fmt.Fprintf(w, "%-10s %10s %10s %10s %9d %9d\n", m.Name, roundTiming(m.Configure), roundTiming(m.Generate), roundTiming(m.Resolve), m.Generated, m.Resolved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roundTiming rounds d to a precision that's readable for its magnitude.
+func roundTiming(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
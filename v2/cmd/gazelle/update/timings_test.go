@@ -0,0 +1,108 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteTimings(t *testing.T) {
+	timings := &phaseTimings{
+		walk:      1500 * time.Millisecond,
+		configure: 250 * time.Millisecond,
+		generate:  2 * time.Second,
+		index:     12345 * time.Microsecond,
+		resolve:   237655 * time.Microsecond,
+		write:     0,
+	}
+	var buf bytes.Buffer
+	if err := writeTimings(&buf, timings); err != nil {
+		t.Fatal(err)
+	}
+	want := `phase            time  share
+walk             1.5s  37.5%
+configure       250ms   6.2%
+generate           2s  50.0%
+index         12.35ms   0.3%
+resolve      237.66ms   5.9%
+write              0s   0.0%
+total              4s
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("timings (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteTimingsLanguages(t *testing.T) {
+	timings := &phaseTimings{generate: time.Second}
+	timings.languageConfigurers("proto")
+	timings.languageConfigurers("go")
+	proto, golang := timings.langsByName["proto"], timings.langsByName["go"]
+	proto.configure = 5 * time.Millisecond
+	proto.generate = 100 * time.Millisecond
+	proto.generated = 3
+	proto.resolve.Add(int64(2 * time.Millisecond))
+	proto.resolved.Add(3)
+	golang.configure = 20 * time.Millisecond
+	golang.generate = 900 * time.Millisecond
+	golang.generated = 12
+	golang.resolve.Add(int64(300 * time.Millisecond))
+	golang.resolved.Add(10)
+
+	var buf bytes.Buffer
+	if err := writeTimings(&buf, timings); err != nil {
+		t.Fatal(err)
+	}
+	want := `phase            time  share
+walk               0s   0.0%
+configure          0s   0.0%
+generate           1s 100.0%
+index              0s   0.0%
+resolve            0s   0.0%
+write              0s   0.0%
+total              1s
+
+language    configure   generate    resolve generated  resolved
+proto             5ms      100ms        2ms         3         3
+go               20ms      900ms      300ms        12        10
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("timings (-want +got):\n%s", diff)
+	}
+}
+
+func TestTimingConfigurer(t *testing.T) {
+	timings := &phaseTimings{}
+	start, end := timings.configurers()
+	start.Configure(nil, "", nil)
+	time.Sleep(time.Millisecond)
+	end.Configure(nil, "", nil)
+	if timings.configure < time.Millisecond {
+		t.Errorf("got configure time %v; want at least 1ms", timings.configure)
+	}
+
+	start, end = timings.languageConfigurers("go")
+	start.Configure(nil, "", nil)
+	time.Sleep(time.Millisecond)
+	end.Configure(nil, "", nil)
+	if got := timings.langsByName["go"].configure; got < time.Millisecond {
+		t.Errorf("got go configure time %v; want at least 1ms", got)
+	}
+}
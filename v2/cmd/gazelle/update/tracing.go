@@ -0,0 +1,226 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracer records spans for the phases of a run and the directories visited
+// in each phase, then exports them in the OpenTelemetry protocol (OTLP)
+// JSON encoding, with -trace. A nil *tracer records nothing, so callers
+// don't need to check whether tracing is enabled. Spans may be started
+// concurrently.
+type tracer struct {
+	traceID [16]byte
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+// span is a timed operation, like generating rules in one directory.
+type span struct {
+	t          *tracer
+	name       string
+	id         [8]byte
+	parent     *span
+	start, end time.Time
+	attrs      map[string]string
+}
+
+func newTracer() *tracer {
+	t := &tracer{}
+	rand.Read(t.traceID[:])
+	return t
+}
+
+// start begins a span named name, as a child of parent, which may be nil.
+// attrs are alternating keys and values.
+func (t *tracer) start(name string, parent *span, attrs ...string) *span {
+	if t == nil {
+		return nil
+	}
+	s := &span{t: t, name: name, parent: parent, start: time.Now()}
+	rand.Read(s.id[:])
+	if len(attrs) > 0 {
+		s.attrs = make(map[string]string)
+		for i := 0; i+1 < len(attrs); i += 2 {
+			s.attrs[attrs[i]] = attrs[i+1]
+		}
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return s
+}
+
+// finish ends the span.
+func (s *span) finish() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+}
+
+// The types below are a subset of the OTLP JSON encoding of
+// ExportTraceServiceRequest. See
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttr `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string        `json:"key"`
+	Value otlpAttrValue `json:"value"`
+}
+
+type otlpAttrValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpSpanKindInternal is SPAN_KIND_INTERNAL.
+const otlpSpanKindInternal = 1
+
+func (t *tracer) request() otlpRequest {
+	resource := []otlpAttr{{Key: "service.name", Value: otlpAttrValue{StringValue: "gazelle"}}}
+	if BazelModuleVersion != "" {
+		resource = append(resource, otlpAttr{Key: "service.version", Value: otlpAttrValue{StringValue: BazelModuleVersion}})
+	}
+	spans := make([]otlpSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		end := s.end
+		if end.IsZero() {
+			end = s.start
+		}
+		o := otlpSpan{
+			TraceID:           hex.EncodeToString(t.traceID[:]),
+			SpanID:            hex.EncodeToString(s.id[:]),
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		}
+		if s.parent != nil {
+			o.ParentSpanID = hex.EncodeToString(s.parent.id[:])
+		}
+		for _, k := range sortedKeys(s.attrs) {
+			o.Attributes = append(o.Attributes, otlpAttr{Key: k, Value: otlpAttrValue{StringValue: s.attrs[k]}})
+		}
+		spans = append(spans, o)
+	}
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: resource},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/bazel-contrib/bazel-gazelle", Version: BazelModuleVersion},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+// export sends the recorded spans to dest. If dest is an http or https URL,
+// spans are posted to an OTLP/HTTP collector; "/v1/traces" is appended if
+// the URL has no path. Headers in OTEL_EXPORTER_OTLP_HEADERS, written as
+// comma-separated key=value pairs, are added to the request. Otherwise, dest
+// is a file where the spans are written as OTLP JSON.
+func (t *tracer) export(ctx context.Context, dest string) error {
+	data, err := json.Marshal(t.request())
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(dest, "http://") && !strings.HasPrefix(dest, "https://") {
+		return os.WriteFile(dest, data, 0o666)
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil {
+		return err
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, kv := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if uv, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = uv
+		}
+		req.Header.Set(strings.TrimSpace(k), v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("exporting traces to %s: %s: %s", u, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// spanTree summarizes exported spans as "parent name > name dir" strings.
+func spanTree(t *testing.T, data []byte) []string {
+	t.Helper()
+	var req otlpRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatal(err)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	names := make(map[string]string)
+	for _, s := range spans {
+		names[s.SpanID] = s.Name
+	}
+	var tree []string
+	for _, s := range spans {
+		desc := s.Name
+		if s.ParentSpanID != "" {
+			desc = names[s.ParentSpanID] + " > " + desc
+		}
+		for _, a := range s.Attributes {
+			desc += " " + a.Key + "=" + a.Value.StringValue
+		}
+		if s.TraceID != spans[0].TraceID {
+			t.Errorf("span %s has trace ID %s; want %s", s.Name, s.TraceID, spans[0].TraceID)
+		}
+		tree = append(tree, desc)
+	}
+	return tree
+}
+
+func recordTestSpans() *tracer {
+	tr := newTracer()
+	root := tr.start("gazelle", nil)
+	walkSpan := tr.start("walk", root)
+	tr.start("generate", walkSpan, "gazelle.dir", "a").finish()
+	walkSpan.finish()
+	tr.start("resolve", root).finish()
+	root.finish()
+	return tr
+}
+
+func TestTracerExportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := recordTestSpans().export(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"gazelle",
+		"gazelle > walk",
+		"walk > generate gazelle.dir=a",
+		"gazelle > resolve",
+	}
+	if diff := cmp.Diff(want, spanTree(t, data)); diff != "" {
+		t.Errorf("spans (-want +got):\n%s", diff)
+	}
+}
+
+func TestTracerExportHTTP(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20token")
+
+	if err := recordTestSpans().export(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/v1/traces" {
+		t.Errorf("got path %q; want /v1/traces", gotPath)
+	}
+	if gotAuth != "Bearer token" {
+		t.Errorf("got Authorization %q; want \"Bearer token\"", gotAuth)
+	}
+	if got := len(spanTree(t, gotBody)); got != 4 {
+		t.Errorf("got %d spans; want 4", got)
+	}
+}
+
+func TestTracerNil(t *testing.T) {
+	var tr *tracer
+	s := tr.start("gazelle", nil)
+	s.finish()
+	if s != nil {
+		t.Errorf("got span %v from nil tracer; want nil", s)
+	}
+}
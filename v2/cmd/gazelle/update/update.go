@@ -26,24 +26,33 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/bazel-contrib/bazel-gazelle/v2/compat"
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
 	gzflag "github.com/bazel-contrib/bazel-gazelle/v2/flag"
 	"github.com/bazel-contrib/bazel-gazelle/v2/internal/wspace"
 	"github.com/bazel-contrib/bazel-gazelle/v2/label"
 	"github.com/bazel-contrib/bazel-gazelle/v2/merger"
+	v2resolve "github.com/bazel-contrib/bazel-gazelle/v2/resolve"
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
-	"github.com/bazelbuild/bazel-gazelle/walk"
+	v2walk "github.com/bazel-contrib/bazel-gazelle/v2/walk"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/language"
 	"github.com/bazelbuild/bazel-gazelle/repo"
 	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/walk"
 	"github.com/bazelbuild/buildtools/build"
 )
 
@@ -66,15 +75,70 @@ var errVersion = errors.New("version printed")
 type updateConfig struct {
 	dirs                   []string
 	emit                   emitFunc
+	emitDeleted            emitFunc
+	deleteEmpty            bool
 	repos                  []repo.Repo
+	remoteCacheDir         string
+	remoteCacheTTL         time.Duration
+	redirects              []repo.Redirect
 	workspaceFiles         []*rule.File
 	walkMode               walk.Mode
 	patchPath              string
 	patchBuffer            bytes.Buffer
+	diffSummaryPath        string
+	diffSummary            diffSummary
+	generationLogPath      string
+	generationLog          generationLog
+	diffFormat             string
+	diffContext            int
+	diffColor              bool
+	diffPathBase           string
 	print0                 bool
+	out                    io.Writer
+	progress               bool
+	timings                bool
+	tracePath              string
+	changedFilesPath       string
+	statePath              string
+	stateFlags             string
+	saveState              bool
+	concurrentEmit         bool
+	stream                 bool
 	profile                Profiler
 	removeNoopKeepComments bool
+	reportStaleKeep        bool
+	deterministic          bool
 	printVersion           bool
+
+	// dryRun is set by -dry_run. Files aren't written; instead, rules and
+	// files that would be renamed or deleted are recorded in dryRunReport
+	// and printed after all files are emitted.
+	dryRun       bool
+	dryRunReport dryRunReport
+
+	// cliWorkDir is the directory where "bazel run" was invoked. Directory
+	// arguments starting at index cliDirStart were typed on its command line
+	// and are interpreted relative to cliWorkDir instead of Config.WorkDir.
+	// cliWorkDir is empty when Gazelle isn't run with "bazel run".
+	cliWorkDir  string
+	cliDirStart int
+
+	// changed describes the files listed with -changed_files. When it's set,
+	// Gazelle updates directories with changed files and directories whose
+	// build files may be affected by them, instead of the directories in
+	// arguments.
+	changed *changedFiles
+
+	// jsonPackages holds the packages recorded in -mode=json, printed
+	// after all files are emitted.
+	jsonOutput   bool
+	jsonPackages []jsonPackage
+
+	// listedFiles holds the files recorded in -mode=list, printed after all
+	// files are emitted in listFormat.
+	listOutput  bool
+	listFormat  string
+	listedFiles []listedFile
 }
 
 type emitFunc func(c *config.Config, f *rule.File) error
@@ -83,6 +147,8 @@ var modeFromName = map[string]emitFunc{
 	"print": printFile,
 	"fix":   fixFile,
 	"diff":  diffFile,
+	"json":  jsonFile,
+	"list":  listFile,
 }
 
 const updateName = "_update"
@@ -98,31 +164,92 @@ type updateConfigurer struct {
 	recursive      bool
 	knownImports   []string
 	repoConfigPath string
+	configFilePath string
+	redirectsPath  string
 	cpuProfile     string
 	memProfile     string
+	logFormat      string
+	diffColor      string
+	diffPaths      string
+	verbose, quiet bool
+
+	// report is attached to the context passed to Run by a program that
+	// embeds Gazelle. It's nil when Gazelle is run as a command.
+	report *Report
+
+	// out is the writer attached to the context passed to Run with
+	// WithOutput. It's nil when output goes to os.Stdout.
+	out io.Writer
+
+	// skipMissing is set by Format, which may be passed files that were
+	// deleted. Arguments naming paths that don't exist are ignored.
+	skipMissing bool
 }
 
 func (ucr *updateConfigurer) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config) {
-	uc := &updateConfig{}
+	uc := &updateConfig{out: ucr.out}
+	if uc.out == nil {
+		uc.out = os.Stdout
+	}
 	c.Exts[updateName] = uc
 
 	c.ShouldFix = cmd == "fix"
 
-	fs.StringVar(&ucr.mode, "mode", "fix", "print: prints all of the updated BUILD files\n\tfix: rewrites all of the BUILD files in place\n\tdiff: computes the rewrite but then just does a diff")
+	fs.StringVar(&ucr.mode, "mode", "fix", "print: prints all of the updated BUILD files\n\tfix: rewrites all of the BUILD files in place\n\tdiff: computes the rewrite but then just does a diff\n\tjson: prints the rules in the updated BUILD files as JSON\n\tlist: prints the directories whose BUILD files would change")
+	fs.StringVar(&uc.listFormat, "list_format", "text", "when set with -mode=list, the format of the list: text (one directory per line) or json")
 	fs.BoolVar(&ucr.recursive, "r", true, "when true, gazelle will update subdirectories recursively")
 	fs.StringVar(&uc.patchPath, "patch", "", "when set with -mode=diff, gazelle will write to a file instead of stdout")
+	fs.StringVar(&uc.diffFormat, "diff_format", "unified", "when set with -mode=diff, the format of the diff: unified, or github to print GitHub Actions annotations")
+	fs.IntVar(&uc.diffContext, "diff_context", 3, "when set with -mode=diff, the number of unchanged lines printed around each change")
+	fs.StringVar(&ucr.diffColor, "diff_color", "auto", "when set with -mode=diff, whether to color the diff: auto (when printing to a terminal), always, or never")
+	fs.StringVar(&ucr.diffPaths, "diff_paths", "repo", "when set with -mode=diff, how paths are printed in the diff: repo (relative to the repository root) or relative (relative to the directory where gazelle was invoked)")
+	fs.StringVar(&uc.diffSummaryPath, "diff_summary", "", "when set with -mode=diff, gazelle will write a JSON summary of changed files and rules to this file")
+	fs.StringVar(&uc.generationLogPath, "generation_log", "", "write a JSON log of the directives read, rules generated, and imports that couldn't be resolved in each directory to this file, even if gazelle fails")
+	fs.StringVar(&uc.changedFilesPath, "changed_files", "", "file listing changed files, one per line, relative to the repository root, or - to read the list from stdin. Gazelle updates directories with changed files and build files that depend on them, instead of directories in arguments")
+	fs.BoolVar(&uc.stream, "stream", false, "write each build file as soon as its dependencies are resolved, instead of after dependencies are resolved in all directories")
+	fs.StringVar(&uc.statePath, "state_file", "", "file where gazelle records fingerprints of each directory's inputs. Directories whose inputs haven't changed since the last run with -mode=fix are skipped")
+	fs.BoolVar(&uc.dryRun, "dry_run", false, "when set with -mode=fix, gazelle doesn't write files, and instead prints a JSON report of the rules and build files it would rename or delete, with the reason for each change")
 	fs.BoolVar(&uc.print0, "print0", false, "when set with -mode=fix, gazelle will print the names of rewritten files separated with \\0 (NULL)")
+	fs.StringVar(&ucr.logFormat, "log_format", "text", "format of log messages: text, or json to print one JSON object per message for log processing tools")
+	fs.BoolVar(&ucr.verbose, "v", false, "verbose: log debug messages, like the rules generated in each directory")
+	fs.BoolVar(&ucr.quiet, "q", false, "quiet: only log errors")
+	fs.BoolVar(&uc.progress, "progress", false, "periodically log how many directories have been walked, packages generated, rules resolved, and warnings logged")
+	fs.BoolVar(&uc.timings, "timings", false, "print the time spent in each phase: walk, configure, generate, index, resolve, and write, and by each language in configure, generate, and resolve")
+	fs.StringVar(&uc.tracePath, "trace", "", "record OpenTelemetry spans for each phase and directory, and post them to this OTLP/HTTP collector URL or write them as OTLP JSON to this file")
 	fs.StringVar(&ucr.cpuProfile, "cpuprofile", "", "write cpu profile to `file`")
 	fs.StringVar(&ucr.memProfile, "memprofile", "", "write memory profile to `file`")
 	fs.Var(&gzflag.MultiFlag{Values: &ucr.knownImports}, "known_import", "import path for which external resolution is skipped (can specify multiple times)")
+	fs.StringVar(&uc.remoteCacheDir, "remote_cache_dir", "", "directory where lookups of remote repositories and modules are persisted when -remote_cache_ttl is set. Defaults to gazelle/remote in the user cache directory")
+	fs.DurationVar(&uc.remoteCacheTTL, "remote_cache_ttl", 0, "when positive, lookups of remote repositories and modules are persisted on disk and reused by later runs for this long")
+	fs.StringVar(&ucr.redirectsPath, "remote_redirects", "", "JSON file with rules mapping import path prefixes to remote repositories, used instead of looking them up over the network")
 	fs.StringVar(&ucr.repoConfigPath, "repo_config", "", "file where Gazelle should load repository configuration. Defaults to WORKSPACE.")
+	fs.StringVar(&ucr.configFilePath, "config_file", "", "file where Gazelle should load default flags and directives. Defaults to gazelle.json in the repository root, if present.")
+	fs.BoolVar(&uc.deleteEmpty, "delete_empty_build_files", false, "when set, gazelle will delete build files that are empty after generated rules for missing sources are removed, and remove references to their rules from deps in other build files")
 	fs.BoolVar(&uc.removeNoopKeepComments, "remove_noop_keep_comments", false, "when set, gazelle will remove noop keep comments from BUILD files")
+	fs.BoolVar(&uc.reportStaleKeep, "report_stale_keep", false, "when set, gazelle will warn about srcs and deps entries marked with # keep that it no longer generates and that no source file or package justifies")
+	fs.BoolVar(&uc.deterministic, "deterministic", false, "when set, gazelle sorts new rules generated by each language by kind and name, and sorts the branches of select expressions in generated attributes, so output doesn't depend on the order in which extensions generate rules")
 	fs.BoolVar(&uc.printVersion, "version", false, "print gazelle's version and exit")
 }
 
 func (ucr *updateConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) error {
 	uc := getUpdateConfig(c)
 
+	logLevel := slog.LevelInfo
+	switch {
+	case ucr.verbose && ucr.quiet:
+		return fmt.Errorf("only one of -v and -q may be set")
+	case ucr.verbose:
+		logLevel = slog.LevelDebug
+	case ucr.quiet:
+		logLevel = slog.LevelError
+	}
+	if err := configureLogging(ucr.logFormat, logLevel, os.Stderr); err != nil {
+		return err
+	}
+	if ucr.report != nil {
+		ucr.report.captureLogs(logLevel)
+	}
+
 	if uc.printVersion {
 		if BazelModuleVersion == "" {
 			fmt.Printf("gazelle version unknown\n")
@@ -142,12 +269,90 @@ func (ucr *updateConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) erro
 	if !ok {
 		return fmt.Errorf("unrecognized emit mode: %q", ucr.mode)
 	}
+	uc.emitDeleted = deleteModeFromName[ucr.mode]
+	uc.jsonOutput = ucr.mode == "json"
+	uc.concurrentEmit = ucr.mode == "fix" && !uc.print0
+	uc.listOutput = ucr.mode == "list"
+	if uc.dryRun {
+		if ucr.mode != "fix" {
+			return fmt.Errorf("-dry_run set but -mode is %s, not fix", ucr.mode)
+		}
+		uc.emit = func(*config.Config, *rule.File) error { return nil }
+		uc.emitDeleted = uc.emit
+		uc.concurrentEmit = false
+	}
+	if uc.listFormat != "text" && uc.listFormat != "json" {
+		return fmt.Errorf("unrecognized list format: %q", uc.listFormat)
+	}
 	if uc.patchPath != "" && ucr.mode != "diff" {
 		return fmt.Errorf("-patch set but -mode is %s, not diff", ucr.mode)
 	}
 	if uc.patchPath != "" && !filepath.IsAbs(uc.patchPath) {
 		uc.patchPath = filepath.Join(c.WorkDir, uc.patchPath)
 	}
+	if uc.diffFormat != "unified" && uc.diffFormat != "github" {
+		return fmt.Errorf("unrecognized diff format: %q", uc.diffFormat)
+	}
+	if uc.diffContext < 0 {
+		return fmt.Errorf("-diff_context must not be negative")
+	}
+	switch ucr.diffColor {
+	case "auto":
+		uc.diffColor = uc.patchPath == "" && isTerminal(uc.out)
+	case "always":
+		uc.diffColor = uc.patchPath == ""
+	case "never":
+	default:
+		return fmt.Errorf("unrecognized diff color setting: %q", ucr.diffColor)
+	}
+	switch ucr.diffPaths {
+	case "repo":
+	case "relative":
+		uc.diffPathBase = c.WorkDir
+		if uc.cliWorkDir != "" {
+			uc.diffPathBase = uc.cliWorkDir
+		}
+	default:
+		return fmt.Errorf("unrecognized diff paths setting: %q", ucr.diffPaths)
+	}
+	if uc.diffSummaryPath != "" && ucr.mode != "diff" {
+		return fmt.Errorf("-diff_summary set but -mode is %s, not diff", ucr.mode)
+	}
+	if uc.diffSummaryPath != "" && !filepath.IsAbs(uc.diffSummaryPath) {
+		uc.diffSummaryPath = filepath.Join(c.WorkDir, uc.diffSummaryPath)
+	}
+	if uc.generationLogPath != "" && !filepath.IsAbs(uc.generationLogPath) {
+		uc.generationLogPath = filepath.Join(c.WorkDir, uc.generationLogPath)
+	}
+	if uc.tracePath != "" && !strings.Contains(uc.tracePath, "://") && !filepath.IsAbs(uc.tracePath) {
+		uc.tracePath = filepath.Join(c.WorkDir, uc.tracePath)
+	}
+	if uc.stream && uc.deleteEmpty {
+		return fmt.Errorf("-stream can't be used with -delete_empty_build_files")
+	}
+	if uc.statePath != "" {
+		if c.IndexLazy {
+			return fmt.Errorf("-state_file can't be used with -index=lazy")
+		}
+		if !filepath.IsAbs(uc.statePath) {
+			uc.statePath = filepath.Join(c.WorkDir, uc.statePath)
+		}
+		uc.stateFlags = flagsFingerprint(fs)
+		uc.saveState = ucr.mode == "fix" && !uc.dryRun
+	}
+	if err := checkRemoteCacheFlags(&uc.remoteCacheDir, uc.remoteCacheTTL, c.WorkDir); err != nil {
+		return err
+	}
+	if ucr.redirectsPath != "" {
+		if !filepath.IsAbs(ucr.redirectsPath) {
+			ucr.redirectsPath = filepath.Join(c.WorkDir, ucr.redirectsPath)
+		}
+		redirects, err := repo.LoadRedirects(ucr.redirectsPath)
+		if err != nil {
+			return err
+		}
+		uc.redirects = redirects
+	}
 	p, err := NewProfiler(ucr.cpuProfile, ucr.memProfile)
 	if err != nil {
 		return err
@@ -158,20 +363,30 @@ func (ucr *updateConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) erro
 	if len(dirs) == 0 {
 		dirs = []string{"."}
 	}
-	uc.dirs = make([]string, len(dirs))
+	uc.dirs = make([]string, 0, len(dirs))
 	for i, arg := range dirs {
 		dir := arg
 		if !filepath.IsAbs(dir) {
-			dir = filepath.Join(c.WorkDir, dir)
+			if uc.cliWorkDir != "" && i >= uc.cliDirStart {
+				dir = filepath.Join(uc.cliWorkDir, dir)
+			} else {
+				dir = filepath.Join(c.WorkDir, dir)
+			}
 		}
-		dir, err = filepath.EvalSymlinks(dir)
-		if err != nil {
-			return fmt.Errorf("%s: failed to resolve symlinks: %v", arg, err)
+		if c.FS == nil {
+			dir, err = filepath.EvalSymlinks(dir)
+			if err != nil && ucr.skipMissing && errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("%s: failed to resolve symlinks: %v", arg, err)
+			}
+		} else {
+			dir = filepath.Clean(dir)
 		}
 		if !isDescendingDir(dir, c.RepoRoot) {
 			return fmt.Errorf("%s: not a subdirectory of repo root %s", arg, c.RepoRoot)
 		}
-		uc.dirs[i] = dir
+		uc.dirs = append(uc.dirs, dir)
 	}
 
 	indexAll := c.IndexLibraries && !c.IndexLazy
@@ -186,11 +401,33 @@ func (ucr *updateConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) erro
 		uc.walkMode = walk.UpdateDirsMode
 	}
 
+	if uc.changedFilesPath != "" {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("-changed_files can't be used with directory arguments")
+		}
+		changedFilesPath := uc.changedFilesPath
+		if changedFilesPath != "-" && !filepath.IsAbs(changedFilesPath) {
+			changedFilesPath = filepath.Join(c.WorkDir, changedFilesPath)
+		}
+		if uc.changed, err = readChangedFiles(c, changedFilesPath); err != nil {
+			return fmt.Errorf("-changed_files: %w", err)
+		}
+		uc.dirs = uc.changed.absDirs(c.RepoRoot)
+		// Build files that depend on changed directories can only be found
+		// when all directories are visited for indexing.
+		if indexAll {
+			uc.walkMode = walk.VisitAllUpdateDirsMode
+		} else {
+			uc.walkMode = walk.UpdateDirsMode
+		}
+	}
+
 	// Load the repo configuration file (WORKSPACE by default) to find out
 	// names and prefixes of other go_repositories. This affects external
 	// dependency resolution for Go.
 	// TODO(jayconrod): Go-specific code should be moved to language/go.
-	if ucr.repoConfigPath == "" {
+	defaultRepoConfig := ucr.repoConfigPath == ""
+	if defaultRepoConfig {
 		ucr.repoConfigPath = wspace.FindWORKSPACEFile(c.RepoRoot)
 	}
 	repoConfigFile, err := rule.LoadWorkspaceFile(ucr.repoConfigPath, "")
@@ -202,6 +439,24 @@ func (ucr *updateConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) erro
 			return err
 		}
 	}
+
+	// Go modules imported with go_deps in MODULE.bazel are also known
+	// repositories, unless WORKSPACE declares repositories with the same names.
+	if defaultRepoConfig {
+		moduleRepos, err := repo.ListModuleRepositories(filepath.Join(c.RepoRoot, "MODULE.bazel"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		declared := make(map[string]bool)
+		for _, r := range c.Repos {
+			declared[r.Name()] = true
+		}
+		for _, r := range moduleRepos {
+			if !declared[r.Name()] {
+				c.Repos = append(c.Repos, r)
+			}
+		}
+	}
 	for _, imp := range ucr.knownImports {
 		uc.repos = append(uc.repos, repo.Repo{
 			Name:     label.ImportPathToBazelRepoName(imp),
@@ -209,6 +464,11 @@ func (ucr *updateConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) erro
 		})
 	}
 
+	// Code in a module fetched from a replacement may import packages by the
+	// replacement's path, which also belong to the replaced module's
+	// repository, unless another repository has that path.
+	var replaced []repo.Repo
+	importPaths := make(map[string]bool)
 	for _, r := range c.Repos {
 		if r.Kind() == "go_repository" {
 			var name string
@@ -221,6 +481,15 @@ func (ucr *updateConfigurer) CheckFlags(fs *flag.FlagSet, c *config.Config) erro
 				Name:     name,
 				GoPrefix: r.AttrString("importpath"),
 			})
+			importPaths[r.AttrString("importpath")] = true
+			if replace := r.AttrString("replace"); replace != "" {
+				replaced = append(replaced, repo.Repo{Name: name, GoPrefix: replace})
+			}
+		}
+	}
+	for _, r := range replaced {
+		if !importPaths[r.GoPrefix] {
+			uc.repos = append(uc.repos, r)
 		}
 	}
 
@@ -282,6 +551,10 @@ type visitRecord struct {
 	// file is the build file being processed.
 	file *rule.File
 
+	// fixed is the list of rules in file after Fix, before generated rules
+	// were merged. It's only recorded with -dry_run.
+	fixed []*rule.Rule
+
 	// mappedKinds are mapped kinds used during this visit.
 	mappedKinds    []config.MappedKind
 	mappedKindInfo map[string]rule.KindInfo
@@ -298,19 +571,25 @@ func Run(
 	ctx context.Context,
 	languages []language.Language,
 	wd string,
-	args []string) error {
-	cexts := make([]config.Configurer, 0, len(languages)+4)
+	args []string) (err error) {
+	timings := &phaseTimings{}
+	timingStart, timingEnd := timings.configurers()
+	stateCfg := &stateConfigurer{}
+	cexts := make([]config.Configurer, 0, 3*len(languages)+7)
 	cexts = append(cexts,
+		timingStart,
 		&config.CommonConfigurer{},
-		&updateConfigurer{},
+		&updateConfigurer{report: reportFromContext(ctx), out: outputFromContext(ctx)},
 		&walk.Configurer{},
 		&resolve.Configurer{})
 
 	for _, lang := range languages {
-		cexts = append(cexts, lang)
+		langStart, langEnd := timings.languageConfigurers(lang.Name())
+		cexts = append(cexts, langStart, lang, langEnd)
 	}
+	cexts = append(cexts, stateCfg, timingEnd)
 
-	c, err := newFixUpdateConfiguration(wd, args, cexts)
+	c, err := newFixUpdateConfiguration(wd, fsFromContext(ctx), args, cexts)
 	if errors.Is(err, errVersion) {
 		// sentinel error; we already printed the version so just exit
 		return nil
@@ -318,7 +597,24 @@ func Run(
 		return err
 	}
 
-	mrslv := newMetaResolver()
+	// With a warm session, the index and the configuration of each directory
+	// from earlier runs are reused, so only directories that are updated need
+	// to be visited.
+	sess := sessionFromContext(ctx)
+	warm := sess.warm(c)
+	uc := getUpdateConfig(c)
+	var mrslv *metaResolver
+	if warm {
+		mrslv = sess.mrslv
+		switch uc.walkMode {
+		case walk.VisitAllUpdateDirsMode:
+			uc.walkMode = walk.UpdateDirsMode
+		case walk.VisitAllUpdateSubdirsMode:
+			uc.walkMode = walk.UpdateSubdirsMode
+		}
+	} else {
+		mrslv = newMetaResolver()
+	}
 	kinds := make(map[string]rule.KindInfo)
 	for kind, info := range rule.GenericKinds {
 		kinds[kind] = info
@@ -337,13 +633,38 @@ func Run(
 		}
 		exts = append(exts, lang)
 	}
-	ruleIndex := resolve.NewRuleIndex(mrslv.Resolver, exts...)
+	var ruleIndex *resolve.RuleIndex
+	if warm {
+		ruleIndex = sess.ruleIndex
+	} else {
+		ruleIndex = resolve.NewRuleIndex(mrslv.Resolver, exts...)
+	}
+	if sess != nil {
+		defer func() { sess.finishRun(c, languages, mrslv, ruleIndex, err) }()
+	}
+
+	if rep := reportFromContext(ctx); rep != nil {
+		rep.wrapEmit(uc)
+	}
+	if uc.generationLogPath != "" {
+		defer func() {
+			if lerr := uc.generationLog.write(uc.generationLogPath, err, ruleIndex.UnresolvedErrors()); lerr != nil {
+				slog.Warn("writing generation log", "path", uc.generationLogPath, "error", lerr)
+			}
+		}()
+	}
 
 	if err = fixRepoFiles(c, loads); err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	// ctx is canceled when Gazelle is interrupted, for example with Ctrl-C.
+	// Gazelle stops walking directories, generating rules, and resolving
+	// dependencies, and returns an error without writing build files. Once it
+	// starts writing files, it finishes, so build files stay consistent with
+	// each other. With -stream, files are written while dependencies are
+	// resolved; files already written are kept, and no more are written.
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	for _, lang := range languages {
 		if life, ok := lang.(language.LifecycleManager); ok {
@@ -352,45 +673,132 @@ func Run(
 	}
 
 	// Visit all directories in the repository.
-	var visits []visitRecord
-	uc := getUpdateConfig(c)
+	var visits []*visitRecord
+	renames := make(fixRenames)
+	problems := &strictProblems{strict: c.Strict}
 	defer func() {
 		if err := uc.profile.Stop(); err != nil {
-			log.Printf("stopping profiler: %v", err)
+			slog.Warn("stopping profiler", "error", err)
 		}
 	}()
 
 	rule.RemoveNoopKeepComments = uc.removeNoopKeepComments || c.ShouldFix
 
-	walkErr := walk.Walk2(c, cexts, uc.dirs, uc.walkMode, func(args walk.Walk2FuncArgs) walk.Walk2FuncResult {
+	var st *incrementalState
+	if uc.statePath != "" {
+		langNames := make([]string, len(languages))
+		for i, lang := range languages {
+			langNames[i] = lang.Name()
+		}
+		seedFiles := []string{
+			filepath.Join(c.RepoRoot, "go.mod"),
+			filepath.Join(c.RepoRoot, "go.work"),
+			filepath.Join(c.RepoRoot, "MODULE.bazel"),
+		}
+		for _, f := range uc.workspaceFiles {
+			seedFiles = append(seedFiles, f.Path)
+		}
+		st = loadIncrementalState(c, uc.statePath, uc.stateFlags, langNames, seedFiles)
+		stateCfg.st = st
+	}
+
+	prog := startProgress(uc.progress)
+	defer prog.stop()
+
+	var tr *tracer
+	if uc.tracePath != "" {
+		tr = newTracer()
+	}
+	rootSpan := tr.start("gazelle", nil, "gazelle.repo_root", c.RepoRoot)
+	defer func() {
+		if tr == nil {
+			return
+		}
+		rootSpan.finish()
+		if err := tr.export(context.WithoutCancel(ctx), uc.tracePath); err != nil {
+			slog.Warn("exporting traces", "error", err)
+		}
+	}()
+
+	walkSpan := tr.start("walk", rootSpan)
+	walkStart := time.Now()
+
+	// Rules are generated in walk order, since languages may depend on rules
+	// generated earlier, for example in subdirectories. Applying kind
+	// mappings and merging generated rules into build files is independent
+	// for each directory, so that's done in the pool while the walk
+	// continues. Rules are indexed in walk order after the walk, when all
+	// build files are merged.
+	pool := newWorkerPool(c.Jobs)
+	var indexQueue []func()
+	updatedRels := make(map[string]bool)
+	indexRules := func(c *config.Config, rel string, f *rule.File) {
+		for _, r := range f.Rules {
+			ruleIndex.AddRule(c, r, f)
+			if st != nil {
+				st.indexRule(c, r, f, mrslv.Resolver(r, rel))
+			}
+		}
+	}
+
+	// visit generates rules in a directory. regenerating is true when a
+	// directory skipped with -state_file is visited again after the walk,
+	// because the index changed. Its rules are already indexed.
+	visit := func(args walk.Walk2FuncArgs, regenerating bool) walk.Walk2FuncResult {
 		dir := args.Dir
 		rel := args.Rel
 		c := args.Config
+		if ctx.Err() != nil {
+			return walk.Walk2FuncResult{}
+		}
+		if !regenerating {
+			prog.dirs.Add(1)
+		}
+		visitStart := time.Now()
 		update := args.Update
 		f := args.File
+		if !update && uc.changed != nil && uc.changed.affects(c, rel, f) {
+			update = true
+		}
 		subdirs := args.Subdirs
 		regularFiles := args.RegularFiles
 		genFiles := args.GenFiles
 
-		mrslv.AliasedKinds(rel, c.AliasMap)
+		mrslv.SetConfig(rel, c)
+		if f != nil {
+			unmapKindAttrs(c, f)
+		}
 		// If this file is ignored or if Gazelle was not asked to update this
 		// directory, just index the build file and move on.
-		if !update {
-			for _, repl := range c.KindMap {
-				mrslv.MappedKind(rel, repl)
-			}
-			if c.IndexLibraries && f != nil {
-				for _, r := range f.Rules {
-					ruleIndex.AddRule(c, r, f)
-				}
+		// Directories whose inputs haven't changed since the last run with
+		// -state_file are indexed the same way.
+		if !update || (st != nil && !regenerating && st.skip(args)) {
+			if c.IndexLibraries && f != nil && !warm {
+				indexQueue = append(indexQueue, func() { indexRules(c, rel, f) })
 			}
 			return walk.Walk2FuncResult{}
 		}
+		updatedRels[rel] = true
+		genSpan := tr.start("generate", walkSpan, "gazelle.dir", rel)
 
 		// Fix any problems in the file.
+		var fixed []*rule.Rule
 		if f != nil {
+			before := ruleNames(f)
 			for _, l := range filterLanguages(c, languages) {
+				var langBefore map[*rule.Rule]string
+				if uc.dryRun {
+					langBefore = ruleNames(f)
+				}
 				l.Fix(c, f)
+				if uc.dryRun {
+					uc.dryRunReport.recordFix(c, l.Name(), f, langBefore)
+				}
+			}
+			renames.record(f, before)
+			merger.MigrateLoads(f, loadMigrations(c, languages), c.ModuleToApparentName)
+			if uc.dryRun {
+				fixed = slices.Clone(f.Rules)
 			}
 		}
 
@@ -399,6 +807,7 @@ func Run(
 		var imports []interface{}
 		var relsToVisit []string
 		for _, l := range filterLanguages(c, languages) {
+			genStart := time.Now()
 			res := l.GenerateRules(language.GenerateArgs{
 				Config:       c,
 				Dir:          dir,
@@ -410,9 +819,18 @@ func Run(
 				OtherEmpty:   empty,
 				OtherGen:     gen,
 			})
+			if lt := timings.langsByName[l.Name()]; lt != nil {
+				lt.generate += time.Since(genStart)
+				lt.generated += len(res.Gen)
+				lt.empty += len(res.Empty)
+			}
 			if len(res.Gen) != len(res.Imports) {
 				log.Panicf("%s: language %s generated %d rules but returned %d imports", rel, l.Name(), len(res.Gen), len(res.Imports))
 			}
+			res.Gen, res.Imports = applyThirdPartyPreset(c, res.Gen, res.Imports)
+			if uc.deterministic {
+				sortGenerated(res.Gen, res.Imports)
+			}
 			empty = append(empty, res.Empty...)
 			gen = append(gen, res.Gen...)
 			imports = append(imports, res.Imports...)
@@ -420,110 +838,81 @@ func Run(
 				relsToVisit = append(relsToVisit, res.RelsToIndex...)
 			}
 		}
+		c.ReleaseParsedFiles(dir)
 		if f == nil && len(gen) == 0 {
+			genSpan.finish()
+			timings.generate += time.Since(visitStart)
 			return walk.Walk2FuncResult{RelsToVisit: relsToVisit}
 		}
 
-		// Apply and record relevant kind mappings.
-		var (
-			mappedKinds    []config.MappedKind
-			mappedKindInfo = make(map[string]rule.KindInfo)
-		)
-		// We apply map_kind to all rules, including pre-existing ones.
-		var allRules []*rule.Rule
-		allRules = append(allRules, gen...)
-		if f != nil {
-			allRules = append(allRules, f.Rules...)
+		if uc.generationLogPath != "" {
+			uc.generationLog.recordPackage(rel, f, gen)
 		}
 
-		maybeRecordReplacement := func(ruleKind string) (*string, error) {
-			var repl *config.MappedKind
-			repl, err = lookupMapKindReplacement(c.KindMap, ruleKind)
-			if err != nil {
-				return nil, err
-			}
-			if repl != nil {
-				mappedKindInfo[repl.KindName] = kinds[ruleKind]
-				mappedKinds = append(mappedKinds, *repl)
-				mrslv.MappedKind(rel, *repl)
-				return &repl.KindName, nil
-			}
-			return nil, nil
+		v := &visitRecord{
+			pkgRel:  rel,
+			c:       c,
+			rules:   gen,
+			imports: imports,
+			empty:   empty,
+			file:    f,
+			fixed:   fixed,
 		}
+		visits = append(visits, v)
+		pool.do(func() error {
+			defer genSpan.finish()
+			err := mergeVisit(v, dir, kinds)
+			prog.generated.Add(1)
+			slog.Debug("generated rules", "dir", rel, "rules", len(gen), "empty", len(empty))
+			return err
+		})
 
-		var errs []error
-		for _, r := range allRules {
-			if replacementName, err := maybeRecordReplacement(r.Kind()); err != nil {
-				errs = append(errs, fmt.Errorf("looking up mapped kind: %w", err))
-			} else if replacementName != nil {
-				r.SetKind(*replacementName)
-			}
-
-			for i, arg := range r.Args() {
-				// Only check the first arg - this supports the maybe(java_library, ...) pattern,
-				// but avoids potential false positives from other uses of symbols.
-				if i != 0 {
-					break
-				}
-				if ident, ok := arg.(*build.Ident); ok {
-					// Don't allow re-mapping symbols that aren't known loads of a plugin.
-					if _, knownKind := kinds[ident.Name]; !knownKind {
-						continue
-					}
-					if replacementName, err := maybeRecordReplacement(ident.Name); err != nil {
-						errs = append(errs, fmt.Errorf("looking up mapped kind: %w", err))
-					} else if replacementName != nil {
-						if err := r.UpdateArg(i, &build.Ident{Name: *replacementName}); err != nil {
-							log.Panicf("%s: %v", rel, err)
-						}
-					}
-				}
-			}
-		}
-		for _, r := range empty {
-			if repl, ok := c.KindMap[r.Kind()]; ok {
-				mappedKindInfo[repl.KindName] = kinds[r.Kind()]
-				mappedKinds = append(mappedKinds, repl)
-				mrslv.MappedKind(rel, repl)
-				r.SetKind(repl.KindName)
-			}
+		// Add library rules to the dependency resolution table after the walk.
+		if c.IndexLibraries && !regenerating {
+			indexQueue = append(indexQueue, func() { indexRules(c, rel, v.file) })
 		}
-
-		// Insert or merge rules into the build file.
-		if f == nil {
-			f = rule.EmptyFile(filepath.Join(dir, c.DefaultBuildFileName()), rel)
-			for _, r := range gen {
-				r.Insert(f)
-			}
-		} else {
-			merger.MergeFile(f, empty, gen, merger.PreResolve,
-				unionKindInfoMaps(kinds, mappedKindInfo),
-				c.AliasMap,
-			)
-		}
-		visits = append(visits, visitRecord{
-			pkgRel:         rel,
-			c:              c,
-			rules:          gen,
-			imports:        imports,
-			empty:          empty,
-			file:           f,
-			mappedKinds:    mappedKinds,
-			mappedKindInfo: mappedKindInfo,
+		timings.generate += time.Since(visitStart)
+		return walk.Walk2FuncResult{RelsToVisit: relsToVisit}
+	}
+	walkErr := walkContext(ctx, c, cexts, uc.dirs, uc.walkMode, func(args walk.Walk2FuncArgs) walk.Walk2FuncResult {
+		return visit(args, false)
+	})
+	timings.walk = time.Since(walkStart) - timings.configure - timings.generate
+	mergeStart := time.Now()
+	mergeErr := pool.wait()
+	timings.generate += time.Since(mergeStart)
+	if err := ctx.Err(); err != nil {
+		return interrupted(err)
+	}
+	indexStart := time.Now()
+	if warm {
+		// Replace the rules of updated directories and directories that changed
+		// or were deleted since the last run.
+		ruleIndex.Reopen(func(pkg string) bool {
+			return updatedRels[pkg] || sess.stale[pkg]
 		})
-
-		// Add library rules to the dependency resolution table.
-		if c.IndexLibraries {
-			for _, r := range f.Rules {
-				ruleIndex.AddRule(c, r, f)
-			}
+	}
+	for _, index := range indexQueue {
+		index()
+	}
+	// The queued functions hold build files that aren't needed after indexing.
+	indexQueue = nil
+	timings.index += time.Since(indexStart)
+
+	// If the index changed since the last run with -state_file, dependencies
+	// of rules in skipped directories may resolve differently, so generate
+	// rules in those directories after all.
+	if walkErr == nil && mergeErr == nil && st != nil && st.indexChanged() {
+		regenerateStart := time.Now()
+		for _, args := range st.skipped {
+			visit(args, true)
 		}
-
-		return walk.Walk2FuncResult{
-			RelsToVisit: relsToVisit,
-			Err:         errors.Join(errs...),
+		mergeErr = pool.wait()
+		timings.generate += time.Since(regenerateStart)
+		if err := ctx.Err(); err != nil {
+			return interrupted(err)
 		}
-	})
+	}
 
 	for _, lang := range languages {
 		if finishable, ok := lang.(language.FinishableLanguage); ok {
@@ -531,60 +920,440 @@ func Run(
 		}
 	}
 
-	if walkErr != nil {
-		return walkErr
+	// Build files aren't written when the configuration is invalid. With
+	// -strict, all the problems found are summarized. Merge errors come from
+	// invalid map_kind directives. Other walk errors, like I/O errors, are
+	// returned as they are.
+	if walkErr != nil || mergeErr != nil {
+		if !isConfigWalkError(walkErr) {
+			return errors.Join(walkErr, mergeErr)
+		}
+		if c.Strict {
+			problems.addWalkErrors(walkErr)
+			problems.add(problemDirective, splitErrors(mergeErr)...)
+			return problems.err()
+		}
+		return configError{errors.Join(walkErr, mergeErr)}
 	}
 
 	// Finish building the index for dependency resolution.
+	walkSpan.finish()
+	indexStart = time.Now()
 	ruleIndex.Finish()
+	timings.index += time.Since(indexStart)
 
 	// Resolve dependencies.
-	rc, cleanupRc := repo.NewRemoteCache(uc.repos)
+	prog.setPhase("resolving")
+	resolveStart := time.Now()
+	resolveSpan := tr.start("resolve", rootSpan)
+	rc, cleanupRc := newRemoteCache(uc)
 	defer func() {
 		if cerr := cleanupRc(); err == nil && cerr != nil {
 			err = cerr
 		}
 	}()
 	if err = maybePopulateRemoteCacheFromGoMod(c, rc); err != nil {
-		log.Print(err)
+		slog.Warn("reading go.mod", "error", err)
 	}
-	for _, v := range visits {
+	resolveVisit := func(v *visitRecord) {
+		dirSpan := tr.start("resolve", resolveSpan, "gazelle.dir", v.pkgRel)
+		defer dirSpan.finish()
 		for i, r := range v.rules {
 			from := label.New(c.RepoName, v.pkgRel, r.Name())
 			if rslv := mrslv.Resolver(r, v.pkgRel); rslv != nil {
+				ruleStart := time.Now()
 				rslv.Resolve(v.c, ruleIndex, rc, r, v.imports[i], from)
+				if lt := timings.langsByName[rslv.Name()]; lt != nil {
+					lt.resolve.Add(int64(time.Since(ruleStart)))
+					lt.resolved.Add(1)
+				}
 			}
+			prog.resolved.Add(1)
+		}
+		if uc.reportStaleKeep {
+			reportStaleKeeps(v.c, v.file, v.rules, ruleIndex.IsIndexed)
 		}
-		merger.MergeFile(v.file, v.empty, v.rules, merger.PostResolve,
+		// Attributes that fail validation are always reported. Generated
+		// rules that can't be merged are only reported with -strict.
+		mergeErrs := merger.MergeFileErrors(v.file, v.empty, v.rules, merger.PostResolve,
 			unionKindInfoMaps(kinds, v.mappedKindInfo),
 			v.c.AliasMap,
 		)
+		for _, err := range mergeErrs {
+			var attrErr *merger.AttrError
+			if errors.As(err, &attrErr) {
+				problems.add(problemInvalidAttr, err)
+			} else if c.Strict {
+				problems.add(problemMerge, err)
+			}
+		}
+		applyRepoMapping(v.c, v.file, v.rules)
+		afterResolve(v.c, v.pkgRel, v.file, languages)
 	}
-	for _, lang := range languages {
-		if life, ok := lang.(language.LifecycleManager); ok {
-			life.AfterResolvingDeps(ctx)
+	prepareVisit := func(v *visitRecord) {
+		mapKindAttrs(v.c, v.file)
+		merger.FixLoads(v.file, applyKindMappings(v.mappedKinds, loads))
+	}
+
+	// Dependencies are resolved in several directories at once only if every
+	// language allows it. Files are written concurrently in fix mode. Other
+	// modes print output or collect it in order, so files are emitted one at
+	// a time.
+	serialPool := &workerPool{sem: make(chan struct{}, 1)}
+	resolvePool := serialPool
+	if resolvesConcurrently(languages) {
+		resolvePool = pool
+	}
+	emitPool := pool
+	if !uc.concurrentEmit {
+		emitPool = serialPool
+	}
+	deletedFiles := make(map[*rule.File]bool)
+	emitResults := make([]emitResult, len(visits))
+	emitVisit := func(i int, v *visitRecord) {
+		emit := uc.emit
+		if deletedFiles[v.file] {
+			emit = uc.emitDeleted
+		}
+		err := emit(v.c, v.file)
+		if uc.dryRun {
+			uc.dryRunReport.recordEmit(v.c, v.file, v.fixed, deletedFiles[v.file])
+		}
+		emitResults[i] = emitResult{path: v.file.Path, err: err}
+		if st != nil {
+			written := v.file
+			if deletedFiles[v.file] {
+				written = nil
+			}
+			st.written(v.pkgRel, written, err == nil || err == ErrDiff)
 		}
 	}
 
-	// Emit merged files.
+	var writeStart time.Time
+	var writeSpan *span
+	if uc.stream {
+		// Each build file is written as soon as its dependencies are resolved.
+		// If Gazelle is interrupted, files that were already written are kept,
+		// and no more are written.
+		streamPool := resolvePool
+		if !uc.concurrentEmit {
+			streamPool = serialPool
+		}
+		for i, v := range visits {
+			streamPool.do(func() error {
+				if ctx.Err() != nil {
+					return nil
+				}
+				resolveVisit(v)
+				prepareVisit(v)
+				rewriteRenamedReferences(v.c, v.file, renames)
+				emitVisit(i, v)
+				// Drop the build file and imports of v once it's written, so
+				// memory use doesn't grow with the number of directories.
+				visits[i] = nil
+				return nil
+			})
+		}
+		streamPool.wait()
+		if err := ctx.Err(); err != nil {
+			return interrupted(err)
+		}
+		for _, lang := range languages {
+			if life, ok := lang.(language.LifecycleManager); ok {
+				life.AfterResolvingDeps(ctx)
+			}
+		}
+		resolveSpan.finish()
+		timings.resolve = time.Since(resolveStart)
+		prog.setPhase("writing")
+		writeStart = time.Now()
+		writeSpan = tr.start("write", rootSpan)
+	} else {
+		for _, v := range visits {
+			resolvePool.do(func() error {
+				if ctx.Err() == nil {
+					resolveVisit(v)
+				}
+				return nil
+			})
+		}
+		resolvePool.wait()
+		if err := ctx.Err(); err != nil {
+			return interrupted(err)
+		}
+		for _, lang := range languages {
+			if life, ok := lang.(language.LifecycleManager); ok {
+				life.AfterResolvingDeps(ctx)
+			}
+		}
+		resolveSpan.finish()
+		timings.resolve = time.Since(resolveStart)
+
+		// Emit merged files.
+		prog.setPhase("writing")
+		writeStart = time.Now()
+		writeSpan = tr.start("write", rootSpan)
+		for _, v := range visits {
+			pool.do(func() error {
+				prepareVisit(v)
+				return nil
+			})
+		}
+		pool.wait()
+		if uc.deleteEmpty {
+			deleted := make(map[label.Label]bool)
+			for _, v := range visits {
+				if isDeletableBuildFile(v.c, v.file) {
+					deletedFiles[v.file] = true
+					for _, l := range deletedLabels(v.file) {
+						deleted[l] = true
+					}
+				}
+			}
+			if len(deleted) > 0 {
+				for _, v := range visits {
+					if !deletedFiles[v.file] {
+						removeDeletedDeps(v.c, v.file, deleted)
+					}
+				}
+			}
+		}
+		if len(renames) > 0 {
+			for _, v := range visits {
+				if !deletedFiles[v.file] {
+					rewriteRenamedReferences(v.c, v.file, renames)
+				}
+			}
+		}
+		for i, v := range visits {
+			emitPool.do(func() error {
+				emitVisit(i, v)
+				return nil
+			})
+		}
+		emitPool.wait()
+	}
 	var exit error
-	for _, v := range visits {
-		merger.FixLoads(v.file, applyKindMappings(v.mappedKinds, loads))
-		if err := uc.emit(v.c, v.file); err != nil {
-			if err == ErrDiff {
-				exit = err
-			} else {
-				log.Print(err)
+	for _, res := range emitResults {
+		if res.err == ErrDiff {
+			exit = res.err
+		} else if res.err != nil {
+			slog.Error(res.err.Error(), "path", res.path)
+		}
+	}
+	if err := writeEmitOutput(uc); err != nil {
+		return err
+	}
+	if st != nil && uc.saveState {
+		if err := st.save(c.RepoRoot); err != nil {
+			slog.Warn("writing state file", "path", uc.statePath, "error", err)
+		}
+	}
+	writeSpan.finish()
+	timings.write = time.Since(writeStart)
+	if uc.timings {
+		if err := writeTimings(os.Stderr, timings); err != nil {
+			return err
+		}
+	}
+	if rep := reportFromContext(ctx); rep != nil {
+		rep.Languages = timings.languageMetrics()
+	}
+
+	// Invalid attributes, unresolved imports, and merge failures don't stop
+	// Gazelle from writing build files. They're summarized and reported as
+	// errors after build files are written. Only invalid attributes are
+	// reported without -strict.
+	if c.Strict {
+		problems.add(problemUnresolved, ruleIndex.UnresolvedErrors()...)
+	}
+	if err := problems.err(); err != nil {
+		return err
+	}
+	return exit
+}
+
+// resolvesConcurrently reports whether every language implements
+// resolve.ConcurrentResolver and allows dependencies to be resolved in
+// several directories at once.
+func resolvesConcurrently(languages []language.Language) bool {
+	for _, lang := range languages {
+		cr, ok := lang.(v2resolve.ConcurrentResolver)
+		if !ok || !cr.ResolvesConcurrently() {
+			return false
+		}
+	}
+	return true
+}
+
+// afterResolve calls AfterResolve for languages that implement
+// language.PostResolver and are enabled in the directory of f, passing the
+// rules in f of kinds each language generates.
+func afterResolve(c *config.Config, rel string, f *rule.File, languages []language.Language) {
+	for _, lang := range languages {
+		pr, ok := lang.(language.PostResolver)
+		if !ok || !c.LangEnabled(lang.Name()) {
+			continue
+		}
+		langKinds := lang.Kinds()
+		var rules []*rule.Rule
+		for _, r := range f.Rules {
+			if _, ok := langKinds[c.UnderlyingKind(r.Kind())]; ok {
+				rules = append(rules, r)
 			}
 		}
+		args := language.AfterResolveArgs{Config: c, Rel: rel, File: f, Rules: rules}
+		if err := pr.AfterResolve(args); err != nil {
+			slog.Error(err.Error(), "path", f.Path)
+		}
 	}
+}
+
+// interrupted returns an error reporting that Gazelle was interrupted, for
+// example with Ctrl-C, with the cause err from a canceled context.
+func interrupted(err error) error {
+	return fmt.Errorf("interrupted: %w", err)
+}
+
+// walkContext calls walk.Walk2, stopping the walk when ctx is canceled.
+func walkContext(ctx context.Context, c *config.Config, cexts []config.Configurer, dirs []string, mode walk.Mode, wf walk.Walk2Func) error {
+	v2cexts := make([]v2config.Configurer, len(cexts))
+	for i, cext := range cexts {
+		v2cexts[i] = compat.MustConfigurerV2(cext)
+	}
+	return v2walk.Walk2(ctx, c, v2cexts, dirs, mode, wf)
+}
+
+// emitResult is the outcome of emitting one build file.
+type emitResult struct {
+	path string
+	err  error
+}
+
+// writeEmitOutput writes output collected while files were emitted: the
+// patch file, the diff summary, JSON or list output, and the -dry_run
+// report.
+func writeEmitOutput(uc *updateConfig) error {
 	if uc.patchPath != "" {
 		if err := os.WriteFile(uc.patchPath, uc.patchBuffer.Bytes(), 0o666); err != nil {
 			return err
 		}
 	}
+	if uc.diffSummaryPath != "" {
+		if err := writeDiffSummary(uc.diffSummaryPath, uc.diffSummary); err != nil {
+			return err
+		}
+	}
+	if uc.jsonOutput {
+		if err := writeJSON(uc.out, uc.jsonPackages); err != nil {
+			return err
+		}
+	}
+	if uc.listOutput {
+		if err := writeList(uc.out, uc.listFormat, uc.listedFiles); err != nil {
+			return err
+		}
+	}
+	if uc.dryRun {
+		if err := writeDryRunReport(uc.out, &uc.dryRunReport); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeVisit applies kind mappings to the rules generated in the directory
+// described by v and to the rules in its build file, then merges the
+// generated rules into the build file, creating it in dir if there was none.
+// It records the mappings used and the build file in v. mergeVisit may be
+// called concurrently for different directories.
+func mergeVisit(v *visitRecord, dir string, kinds map[string]rule.KindInfo) error {
+	c, f, rel := v.c, v.file, v.pkgRel
+
+	// Apply and record relevant kind mappings.
+	var (
+		mappedKinds    []config.MappedKind
+		mappedKindInfo = make(map[string]rule.KindInfo)
+	)
+	// We apply map_kind to all rules, including pre-existing ones.
+	var allRules []*rule.Rule
+	allRules = append(allRules, v.rules...)
+	if f != nil {
+		allRules = append(allRules, f.Rules...)
+	}
+
+	maybeRecordReplacement := func(ruleKind string) (*string, error) {
+		repl, err := lookupMapKindReplacement(c.KindMap, ruleKind)
+		if err != nil {
+			return nil, err
+		}
+		if repl != nil {
+			mappedKindInfo[repl.KindName] = kinds[ruleKind]
+			mappedKinds = append(mappedKinds, *repl)
+			return &repl.KindName, nil
+		}
+		return nil, nil
+	}
 
-	return exit
+	var errs []error
+	for _, r := range allRules {
+		if replacementName, err := maybeRecordReplacement(r.Kind()); err != nil {
+			errs = append(errs, fmt.Errorf("looking up mapped kind: %w", err))
+		} else if replacementName != nil {
+			r.SetKind(*replacementName)
+		}
+
+		for i, arg := range r.Args() {
+			// Only check the first arg - this supports the maybe(java_library, ...) pattern,
+			// but avoids potential false positives from other uses of symbols.
+			if i != 0 {
+				break
+			}
+			if ident, ok := arg.(*build.Ident); ok {
+				// Don't allow re-mapping symbols that aren't known loads of a plugin.
+				if _, knownKind := kinds[ident.Name]; !knownKind {
+					continue
+				}
+				if replacementName, err := maybeRecordReplacement(ident.Name); err != nil {
+					errs = append(errs, fmt.Errorf("looking up mapped kind: %w", err))
+				} else if replacementName != nil {
+					if err := r.UpdateArg(i, &build.Ident{Name: *replacementName}); err != nil {
+						log.Panicf("%s: %v", rel, err)
+					}
+				}
+			}
+		}
+	}
+	for _, r := range v.empty {
+		if repl, ok := c.KindMap[r.Kind()]; ok {
+			mappedKindInfo[repl.KindName] = kinds[r.Kind()]
+			mappedKinds = append(mappedKinds, repl)
+			r.SetKind(repl.KindName)
+		}
+	}
+
+	// Record which kind each call to a wrapper macro stands for.
+	if f != nil {
+		assignWrappedKinds(c, f, v.rules, v.empty)
+	}
+
+	// Insert or merge rules into the build file.
+	if f == nil {
+		f = rule.EmptyFile(filepath.Join(dir, c.DefaultBuildFileName()), rel)
+		for _, r := range v.rules {
+			r.Insert(f)
+		}
+	} else {
+		merger.MergeFile(f, v.empty, v.rules, merger.PreResolve,
+			unionKindInfoMaps(kinds, mappedKindInfo),
+			c.AliasMap,
+		)
+	}
+
+	v.file = f
+	v.mappedKinds = mappedKinds
+	v.mappedKindInfo = mappedKindInfo
+	return errors.Join(errs...)
 }
 
 // lookupMapKindReplacement finds a mapped replacement for rule kind `kind`, resolving transitively.
@@ -618,9 +1387,10 @@ func lookupMapKindReplacement(kindMap map[string]config.MappedKind, kind string)
 	return mapped, nil
 }
 
-func newFixUpdateConfiguration(wd string, args []string, cexts []config.Configurer) (*config.Config, error) {
+func newFixUpdateConfiguration(wd string, fsys fs.FS, args []string, cexts []config.Configurer) (*config.Config, error) {
 	c := config.New()
 	c.WorkDir = wd
+	c.FS = fsys
 
 	fs := flag.NewFlagSet("gazelle", flag.ContinueOnError)
 	// Flag will call this on any parse error. Don't print usage unless
@@ -639,28 +1409,226 @@ func newFixUpdateConfiguration(wd string, args []string, cexts []config.Configur
 		}
 	}
 
-	for _, cext := range cexts {
-		cext.RegisterFlags(fs, cmdName, c)
+	if err := RegisterFlags(fs, cmdName, c, cexts); err != nil {
+		return nil, configError{err}
+	}
+
+	// Parse flags from the configuration file first, so that flags on the
+	// command line take precedence.
+	configFile, err := loadConfigFile(wd, args)
+	if err != nil {
+		return nil, configError{err}
+	}
+	if configFile != nil {
+		// Flags set on the command line replace values from the configuration
+		// file rather than adding to them, so drop them from the file's flags.
+		// Otherwise repeatable flags like -known_import would collect values
+		// from both parses.
+		cliFlags, _ := splitFlagArgs(fs, args)
+		onCLI := make(map[string]bool)
+		for _, f := range cliFlags {
+			onCLI[f.name] = true
+			if fl := fs.Lookup(f.name); fl != nil {
+				if a, ok := fl.Value.(interface{ Alias() string }); ok && a.Alias() != "" {
+					onCLI[a.Alias()] = true
+				}
+			}
+		}
+		fileFlags, fileRest := splitFlagArgs(fs, configFile.Args())
+		var fileArgs []string
+		for _, f := range fileFlags {
+			if !onCLI[f.name] {
+				fileArgs = append(fileArgs, f.args...)
+			}
+		}
+		fileArgs = append(fileArgs, fileRest...)
+		if err := fs.Parse(fileArgs); err != nil {
+			return nil, configError{fmt.Errorf("%s: %v", configFile.Path, err)}
+		}
+		if fs.NArg() > 0 {
+			return nil, configError{fmt.Errorf("%s: unexpected positional arguments in flags: %q", configFile.Path, fs.Args())}
+		}
 	}
 
-	if err := fs.Parse(args); err != nil {
+	// Arguments from the gazelle macro and from the "bazel run" command line
+	// are parsed separately, so flags on the command line are recognized
+	// after directories from the macro.
+	uc := getUpdateConfig(c)
+	var cliStart int
+	uc.cliWorkDir, cliStart = cliWorkDir(len(args))
+	var macroDirs []string
+	err = fs.Parse(args[:cliStart])
+	if err == nil {
+		macroDirs = fs.Args()
+		err = fs.Parse(args[cliStart:])
+	}
+	if err != nil {
 		if err == flag.ErrHelp {
 			fixUpdateUsage(fs)
 			return nil, err
 		}
 		// flag already prints the error; don't print it again.
-		log.Fatal("Try -help for more information.")
+		return nil, configError{errors.New("Try -help for more information.")}
+	}
+	if len(macroDirs) > 0 {
+		// Leave all directories in fs.Args for CheckFlags.
+		uc.cliDirStart = len(macroDirs)
+		fs.Parse(append(append([]string{"--"}, macroDirs...), fs.Args()...))
 	}
 
 	for _, cext := range cexts {
-		if err := cext.CheckFlags(fs, c); err != nil {
+		if err := cext.CheckFlags(fs, c); errors.Is(err, errVersion) {
 			return nil, err
+		} else if err != nil {
+			return nil, configError{err}
+		}
+	}
+
+	if configFile != nil {
+		if c.ConfigFileDirectives, err = configFile.RuleDirectives(); err != nil {
+			return nil, configError{err}
 		}
+		c.LoadMigrations = configFile.LoadMigrations
 	}
 
 	return c, nil
 }
 
+// cliWorkDir returns the directory where "bazel run" was invoked and the
+// index of the first of numArgs arguments that were typed on its command
+// line. Directories among those arguments are interpreted relative to that
+// directory, so "bazel run //:gazelle -- ." updates the directory it's run in.
+//
+// The wrapper script generated by the gazelle macro adds arguments from the
+// macro before arguments from the command line. Directories among them are
+// still interpreted relative to the workspace directory. It sets
+// GAZELLE_NUM_CLI_ARGS to the number of arguments at the end, flags included,
+// that came from the command line. When the variable isn't set, all arguments
+// are assumed to come from the command line.
+func cliWorkDir(numArgs int) (string, int) {
+	wd := os.Getenv("BUILD_WORKING_DIRECTORY")
+	if wd == "" {
+		return "", numArgs
+	}
+	numCLIArgs := numArgs
+	if s, ok := os.LookupEnv("GAZELLE_NUM_CLI_ARGS"); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			slog.Warn("invalid GAZELLE_NUM_CLI_ARGS; interpreting paths relative to the workspace", "value", s)
+			return "", numArgs
+		}
+		numCLIArgs = min(n, numArgs)
+	}
+	return wd, numArgs - numCLIArgs
+}
+
+// RegisterFlags registers the flags of each configuration extension in fs.
+// Flags defined by languages are also available with the language name as a
+// prefix, like -go.prefix. RegisterFlags returns an error naming the
+// extensions involved if two of them define the same flag, unless both are
+// languages, in which case only the prefixed flags may be used.
+func RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config, cexts []config.Configurer) error {
+	ef := gzflag.NewExtensionFlags(fs)
+	for _, cext := range cexts {
+		owner, namespace := fmt.Sprintf("%T", cext), ""
+		if lang, ok := cext.(language.Language); ok {
+			owner, namespace = fmt.Sprintf("language %q", lang.Name()), lang.Name()
+		}
+		register := func(fs *flag.FlagSet) { cext.RegisterFlags(fs, cmd, c) }
+		if err := ef.Register(owner, namespace, register); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadConfigFile loads the configuration file named by the -config_file flag
+// in args, or the default configuration file in the repository root directory
+// if there is one. The flags in args haven't been parsed yet, so
+// loadConfigFile looks for -config_file and -repo_root on its own.
+// loadConfigFile returns nil if there is no configuration file.
+func loadConfigFile(wd string, args []string) (*v2config.ConfigFile, error) {
+	var configFilePath, repoRoot string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "config_file" && name != "repo_root" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		if name == "config_file" {
+			configFilePath = value
+		} else {
+			repoRoot = value
+		}
+	}
+
+	if configFilePath != "" {
+		if !filepath.IsAbs(configFilePath) {
+			configFilePath = filepath.Join(wd, configFilePath)
+		}
+		return v2config.LoadConfigFile(configFilePath)
+	}
+
+	switch {
+	case repoRoot != "":
+		if !filepath.IsAbs(repoRoot) {
+			repoRoot = filepath.Join(wd, repoRoot)
+		}
+	case os.Getenv("BUILD_WORKSPACE_DIRECTORY") != "":
+		repoRoot = os.Getenv("BUILD_WORKSPACE_DIRECTORY")
+	default:
+		var err error
+		if repoRoot, err = wspace.FindRepoRoot(wd); err != nil {
+			// CommonConfigurer.CheckFlags reports this.
+			return nil, nil
+		}
+	}
+	if configFilePath = v2config.FindConfigFile(repoRoot); configFilePath == "" {
+		return nil, nil
+	}
+	return v2config.LoadConfigFile(configFilePath)
+}
+
+// flagArg is a flag in a list of arguments, together with its value if the
+// value is a separate argument.
+type flagArg struct {
+	name string
+	args []string
+}
+
+// splitFlagArgs splits the flags at the beginning of args the same way fs
+// would parse them. It returns the flags and the remaining arguments,
+// starting with the first positional argument or "--".
+func splitFlagArgs(fs *flag.FlagSet, args []string) ([]flagArg, []string) {
+	var flags []flagArg
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		n := 1
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f.Value) && len(args) > 1 {
+			n = 2
+		}
+		flags = append(flags, flagArg{name: name, args: args[:n]})
+		args = args[n:]
+	}
+	return flags, args
+}
+
+func isBoolFlag(v flag.Value) bool {
+	b, ok := v.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
 // TODO(v2): Revise help text and all flag descriptions. This can mostly be
 // shared between v1 and v2, though we may need to change it in a few cases:
 // some flags may not be available or may have different defaults in v2,
@@ -681,6 +1649,8 @@ output mode determines what Gazelle does with updated BUILD files.
   fix (default) - write updated BUILD files back to disk.
   print - print updated BUILD files to stdout.
   diff - diff updated BUILD files against existing files in unified format.
+  json - print the rules in updated BUILD files as JSON.
+  list - print the directories whose BUILD files would change.
 
 Gazelle accepts a list of paths to Go package directories to process (defaults
 to the working directory if none are given). It recursively traverses
@@ -747,7 +1717,7 @@ func findOutputPath(c *config.Config, f *rule.File) string {
 	}
 	outputDir := filepath.Join(baseDir, filepath.FromSlash(f.Pkg))
 	defaultOutputPath := filepath.Join(outputDir, c.DefaultBuildFileName())
-	ents, err := os.ReadDir(outputDir)
+	ents, err := c.ReadDir(outputDir)
 	if err != nil {
 		// Ignore error. Directory probably doesn't exist.
 		return defaultOutputPath
@@ -759,6 +1729,39 @@ func findOutputPath(c *config.Config, f *rule.File) string {
 	return outputPath
 }
 
+// checkRemoteCacheFlags validates -remote_cache_ttl and resolves
+// -remote_cache_dir, defaulting to a directory in the user cache directory.
+func checkRemoteCacheFlags(dir *string, ttl time.Duration, workDir string) error {
+	if ttl < 0 {
+		return fmt.Errorf("-remote_cache_ttl must not be negative")
+	}
+	if ttl == 0 {
+		return nil
+	}
+	if *dir == "" {
+		defaultDir, err := repo.DefaultDiskCacheDir()
+		if err != nil {
+			return fmt.Errorf("-remote_cache_ttl set but -remote_cache_dir is not: %v", err)
+		}
+		*dir = defaultDir
+	} else if !filepath.IsAbs(*dir) {
+		*dir = filepath.Join(workDir, *dir)
+	}
+	return nil
+}
+
+// newRemoteCache creates a RemoteCache for repositories in the workspace,
+// which persists lookups on disk if -remote_cache_ttl is set and uses
+// redirects from -remote_redirects.
+func newRemoteCache(uc *updateConfig) (*repo.RemoteCache, func() error) {
+	rc, cleanup := repo.NewRemoteCache(uc.repos)
+	if uc.remoteCacheTTL > 0 {
+		rc.UseDiskCache(uc.remoteCacheDir, uc.remoteCacheTTL)
+	}
+	rc.UseRedirects(uc.redirects)
+	return rc, cleanup
+}
+
 // maybePopulateRemoteCacheFromGoMod reads go.mod and adds a root to rc for each
 // module requirement. This lets the Go extension avoid a network lookup for
 // unknown imports with -external=external, and it lets dependency resolution
@@ -811,6 +1814,93 @@ func unionKindInfoMaps(a, b map[string]rule.KindInfo) map[string]rule.KindInfo {
 	return result
 }
 
+// assignWrappedKinds records which kind each call to a wrapper macro in f
+// stands for, for macros that may wrap more than one kind. A call stands for
+// the kind of the generated or empty rule with the same name, if that kind is
+// one of the kinds the macro wraps. Other calls are treated as the first
+// wrapped kind, through c.AliasMap.
+func assignWrappedKinds(c *config.Config, f *rule.File, gen, empty []*rule.Rule) {
+	if len(c.WrapperMacros) == 0 {
+		return
+	}
+	kindsByName := make(map[string][]string)
+	for _, r := range gen {
+		kindsByName[r.Name()] = append(kindsByName[r.Name()], r.Kind())
+	}
+	for _, r := range empty {
+		kindsByName[r.Name()] = append(kindsByName[r.Name()], r.Kind())
+	}
+	for _, r := range f.Rules {
+		wrapped := c.WrapperMacros[r.Kind()]
+		if len(wrapped) < 2 {
+			continue
+		}
+		for _, kind := range kindsByName[r.Name()] {
+			if slices.Contains(wrapped, kind) {
+				r.SetPrivateAttr(merger.WrappedKindKey, kind)
+				break
+			}
+		}
+	}
+}
+
+// unmapKindAttrs renames attributes of rules in f with a mapped kind back to
+// the names used by the original kind, so that language extensions and the
+// merger see the attributes they expect. mapKindAttrs reverses this before
+// the file is written.
+func unmapKindAttrs(c *config.Config, f *rule.File) {
+	for _, r := range f.Rules {
+		attrs, ok := attrMappingForRule(c, r)
+		if !ok {
+			continue
+		}
+		for from, to := range attrs.Renamed {
+			r.RenameAttr(to, from)
+		}
+	}
+}
+
+// mapKindAttrs renames and drops attributes of rules in f with a mapped kind,
+// as configured with map_kind.
+func mapKindAttrs(c *config.Config, f *rule.File) {
+	for _, r := range f.Rules {
+		attrs, ok := attrMappingForRule(c, r)
+		if !ok {
+			continue
+		}
+		for from, to := range attrs.Renamed {
+			r.RenameAttr(from, to)
+		}
+		if r.ShouldKeep() {
+			continue
+		}
+		for _, attr := range attrs.Dropped {
+			r.DelAttr(attr)
+		}
+	}
+}
+
+// attrMappingForRule returns the kind mapping that renames or drops attributes
+// of r, if there is one. Rules whose kind is an alias of a mapped kind are
+// treated like rules of the mapped kind.
+func attrMappingForRule(c *config.Config, r *rule.Rule) (v2config.MappedAttrs, bool) {
+	kind := r.Kind()
+	if underlying, ok := c.AliasMap[kind]; ok {
+		kind = underlying
+	}
+	fromKinds := make([]string, 0, len(c.KindAttrMap))
+	for fromKind := range c.KindAttrMap {
+		fromKinds = append(fromKinds, fromKind)
+	}
+	sort.Strings(fromKinds)
+	for _, fromKind := range fromKinds {
+		if c.KindMap[fromKind].KindName == kind {
+			return c.KindAttrMap[fromKind], true
+		}
+	}
+	return v2config.MappedAttrs{}, false
+}
+
 // applyKindMappings returns a copy of LoadInfo that includes c.KindMap.
 func applyKindMappings(mappedKinds []config.MappedKind, loads []rule.LoadInfo) []rule.LoadInfo {
 	if len(mappedKinds) == 0 {
@@ -831,7 +1921,7 @@ func appendOrMergeKindMapping(mappedLoads []rule.LoadInfo, mappedKind config.Map
 	// If mappedKind.KindLoad already exists in the list, create a merged copy.
 	for i, load := range mappedLoads {
 		if load.Name == mappedKind.KindLoad {
-			mappedLoads[i].Symbols = append(load.Symbols, mappedKind.KindName)
+			mappedLoads[i].Symbols = append(slices.Clip(load.Symbols), mappedKind.KindName)
 			return mappedLoads
 		}
 	}
@@ -851,24 +1941,27 @@ func isDirErr(err error) bool {
 // filterLanguages returns the subset of input languages that pass the config's
 // filter, if any. Gazelle should not generate rules for languages not returned.
 func filterLanguages(c *config.Config, langs []language.Language) []language.Language {
-	if len(c.Langs) == 0 {
+	if len(c.Langs) == 0 && len(c.DisabledLangs) == 0 {
 		return langs
 	}
 
 	var result []language.Language
 	for _, inputLang := range langs {
-		if containsLang(c.Langs, inputLang) {
+		if c.LangEnabled(inputLang.Name()) {
 			result = append(result, inputLang)
 		}
 	}
 	return result
 }
 
-func containsLang(langNames []string, lang language.Language) bool {
-	for _, langName := range langNames {
-		if langName == lang.Name() {
-			return true
+// loadMigrations returns the load migrations from the configuration file,
+// followed by those declared by languages enabled in c.
+func loadMigrations(c *config.Config, languages []language.Language) []rule.LoadMigration {
+	migrations := append([]rule.LoadMigration(nil), c.LoadMigrations...)
+	for _, l := range filterLanguages(c, languages) {
+		if lm, ok := l.(language.LoadMigrator); ok {
+			migrations = append(migrations, lm.LoadMigrations()...)
 		}
 	}
-	return false
+	return migrations
 }
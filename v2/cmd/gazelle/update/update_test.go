@@ -0,0 +1,402 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/repo"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/walk"
+	bzl "github.com/bazelbuild/buildtools/build"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCLIDirs(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "a/"},
+		{Path: "sub/b/"},
+	})
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc, workingDir, numCLIArgs string
+		args, want                   []string
+	}{
+		{
+			desc: "no_bazel_run",
+			args: []string{"a", "."},
+			want: []string{"a", ""},
+		},
+		{
+			desc:       "all_cli",
+			workingDir: "sub",
+			args:       []string{"-r=false", "b", "."},
+			want:       []string{"sub/b", "sub"},
+		},
+		{
+			desc:       "macro_and_cli",
+			workingDir: "sub",
+			numCLIArgs: "1",
+			args:       []string{"-index=none", "a", "b"},
+			want:       []string{"a", "sub/b"},
+		},
+		{
+			desc:       "macro_flags_only",
+			workingDir: "sub",
+			numCLIArgs: "1",
+			args:       []string{"-index=none", "."},
+			want:       []string{"sub"},
+		},
+		{
+			desc:       "macro_dirs_and_cli_flags",
+			workingDir: "sub",
+			numCLIArgs: "2",
+			args:       []string{"-index=none", "a", "-r=false", "b"},
+			want:       []string{"a", "sub/b"},
+		},
+		{
+			desc:       "macro_dirs_cli_flags_only",
+			workingDir: "sub",
+			numCLIArgs: "1",
+			args:       []string{"a", "-r=false"},
+			want:       []string{"a"},
+		},
+		{
+			desc:       "no_cli_args",
+			workingDir: "sub",
+			numCLIArgs: "0",
+			args:       []string{"a"},
+			want:       []string{"a"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.workingDir != "" {
+				t.Setenv("BUILD_WORKING_DIRECTORY", filepath.Join(dir, tc.workingDir))
+			} else {
+				t.Setenv("BUILD_WORKING_DIRECTORY", "")
+			}
+			if tc.numCLIArgs != "" {
+				t.Setenv("GAZELLE_NUM_CLI_ARGS", tc.numCLIArgs)
+			}
+			cexts := []config.Configurer{
+				&config.CommonConfigurer{},
+				&updateConfigurer{},
+				&walk.Configurer{},
+				&resolve.Configurer{},
+			}
+			c, err := newFixUpdateConfiguration(dir, nil, append([]string{"-repo_root", dir}, tc.args...), cexts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range getUpdateConfig(c).dirs {
+				rel, err := filepath.Rel(dir, d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rel == "." {
+					rel = ""
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("dirs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigFileRepeatedFlags(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path:    "gazelle.json",
+			Content: `{"flags": ["-known_import", "example.com/file", "-index=none"]}`,
+		},
+	})
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc       string
+		args, want []string
+	}{
+		{
+			desc: "file_only",
+			want: []string{"example.com/file"},
+		},
+		{
+			desc: "cli_replaces_file",
+			args: []string{"-known_import=example.com/a", "-known_import", "example.com/b"},
+			want: []string{"example.com/a", "example.com/b"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ucr := &updateConfigurer{}
+			cexts := []config.Configurer{
+				&config.CommonConfigurer{},
+				ucr,
+				&walk.Configurer{},
+				&resolve.Configurer{},
+			}
+			if _, err := newFixUpdateConfiguration(dir, nil, append([]string{"-repo_root", dir}, tc.args...), cexts); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, ucr.knownImports); diff != "" {
+				t.Errorf("known imports (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// taggingProtoLang is the proto extension with a PostResolver that tags
+// rules with their number of deps and records the packages it saw.
+type taggingProtoLang struct {
+	language.Language
+	mu   sync.Mutex
+	rels []string
+}
+
+func (l *taggingProtoLang) ApparentLoads(moduleToApparentName func(string) string) []rule.LoadInfo {
+	return l.Language.(language.ModuleAwareLanguage).ApparentLoads(moduleToApparentName)
+}
+
+func (l *taggingProtoLang) AfterResolve(args language.AfterResolveArgs) error {
+	l.mu.Lock()
+	l.rels = append(l.rels, args.Rel)
+	l.mu.Unlock()
+	for _, r := range args.Rules {
+		r.SetAttr("tags", []string{fmt.Sprintf("deps=%d", len(r.AttrStrings("deps")))})
+	}
+	if args.Rel == "bad" {
+		return errors.New("invalid package")
+	}
+	return nil
+}
+
+func TestAfterResolve(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "a/a.proto", Content: `syntax = "proto3";`},
+		{Path: "b/b.proto", Content: `syntax = "proto3";`},
+		{
+			Path: "c/c.proto",
+			Content: `syntax = "proto3";
+import "a/a.proto";
+import "b/b.proto";
+`,
+		},
+		{Path: "bad/bad.proto", Content: `syntax = "proto3";`},
+		{Path: "off/BUILD.bazel", Content: "# gazelle:lang go\n"},
+		{Path: "off/off.proto", Content: `syntax = "proto3";`},
+	})
+	defer cleanup()
+
+	lang := &taggingProtoLang{Language: proto.NewLanguage()}
+	rep := &Report{Logger: slog.New(slog.DiscardHandler)}
+	defer func(l *slog.Logger) { slog.SetDefault(l) }(slog.Default())
+	if err := Run(WithReport(context.Background(), rep), []language.Language{lang}, dir, []string{"-repo_root", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(lang.rels)
+	if diff := cmp.Diff([]string{"a", "b", "bad", "c"}, lang.rels); diff != "" {
+		t.Errorf("packages (-want +got):\n%s", diff)
+	}
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "c/BUILD.bazel",
+		Content: `
+load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+
+proto_library(
+    name = "c_proto",
+    srcs = ["c.proto"],
+    tags = ["deps=2"],
+    visibility = ["//visibility:public"],
+    deps = [
+        "//a:a_proto",
+        "//b:b_proto",
+    ],
+)
+`,
+	}})
+	wantDiags := []ReportDiagnostic{{
+		Level:   slog.LevelError,
+		Message: "invalid package",
+		Path:    filepath.Join(dir, "bad", "BUILD.bazel"),
+	}}
+	if diff := cmp.Diff(wantDiags, rep.Diagnostics); diff != "" {
+		t.Errorf("diagnostics (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{
+			Path: "gazelle.json",
+			Content: `{
+  "load_migrations": [
+    {"from": "//build:defs.bzl", "symbol": "lib", "to": "//build:lib.bzl"}
+  ]
+}`,
+		},
+		{
+			Path: "a/BUILD.bazel",
+			Content: `load("//build:defs.bzl", "bin", "lib")
+
+lib(name = "a")
+
+bin(name = "a_bin")
+`,
+		},
+	})
+	defer cleanup()
+
+	langs := []language.Language{proto.NewLanguage()}
+	if err := Run(context.Background(), langs, dir, []string{"-repo_root", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CheckFiles(t, dir, []testtools.FileSpec{{
+		Path: "a/BUILD.bazel",
+		Content: `
+load("//build:defs.bzl", "bin")
+load("//build:lib.bzl", "lib")
+
+lib(name = "a")
+
+bin(name = "a_bin")
+`,
+	}})
+}
+
+// validatingProtoLang is the proto extension with a validator that requires
+// proto_library rules to have one source.
+type validatingProtoLang struct {
+	language.Language
+}
+
+func (l *validatingProtoLang) ApparentLoads(moduleToApparentName func(string) string) []rule.LoadInfo {
+	return l.Language.(language.ModuleAwareLanguage).ApparentLoads(moduleToApparentName)
+}
+
+func (l *validatingProtoLang) Kinds() map[string]rule.KindInfo {
+	kinds := make(map[string]rule.KindInfo)
+	for kind, info := range l.Language.Kinds() {
+		kinds[kind] = info
+	}
+	info := kinds["proto_library"]
+	info.Validators = map[string]func(*rule.Rule, bzl.Expr) error{
+		"srcs": func(r *rule.Rule, _ bzl.Expr) error {
+			if len(r.AttrStrings("srcs")) != 1 {
+				return errors.New("must have exactly one source")
+			}
+			return nil
+		},
+	}
+	kinds["proto_library"] = info
+	return kinds
+}
+
+func TestInvalidAttrs(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "WORKSPACE"},
+		{Path: "a/a.proto", Content: `syntax = "proto3";`},
+		{Path: "b/b1.proto", Content: `syntax = "proto3";`},
+		{Path: "b/b2.proto", Content: `syntax = "proto3";`},
+	})
+	defer cleanup()
+
+	// Invalid attributes are reported without -strict, after build files are
+	// written.
+	langs := []language.Language{&validatingProtoLang{Language: proto.NewLanguage()}}
+	err := Run(context.Background(), langs, dir, []string{"-repo_root", dir})
+	if got := ExitCode(err); got != ExitConfig {
+		t.Errorf("got exit code %d (%v); want %d", got, err, ExitConfig)
+	}
+	want := `found 1 problem:
+invalid attributes (1):
+	` + filepath.Join(dir, "b", "BUILD.bazel") + `: proto_library "b_proto": srcs: must have exactly one source`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error:\n%v\nwant:\n%s", err, want)
+	}
+	for _, path := range []string{"a/BUILD.bazel", "b/BUILD.bazel"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("build file not written: %v", err)
+		}
+	}
+}
+
+// serialProtoLang is the proto extension without ResolvesConcurrently. It
+// records the largest number of Resolve calls running at once.
+type serialProtoLang struct {
+	language.Language
+	running, maxRunning atomic.Int32
+}
+
+func (l *serialProtoLang) ApparentLoads(moduleToApparentName func(string) string) []rule.LoadInfo {
+	return l.Language.(language.ModuleAwareLanguage).ApparentLoads(moduleToApparentName)
+}
+
+func (l *serialProtoLang) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, r *rule.Rule, imports interface{}, from label.Label) {
+	n := l.running.Add(1)
+	defer l.running.Add(-1)
+	for {
+		max := l.maxRunning.Load()
+		if n <= max || l.maxRunning.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	l.Language.Resolve(c, ix, rc, r, imports, from)
+}
+
+func TestSerialResolve(t *testing.T) {
+	files := []testtools.FileSpec{{Path: "WORKSPACE"}}
+	for i := range 16 {
+		files = append(files, testtools.FileSpec{Path: fmt.Sprintf("p%d/p.proto", i), Content: `syntax = "proto3";`})
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	// Languages that don't implement ConcurrentResolver are resolved one rule
+	// at a time, regardless of -jobs.
+	lang := &serialProtoLang{Language: proto.NewLanguage()}
+	if err := Run(context.Background(), []language.Language{lang}, dir, []string{"-repo_root", dir, "-jobs=8"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := lang.maxRunning.Load(); got != 1 {
+		t.Errorf("got %d concurrent Resolve calls; want 1", got)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/bazel-contrib/bazel-gazelle/v2/config"
 	"github.com/bazel-contrib/bazel-gazelle/v2/language"
+	"github.com/bazel-contrib/bazel-gazelle/v2/merger"
 	"github.com/bazel-contrib/bazel-gazelle/v2/resolve"
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
 	configv1 "github.com/bazelbuild/bazel-gazelle/config"
@@ -205,6 +206,9 @@ type fixerAdapter struct {
 
 func (f fixerAdapter) Fix(ctx context.Context, args language.FixArgs) error {
 	f.v1.Fix(args.Config, args.File)
+	if lm, ok := f.v1.(languagev1.LoadMigrator); ok {
+		merger.MigrateLoads(args.File, lm.LoadMigrations(), args.Config.ModuleToApparentName)
+	}
 	return nil
 }
 
@@ -30,8 +30,10 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/bazel-contrib/bazel-gazelle/v2/internal/module"
@@ -78,8 +80,9 @@ type Config struct {
 	// usage of deprecated rules.
 	ShouldFix bool
 
-	// Strict determines how Gazelle handles build file and directive errors. When
-	// set, Gazelle will exit with non-zero value after logging such errors.
+	// Strict determines how Gazelle handles build file and directive errors,
+	// merge failures, and unresolved imports. When set, Gazelle collects them
+	// and exits with a non-zero value and a summary at the end of the run.
 	Strict bool
 
 	// IndexLibraries determines whether Gazelle should build an index of
@@ -91,17 +94,34 @@ type Config struct {
 	// When false, Gazelle indexes all directories.
 	IndexLazy bool
 
+	// Jobs is the maximum number of operations, like reading directories,
+	// that Gazelle and extensions should run concurrently. Zero means a
+	// default chosen by the operation.
+	Jobs int
+
 	// KindMap maps from a kind name to its replacement. It provides a way for
 	// users to customize the kind of rules created by Gazelle, via
 	// # gazelle:map_kind.
 	KindMap map[string]MappedKind
 
+	// KindAttrMap maps from a kind name in KindMap to the attributes renamed
+	// or dropped by its replacement, for replacements with a different
+	// signature. Kinds replaced without attribute changes are not listed.
+	KindAttrMap map[string]MappedAttrs
+
 	// AliasMap maps a wrapper macro name to the kind of rule that it wraps.
 	// It provides a way for users to define custom macros that generate rules
 	// that are understood by gazelle, while still allowing gazelle to update
-	// the attrs for the macro calls. Configured via # gazelle:macro.
+	// the attrs for the macro calls. Configured via # gazelle:alias_kind
+	// and # gazelle:wrapper_macro.
 	AliasMap map[string]string
 
+	// WrapperMacros maps a wrapper macro name to the kinds of rules that it
+	// may wrap, for macros declared with # gazelle:wrapper_macro. AliasMap
+	// maps each of these macros to the first kind, which is used when Gazelle
+	// can't tell which kind a particular call wraps.
+	WrapperMacros map[string][]string
+
 	// Repos is a list of repository rules declared in the main WORKSPACE file
 	// or in macros called by the main WORKSPACE file. This may affect rule
 	// generation and dependency resolution.
@@ -111,6 +131,35 @@ type Config struct {
 	// An empty list means "all languages".
 	Langs []string
 
+	// DisabledLangs is a list of language names which Gazelle should not
+	// process, even if they're listed in Langs. This is set by "-name"
+	// entries in the lang directive or flag.
+	DisabledLangs []string
+
+	// ThirdParty is true in directories marked with # gazelle:third_party and
+	// their subdirectories. Gazelle treats code there as imported from
+	// elsewhere: it doesn't generate tests, new rules are public, and
+	// ThirdPartyTags are added to the tags of new rules.
+	ThirdParty     bool
+	ThirdPartyTags []string
+
+	// ConfigFileDirectives is a list of directives read from the repository's
+	// configuration file (see ConfigFile). They are applied in the repository
+	// root directory before directives in the root build file.
+	ConfigFileDirectives []rule.Directive
+
+	// LoadMigrations is a list of load migrations read from the repository's
+	// configuration file. They take precedence over migrations declared by
+	// languages.
+	LoadMigrations []rule.LoadMigration
+
+	// directiveErrs collects errors reported with ReportDirectiveError. It's
+	// shared with clones.
+	directiveErrs *directiveErrors
+
+	// parsed caches results of ParseFile. It's shared with clones.
+	parsed *parseCache
+
 	// Exts is a set of configurable extensions. Generally, each language
 	// has its own set of extensions, but other modules may provide their own
 	// extensions as well. Values in here may be populated by command line
@@ -124,6 +173,25 @@ type Config struct {
 	// to the apparent name (repo_name) specified in the MODULE.bazel file. It
 	// returns the empty string if the module is not found.
 	ModuleToApparentName func(string) string
+
+	// RepoMapping maps the canonical names of repositories to the apparent
+	// names they have in this repository. It's read from the file given with
+	// -repo_mapping and may be nil. Use ApparentRepoName and ApparentLabel to
+	// find the names labels should use.
+	RepoMapping map[string]string
+
+	// repoMappingNames is the set of apparent names in RepoMapping.
+	repoMappingNames map[string]bool
+
+	// FS, if set, is the file system Gazelle reads the repository from
+	// instead of the OS file system, for example an in-memory tree or an
+	// overlay of edited files. It's rooted at RepoRoot. When FS is set,
+	// RepoRoot defaults to WorkDir, and it doesn't need to exist on the OS
+	// file system. Read files with the ReadDir, ReadFile, Open, and Stat
+	// methods so that FS is used when it's set. Build files are still written
+	// to the OS file system, and files named by flags, like -config_file and
+	// -repo_config, are still read from it.
+	FS fs.FS
 }
 
 // MappedKind describes a replacement to use for a built-in kind.
@@ -131,10 +199,24 @@ type MappedKind struct {
 	FromKind, KindName, KindLoad string
 }
 
+// MappedAttrs describes how the attributes of a kind are changed when it's
+// replaced with a kind that has a different signature.
+type MappedAttrs struct {
+	// Renamed maps attribute names used by the original kind to the names
+	// used by the replacement. Attributes not listed here keep their names.
+	Renamed map[string]string
+
+	// Dropped lists attributes of the original kind that the replacement does
+	// not accept. These attributes are removed from rules of the replacement.
+	Dropped []string
+}
+
 func New() *Config {
 	return &Config{
 		ValidBuildFileNames: DefaultValidBuildFileNames,
 		Exts:                make(map[string]any),
+		directiveErrs:       &directiveErrors{},
+		parsed:              &parseCache{byDir: make(map[string]map[parseKey]*parseEntry)},
 	}
 }
 
@@ -151,10 +233,18 @@ func (c *Config) Clone() *Config {
 	for k, v := range c.KindMap {
 		cc.KindMap[k] = v
 	}
+	cc.KindAttrMap = make(map[string]MappedAttrs)
+	for k, v := range c.KindAttrMap {
+		cc.KindAttrMap[k] = v
+	}
 	cc.AliasMap = make(map[string]string)
 	for k, v := range c.AliasMap {
 		cc.AliasMap[k] = v
 	}
+	cc.WrapperMacros = make(map[string][]string)
+	for k, v := range c.WrapperMacros {
+		cc.WrapperMacros[k] = v
+	}
 	return &cc
 }
 
@@ -210,8 +300,10 @@ type ConfigureArgs struct {
 type CommonConfigurer struct {
 	repoRoot                          string
 	indexLibraries, indexLazy, strict bool
+	jobs                              int
 	langCsv                           string
 	bzlmod                            bool
+	repoMappingPath                   string
 }
 
 var _ Configurer = (*CommonConfigurer)(nil)
@@ -225,14 +317,18 @@ func (cc *CommonConfigurer) RegisterFlags(fs *flag.FlagSet, cmd string, c *Confi
 	cc.indexLazy = false
 	fs.StringVar(&cc.repoRoot, "repo_root", "", "path to a directory which corresponds to go_prefix, otherwise gazelle searches for it.")
 	fs.Var(indexFlag{indexLibraries: &cc.indexLibraries, indexLazy: &cc.indexLazy}, "index", "determines how Gazelle indexes library rules. 'all' means index all libraries in all repo directories. 'lazy' means specific directories, determined by extensions. 'none' means indexing is disabled.")
-	fs.BoolVar(&cc.strict, "strict", false, "when true, gazelle will exit with none-zero value for build file syntax errors or unknown directives")
+	fs.BoolVar(&cc.strict, "strict", false, "when true, gazelle will exit with a non-zero value and a summary of build file syntax errors, invalid directives, merge failures, and unresolved imports")
+	fs.IntVar(&cc.jobs, "jobs", 0, "maximum number of concurrent operations, like reading directories. 0 chooses a default")
 	fs.StringVar(&cc.langCsv, "lang", "", "if non-empty, process only these languages (e.g. \"go,proto\")")
 	fs.BoolVar(&cc.bzlmod, "bzlmod", false, "for internal usage only")
+	fs.StringVar(&cc.repoMappingPath, "repo_mapping", "", "path to a repository mapping file, in the format of Bazel's _repo_mapping runfiles file, used to write labels with the apparent names of external repositories")
 }
 
 func (cc *CommonConfigurer) CheckFlags(fs *flag.FlagSet, c *Config) error {
 	var err error
-	if cc.repoRoot == "" {
+	if cc.repoRoot == "" && c.FS != nil {
+		cc.repoRoot = c.WorkDir
+	} else if cc.repoRoot == "" {
 		if wsDir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); wsDir != "" {
 			cc.repoRoot = wsDir
 		} else if parent, err := wspace.FindRepoRoot(c.WorkDir); err == nil {
@@ -246,9 +342,13 @@ func (cc *CommonConfigurer) CheckFlags(fs *flag.FlagSet, c *Config) error {
 	} else {
 		c.RepoRoot = filepath.Join(c.WorkDir, cc.repoRoot)
 	}
-	c.RepoRoot, err = filepath.EvalSymlinks(c.RepoRoot)
-	if err != nil {
-		return fmt.Errorf("%s: failed to resolve symlinks: %v", cc.repoRoot, err)
+	if c.FS == nil {
+		c.RepoRoot, err = filepath.EvalSymlinks(c.RepoRoot)
+		if err != nil {
+			return fmt.Errorf("%s: failed to resolve symlinks: %v", cc.repoRoot, err)
+		}
+	} else {
+		c.RepoRoot = filepath.Clean(c.RepoRoot)
 	}
 	c.RepoName, err = extractRepositoryName(c.RepoRoot)
 	if err != nil {
@@ -257,19 +357,37 @@ func (cc *CommonConfigurer) CheckFlags(fs *flag.FlagSet, c *Config) error {
 	c.IndexLibraries = cc.indexLibraries
 	c.IndexLazy = cc.indexLazy
 	c.Strict = cc.strict
+	if cc.jobs < 0 {
+		return fmt.Errorf("-jobs must not be negative, got %d", cc.jobs)
+	}
+	c.Jobs = cc.jobs
 	if len(cc.langCsv) > 0 {
-		c.Langs = strings.Split(cc.langCsv, ",")
+		c.Langs, c.DisabledLangs = parseLangs(nil, nil, cc.langCsv)
 	}
 	c.Bzlmod = cc.bzlmod
 	c.ModuleToApparentName, err = module.ExtractModuleToApparentNameMapping(c.RepoRoot)
 	if err != nil {
 		return fmt.Errorf("failed to parse MODULE.bazel: %v", err)
 	}
+	if cc.repoMappingPath != "" {
+		path := cc.repoMappingPath
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.WorkDir, path)
+		}
+		c.RepoMapping, err = readRepoMapping(c, path, repoMappingSource(c.RepoRoot))
+		if err != nil {
+			return fmt.Errorf("-repo_mapping: %v", err)
+		}
+		c.repoMappingNames = make(map[string]bool, len(c.RepoMapping))
+		for _, name := range c.RepoMapping {
+			c.repoMappingNames[name] = true
+		}
+	}
 	return nil
 }
 
 func (cc *CommonConfigurer) KnownDirectives() []string {
-	return []string{"map_kind", "alias_kind", "lang"}
+	return []string{"map_kind", "alias_kind", "wrapper_macro", "lang", "reset", "third_party"}
 }
 
 func (cc *CommonConfigurer) Configure(ctx context.Context, args ConfigureArgs) error {
@@ -280,46 +398,300 @@ func (cc *CommonConfigurer) Configure(ctx context.Context, args ConfigureArgs) e
 		switch d.Key {
 		case "map_kind":
 			vals := strings.Fields(d.Value)
-			if len(vals) != 3 {
-				return fmt.Errorf("expected three arguments (gazelle:map_kind from_kind to_kind load_file), got %v", vals)
+			if len(vals) < 3 {
+				return NewDirectiveError(args.File, d, fmt.Errorf("expected at least three arguments (gazelle:map_kind from_kind to_kind load_file [attr=new_attr...] [-attr...]), got %v", vals))
 			}
-			if args.Config.KindMap == nil {
-				args.Config.KindMap = make(map[string]MappedKind)
-			}
-			args.Config.KindMap[vals[0]] = MappedKind{
+			mapped := MappedKind{
 				FromKind: vals[0],
 				KindName: vals[1],
 				KindLoad: vals[2],
 			}
+			var attrs MappedAttrs
+			for _, v := range vals[3:] {
+				if attr, ok := strings.CutPrefix(v, "-"); ok && attr != "" {
+					attrs.Dropped = append(attrs.Dropped, attr)
+				} else if from, to, ok := strings.Cut(v, "="); ok && from != "" && to != "" {
+					if attrs.Renamed == nil {
+						attrs.Renamed = make(map[string]string)
+					}
+					attrs.Renamed[from] = to
+				} else {
+					return NewDirectiveError(args.File, d, fmt.Errorf("map_kind %s: invalid attribute mapping %q: expected attr=new_attr or -attr", vals[0], v))
+				}
+			}
+			if args.Config.KindMap == nil {
+				args.Config.KindMap = make(map[string]MappedKind)
+			}
+			args.Config.KindMap[vals[0]] = mapped
+			if args.Config.KindAttrMap == nil {
+				args.Config.KindAttrMap = make(map[string]MappedAttrs)
+			}
+			if len(attrs.Renamed) > 0 || len(attrs.Dropped) > 0 {
+				args.Config.KindAttrMap[vals[0]] = attrs
+			} else {
+				delete(args.Config.KindAttrMap, vals[0])
+			}
 
 		case "alias_kind":
 			vals := strings.Fields(d.Value)
 			if len(vals) != 2 {
-				return fmt.Errorf("expected two arguments (gazelle:alias_kind alias_kind underlying_kind), got %v", vals)
+				return NewDirectiveError(args.File, d, fmt.Errorf("expected two arguments (gazelle:alias_kind alias_kind underlying_kind), got %v", vals))
 			}
 
 			aliasName := vals[0]
 			underlyingKind := vals[1]
 			if aliasName == underlyingKind {
-				return fmt.Errorf("alias_kind: alias kind %q is the same as the underlying kind %q", aliasName, underlyingKind)
+				return NewDirectiveError(args.File, d, fmt.Errorf("alias_kind: alias kind %q is the same as the underlying kind %q", aliasName, underlyingKind))
 			}
 
 			if args.Config.AliasMap == nil {
 				args.Config.AliasMap = make(map[string]string)
 			}
 			args.Config.AliasMap[aliasName] = underlyingKind
+			delete(args.Config.WrapperMacros, aliasName)
 
-		case "lang":
-			if len(d.Value) > 0 {
-				args.Config.Langs = strings.Split(d.Value, ",")
-			} else {
+		case "wrapper_macro":
+			vals := strings.Fields(d.Value)
+			if len(vals) != 2 {
+				return NewDirectiveError(args.File, d, fmt.Errorf("expected two arguments (gazelle:wrapper_macro macro_name kind1,kind2,...), got %v", vals))
+			}
+			macroName := vals[0]
+			var kinds []string
+			for _, kind := range strings.Split(vals[1], ",") {
+				if kind == "" {
+					continue
+				}
+				if kind == macroName {
+					return NewDirectiveError(args.File, d, fmt.Errorf("wrapper_macro: macro %q can't wrap itself", macroName))
+				}
+				kinds = append(kinds, kind)
+			}
+			if len(kinds) == 0 {
+				return NewDirectiveError(args.File, d, fmt.Errorf("wrapper_macro: no wrapped kinds given for macro %q", macroName))
+			}
+			if args.Config.AliasMap == nil {
+				args.Config.AliasMap = make(map[string]string)
+			}
+			if args.Config.WrapperMacros == nil {
+				args.Config.WrapperMacros = make(map[string][]string)
+			}
+			args.Config.AliasMap[macroName] = kinds[0]
+			args.Config.WrapperMacros[macroName] = kinds
+
+		case "reset":
+			if ResetsDirective(d, "map_kind") {
+				args.Config.KindMap = make(map[string]MappedKind)
+				args.Config.KindAttrMap = make(map[string]MappedAttrs)
+			}
+			if ResetsDirective(d, "alias_kind") {
+				aliasMap := make(map[string]string)
+				for name := range args.Config.WrapperMacros {
+					aliasMap[name] = args.Config.AliasMap[name]
+				}
+				args.Config.AliasMap = aliasMap
+			}
+			if ResetsDirective(d, "wrapper_macro") {
+				for name := range args.Config.WrapperMacros {
+					delete(args.Config.AliasMap, name)
+				}
+				args.Config.WrapperMacros = make(map[string][]string)
+			}
+			if ResetsDirective(d, "lang") {
 				args.Config.Langs = nil
+				args.Config.DisabledLangs = nil
 			}
+			if ResetsDirective(d, "third_party") {
+				args.Config.ThirdParty = false
+				args.Config.ThirdPartyTags = nil
+			}
+
+		case "lang":
+			args.Config.Langs, args.Config.DisabledLangs = parseLangs(args.Config.Langs, args.Config.DisabledLangs, d.Value)
+
+		case "third_party":
+			args.Config.ThirdParty, args.Config.ThirdPartyTags = parseThirdParty(d.Value)
 		}
 	}
 	return nil
 }
 
+// defaultThirdPartyTag is the tag added to rules generated in directories
+// marked with a # gazelle:third_party directive that doesn't list tags.
+const defaultThirdPartyTag = "third_party"
+
+// parseThirdParty parses the value of a third_party directive: empty to mark
+// a subtree as third-party code with the default tag, "off" to unmark it, or
+// a comma-separated list of tags to add instead of the default.
+func parseThirdParty(value string) (bool, []string) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return true, []string{defaultThirdPartyTag}
+	case "off":
+		return false, nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return true, tags
+}
+
+// UnderlyingKind returns the kind of rule that language extensions know
+// rules of the given kind by. It undoes alias_kind and wrapper_macro
+// directives, then map_kind directives, following chains of mappings back to
+// the original kind. For example, with "# gazelle:map_kind go_library
+// my_library //:my.bzl", UnderlyingKind("my_library") returns "go_library".
+// kind is returned unchanged if it's not an alias or a mapped kind.
+//
+// A wrapper macro that may wrap several kinds is treated as the first kind.
+func (c *Config) UnderlyingKind(kind string) string {
+	if wrapped, ok := c.AliasMap[kind]; ok {
+		kind = wrapped
+	}
+	if len(c.KindMap) == 0 {
+		return kind
+	}
+	fromKinds := make([]string, 0, len(c.KindMap))
+	for fromKind := range c.KindMap {
+		fromKinds = append(fromKinds, fromKind)
+	}
+	sort.Strings(fromKinds)
+	seen := map[string]bool{kind: true}
+	for {
+		next := ""
+		for _, fromKind := range fromKinds {
+			if mapped := c.KindMap[fromKind]; mapped.KindName == kind && fromKind != kind {
+				next = fromKind
+				break
+			}
+		}
+		if next == "" || seen[next] {
+			return kind
+		}
+		seen[next] = true
+		kind = next
+	}
+}
+
+// LangEnabled returns whether Gazelle should process the language with the
+// given name in the current directory.
+func (c *Config) LangEnabled(name string) bool {
+	for _, l := range c.DisabledLangs {
+		if l == name {
+			return false
+		}
+	}
+	if len(c.Langs) == 0 {
+		return true
+	}
+	for _, l := range c.Langs {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLangs applies the value of a lang directive or flag to the inherited
+// language lists and returns new lists. The value is a comma-separated list
+// of language names. A name prefixed with "-" disables that language, and a
+// name prefixed with "+" enables it again, keeping other inherited settings.
+// If the value contains any names without a prefix, those names replace the
+// inherited selection. An empty value enables all languages.
+//
+// parseLangs does not modify langs or disabled, since they may be shared with
+// the configuration for a parent directory.
+func parseLangs(langs, disabled []string, value string) ([]string, []string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	names := strings.Split(value, ",")
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" && name[0] != '-' && name[0] != '+' {
+			langs, disabled = nil, nil
+			break
+		}
+	}
+	langs = append([]string(nil), langs...)
+	disabled = append([]string(nil), disabled...)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case name[0] == '-':
+			name = name[1:]
+			if !containsString(disabled, name) {
+				disabled = append(disabled, name)
+			}
+		case name[0] == '+':
+			name = name[1:]
+			disabled = removeString(disabled, name)
+			if len(langs) > 0 && !containsString(langs, name) {
+				langs = append(langs, name)
+			}
+		default:
+			if !containsString(langs, name) {
+				langs = append(langs, name)
+			}
+		}
+	}
+	if len(langs) == 0 {
+		langs = nil
+	}
+	if len(disabled) == 0 {
+		disabled = nil
+	}
+	return langs, disabled
+}
+
+func containsString(strs []string, s string) bool {
+	for _, x := range strs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(strs []string, s string) []string {
+	result := strs[:0]
+	for _, x := range strs {
+		if x != s {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+// ResetsDirective reports whether d is a "reset" directive that clears values
+// of the directive named key inherited from parent directories. A "reset"
+// directive lists the keys it applies to, separated by commas. A "reset"
+// directive without a value applies to all keys.
+//
+// Configurers that accumulate values for a directive across directories
+// should check for "reset" directives in Configure and discard accumulated
+// values when this returns true. Directives after the "reset" directive in
+// the same file still apply.
+func ResetsDirective(d rule.Directive, key string) bool {
+	if d.Key != "reset" {
+		return false
+	}
+	value := strings.TrimSpace(d.Value)
+	if value == "" {
+		return true
+	}
+	for _, k := range strings.Split(value, ",") {
+		if strings.TrimSpace(k) == key {
+			return true
+		}
+	}
+	return false
+}
+
 type indexFlag struct {
 	indexLibraries, indexLazy *bool
 }
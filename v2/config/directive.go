@@ -0,0 +1,126 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+)
+
+// DirectiveError describes a directive with an invalid value, along with
+// where the directive was written.
+type DirectiveError struct {
+	// Path is the path to the file containing the directive. It may be empty
+	// if the directive didn't come from a file.
+	Path string
+
+	// Line is the line number of the directive within the file, or 0 if
+	// it's not known.
+	Line int
+
+	// Directive is the invalid directive.
+	Directive rule.Directive
+
+	// Err describes what's wrong with the directive.
+	Err error
+}
+
+// NewDirectiveError returns a DirectiveError for the directive d, found in
+// the build file f. The line of d is looked up in f.
+func NewDirectiveError(f *rule.File, d rule.Directive, err error) *DirectiveError {
+	e := &DirectiveError{Directive: d, Err: err}
+	if f != nil {
+		e.Path = f.Path
+		e.Line = f.DirectiveLine(d)
+	}
+	return e
+}
+
+func (e *DirectiveError) Error() string {
+	switch {
+	case e.Path == "":
+		return fmt.Sprintf("gazelle:%s: %v", e.Directive.Key, e.Err)
+	case e.Line == 0:
+		return fmt.Sprintf("%s: gazelle:%s: %v", e.Path, e.Directive.Key, e.Err)
+	default:
+		return fmt.Sprintf("%s:%d: gazelle:%s: %v", e.Path, e.Line, e.Directive.Key, e.Err)
+	}
+}
+
+func (e *DirectiveError) Unwrap() error {
+	return e.Err
+}
+
+// directiveErrors collects errors reported with Config.ReportDirectiveError.
+// It's shared by a configuration and all its clones.
+type directiveErrors struct {
+	mu   sync.Mutex
+	errs []*DirectiveError
+}
+
+// ReportDirectiveError records that the directive d in the build file f has
+// an invalid value. Configurers should call this instead of logging, so that
+// errors are reported with their locations after the walk, and so that they
+// cause Gazelle to fail in strict mode. Configurers should ignore d after
+// reporting it.
+func (c *Config) ReportDirectiveError(f *rule.File, d rule.Directive, err error) {
+	e := NewDirectiveError(f, d, err)
+	if c.directiveErrs == nil {
+		// Config was not created with New; there's nowhere to collect errors.
+		log.Print(e)
+		return
+	}
+	c.directiveErrs.mu.Lock()
+	defer c.directiveErrs.mu.Unlock()
+	c.directiveErrs.errs = append(c.directiveErrs.errs, e)
+}
+
+// DirectiveErrors returns the errors reported with ReportDirectiveError in
+// this configuration and configurations cloned from or into it, sorted by
+// location.
+func (c *Config) DirectiveErrors() []*DirectiveError {
+	if c.directiveErrs == nil {
+		return nil
+	}
+	c.directiveErrs.mu.Lock()
+	defer c.directiveErrs.mu.Unlock()
+	errs := append([]*DirectiveError(nil), c.directiveErrs.errs...)
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Path != errs[j].Path {
+			return errs[i].Path < errs[j].Path
+		}
+		return errs[i].Line < errs[j].Line
+	})
+	return errs
+}
+
+// ResetDirectiveErrors discards the errors reported with ReportDirectiveError
+// in this configuration and configurations cloned from or into it. Walks
+// call this before they start, so that a configuration reused for several
+// walks, as in watch and server mode, doesn't report errors that have since
+// been fixed.
+func (c *Config) ResetDirectiveErrors() {
+	if c.directiveErrs == nil {
+		return
+	}
+	c.directiveErrs.mu.Lock()
+	defer c.directiveErrs.mu.Unlock()
+	c.directiveErrs.errs = nil
+}
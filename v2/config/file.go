@@ -0,0 +1,216 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+)
+
+// ConfigFileNames is a list of base names of configuration files Gazelle
+// looks for in the repository root directory, in order of preference.
+var ConfigFileNames = []string{"gazelle.json", ".gazelle.json"}
+
+// ConfigFile holds settings read from a configuration file checked into the
+// repository. It lets teams keep flags and directives out of the root build
+// file and the arguments of the gazelle rule. A configuration file looks
+// like this:
+//
+//	{
+//	  "flags": ["-index=lazy"],
+//	  "exclude": ["third_party/**"],
+//	  "resolve": ["go example.com/foo //third_party/foo"],
+//	  "directives": ["build_file_name BUILD.bazel"],
+//	  "load_migrations": [
+//	    {"from": "@old_rules//:defs.bzl", "to": "@new_rules//:defs.bzl"}
+//	  ],
+//	  "languages": {
+//	    "go": {
+//	      "flags": ["-external=static"],
+//	      "directives": ["go_naming_convention import"]
+//	    }
+//	  }
+//	}
+//
+// Flags are parsed before command line flags, so command line flags take
+// precedence. Directives are applied in the repository root directory before
+// directives in the root build file, so the root build file and build files
+// in subdirectories take precedence.
+type ConfigFile struct {
+	// Path is the absolute path to the file.
+	Path string `json:"-"`
+
+	// Flags is a list of command line arguments.
+	Flags []string `json:"flags,omitempty"`
+
+	// Exclude is a list of patterns, each treated like a
+	// "# gazelle:exclude" directive.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Resolve is a list of resolve rules, each treated like a
+	// "# gazelle:resolve" directive.
+	Resolve []string `json:"resolve,omitempty"`
+
+	// Directives is a list of directives, written either as "key value"
+	// or as "# gazelle:key value".
+	Directives []string `json:"directives,omitempty"`
+
+	// LoadMigrations is a list of symbols that moved from one .bzl file to
+	// another. Load statements in updated build files are rewritten to load
+	// them from their new locations.
+	LoadMigrations []rule.LoadMigration `json:"load_migrations,omitempty"`
+
+	// Languages holds settings for individual languages, keyed by
+	// language name.
+	Languages map[string]LanguageConfigFile `json:"languages,omitempty"`
+}
+
+// LanguageConfigFile holds settings for one language in a ConfigFile.
+type LanguageConfigFile struct {
+	Flags      []string `json:"flags,omitempty"`
+	Directives []string `json:"directives,omitempty"`
+}
+
+// FindConfigFile returns the path to the configuration file in the given
+// repository root directory, or "" if there is none.
+func FindConfigFile(repoRoot string) string {
+	for _, name := range ConfigFileNames {
+		p := filepath.Join(repoRoot, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// LoadConfigFile reads and parses a configuration file. Unknown fields are
+// reported as errors so that typos don't silently change behavior.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadConfigFileData(path, data)
+}
+
+// LoadConfigFileData parses a configuration file from a byte slice.
+func LoadConfigFileData(path string, data []byte) (*ConfigFile, error) {
+	f := &ConfigFile{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(f); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	f.Path = path
+	if _, err := f.RuleDirectives(); err != nil {
+		return nil, err
+	}
+	for _, m := range f.LoadMigrations {
+		if err := checkLoadMigration(m); err != nil {
+			return nil, fmt.Errorf("%s: load_migrations: %v", path, err)
+		}
+	}
+	return f, nil
+}
+
+// Args returns the command line arguments in the file: global flags first,
+// then flags for each language, in order of language name.
+func (f *ConfigFile) Args() []string {
+	args := append([]string(nil), f.Flags...)
+	for _, name := range f.languageNames() {
+		args = append(args, f.Languages[name].Flags...)
+	}
+	return args
+}
+
+// RuleDirectives returns the directives in the file: exclude directives,
+// then resolve directives, then other global directives, then directives
+// for each language, in order of language name.
+func (f *ConfigFile) RuleDirectives() ([]rule.Directive, error) {
+	var directives []rule.Directive
+	for _, v := range f.Exclude {
+		directives = append(directives, rule.Directive{Key: "exclude", Value: strings.TrimSpace(v)})
+	}
+	for _, v := range f.Resolve {
+		directives = append(directives, rule.Directive{Key: "resolve", Value: strings.TrimSpace(v)})
+	}
+	lines := append([]string(nil), f.Directives...)
+	for _, name := range f.languageNames() {
+		lines = append(lines, f.Languages[name].Directives...)
+	}
+	for _, line := range lines {
+		d, err := parseConfigFileDirective(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Path, err)
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+func (f *ConfigFile) languageNames() []string {
+	names := make([]string, 0, len(f.Languages))
+	for name := range f.Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkLoadMigration(m rule.LoadMigration) error {
+	if m.From == "" {
+		return fmt.Errorf("missing \"from\" file")
+	}
+	for _, l := range []string{m.From, m.To} {
+		if l == "" {
+			continue
+		}
+		if _, err := label.Parse(l); err != nil {
+			return err
+		}
+	}
+	if m.NewSymbol != "" && (m.Symbol == "" || m.To == "") {
+		return fmt.Errorf("%s: \"new_symbol\" requires \"symbol\" and \"to\"", m.From)
+	}
+	return nil
+}
+
+func parseConfigFileDirective(line string) (rule.Directive, error) {
+	s := strings.TrimSpace(line)
+	if t := strings.TrimPrefix(s, "#"); t != s {
+		t = strings.TrimSpace(t)
+		if !strings.HasPrefix(t, "gazelle:") {
+			return rule.Directive{}, fmt.Errorf("invalid directive %q: directive must use format '# gazelle:key value' or 'key value'", line)
+		}
+		s = strings.TrimPrefix(t, "gazelle:")
+	}
+	key, value := s, ""
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		key, value = s[:i], s[i+1:]
+	}
+	if key == "" {
+		return rule.Directive{}, fmt.Errorf("invalid directive %q: missing key", line)
+	}
+	return rule.Directive{Key: key, Value: strings.TrimSpace(value)}, nil
+}
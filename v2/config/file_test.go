@@ -0,0 +1,115 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+)
+
+func TestLoadConfigFileData(t *testing.T) {
+	f, err := LoadConfigFileData("gazelle.json", []byte(`{
+  "flags": ["-index=lazy"],
+  "exclude": ["third_party/**"],
+  "resolve": ["go example.com/foo //third_party/foo"],
+  "directives": ["build_file_name BUILD.bazel", "# gazelle:prefix example.com/repo"],
+  "load_migrations": [
+    {"from": "@old_rules//:defs.bzl", "symbol": "lib", "to": "@new_rules//:lib.bzl", "new_symbol": "library"}
+  ],
+  "languages": {
+    "proto": {"flags": ["-proto_group=package"]},
+    "go": {
+      "flags": ["-external=static"],
+      "directives": ["go_naming_convention import"]
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantArgs := []string{"-index=lazy", "-external=static", "-proto_group=package"}
+	if got := f.Args(); !reflect.DeepEqual(got, wantArgs) {
+		t.Errorf("Args: got %q; want %q", got, wantArgs)
+	}
+
+	got, err := f.RuleDirectives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rule.Directive{
+		{Key: "exclude", Value: "third_party/**"},
+		{Key: "resolve", Value: "go example.com/foo //third_party/foo"},
+		{Key: "build_file_name", Value: "BUILD.bazel"},
+		{Key: "prefix", Value: "example.com/repo"},
+		{Key: "go_naming_convention", Value: "import"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RuleDirectives: got %v; want %v", got, want)
+	}
+
+	wantMigrations := []rule.LoadMigration{
+		{From: "@old_rules//:defs.bzl", Symbol: "lib", To: "@new_rules//:lib.bzl", NewSymbol: "library"},
+	}
+	if !reflect.DeepEqual(f.LoadMigrations, wantMigrations) {
+		t.Errorf("LoadMigrations: got %v; want %v", f.LoadMigrations, wantMigrations)
+	}
+}
+
+func TestLoadConfigFileDataErrors(t *testing.T) {
+	for _, tc := range []struct {
+		desc, data string
+	}{
+		{desc: "unknown field", data: `{"flag": ["-index=lazy"]}`},
+		{desc: "wrong type", data: `{"exclude": "third_party/**"}`},
+		{desc: "bad comment directive", data: `{"directives": ["# prefix example.com/repo"]}`},
+		{desc: "missing key", data: `{"directives": ["  "]}`},
+		{desc: "migration without from", data: `{"load_migrations": [{"to": "//:defs.bzl"}]}`},
+		{desc: "migration with bad label", data: `{"load_migrations": [{"from": "@@@bad"}]}`},
+		{desc: "migration renames all symbols", data: `{"load_migrations": [{"from": "//:old.bzl", "to": "//:new.bzl", "new_symbol": "x"}]}`},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := LoadConfigFileData("gazelle.json", []byte(tc.data)); err == nil {
+				t.Error("got nil error")
+			}
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	if got := FindConfigFile(dir); got != "" {
+		t.Errorf("FindConfigFile in empty directory: got %q; want \"\"", got)
+	}
+	hidden := filepath.Join(dir, ".gazelle.json")
+	if err := os.WriteFile(hidden, []byte("{}"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindConfigFile(dir); got != hidden {
+		t.Errorf("FindConfigFile: got %q; want %q", got, hidden)
+	}
+	visible := filepath.Join(dir, "gazelle.json")
+	if err := os.WriteFile(visible, []byte("{}"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindConfigFile(dir); got != visible {
+		t.Errorf("FindConfigFile: got %q; want %q", got, visible)
+	}
+}
@@ -0,0 +1,89 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The methods below read files through Config.FS when it's set, and from
+// the OS file system otherwise. Gazelle and extensions should use them
+// instead of the os package to read sources and build files, so they work
+// on virtual and overlay file systems. Paths are absolute OS paths, as
+// elsewhere in Gazelle. Paths outside RepoRoot are always read from the OS
+// file system. The methods may be called on a nil *Config.
+
+// ReadDir reads the directory at path and returns its entries sorted by
+// name.
+func (c *Config) ReadDir(path string) ([]fs.DirEntry, error) {
+	if name, ok := c.fsName(path); ok {
+		return fs.ReadDir(c.FS, name)
+	}
+	return os.ReadDir(path)
+}
+
+// ReadFile reads the file at path.
+func (c *Config) ReadFile(path string) ([]byte, error) {
+	if name, ok := c.fsName(path); ok {
+		return fs.ReadFile(c.FS, name)
+	}
+	return os.ReadFile(path)
+}
+
+// Open opens the file at path for reading.
+func (c *Config) Open(path string) (fs.File, error) {
+	if name, ok := c.fsName(path); ok {
+		return c.FS.Open(name)
+	}
+	return os.Open(path)
+}
+
+// Stat returns information about the file at path, following symbolic
+// links.
+func (c *Config) Stat(path string) (fs.FileInfo, error) {
+	if name, ok := c.fsName(path); ok {
+		return fs.Stat(c.FS, name)
+	}
+	return os.Stat(path)
+}
+
+// WalkDir walks the file tree rooted at root like filepath.WalkDir, calling
+// fn with absolute OS paths.
+func (c *Config) WalkDir(root string, fn fs.WalkDirFunc) error {
+	name, ok := c.fsName(root)
+	if !ok {
+		return filepath.WalkDir(root, fn)
+	}
+	return fs.WalkDir(c.FS, name, func(p string, d fs.DirEntry, err error) error {
+		return fn(filepath.Join(c.RepoRoot, filepath.FromSlash(p)), d, err)
+	})
+}
+
+// fsName converts path to a name in c.FS. It returns false if c.FS is not
+// set or path is not in RepoRoot.
+func (c *Config) fsName(path string) (string, bool) {
+	if c == nil || c.FS == nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(c.RepoRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
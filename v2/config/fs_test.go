@@ -0,0 +1,63 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	osDir := t.TempDir()
+	osFile := filepath.Join(osDir, "os.txt")
+	if err := os.WriteFile(osFile, []byte("os"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(osDir, "repo")
+	c := New()
+	c.RepoRoot = root
+	c.FS = fstest.MapFS{"a/b.txt": {Data: []byte("fs")}}
+
+	if data, err := c.ReadFile(filepath.Join(root, "a", "b.txt")); err != nil || string(data) != "fs" {
+		t.Errorf("reading file in FS: got %q, %v; want \"fs\"", data, err)
+	}
+	if data, err := c.ReadFile(osFile); err != nil || string(data) != "os" {
+		t.Errorf("reading file outside RepoRoot: got %q, %v; want \"os\"", data, err)
+	}
+	if ents, err := c.ReadDir(root); err != nil || len(ents) != 1 || ents[0].Name() != "a" {
+		t.Errorf("reading RepoRoot: got %v, %v; want [a]", ents, err)
+	}
+	if _, err := c.Stat(filepath.Join(root, "os.txt")); !os.IsNotExist(err) {
+		t.Errorf("stat of file not in FS: got %v; want not exist", err)
+	}
+	var walked []string
+	err := c.WalkDir(root, func(p string, _ fs.DirEntry, err error) error {
+		walked = append(walked, p)
+		return err
+	})
+	if want := []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "b.txt")}; err != nil || !slices.Equal(walked, want) {
+		t.Errorf("walking RepoRoot: got %v, %v; want %v", walked, err, want)
+	}
+
+	var nilConfig *Config
+	if data, err := nilConfig.ReadFile(osFile); err != nil || string(data) != "os" {
+		t.Errorf("reading file with nil Config: got %q, %v; want \"os\"", data, err)
+	}
+}
@@ -0,0 +1,104 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// parseCache holds results of ParseFile, grouped by the directory containing
+// each file. It's shared by a configuration and all its clones.
+type parseCache struct {
+	mu    sync.Mutex
+	byDir map[string]map[parseKey]*parseEntry
+}
+
+type parseKey struct {
+	path string
+	key  any
+}
+
+type parseEntry struct {
+	once sync.Once
+	v    any
+	err  error
+}
+
+// ParseFile reads the file at path with c's file system, parses it with
+// parse, and returns the result. Results are cached by path and key until
+// rules are generated for the directory containing the file, so extensions
+// that parse the same file the same way while generating rules share one
+// read and one parse. For example, the Go extension parses Go files through
+// this cache, so another extension that needs the same syntax trees doesn't
+// parse them again.
+//
+// key identifies the parser and how it's configured. It must be comparable.
+// Extensions should use a value of an unexported type for parsers that
+// aren't meant to be shared, like context keys, and should export a function
+// that calls ParseFile for parsers that are. parse is called at most once for
+// each path and key while the result is cached, even when ParseFile is called
+// concurrently, and its error is cached with its result. Callers must not
+// modify the returned value, since it may be shared.
+//
+// Files are assumed not to change during a run.
+func (c *Config) ParseFile(path string, key any, parse func(path string, data []byte) (any, error)) (any, error) {
+	if c == nil || c.parsed == nil {
+		// Config was not created with New; there's nowhere to cache results.
+		data, err := c.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return parse(path, data)
+	}
+	dir := filepath.Dir(path)
+	k := parseKey{path: path, key: key}
+	c.parsed.mu.Lock()
+	entries := c.parsed.byDir[dir]
+	if entries == nil {
+		entries = make(map[parseKey]*parseEntry)
+		c.parsed.byDir[dir] = entries
+	}
+	e, ok := entries[k]
+	if !ok {
+		e = &parseEntry{}
+		entries[k] = e
+	}
+	c.parsed.mu.Unlock()
+
+	e.once.Do(func() {
+		data, err := c.ReadFile(path)
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.v, e.err = parse(path, data)
+	})
+	return e.v, e.err
+}
+
+// ReleaseParsedFiles drops results of ParseFile for files in dir, so they
+// don't stay in memory for the rest of the run. Gazelle calls it after all
+// extensions have generated rules for dir. Files in dir that are parsed again
+// later are read again.
+func (c *Config) ReleaseParsedFiles(dir string) {
+	if c == nil || c.parsed == nil {
+		return
+	}
+	c.parsed.mu.Lock()
+	defer c.parsed.mu.Unlock()
+	delete(c.parsed.byDir, filepath.Clean(dir))
+}
@@ -0,0 +1,118 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	type upperKey struct{}
+	type lenKey struct{}
+	var calls atomic.Int32
+	upper := func(_ string, data []byte) (any, error) {
+		calls.Add(1)
+		return string(data) + "!", nil
+	}
+
+	c := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		cc := c.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := cc.ParseFile(path, upperKey{}, upper); err != nil || v != "a!" {
+				t.Errorf("got %v, %v; want \"a!\"", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("parse called %d times; want 1", n)
+	}
+
+	v, err := c.ParseFile(path, lenKey{}, func(_ string, data []byte) (any, error) {
+		return len(data), nil
+	})
+	if err != nil || v != 1 {
+		t.Errorf("with another key: got %v, %v; want 1", v, err)
+	}
+
+	errParse := errors.New("bad")
+	for i := 0; i < 2; i++ {
+		_, err := c.ParseFile(filepath.Join(dir, "b.txt"), upperKey{}, upper)
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("missing file: got %v; want not exist", err)
+		}
+		_, err = c.ParseFile(path, errParse, func(string, []byte) (any, error) {
+			calls.Add(1)
+			return nil, errParse
+		})
+		if err != errParse {
+			t.Errorf("parse error: got %v; want %v", err, errParse)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("parse called %d times; want 2", n)
+	}
+}
+
+func TestReleaseParsedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	type key struct{}
+	calls := 0
+	parse := func(_ string, data []byte) (any, error) {
+		calls++
+		return string(data), nil
+	}
+
+	c := New()
+	for i := 0; i < 2; i++ {
+		if _, err := c.ParseFile(path, key{}, parse); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.ReleaseParsedFiles(filepath.Join(dir, "other"))
+	if _, err := c.ParseFile(path, key{}, parse); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("before release: parse called %d times; want 1", calls)
+	}
+
+	c.Clone().ReleaseParsedFiles(dir)
+	if _, err := c.ParseFile(path, key{}, parse); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("after release: parse called %d times; want 2", calls)
+	}
+}
@@ -0,0 +1,128 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+)
+
+// wellKnownModuleRepos maps the names repositories of some Bazel modules
+// had in WORKSPACE to the names of the modules. Extensions still write
+// labels with these names, for example "@io_bazel_rules_go//proto/wkt:...".
+var wellKnownModuleRepos = map[string]string{
+	"bazel_gazelle":       "gazelle",
+	"com_google_protobuf": "protobuf",
+	"io_bazel_rules_go":   "rules_go",
+}
+
+// ApparentRepoName returns the name labels written in this repository should
+// use to refer to the repository named repo. repo may be a canonical name,
+// the name of a Bazel module, or the name a module's repository had in
+// WORKSPACE, like "io_bazel_rules_go". The name is found in RepoMapping or
+// through ModuleToApparentName. If no other name is known, repo is returned.
+func (c *Config) ApparentRepoName(repo string) string {
+	if name, ok := c.RepoMapping[repo]; ok {
+		return name
+	}
+	if c.repoMappingNames[repo] || c.ModuleToApparentName == nil {
+		return repo
+	}
+	module := repo
+	if m, ok := label.CanonicalModuleName(repo); ok {
+		module = m
+	} else if m, ok := wellKnownModuleRepos[repo]; ok {
+		module = m
+	}
+	if name := c.ModuleToApparentName(module); name != "" {
+		return name
+	}
+	return repo
+}
+
+// ApparentLabel returns l with its repository name replaced by the name
+// returned by ApparentRepoName. Canonical labels that refer to the main
+// repository become labels without a repository name.
+func (c *Config) ApparentLabel(l label.Label) label.Label {
+	if l.Relative || l.Repo == "" || l.Repo == "@" {
+		return l.Apparent(nil)
+	}
+	repo := c.ApparentRepoName(l.Repo)
+	if repo == l.Repo {
+		return l
+	}
+	return label.New(repo, l.Pkg, l.Name)
+}
+
+// readRepoMapping reads a repository mapping file in the format Bazel writes
+// to _repo_mapping in runfiles trees. Each line has the form
+// "source,apparent,canonical": the repository with the canonical name source
+// sees the repository with the canonical name canonical as apparent. A source
+// ending with "*" matches all repositories whose names start with the rest
+// of it.
+//
+// readRepoMapping returns a map from canonical names to the apparent names
+// they have in the repository named source. If a repository has several
+// apparent names, the smallest is used. The file is read through c.
+func readRepoMapping(c *Config, path, source string) (map[string]string, error) {
+	data, err := c.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mapping := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected source,apparent,canonical: %q", path, i+1, line)
+		}
+		from, apparent, canonical := fields[0], fields[1], fields[2]
+		if prefix, ok := strings.CutSuffix(from, "*"); ok {
+			if !strings.HasPrefix(source, prefix) {
+				continue
+			}
+		} else if from != source {
+			continue
+		}
+		if apparent == "" || canonical == "" {
+			// Labels in the main repository don't need a repository name.
+			continue
+		}
+		if prev, ok := mapping[canonical]; !ok || apparent < prev {
+			mapping[canonical] = apparent
+		}
+	}
+	return mapping, nil
+}
+
+// repoMappingSource returns the canonical name of the repository rooted at
+// repoRoot, which selects the entries of a repository mapping file that
+// apply to it. Bazel fetches external repositories into directories named
+// after their canonical names inside an "external" directory; all other
+// directories are treated as the main repository, whose canonical name is
+// empty.
+func repoMappingSource(repoRoot string) string {
+	if filepath.Base(filepath.Dir(repoRoot)) == "external" {
+		return filepath.Base(repoRoot)
+	}
+	return ""
+}
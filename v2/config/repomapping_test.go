@@ -0,0 +1,127 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testRepoMapping = `
+,my_module,
+,rules_go,rules_go+
+,io_bazel_rules_go,rules_go+
+,com_github_pkg_errors,gazelle++go_deps+com_github_pkg_errors
+gazelle++go_deps+*,com_github_pkg_errors,gazelle++go_deps+com_github_pkg_errors
+gazelle++go_deps+*,io_bazel_rules_go,rules_go+
+`
+
+func TestReadRepoMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "_repo_mapping")
+	if err := os.WriteFile(path, []byte(testRepoMapping), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		desc, source string
+		want         map[string]string
+	}{
+		{
+			desc: "main",
+			want: map[string]string{
+				"rules_go+":                              "io_bazel_rules_go",
+				"gazelle++go_deps+com_github_pkg_errors": "com_github_pkg_errors",
+			},
+		}, {
+			desc:   "wildcard",
+			source: "gazelle++go_deps+org_golang_x_sys",
+			want: map[string]string{
+				"rules_go+":                              "io_bazel_rules_go",
+				"gazelle++go_deps+com_github_pkg_errors": "com_github_pkg_errors",
+			},
+		}, {
+			desc:   "unknown",
+			source: "other+",
+			want:   map[string]string{},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := readRepoMapping(nil, path, tc.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want,+got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := os.WriteFile(path, []byte("a,b\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readRepoMapping(nil, path, ""); err == nil {
+		t.Error("readRepoMapping succeeded on malformed file; want error")
+	}
+}
+
+func TestRepoMappingSource(t *testing.T) {
+	for root, want := range map[string]string{
+		"/home/user/repo": "",
+		"/cache/external/gazelle++go_deps+com_github_pkg_errors": "gazelle++go_deps+com_github_pkg_errors",
+	} {
+		if got := repoMappingSource(filepath.FromSlash(root)); got != want {
+			t.Errorf("repoMappingSource(%q) = %q; want %q", root, got, want)
+		}
+	}
+}
+
+func TestApparentLabel(t *testing.T) {
+	c := New()
+	c.ModuleToApparentName = func(module string) string {
+		return map[string]string{"rules_go": "my_rules_go", "protobuf": "protobuf"}[module]
+	}
+	c.RepoMapping = map[string]string{"gazelle++go_deps+com_github_pkg_errors": "com_github_pkg_errors"}
+	c.repoMappingNames = map[string]bool{"com_github_pkg_errors": true}
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{in: "//pkg:name"},
+		{in: ":name"},
+		{in: "@@//pkg:name", want: "//pkg:name"},
+		{in: "@rules_go//go:def.bzl", want: "@my_rules_go//go:def.bzl"},
+		{in: "@io_bazel_rules_go//proto/wkt:any_go_proto", want: "@my_rules_go//proto/wkt:any_go_proto"},
+		{in: "@@rules_go+//go:def.bzl", want: "@my_rules_go//go:def.bzl"},
+		{in: "@com_google_protobuf//:any_proto", want: "@protobuf//:any_proto"},
+		{in: "@@gazelle++go_deps+com_github_pkg_errors//:errors", want: "@com_github_pkg_errors//:errors"},
+		{in: "@com_github_pkg_errors//:errors"},
+		{in: "@org_golang_x_sys//unix"},
+	} {
+		l, err := label.Parse(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := tc.want
+		if want == "" {
+			want = tc.in
+		}
+		if got := c.ApparentLabel(l).String(); got != want {
+			t.Errorf("ApparentLabel(%q) = %q; want %q", tc.in, got, want)
+		}
+	}
+}
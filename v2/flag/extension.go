@@ -0,0 +1,160 @@
+// Copyright 2026 The Bazel Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flag
+
+import (
+	stdflag "flag"
+	"fmt"
+	"strings"
+)
+
+// ExtensionFlags registers flags from several extensions into one FlagSet.
+// Unlike registering directly into the FlagSet, which panics when two
+// extensions define a flag with the same name, ExtensionFlags reports
+// collisions with the names of the extensions involved.
+//
+// Flags registered with a namespace (usually a language name) are also
+// available with the namespace as a prefix, for example -go.build_tags.
+// The two names are aliases for the same flag.Value, and setting either one
+// marks both as set, so FlagSet.Visit reports both. When two namespaced
+// extensions define the same flag, the unprefixed name becomes ambiguous:
+// setting it is an error, but the prefixed names still work.
+type ExtensionFlags struct {
+	fs    *stdflag.FlagSet
+	flags map[string]*extensionValue
+}
+
+// NewExtensionFlags returns an ExtensionFlags that registers flags into fs.
+func NewExtensionFlags(fs *stdflag.FlagSet) *ExtensionFlags {
+	return &ExtensionFlags{fs: fs, flags: make(map[string]*extensionValue)}
+}
+
+// Register calls register with a new FlagSet, then adds the flags it defines
+// to the underlying FlagSet on behalf of the extension named owner.
+// namespace may be empty for extensions whose flags should not be prefixed.
+// Register returns an error if a flag collides with a flag of an extension
+// without a namespace.
+func (ef *ExtensionFlags) Register(owner, namespace string, register func(fs *stdflag.FlagSet)) error {
+	scratch := stdflag.NewFlagSet(owner, stdflag.ContinueOnError)
+	register(scratch)
+
+	var err error
+	scratch.VisitAll(func(f *stdflag.Flag) {
+		if err != nil {
+			return
+		}
+		err = ef.add(owner, namespace, f)
+	})
+	return err
+}
+
+func (ef *ExtensionFlags) add(owner, namespace string, f *stdflag.Flag) error {
+	var prefixed string
+	if namespace != "" {
+		prefixed = namespace + "." + f.Name
+		if ev, ok := ef.flags[prefixed]; ok {
+			return fmt.Errorf("flag -%s is defined by both %s and %s", prefixed, ev.owners[0].name, owner)
+		}
+	}
+
+	if ev, ok := ef.flags[f.Name]; ok {
+		if namespace == "" || ev.owners[0].namespace == "" {
+			return fmt.Errorf("flag -%s is defined by both %s and %s", f.Name, ev.owners[0].name, owner)
+		}
+		ev.owners = append(ev.owners, flagOwner{owner, namespace})
+	} else {
+		ev := &extensionValue{ef: ef, name: f.Name, alias: prefixed, value: f.Value, owners: []flagOwner{{owner, namespace}}}
+		ef.flags[f.Name] = ev
+		ef.fs.Var(ev, f.Name, f.Usage)
+	}
+
+	if prefixed != "" {
+		ev := &extensionValue{ef: ef, name: prefixed, alias: f.Name, value: f.Value, owners: []flagOwner{{owner, namespace}}}
+		ef.flags[prefixed] = ev
+		ef.fs.Var(ev, prefixed, f.Usage)
+	}
+	return nil
+}
+
+type flagOwner struct {
+	name, namespace string
+}
+
+// extensionValue wraps the flag.Value registered by an extension, so that
+// it can be made ambiguous when another extension defines the same flag.
+// Namespaced flags have two extensionValues, one for each name, that share
+// the same value. alias is the other name.
+type extensionValue struct {
+	ef     *ExtensionFlags
+	name   string
+	alias  string
+	value  stdflag.Value
+	owners []flagOwner
+
+	// marking is true while the flag is being marked as set because its
+	// alias was set. The value is not set a second time.
+	marking bool
+}
+
+var _ stdflag.Value = (*extensionValue)(nil)
+
+func (v *extensionValue) Set(s string) error {
+	if len(v.owners) > 1 {
+		names := make([]string, len(v.owners))
+		prefixed := make([]string, len(v.owners))
+		for i, o := range v.owners {
+			names[i] = o.name
+			prefixed[i] = "-" + o.namespace + "." + v.name
+		}
+		return fmt.Errorf("flag is defined by %s; use %s instead", strings.Join(names, " and "), strings.Join(prefixed, " or "))
+	}
+	if v.marking {
+		return nil
+	}
+	if err := v.value.Set(s); err != nil {
+		return err
+	}
+	if a := v.ef.flags[v.alias]; a != nil && len(a.owners) == 1 {
+		a.marking = true
+		defer func() { a.marking = false }()
+		return v.ef.fs.Set(a.name, s)
+	}
+	return nil
+}
+
+// Alias returns the other name of a namespaced flag, or "" if the flag has
+// only one name.
+func (v *extensionValue) Alias() string {
+	if a := v.ef.flags[v.alias]; a != nil && len(a.owners) == 1 {
+		return v.alias
+	}
+	return ""
+}
+
+func (v *extensionValue) String() string {
+	if v == nil || v.value == nil {
+		return ""
+	}
+	return v.value.String()
+}
+
+// IsBoolFlag lets boolean flags be set without a value, like -r instead of
+// -r=true.
+func (v *extensionValue) IsBoolFlag() bool {
+	if b, ok := v.value.(interface{ IsBoolFlag() bool }); ok {
+		return b.IsBoolFlag()
+	}
+	return false
+}
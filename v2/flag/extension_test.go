@@ -0,0 +1,103 @@
+// Copyright 2026 The Bazel Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flag
+
+import (
+	stdflag "flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestExtensionFlags(t *testing.T) {
+	var mode, goTags, protoTags string
+	var recursive bool
+	fs := stdflag.NewFlagSet("test", stdflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	ef := NewExtensionFlags(fs)
+	if err := ef.Register("core", "", func(fs *stdflag.FlagSet) {
+		fs.StringVar(&mode, "mode", "fix", "")
+		fs.BoolVar(&recursive, "r", false, "")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ef.Register("go", "go", func(fs *stdflag.FlagSet) {
+		fs.StringVar(&goTags, "build_tags", "", "")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ef.Register("proto", "proto", func(fs *stdflag.FlagSet) {
+		fs.StringVar(&protoTags, "build_tags", "", "")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Parse([]string{"-mode=diff", "-r", "-go.build_tags=a", "-proto.build_tags=b"}); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "diff" || !recursive || goTags != "a" || protoTags != "b" {
+		t.Errorf("got mode=%q r=%v go.build_tags=%q proto.build_tags=%q", mode, recursive, goTags, protoTags)
+	}
+
+	err := fs.Parse([]string{"-build_tags=c"})
+	if err == nil || !strings.Contains(err.Error(), "-go.build_tags or -proto.build_tags") {
+		t.Errorf("ambiguous flag: got error %v", err)
+	}
+}
+
+func TestExtensionFlagsCollision(t *testing.T) {
+	fs := stdflag.NewFlagSet("test", stdflag.ContinueOnError)
+	ef := NewExtensionFlags(fs)
+	if err := ef.Register("core", "", func(fs *stdflag.FlagSet) {
+		fs.String("mode", "", "")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	err := ef.Register("go", "go", func(fs *stdflag.FlagSet) {
+		fs.String("mode", "", "")
+	})
+	if want := "flag -mode is defined by both core and go"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
+
+func TestExtensionFlagsAlias(t *testing.T) {
+	var tags []string
+	fs := stdflag.NewFlagSet("test", stdflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	ef := NewExtensionFlags(fs)
+	if err := ef.Register("go", "go", func(fs *stdflag.FlagSet) {
+		fs.Var(&MultiFlag{Values: &tags}, "build_tags", "")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Parse([]string{"-go.build_tags=a", "-build_tags=b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(tags, ","); got != "a,b" {
+		t.Errorf("got tags %q, want %q", got, "a,b")
+	}
+	var set []string
+	fs.Visit(func(f *stdflag.Flag) {
+		set = append(set, f.Name+"="+f.Value.String())
+	})
+	if got, want := strings.Join(set, " "), "build_tags=a,b go.build_tags=a,b"; got != want {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+	if got := fs.Lookup("go.build_tags").Value.(interface{ Alias() string }).Alias(); got != "build_tags" {
+		t.Errorf("got alias %q, want %q", got, "build_tags")
+	}
+}
@@ -47,6 +47,21 @@ func FindWORKSPACEFile(root string) string {
 	return filepath.Join(root, "WORKSPACE")
 }
 
+// IsWorkspaceless reports whether root is a repository root without a
+// WORKSPACE file, marked instead by MODULE.bazel or REPO.bazel as Bazel 8 and
+// later allow. External repositories of such a repository are declared in
+// MODULE.bazel.
+func IsWorkspaceless(root string) bool {
+	for _, boundaryFile := range repoBoundaryMarkerFiles {
+		info, err := os.Stat(filepath.Join(root, boundaryFile))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		return !IsWORKSPACE(boundaryFile)
+	}
+	return false
+}
+
 // FindRepoRoot searches from the given dir and up for a directory containing a "boundary marker file"
 // which delimit a repository as of Bazel 8,
 // returning the directory containing it, or an error if none found in the tree.
@@ -49,6 +49,10 @@ func TestFind(t *testing.T) {
 		// Test outside a workspace but within a directory named WORKSPACE
 		{filepath.Join(tmp, "WORKSPACE", "file.txt"), filepath.Join(tmp, "dir1"), false},
 		{filepath.Join(tmp, "WORKSPACE", "file.txt"), filepath.Join(tmp, "WORKSPACE", "dir1"), false},
+		// Test repositories without a WORKSPACE file
+		{filepath.Join(tmp, "MODULE.bazel"), tmp, true},
+		{filepath.Join(tmp, "MODULE.bazel"), filepath.Join(tmp, "dir1"), true},
+		{filepath.Join(tmp, "REPO.bazel"), filepath.Join(tmp, "dir1"), true},
 	} {
 		t.Run(tc.file, func(t *testing.T) {
 			if err := os.RemoveAll(tmp); err != nil {
@@ -85,6 +89,18 @@ func TestFind(t *testing.T) {
 				t.Errorf("FindRoot(%q): got error %v, wanted %v", tc.testdir, err, tc.file)
 			}
 
+			if !IsWORKSPACE(tc.file) {
+				if want := filepath.Dir(tc.file); dir != want {
+					t.Errorf("FindRoot(%q): got %v, wanted %v", tc.testdir, dir, want)
+				}
+				if !IsWorkspaceless(dir) {
+					t.Errorf("IsWorkspaceless(%q): got false, wanted true", dir)
+				}
+				return
+			}
+			if IsWorkspaceless(dir) {
+				t.Errorf("IsWorkspaceless(%q): got true, wanted false", dir)
+			}
 			file := FindWORKSPACEFile(dir)
 			if file != tc.file {
 				t.Errorf("FindWorkspaceFile(FindRoot(%q)): got %v, wanted %v", tc.testdir, file, tc.file)
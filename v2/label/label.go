@@ -204,6 +204,51 @@ func (l Label) Rel(repo, pkg string) Label {
 	return Label{Pkg: l.Pkg, Name: l.Name}
 }
 
+// Apparent converts a label with a canonical repository name (one written
+// with "@@") into a label with the apparent name the repository has in the
+// main repository. moduleToApparentName maps Bazel module names to apparent
+// names, as Config.ModuleToApparentName does; it may be nil.
+//
+// "@@//pkg:name" refers to the main repository and becomes "//pkg:name". The
+// canonical name of a module's repository ("rules_go+" since Bazel 8,
+// "rules_go~" or "rules_go~0.50.0" before) is mapped through the module's
+// name. Labels that aren't canonical and canonical names that can't be mapped,
+// like those of repositories created by module extensions, are returned
+// unchanged.
+func (l Label) Apparent(moduleToApparentName func(string) string) Label {
+	if !l.Canonical || l.Relative {
+		return l
+	}
+	if l.Repo == "" || l.Repo == "@" {
+		return Label{Pkg: l.Pkg, Name: l.Name}
+	}
+	module, ok := CanonicalModuleName(l.Repo)
+	if !ok || moduleToApparentName == nil {
+		return l
+	}
+	if repo := moduleToApparentName(module); repo != "" {
+		return Label{Repo: repo, Pkg: l.Pkg, Name: l.Name}
+	}
+	return l
+}
+
+// CanonicalModuleName returns the name of the Bazel module whose repository
+// has the given canonical name. ok is false if repo is not the canonical name
+// of a module's repository, for example if it was created by a module
+// extension.
+func CanonicalModuleName(repo string) (module string, ok bool) {
+	i := strings.IndexAny(repo, "+~")
+	if i <= 0 {
+		return "", false
+	}
+	if strings.ContainsAny(repo[i+1:], "+~") {
+		// Repositories created by module extensions have names like
+		// "rules_go++go_sdk+go_default_sdk" or "rules_go~0.50.0~go_sdk~go_default_sdk".
+		return "", false
+	}
+	return repo[:i], true
+}
+
 // Equal returns whether two labels are exactly the same. It does not return
 // true for different labels that refer to the same target.
 func (l Label) Equal(other Label) bool {
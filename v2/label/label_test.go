@@ -122,6 +122,41 @@ func TestImportPathToBazelRepoName(t *testing.T) {
 	}
 }
 
+func TestApparent(t *testing.T) {
+	moduleToApparentName := func(module string) string {
+		return map[string]string{
+			"rules_go": "io_bazel_rules_go",
+			"gazelle":  "gazelle",
+		}[module]
+	}
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{in: ":target"},
+		{in: "@repo//pkg:target"},
+		{in: "@@//pkg:target", want: "//pkg:target"},
+		{in: "@@rules_go+//go:def.bzl", want: "@io_bazel_rules_go//go:def.bzl"},
+		{in: "@@rules_go~//go:def.bzl", want: "@io_bazel_rules_go//go:def.bzl"},
+		{in: "@@rules_go~0.50.0//go:def.bzl", want: "@io_bazel_rules_go//go:def.bzl"},
+		{in: "@@gazelle+//:gazelle", want: "@gazelle"},
+		{in: "@@unknown+//:target"},
+		{in: "@@rules_go++go_sdk+go_default_sdk//:go"},
+		{in: "@@rules_go~0.50.0~go_sdk~go_default_sdk//:go"},
+	} {
+		l, err := Parse(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := tc.want
+		if want == "" {
+			want = tc.in
+		}
+		if got := l.Apparent(moduleToApparentName).String(); got != want {
+			t.Errorf("Parse(%q).Apparent() = %q; want %q", tc.in, got, want)
+		}
+	}
+}
+
 func TestParseStringRoundtrip(t *testing.T) {
 	for _, tc := range []struct {
 		in  string
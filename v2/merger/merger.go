@@ -38,6 +38,7 @@ package merger
 
 import (
 	"fmt"
+	"log"
 	"sort"
 	"strings"
 
@@ -70,6 +71,13 @@ const (
 // TODO(jayconrod): make this stable *or* find a better way to express it.
 const UnstableInsertIndexKey = "_gazelle_insert_index"
 
+// WrappedKindKey is the name of an internal attribute that may be set on an
+// existing rule whose kind is a wrapper macro. Its value is the kind of rule
+// the macro call stands for. This is needed for macros that may wrap more
+// than one kind, since aliasedKinds maps each macro to a single kind. When
+// set, it takes precedence over aliasedKinds.
+const WrappedKindKey = "_gazelle_wrapped_kind"
+
 // MergeFile combines information from newly generated rules with matching
 // rules in an existing build file. MergeFile can also delete rules which
 // are empty after merging.
@@ -106,7 +114,29 @@ const UnstableInsertIndexKey = "_gazelle_insert_index"
 // If an attribute is marked with a "# keep" comment, it will not be merged.
 // If a rule is marked with a "# keep" comment, the whole rule will not
 // be modified.
+//
+// In the post-resolve phase, after rules are merged, attributes listed in
+// KindInfo.ComputedDefaults are set on rules that don't have them, then
+// KindInfo.Validators are run. Validation errors are logged; use
+// MergeFileErrors to get them instead.
 func MergeFile(oldFile *rule.File, emptyRules, genRules []*rule.Rule, phase Phase, kinds map[string]rule.KindInfo, aliasedKinds map[string]string) {
+	_, checkErrs := mergeFile(oldFile, emptyRules, genRules, phase, kinds, aliasedKinds)
+	for _, err := range checkErrs {
+		log.Print(err)
+	}
+}
+
+// MergeFileErrors is like MergeFile, but instead of silently skipping
+// generated rules that can't be matched unambiguously with existing rules and
+// logging attributes that fail validation, it returns errors describing them.
+func MergeFileErrors(oldFile *rule.File, emptyRules, genRules []*rule.Rule, phase Phase, kinds map[string]rule.KindInfo, aliasedKinds map[string]string) []error {
+	matchErrs, checkErrs := mergeFile(oldFile, emptyRules, genRules, phase, kinds, aliasedKinds)
+	return append(matchErrs, checkErrs...)
+}
+
+// mergeFile implements MergeFile. It returns errors for generated rules that
+// couldn't be matched and for attributes that failed validation.
+func mergeFile(oldFile *rule.File, emptyRules, genRules []*rule.Rule, phase Phase, kinds map[string]rule.KindInfo, aliasedKinds map[string]string) (matchErrs, checkErrs []error) {
 	getMergeAttrs := func(r *rule.Rule) map[string]bool {
 		if phase == PreResolve {
 			return kinds[r.Kind()].MergeableAttrs
@@ -126,7 +156,7 @@ func MergeFile(oldFile *rule.File, emptyRules, genRules []*rule.Rule, phase Phas
 			// e.g., if oldRule is "my_py_library" aliased to "py_library",
 			// use KindInfo for "py_library" to determine emptiness.
 			kindForInfo := oldRule.Kind()
-			if underlying, ok := aliasedKinds[kindForInfo]; ok {
+			if underlying := wrappedKind(oldRule, aliasedKinds); underlying != "" {
 				kindForInfo = underlying
 			}
 			if oldRule.IsEmpty(kinds[kindForInfo]) {
@@ -167,6 +197,7 @@ func MergeFile(oldFile *rule.File, emptyRules, genRules []*rule.Rule, phase Phas
 	// Merge generated rules with existing rules or append to the end of the file.
 	for i, genRule := range genRules {
 		if matchErrors[i] != nil {
+			matchErrs = append(matchErrs, fmt.Errorf("%s: %w", oldFile.Path, matchErrors[i]))
 			continue
 		}
 		if matchRules[i] == nil {
@@ -179,6 +210,75 @@ func MergeFile(oldFile *rule.File, emptyRules, genRules []*rule.Rule, phase Phas
 			rule.MergeRules(genRule, matchRules[i], getMergeAttrs(genRule), oldFile.Path)
 		}
 	}
+
+	if phase == PostResolve {
+		checkErrs = CheckAttrs(oldFile, kinds, aliasedKinds)
+	}
+	return matchErrs, checkErrs
+}
+
+// AttrError describes an attribute that failed validation in CheckAttrs.
+type AttrError struct {
+	// Pos is the path of the build file, followed by the line of the rule
+	// if it's known.
+	Pos string
+
+	// Kind and Name identify the rule, and Attr is the attribute's name.
+	Kind, Name, Attr string
+
+	// Err is the error returned by the validator.
+	Err error
+}
+
+func (e *AttrError) Error() string {
+	return fmt.Sprintf("%s: %s %q: %s: %v", e.Pos, e.Kind, e.Name, e.Attr, e.Err)
+}
+
+func (e *AttrError) Unwrap() error { return e.Err }
+
+// CheckAttrs sets computed defaults and runs validators declared in kinds
+// for rules in f, as MergeFile does after dependencies are resolved. It
+// returns an *AttrError for each attribute that fails validation.
+func CheckAttrs(f *rule.File, kinds map[string]rule.KindInfo, aliasedKinds map[string]string) []error {
+	var errs []error
+	for _, r := range f.Rules {
+		kind := r.Kind()
+		if _, ok := kinds[kind]; !ok {
+			if underlying := wrappedKind(r, aliasedKinds); underlying != "" {
+				kind = underlying
+			}
+		}
+		info := kinds[kind]
+		if len(info.ComputedDefaults) > 0 && !r.ShouldKeep() {
+			for _, key := range sortedKeys(info.ComputedDefaults) {
+				if r.Attr(key) != nil {
+					continue
+				}
+				if v := info.ComputedDefaults[key](r); v != nil {
+					r.SetAttr(key, v)
+				}
+			}
+		}
+		for _, key := range sortedKeys(info.Validators) {
+			if err := info.Validators[key](r, r.Attr(key)); err != nil {
+				pos := f.Path
+				if line := r.Line(); line > 0 {
+					pos = fmt.Sprintf("%s:%d", f.Path, line)
+				}
+				errs = append(errs, &AttrError{Pos: pos, Kind: r.Kind(), Name: r.Name(), Attr: key, Err: err})
+			}
+		}
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // substituteRule replaces local labels (those beginning with ":", referring to
@@ -230,14 +330,14 @@ func match(rules []*rule.Rule, x *rule.Rule, info rule.KindInfo, wantError bool,
 		if xname == y.Name() {
 			nameMatches = append(nameMatches, y)
 		}
-		if xkind == y.Kind() || aliasedKinds[y.Kind()] == xkind {
+		if xkind == y.Kind() || xkind == wrappedKind(y, aliasedKinds) {
 			kindMatches = append(kindMatches, y)
 		}
 	}
 
 	if len(nameMatches) == 1 {
 		y := nameMatches[0]
-		if xkind == y.Kind() || xkind == aliasedKinds[y.Kind()] {
+		if xkind == y.Kind() || xkind == wrappedKind(y, aliasedKinds) {
 			return y, nil
 		}
 		if xname != "" {
@@ -285,6 +385,15 @@ func match(rules []*rule.Rule, x *rule.Rule, info rule.KindInfo, wantError bool,
 	return nil, nil
 }
 
+// wrappedKind returns the kind of rule that r stands for if r is a call to a
+// wrapper macro, or "" otherwise.
+func wrappedKind(r *rule.Rule, aliasedKinds map[string]string) string {
+	if kind, ok := r.PrivateAttr(WrappedKindKey).(string); ok {
+		return kind
+	}
+	return aliasedKinds[r.Kind()]
+}
+
 func attrMatch(x, y *rule.Rule, key string) bool {
 	xValue := x.AttrString(key)
 	if xValue != "" && xValue == y.AttrString(key) {
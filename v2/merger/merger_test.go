@@ -16,6 +16,10 @@ limitations under the License.
 package merger_test
 
 import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -24,6 +28,8 @@ import (
 	"github.com/bazelbuild/bazel-gazelle/language"
 	golang "github.com/bazelbuild/bazel-gazelle/language/go"
 	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	bzl "github.com/bazelbuild/buildtools/build"
+	"github.com/google/go-cmp/cmp"
 )
 
 // should fix
@@ -1036,6 +1042,76 @@ exports_files(["foo.txt"])
 exports_files(["bar.txt"])
 
 package(default_visibility = ["//visibility:public"])
+`,
+	},
+	{
+		desc: "complex starlark is matched and preserved",
+		previous: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+NAME = "foo"
+
+go_library(
+    name = NAME + "_lib",
+    srcs = [f for f in glob(["*.go"]) if not f.endswith("_test.go")],
+    cgo = True if NAME else False,
+    importpath = "example.com/repo/" + NAME,
+    embedsrcs = ["a.txt"] + EXTRA_EMBEDSRCS,
+)
+`,
+		current: `
+go_library(
+    name = "foo_lib",
+    srcs = ["foo.go"],
+    importpath = "example.com/repo/foo",
+    embedsrcs = ["a.txt", "b.txt"],
+)
+`,
+		expected: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+NAME = "foo"
+
+go_library(
+    name = NAME + "_lib",
+    srcs = [f for f in glob(["*.go"]) if not f.endswith("_test.go")],
+    cgo = True if NAME else False,
+    embedsrcs = ["a.txt"] + EXTRA_EMBEDSRCS,
+    importpath = "example.com/repo/" + NAME,
+)
+`,
+	},
+	{
+		desc: "non-string list elements are preserved",
+		previous: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "foo",
+    srcs = [
+        "old.go",
+        EXTRA_SRC,
+        "cond.go" if USE_COND else "other.go",
+    ],
+)
+`,
+		current: `
+go_library(
+    name = "foo",
+    srcs = ["new.go"],
+)
+`,
+		expected: `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "foo",
+    srcs = [
+        EXTRA_SRC,
+        "cond.go" if USE_COND else "other.go",
+        "new.go",
+    ],
+)
 `,
 	},
 }
@@ -1070,6 +1146,49 @@ func TestMergeFile(t *testing.T) {
 	}
 }
 
+func TestMergeFileOpaqueCalls(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	previous := `
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+go_library(
+    name = "foo",
+    srcs = glob(["*.go"]),
+    importpath = "example.com/foo",
+    deps = some_macro() + [":old"],
+)
+`
+	current := `
+go_library(
+    name = "foo",
+    srcs = ["foo.go"],
+    importpath = "example.com/foo",
+    deps = [":new"],
+)
+`
+	f, err := rule.LoadData(filepath.Join("previous", "BUILD.bazel"), "", []byte(previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, phase := range []merger.Phase{merger.PreResolve, merger.PostResolve} {
+		genFile, err := rule.LoadData(filepath.Join("current", "BUILD.bazel"), "", []byte(current))
+		if err != nil {
+			t.Fatal(err)
+		}
+		merger.MergeFile(f, nil, genFile.Rules, phase, testKinds, nil)
+	}
+
+	if got, want := string(f.Format()), previous[1:]; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if logs.Len() > 0 {
+		t.Errorf("unexpected log output:\n%s", logs.String())
+	}
+}
+
 var (
 	testKinds map[string]rule.KindInfo
 	testLoads []rule.LoadInfo
@@ -1208,3 +1327,98 @@ go_binary(name = "z")
 		})
 	}
 }
+
+func TestCheckAttrs(t *testing.T) {
+	kinds := map[string]rule.KindInfo{
+		"sh_test": {
+			ComputedDefaults: map[string]func(*rule.Rule) interface{}{
+				"size": func(r *rule.Rule) interface{} {
+					if r.AttrBool("flaky") {
+						return "large"
+					}
+					return "small"
+				},
+				"timeout": func(*rule.Rule) interface{} { return nil },
+			},
+			Validators: map[string]func(*rule.Rule, bzl.Expr) error{
+				"srcs": func(r *rule.Rule, value bzl.Expr) error {
+					if len(r.AttrStrings("srcs")) != 1 {
+						return errors.New("must have exactly one source")
+					}
+					return nil
+				},
+			},
+		},
+	}
+	f, err := rule.LoadData(filepath.Join("pkg", "BUILD.bazel"), "pkg", []byte(`
+sh_test(
+    name = "a",
+    srcs = ["a.sh"],
+)
+
+sh_test(
+    name = "b",
+    srcs = ["b.sh", "c.sh"],
+    flaky = True,
+)
+
+# keep
+sh_test(name = "c")
+
+my_test(name = "d")
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := rule.NewRule("sh_test", "e")
+	added.Insert(f)
+
+	errs := merger.CheckAttrs(f, kinds, map[string]string{"my_test": "sh_test"})
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	path := filepath.Join("pkg", "BUILD.bazel")
+	want := []string{
+		path + `:7: sh_test "b": srcs: must have exactly one source`,
+		path + `:14: sh_test "c": srcs: must have exactly one source`,
+		path + `:16: my_test "d": srcs: must have exactly one source`,
+		path + `: sh_test "e": srcs: must have exactly one source`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("errors (-want +got):\n%s", diff)
+	}
+
+	wantFile := `sh_test(
+    name = "a",
+    size = "small",
+    srcs = ["a.sh"],
+)
+
+sh_test(
+    name = "b",
+    size = "large",
+    srcs = [
+        "b.sh",
+        "c.sh",
+    ],
+    flaky = True,
+)
+
+# keep
+sh_test(name = "c")
+
+my_test(
+    name = "d",
+    size = "small",
+)
+
+sh_test(
+    name = "e",
+    size = "small",
+)
+`
+	if diff := cmp.Diff(wantFile, string(f.Format())); diff != "" {
+		t.Errorf("file (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,140 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package merger
+
+import (
+	"log"
+	"sort"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/label"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+)
+
+// MigrateLoads rewrites load statements in f according to migrations, so
+// that symbols that moved from one .bzl file to another are loaded from their
+// new location. Symbols are loaded from each new file with a single load
+// statement, added in place of the old statement, or merged into an existing
+// load of that file. Old statements are removed once they don't load
+// anything. Local names of symbols don't change; renamed symbols are loaded
+// with an alias.
+//
+// When several migrations apply to the same symbol, the first one wins, so
+// callers may list migrations that override others before them.
+// moduleToApparentName maps module names in migration labels to apparent
+// repository names; it may be nil.
+func MigrateLoads(f *rule.File, migrations []rule.LoadMigration, moduleToApparentName func(string) string) {
+	if len(migrations) == 0 || len(f.Loads) == 0 {
+		return
+	}
+
+	// Sync the file, so f.Loads doesn't contain deleted statements.
+	f.Sync()
+
+	apparentLabel := func(s string) string {
+		l, err := label.Parse(s)
+		if err != nil || l.Repo == "" || moduleToApparentName == nil {
+			return s
+		}
+		if repo := moduleToApparentName(l.Repo); repo != "" {
+			l.Repo = repo
+		}
+		return l.String()
+	}
+	byFrom := make(map[string][]rule.LoadMigration)
+	for _, m := range migrations {
+		if m.Module != "" && (moduleToApparentName == nil || moduleToApparentName(m.Module) == "") {
+			continue
+		}
+		from := apparentLabel(m.From)
+		byFrom[from] = append(byFrom[from], m)
+	}
+
+	loadsByName := make(map[string]*rule.Load)
+	for _, l := range f.Loads {
+		loadsByName[l.Name()] = l
+	}
+
+	for _, l := range append([]*rule.Load(nil), f.Loads...) {
+		fileMigrations := byFrom[l.Name()]
+		if len(fileMigrations) == 0 {
+			continue
+		}
+
+		type symbol struct{ from, to string }
+		moved := make(map[string][]symbol)
+		for _, pair := range l.SymbolPairs() {
+			m, ok := findLoadMigration(fileMigrations, pair.From)
+			if !ok {
+				continue
+			}
+			l.Remove(pair.To)
+			if m.To == "" {
+				log.Printf("%s: removed %q loaded from %s, which no longer provides it", f.Path, pair.From, l.Name())
+				continue
+			}
+			from := pair.From
+			if m.NewSymbol != "" {
+				from = m.NewSymbol
+			}
+			to := apparentLabel(m.To)
+			moved[to] = append(moved[to], symbol{from: from, to: pair.To})
+		}
+		if len(moved) == 0 {
+			continue
+		}
+
+		index := l.Index()
+		if l.IsEmpty() {
+			l.Delete()
+		}
+		names := make([]string, 0, len(moved))
+		for name := range moved {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			nl := loadsByName[name]
+			if nl == nil {
+				nl = rule.NewLoad(name)
+				nl.Insert(f, index)
+				loadsByName[name] = nl
+			}
+			for _, sym := range moved[name] {
+				if sym.from == sym.to {
+					nl.Add(sym.to)
+				} else {
+					nl.AddAlias(sym.from, sym.to)
+				}
+			}
+		}
+	}
+}
+
+// findLoadMigration returns the first migration in migrations for the
+// symbol sym, or the first migration for all symbols if there is none.
+func findLoadMigration(migrations []rule.LoadMigration, sym string) (rule.LoadMigration, bool) {
+	for _, m := range migrations {
+		if m.Symbol == sym {
+			return m, true
+		}
+	}
+	for _, m := range migrations {
+		if m.Symbol == "" {
+			return m, true
+		}
+	}
+	return rule.LoadMigration{}, false
+}
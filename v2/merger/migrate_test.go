@@ -0,0 +1,162 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package merger_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/merger"
+	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMigrateLoads(t *testing.T) {
+	moduleToApparentName := func(name string) string {
+		if name == "new_rules" {
+			return "my_new_rules"
+		}
+		return ""
+	}
+
+	for name, tc := range map[string]struct {
+		migrations []rule.LoadMigration
+		input      string
+		want       string
+	}{
+		"move symbol": {
+			migrations: []rule.LoadMigration{
+				{From: "@old_rules//:defs.bzl", Symbol: "foo_library", To: "@new_rules//foo:foo_library.bzl"},
+			},
+			input: `
+load("@old_rules//:defs.bzl", "foo_library")
+
+foo_library(name = "foo")
+`,
+			want: `
+load("@my_new_rules//foo:foo_library.bzl", "foo_library")
+
+foo_library(name = "foo")
+`,
+		},
+		"keep symbols without migrations": {
+			migrations: []rule.LoadMigration{
+				{From: "@old_rules//:defs.bzl", Symbol: "foo_library", To: "@new_rules//foo:foo_library.bzl"},
+			},
+			input: `
+load("@old_rules//:defs.bzl", "bar_library", "foo_library")
+`,
+			want: `
+load("@my_new_rules//foo:foo_library.bzl", "foo_library")
+load("@old_rules//:defs.bzl", "bar_library")
+`,
+		},
+		"merge into existing load": {
+			migrations: []rule.LoadMigration{
+				{From: "@old_rules//:defs.bzl", To: "@new_rules//:defs.bzl"},
+			},
+			input: `
+load("@my_new_rules//:defs.bzl", "bar_library")
+load("@old_rules//:defs.bzl", "baz_library", "foo_library")
+`,
+			want: `
+load("@my_new_rules//:defs.bzl", "bar_library", "baz_library", "foo_library")
+`,
+		},
+		"renamed symbol": {
+			migrations: []rule.LoadMigration{
+				{From: "//build:defs.bzl", Symbol: "lib", To: "//build:lib.bzl", NewSymbol: "library"},
+			},
+			input: `
+load("//build:defs.bzl", "lib")
+
+lib(name = "foo")
+`,
+			want: `
+load(
+    "//build:lib.bzl",
+    lib = "library",
+)
+
+lib(name = "foo")
+`,
+		},
+		"aliased symbol": {
+			migrations: []rule.LoadMigration{
+				{From: "//build:defs.bzl", Symbol: "lib", To: "//build:lib.bzl"},
+			},
+			input: `
+load("//build:defs.bzl", my_lib = "lib")
+`,
+			want: `
+load(
+    "//build:lib.bzl",
+    my_lib = "lib",
+)
+`,
+		},
+		"removed symbol": {
+			migrations: []rule.LoadMigration{
+				{From: "//build:defs.bzl", Symbol: "lib", To: "//build:lib.bzl"},
+				{From: "//build:defs.bzl"},
+			},
+			input: `
+load("//build:defs.bzl", "lib", "old_macro")
+`,
+			want: `
+load("//build:lib.bzl", "lib")
+`,
+		},
+		"first migration wins": {
+			migrations: []rule.LoadMigration{
+				{From: "//build:defs.bzl", Symbol: "lib", To: "//custom:lib.bzl"},
+				{From: "//build:defs.bzl", Symbol: "lib", To: "//build:lib.bzl"},
+			},
+			input: `
+load("//build:defs.bzl", "lib")
+`,
+			want: `
+load("//custom:lib.bzl", "lib")
+`,
+		},
+		"module not a dependency": {
+			migrations: []rule.LoadMigration{
+				{From: "@old_rules//:defs.bzl", To: "@other_rules//:defs.bzl", Module: "other_rules"},
+			},
+			input: `
+load("@old_rules//:defs.bzl", "foo_library")
+`,
+			want: `
+load("@old_rules//:defs.bzl", "foo_library")
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			merger.MigrateLoads(f, tc.migrations, moduleToApparentName)
+
+			want := strings.TrimSpace(tc.want)
+			got := strings.TrimSpace(string(f.Format()))
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("MigrateLoads() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
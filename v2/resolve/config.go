@@ -17,10 +17,11 @@ package resolve
 
 import (
 	"flag"
-	"log"
+	"fmt"
 	"regexp"
 	"strings"
 
+	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
 	"github.com/bazel-contrib/bazel-gazelle/v2/label"
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
 	"github.com/bazelbuild/bazel-gazelle/config"
@@ -147,7 +148,15 @@ func (*Configurer) Configure(c *config.Config, rel string, f *rule.File) {
 	regexpOverrides := rc.regexpOverrides[:len(rc.regexpOverrides):len(rc.regexpOverrides)]
 
 	for _, d := range f.Directives {
-		if d.Key == "resolve" {
+		if d.Key == "reset" {
+			if v2config.ResetsDirective(d, "resolve") {
+				rc = &resolveConfig{}
+				newOverrides = nil
+			}
+			if v2config.ResetsDirective(d, "resolve_regexp") {
+				regexpOverrides = nil
+			}
+		} else if d.Key == "resolve" {
 			parts := strings.Fields(d.Value)
 			key := overrideKey{}
 			var lbl string
@@ -162,15 +171,15 @@ func (*Configurer) Configure(c *config.Config, rel string, f *rule.File) {
 				key.imp.Imp = parts[2]
 				lbl = parts[3]
 			} else {
-				log.Printf("could not parse directive: %s\n\texpected gazelle:resolve source-language [import-language] import-string label", d.Value)
+				c.ReportDirectiveError(f, d, fmt.Errorf("could not parse %q: expected source-language [import-language] import-string label", d.Value))
 				continue
 			}
 			dep, err := label.Parse(lbl)
 			if err != nil {
-				log.Printf("gazelle:resolve %s: %v", d.Value, err)
+				c.ReportDirectiveError(f, d, err)
 				continue
 			}
-			dep = dep.Abs("", rel)
+			dep = dep.Apparent(c.ModuleToApparentName).Abs("", rel)
 			if newOverrides == nil {
 				newOverrides = make(map[overrideKey]label.Label, len(f.Directives))
 			}
@@ -184,7 +193,7 @@ func (*Configurer) Configure(c *config.Config, rel string, f *rule.File) {
 				var err error
 				o.ImpRegex, err = regexp.Compile(parts[1])
 				if err != nil {
-					log.Printf("gazelle:resolve_regexp %s: %v", d.Value, err)
+					c.ReportDirectiveError(f, d, err)
 					continue
 				}
 				lbl = parts[2]
@@ -194,22 +203,22 @@ func (*Configurer) Configure(c *config.Config, rel string, f *rule.File) {
 				var err error
 				o.ImpRegex, err = regexp.Compile(parts[2])
 				if err != nil {
-					log.Printf("gazelle:resolve_regexp %s: %v", d.Value, err)
+					c.ReportDirectiveError(f, d, err)
 					continue
 				}
 
 				lbl = parts[3]
 			} else {
-				log.Printf("could not parse directive: %s\n\texpected gazelle:resolve_regexp source-language [import-language] import-string-regex label", d.Value)
+				c.ReportDirectiveError(f, d, fmt.Errorf("could not parse %q: expected source-language [import-language] import-string-regex label", d.Value))
 				continue
 			}
 			var err error
 			o.dep, err = label.Parse(lbl)
 			if err != nil {
-				log.Printf("gazelle:resolve_regexp %s: %v", d.Value, err)
+				c.ReportDirectiveError(f, d, err)
 				continue
 			}
-			o.dep = o.dep.Abs("", rel)
+			o.dep = o.dep.Apparent(c.ModuleToApparentName).Abs("", rel)
 			regexpOverrides = append(regexpOverrides, o)
 		}
 	}
@@ -18,6 +18,8 @@ package resolve
 import (
 	"context"
 	"log"
+	"sync"
+	"sync/atomic"
 
 	"github.com/bazel-contrib/bazel-gazelle/v2/config"
 	"github.com/bazel-contrib/bazel-gazelle/v2/label"
@@ -113,6 +115,18 @@ type Resolver interface {
 	Resolve(context.Context, ResolveArgs) error
 }
 
+// ConcurrentResolver may be implemented by a language extension to declare
+// that its resolver is safe to call concurrently for rules in different
+// directories. That includes cross-resolving imports for other languages and,
+// for extensions that adjust build files after resolution, AfterResolve.
+// Gazelle resolves dependencies in several directories at once only when
+// every language reports this; otherwise, it resolves one rule at a time.
+type ConcurrentResolver interface {
+	// ResolvesConcurrently reports whether the resolver may be called
+	// concurrently.
+	ResolvesConcurrently() bool
+}
+
 type ResolveArgs struct {
 	// Config is the configuration for the current directory.
 	Config *config.Config
@@ -172,12 +186,16 @@ type RuleIndex struct {
 	// the Embeds method). This may include imports of other languages.
 	// Computed from `rules` when indexing.
 	imports map[label.Label][]ImportSpec
+
+	// The number of imports resolvers couldn't resolve, reported with
+	// ReportUnresolved, and the errors explaining why.
+	unresolved     atomic.Int64
+	unresolvedMu   sync.Mutex
+	unresolvedErrs []error
 }
 
 // ruleRecord contains information about a rule relevant to import indexing.
 type ruleRecord struct {
-	rule *rule.Rule
-
 	Kind  string      `json:"kind"`
 	Label label.Label `json:"label"`
 
@@ -225,7 +243,7 @@ func (ix *RuleIndex) AddRule(c *config.Config, r *rule.Rule, f *rule.File) {
 
 	if rslv := ix.mrslv(r, f.Pkg); rslv != nil {
 		lang = rslv.Name()
-		if passesLanguageFilter(c.Langs, lang) {
+		if c.LangEnabled(lang) {
 			result, err := rslv.Imports(context.TODO(), ImportsArgs{
 				Config: c,
 				Rule:   r,
@@ -250,7 +268,6 @@ func (ix *RuleIndex) AddRule(c *config.Config, r *rule.Rule, f *rule.File) {
 	}
 
 	record := &ruleRecord{
-		rule:       r,
 		Kind:       r.Kind(),
 		Pkg:        f.Pkg,
 		Label:      l,
@@ -287,6 +304,29 @@ func (ix *RuleIndex) Finish() {
 	ix.indexed = true
 }
 
+// Reopen lets rules be added to a finished index again, so that a
+// long-running process, like gazelle watch, can update the index when build
+// files change instead of indexing the whole repository again. Rules in
+// packages for which drop returns true are removed, and imports reported with
+// ReportUnresolved are forgotten. AddRule may then be called with the new
+// rules in those packages, and Finish must be called again before the index
+// is used.
+func (ix *RuleIndex) Reopen(drop func(pkg string) bool) {
+	rules := make([]*ruleRecord, 0, len(ix.rules))
+	for _, r := range ix.rules {
+		if !drop(r.Pkg) {
+			rules = append(rules, r)
+		}
+	}
+	ix.rules = rules
+	ix.indexed = false
+
+	ix.unresolvedMu.Lock()
+	defer ix.unresolvedMu.Unlock()
+	ix.unresolved.Store(0)
+	ix.unresolvedErrs = nil
+}
+
 func (ix *RuleIndex) collectEmbeds() {
 	ix.embeds = make(map[label.Label][]label.Label)
 	ix.embedded = make(map[label.Label]struct{})
@@ -301,7 +341,6 @@ func (ix *RuleIndex) collectRecordEmbeds(r *ruleRecord, didCollectEmbeds map[lab
 	if _, ok := didCollectEmbeds[r.Label]; ok {
 		return
 	}
-	resolver := ix.mrslv(r.rule, r.Pkg)
 	didCollectEmbeds[r.Label] = true
 	ix.embeds[r.Label] = r.Embeds
 	for _, e := range r.Embeds {
@@ -310,8 +349,7 @@ func (ix *RuleIndex) collectRecordEmbeds(r *ruleRecord, didCollectEmbeds map[lab
 			continue
 		}
 		ix.collectRecordEmbeds(er, didCollectEmbeds)
-		erResolver := ix.mrslv(er.rule, er.Pkg)
-		if resolver.Name() == erResolver.Name() {
+		if r.Lang == er.Lang {
 			ix.embedded[er.Label] = struct{}{}
 			ix.embeds[r.Label] = append(ix.embeds[r.Label], ix.embeds[er.Label]...)
 		}
@@ -348,6 +386,44 @@ type FindResult struct {
 	Embeds []label.Label
 }
 
+// ReportUnresolved logs err, which explains why an import couldn't be
+// resolved, and counts it. Resolvers should call this instead of logging
+// resolution errors themselves, so that Gazelle can fail with a distinct
+// exit code when imports can't be resolved in -strict mode. It may be called
+// concurrently.
+func (ix *RuleIndex) ReportUnresolved(err error) {
+	ix.unresolved.Add(1)
+	ix.unresolvedMu.Lock()
+	ix.unresolvedErrs = append(ix.unresolvedErrs, err)
+	ix.unresolvedMu.Unlock()
+	log.Print(err)
+}
+
+// UnresolvedErrors returns the errors reported with ReportUnresolved, in the
+// order they were reported.
+func (ix *RuleIndex) UnresolvedErrors() []error {
+	ix.unresolvedMu.Lock()
+	defer ix.unresolvedMu.Unlock()
+	return append([]error(nil), ix.unresolvedErrs...)
+}
+
+// UnresolvedCount returns the number of imports reported with
+// ReportUnresolved.
+func (ix *RuleIndex) UnresolvedCount() int {
+	return int(ix.unresolved.Load())
+}
+
+// IsIndexed returns whether the rule with label l was indexed, meaning other
+// rules may depend on it through one of its imports. Rules embedded by other
+// rules aren't indexed. IsIndexed may only be called after Finish.
+func (ix *RuleIndex) IsIndexed(l label.Label) bool {
+	if _, ok := ix.labelMap[l]; !ok {
+		return false
+	}
+	_, embedded := ix.embedded[l]
+	return !embedded
+}
+
 // FindRulesByImport attempts to resolve an import string to a rule record.
 // imp is the import to resolve (which includes the target language). lang is
 // the language of the rule with the dependency (for example, in
@@ -418,17 +494,3 @@ func (r FindResult) IsSelfImport(from label.Label) bool {
 	}
 	return false
 }
-
-// passesLanguageFilter returns true if the filter is empty (disabled) or if the
-// given language name appears in it.
-func passesLanguageFilter(langFilter []string, langName string) bool {
-	if len(langFilter) == 0 {
-		return true
-	}
-	for _, l := range langFilter {
-		if l == langName {
-			return true
-		}
-	}
-	return false
-}
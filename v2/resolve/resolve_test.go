@@ -30,10 +30,27 @@ func TestFindRuleWithOverride_ParentTraversal(t *testing.T) {
 		{Key: "resolve_regexp", Value: "go ^github.com/foo/(.*)/(.*)$ @com_example//$1/bar_sub_dir/$2:replacement"},
 	}, rootCfg)
 
+	resetCfg := getConfig(t, "reset", []rule.Directive{
+		{Key: "reset", Value: "resolve"},
+		{Key: "resolve", Value: "go github.com/child/repo //reset:replacement"},
+	}, rootCfg)
+
 	nonCapturedRegexpCfg := getConfig(t, "", []rule.Directive{
 		{Key: "resolve_regexp", Value: "py github.com/\\.* @com_example//regexp:no_replacement"},
 	}, nil)
 
+	canonicalCfg := &config.Config{
+		Exts: map[string]interface{}{},
+		ModuleToApparentName: func(module string) string {
+			return map[string]string{"rules_foo": "foo"}[module]
+		},
+	}
+	(&Configurer{}).RegisterFlags(nil, "", canonicalCfg)
+	(&Configurer{}).Configure(canonicalCfg, "", &rule.File{Directives: []rule.Directive{
+		{Key: "resolve", Value: "go github.com/main/repo @@//main:lib"},
+		{Key: "resolve", Value: "go github.com/foo/repo @@rules_foo+//foo:lib"},
+	}})
+
 	tests := []struct {
 		name       string
 		cfg        *config.Config
@@ -98,6 +115,22 @@ func TestFindRuleWithOverride_ParentTraversal(t *testing.T) {
 			want:       getTestLabel(t, "@com_example//root:replacement"),
 			wantFound:  true,
 		},
+		{
+			name:       "Reset child falls back to root regexp directive",
+			cfg:        resetCfg,
+			importSpec: ImportSpec{Lang: "go", Imp: "github.com/root/repo"},
+			lang:       "go",
+			want:       getTestLabel(t, "@com_example//regexp:replacement"),
+			wantFound:  true,
+		},
+		{
+			name:       "Reset child finds directive after reset",
+			cfg:        resetCfg,
+			importSpec: ImportSpec{Lang: "go", Imp: "github.com/child/repo"},
+			lang:       "go",
+			want:       getTestLabel(t, "//reset:replacement"),
+			wantFound:  true,
+		},
 		{
 			name:       "Target resolves to label populated by regexp",
 			cfg:        dualResolveRegexpCfg,
@@ -122,6 +155,22 @@ func TestFindRuleWithOverride_ParentTraversal(t *testing.T) {
 			want:       getTestLabel(t, "@com_example//regexp:no_replacement"),
 			wantFound:  true,
 		},
+		{
+			name:       "Canonical main repository label",
+			cfg:        canonicalCfg,
+			importSpec: ImportSpec{Lang: "go", Imp: "github.com/main/repo"},
+			lang:       "go",
+			want:       getTestLabel(t, "//main:lib"),
+			wantFound:  true,
+		},
+		{
+			name:       "Canonical module label mapped to apparent name",
+			cfg:        canonicalCfg,
+			importSpec: ImportSpec{Lang: "go", Imp: "github.com/foo/repo"},
+			lang:       "go",
+			want:       getTestLabel(t, "@foo//foo:lib"),
+			wantFound:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -18,6 +18,7 @@ package rule
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 
@@ -75,6 +76,33 @@ func parseDirectives(stmt []bzl.Expr) []Directive {
 	return directives
 }
 
+// DirectiveLine returns the line in f where the directive d is written,
+// or 0 if d doesn't appear in a comment in f. Directives added after f was
+// loaded, or read from other files, don't have lines.
+func (f *File) DirectiveLine(d Directive) int {
+	if f == nil || f.File == nil {
+		return 0
+	}
+	stmts := f.File.Stmt
+	for _, s := range f.File.Stmt {
+		if def, ok := s.(*bzl.DefStmt); ok && def.Name == f.DefName {
+			stmts = append(stmts[:len(stmts):len(stmts)], def.Body...)
+		}
+	}
+	for _, s := range stmts {
+		coms := s.Comment()
+		for _, list := range [][]bzl.Comment{coms.Before, coms.After} {
+			for _, com := range list {
+				match := directiveRe.FindStringSubmatch(com.Token)
+				if match != nil && match[1] == d.Key && match[2] == d.Value {
+					return com.Start.Line
+				}
+			}
+		}
+	}
+	return 0
+}
+
 var directiveRe = regexp.MustCompile(`^#\s*gazelle:(\w+)\s*(.*?)\s*$`)
 var fileDirectiveRe = regexp.MustCompile(`^(?:#\s*gazelle:)?(\w+)\s*(.*?)\s*$`)
 
@@ -88,9 +116,14 @@ func ParseDirectivesFromFile(filePath string) ([]Directive, error) {
 		return nil, fmt.Errorf("reading directive file: %w", err)
 	}
 	defer f.Close()
+	return ParseDirectivesFromReader(f)
+}
 
+// ParseDirectivesFromReader is like ParseDirectivesFromFile, but it reads
+// directives from r.
+func ParseDirectivesFromReader(r io.Reader) ([]Directive, error) {
 	var directives []Directive
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
 		match := fileDirectiveRe.FindStringSubmatch(line)
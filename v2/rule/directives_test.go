@@ -64,6 +64,35 @@ foo(
 	}
 }
 
+func TestDirectiveLine(t *testing.T) {
+	f, err := LoadData("BUILD.bazel", "", []byte(`# gazelle:prefix example.com/foo
+
+# gazelle:exclude a
+foo(
+    # gazelle:exclude b
+    name = "foo",
+)
+# gazelle:exclude c
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		d    Directive
+		want int
+	}{
+		{d: Directive{"prefix", "example.com/foo"}, want: 1},
+		{d: Directive{"exclude", "a"}, want: 3},
+		{d: Directive{"exclude", "b"}, want: 0},
+		{d: Directive{"exclude", "c"}, want: 8},
+		{d: Directive{"exclude", "d"}, want: 0},
+	} {
+		if got := f.DirectiveLine(tc.d); got != tc.want {
+			t.Errorf("DirectiveLine(%v): got %d, want %d", tc.d, got, tc.want)
+		}
+	}
+}
+
 func TestParseDirectivesFromFile(t *testing.T) {
 	for _, tc := range []struct {
 		desc    string
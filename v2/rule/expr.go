@@ -16,6 +16,7 @@ limitations under the License.
 package rule
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -164,6 +165,95 @@ func FlattenExpr(e bzl.Expr) bzl.Expr {
 	return ls.list()
 }
 
+// errOpaqueExpr indicates that an expression contains a part that Gazelle
+// can't interpret statically, like a list comprehension, a conditional
+// expression, a reference to a variable, or a call to a function other than
+// select. Attributes with such expressions are preserved during merging.
+var errOpaqueExpr = errors.New("expression is not a list or select")
+
+// isOpaqueExpr returns whether e contains a part that Gazelle can't interpret
+// statically. See errOpaqueExpr.
+func isOpaqueExpr(e bzl.Expr) bool {
+	switch e := e.(type) {
+	case nil, *bzl.StringExpr, *bzl.LiteralExpr:
+		return false
+	case *bzl.Ident:
+		return e.Name != "True" && e.Name != "False" && e.Name != "None"
+	}
+	_, err := extractPlatformStringsExprs(e)
+	return errors.Is(err, errOpaqueExpr)
+}
+
+// staticString returns the value of e if it can be determined without
+// evaluating the file: e must be a string literal, a reference to a
+// string constant in consts, or a concatenation of those with +.
+// staticString returns "" and false otherwise.
+func staticString(e bzl.Expr, consts map[string]bzl.Expr) (string, bool) {
+	return staticStringDepth(e, consts, 0)
+}
+
+func staticStringDepth(e bzl.Expr, consts map[string]bzl.Expr, depth int) (string, bool) {
+	if depth > len(consts) {
+		// Constants refer to each other in a cycle.
+		return "", false
+	}
+	switch e := e.(type) {
+	case *bzl.StringExpr:
+		return e.Value, true
+	case *bzl.ParenExpr:
+		return staticStringDepth(e.X, consts, depth)
+	case *bzl.Ident:
+		if v, ok := consts[e.Name]; ok {
+			return staticStringDepth(v, consts, depth+1)
+		}
+	case *bzl.BinaryExpr:
+		if e.Op != "+" {
+			break
+		}
+		x, ok := staticStringDepth(e.X, consts, depth)
+		if !ok {
+			break
+		}
+		if y, ok := staticStringDepth(e.Y, consts, depth); ok {
+			return x + y, true
+		}
+	}
+	return "", false
+}
+
+// collectConstants returns a map from names assigned exactly once in stmts
+// to the expressions assigned to them. These may be used by staticString
+// to determine values of attributes like name and importpath that are
+// computed from constants, for example:
+//
+//	NAME = "foo"
+//
+//	go_library(
+//	    name = NAME + "_lib",
+//	    importpath = "example.com/" + NAME,
+//	)
+func collectConstants(stmts []bzl.Expr) map[string]bzl.Expr {
+	consts := make(map[string]bzl.Expr)
+	seen := make(map[string]bool)
+	for _, stmt := range stmts {
+		assign, ok := stmt.(*bzl.AssignExpr)
+		if !ok || assign.Op != "=" {
+			continue
+		}
+		lhs, ok := assign.LHS.(*bzl.Ident)
+		if !ok {
+			continue
+		}
+		if seen[lhs.Name] {
+			delete(consts, lhs.Name)
+			continue
+		}
+		seen[lhs.Name] = true
+		consts[lhs.Name] = assign.RHS
+	}
+	return consts
+}
+
 func isScalar(e bzl.Expr) bool {
 	switch e.(type) {
 	case *bzl.StringExpr, *bzl.LiteralExpr, *bzl.Ident:
@@ -189,6 +279,11 @@ func dictEntryKeyValue(e bzl.Expr) (string, *bzl.ListExpr, error) {
 	return k.Value, v, nil
 }
 
+func isStringExpr(e bzl.Expr) bool {
+	_, ok := e.(*bzl.StringExpr)
+	return ok
+}
+
 func stringValue(e bzl.Expr) string {
 	s, ok := e.(*bzl.StringExpr)
 	if !ok {
@@ -247,8 +342,11 @@ func extractPlatformStringsExprs(expr bzl.Expr) (platformStringsExprs, error) {
 
 		case *bzl.CallExpr:
 			x, ok := part.X.(*bzl.Ident)
-			if !ok || x.Name != "select" || len(part.List) != 1 {
-				return platformStringsExprs{}, fmt.Errorf("expression could not be matched: callee other than select or wrong number of args")
+			if !ok || x.Name != "select" {
+				return platformStringsExprs{}, fmt.Errorf("expression could not be matched: callee other than select: %w", errOpaqueExpr)
+			}
+			if len(part.List) != 1 {
+				return platformStringsExprs{}, fmt.Errorf("expression could not be matched: wrong number of args to select")
 			}
 			arg, ok := part.List[0].(*bzl.DictExpr)
 			if !ok {
@@ -292,6 +390,9 @@ func extractPlatformStringsExprs(expr bzl.Expr) (platformStringsExprs, error) {
 				return platformStringsExprs{}, fmt.Errorf("expression could not be matched: multiple selects that are either os-specific, arch-specific, or platform-specific")
 			}
 			*dict = arg
+
+		default:
+			return platformStringsExprs{}, fmt.Errorf("expression could not be matched: %w", errOpaqueExpr)
 		}
 	}
 	return ps, nil
@@ -118,7 +118,11 @@ func areScalarsAndEqual(x, y bzl.Expr) bool {
 //     and the values must be lists of strings.
 //   - a list of strings combined with a select call using +. The list must
 //     be the left operand.
-//   - an attr value that implements the Merger interface.
+//   - an attr value that implements the Merger interface. Mergers may also
+//     merge into dict literals, which are otherwise preserved.
+//
+// Existing values that contain other expressions, like list comprehensions,
+// conditional expressions, or variable references, are preserved.
 //
 // An error is returned if the expressions can't be merged, for example
 // because they are not in one of the above formats.
@@ -132,6 +136,11 @@ func mergeAttrValues(srcAttr, dstAttr *attrValue) (bzl.Expr, error) {
 		return mergedScalarDst, nil
 	}
 	dst := dstAttr.expr.RHS
+	if isOpaqueExpr(dst) && !mergesIntoDict(srcAttr, dst) {
+		// The existing value is computed in a way Gazelle doesn't understand.
+		// Treat it as if it were marked with "# keep".
+		return dst, nil
+	}
 	if srcAttr == nil && (dst == nil || isScalar(dst)) {
 		return mergedScalarDst, nil
 	}
@@ -160,15 +169,24 @@ func mergeAttrValues(srcAttr, dstAttr *attrValue) (bzl.Expr, error) {
 			return srcMerger.Merge(dst), nil
 		}
 	}
-	var srcExprs platformStringsExprs
-	var err error
+	var src bzl.Expr
 	if srcAttr != nil {
-		srcExprs, err = extractPlatformStringsExprs(srcAttr.expr.RHS)
-		if err != nil {
-			return nil, err
-		}
+		src = srcAttr.expr.RHS
 	}
+	return MergePlatformStrings(src, dst)
+}
 
+// MergePlatformStrings merges src into dst the way mergeable attributes are
+// merged when the generated value doesn't implement Merger. Each may be nil,
+// a list of strings, a call to select with a dict argument, or a list
+// combined with select calls using +. An error is returned if either is in
+// some other format. Mergers can use it for existing values they don't
+// handle themselves.
+func MergePlatformStrings(src, dst bzl.Expr) (bzl.Expr, error) {
+	srcExprs, err := extractPlatformStringsExprs(src)
+	if err != nil {
+		return nil, err
+	}
 	dstExprs, err := extractPlatformStringsExprs(dst)
 	if err != nil {
 		return nil, err
@@ -180,6 +198,18 @@ func mergeAttrValues(srcAttr, dstAttr *attrValue) (bzl.Expr, error) {
 	return makePlatformStringsExpr(mergedExprs), nil
 }
 
+// mergesIntoDict returns whether the value of srcAttr implements Merger and
+// dst is a dict literal. Dict literals are opaque to the default merge, but
+// a Merger for a dict-valued attribute, like env, can merge into them.
+func mergesIntoDict(srcAttr *attrValue, dst bzl.Expr) bool {
+	if srcAttr == nil {
+		return false
+	}
+	_, isMerger := srcAttr.val.(Merger)
+	_, isDict := dst.(*bzl.DictExpr)
+	return isMerger && isDict
+}
+
 func mergePlatformStringsExprs(src, dst platformStringsExprs) (platformStringsExprs, error) {
 	var ps platformStringsExprs
 	var err error
@@ -206,6 +236,8 @@ var RemoveNoopKeepComments bool = false
 //   - If a string appears in only src list, it appears in the result.
 //   - If a string appears in only dst list, it is dropped from the result.
 //   - If a string appears in neither list, it is dropped from the result.
+//   - Elements of the dst list that are not string literals (for example,
+//     variable references or conditional expressions) are preserved.
 //
 // The result is nil if both lists are nil or empty.
 //
@@ -240,7 +272,7 @@ func MergeList(srcExpr, dstExpr bzl.Expr) *bzl.ListExpr {
 	keepComment := false
 	for _, v := range dst.List {
 		s := stringValue(v)
-		if keep := ShouldKeep(v); keep || srcSet[s] {
+		if keep := ShouldKeep(v); keep || srcSet[s] || !isStringExpr(v) {
 			if srcSet[s] && RemoveNoopKeepComments {
 				*v.Comment() = removeKeep(v)
 			} else {
@@ -364,11 +396,18 @@ type dictEntry struct {
 	dstValue, srcValue, mergedValue *bzl.ListExpr
 }
 
+// SquashedIntoKey is the key of a private attribute SquashRules sets on
+// src to the name of dst. When src is deleted afterward, for example by a
+// language's Fix method, Gazelle updates references to src in other build
+// files to refer to dst.
+const SquashedIntoKey = "_gazelle_squashed_into"
+
 // SquashRules copies information from src into dst without discarding
 // information in dst. SquashRules detects duplicate elements in lists and
 // dictionaries, but it doesn't sort elements after squashing. If squashing
 // fails because the expression is not understood, an error is returned,
-// and neither rule is modified.
+// and neither rule is modified. Otherwise, the SquashedIntoKey private
+// attribute is set on src.
 func SquashRules(src, dst *Rule, filename string) error {
 	if dst.ShouldKeep() {
 		return nil
@@ -391,6 +430,7 @@ func SquashRules(src, dst *Rule, filename string) error {
 	dst.expr.Comment().Before = append(dst.expr.Comment().Before, src.expr.Comment().Before...)
 	dst.expr.Comment().Suffix = append(dst.expr.Comment().Suffix, src.expr.Comment().Suffix...)
 	dst.expr.Comment().After = append(dst.expr.Comment().After, src.expr.Comment().After...)
+	src.SetPrivateAttr(SquashedIntoKey, dst.Name())
 	return nil
 }
 
@@ -398,7 +438,7 @@ func squashExprs(src, dst bzl.Expr) (bzl.Expr, error) {
 	if ShouldKeep(dst) {
 		return dst, nil
 	}
-	if isScalar(dst) {
+	if isScalar(dst) || isOpaqueExpr(dst) {
 		// may lose src, but they should always be the same.
 		return dst, nil
 	}
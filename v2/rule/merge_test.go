@@ -148,6 +148,51 @@ func TestMergeRules_WithUnsortedStringAttr(t *testing.T) {
 	})
 }
 
+// dictAdder is a Merger that adds a key to a dict.
+type dictAdder struct{ key, value string }
+
+func (d dictAdder) BzlExpr() bzl.Expr {
+	return d.Merge(nil)
+}
+
+func (d dictAdder) Merge(other bzl.Expr) bzl.Expr {
+	kv := &bzl.KeyValueExpr{Key: &bzl.StringExpr{Value: d.key}, Value: &bzl.StringExpr{Value: d.value}}
+	if dict, ok := other.(*bzl.DictExpr); ok {
+		return &bzl.DictExpr{List: append(dict.List[:len(dict.List):len(dict.List)], kv)}
+	}
+	return &bzl.DictExpr{List: []*bzl.KeyValueExpr{kv}}
+}
+
+func TestMergeRules_MergerIntoDict(t *testing.T) {
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+go_test(
+    name = "a_test",
+    env = {"OLD": "1"},
+    tags = {"not": "a list"},
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := rule.NewRule("go_test", "a_test")
+	src.SetAttr("env", dictAdder{key: "NEW", value: "2"})
+	rule.MergeRules(src, f.Rules[0], map[string]bool{"env": true, "tags": true}, f.Path)
+
+	// Dicts without a Merger are still preserved.
+	want := `go_test(
+    name = "a_test",
+    env = {
+        "OLD": "1",
+        "NEW": "2",
+    },
+    tags = {"not": "a list"},
+)
+`
+	if got := string(f.Format()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
 func TestMergeRules_DictWithExplicitEmptyList(t *testing.T) {
 	const want = `{
     "@platforms//os:linux": [],
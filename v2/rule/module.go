@@ -0,0 +1,767 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rule
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// ModuleFile provides editing functionality for a MODULE.bazel file. You can
+// create a new file with EmptyModuleFile or load an existing file with
+// LoadModuleFile. After changes have been made, call Save to write changes
+// back to a file.
+//
+// Unlike File, ModuleFile understands the structure of MODULE.bazel:
+// bazel_dep calls, module extension proxies declared with use_extension,
+// use_repo calls that import repositories from those extensions, extension
+// tags, and overrides. Editing methods apply Gazelle's usual merge semantics:
+// existing statements are updated in place, statements and list entries
+// marked with "# keep" comments are not modified or removed, and new
+// statements are inserted next to related existing statements.
+type ModuleFile struct {
+	// File is the underlying syntax tree. Editing methods modify this tree
+	// directly, except for changes made through *Rule values returned by
+	// Overrides and Tags, which are written back when Sync is called.
+	File *bzl.File
+
+	// Path is the file system path to the module file (same as File.Path).
+	Path string
+
+	// Content is the file's underlying disk content, which is recorded when the
+	// file is initially loaded and whenever it is saved back to disk.
+	Content []byte
+
+	// rules contains rules returned by Overrides and Tags, which must be
+	// synced back into the syntax tree.
+	rules []*Rule
+}
+
+// BazelDep describes a bazel_dep call in a MODULE.bazel file.
+type BazelDep struct {
+	// Name is the name of the module.
+	Name string
+
+	// Version is the requested version of the module. It may be empty.
+	Version string
+
+	// RepoName is the apparent name of the module's repository, if it differs
+	// from Name.
+	RepoName string
+
+	// DevDependency is true if the dependency is only used when this module is
+	// the root module.
+	DevDependency bool
+}
+
+// ExtensionProxy describes a module extension usage, declared with an
+// assignment like:
+//
+//	go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+type ExtensionProxy struct {
+	// Ident is the name of the variable the proxy is assigned to.
+	Ident string
+
+	// BzlFile is the label of the .bzl file that defines the extension.
+	BzlFile string
+
+	// Name is the name of the extension within BzlFile.
+	Name string
+
+	// DevDependency is true if the proxy was declared with
+	// dev_dependency = True.
+	DevDependency bool
+
+	// Isolate is true if the proxy was declared with isolate = True.
+	Isolate bool
+}
+
+// overrideKinds is the set of built-in functions that override the version or
+// source of a module. All of them identify the module with a module_name
+// attribute.
+var overrideKinds = map[string]bool{
+	"archive_override":          true,
+	"git_override":              true,
+	"local_path_override":       true,
+	"multiple_version_override": true,
+	"single_version_override":   true,
+}
+
+// EmptyModuleFile creates a ModuleFile wrapped around an empty syntax tree.
+func EmptyModuleFile(path string) *ModuleFile {
+	return &ModuleFile{
+		File: &bzl.File{Path: path, Type: bzl.TypeModule},
+		Path: path,
+	}
+}
+
+// LoadModuleFile loads a MODULE.bazel file from disk and parses it.
+//
+// This function returns I/O and parse errors without modification. It's safe
+// to use os.IsNotExist and similar predicates.
+func LoadModuleFile(path string) (*ModuleFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadModuleData(path, data)
+}
+
+// LoadModuleData parses a MODULE.bazel file from a byte slice.
+func LoadModuleData(path string, data []byte) (*ModuleFile, error) {
+	ast, err := bzl.ParseModule(path, data)
+	if err != nil {
+		return nil, err
+	}
+	return &ModuleFile{
+		File:    ast,
+		Path:    path,
+		Content: data,
+	}, nil
+}
+
+// ModuleName returns the name declared by the module() call, or "" if there
+// is no such call.
+func (f *ModuleFile) ModuleName() string {
+	for _, stmt := range f.File.Stmt {
+		if call, ok := callNamed(stmt, "module"); ok {
+			return callAttrString(call, "name")
+		}
+	}
+	return ""
+}
+
+// BazelDeps returns the bazel_dep calls in the file in the order they appear.
+func (f *ModuleFile) BazelDeps() []BazelDep {
+	var deps []BazelDep
+	for _, stmt := range f.File.Stmt {
+		if call, ok := callNamed(stmt, "bazel_dep"); ok {
+			deps = append(deps, bazelDepFromCall(call))
+		}
+	}
+	return deps
+}
+
+// BazelDep returns the bazel_dep for the named module and true, or the zero
+// value and false if the module is not a dependency.
+func (f *ModuleFile) BazelDep(name string) (BazelDep, bool) {
+	if call := f.findBazelDep(name); call != nil {
+		return bazelDepFromCall(call), true
+	}
+	return BazelDep{}, false
+}
+
+// SetBazelDep adds a bazel_dep for dep.Name or updates an existing one.
+//
+// When a bazel_dep for the module already exists, its version, repo_name, and
+// dev_dependency attributes are replaced, and other attributes and comments
+// are preserved. Existing calls marked with "# keep" are not modified. New
+// calls are inserted after the last bazel_dep, or after the module() call if
+// there are no dependencies yet.
+func (f *ModuleFile) SetBazelDep(dep BazelDep) {
+	if call := f.findBazelDep(dep.Name); call != nil {
+		if ShouldKeep(call) {
+			return
+		}
+		setCallAttr(call, "version", stringExprOrNil(dep.Version))
+		setCallAttr(call, "repo_name", stringExprOrNil(dep.RepoName))
+		if dep.DevDependency {
+			setCallAttr(call, "dev_dependency", &bzl.Ident{Name: "True"})
+		} else {
+			setCallAttr(call, "dev_dependency", nil)
+		}
+		return
+	}
+
+	call := &bzl.CallExpr{X: &bzl.Ident{Name: "bazel_dep"}}
+	setCallAttr(call, "name", &bzl.StringExpr{Value: dep.Name})
+	setCallAttr(call, "version", stringExprOrNil(dep.Version))
+	setCallAttr(call, "repo_name", stringExprOrNil(dep.RepoName))
+	if dep.DevDependency {
+		setCallAttr(call, "dev_dependency", &bzl.Ident{Name: "True"})
+	}
+	index := f.lastIndex(func(stmt bzl.Expr) bool {
+		_, ok := callNamed(stmt, "bazel_dep")
+		return ok
+	})
+	if index < 0 {
+		index = f.lastIndex(func(stmt bzl.Expr) bool {
+			_, ok := callNamed(stmt, "module")
+			return ok
+		})
+	}
+	f.insertStmt(index+1, call)
+}
+
+// RemoveBazelDep removes the bazel_dep for the named module. It returns false
+// if there is no such dependency or if the call is marked with "# keep".
+func (f *ModuleFile) RemoveBazelDep(name string) bool {
+	call := f.findBazelDep(name)
+	if call == nil || ShouldKeep(call) {
+		return false
+	}
+	f.deleteStmt(call)
+	return true
+}
+
+// ExtensionProxies returns the module extension usages declared in the file
+// in the order they appear.
+func (f *ModuleFile) ExtensionProxies() []ExtensionProxy {
+	var proxies []ExtensionProxy
+	for _, stmt := range f.File.Stmt {
+		if p, ok := extensionProxyFromStmt(stmt); ok {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// UseExtension returns the name of the proxy variable for the given extension,
+// declaring it with use_extension if needed. Dev and non-dev usages of the
+// same extension are distinct proxies.
+func (f *ModuleFile) UseExtension(bzlFile, name string, devDependency bool) string {
+	for _, p := range f.ExtensionProxies() {
+		if p.BzlFile == bzlFile && p.Name == name && p.DevDependency == devDependency && !p.Isolate {
+			return p.Ident
+		}
+	}
+
+	ident := name
+	if devDependency {
+		ident += "_dev"
+	}
+	taken := make(map[string]bool)
+	for _, stmt := range f.File.Stmt {
+		if assign, ok := stmt.(*bzl.AssignExpr); ok {
+			if lhs, ok := assign.LHS.(*bzl.Ident); ok {
+				taken[lhs.Name] = true
+			}
+		}
+	}
+	for i := 2; taken[ident]; i++ {
+		ident = fmt.Sprintf("%s%d", name, i)
+		if devDependency {
+			ident = fmt.Sprintf("%s_dev%d", name, i)
+		}
+	}
+
+	call := &bzl.CallExpr{
+		X: &bzl.Ident{Name: "use_extension"},
+		List: []bzl.Expr{
+			&bzl.StringExpr{Value: bzlFile},
+			&bzl.StringExpr{Value: name},
+		},
+	}
+	if devDependency {
+		setCallAttr(call, "dev_dependency", &bzl.Ident{Name: "True"})
+	}
+	assign := &bzl.AssignExpr{
+		LHS: &bzl.Ident{Name: ident},
+		Op:  "=",
+		RHS: call,
+	}
+	index := f.lastIndex(func(stmt bzl.Expr) bool {
+		_, ok := callNamed(stmt, "bazel_dep")
+		return ok || f.usesProxy(stmt) != ""
+	})
+	if index < 0 {
+		index = len(f.File.Stmt) - 1
+	}
+	f.insertStmt(index+1, assign)
+	return ident
+}
+
+// UseRepos returns the apparent repository names imported from the given
+// extension proxy by use_repo calls. Names that are remapped with keyword
+// arguments are returned with their new (local) names.
+func (f *ModuleFile) UseRepos(ident string) []string {
+	var repos []string
+	for _, call := range f.useRepoCalls(ident) {
+		for _, arg := range call.List[1:] {
+			if name := useRepoArgName(arg); name != "" {
+				repos = append(repos, name)
+			}
+		}
+	}
+	return repos
+}
+
+// UseRepoMapping returns a map from the apparent names of repositories
+// imported from the given extension proxy by use_repo calls to the names the
+// extension gives them. The names are the same unless they're remapped with
+// keyword arguments.
+func (f *ModuleFile) UseRepoMapping(ident string) map[string]string {
+	m := make(map[string]string)
+	for _, call := range f.useRepoCalls(ident) {
+		for _, arg := range call.List[1:] {
+			switch arg := arg.(type) {
+			case *bzl.StringExpr:
+				m[arg.Value] = arg.Value
+			case *bzl.AssignExpr:
+				lhs, ok := arg.LHS.(*bzl.Ident)
+				rhs, ok2 := arg.RHS.(*bzl.StringExpr)
+				if ok && ok2 {
+					m[lhs.Name] = rhs.Value
+				}
+			}
+		}
+	}
+	return m
+}
+
+// AddUseRepos adds repos to the use_repo call for the given extension proxy,
+// creating the call if needed. Names that are already imported are skipped.
+// New names are merged into the last use_repo call for the proxy, and the
+// string arguments of that call are kept in sorted order.
+func (f *ModuleFile) AddUseRepos(ident string, repos ...string) {
+	have := make(map[string]bool)
+	for _, r := range f.UseRepos(ident) {
+		have[r] = true
+	}
+	var add []string
+	for _, r := range repos {
+		if !have[r] {
+			add = append(add, r)
+			have[r] = true
+		}
+	}
+	if len(add) == 0 {
+		return
+	}
+
+	calls := f.useRepoCalls(ident)
+	var call *bzl.CallExpr
+	if len(calls) > 0 {
+		call = calls[len(calls)-1]
+	} else {
+		call = &bzl.CallExpr{
+			X:    &bzl.Ident{Name: "use_repo"},
+			List: []bzl.Expr{&bzl.Ident{Name: ident}},
+		}
+		index := f.lastIndex(func(stmt bzl.Expr) bool {
+			return f.usesProxy(stmt) == ident
+		})
+		f.insertStmt(index+1, call)
+	}
+	for _, r := range add {
+		call.List = append(call.List, &bzl.StringExpr{Value: r})
+	}
+	sortUseRepoArgs(call)
+}
+
+// SetUseRepos replaces the repositories imported from the given extension
+// proxy with repos. Existing names that are not in repos are removed unless
+// they are marked with "# keep", and use_repo calls that become empty are
+// deleted. Missing names are added as with AddUseRepos.
+func (f *ModuleFile) SetUseRepos(ident string, repos []string) {
+	want := make(map[string]bool)
+	for _, r := range repos {
+		want[r] = true
+	}
+	for _, call := range f.useRepoCalls(ident) {
+		if ShouldKeep(call) {
+			continue
+		}
+		list := call.List[:1]
+		for _, arg := range call.List[1:] {
+			if name := useRepoArgName(arg); name == "" || want[name] || ShouldKeep(arg) {
+				list = append(list, arg)
+			}
+		}
+		call.List = list
+		if len(call.List) == 1 {
+			f.deleteStmt(call)
+		}
+	}
+	f.AddUseRepos(ident, repos...)
+}
+
+// Tags returns rules representing the tags of the given extension proxy,
+// for example, go_deps.module(...) calls. The kind of each rule is the full
+// dotted name of the tag (for example, "go_deps.module"). Changes made to the
+// returned rules are written back when Sync is called; to remove a tag, call
+// Delete on its rule.
+func (f *ModuleFile) Tags(ident string) []*Rule {
+	var rules []*Rule
+	for i, stmt := range f.File.Stmt {
+		call, ok := stmt.(*bzl.CallExpr)
+		if !ok {
+			continue
+		}
+		dot, ok := call.X.(*bzl.DotExpr)
+		if !ok {
+			continue
+		}
+		if x, ok := dot.X.(*bzl.Ident); !ok || x.Name != ident {
+			continue
+		}
+		rules = append(rules, f.trackRule(i, call))
+	}
+	return rules
+}
+
+// AddTag inserts r as a tag of an extension proxy. r's kind must have the
+// form "<proxy>.<tag>", for example, "go_deps.gazelle_override". The tag is
+// inserted after the last statement that uses the proxy.
+func (f *ModuleFile) AddTag(r *Rule) {
+	ident := r.Kind()
+	for i, c := range ident {
+		if c == '.' {
+			ident = ident[:i]
+			break
+		}
+	}
+	index := f.lastIndex(func(stmt bzl.Expr) bool {
+		return f.usesProxy(stmt) == ident
+	})
+	r.sync()
+	f.insertStmt(index+1, r.expr)
+	f.rules = append(f.rules, r)
+}
+
+// Overrides returns rules representing the override calls in the file
+// (single_version_override, git_override, and so on). Changes made to the
+// returned rules are written back when Sync is called; to remove an override,
+// call Delete on its rule.
+func (f *ModuleFile) Overrides() []*Rule {
+	var rules []*Rule
+	for i, stmt := range f.File.Stmt {
+		call, ok := stmt.(*bzl.CallExpr)
+		if !ok {
+			continue
+		}
+		if x, ok := call.X.(*bzl.Ident); ok && overrideKinds[x.Name] {
+			rules = append(rules, f.trackRule(i, call))
+		}
+	}
+	return rules
+}
+
+// SetOverride adds an override or merges it into an existing one for the same
+// module_name. If an override of the same kind exists, attributes set in r
+// replace the corresponding attributes of the existing call, and other
+// attributes are preserved. If an override of a different kind exists, it is
+// replaced, since a module may only have one override. Overrides marked with
+// "# keep" are not modified.
+func (f *ModuleFile) SetOverride(r *Rule) error {
+	if !overrideKinds[r.Kind()] {
+		return fmt.Errorf("%s: %q is not an override kind", f.Path, r.Kind())
+	}
+	moduleName := r.AttrString("module_name")
+	if moduleName == "" {
+		return fmt.Errorf("%s: %s has no module_name", f.Path, r.Kind())
+	}
+	r.sync()
+	for _, existing := range f.Overrides() {
+		if existing.AttrString("module_name") != moduleName {
+			continue
+		}
+		if existing.ShouldKeep() {
+			return nil
+		}
+		if existing.Kind() == r.Kind() {
+			for _, key := range r.AttrKeys() {
+				existing.SetAttr(key, r.Attr(key))
+			}
+			return nil
+		}
+		call := r.expr.(*bzl.CallExpr)
+		call.Comments = *existing.expr.Comment()
+		f.replaceStmt(existing.expr, call)
+		f.untrackRule(existing)
+		f.rules = append(f.rules, r)
+		return nil
+	}
+	f.insertStmt(len(f.File.Stmt), r.expr)
+	f.rules = append(f.rules, r)
+	return nil
+}
+
+// Sync writes changes made through rules returned by Overrides and Tags back
+// to the syntax tree.
+func (f *ModuleFile) Sync() {
+	w := 0
+	for _, r := range f.rules {
+		if r.deleted {
+			f.deleteStmt(r.expr)
+			continue
+		}
+		r.sync()
+		f.rules[w] = r
+		w++
+	}
+	f.rules = f.rules[:w]
+}
+
+// Format formats the module file in a form that can be written to disk.
+// This method calls Sync internally.
+func (f *ModuleFile) Format() []byte {
+	f.Sync()
+	return bzl.Format(f.File)
+}
+
+// Save writes the module file to disk. This method calls Sync internally.
+func (f *ModuleFile) Save(path string) error {
+	f.Content = f.Format()
+	return os.WriteFile(path, f.Content, 0o666)
+}
+
+func (f *ModuleFile) findBazelDep(name string) *bzl.CallExpr {
+	for _, stmt := range f.File.Stmt {
+		if call, ok := callNamed(stmt, "bazel_dep"); ok && callAttrString(call, "name") == name {
+			return call
+		}
+	}
+	return nil
+}
+
+func (f *ModuleFile) useRepoCalls(ident string) []*bzl.CallExpr {
+	var calls []*bzl.CallExpr
+	for _, stmt := range f.File.Stmt {
+		call, ok := callNamed(stmt, "use_repo")
+		if !ok || len(call.List) == 0 {
+			continue
+		}
+		if x, ok := call.List[0].(*bzl.Ident); ok && x.Name == ident {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// usesProxy returns the name of the extension proxy that stmt declares or
+// refers to, or "" if stmt is not related to an extension.
+func (f *ModuleFile) usesProxy(stmt bzl.Expr) string {
+	if p, ok := extensionProxyFromStmt(stmt); ok {
+		return p.Ident
+	}
+	call, ok := stmt.(*bzl.CallExpr)
+	if !ok {
+		return ""
+	}
+	if dot, ok := call.X.(*bzl.DotExpr); ok {
+		if x, ok := dot.X.(*bzl.Ident); ok {
+			return x.Name
+		}
+	}
+	if x, ok := call.X.(*bzl.Ident); ok && x.Name == "use_repo" && len(call.List) > 0 {
+		if arg, ok := call.List[0].(*bzl.Ident); ok {
+			return arg.Name
+		}
+	}
+	return ""
+}
+
+func (f *ModuleFile) lastIndex(pred func(bzl.Expr) bool) int {
+	for i := len(f.File.Stmt) - 1; i >= 0; i-- {
+		if pred(f.File.Stmt[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *ModuleFile) insertStmt(index int, stmt bzl.Expr) {
+	f.File.Stmt = append(f.File.Stmt, nil)
+	copy(f.File.Stmt[index+1:], f.File.Stmt[index:])
+	f.File.Stmt[index] = stmt
+}
+
+func (f *ModuleFile) deleteStmt(stmt bzl.Expr) {
+	for i, s := range f.File.Stmt {
+		if s == stmt {
+			f.File.Stmt = append(f.File.Stmt[:i], f.File.Stmt[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *ModuleFile) replaceStmt(old, new bzl.Expr) {
+	for i, s := range f.File.Stmt {
+		if s == old {
+			f.File.Stmt[i] = new
+			return
+		}
+	}
+}
+
+// trackRule returns the tracked rule for call, creating it if needed, so that
+// repeated calls to Overrides and Tags return the same *Rule values.
+func (f *ModuleFile) trackRule(index int, call *bzl.CallExpr) *Rule {
+	for _, r := range f.rules {
+		if r.expr == call {
+			r.index = index
+			return r
+		}
+	}
+	r := ruleFromExpr(index, call)
+	f.rules = append(f.rules, r)
+	return r
+}
+
+func (f *ModuleFile) untrackRule(r *Rule) {
+	for i, tr := range f.rules {
+		if tr == r {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return
+		}
+	}
+}
+
+func callNamed(stmt bzl.Expr, name string) (*bzl.CallExpr, bool) {
+	call, ok := stmt.(*bzl.CallExpr)
+	if !ok {
+		return nil, false
+	}
+	x, ok := call.X.(*bzl.Ident)
+	if !ok || x.Name != name {
+		return nil, false
+	}
+	return call, true
+}
+
+func callAttr(call *bzl.CallExpr, key string) bzl.Expr {
+	for _, arg := range call.List {
+		if assign, ok := arg.(*bzl.AssignExpr); ok {
+			if lhs, ok := assign.LHS.(*bzl.Ident); ok && lhs.Name == key {
+				return assign.RHS
+			}
+		}
+	}
+	return nil
+}
+
+func callAttrString(call *bzl.CallExpr, key string) string {
+	if s, ok := callAttr(call, key).(*bzl.StringExpr); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func callAttrTrue(call *bzl.CallExpr, key string) bool {
+	x, ok := callAttr(call, key).(*bzl.Ident)
+	return ok && x.Name == "True"
+}
+
+// setCallAttr sets a keyword argument of call, replacing the existing value if
+// there is one. If value is nil, the argument is removed.
+func setCallAttr(call *bzl.CallExpr, key string, value bzl.Expr) {
+	for i, arg := range call.List {
+		assign, ok := arg.(*bzl.AssignExpr)
+		if !ok {
+			continue
+		}
+		if lhs, ok := assign.LHS.(*bzl.Ident); !ok || lhs.Name != key {
+			continue
+		}
+		if value == nil {
+			call.List = append(call.List[:i], call.List[i+1:]...)
+		} else {
+			assign.RHS = value
+		}
+		return
+	}
+	if value != nil {
+		call.List = append(call.List, &bzl.AssignExpr{
+			LHS: &bzl.Ident{Name: key},
+			Op:  "=",
+			RHS: value,
+		})
+	}
+}
+
+func stringExprOrNil(s string) bzl.Expr {
+	if s == "" {
+		return nil
+	}
+	return &bzl.StringExpr{Value: s}
+}
+
+func bazelDepFromCall(call *bzl.CallExpr) BazelDep {
+	return BazelDep{
+		Name:          callAttrString(call, "name"),
+		Version:       callAttrString(call, "version"),
+		RepoName:      callAttrString(call, "repo_name"),
+		DevDependency: callAttrTrue(call, "dev_dependency"),
+	}
+}
+
+func extensionProxyFromStmt(stmt bzl.Expr) (ExtensionProxy, bool) {
+	assign, ok := stmt.(*bzl.AssignExpr)
+	if !ok {
+		return ExtensionProxy{}, false
+	}
+	lhs, ok := assign.LHS.(*bzl.Ident)
+	if !ok {
+		return ExtensionProxy{}, false
+	}
+	call, ok := callNamed(assign.RHS, "use_extension")
+	if !ok {
+		return ExtensionProxy{}, false
+	}
+	var positional []string
+	for _, arg := range call.List {
+		if s, ok := arg.(*bzl.StringExpr); ok {
+			positional = append(positional, s.Value)
+		}
+	}
+	if len(positional) < 2 {
+		return ExtensionProxy{}, false
+	}
+	return ExtensionProxy{
+		Ident:         lhs.Name,
+		BzlFile:       positional[0],
+		Name:          positional[1],
+		DevDependency: callAttrTrue(call, "dev_dependency"),
+		Isolate:       callAttrTrue(call, "isolate"),
+	}, true
+}
+
+// useRepoArgName returns the local name of a repository imported by an
+// argument to use_repo. Positional strings import a repository under its own
+// name; keyword arguments import a repository under the keyword.
+func useRepoArgName(arg bzl.Expr) string {
+	switch arg := arg.(type) {
+	case *bzl.StringExpr:
+		return arg.Value
+	case *bzl.AssignExpr:
+		if lhs, ok := arg.LHS.(*bzl.Ident); ok {
+			return lhs.Name
+		}
+	}
+	return ""
+}
+
+// sortUseRepoArgs sorts the positional string arguments of a use_repo call,
+// leaving the proxy argument first and keyword arguments last.
+func sortUseRepoArgs(call *bzl.CallExpr) {
+	var strs, rest []bzl.Expr
+	for _, arg := range call.List[1:] {
+		if _, ok := arg.(*bzl.StringExpr); ok {
+			strs = append(strs, arg)
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	sort.SliceStable(strs, func(i, j int) bool {
+		return strs[i].(*bzl.StringExpr).Value < strs[j].(*bzl.StringExpr).Value
+	})
+	list := append(call.List[:1:1], strs...)
+	call.List = append(list, rest...)
+}
@@ -0,0 +1,217 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestModuleFileBazelDeps(t *testing.T) {
+	f, err := LoadModuleData("MODULE.bazel", []byte(`
+module(name = "example")
+
+bazel_dep(name = "rules_go", version = "0.50.0")
+bazel_dep(name = "gazelle", version = "0.40.0", repo_name = "bazel_gazelle")
+bazel_dep(name = "pinned", version = "1.0.0")  # keep
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.ModuleName(); got != "example" {
+		t.Errorf("ModuleName: got %q; want %q", got, "example")
+	}
+
+	f.SetBazelDep(BazelDep{Name: "rules_go", Version: "0.59.0"})
+	f.SetBazelDep(BazelDep{Name: "pinned", Version: "2.0.0"})
+	f.SetBazelDep(BazelDep{Name: "rules_testing", Version: "0.6.0", DevDependency: true})
+	if !f.RemoveBazelDep("gazelle") {
+		t.Error("RemoveBazelDep(gazelle): got false; want true")
+	}
+	if f.RemoveBazelDep("pinned") {
+		t.Error("RemoveBazelDep(pinned): got true for kept dependency")
+	}
+
+	wantDeps := []BazelDep{
+		{Name: "rules_go", Version: "0.59.0"},
+		{Name: "pinned", Version: "1.0.0"},
+		{Name: "rules_testing", Version: "0.6.0", DevDependency: true},
+	}
+	if diff := cmp.Diff(wantDeps, f.BazelDeps()); diff != "" {
+		t.Errorf("BazelDeps (-want +got):\n%s", diff)
+	}
+
+	got := strings.TrimSpace(string(f.Format()))
+	want := strings.TrimSpace(`
+module(name = "example")
+
+bazel_dep(name = "rules_go", version = "0.59.0")
+bazel_dep(name = "pinned", version = "1.0.0")  # keep
+
+bazel_dep(name = "rules_testing", version = "0.6.0", dev_dependency = True)
+`)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestModuleFileUseRepos(t *testing.T) {
+	f, err := LoadModuleData("MODULE.bazel", []byte(`
+bazel_dep(name = "gazelle", version = "0.40.0")
+
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+use_repo(
+    go_deps,
+    "com_github_old",
+    "org_golang_x_tools",
+    "com_github_pinned",  # keep
+    renamed = "com_github_renamed",
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMapping := map[string]string{
+		"com_github_old":     "com_github_old",
+		"org_golang_x_tools": "org_golang_x_tools",
+		"com_github_pinned":  "com_github_pinned",
+		"renamed":            "com_github_renamed",
+	}
+	if diff := cmp.Diff(wantMapping, f.UseRepoMapping("go_deps")); diff != "" {
+		t.Errorf("UseRepoMapping (-want +got):\n%s", diff)
+	}
+
+	ident := f.UseExtension("@gazelle//:extensions.bzl", "go_deps", false)
+	if ident != "go_deps" {
+		t.Errorf("UseExtension: got %q; want %q", ident, "go_deps")
+	}
+	f.SetUseRepos(ident, []string{"org_golang_x_tools", "com_github_new", "renamed"})
+
+	devIdent := f.UseExtension("@gazelle//:extensions.bzl", "go_deps", true)
+	if devIdent != "go_deps_dev" {
+		t.Errorf("UseExtension(dev): got %q; want %q", devIdent, "go_deps_dev")
+	}
+	f.AddUseRepos(devIdent, "com_github_test", "com_github_test")
+
+	wantProxies := []ExtensionProxy{
+		{Ident: "go_deps", BzlFile: "@gazelle//:extensions.bzl", Name: "go_deps"},
+		{Ident: "go_deps_dev", BzlFile: "@gazelle//:extensions.bzl", Name: "go_deps", DevDependency: true},
+	}
+	if diff := cmp.Diff(wantProxies, f.ExtensionProxies()); diff != "" {
+		t.Errorf("ExtensionProxies (-want +got):\n%s", diff)
+	}
+
+	got := strings.TrimSpace(string(f.Format()))
+	want := strings.TrimSpace(`
+bazel_dep(name = "gazelle", version = "0.40.0")
+
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.from_file(go_mod = "//:go.mod")
+use_repo(
+    go_deps,
+    "com_github_new",
+    "com_github_pinned",  # keep
+    "org_golang_x_tools",
+    renamed = "com_github_renamed",
+)
+
+go_deps_dev = use_extension("@gazelle//:extensions.bzl", "go_deps", dev_dependency = True)
+use_repo(go_deps_dev, "com_github_test")
+`)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestModuleFileOverridesAndTags(t *testing.T) {
+	f, err := LoadModuleData("MODULE.bazel", []byte(`
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+go_deps.gazelle_override(
+    path = "example.com/a",
+    directives = ["gazelle:proto disable"],
+)
+use_repo(go_deps, "com_example_a")
+
+single_version_override(
+    module_name = "rules_go",
+    version = "0.50.0",
+)
+
+git_override(
+    module_name = "rules_cc",
+    commit = "abc",
+    remote = "https://example.com/rules_cc",
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svo := NewRule("single_version_override", "")
+	svo.SetAttr("module_name", "rules_go")
+	svo.SetAttr("patches", []string{"//:rules_go.patch"})
+	if err := f.SetOverride(svo); err != nil {
+		t.Fatal(err)
+	}
+	lpo := NewRule("local_path_override", "")
+	lpo.SetAttr("module_name", "rules_cc")
+	lpo.SetAttr("path", "../rules_cc")
+	if err := f.SetOverride(lpo); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetOverride(NewRule("go_library", "")); err == nil {
+		t.Error("SetOverride(go_library): got nil error")
+	}
+
+	tags := f.Tags("go_deps")
+	if len(tags) != 1 || tags[0].Kind() != "go_deps.gazelle_override" {
+		t.Fatalf("Tags: got %v", tags)
+	}
+	tags[0].Delete()
+	tag := NewRule("go_deps.module_override", "")
+	tag.SetAttr("path", "example.com/b")
+	tag.SetAttr("patches", []string{"//:b.patch"})
+	f.AddTag(tag)
+
+	got := strings.TrimSpace(string(f.Format()))
+	want := strings.TrimSpace(`
+go_deps = use_extension("@gazelle//:extensions.bzl", "go_deps")
+use_repo(go_deps, "com_example_a")
+
+go_deps.module_override(
+    patches = ["//:b.patch"],
+    path = "example.com/b",
+)
+
+single_version_override(
+    module_name = "rules_go",
+    patches = ["//:rules_go.patch"],
+    version = "0.50.0",
+)
+
+local_path_override(
+    module_name = "rules_cc",
+    path = "../rules_cc",
+)
+`)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
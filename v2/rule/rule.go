@@ -208,9 +208,16 @@ func ScanASTBody(pkg, defName string, bzlFile *bzl.File) *File {
 		DefName: defName,
 	}
 	var defStmt *bzl.DefStmt
-	f.Rules, f.Loads, defStmt = scanExprs(defName, bzlFile.Stmt)
+	consts := collectConstants(bzlFile.Stmt)
+	f.Rules, f.Loads, defStmt = scanExprs(defName, bzlFile.Stmt, consts)
 	if defStmt != nil {
-		f.Rules, _, _ = scanExprs("", defStmt.Body)
+		for k, v := range collectConstants(defStmt.Body) {
+			consts[k] = v
+		}
+		f.Rules, _, _ = scanExprs("", defStmt.Body, consts)
+		for _, r := range f.Rules {
+			r.preserveAttrOrder = true
+		}
 		f.function = &function{
 			stmt:     defStmt,
 			inserted: true,
@@ -234,7 +241,7 @@ func ScanASTBody(pkg, defName string, bzlFile *bzl.File) *File {
 	return f
 }
 
-func scanExprs(defName string, stmt []bzl.Expr) (rules []*Rule, loads []*Load, fn *bzl.DefStmt) {
+func scanExprs(defName string, stmt []bzl.Expr, consts map[string]bzl.Expr) (rules []*Rule, loads []*Load, fn *bzl.DefStmt) {
 	for i, expr := range stmt {
 		switch expr := expr.(type) {
 		case *bzl.LoadStmt:
@@ -242,6 +249,7 @@ func scanExprs(defName string, stmt []bzl.Expr) (rules []*Rule, loads []*Load, f
 			loads = append(loads, l)
 		case *bzl.CallExpr:
 			if r := ruleFromExpr(i, expr); r != nil {
+				r.consts = consts
 				rules = append(rules, r)
 			}
 		case *bzl.DefStmt:
@@ -284,7 +292,7 @@ func MatchBuildFileName(dir string, names []string, files []os.FileInfo) string
 // useful for keeping multiple macro definitions from the same .bzl file in sync.
 func (f *File) SyncMacroFile(from *File) {
 	fromFunc := *from.function.stmt
-	_, _, toFunc := scanExprs(from.function.stmt.Name, f.File.Stmt)
+	_, _, toFunc := scanExprs(from.function.stmt.Name, f.File.Stmt, nil)
 	if toFunc != nil {
 		*toFunc = fromFunc
 	} else {
@@ -416,8 +424,22 @@ func (f *File) SortMacro() {
 		panic(fmt.Sprintf("%s: not loaded as macro file", f.Path))
 	}
 
+	// Comments at the end of the macro are attached to its last statement.
+	// They're moved so they stay at the end after the rules are sorted.
+	body := f.function.stmt.Body
+	var trailing []bzl.Comment
+	if len(body) > 0 {
+		last := body[len(body)-1].Comment()
+		trailing, last.After = last.After, nil
+	}
+
 	sort.Stable(loadsByName{f.Loads, f.File.Stmt})
-	sort.Stable(rulesByKindAndName{f.Rules, f.function.stmt.Body})
+	sort.Stable(rulesByKindAndName{f.Rules, body})
+
+	if len(body) > 0 {
+		last := body[len(body)-1].Comment()
+		last.After = append(last.After, trailing...)
+	}
 }
 
 // Save writes the build file to disk. This method calls Sync internally.
@@ -730,6 +752,15 @@ type Rule struct {
 	attrs       map[string]attrValue
 	private     map[string]interface{}
 	sortedAttrs []string
+
+	// preserveAttrOrder is set for rules read from a macro. Attributes that
+	// already exist keep their order when the rule is updated, and new
+	// attributes are added after them.
+	preserveAttrOrder bool
+
+	// consts maps names of constants defined in the file to their values.
+	// It's used to evaluate attributes computed from constants.
+	consts map[string]bzl.Expr
 }
 
 type attrValue struct {
@@ -864,6 +895,16 @@ func (r *Rule) ShouldKeep() bool {
 	return ShouldKeep(r.expr)
 }
 
+// Line returns the line where the rule starts in its build file, or 0 if
+// the rule hasn't been written to the file yet.
+func (r *Rule) Line() int {
+	if r.expr == nil {
+		return 0
+	}
+	start, _ := r.expr.Span()
+	return start.Line
+}
+
 // Kind returns the kind of rule this is (for example, "go_library").
 func (r *Rule) Kind() string {
 	return r.kind
@@ -913,17 +954,19 @@ func (r *Rule) Attr(key string) bzl.Expr {
 
 // AttrString returns the value of the named attribute if it is a scalar string.
 // "" is returned if the attribute is not set or is not a string.
+//
+// The value may also be a concatenation of strings and references to string
+// constants defined at the top level of the file, like NAME + "_lib".
+// "" is returned for other expressions that can't be evaluated statically.
 func (r *Rule) AttrString(key string) string {
-	str, ok := r.Attr(key).(*bzl.StringExpr)
-	if !ok {
-		return ""
-	}
-	return str.Value
+	s, _ := staticString(r.Attr(key), r.consts)
+	return s
 }
 
 // AttrStrings returns the string values of an attribute if it is a list.
 // nil is returned if the attribute is not set or is not a list. Non-string
-// values within the list won't be returned.
+// values within the list won't be returned, though values that can be
+// evaluated statically, as with AttrString, will be.
 func (r *Rule) AttrStrings(key string) []string {
 	list, ok := r.Attr(key).(*bzl.ListExpr)
 	if !ok {
@@ -931,8 +974,8 @@ func (r *Rule) AttrStrings(key string) []string {
 	}
 	strs := make([]string, 0, len(list.List))
 	for _, e := range list.List {
-		if str, ok := e.(*bzl.StringExpr); ok {
-			strs = append(strs, str.Value)
+		if str, ok := staticString(e, r.consts); ok {
+			strs = append(strs, str)
 		}
 	}
 	return strs
@@ -954,6 +997,20 @@ func (r *Rule) DelAttr(key string) {
 	r.updated = true
 }
 
+// RenameAttr changes the name of an attribute, keeping its value and
+// comments. If an attribute named to already exists, it is replaced.
+// RenameAttr does nothing if the rule has no attribute named from.
+func (r *Rule) RenameAttr(from, to string) {
+	attr, ok := r.attrs[from]
+	if !ok || from == to {
+		return
+	}
+	delete(r.attrs, from)
+	attr.expr.LHS = &bzl.Ident{NamePos: attr.expr.LHS.(*bzl.Ident).NamePos, Name: to}
+	r.attrs[to] = attr
+	r.updated = true
+}
+
 // SetAttr adds or replaces the named attribute with value. If the attribute is
 // mergeable, then the value must implement the Merger interface, or an error will
 // be returned.
@@ -1115,9 +1172,24 @@ func (r *Rule) sync() {
 	}
 	sortedAttrs := list[len(r.args):]
 	key := func(e bzl.Expr) string { return e.(*bzl.AssignExpr).LHS.(*bzl.Ident).Name }
+	oldPos := make(map[string]int)
+	if r.preserveAttrOrder {
+		for i, e := range call.List {
+			if attr, ok := e.(*bzl.AssignExpr); ok {
+				oldPos[attr.LHS.(*bzl.Ident).Name] = i
+			}
+		}
+	}
 	sort.SliceStable(sortedAttrs, func(i, j int) bool {
 		ki := key(sortedAttrs[i])
 		kj := key(sortedAttrs[j])
+		pi, oldi := oldPos[ki]
+		pj, oldj := oldPos[kj]
+		if oldi && oldj {
+			return pi < pj
+		} else if oldi != oldj {
+			return oldi
+		}
 		if cmp := bt.NamePriority[ki] - bt.NamePriority[kj]; cmp != 0 {
 			return cmp < 0
 		}
@@ -588,6 +588,110 @@ def repos():
 	}
 }
 
+func TestSortLabelsByCommentGroup(t *testing.T) {
+	f := EmptyFile("BUILD.bazel", "")
+	r := NewRule("go_library", "x")
+	r.SetAttr("srcs", &bzl.ListExpr{
+		List: []bzl.Expr{
+			&bzl.StringExpr{Value: "b.go", Comments: bzl.Comments{Before: []bzl.Comment{{Token: "# first"}}}},
+			&bzl.StringExpr{Value: "a.go"},
+			&bzl.StringExpr{Value: "d.go", Comments: bzl.Comments{Before: []bzl.Comment{{Token: "# second"}}}},
+			&bzl.StringExpr{Value: "c.go"},
+		},
+		ForceMultiLine: true,
+	})
+	r.Insert(f)
+
+	got := strings.TrimSpace(string(f.Format()))
+	want := strings.TrimSpace(`
+go_library(
+    name = "x",
+    srcs = [
+        # first
+        "a.go",
+        "b.go",
+        # second
+        "c.go",
+        "d.go",
+    ],
+)
+`)
+	if got != want {
+		t.Errorf("got:%s\nwant:%s", got, want)
+	}
+}
+
+func TestSortMacroKeepsTrailingComments(t *testing.T) {
+	f, err := LoadMacroData("repos.bzl", "", "repos", []byte(`
+def repos():
+    go_repository(
+        name = "org_golang_x_tools",
+    )
+    go_repository(
+        name = "com_github_pkg_errors",
+    )
+    # Add new repositories above.
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SortMacro()
+
+	got := strings.TrimSpace(string(f.Format()))
+	want := strings.TrimSpace(`
+def repos():
+    go_repository(
+        name = "com_github_pkg_errors",
+    )
+    go_repository(
+        name = "org_golang_x_tools",
+    )
+    # Add new repositories above.
+`)
+	if got != want {
+		t.Errorf("got:%s\nwant:%s", got, want)
+	}
+}
+
+func TestMacroRuleAttrOrder(t *testing.T) {
+	f, err := LoadMacroData("repos.bzl", "", "repos", []byte(`
+def repos():
+    go_repository(
+        name = "com_github_pkg_errors",
+        # Fixes a data race.
+        patches = ["//patches:errors.patch"],
+        importpath = "github.com/pkg/errors",
+        version = "v0.9.0",  # pinned
+        patch_args = ["-p1"],
+    )
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := f.Rules[0]
+	r.SetAttr("version", "v0.9.1")
+	r.SetAttr("sum", "h1:abc=")
+	r.SetAttr("build_tags", []string{"foo"})
+
+	got := strings.TrimSpace(string(f.Format()))
+	want := strings.TrimSpace(`
+def repos():
+    go_repository(
+        name = "com_github_pkg_errors",
+        # Fixes a data race.
+        patches = ["//patches:errors.patch"],
+        importpath = "github.com/pkg/errors",
+        version = "v0.9.1",  # pinned
+        patch_args = ["-p1"],
+        build_tags = ["foo"],
+        sum = "h1:abc=",
+    )
+`)
+	if got != want {
+		t.Errorf("got:%s\nwant:%s", got, want)
+	}
+}
+
 func TestCheckFile(t *testing.T) {
 	f := File{Rules: []*Rule{
 		NewRule("go_repository", "com_google_cloud_go_pubsub"),
@@ -647,6 +751,43 @@ a_rule(
 	}
 }
 
+func TestRenameAttr(t *testing.T) {
+	f, err := LoadData(filepath.Join("old", "BUILD.bazel"), "", []byte(`
+my_library(
+    name = "lib",
+    # comment
+    deps = [
+        "//b",
+        "//a",  # keep
+    ],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := f.Rules[0]
+	r.RenameAttr("deps", "dependencies")
+	r.RenameAttr("missing", "other")
+	if r.Attr("deps") != nil {
+		t.Error("deps is still set after rename")
+	}
+
+	got := strings.TrimSpace(string(f.Format()))
+	want := strings.TrimSpace(`
+my_library(
+    name = "lib",
+    # comment
+    dependencies = [
+        "//b",
+        "//a",  # keep
+    ],
+)
+`)
+	if got != want {
+		t.Errorf("got:%s\nwant:%s", got, want)
+	}
+}
+
 func TestSimpleArgument(t *testing.T) {
 	f := EmptyFile("foo", "bar")
 
@@ -793,3 +934,73 @@ func TestAttributeBool(t *testing.T) {
 		})
 	}
 }
+
+func TestAttributeStringComputed(t *testing.T) {
+	for _, tc := range []struct {
+		desc, src string
+		want      string
+		wantList  []string
+	}{
+		{
+			desc: "string literal",
+			src:  `my_rule(name = "my_name", str_attr = "foo", list_attr = ["a"])`,
+			want: "foo", wantList: []string{"a"},
+		}, {
+			desc: "concatenation with constant",
+			src: `
+PREFIX = "example.com/"
+NAME = "foo"
+
+my_rule(
+    name = "my_name",
+    str_attr = PREFIX + (NAME + "/bar"),
+    list_attr = ["a", NAME + "_b", FOO],
+)
+`,
+			want: "example.com/foo/bar", wantList: []string{"a", "foo_b"},
+		}, {
+			desc: "constant assigned twice",
+			src: `
+NAME = "foo"
+NAME = "bar"
+
+my_rule(name = "my_name", str_attr = NAME)
+`,
+			want: "",
+		}, {
+			desc: "constant cycle",
+			src: `
+A = B
+B = A
+
+my_rule(name = "my_name", str_attr = A)
+`,
+			want: "",
+		}, {
+			desc: "conditional",
+			src:  `my_rule(name = "my_name", str_attr = "a" if COND else "b")`,
+			want: "",
+		}, {
+			desc: "function call",
+			src:  `my_rule(name = "my_name", str_attr = compute("a"))`,
+			want: "",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			f, err := LoadData("BUILD.bazel", "", []byte(tc.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.Rules) != 1 {
+				t.Fatalf("expected 1 rule, got %d", len(f.Rules))
+			}
+			r := f.Rules[0]
+			if got := r.AttrString("str_attr"); got != tc.want {
+				t.Errorf("AttrString: got %q; want %q", got, tc.want)
+			}
+			if got := r.AttrStrings("list_attr"); !reflect.DeepEqual(got, tc.wantList) {
+				t.Errorf("AttrStrings: got %q; want %q", got, tc.wantList)
+			}
+		})
+	}
+}
@@ -24,7 +24,9 @@ import (
 
 // sortExprLabels sorts lists of strings using the same order as buildifier.
 // Buildifier also sorts string lists, but not those involved with "select"
-// expressions. This function is intended to be used with bzl.Walk.
+// expressions. Line comments before an element other than the first start a
+// new group; each group is sorted separately, and its comment stays at the
+// start of the group. This function is intended to be used with bzl.Walk.
 func sortExprLabels(e bzl.Expr, _ []bzl.Expr) {
 	list, ok := e.(*bzl.ListExpr)
 	if !ok || len(list.List) == 0 {
@@ -40,10 +42,18 @@ func sortExprLabels(e bzl.Expr, _ []bzl.Expr) {
 		keys[i] = makeSortKey(i, s)
 	}
 
-	before := keys[0].x.Comment().Before
-	keys[0].x.Comment().Before = nil
-	sort.Sort(byStringExpr(keys))
-	keys[0].x.Comment().Before = append(before, keys[0].x.Comment().Before...)
+	start := 0
+	for i := 1; i <= len(keys); i++ {
+		if i < len(keys) && len(keys[i].x.Comment().Before) == 0 {
+			continue
+		}
+		group := keys[start:i]
+		before := group[0].x.Comment().Before
+		group[0].x.Comment().Before = nil
+		sort.Sort(byStringExpr(group))
+		group[0].x.Comment().Before = append(before, group[0].x.Comment().Before...)
+		start = i
+	}
 	for i, k := range keys {
 		list.List[i] = k.x
 	}
@@ -15,6 +15,8 @@ limitations under the License.
 
 package rule
 
+import bzl "github.com/bazelbuild/buildtools/build"
+
 // LoadInfo describes a file that Gazelle knows about and the symbols
 // it defines.
 type LoadInfo struct {
@@ -23,6 +25,35 @@ type LoadInfo struct {
 	After   []string
 }
 
+// LoadMigration describes a symbol that moved from one .bzl file to another.
+// Fix rewrites load statements that load the symbol from From to load it
+// from To instead. See merger.MigrateLoads.
+//
+// Repository names in From and To are Bazel module names. They're replaced
+// with the apparent names the main module gives them, if it depends on them.
+type LoadMigration struct {
+	// From is the label of the .bzl file the symbol was loaded from, for
+	// example, "@rules_proto//proto:defs.bzl".
+	From string `json:"from"`
+
+	// Symbol is the name of the symbol in From. If empty, the migration
+	// applies to symbols loaded from From that no other migration names.
+	Symbol string `json:"symbol,omitempty"`
+
+	// To is the label of the .bzl file the symbol should be loaded from. If
+	// empty, the symbol is no longer provided anywhere, and it's removed from
+	// load statements.
+	To string `json:"to,omitempty"`
+
+	// NewSymbol is the name of the symbol in To, if it was renamed. The
+	// symbol is loaded with an alias, so references to it don't change.
+	NewSymbol string `json:"new_symbol,omitempty"`
+
+	// Module is the name of a Bazel module the main module must depend on
+	// for the migration to apply. If empty, the migration always applies.
+	Module string `json:"module,omitempty"`
+}
+
 // KindInfo stores metadata for a kind of rule, for example, "go_library".
 type KindInfo struct {
 	// MatchAny is true if a rule of this kind may be matched with any rule
@@ -53,4 +84,17 @@ type KindInfo struct {
 	// ResolveAttrs is a set of attributes that should be merged after
 	// dependency resolution. See rule.Merge.
 	ResolveAttrs map[string]bool
+
+	// ComputedDefaults maps attribute names to functions that compute values
+	// for attributes that aren't set. They're called after dependencies are
+	// resolved and merged, for rules of this kind that aren't marked with
+	// "# keep". A function may return nil to leave the attribute unset.
+	// Other values are set with Rule.SetAttr.
+	ComputedDefaults map[string]func(r *Rule) interface{}
+
+	// Validators maps attribute names to functions that check the values of
+	// attributes. They're called after computed defaults are set, for every
+	// rule of this kind. value is nil if the attribute isn't set. Errors are
+	// logged with the location of the rule.
+	Validators map[string]func(r *Rule, value bzl.Expr) error
 }
@@ -0,0 +1,237 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runner runs Gazelle from another Go program, without executing the
+// gazelle command. Programs choose the languages Gazelle uses, pass flags and
+// directories the same way they would on the command line, and get back a
+// description of the build files that changed and the problems Gazelle
+// found.
+//
+// Gazelle logs with the default logger in the log/slog package. Run replaces
+// the default logger while it's running and restores it afterward, so Run
+// must not be called concurrently, and other code logging during Run may be
+// captured.
+package runner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/cmd/gazelle/update"
+	"github.com/bazelbuild/bazel-gazelle/language"
+)
+
+// Mode determines whether Run writes build files.
+type Mode int
+
+const (
+	// Fix writes changed build files. This is the default.
+	Fix Mode = iota
+
+	// Check doesn't write anything. Result lists the build files that would
+	// change.
+	Check
+)
+
+// Options configures a call to Run.
+type Options struct {
+	// Languages generate and resolve rules. At least one is required.
+	Languages []language.Language
+
+	// WorkDir is the directory Gazelle runs in. Directories in Args are
+	// relative to it, and the repository root is found by searching from it.
+	// Defaults to the current directory.
+	WorkDir string
+
+	// Command is "update" or "fix". Defaults to "update".
+	Command string
+
+	// Args holds flags and directories as they'd be written on the command
+	// line after the command name. -mode is set by Mode and shouldn't be
+	// included.
+	Args []string
+
+	// Mode determines whether build files are written.
+	Mode Mode
+
+	// FS, if set, is the file system Gazelle reads the repository from
+	// instead of the OS file system. It's rooted at the repository root,
+	// which is WorkDir unless -repo_root is set in Args. WorkDir doesn't need
+	// to exist. FS is only read, so Mode should be Check unless the
+	// repository is also on the OS file system, for example when FS overlays
+	// edited files on it.
+	FS fs.FS
+
+	// Logger receives Gazelle's log messages. If nil, messages are
+	// discarded. Warnings and errors are also returned in
+	// Result.Diagnostics.
+	Logger *slog.Logger
+}
+
+// Change describes how a build file changed.
+type Change int
+
+const (
+	// Created means the file didn't exist before.
+	Created Change = iota
+
+	// Updated means the file's content changed.
+	Updated
+
+	// Deleted means the file was deleted with -delete_empty_build_files.
+	Deleted
+)
+
+func (c Change) String() string {
+	switch c {
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	case Deleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// File is a build file changed by Run, or a file that would be changed in
+// Check mode.
+type File struct {
+	// Path is the absolute path to the file.
+	Path string
+
+	Change Change
+
+	// Old and New are the file's content before and after Run. Old is nil
+	// for created files, and New is nil for deleted files.
+	Old, New []byte
+}
+
+// Diagnostic is a warning or error found by Gazelle.
+type Diagnostic struct {
+	Level   slog.Level
+	Message string
+
+	// Path is the file or directory the diagnostic is about, if known.
+	Path string
+}
+
+// LanguageMetrics describes the work one language did: the time it spent
+// configuring directories, generating rules, and resolving dependencies, and
+// the number of rules it generated and resolved.
+type LanguageMetrics = update.LanguageMetrics
+
+// Result describes what Run did.
+type Result struct {
+	// Files lists changed build files, sorted by path.
+	Files []File
+
+	// Diagnostics lists warnings and errors in the order they were found.
+	Diagnostics []Diagnostic
+
+	// Languages lists metrics for each language, in the order of
+	// Options.Languages.
+	Languages []LanguageMetrics
+}
+
+// Run runs Gazelle with the given options. The returned Result describes
+// files changed and problems found, even if Run returns an error partway
+// through. Canceling ctx stops Gazelle before it starts writing files.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	if len(opts.Languages) == 0 {
+		return nil, errors.New("runner: no languages")
+	}
+	wd := opts.WorkDir
+	if wd == "" {
+		var err error
+		if wd, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	} else if !filepath.IsAbs(wd) {
+		var err error
+		if wd, err = filepath.Abs(wd); err != nil {
+			return nil, err
+		}
+	}
+	cmd := opts.Command
+	if cmd == "" {
+		cmd = "update"
+	}
+	if cmd != "update" && cmd != "fix" {
+		return nil, errors.New("runner: command must be \"update\" or \"fix\", not " + cmd)
+	}
+	args := make([]string, 0, len(opts.Args)+2)
+	args = append(args, cmd, "-mode=fix")
+	args = append(args, opts.Args...)
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	rep := &update.Report{Logger: logger, DryRun: opts.Mode == Check}
+	defer restoreLogging()()
+
+	ctx = update.WithReport(ctx, rep)
+	if opts.FS != nil {
+		ctx = update.WithFS(ctx, opts.FS)
+	}
+	err := update.Run(ctx, opts.Languages, wd, args)
+	return newResult(rep), err
+}
+
+// restoreLogging saves the configuration of the default loggers in the log
+// and log/slog packages and returns a function that restores it.
+func restoreLogging() func() {
+	logger := slog.Default()
+	w, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	return func() {
+		slog.SetDefault(logger)
+		log.SetOutput(w)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+func newResult(rep *update.Report) *Result {
+	res := &Result{
+		Files:       make([]File, 0, len(rep.Files)),
+		Diagnostics: make([]Diagnostic, 0, len(rep.Diagnostics)),
+		Languages:   rep.Languages,
+	}
+	for _, f := range rep.Files {
+		change := Updated
+		switch {
+		case f.Created:
+			change = Created
+		case f.Deleted:
+			change = Deleted
+		}
+		res.Files = append(res.Files, File{Path: f.Path, Change: change, Old: f.Old, New: f.New})
+	}
+	sort.Slice(res.Files, func(i, j int) bool {
+		return res.Files[i].Path < res.Files[j].Path
+	})
+	for _, d := range rep.Diagnostics {
+		res.Diagnostics = append(res.Diagnostics, Diagnostic{Level: d.Level, Message: d.Message, Path: d.Path})
+	}
+	return res
+}
@@ -0,0 +1,215 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bazel-contrib/bazel-gazelle/v2/testtools"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	golang "github.com/bazelbuild/bazel-gazelle/language/go"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		mode      Mode
+		wantFiles []testtools.FileSpec
+	}{
+		{
+			desc: "fix",
+			mode: Fix,
+			wantFiles: []testtools.FileSpec{{
+				Path: "a/BUILD.bazel",
+				Content: `
+load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+
+proto_library(
+    name = "a_proto",
+    srcs = ["a.proto"],
+    visibility = ["//visibility:public"],
+)
+`,
+			}},
+		},
+		{
+			desc:      "check",
+			mode:      Check,
+			wantFiles: []testtools.FileSpec{{Path: "a/BUILD.bazel", NotExist: true}},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+				{Path: "WORKSPACE"},
+				{Path: "BUILD.bazel", Content: "# gazelle:not_a_directive\n"},
+				{Path: "a/a.proto", Content: `syntax = "proto3";`},
+			})
+			defer cleanup()
+
+			logger := slog.Default()
+			res, err := Run(context.Background(), Options{
+				Languages: []language.Language{proto.NewLanguage()},
+				WorkDir:   dir,
+				Args:      []string{"a"},
+				Mode:      tc.mode,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Files) != 1 {
+				t.Fatalf("got %d changed files; want 1", len(res.Files))
+			}
+			f := res.Files[0]
+			if want := filepath.Join(dir, "a", "BUILD.bazel"); f.Path != want || f.Change != Created || f.Old != nil {
+				t.Errorf("got file %s %s; want %s created", f.Path, f.Change, want)
+			}
+			if tc.mode == Fix {
+				got, err := os.ReadFile(f.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(string(f.New), string(got)); diff != "" {
+					t.Errorf("written content differs from result (-result +written):\n%s", diff)
+				}
+			}
+			testtools.CheckFiles(t, dir, tc.wantFiles)
+
+			wantDiags := []Diagnostic{
+				{Level: slog.LevelWarn, Message: filepath.Join(dir, "BUILD.bazel") + ":1: gazelle:not_a_directive: unknown directive"},
+				{Level: slog.LevelWarn, Message: "found 1 invalid directives; run with -strict to make these errors"},
+			}
+			if diff := cmp.Diff(wantDiags, res.Diagnostics); diff != "" {
+				t.Errorf("diagnostics (-want +got):\n%s", diff)
+			}
+			if len(res.Languages) != 1 {
+				t.Fatalf("got metrics for %d languages; want 1", len(res.Languages))
+			}
+			if m := res.Languages[0]; m.Name != "proto" || m.Generated != 1 || m.Resolved != 1 {
+				t.Errorf("got proto metrics %+v; want 1 rule generated and resolved", m)
+			}
+			if slog.Default() != logger {
+				t.Error("default logger was not restored")
+			}
+		})
+	}
+}
+
+func TestRunFS(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "virtual")
+	fsys := fstest.MapFS{
+		"WORKSPACE":       {},
+		"BUILD.bazel":     {Data: []byte("# gazelle:exclude skip\n")},
+		"a/a.proto":       {Data: []byte(`syntax = "proto3";`)},
+		"a/b/b.proto":     {Data: []byte(`syntax = "proto3";` + "\nimport \"a/a.proto\";\n")},
+		"a/b/BUILD.bazel": {Data: []byte("# existing\n")},
+		"skip/c.proto":    {Data: []byte(`syntax = "proto3";`)},
+	}
+
+	res, err := Run(context.Background(), Options{
+		Languages: []language.Language{proto.NewLanguage()},
+		WorkDir:   dir,
+		Mode:      Check,
+		FS:        fsys,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type file struct {
+		Path, Change, New string
+	}
+	var got []file
+	for _, f := range res.Files {
+		rel, _ := filepath.Rel(dir, f.Path)
+		got = append(got, file{filepath.ToSlash(rel), f.Change.String(), string(f.New)})
+	}
+	want := []file{
+		{
+			Path:   "a/BUILD.bazel",
+			Change: "created",
+			New: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+
+proto_library(
+    name = "a_proto",
+    srcs = ["a.proto"],
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		{
+			Path:   "a/b/BUILD.bazel",
+			Change: "updated",
+			New: `load("@com_google_protobuf//bazel:proto_library.bzl", "proto_library")
+
+# existing
+
+proto_library(
+    name = "b_proto",
+    srcs = ["b.proto"],
+    visibility = ["//visibility:public"],
+    deps = ["//a:a_proto"],
+)
+`,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("files (-want +got):\n%s", diff)
+	}
+	if len(res.Diagnostics) > 0 {
+		t.Errorf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("%s was created on the OS file system", dir)
+	}
+}
+
+func TestRunFSGoMod(t *testing.T) {
+	// The Go extension reads the module path from go.mod in the virtual file
+	// system, not from the OS.
+	dir := filepath.Join(t.TempDir(), "virtual")
+	fsys := fstest.MapFS{
+		"WORKSPACE":   {},
+		"BUILD.bazel": {},
+		"go.mod":      {Data: []byte("module example.com/m\n")},
+		"a/a.go":      {Data: []byte("package a\n")},
+	}
+
+	res, err := Run(context.Background(), Options{
+		Languages: []language.Language{golang.NewLanguage()},
+		WorkDir:   dir,
+		Mode:      Check,
+		FS:        fsys,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	for _, f := range res.Files {
+		if f.Path == filepath.Join(dir, "a", "BUILD.bazel") {
+			got = string(f.New)
+		}
+	}
+	if want := `importpath = "example.com/m/a"`; !strings.Contains(got, want) {
+		t.Errorf("a/BUILD.bazel doesn't contain %s:\n%s", want, got)
+	}
+}
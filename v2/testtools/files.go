@@ -26,6 +26,9 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -54,6 +57,29 @@ type FileSpec struct {
 	// NotExist asserts that no file at this path exists.
 	// It is only valid in CheckFiles.
 	NotExist bool
+
+	// Golden is the path of a file holding the expected content, relative to
+	// the test's working directory. If set, it is used instead of Content.
+	// When UpdateGolden is true, the golden file is rewritten with the
+	// actual content instead of reporting a difference.
+	// It is only valid in CheckFiles.
+	Golden string
+
+	// Pattern indicates that the expected content contains placeholders:
+	// regular expressions enclosed in "{{" and "}}", for example
+	// `sum = "{{h1:\S+}}"`. Text outside placeholders is matched literally.
+	// Placeholders do not match newlines unless they say so with "(?s)".
+	// Golden files with placeholders are never rewritten by UpdateGolden.
+	// It is only valid in CheckFiles.
+	Pattern bool
+
+	// Mode is the permission bits of the file or directory, for example 0o755
+	// for an executable file. If zero, CreateFiles uses 0o600 for files and
+	// 0o700 for directories, and CheckFiles does not check permissions.
+	// Directory permissions are applied after all files are created, so a
+	// read-only directory may still be populated. Mode may not be set with
+	// Symlink.
+	Mode fs.FileMode
 }
 
 // CreateFiles creates a directory of test files. This is a more compact
@@ -71,35 +97,80 @@ func CreateFiles(t *testing.T, files []FileSpec) (dir string, cleanup func()) {
 		t.Fatal(err)
 	}
 
+	var dirModes []FileSpec
 	for _, f := range files {
 		if f.NotExist {
 			t.Fatalf("CreateFiles: NotExist may not be set: %s", f.Path)
 		}
+		if f.Golden != "" {
+			t.Fatalf("CreateFiles: Golden may not be set: %s", f.Path)
+		}
+		if f.Pattern {
+			t.Fatalf("CreateFiles: Pattern may not be set: %s", f.Path)
+		}
+		if f.Symlink != "" && f.Mode != 0 {
+			t.Fatalf("CreateFiles: Mode may not be set with Symlink: %s", f.Path)
+		}
 		path := filepath.Join(dir, filepath.FromSlash(f.Path))
 		if strings.HasSuffix(f.Path, "/") {
 			if err := os.MkdirAll(path, 0o700); err != nil {
-				os.RemoveAll(dir)
+				removeAll(dir)
 				t.Fatal(err)
 			}
+			if f.Mode != 0 {
+				dirModes = append(dirModes, f)
+			}
 			continue
 		}
 		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
-			os.RemoveAll(dir)
+			removeAll(dir)
 			t.Fatal(err)
 		}
 		if f.Symlink != "" {
 			if err := os.Symlink(f.Symlink, path); err != nil {
+				removeAll(dir)
 				t.Fatal(err)
 			}
 			continue
 		}
 		if err := os.WriteFile(path, []byte(f.Content), 0o600); err != nil {
-			os.RemoveAll(dir)
+			removeAll(dir)
+			t.Fatal(err)
+		}
+		if f.Mode != 0 {
+			// Set the mode explicitly, since the umask applies to WriteFile.
+			if err := os.Chmod(path, f.Mode); err != nil {
+				removeAll(dir)
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Apply directory modes deepest first, so that a directory without search
+	// permission doesn't prevent changing the modes of its subdirectories.
+	sort.SliceStable(dirModes, func(i, j int) bool {
+		return strings.Count(dirModes[i].Path, "/") > strings.Count(dirModes[j].Path, "/")
+	})
+	for _, f := range dirModes {
+		if err := os.Chmod(filepath.Join(dir, filepath.FromSlash(f.Path)), f.Mode); err != nil {
+			removeAll(dir)
 			t.Fatal(err)
 		}
 	}
 
-	return dir, func() { os.RemoveAll(dir) }
+	return dir, func() { removeAll(dir) }
+}
+
+// removeAll removes dir and its contents, first restoring permissions on
+// directories that CreateFiles may have made read-only.
+func removeAll(dir string) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(path, 0o700)
+		}
+		return nil
+	})
+	os.RemoveAll(dir)
 }
 
 // CheckFiles checks that files in "dir" exist and have the content specified
@@ -120,6 +191,10 @@ func CheckFiles(t *testing.T, dir string, files []FileSpec) {
 			continue
 		}
 
+		if err == nil && f.Mode != 0 && runtime.GOOS != "windows" && st.Mode().Perm() != f.Mode.Perm() {
+			t.Errorf("%s has mode %v; want %v", f.Path, st.Mode().Perm(), f.Mode.Perm())
+		}
+
 		if strings.HasSuffix(f.Path, "/") {
 			if err != nil {
 				t.Errorf("could not stat %s: %v", f.Path, err)
@@ -127,13 +202,36 @@ func CheckFiles(t *testing.T, dir string, files []FileSpec) {
 				t.Errorf("not a directory: %s", f.Path)
 			}
 		} else {
-			want := normalizeSpace(f.Content)
 			gotBytes, err := os.ReadFile(filepath.Join(dir, f.Path))
 			if err != nil {
 				t.Errorf("could not read %s: %v", f.Path, err)
 				continue
 			}
+			content := f.Content
+			goldenMissing := false
+			if f.Golden != "" {
+				goldenBytes, err := os.ReadFile(f.Golden)
+				if err != nil && !UpdateGolden() {
+					t.Errorf("could not read golden file for %s: %v", f.Path, err)
+					continue
+				}
+				content, goldenMissing = string(goldenBytes), err != nil
+			}
+			want := normalizeSpace(content)
 			got := normalizeSpace(string(gotBytes))
+			if f.Pattern {
+				re, err := compilePattern(want)
+				if err != nil {
+					t.Errorf("%s: %v", f.Path, err)
+				} else if !re.MatchString(got) {
+					t.Errorf("%s does not match pattern (-want,+got):\n%s", f.Path, cmp.Diff(want, got))
+				}
+				continue
+			}
+			if f.Golden != "" && UpdateGolden() && (want != got || goldenMissing) {
+				writeGolden(t, f.Golden, string(gotBytes))
+				continue
+			}
 			if diff := cmp.Diff(want, got); diff != "" {
 				t.Errorf("%s diff (-want,+got):\n%s", f.Path, diff)
 			}
@@ -279,9 +377,9 @@ func TestGazelleGenerationOnPath(t *testing.T, args *TestGazelleGenerationArgs)
 		defer cleanup()
 		defer func() {
 			if t.Failed() {
-				shouldUpdate := os.Getenv("UPDATE_SNAPSHOTS") != ""
+				shouldUpdate := UpdateGolden()
 				buildWorkspaceDirectory := os.Getenv("BUILD_WORKSPACE_DIRECTORY")
-				updateCommand := fmt.Sprintf("UPDATE_SNAPSHOTS=true bazel run %s", os.Getenv("TEST_TARGET"))
+				updateCommand := fmt.Sprintf("UPDATE_GOLDEN=1 bazel run %s", os.Getenv("TEST_TARGET"))
 				// srcTestDirectory is the directory of the source code of the test case.
 				srcTestDirectory := path.Join(buildWorkspaceDirectory, path.Dir(args.TestDataPathRelative), args.Name)
 				if shouldUpdate {
@@ -438,3 +536,30 @@ func normalizeSlash(s string) string {
 func normalizeSpace(s string) string {
 	return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
 }
+
+// compilePattern converts expected content with "{{regexp}}" placeholders
+// into a regular expression that matches the whole content.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for {
+		start := strings.Index(pattern, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(pattern[start+2:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in pattern: %q", pattern[start:])
+		}
+		b.WriteString(regexp.QuoteMeta(pattern[:start]))
+		expr := pattern[start+2 : start+2+end]
+		if _, err := regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("invalid placeholder {{%s}}: %v", expr, err)
+		}
+		b.WriteString("(?:" + expr + ")")
+		pattern = pattern[start+2+end+2:]
+	}
+	b.WriteString(regexp.QuoteMeta(pattern))
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,118 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testtools
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	for _, tc := range []struct {
+		desc, pattern, content string
+		want                   bool
+	}{
+		{
+			desc:    "literal",
+			pattern: `go_library(name = "a.b")`,
+			content: `go_library(name = "a.b")`,
+			want:    true,
+		}, {
+			desc:    "literal_metacharacters",
+			pattern: `go_library(name = "a.b")`,
+			content: `go_library(name = "axb")`,
+			want:    false,
+		}, {
+			desc:    "placeholder",
+			pattern: `sum = "{{h1:\S+}}",` + "\n" + `version = "{{v[0-9.]+}}",`,
+			content: `sum = "h1:abc=",` + "\n" + `version = "v1.2.3",`,
+			want:    true,
+		}, {
+			desc:    "placeholder_no_newline",
+			pattern: `a{{.*}}b`,
+			content: "a\nb",
+			want:    false,
+		}, {
+			desc:    "placeholder_dotall",
+			pattern: `a{{(?s).*}}b`,
+			content: "a\nb",
+			want:    true,
+		}, {
+			desc:    "whole_content",
+			pattern: `{{[a-z]+}}`,
+			content: "abc1",
+			want:    false,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			re, err := compilePattern(tc.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := re.MatchString(tc.content); got != tc.want {
+				t.Errorf("got %v; want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatternError(t *testing.T) {
+	for _, pattern := range []string{"a{{b", "{{[}}"} {
+		if _, err := compilePattern(pattern); err == nil {
+			t.Errorf("%q: got nil error; want error", pattern)
+		}
+	}
+}
+
+func TestCheckFilesPattern(t *testing.T) {
+	dir, cleanup := CreateFiles(t, []FileSpec{{
+		Path:    "go.sum",
+		Content: "example.com/m v1.2.3 h1:Zm9v=\n",
+	}})
+	defer cleanup()
+	CheckFiles(t, dir, []FileSpec{{
+		Path:    "go.sum",
+		Content: "example.com/m {{v[0-9.]+}} {{h1:\\S+}}",
+		Pattern: true,
+	}})
+}
+
+func TestCreateFilesModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on windows")
+	}
+	files := []FileSpec{
+		{Path: "tool.sh", Content: "#!/bin/sh\n", Mode: 0o755},
+		{Path: "locked/", Mode: 0o500},
+		{Path: "locked/inner/", Mode: 0o500},
+		{Path: "locked/inner/file.txt", Content: "content"},
+		{Path: "link", Symlink: "locked"},
+	}
+	dir, cleanup := CreateFiles(t, files)
+	CheckFiles(t, dir, files[:len(files)-1])
+	if st, err := os.Lstat(filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	} else if st.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link is not a symlink")
+	}
+
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cleanup did not remove %s: %v", dir, err)
+	}
+}
@@ -0,0 +1,77 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testtools
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var updateGolden = flag.Bool("update_golden", false, "if true, tests using testtools rewrite golden files with their actual output instead of failing")
+
+// UpdateGolden reports whether tests should rewrite their golden files
+// instead of comparing against them. It is true when the test binary is run
+// with -update_golden, or when UPDATE_GOLDEN or UPDATE_SNAPSHOTS is set in
+// the environment. With Bazel, use
+// "UPDATE_GOLDEN=1 bazel run //path/to:test" so the files in the source
+// tree are written rather than copies in the sandbox.
+func UpdateGolden() bool {
+	return *updateGolden || os.Getenv("UPDATE_GOLDEN") != "" || os.Getenv("UPDATE_SNAPSHOTS") != ""
+}
+
+// CheckGolden compares got with the content of the golden file at path.
+// Line endings in the golden file are normalized before comparing. When
+// UpdateGolden is true and the contents differ, the golden file is rewritten
+// with got instead of reporting an error.
+func CheckGolden(t *testing.T, path, got string) {
+	t.Helper()
+	wantBytes, err := os.ReadFile(path)
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && UpdateGolden()) {
+		t.Fatalf("error reading golden file: %v", err)
+	}
+	want := strings.ReplaceAll(string(wantBytes), "\r\n", "\n")
+	if want == got && err == nil {
+		return
+	}
+	if UpdateGolden() {
+		writeGolden(t, path, got)
+		return
+	}
+	t.Errorf("%s diff (-want,+got):\n%s", path, cmp.Diff(want, got))
+}
+
+// writeGolden writes content to the golden file at path. If path is a
+// symbolic link, as it is in a Bazel runfiles tree, the file it points to is
+// written so that the update lands in the source tree.
+func writeGolden(t *testing.T, path, content string) {
+	t.Helper()
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o666); err != nil {
+		t.Fatalf("error updating golden file: %v", err)
+	}
+	t.Logf("updated golden file %s", path)
+}
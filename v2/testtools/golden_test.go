@@ -0,0 +1,57 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testtools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckGoldenUpdate(t *testing.T) {
+	dir, cleanup := CreateFiles(t, []FileSpec{
+		{Path: "src/BUILD.want", Content: "old\n"},
+		{Path: "runfiles/BUILD.want", Symlink: "../src/BUILD.want"},
+		{Path: "out/BUILD.bazel", Content: "generated\n"},
+	})
+	defer cleanup()
+	defer func(old bool) { *updateGolden = old }(*updateGolden)
+	*updateGolden = true
+
+	CheckGolden(t, filepath.Join(dir, "runfiles/BUILD.want"), "new\n")
+	CheckFiles(t, dir, []FileSpec{{
+		Path:   "out/BUILD.bazel",
+		Golden: filepath.Join(dir, "new/BUILD.want"),
+	}})
+
+	for path, want := range map[string]string{
+		"src/BUILD.want": "new\n",
+		"new/BUILD.want": "generated\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q; want %q", path, got, want)
+		}
+	}
+	if st, err := os.Lstat(filepath.Join(dir, "runfiles/BUILD.want")); err != nil {
+		t.Fatal(err)
+	} else if st.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("runfiles/BUILD.want was replaced; want symlink preserved")
+	}
+}
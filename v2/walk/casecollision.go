@@ -0,0 +1,94 @@
+/* Copyright 2026 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package walk
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Values of the case_collisions directive.
+const (
+	// caseCollisionsWarn logs a warning for names in a directory that differ
+	// only by case. It's the default.
+	caseCollisionsWarn = "warn"
+
+	// caseCollisionsExclude logs a warning and excludes all but the first of
+	// the colliding names, in byte order.
+	caseCollisionsExclude = "exclude"
+
+	// caseCollisionsOff disables the check.
+	caseCollisionsOff = "off"
+)
+
+// checkCaseCollisions looks for subdirectories and regular files of the
+// directory rel whose names differ only by case. Such names can't be checked
+// out together on case-insensitive file systems, which are the default on
+// macOS and Windows, so build files that refer to both would break there.
+// Collisions are logged, and with the exclude mode, all but the first name
+// in each group are removed from subdirs and regularFiles.
+func checkCaseCollisions(mode, rel string, subdirs, regularFiles []string) ([]string, []string) {
+	if mode == caseCollisionsOff {
+		return subdirs, regularFiles
+	}
+	groups := make(map[string][]string)
+	for _, names := range [][]string{subdirs, regularFiles} {
+		for _, name := range names {
+			folded := strings.ToLower(name)
+			groups[folded] = append(groups[folded], name)
+		}
+	}
+	excluded := make(map[string]bool)
+	var folds []string
+	for folded, names := range groups {
+		if len(names) > 1 {
+			folds = append(folds, folded)
+		}
+	}
+	sort.Strings(folds)
+	for _, folded := range folds {
+		names := groups[folded]
+		sort.Strings(names)
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = path.Join(rel, name)
+		}
+		if mode == caseCollisionsExclude {
+			for _, name := range names[1:] {
+				excluded[name] = true
+			}
+			log.Printf("%s: names differ only by case and can't coexist on case-insensitive file systems; excluding %s", strings.Join(paths, ", "), strings.Join(paths[1:], ", "))
+		} else {
+			log.Printf("%s: names differ only by case and can't coexist on case-insensitive file systems; rename one, exclude one with # gazelle:exclude, or set # gazelle:case_collisions exclude", strings.Join(paths, ", "))
+		}
+	}
+	if len(excluded) == 0 {
+		return subdirs, regularFiles
+	}
+	return filterNames(subdirs, excluded), filterNames(regularFiles, excluded)
+}
+
+func filterNames(names []string, excluded map[string]bool) []string {
+	var kept []string
+	for _, name := range names {
+		if !excluded[name] {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
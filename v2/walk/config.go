@@ -58,6 +58,14 @@ type walkConfig struct {
 	ignore              bool
 	follow              []string
 	validBuildFileNames []string // to be copied to config.Config
+
+	// caseCollisions is the mode set with the case_collisions directive.
+	// "" means caseCollisionsWarn.
+	caseCollisions string
+
+	// directiveEnv maps names of variables that may be referenced in
+	// directive values to their values. It's only set in the root directory.
+	directiveEnv map[string]string
 }
 
 const (
@@ -91,6 +99,15 @@ func (wc *walkConfig) shouldFollow(p string) bool {
 	return matchAnyGlob(wc.follow, p)
 }
 
+// ShouldFollow returns whether Gazelle follows the symbolic link at rel, a
+// slash-separated path relative to the repository root, according to
+// # gazelle:follow directives. c is the configuration for the directory
+// containing the link or one of its subdirectories.
+func ShouldFollow(c *config.Config, rel string) bool {
+	wc, ok := c.Exts[walkName].(*walkConfig)
+	return ok && wc.shouldFollow(rel)
+}
+
 var _ config.Configurer = (*Configurer)(nil)
 
 type Configurer struct {
@@ -99,6 +116,9 @@ type Configurer struct {
 	cliExcludes       []string
 	cliBuildFileNames string
 
+	// Environment variables that may be referenced in directive values.
+	directiveEnv []string
+
 	// Alternate BUILD read/write directories
 	readBuildFilesDir, writeBuildFilesDir string
 }
@@ -106,6 +126,7 @@ type Configurer struct {
 func (cr *Configurer) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config) {
 	fs.Var(&gzflag.MultiFlag{Values: &cr.cliExcludes}, "exclude", "pattern that should be ignored (may be repeated)")
 	fs.StringVar(&cr.cliBuildFileNames, "build_file_name", strings.Join(config.DefaultValidBuildFileNames, ","), "comma-separated list of valid build file names.\nThe first element of the list is the name of output build files to generate.")
+	fs.Var(&gzflag.MultiFlag{Values: &cr.directiveEnv}, "directive_env", "environment variable that may be referenced as ${NAME} in directive values (may be repeated)")
 	fs.StringVar(&cr.readBuildFilesDir, "experimental_read_build_files_dir", "", "path to a directory where build files should be read from (instead of -repo_root)")
 	fs.StringVar(&cr.writeBuildFilesDir, "experimental_write_build_files_dir", "", "path to a directory where build files should be written to (instead of -repo_root)")
 }
@@ -127,19 +148,20 @@ func (cr *Configurer) CheckFlags(_ *flag.FlagSet, c *config.Config) error {
 		}
 	}
 
-	ignoreFilter := newIgnoreFilter(c.RepoRoot)
+	ignoreFilter := newIgnoreFilter(c)
 
 	wc := &walkConfig{
 		ignoreFilter:        ignoreFilter,
 		excludes:            cr.cliExcludes,
 		validBuildFileNames: c.ValidBuildFileNames,
+		directiveEnv:        directiveEnv(c.RepoRoot, cr.directiveEnv),
 	}
 	c.Exts[walkName] = wc
 	return nil
 }
 
 func (*Configurer) KnownDirectives() []string {
-	return []string{"build_file_name", "directive_file", "generation_mode", "exclude", "follow", "ignore"}
+	return []string{"build_file_name", "case_collisions", "directive_file", "generation_mode", "exclude", "follow", "ignore"}
 }
 
 func (cr *Configurer) Configure(_ context.Context, args config.ConfigureArgs) error {
@@ -156,13 +178,16 @@ func (cr *Configurer) Configure(_ context.Context, args config.ConfigureArgs) er
 	} else {
 		// In some unit tests, c.Exts[walkNameCached] is not set.
 		// Process directives normally using the same code.
-		c.Exts[walkName] = configureForWalk(getWalkConfig(c), args.Rel, args.File)
+		c.Exts[walkName] = configureForWalk(c, getWalkConfig(c), args.Rel, args.File)
 	}
 	c.ValidBuildFileNames = getWalkConfig(c).validBuildFileNames
 	return nil
 }
 
-func configureForWalk(parent *walkConfig, rel string, f *rule.File) *walkConfig {
+// configureForWalk applies walk directives in f to a copy of parent.
+// Invalid directives are reported to c, which may be shared with other
+// goroutines.
+func configureForWalk(c *config.Config, parent *walkConfig, rel string, f *rule.File) *walkConfig {
 	wc := parent.clone()
 	wc.ignore = false
 
@@ -181,21 +206,35 @@ func configureForWalk(parent *walkConfig, rel string, f *rule.File) *walkConfig
 					log.Fatalf("unknown generation_mode %q in //%s", d.Value, f.Pkg)
 					continue
 				}
+			case "case_collisions":
+				switch mode := strings.TrimSpace(d.Value); mode {
+				case caseCollisionsWarn, caseCollisionsExclude, caseCollisionsOff:
+					wc.caseCollisions = mode
+				default:
+					c.ReportDirectiveError(f, d, fmt.Errorf("unknown mode %q; expected %q, %q, or %q", mode, caseCollisionsWarn, caseCollisionsExclude, caseCollisionsOff))
+				}
 			case "exclude":
 				if err := checkPathMatchPattern(path.Join(rel, d.Value)); err != nil {
-					log.Printf("the exclusion pattern is not valid %q: %s", path.Join(rel, d.Value), err)
+					c.ReportDirectiveError(f, d, fmt.Errorf("the exclusion pattern %q is not valid: %v", path.Join(rel, d.Value), err))
 					continue
 				}
 				wc.excludes = append(wc.excludes, path.Join(rel, d.Value))
 			case "follow":
 				if err := checkPathMatchPattern(path.Join(rel, d.Value)); err != nil {
-					log.Printf("the follow pattern is not valid %q: %s", path.Join(rel, d.Value), err)
+					c.ReportDirectiveError(f, d, fmt.Errorf("the follow pattern %q is not valid: %v", path.Join(rel, d.Value), err))
 					continue
 				}
 				wc.follow = append(wc.follow, path.Join(rel, d.Value))
+			case "reset":
+				if config.ResetsDirective(d, "exclude") {
+					wc.excludes = nil
+				}
+				if config.ResetsDirective(d, "follow") {
+					wc.follow = nil
+				}
 			case "ignore":
 				if d.Value != "" {
-					log.Printf("the ignore directive does not take any arguments. Did you mean to use gazelle:exclude instead? in //%s '# gazelle:ignore %s'", f.Pkg, d.Value)
+					c.ReportDirectiveError(f, d, errors.New("the ignore directive does not take any arguments. Did you mean to use gazelle:exclude instead?"))
 				}
 				wc.ignore = true
 			}
@@ -205,18 +244,38 @@ func configureForWalk(parent *walkConfig, rel string, f *rule.File) *walkConfig
 	return wc
 }
 
+// directiveEnv returns the variables that may be referenced in directive
+// values. BUILD_WORKSPACE_DIRECTORY is always available and defaults to the
+// repository root directory when Gazelle isn't run with "bazel run". Other
+// environment variables must be allowed explicitly. Variables that aren't set
+// are omitted.
+func directiveEnv(repoRoot string, allowed []string) map[string]string {
+	env := map[string]string{"BUILD_WORKSPACE_DIRECTORY": repoRoot}
+	if wsDir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); wsDir != "" {
+		env["BUILD_WORKSPACE_DIRECTORY"] = wsDir
+	}
+	for _, names := range allowed {
+		for _, name := range strings.Split(names, ",") {
+			if value, ok := os.LookupEnv(name); ok {
+				env[name] = value
+			}
+		}
+	}
+	return env
+}
+
 type ignoreFilter struct {
 	ignoreDirectoryGlobs []string
 	ignorePaths          map[string]struct{}
 }
 
-func newIgnoreFilter(repoRoot string) *ignoreFilter {
-	bazelignorePaths, err := loadBazelIgnore(repoRoot)
+func newIgnoreFilter(c *config.Config) *ignoreFilter {
+	bazelignorePaths, err := loadBazelIgnore(c)
 	if err != nil {
 		log.Printf("error loading .bazelignore: %v", err)
 	}
 
-	repoDirectoryIgnores, err := loadRepoDirectoryIgnore(repoRoot)
+	repoDirectoryIgnores, err := loadRepoDirectoryIgnore(c)
 	if err != nil {
 		log.Printf("error loading REPO.bazel ignore_directories(): %v", err)
 	}
@@ -239,9 +298,9 @@ func (f *ignoreFilter) isFileIgnored(p string) bool {
 	return ok
 }
 
-func loadBazelIgnore(repoRoot string) (map[string]struct{}, error) {
-	ignorePath := path.Join(repoRoot, ".bazelignore")
-	file, err := os.Open(ignorePath)
+func loadBazelIgnore(c *config.Config) (map[string]struct{}, error) {
+	ignorePath := path.Join(c.RepoRoot, ".bazelignore")
+	file, err := c.Open(ignorePath)
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, nil
 	}
@@ -275,9 +334,9 @@ func loadBazelIgnore(repoRoot string) (map[string]struct{}, error) {
 	return excludes, nil
 }
 
-func loadRepoDirectoryIgnore(repoRoot string) ([]string, error) {
-	repoFilePath := path.Join(repoRoot, "REPO.bazel")
-	repoFileContent, err := os.ReadFile(repoFilePath)
+func loadRepoDirectoryIgnore(c *config.Config) ([]string, error) {
+	repoFilePath := path.Join(c.RepoRoot, "REPO.bazel")
+	repoFileContent, err := c.ReadFile(repoFilePath)
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, nil
 	}
@@ -285,7 +344,7 @@ func loadRepoDirectoryIgnore(repoRoot string) ([]string, error) {
 		return nil, fmt.Errorf("REPO.bazel exists but couldn't be read: %v", err)
 	}
 
-	ast, err := bzl.Parse(repoRoot, repoFileContent)
+	ast, err := bzl.Parse(c.RepoRoot, repoFileContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse REPO.bazel: %v", err)
 	}
@@ -81,3 +81,35 @@ func TestConfigurerDirectives(t *testing.T) {
 		t.Errorf("for ValidBuildFileNames, got %#v, want %#v", c.ValidBuildFileNames, want)
 	}
 }
+
+func TestShouldFollow(t *testing.T) {
+	c := config.New()
+	cc := &Configurer{}
+	if err := cc.CheckFlags(nil, c); err != nil {
+		t.Fatal(err)
+	}
+	if ShouldFollow(c, "a/link") {
+		t.Error("ShouldFollow before follow directive: got true, want false")
+	}
+	f, err := rule.LoadData(filepath.Join("a", "BUILD.bazel"), "a", []byte(`# gazelle:follow link*`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc.Configure(context.TODO(), config.ConfigureArgs{Config: c, Rel: "a", File: f})
+	for _, tc := range []struct {
+		rel  string
+		want bool
+	}{
+		{rel: "a/link", want: true},
+		{rel: "a/linked", want: true},
+		{rel: "a/other", want: false},
+		{rel: "link", want: false},
+	} {
+		if got := ShouldFollow(c, tc.rel); got != tc.want {
+			t.Errorf("ShouldFollow(%q): got %v, want %v", tc.rel, got, tc.want)
+		}
+	}
+	if ShouldFollow(config.New(), "a/link") {
+		t.Error("ShouldFollow without walk configuration: got true, want false")
+	}
+}
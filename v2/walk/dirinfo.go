@@ -1,13 +1,17 @@
 package walk
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 
+	"github.com/bazel-contrib/bazel-gazelle/v2/config"
 	"github.com/bazel-contrib/bazel-gazelle/v2/rule"
 )
 
@@ -24,6 +28,12 @@ type DirInfo struct {
 	// exist or contains errors.
 	File *rule.File
 
+	// configFile is the build file passed to Configurer. It's the same as
+	// File, except that it also includes directives from the directory's
+	// DirectoryDirectiveFileName file and, in the repository root directory,
+	// from the repository's configuration file.
+	configFile *rule.File
+
 	// config is the configuration used by Configurer. We may precompute this
 	// before Configure is called to parallelize directory traversal without
 	// visiting excluded subdirectories.
@@ -47,7 +57,7 @@ func (w *walker) loadDirInfo(rel string) (DirInfo, error) {
 	var errs []error
 	var err error
 	dir := filepath.Join(w.rootConfig.RepoRoot, rel)
-	entries, err := os.ReadDir(dir)
+	entries, err := w.rootConfig.ReadDir(dir)
 	if err != nil {
 		errs = append(errs, err)
 	}
@@ -64,7 +74,7 @@ func (w *walker) loadDirInfo(rel string) (DirInfo, error) {
 		parentConfig = parentInfo.config
 	}
 
-	info.File, err = loadBuildFile(parentConfig, w.rootConfig.ReadBuildFilesDir, rel, dir, entries)
+	info.File, err = loadBuildFile(w.rootConfig, parentConfig, rel, dir, entries)
 	if err != nil {
 		errs = append(errs, err)
 	}
@@ -73,12 +83,25 @@ func (w *walker) loadDirInfo(rel string) (DirInfo, error) {
 	// directives loaded from external files (including walk directives like
 	// exclude and ignore) are visible to all configurers.
 	if info.File != nil {
-		if err := expandDirectiveFiles(info.File, w.rootConfig.RepoRoot); err != nil {
+		if err := expandDirectiveFiles(w.rootConfig, info.File); err != nil {
+			errs = append(errs, err)
+		}
+		if err := expandDirectiveEnv(info.File.Path, info.File.Directives, getWalkConfig(w.rootConfig).directiveEnv); err != nil {
 			errs = append(errs, err)
 		}
 	}
 
-	info.config = configureForWalk(parentConfig, rel, info.File)
+	info.configFile, err = withDirectoryDirectives(w.rootConfig, dir, rel, info.File, entries)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if rel == "" {
+		info.configFile, err = withConfigFileDirectives(w.rootConfig, info.configFile)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	info.config = configureForWalk(w.rootConfig, parentConfig, rel, info.configFile)
 	if info.config.isExcludedDir(rel) {
 		// Build file excludes the current directory. Ignore contents.
 		entries = nil
@@ -86,7 +109,7 @@ func (w *walker) loadDirInfo(rel string) (DirInfo, error) {
 
 	for _, e := range entries {
 		entryRel := path.Join(rel, e.Name())
-		e = maybeResolveSymlink(info.config, dir, entryRel, e)
+		e = maybeResolveSymlink(w.rootConfig, info.config, dir, entryRel, e)
 		if e.IsDir() && !info.config.isExcludedDir(entryRel) {
 			info.Subdirs = append(info.Subdirs, e.Name())
 		} else if !e.IsDir() && !info.config.isExcludedFile(entryRel) {
@@ -94,6 +117,8 @@ func (w *walker) loadDirInfo(rel string) (DirInfo, error) {
 		}
 	}
 
+	info.Subdirs, info.RegularFiles = checkCaseCollisions(info.config.caseCollisions, rel, info.Subdirs, info.RegularFiles)
+
 	info.GenFiles = findGenFiles(info.config, info.File)
 
 	// Reduce cap of each slice to len, so that if the caller appends, they'll
@@ -117,7 +142,7 @@ func (w *walker) loadDirInfo(rel string) (DirInfo, error) {
 // directives behave as if they were written inline in that BUILD file.
 // Directive files may not themselves contain directive_file entries (no
 // recursion); any such entries are reported as errors.
-func expandDirectiveFiles(f *rule.File, repoRoot string) error {
+func expandDirectiveFiles(c *config.Config, f *rule.File) error {
 	hasDirectiveFile := false
 	for _, d := range f.Directives {
 		if d.Key == "directive_file" {
@@ -129,7 +154,7 @@ func expandDirectiveFiles(f *rule.File, repoRoot string) error {
 		return nil
 	}
 
-	pkgDir := filepath.Join(repoRoot, filepath.FromSlash(f.Pkg))
+	pkgDir := filepath.Join(c.RepoRoot, filepath.FromSlash(f.Pkg))
 
 	var expanded []rule.Directive
 	var errs []error
@@ -139,14 +164,17 @@ func expandDirectiveFiles(f *rule.File, repoRoot string) error {
 			continue
 		}
 		filePath := filepath.Join(pkgDir, filepath.FromSlash(d.Value))
-		loaded, err := rule.ParseDirectivesFromFile(filePath)
+		loaded, err := readDirectiveFile(c, filePath)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("%s: %v", f.Path, err))
+			errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
 			continue
 		}
 		for _, ld := range loaded {
 			if ld.Key == "directive_file" {
-				errs = append(errs, fmt.Errorf("%s: directive_file in %s: recursive directive_file is not supported", f.Path, d.Value))
+				errs = append(errs, &BuildFileError{
+					Path: f.Path,
+					Err:  fmt.Errorf("%s: directive_file in %s: recursive directive_file is not supported", f.Path, d.Value),
+				})
 				continue
 			}
 			expanded = append(expanded, ld)
@@ -156,12 +184,137 @@ func expandDirectiveFiles(f *rule.File, repoRoot string) error {
 	return errors.Join(errs...)
 }
 
+// readDirectiveFile reads directives from the file at path, as
+// rule.ParseDirectivesFromFile does, using c's file system.
+func readDirectiveFile(c *config.Config, path string) ([]rule.Directive, error) {
+	f, err := c.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading directive file: %w", err)
+	}
+	defer f.Close()
+	directives, err := rule.ParseDirectivesFromReader(f)
+	if err != nil && !errors.As(err, new(*fs.PathError)) {
+		// The file was read but contains an invalid directive.
+		return nil, &BuildFileError{Path: path, Err: err}
+	}
+	return directives, err
+}
+
+// DirectoryDirectiveFileName is the name of a file containing directives for
+// the directory it's in. This lets directories that intentionally have no
+// build file still be configured. The file uses the same format as files
+// loaded with directive_file.
+const DirectoryDirectiveFileName = ".gazelle"
+
+// withDirectoryDirectives returns a copy of the build file f with directives
+// from the directory's DirectoryDirectiveFileName file inserted before its own
+// directives. If f is nil, an empty file is created to hold the directives.
+// If there's no such file, f is returned unchanged. Like
+// withConfigFileDirectives, the returned file is only used for configuration.
+func withDirectoryDirectives(c *config.Config, dir, rel string, f *rule.File, entries []fs.DirEntry) (*rule.File, error) {
+	found := false
+	for _, e := range entries {
+		if e.Name() == DirectoryDirectiveFileName && e.Type().IsRegular() {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return f, nil
+	}
+
+	path := filepath.Join(dir, DirectoryDirectiveFileName)
+	loaded, err := readDirectiveFile(c, path)
+	if err != nil {
+		return f, fmt.Errorf("%s: %w", path, err)
+	}
+	var errs []error
+	directives := loaded[:0]
+	for _, d := range loaded {
+		if d.Key == "directive_file" {
+			errs = append(errs, &BuildFileError{
+				Path: path,
+				Err:  fmt.Errorf("%s: directive_file is not supported", path),
+			})
+			continue
+		}
+		directives = append(directives, d)
+	}
+	if err := expandDirectiveEnv(path, directives, getWalkConfig(c).directiveEnv); err != nil {
+		errs = append(errs, err)
+	}
+
+	var df rule.File
+	if f == nil {
+		df = *rule.EmptyFile(filepath.Join(dir, c.DefaultBuildFileName()), rel)
+	} else {
+		df = *f
+	}
+	df.Directives = append(directives, df.Directives...)
+	return &df, errors.Join(errs...)
+}
+
+// withConfigFileDirectives returns a copy of the root build file f with
+// directives from the repository's configuration file inserted before its own
+// directives. If f is nil, an empty file is created to hold the directives.
+// The returned file is only used for configuration; rules are still generated
+// and merged using f.
+func withConfigFileDirectives(c *config.Config, f *rule.File) (*rule.File, error) {
+	if len(c.ConfigFileDirectives) == 0 {
+		return f, nil
+	}
+	var cf rule.File
+	if f == nil {
+		cf = *rule.EmptyFile(filepath.Join(c.RepoRoot, c.DefaultBuildFileName()), "")
+	} else {
+		cf = *f
+	}
+	directives := append([]rule.Directive(nil), c.ConfigFileDirectives...)
+	err := expandDirectiveEnv("configuration file", directives, getWalkConfig(c).directiveEnv)
+	cf.Directives = append(directives, cf.Directives...)
+	return &cf, err
+}
+
+var directiveEnvPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandDirectiveEnv replaces references like ${NAME} in directive values
+// with values from env. References to variables not in env are reported as
+// errors and left unchanged. Other uses of "$", like backreferences in
+// resolve_regexp, are not affected.
+func expandDirectiveEnv(path string, directives []rule.Directive, env map[string]string) error {
+	var errs []error
+	for i, d := range directives {
+		if !strings.Contains(d.Value, "${") {
+			continue
+		}
+		directives[i].Value = directiveEnvPattern.ReplaceAllStringFunc(d.Value, func(ref string) string {
+			name := ref[len("${") : len(ref)-len("}")]
+			value, ok := env[name]
+			if !ok {
+				errs = append(errs, &BuildFileError{
+					Path: path,
+					Err:  fmt.Errorf("%s: gazelle:%s %s: variable %s is not set or not allowed; allow it with -directive_env=%s", path, d.Key, d.Value, name, name),
+				})
+				return ref
+			}
+			return value
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// defaultJobs is the number of directories populateCache reads at once when
+// the -jobs flag isn't set.
+const defaultJobs = 6
+
 // populateCache loads directory information in a parallel tree traversal.
 // This has no semantic effect but should speed up I/O.
 //
 // populateCache should only be called when recursion is enabled. It avoids
-// traversing excluded subdirectories.
-func (w *walker) populateCache(mode Mode) {
+// traversing excluded subdirectories. At most jobs directories are read at
+// once, or defaultJobs if jobs is not positive. It stops reading directories
+// when ctx is canceled.
+func (w *walker) populateCache(ctx context.Context, mode Mode, jobs int) {
 	// sem is a semaphore.
 	//
 	// Acquiring the semaphore by sending struct{}{} grants permission to spawn
@@ -170,7 +323,10 @@ func (w *walker) populateCache(mode Mode) {
 	// Each goroutine releases the semaphore for itself before acquiring it again
 	// for each child. This prevents a deadlock that could occur for a deeply
 	// nested series of directories.
-	sem := make(chan struct{}, 6)
+	if jobs <= 0 {
+		jobs = defaultJobs
+	}
+	sem := make(chan struct{}, jobs)
 	var wg sync.WaitGroup
 
 	var visit func(string)
@@ -184,8 +340,9 @@ func (w *walker) populateCache(mode Mode) {
 		for _, subdir := range info.Subdirs {
 			subdirRel := path.Join(rel, subdir)
 
-			// Navigate to the subdirectory if it should be visited.
-			if w.shouldVisit(mode, subdirRel, true) {
+			// Navigate to the subdirectory if it should be visited. Stop reading
+			// directories once the walk is canceled.
+			if ctx.Err() == nil && w.shouldVisit(mode, subdirRel, true) {
 				sem <- struct{}{} // acquire semaphore for child
 				wg.Add(1)
 				go func() {
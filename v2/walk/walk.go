@@ -120,7 +120,7 @@ func Walk(c *config.Config, cexts []config.Configurer, dirs []string, mode Mode,
 		wf(args.Dir, args.Rel, args.Config, args.Update, args.File, args.Subdirs, args.RegularFiles, args.GenFiles)
 		return Walk2FuncResult{}
 	}
-	err := Walk2(c, cexts, dirs, mode, w2f)
+	err := Walk2(context.Background(), c, cexts, dirs, mode, w2f)
 	if err != nil {
 		log.Print(err)
 		if c.Strict {
@@ -171,9 +171,9 @@ type Walk2FuncArgs struct {
 }
 
 type Walk2FuncResult struct {
-	// Err is an error encountered by the callback function. It's logged to the
-	// console. When Config.Strict is set, setting Err causes Walk2 to return
-	// early.
+	// Err is an error encountered by the callback function. Walk2 continues
+	// visiting other directories and returns it along with other errors
+	// after the walk.
 	Err error
 
 	// RelsToVisit is a list of additional directories to visit. Each directory is
@@ -208,23 +208,26 @@ type Walk2FuncResult struct {
 // that language.Configurer.Configure is called on each extension in cexts in a
 // directory *before* visiting its subdirectories; wf is called in a directory
 // *after* its subdirectories.
-func Walk2(c *config.Config, cexts []config.Configurer, dirs []string, mode Mode, wf Walk2Func) error {
-	w, err := newWalker(c, cexts, dirs, mode, wf)
+//
+// If ctx is canceled, Walk2 stops visiting directories and returns ctx's
+// error. wf isn't called in directories that weren't visited yet.
+func Walk2(ctx context.Context, c *config.Config, cexts []config.Configurer, dirs []string, mode Mode, wf Walk2Func) error {
+	w, err := newWalker(ctx, c, cexts, dirs, mode, wf)
 	if err != nil {
 		return err
 	}
 	cleanup := setGlobalWalker(w)
 	defer cleanup()
 
+	// Only report directive errors found in this walk.
+	c.ResetDirectiveErrors()
+
 	// Do the main tree walk, visiting directories the user requested.
 	w.visit(mode, c, "", false)
-	if c.Strict && len(w.errs) > 0 {
-		return errors.Join(w.errs...)
-	}
 
 	// Visit additional directories that extensions requested for indexing.
 	// Don't visit subdirectories recursively, even when recursion is enabled.
-	for len(w.relsToVisit) > 0 {
+	for len(w.relsToVisit) > 0 && ctx.Err() == nil {
 		// Don't simply range over relsToVisit. We may append more.
 		relToVisit := w.relsToVisit[0]
 		w.relsToVisit = w.relsToVisit[1:]
@@ -257,18 +260,42 @@ func Walk2(c *config.Config, cexts []config.Configurer, dirs []string, mode Mode
 				}
 				c := parentCfg.Clone()
 				w.visit(UpdateDirsMode, c, rel, false)
-				if c.Strict && len(w.errs) > 0 {
-					return false
-				}
 			}
 			return true
 		})
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return w.finish()
+}
+
+// finish reports errors in directives found during the walk, sorted by
+// location, and returns the errors encountered. Directive errors are logged
+// with a summary, unless Config.Strict is set, in which case they're
+// returned with other errors.
+func (w *walker) finish() error {
+	directiveErrs := w.rootConfig.DirectiveErrors()
+	if len(directiveErrs) > 0 {
+		if w.rootConfig.Strict {
+			for _, err := range directiveErrs {
+				w.errs = append(w.errs, err)
+			}
+		} else {
+			for _, err := range directiveErrs {
+				log.Print(err)
+			}
+			log.Printf("found %d invalid directives; run with -strict to make these errors", len(directiveErrs))
+		}
+	}
 	return errors.Join(w.errs...)
 }
 
 // walker holds state needed for a walk of the source tree.
 type walker struct {
+	// ctx is the context passed to Walk2. The walk stops when it's canceled.
+	ctx context.Context
+
 	// repoRoot is the absolute file path to the repo's root directory.
 	repoRoot string
 
@@ -311,8 +338,8 @@ type walker struct {
 	relsToVisitSeen map[string]struct{}
 
 	// errs is a list of errors encountered while walking the directory tree.
-	// If the Config.Strict flag is set in the root configuration, we return
-	// quickly after the first error.
+	// The walk continues after errors, even when Config.Strict is set, so
+	// that all of them can be reported at once.
 	errs []error
 }
 
@@ -326,7 +353,7 @@ type visitInfo struct {
 	regularFiles, subdirs []string
 }
 
-func newWalker(c *config.Config, cexts []config.Configurer, dirs []string, mode Mode, wf Walk2Func) (*walker, error) {
+func newWalker(ctx context.Context, c *config.Config, cexts []config.Configurer, dirs []string, mode Mode, wf Walk2Func) (*walker, error) {
 	knownDirectives := make(map[string]bool)
 	for _, cext := range cexts {
 		for _, d := range cext.KnownDirectives() {
@@ -365,6 +392,7 @@ func newWalker(c *config.Config, cexts []config.Configurer, dirs []string, mode
 	}
 
 	w := &walker{
+		ctx:             ctx,
 		repoRoot:        c.RepoRoot,
 		rootConfig:      c,
 		cache:           new(cache),
@@ -377,7 +405,7 @@ func newWalker(c *config.Config, cexts []config.Configurer, dirs []string, mode
 	}
 
 	// Asynchronously populate the walker cache in the background.
-	go w.populateCache(mode)
+	go w.populateCache(ctx, mode, c.Jobs)
 
 	return w, nil
 }
@@ -416,6 +444,10 @@ func (w *walker) shouldUpdate(mode Mode, rel string, updateParent bool) bool {
 // shouldUpdate). The callback may not actually be called if the build file
 // contains syntax errors or a gazelle:ignore directive.
 func (w *walker) visit(mode Mode, c *config.Config, rel string, updateParent bool) {
+	if w.ctx.Err() != nil {
+		return
+	}
+
 	// Absolute path to the directory being visited
 	dir := filepath.Join(c.RepoRoot, rel)
 
@@ -436,7 +468,7 @@ func (w *walker) visit(mode Mode, c *config.Config, rel string, updateParent boo
 	// Configure the directory, if we haven't done so already.
 	_, alreadyConfigured := w.visits[rel]
 	if !containedByParent && !alreadyConfigured {
-		if err := configure(w.cexts, w.knownDirectives, c, rel, info.File, info.config); err != nil {
+		if err := configure(w.ctx, w.cexts, w.knownDirectives, c, rel, info.configFile, info.config); err != nil {
 			w.errs = append(w.errs, err)
 		}
 	}
@@ -456,11 +488,11 @@ func (w *walker) visit(mode Mode, c *config.Config, rel string, updateParent boo
 		subdirRel := path.Join(rel, subdir)
 		if w.shouldVisit(mode, subdirRel, shouldUpdate) {
 			w.visit(mode, c.Clone(), subdirRel, shouldUpdate)
-			if c.Strict && len(w.errs) > 0 {
-				return
-			}
 		}
 	}
+	if w.ctx.Err() != nil {
+		return
+	}
 
 	// Recursively collect regular files from subdirectories that won't contain
 	// build files. Files are added in depth-first pre-order.
@@ -516,13 +548,13 @@ func (w *walker) visit(mode Mode, c *config.Config, rel string, updateParent boo
 	}
 }
 
-func loadBuildFile(wc *walkConfig, readBuildFilesDir string, pkg, dir string, ents []fs.DirEntry) (*rule.File, error) {
+func loadBuildFile(c *config.Config, wc *walkConfig, pkg, dir string, ents []fs.DirEntry) (*rule.File, error) {
 	var err error
 	readDir := dir
 	readEnts := ents
-	if readBuildFilesDir != "" {
-		readDir = filepath.Join(readBuildFilesDir, filepath.FromSlash(pkg))
-		readEnts, err = os.ReadDir(readDir)
+	if c.ReadBuildFilesDir != "" {
+		readDir = filepath.Join(c.ReadBuildFilesDir, filepath.FromSlash(pkg))
+		readEnts, err = c.ReadDir(readDir)
 		if err != nil {
 			return nil, err
 		}
@@ -531,26 +563,49 @@ func loadBuildFile(wc *walkConfig, readBuildFilesDir string, pkg, dir string, en
 	if path == "" {
 		return nil, nil
 	}
-	return rule.LoadFile(path, pkg)
+	data, err := c.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := rule.LoadData(path, pkg, data)
+	if err != nil {
+		return nil, &BuildFileError{Path: path, Err: err}
+	}
+	return f, nil
 }
 
-func configure(cexts []config.Configurer, knownDirectives map[string]bool, c *config.Config, rel string, f *rule.File, wc *walkConfig) error {
+// BuildFileError describes a build file or directive file that can't be
+// parsed or has invalid contents. Walk2 returns these, joined with other
+// errors like failures to read directories, so callers can tell problems in
+// the user's configuration from other failures.
+type BuildFileError struct {
+	// Path is the path to the invalid file.
+	Path string
+
+	// Err describes what's wrong with the file. Its message includes Path.
+	Err error
+}
+
+func (e *BuildFileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BuildFileError) Unwrap() error {
+	return e.Err
+}
+
+func configure(ctx context.Context, cexts []config.Configurer, knownDirectives map[string]bool, c *config.Config, rel string, f *rule.File, wc *walkConfig) error {
 	if f != nil {
 		for _, d := range f.Directives {
 			if !knownDirectives[d.Key] {
-				log.Printf("%s: unknown directive: gazelle:%s", f.Path, d.Key)
-				if c.Strict {
-					// TODO(https://github.com/bazelbuild/bazel-gazelle/issues/1029):
-					// Refactor to accumulate and propagate errors to main.
-					log.Fatal("Exit as strict mode is on")
-				}
+				c.ReportDirectiveError(f, d, errors.New("unknown directive"))
 			}
 		}
 	}
 	c.Exts[walkNameCached] = wc
 	var errs []error
 	for _, cext := range cexts {
-		if err := cext.Configure(context.TODO(), config.ConfigureArgs{
+		if err := cext.Configure(ctx, config.ConfigureArgs{
 			Config: c,
 			Rel:    rel,
 			File:   f,
@@ -593,7 +648,7 @@ func findGenFiles(wc *walkConfig, f *rule.File) []string {
 // the target file or directory.
 //
 // Otherwise, maybeResolveSymlink returns ent as-is.
-func maybeResolveSymlink(wc *walkConfig, dir, rel string, ent fs.DirEntry) fs.DirEntry {
+func maybeResolveSymlink(c *config.Config, wc *walkConfig, dir, rel string, ent fs.DirEntry) fs.DirEntry {
 	if ent.Type()&os.ModeSymlink == 0 {
 		// Not a symlink, use the original FileInfo.
 		return ent
@@ -602,7 +657,7 @@ func maybeResolveSymlink(wc *walkConfig, dir, rel string, ent fs.DirEntry) fs.Di
 		// A symlink, but not one we should follow.
 		return ent
 	}
-	fi, err := os.Stat(path.Join(dir, ent.Name()))
+	fi, err := c.Stat(path.Join(dir, ent.Name()))
 	if err != nil {
 		// A symlink, but not one we could resolve.
 		return ent
@@ -18,8 +18,10 @@ package walk
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log"
 	"os"
 	"path"
 	"path/filepath"
@@ -66,7 +68,7 @@ func TestConfigureCallbackOrder(t *testing.T) {
 		cexts = append(cexts, &testConfigurer{func(_ *config.Config, rel string, _ *rule.File) {
 			configureRels = append(configureRels, rel)
 		}})
-		err := Walk2(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			callbackRels = append(callbackRels, args.Rel)
 			return Walk2FuncResult{}
 		})
@@ -178,7 +180,7 @@ func TestUpdateDirs(t *testing.T) {
 
 			t.Run("Walk2", func(t *testing.T) {
 				var visits []visitSpec
-				err := Walk2(c, cexts, dirs, tc.mode, func(args Walk2FuncArgs) Walk2FuncResult {
+				err := Walk2(context.Background(), c, cexts, dirs, tc.mode, func(args Walk2FuncArgs) Walk2FuncResult {
 					visits = append(visits, visitSpec{args.Rel, args.Update})
 					return Walk2FuncResult{}
 				})
@@ -288,7 +290,7 @@ func TestGenMode(t *testing.T) {
 	t.Run("Walk2 generation_mode create vs update", func(t *testing.T) {
 		c, cexts := testConfig(t, dir)
 		var visits []visitSpec
-		err := Walk2(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			visits = append(visits, visitSpec{
 				rel:     args.Rel,
 				subdirs: args.Subdirs,
@@ -346,7 +348,7 @@ func TestCustomBuildName(t *testing.T) {
 	t.Run("Walk2", func(t *testing.T) {
 		c, cexts := testConfig(t, dir)
 		var rels []string
-		err := Walk2(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			rel, err := filepath.Rel(c.RepoRoot, args.File.Path)
 			if err != nil {
 				t.Error(err)
@@ -449,7 +451,7 @@ a.file
 	t.Run("Walk2", func(t *testing.T) {
 		c, cexts := testConfig(t, dir)
 		var files []string
-		err := Walk2(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			for _, f := range args.RegularFiles {
 				files = append(files, path.Join(args.Rel, f))
 			}
@@ -498,7 +500,7 @@ func TestExcludeSelf(t *testing.T) {
 	t.Run("Walk2", func(t *testing.T) {
 		c, cexts := testConfig(t, dir)
 		var rels []string
-		err := Walk2(c, cexts, []string{dir}, VisitAllUpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			rels = append(rels, args.Rel)
 			return Walk2FuncResult{}
 		})
@@ -562,7 +564,7 @@ unknown_rule(
 	t.Run("Walk2", func(t *testing.T) {
 		c, cexts := testConfig(t, dir)
 		var regularFiles, genFiles []string
-		err := Walk2(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			for _, f := range args.RegularFiles {
 				regularFiles = append(regularFiles, path.Join(args.Rel, f))
 			}
@@ -630,7 +632,7 @@ func TestFollow(t *testing.T) {
 	t.Run("Walk2", func(t *testing.T) {
 		c, cexts := testConfig(t, dir)
 		var gotRegularFiles, gotSubdirs []string
-		err := Walk2(c, cexts, []string{dir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			gotRegularFiles = args.RegularFiles
 			gotSubdirs = args.Subdirs
 			return Walk2FuncResult{}
@@ -698,7 +700,7 @@ func TestSubdirsContained(t *testing.T) {
 	t.Run("Walk2", func(t *testing.T) {
 		c, cexts := testConfig(t, dir)
 		var rootRegularFiles, rootSubdirs []string
-		err := Walk2(c, cexts, []string{dir}, VisitAllUpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 			if args.Rel == "" {
 				rootRegularFiles = args.RegularFiles
 				rootSubdirs = args.Subdirs
@@ -731,7 +733,7 @@ func TestRelsToVisit(t *testing.T) {
 		},
 	})
 	updateDir := filepath.Join(dir, "update")
-	err := Walk2(c, cexts, []string{updateDir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+	err := Walk2(context.Background(), c, cexts, []string{updateDir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 		visitedRels = append(visitedRels, args.Rel)
 		if args.Update {
 			updatedRels = append(updatedRels, args.Rel)
@@ -772,6 +774,41 @@ func TestRelsToVisit(t *testing.T) {
 	}
 }
 
+func TestCancel(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a/"},
+		{Path: "b/"},
+		{Path: "c/"},
+	})
+	defer cleanup()
+
+	// Cancel the walk in the first directory visited. No other directories
+	// should be configured or visited after that.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var configuredRels, visitedRels []string
+	c, cexts := testConfig(t, dir)
+	cexts = append(cexts, &testConfigurer{
+		configure: func(_ *config.Config, rel string, _ *rule.File) {
+			configuredRels = append(configuredRels, rel)
+		},
+	})
+	err := Walk2(ctx, c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		visitedRels = append(visitedRels, args.Rel)
+		cancel()
+		return Walk2FuncResult{RelsToVisit: []string{"c"}}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v; want %v", err, context.Canceled)
+	}
+	if diff := cmp.Diff([]string{"", "a"}, configuredRels); diff != "" {
+		t.Errorf("configured rels (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a"}, visitedRels); diff != "" {
+		t.Errorf("visited rels (-want,+got):\n%s", diff)
+	}
+}
+
 func TestGetDirInfo(t *testing.T) {
 	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
 		{
@@ -801,7 +838,7 @@ genrule(
 	wantGenFiles := []string{"gen.txt"}
 
 	c, cexts := testConfig(t, dir)
-	err := Walk2(c, cexts, []string{dir}, VisitAllUpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+	err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 		di, err := GetDirInfo("")
 		if err != nil {
 			t.Fatal(err)
@@ -838,7 +875,7 @@ func TestGetDirInfoSubdir(t *testing.T) {
 	defer cleanup()
 
 	c, cexts := testConfig(t, dir)
-	err := Walk2(c, cexts, []string{dir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+	err := Walk2(context.Background(), c, cexts, []string{dir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 		bInfo, err := GetDirInfo("a/b")
 		if err != nil {
 			t.Fatal(err)
@@ -870,7 +907,7 @@ func TestGetDirInfoErrorOnParent(t *testing.T) {
 	defer cleanup()
 
 	c, cexts := testConfig(t, dir)
-	err := Walk2(c, cexts, []string{dir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+	err := Walk2(context.Background(), c, cexts, []string{dir}, UpdateDirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 		di, err := GetDirInfo("parent/child")
 		if err == nil {
 			t.Error("expected error due to error in parent")
@@ -1049,7 +1086,7 @@ func TestDirectiveFileNoRecursion(t *testing.T) {
 			gotDirectives = f.Directives
 		}
 	}})
-	err := Walk2(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+	err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
 		return Walk2FuncResult{}
 	})
 
@@ -1187,3 +1224,331 @@ func BenchmarkWalk(b *testing.B) {
 		Walk(c, nil, fs.Args(), VisitAllUpdateSubdirsMode, wf)
 	}
 }
+
+func TestConfigFileDirectives(t *testing.T) {
+	for _, tc := range []struct {
+		desc  string
+		files []testtools.FileSpec
+	}{
+		{
+			desc: "with root build file",
+			files: []testtools.FileSpec{
+				{Path: "BUILD.bazel", Content: "# gazelle:exclude inline_excluded\n"},
+				{Path: "kept/"},
+				{Path: "inline_excluded/"},
+				{Path: "config_excluded/"},
+			},
+		},
+		{
+			desc: "without root build file",
+			files: []testtools.FileSpec{
+				{Path: "kept/"},
+				{Path: "config_excluded/"},
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir, cleanup := testtools.CreateFiles(t, tc.files)
+			defer cleanup()
+
+			var visited []string
+			var rootFile *rule.File
+			c, cexts := testConfig(t, dir)
+			c.ConfigFileDirectives = []rule.Directive{{Key: "exclude", Value: "config_excluded"}}
+			Walk(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(_ string, rel string, _ *config.Config, _ bool, f *rule.File, _, _, _ []string) {
+				visited = append(visited, rel)
+				if rel == "" {
+					rootFile = f
+				}
+			})
+
+			want := []string{"kept", ""}
+			if diff := cmp.Diff(want, visited); diff != "" {
+				t.Errorf("visited directories (-want +got):\n%s", diff)
+			}
+			// Directives from the configuration file must not leak into the
+			// file that rules are generated into.
+			if rootFile != nil {
+				for _, d := range rootFile.Directives {
+					if d.Value == "config_excluded" {
+						t.Errorf("root build file contains configuration file directive %v", d)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResetDirective(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:exclude **/gen\n",
+		},
+		{Path: "a/gen/"},
+		{
+			Path: "vendor/BUILD.bazel",
+			Content: `# gazelle:reset exclude
+# gazelle:exclude testdata
+`,
+		},
+		{Path: "vendor/gen/"},
+		{Path: "vendor/testdata/"},
+	})
+	defer cleanup()
+
+	var visited []string
+	c, cexts := testConfig(t, dir)
+	Walk(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(_ string, rel string, _ *config.Config, _ bool, _ *rule.File, _, _, _ []string) {
+		visited = append(visited, rel)
+	})
+
+	want := []string{"a", "vendor/gen", "vendor", ""}
+	if diff := cmp.Diff(want, visited); diff != "" {
+		t.Errorf("visited directories (-want +got):\n%s", diff)
+	}
+}
+
+func TestDirectiveEnv(t *testing.T) {
+	t.Setenv("GAZELLE_TEST_EXCLUDE", "env_excluded")
+	t.Setenv("GAZELLE_TEST_SECRET", "secret")
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:exclude ${GAZELLE_TEST_EXCLUDE}
+# gazelle:resolve go example.com/ws ${BUILD_WORKSPACE_DIRECTORY}
+# gazelle:resolve_regexp go example.com/(.*) //$1
+`,
+		},
+		{Path: "kept/"},
+		{Path: "env_excluded/"},
+		{
+			Path:    "secret/BUILD.bazel",
+			Content: "# gazelle:exclude ${GAZELLE_TEST_SECRET}\n",
+		},
+	})
+	defer cleanup()
+
+	args := []string{"-repo_root", dir, "-directive_env", "GAZELLE_TEST_EXCLUDE"}
+	cexts := []config.Configurer{&config.CommonConfigurer{}, &Configurer{}}
+	c := testtools.NewTestConfig(t, cexts, nil, args)
+
+	var visited []string
+	var rootDirectives []rule.Directive
+	err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		visited = append(visited, args.Rel)
+		if args.Rel == "" {
+			rootDirectives = args.File.Directives
+		}
+		return Walk2FuncResult{}
+	})
+	if err == nil {
+		t.Error("got nil error for variable that is not allowed")
+	}
+
+	wantVisited := []string{"kept", "secret", ""}
+	if diff := cmp.Diff(wantVisited, visited); diff != "" {
+		t.Errorf("visited directories (-want +got):\n%s", diff)
+	}
+	wantDirectives := []rule.Directive{
+		{Key: "exclude", Value: "env_excluded"},
+		{Key: "resolve", Value: "go example.com/ws " + c.RepoRoot},
+		{Key: "resolve_regexp", Value: "go example.com/(.*) //$1"},
+	}
+	if diff := cmp.Diff(wantDirectives, rootDirectives); diff != "" {
+		t.Errorf("root directives (-want +got):\n%s", diff)
+	}
+}
+
+func TestDirectoryDirectiveFile(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "sub/.gazelle",
+			Content: `# Directives for a directory without a build file.
+exclude gen
+# gazelle:exclude other
+`,
+		},
+		{Path: "sub/gen/"},
+		{Path: "sub/other/"},
+		{Path: "sub/kept/"},
+		{
+			Path:    "built/.gazelle",
+			Content: "exclude gen\n",
+		},
+		{
+			Path:    "built/BUILD.bazel",
+			Content: "# gazelle:exclude other\n",
+		},
+		{Path: "built/gen/"},
+		{Path: "built/other/"},
+	})
+	defer cleanup()
+
+	var visited []string
+	var subDirectives []rule.Directive
+	c, cexts := testConfig(t, dir)
+	cexts = append(cexts, &testConfigurer{func(_ *config.Config, rel string, f *rule.File) {
+		if rel == "sub" && f != nil {
+			subDirectives = f.Directives
+		}
+	}})
+	Walk(c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(_ string, rel string, _ *config.Config, _ bool, f *rule.File, _, _, _ []string) {
+		visited = append(visited, rel)
+		if rel == "sub" && f != nil {
+			t.Errorf("got build file for directory with only %s", DirectoryDirectiveFileName)
+		}
+	})
+
+	wantVisited := []string{"built", "sub/kept", "sub", ""}
+	if diff := cmp.Diff(wantVisited, visited); diff != "" {
+		t.Errorf("visited directories (-want +got):\n%s", diff)
+	}
+	wantDirectives := []rule.Directive{
+		{Key: "exclude", Value: "gen"},
+		{Key: "exclude", Value: "other"},
+	}
+	if diff := cmp.Diff(wantDirectives, subDirectives); diff != "" {
+		t.Errorf("directives (-want +got):\n%s", diff)
+	}
+}
+
+func TestDirectiveErrors(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path: "BUILD.bazel",
+			Content: `# gazelle:exclude a
+
+# gazelle:unknown value
+`,
+		},
+		{
+			Path:    "sub/BUILD.bazel",
+			Content: "# gazelle:exclude [\n",
+		},
+	})
+	defer cleanup()
+
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			args := []string{"-repo_root", dir, fmt.Sprintf("-strict=%v", strict)}
+			cexts := []config.Configurer{&config.CommonConfigurer{}, &Configurer{}}
+			c := testtools.NewTestConfig(t, cexts, nil, args)
+			err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(Walk2FuncArgs) Walk2FuncResult {
+				return Walk2FuncResult{}
+			})
+
+			var got []string
+			for _, e := range c.DirectiveErrors() {
+				got = append(got, fmt.Sprintf("%s:%d: %s", filepath.Base(e.Path), e.Line, e.Directive.Key))
+			}
+			want := []string{"BUILD.bazel:3: unknown", "BUILD.bazel:1: exclude"}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("directive errors (-want +got):\n%s", diff)
+			}
+			if strict && err == nil {
+				t.Error("got nil error in strict mode")
+			} else if !strict && err != nil {
+				t.Errorf("got error %v, want nil outside strict mode", err)
+			}
+		})
+	}
+}
+
+func TestDirectiveErrorsReset(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{
+			Path:    "BUILD.bazel",
+			Content: "# gazelle:unknown value\n",
+		},
+	})
+	defer cleanup()
+
+	args := []string{"-repo_root", dir, "-strict"}
+	cexts := []config.Configurer{&config.CommonConfigurer{}, &Configurer{}}
+	c := testtools.NewTestConfig(t, cexts, nil, args)
+	wf := func(Walk2FuncArgs) Walk2FuncResult { return Walk2FuncResult{} }
+	if err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, wf); err == nil {
+		t.Fatal("got nil error for invalid directive")
+	}
+
+	// Fix the directive and walk again with the same configuration, as watch
+	// and server mode do.
+	if err := os.WriteFile(filepath.Join(dir, "BUILD.bazel"), nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, wf); err != nil {
+		t.Errorf("second walk: got error %v, want nil", err)
+	}
+	if errs := c.DirectiveErrors(); len(errs) > 0 {
+		t.Errorf("second walk: got directive errors %v", errs)
+	}
+}
+
+func TestCaseCollisions(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "warn/Foo.go"},
+		{Path: "warn/foo.go"},
+		{Path: "warn/other.go"},
+		{
+			Path:    "excl/BUILD.bazel",
+			Content: "# gazelle:case_collisions exclude\n",
+		},
+		{Path: "excl/README"},
+		{Path: "excl/readme"},
+		{Path: "excl/Sub/a.go"},
+		{Path: "excl/sub/b.go"},
+		{
+			Path:    "off/BUILD.bazel",
+			Content: "# gazelle:case_collisions off\n",
+		},
+		{Path: "off/A.txt"},
+		{Path: "off/a.txt"},
+	})
+	defer cleanup()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	c, cexts := testConfig(t, dir)
+	var files []string
+	err := Walk2(context.Background(), c, cexts, []string{dir}, VisitAllUpdateSubdirsMode, func(args Walk2FuncArgs) Walk2FuncResult {
+		for _, f := range args.RegularFiles {
+			files = append(files, path.Join(args.Rel, f))
+		}
+		return Walk2FuncResult{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := []string{
+		"excl/Sub/a.go",
+		"excl/BUILD.bazel",
+		"excl/README",
+		"off/A.txt",
+		"off/BUILD.bazel",
+		"off/a.txt",
+		"warn/Foo.go",
+		"warn/foo.go",
+		"warn/other.go",
+	}
+	if diff := cmp.Diff(wantFiles, files); diff != "" {
+		t.Errorf("files (-want +got):\n%s", diff)
+	}
+	for _, want := range []string{
+		"warn/Foo.go, warn/foo.go: names differ only by case",
+		"excl/README, excl/readme: names differ only by case and can't coexist on case-insensitive file systems; excluding excl/readme",
+		"excl/Sub, excl/sub: names differ only by case and can't coexist on case-insensitive file systems; excluding excl/sub",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log does not contain %q:\n%s", want, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "off/") {
+		t.Errorf("got warning for directory with case_collisions off:\n%s", logs.String())
+	}
+}
@@ -18,6 +18,8 @@ limitations under the License.
 package walk
 
 import (
+	"context"
+
 	"github.com/bazel-contrib/bazel-gazelle/v2/compat"
 	v2config "github.com/bazel-contrib/bazel-gazelle/v2/config"
 	v2 "github.com/bazel-contrib/bazel-gazelle/v2/walk"
@@ -144,5 +146,5 @@ func Walk2(c *config.Config, cexts []config.Configurer, dirs []string, mode Mode
 	for i, cext := range cexts {
 		v2cexts[i] = compat.MustConfigurerV2(cext)
 	}
-	return v2.Walk2(c, v2cexts, dirs, mode, wf)
+	return v2.Walk2(context.Background(), c, v2cexts, dirs, mode, wf)
 }